@@ -167,11 +167,14 @@ func {{.LowerName}}Router(
 func {{.LowerName}}Middlewares(c *middlewareConfig) {
 	// set up group route middleware, group path is left prefix rules,
 	// if the left prefix is hit, the middleware will take effect, e.g. group route is /api/v1, route /api/v1/{{.LowerName}}/:id  will take effect
-	// c.setGroupPath("/api/v1/{{.LowerName}}", middleware.Auth())
+	// c.setGroupPath("/api/v1/{{.LowerName}}", TagMiddleware(ClassAuth, middleware.Auth()))
 
-	// set up single route middleware, just uncomment the code and fill in the middlewares, nothing else needs to be changed
+	// set up single route middleware, just uncomment the code and fill in the middlewares, nothing else needs to be changed,
+	// the mutation routes are marked public by AllowAnonymous(), replace the marks with the authentication, e.g.
+	// TagMiddleware(ClassAuth, middleware.Auth()), the mutation routes without the authentication or the mark fail
+	// the startup, see checkRoutePolicy
 {{- range .Methods}}
-	{{if eq .InvokeType 0}}{{if .Path}}//c.setSinglePath("{{.Method}}", "{{.Path}}", middleware.Auth())    {{.Comment}}{{end}}{{end}}
+	{{if eq .InvokeType 0}}{{if .Path}}{{if eq .Method "GET"}}//c.setSinglePath("{{.Method}}", "{{.Path}}", TagMiddleware(ClassAuth, middleware.Auth()))    {{.Comment}}{{else}}c.setSinglePath("{{.Method}}", "{{.Path}}", AllowAnonymous())    {{.Comment}}{{end}}{{end}}{{end}}
 {{- end}}
 }
 
@@ -35,6 +35,15 @@ func CreateServices() []app.IServer {
 
 	servers = append(servers, httpServer, grpcServer)
 
+	// create a http service of the admin and debug APIs if the admin port is set
+	if cfg.HTTP.Admin.Port > 0 {
+		adminAddr := ":" + strconv.Itoa(cfg.HTTP.Admin.Port)
+		adminServer := server.NewAdminHTTPServer(adminAddr,
+			server.WithHTTPIsProd(cfg.App.Env == "prod"),
+		)
+		servers = append(servers, adminServer)
+	}
+
 	return servers
 }
 
@@ -8,6 +8,7 @@ import (
 	"strconv"
 
 	"github.com/go-dev-frame/sponge/pkg/logger"
+	"github.com/go-dev-frame/sponge/pkg/prof"
 	"github.com/go-dev-frame/sponge/pkg/stat"
 	"github.com/go-dev-frame/sponge/pkg/tracer"
 
@@ -30,6 +31,7 @@ func InitApp() {
 	_, err := logger.Init(
 		logger.WithLevel(cfg.Logger.Level),
 		logger.WithFormat(cfg.Logger.Format),
+		logger.WithModuleLevels(cfg.Logger.ModuleLevels),
 		logger.WithSave(
 			cfg.Logger.IsSave,
 			//logger.WithFileName(cfg.Logger.LogFileConfig.Filename),
@@ -60,9 +62,19 @@ func InitApp() {
 
 	// initializing the print system and process resources
 	if cfg.App.EnableStat {
+		var collectorOpts []stat.CollectorOption
+		if autoProfiler, err := prof.InitAutoProfiler(); err != nil {
+			logger.Warn("init auto profiler error", logger.Err(err))
+		} else {
+			// capture the profiles automatically on high load, the captures are listed by /debug/profiles
+			collectorOpts = append(collectorOpts, stat.WithSampleHook(func(s *stat.Sample) {
+				autoProfiler.Observe(s.CPUUsage, s.Goroutines)
+			}))
+		}
 		stat.Init(
 			stat.WithLog(logger.Get()),
-			stat.WithAlarm(), // invalid if it is windows, the default threshold for cpu and memory is 0.8, you can modify them
+			stat.WithAlarm(),                     // invalid if it is windows, the default threshold for cpu and memory is 0.8, you can modify them
+			stat.WithCollector(collectorOpts...), // export the process metrics to prometheus, the last samples are queried by /debug/stat
 			stat.WithPrintField(logger.String("service_name", cfg.App.Name), logger.String("host", cfg.App.Host)),
 		)
 		logger.Info("[resource statistics] was initialized")
@@ -2,9 +2,12 @@
 package main
 
 import (
+	"time"
+
 	"github.com/go-dev-frame/sponge/pkg/app"
 
 	"github.com/go-dev-frame/sponge/cmd/serverNameExample_grpcHttpPbExample/initial"
+	"github.com/go-dev-frame/sponge/internal/config"
 )
 
 func main() {
@@ -12,6 +15,6 @@ func main() {
 	services := initial.CreateServices()
 	closes := initial.Close(services)
 
-	a := app.New(services, closes)
+	a := app.New(services, closes, app.WithDrainPeriod(time.Duration(config.Get().App.DrainPeriod)*time.Second))
 	a.Run()
 }
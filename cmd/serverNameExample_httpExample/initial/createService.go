@@ -21,5 +21,14 @@ func CreateServices() []app.IServer {
 	)
 	servers = append(servers, httpServer)
 
+	// create a http service of the admin and debug APIs if the admin port is set
+	if cfg.HTTP.Admin.Port > 0 {
+		adminAddr := ":" + strconv.Itoa(cfg.HTTP.Admin.Port)
+		adminServer := server.NewAdminHTTPServer(adminAddr,
+			server.WithHTTPIsProd(cfg.App.Env == "prod"),
+		)
+		servers = append(servers, adminServer)
+	}
+
 	return servers
 }
@@ -29,6 +29,15 @@ func CreateServices() []app.IServer {
 	)
 	servers = append(servers, httpServer)
 
+	// create a http service of the admin and debug APIs if the admin port is set
+	if cfg.HTTP.Admin.Port > 0 {
+		adminAddr := ":" + strconv.Itoa(cfg.HTTP.Admin.Port)
+		adminServer := server.NewAdminHTTPServer(adminAddr,
+			server.WithHTTPIsProd(cfg.App.Env == "prod"),
+		)
+		servers = append(servers, adminServer)
+	}
+
 	// create a grpc service
 	grpcAddr := ":" + strconv.Itoa(cfg.Grpc.Port)
 	grpcRegistry, grpcInstance := registerService("grpc", cfg.App.Host, cfg.Grpc.Port)
@@ -13,12 +13,14 @@ import (
 	"github.com/go-dev-frame/sponge/pkg/conf"
 	"github.com/go-dev-frame/sponge/pkg/logger"
 	"github.com/go-dev-frame/sponge/pkg/nacoscli"
+	"github.com/go-dev-frame/sponge/pkg/prof"
 	"github.com/go-dev-frame/sponge/pkg/stat"
 	"github.com/go-dev-frame/sponge/pkg/tracer"
 
 	"github.com/go-dev-frame/sponge/configs"
 	"github.com/go-dev-frame/sponge/internal/config"
 	"github.com/go-dev-frame/sponge/internal/database"
+	"github.com/go-dev-frame/sponge/internal/handler"
 )
 
 var (
@@ -36,6 +38,7 @@ func InitApp() {
 	_, err := logger.Init(
 		logger.WithLevel(cfg.Logger.Level),
 		logger.WithFormat(cfg.Logger.Format),
+		logger.WithModuleLevels(cfg.Logger.ModuleLevels),
 		logger.WithSave(
 			cfg.Logger.IsSave,
 			//logger.WithFileName(cfg.Logger.LogFileConfig.Filename),
@@ -66,14 +69,27 @@ func InitApp() {
 
 	// initializing the print system and process resources
 	if cfg.App.EnableStat {
+		var collectorOpts []stat.CollectorOption
+		if autoProfiler, err := prof.InitAutoProfiler(); err != nil {
+			logger.Warn("init auto profiler error", logger.Err(err))
+		} else {
+			// capture the profiles automatically on high load, the captures are listed by /debug/profiles
+			collectorOpts = append(collectorOpts, stat.WithSampleHook(func(s *stat.Sample) {
+				autoProfiler.Observe(s.CPUUsage, s.Goroutines)
+			}))
+		}
 		stat.Init(
 			stat.WithLog(logger.Get()),
-			stat.WithAlarm(), // invalid if it is windows, the default threshold for cpu and memory is 0.8, you can modify them
+			stat.WithAlarm(),                     // invalid if it is windows, the default threshold for cpu and memory is 0.8, you can modify them
+			stat.WithCollector(collectorOpts...), // export the process metrics to prometheus, the last samples are queried by /debug/stat
 			stat.WithPrintField(logger.String("service_name", cfg.App.Name), logger.String("host", cfg.App.Host)),
 		)
 		logger.Info("[resource statistics] was initialized")
 	}
 
+	// initializing the feature flags of the routes
+	handler.SetFeatureFlags(cfg.FeatureFlags)
+
 	// initializing database
 	database.InitDB()
 	logger.Infof("[%s] was initialized", cfg.Database.Driver)
@@ -126,6 +142,25 @@ func getConfigFromNacos() {
 		panic("read the config from center error, config data is empty")
 	}
 	config.Set(appConfig)
+
+	// apply the log level and the feature flags live when the config in nacos is changed
+	_, err = nacoscli.ListenConfig(params, func(format string, data []byte) {
+		newConfig := &config.Config{}
+		if err := conf.ParseConfigData(data, format, newConfig); err != nil {
+			logger.Warn("parse the changed configuration data error", logger.Err(err))
+			return
+		}
+		if err := logger.SetLevel(newConfig.Logger.Level); err != nil {
+			logger.Warn("set log level error", logger.Err(err))
+		}
+		if err := logger.SetModuleLevels(newConfig.Logger.ModuleLevels); err != nil {
+			logger.Warn("set module log levels error", logger.Err(err))
+		}
+		handler.SetFeatureFlags(newConfig.FeatureFlags)
+	})
+	if err != nil {
+		panic(fmt.Sprintf("listen to configuration center err, %v", err))
+	}
 }
 
 // get configuration from local configuration file
@@ -91,6 +91,7 @@ var (
 	typesFile         = "types/userExample_types.go"
 	typesMgoFile      = "types/userExample_types.go.mgo"
 	handlerFileMark   = "// todo generate the request and response struct to here"
+	handlerFile       = "handler/userExample.go"
 	handlerTestFile   = "handler/userExample_test.go"
 	handlerPbFile     = "handler/userExample_logic.go"
 	handlerPbTestFile = "handler/userExample_logic_test.go"
@@ -99,6 +100,8 @@ var (
 	serviceLogicFile = "service/userExample.go"
 	embedTimeMark    = "// todo generate the conversion createdAt and updatedAt code here"
 
+	routerFile = "routers/userExample.go"
+
 	httpFile = "server/http.go"
 
 	protoFile     = "v1/userExample.proto"
@@ -790,14 +793,85 @@ func changeOutPath(outPath string, serverName string) string {
 	return outPath + gofile.GetPathDelimiter() + serverName
 }
 
+// the template files that are generated together with the selected file, the files named after the model are
+// generated with the file of the model, the files shared by the models are generated with the file of the service
+var dependentFiles = map[string][]string{
+	"internal/database/init.go": {
+		"internal/cache/tenant.go",
+		"internal/dao/request_cache.go",
+	},
+	"internal/database/init.go.mgo": {
+		"internal/cache/tenant.go",
+		"internal/dao/request_cache.go",
+	},
+	"internal/dao/userExample.go": {
+		"internal/dao/budget.go",
+		"internal/dao/userExample_norepo.go",
+		"internal/dao/userExample_repo.go",
+	},
+	"internal/handler/userExample.go": {
+		"internal/dao/userExampleActivity.go",
+		"internal/handler/budget.go",
+		"internal/handler/userExample_activity.go",
+		"internal/handler/userExample_aggregate.go",
+		"internal/handler/userExample_distinct.go",
+		"internal/handler/userExample_lastmodified.go",
+		"internal/handler/userExample_quota.go",
+		"internal/model/userExampleActivity.go",
+		"internal/types/userExampleActivity_types.go",
+		"internal/types/userExampleAggregate_types.go",
+	},
+	"internal/handler/userExample.go.mgo": {
+		"internal/dao/userExampleActivity.go.mgo",
+		"internal/handler/userExample_activity.go.mgo",
+		"internal/handler/userExample_distinct.go",
+		"internal/model/userExampleActivity.go.mgo",
+		"internal/types/userExampleActivity_types.go.mgo",
+	},
+	"internal/handler/userExample.go.tpl": {
+		"internal/dao/userExampleActivity.go.tpl",
+		"internal/handler/userExample_activity.go.tpl",
+		"internal/model/userExampleActivity.go.tpl",
+		"internal/types/userExampleActivity_types.go",
+	},
+	"internal/routers/routers.go": {
+		"internal/handler/featureflag.go",
+		"internal/handler/request_cache.go",
+		"internal/handler/tenant.go",
+		"internal/routers/methods.go",
+		"internal/routers/openapi.go",
+		"internal/routers/policy.go",
+	},
+	"internal/routers/routers_pbExample.go": {
+		"internal/routers/methods.go",
+		"internal/routers/policy.go",
+	},
+}
+
+// GetDependentFiles get the template files generated together with the file, e.g. internal/database/init.go
+func GetDependentFiles(file string) []string {
+	return dependentFiles[file]
+}
+
 func getSubFiles(selectFiles map[string][]string, replaceFiles map[string][]string) []string {
 	files := []string{}
+	exists := map[string]bool{}
+	add := func(file string) {
+		if !exists[file] {
+			exists[file] = true
+			files = append(files, file)
+		}
+	}
 	for dir, filenames := range selectFiles {
 		if v, ok := replaceFiles[dir]; ok {
 			filenames = v
 		}
 		for _, filename := range filenames {
-			files = append(files, dir+"/"+filename)
+			file := dir + "/" + filename
+			add(file)
+			for _, dependentFile := range dependentFiles[file] {
+				add(dependentFile)
+			}
 		}
 	}
 	return files
@@ -952,8 +1026,14 @@ func getTemplateFiles(files map[string][]string) []string {
 	var templateFiles []string
 	for dir, filenames := range files {
 		for _, filename := range filenames {
+			file := dir + "/" + filename
 			if strings.HasSuffix(filename, tplSuffix) {
-				templateFiles = append(templateFiles, dir+"/"+filename)
+				templateFiles = append(templateFiles, file)
+			}
+			for _, dependentFile := range dependentFiles[file] {
+				if strings.HasSuffix(dependentFile, tplSuffix) {
+					templateFiles = append(templateFiles, dependentFile)
+				}
 			}
 		}
 	}
@@ -349,13 +349,27 @@ func (g *httpAndGRPCPbGenerator) addFields(r replacer.Replacer) []replacer.Field
 			New: "",
 		},
 		{
-			Old: "prof.Register(r, prof.WithIOWaitTime())",
+			Old: `prof.Register(r, prof.WithIOWaitTime(), prof.WithProfiles()) // the captures of the auto profiler are listed by /debug/profiles
+		// change the log level at runtime, e.g. PUT {"level":"debug","duration":"10m"}
+		r.GET("/debug/loglevel", gin.WrapF(logger.LevelHandler))
+		r.PUT("/debug/loglevel", gin.WrapF(logger.LevelHandler))
+		// the last samples of the process metrics, e.g. GET /debug/stat?n=10
+		r.GET("/debug/stat", gin.WrapF(stat.Handler))`,
 			New: "// implemented on port 8283",
 		},
 		{
 			Old: `"github.com/go-dev-frame/sponge/pkg/gin/prof"`,
 			New: "",
 		},
+		{
+			Old: `"github.com/go-dev-frame/sponge/pkg/logger"
+	"github.com/go-dev-frame/sponge/pkg/stat"
+
+	"github.com/go-dev-frame/sponge/docs"`,
+			New: `"github.com/go-dev-frame/sponge/pkg/logger"
+
+	"github.com/go-dev-frame/sponge/docs"`,
+		},
 		{
 			Old: "reference-db-config-url",
 			New: "Reference: https://github.com/go-dev-frame/sponge/blob/main/configs/serverNameExample.yml#L87",
@@ -252,6 +252,9 @@ func (g *handlerGenerator) generateCode() (string, error) {
 				"internal/handler": {
 					"userExample.go.mgo",
 				},
+				"internal/routers": {
+					"userExample.go.mgo",
+				},
 				"internal/types": {
 					"userExample_types.go.mgo",
 				},
@@ -279,12 +282,14 @@ func (g *handlerGenerator) addFields(r replacer.Replacer) []replacer.Field {
 	var fields []replacer.Field
 	fields = append(fields, g.fields...)
 	fields = append(fields, deleteFieldsMark(r, modelFile, startMark, endMark)...)
-	fields = append(fields, deleteFieldsMark(r, daoFile, startMark, endMark)...)
+	fields = append(fields, deleteAllFieldsMark(r, daoFile, startMark, endMark)...)
 	fields = append(fields, deleteFieldsMark(r, daoMgoFile, startMark, endMark)...)
-	fields = append(fields, deleteFieldsMark(r, daoTestFile, startMark, endMark)...)
+	fields = append(fields, deleteAllFieldsMark(r, daoTestFile, startMark, endMark)...)
 	fields = append(fields, deleteFieldsMark(r, typesFile, startMark, endMark)...)
 	fields = append(fields, deleteFieldsMark(r, typesMgoFile, startMark, endMark)...)
-	fields = append(fields, deleteFieldsMark(r, handlerTestFile, startMark, endMark)...)
+	fields = append(fields, deleteAllFieldsMark(r, handlerFile, startMark, endMark)...)
+	fields = append(fields, deleteAllFieldsMark(r, handlerTestFile, startMark, endMark)...)
+	fields = append(fields, deleteAllFieldsMark(r, routerFile, startMark, endMark)...)
 	fields = append(fields, []replacer.Field{
 		{ // replace the contents of the model/userExample.go file
 			Old: modelFileMark,
@@ -318,6 +323,18 @@ func (g *handlerGenerator) addFields(r replacer.Replacer) []replacer.Field {
 			Old: "userExample_types.go.mgo",
 			New: "userExample_types.go",
 		},
+		{
+			Old: "userExampleActivity_types.go.mgo",
+			New: "userExampleActivity_types.go",
+		},
+		{
+			Old: "userExample_activity.go.mgo",
+			New: "userExample_activity.go",
+		},
+		{
+			Old: "userExampleActivity.go.mgo",
+			New: "userExampleActivity.go",
+		},
 		{
 			Old: showDbNameMark,
 			New: CurrentDbDriver(g.dbDriver),
@@ -464,6 +481,14 @@ func commonHandlerFields(r replacer.Replacer) []replacer.Field {
 			Old: "userExample_types.go.tpl",
 			New: "userExample_types.go",
 		},
+		{
+			Old: "userExample_activity.go.tpl",
+			New: "userExample_activity.go",
+		},
+		{
+			Old: "userExampleActivity.go.tpl",
+			New: "userExampleActivity.go",
+		},
 		{
 			Old: "userExample.go.tpl",
 			New: "userExample.go",
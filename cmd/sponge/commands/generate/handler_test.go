@@ -0,0 +1,61 @@
+package generate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-dev-frame/sponge/pkg/sql2code"
+)
+
+// the status state machine of userExample is example code, the tables without a status column must not get it
+func TestHandlerGenerator_withoutStatusColumn(t *testing.T) {
+	spongeDir := SpongeDir
+	defer func() { SpongeDir = spongeDir }()
+	SpongeDir = "../../../.."
+
+	codes, err := sql2code.Generate(&sql2code.Args{
+		SQL: "CREATE TABLE `book` (`id` bigint unsigned NOT NULL AUTO_INCREMENT, `created_at` datetime DEFAULT NULL, " +
+			"`updated_at` datetime DEFAULT NULL, `deleted_at` datetime DEFAULT NULL, `title` varchar(50) NOT NULL, PRIMARY KEY (`id`))",
+		DBDriver: DBDriverMysql,
+		Package:  "model",
+		JSONTag:  true,
+		GormType: true,
+		IsEmbed:  true,
+	})
+	require.NoError(t, err)
+
+	g := &handlerGenerator{
+		moduleName: "github.com/foo/bar",
+		dbDriver:   DBDriverMysql,
+		codes:      codes,
+		outPath:    t.TempDir(),
+		isEmbed:    true,
+	}
+	outDir, err := g.generateCode()
+	require.NoError(t, err)
+
+	for _, file := range []string{
+		"internal/dao/book.go",
+		"internal/dao/book_test.go",
+		"internal/handler/book.go",
+		"internal/handler/book_test.go",
+		"internal/routers/book.go",
+	} {
+		data, err := os.ReadFile(filepath.Join(outDir, file))
+		require.NoError(t, err, file)
+		content := string(data)
+		assert.NotContains(t, content, "UpdateStatusByID", file)
+		assert.NotContains(t, content, "Status:", file)
+		assert.NotContains(t, content, "Archive", file)
+		assert.NotContains(t, content, "statemachine", file)
+		assert.NotContains(t, content, startMarkStr, file)
+	}
+	for _, file := range []string{"internal/dao/book_status.go", "internal/handler/book_status.go"} {
+		_, err = os.Stat(filepath.Join(outDir, file))
+		assert.True(t, os.IsNotExist(err), file)
+	}
+}
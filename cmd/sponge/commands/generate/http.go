@@ -300,6 +300,9 @@ func (g *httpGenerator) generateCode() (string, error) {
 				"internal/handler": {
 					"userExample.go.mgo",
 				},
+				"internal/routers": {
+					"routers.go", "userExample.go.mgo",
+				},
 				"internal/types": {
 					"swagger_types.go", "userExample_types.go.mgo",
 				},
@@ -338,12 +341,14 @@ func (g *httpGenerator) addFields(r replacer.Replacer) []replacer.Field {
 	fields = append(fields, g.fields...)
 	fields = append(fields, deleteFieldsMark(r, modelFile, startMark, endMark)...)
 	fields = append(fields, deleteFieldsMark(r, databaseInitDBFile, startMark, endMark)...)
-	fields = append(fields, deleteFieldsMark(r, daoFile, startMark, endMark)...)
+	fields = append(fields, deleteAllFieldsMark(r, daoFile, startMark, endMark)...)
 	fields = append(fields, deleteFieldsMark(r, daoMgoFile, startMark, endMark)...)
-	fields = append(fields, deleteFieldsMark(r, daoTestFile, startMark, endMark)...)
+	fields = append(fields, deleteAllFieldsMark(r, daoTestFile, startMark, endMark)...)
 	fields = append(fields, deleteFieldsMark(r, typesFile, startMark, endMark)...)
 	fields = append(fields, deleteFieldsMark(r, typesMgoFile, startMark, endMark)...)
-	fields = append(fields, deleteFieldsMark(r, handlerTestFile, startMark, endMark)...)
+	fields = append(fields, deleteAllFieldsMark(r, handlerFile, startMark, endMark)...)
+	fields = append(fields, deleteAllFieldsMark(r, handlerTestFile, startMark, endMark)...)
+	fields = append(fields, deleteAllFieldsMark(r, routerFile, startMark, endMark)...)
 	fields = append(fields, deleteFieldsMark(r, httpFile, startMark, endMark)...)
 	fields = append(fields, deleteFieldsMark(r, httpFile+".noregistry", startMark, endMark)...)
 	fields = append(fields, deleteFieldsMark(r, dockerFile, wellStartMark, wellEndMark)...)
@@ -527,6 +532,18 @@ func (g *httpGenerator) addFields(r replacer.Replacer) []replacer.Field {
 			Old: "userExample_types.go.mgo",
 			New: "userExample_types.go",
 		},
+		{
+			Old: "userExampleActivity_types.go.mgo",
+			New: "userExampleActivity_types.go",
+		},
+		{
+			Old: "userExample_activity.go.mgo",
+			New: "userExample_activity.go",
+		},
+		{
+			Old: "userExampleActivity.go.mgo",
+			New: "userExampleActivity.go",
+		},
 		{
 			Old: "userExample.go.mgo",
 			New: "userExample.go",
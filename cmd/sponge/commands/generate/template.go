@@ -370,7 +370,8 @@ func NewCenter(configFile string) (*Center, error) {
 	httpServerConfigCode = `# http server settings
 http:
   port: 8080                # listen port
-  timeout: 0                # request timeout, unit(second), if 0 means not set, if greater than 0 means set timeout, if enableHTTPProfile is true, it needs to set 0 or greater than 60s`
+  timeout: 0                # request timeout, unit(second), if 0 means not set, if greater than 0 means set timeout, if enableHTTPProfile is true, it needs to set 0 or greater than 60s
+  responseMode: "envelope"  # response mode of errors: envelope (status code flat 200, error code in body), statusCode, problem (RFC 7807 application/problem+json)`
 
 	rpcServerConfigCode = `# grpc server settings
 grpc:
@@ -414,7 +415,7 @@ grpcClient:
 http:
   port: 8080                # listen port
   timeout: 0                 # request timeout, unit(second), if 0 means not set, if greater than 0 means set timeout, if enableHTTPProfile is true, it needs to set 0 or greater than 60s
-
+  responseMode: "envelope"  # response mode of errors: envelope (status code flat 200, error code in body), statusCode, problem (RFC 7807 application/problem+json)
 
 # grpc client-side settings, support for setting up multiple grpc clients.
 grpcClient:
@@ -442,7 +443,7 @@ grpcClient:
 http:
   port: 8080                # listen port
   timeout: 0                # request timeout, unit(second), if 0 means not set, if greater than 0 means set timeout, if enableHTTPProfile is true, it needs to set 0 or greater than 60s
-
+  responseMode: "envelope"  # response mode of errors: envelope (status code flat 200, error code in body), statusCode, problem (RFC 7807 application/problem+json)
 
 # grpc server settings
 grpc:
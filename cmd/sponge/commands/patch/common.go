@@ -12,6 +12,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/go-dev-frame/sponge/cmd/sponge/commands/generate"
 	"github.com/go-dev-frame/sponge/pkg/gofile"
 )
 
@@ -88,6 +89,7 @@ func getSubFiles(selectedFiles map[string][]string) []string {
 	for dir, files := range selectedFiles {
 		for _, file := range files {
 			subFiles = append(subFiles, dir+"/"+file)
+			subFiles = append(subFiles, generate.GetDependentFiles(dir+"/"+file)...)
 		}
 	}
 	return subFiles
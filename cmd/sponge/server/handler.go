@@ -378,7 +378,11 @@ func UploadFiles(c *gin.Context) {
 	var filePath string
 	for _, files := range form.File {
 		for _, file := range files {
-			filename := filepath.Base(file.Filename)
+			filename := gofile.SanitizeFilename(file.Filename)
+			if filename == "" {
+				response.Error(c, errcode.InvalidParams.RewriteMsg("invalid file name "+file.Filename))
+				return
+			}
 			fileType = path.Ext(filename)
 			//if !checkFileType(fileType) {
 			//	response.Error(c, errcode.InvalidParams.RewriteMsg("only .proto or yaml files are allowed to be uploaded"))
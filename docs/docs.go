@@ -447,11 +447,32 @@ const docTemplate = `{
                     "description": "return data",
                     "type": "object",
                     "properties": {
-                        "userExamples": {
+                        "hasNext": {
+                            "description": "whether there is a next page",
+                            "type": "boolean"
+                        },
+                        "items": {
+                            "description": "records",
                             "type": "array",
                             "items": {
                                 "$ref": "#/definitions/types.UserExampleObjDetail"
                             }
+                        },
+                        "limit": {
+                            "description": "lines per page, offset pagination",
+                            "type": "integer"
+                        },
+                        "nextCursor": {
+                            "description": "cursor of the next page, cursor pagination",
+                            "type": "string"
+                        },
+                        "page": {
+                            "description": "page number, starts from 0, offset pagination",
+                            "type": "integer"
+                        },
+                        "total": {
+                            "description": "total number of records, offset pagination",
+                            "type": "integer"
                         }
                     }
                 },
@@ -49,7 +49,7 @@ func NewCacheNameExampleCache(cacheType *database.CacheType) CacheNameExampleCac
 	cType := strings.ToLower(cacheType.CType)
 	switch cType {
 	case "redis":
-		c := cache.NewRedisCache(cacheType.Rdb, cachePrefix, jsonEncoding, newObject)
+		c := cache.NewRedisUniversalCache(cacheType.Rdb, cachePrefix, jsonEncoding, newObject)
 		return &cacheNameExampleCache{cache: c}
 	case "memory":
 		c := cache.NewMemoryCache(cachePrefix, jsonEncoding, newObject)
@@ -0,0 +1,25 @@
+package cache
+
+import "context"
+
+type tenantCtxKey struct{}
+
+// WithTenant set the tenant id of the writes and reads in ctx, the cache keeps a last modified time
+// per tenant in addition to the resource-global one, an empty tenantID is ignored
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	if tenantID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, tenantCtxKey{}, tenantID)
+}
+
+// TenantFromContext get the tenant id set by WithTenant, empty if not set
+func TenantFromContext(ctx context.Context) string {
+	tenantID, _ := ctx.Value(tenantCtxKey{}).(string)
+	return tenantID
+}
+
+// the resource of the tenant, e.g. userExample:tenant:t1
+func tenantResource(resource string, tenantID string) string {
+	return resource + ":tenant:" + tenantID
+}
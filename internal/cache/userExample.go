@@ -17,8 +17,13 @@ import (
 const (
 	// cache prefix key, must end with a colon
 	userExampleCachePrefixKey = "userExample:"
+	// the resource name of the list generation
+	userExampleCacheResource = "userExample"
 	// UserExampleExpireTime expire time
 	UserExampleExpireTime = 5 * time.Minute
+	// UserExampleDistinctExpireTime expire time of the distinct values of a column, it is short, because the
+	// distinct values are used by the filter dropdowns, which tolerate the slightly stale values
+	UserExampleDistinctExpireTime = 30 * time.Second
 )
 
 var _ UserExampleCache = (*userExampleCache)(nil)
@@ -32,30 +37,46 @@ type UserExampleCache interface {
 	Del(ctx context.Context, id uint64) error
 	SetPlaceholder(ctx context.Context, id uint64) error
 	IsPlaceholderErr(err error) bool
+	GetOrLoad(ctx context.Context, id uint64, loader func(ctx context.Context) (*model.UserExample, error)) (*model.UserExample, error)
+	Invalidate(ctx context.Context, ids ...uint64) error
+	ListGeneration(ctx context.Context) (int64, error)
+	ListLastModified(ctx context.Context) (time.Time, error)
+	GetDistinct(ctx context.Context, params string) (*UserExampleDistinct, error)
+	SetDistinct(ctx context.Context, params string, data *UserExampleDistinct) error
+}
+
+// UserExampleDistinct the distinct values of a column
+type UserExampleDistinct struct {
+	Values []interface{} `json:"values"`
 }
 
 // userExampleCache define a cache struct
 type userExampleCache struct {
-	cache cache.Cache
+	cache       cache.Cache
+	invalidator cache.Invalidator
 }
 
-// NewUserExampleCache new a cache
-func NewUserExampleCache(cacheType *database.CacheType) UserExampleCache {
-	jsonEncoding := encoding.JSONEncoding{}
+// NewUserExampleCache new a cache, the codec of cached values is json by default,
+// e.g. encoding.NewCacheCodec(encoding.CodecMsgPack, encoding.WithCompression(encoding.CompressionSnappy))
+func NewUserExampleCache(cacheType *database.CacheType, codec ...encoding.Encoding) UserExampleCache {
+	var valueEncoding encoding.Encoding = encoding.JSONEncoding{}
+	if len(codec) > 0 && codec[0] != nil {
+		valueEncoding = codec[0]
+	}
 	cachePrefix := ""
 
 	cType := strings.ToLower(cacheType.CType)
 	switch cType {
 	case "redis":
-		c := cache.NewRedisCache(cacheType.Rdb, cachePrefix, jsonEncoding, func() interface{} {
+		c := cache.NewRedisUniversalCache(cacheType.Rdb, cachePrefix, valueEncoding, func() interface{} {
 			return &model.UserExample{}
 		})
-		return &userExampleCache{cache: c}
+		return &userExampleCache{cache: c, invalidator: cache.NewRedisInvalidator(cacheType.Rdb, cachePrefix)}
 	case "memory":
-		c := cache.NewMemoryCache(cachePrefix, jsonEncoding, func() interface{} {
+		c := cache.NewMemoryCache(cachePrefix, valueEncoding, func() interface{} {
 			return &model.UserExample{}
 		})
-		return &userExampleCache{cache: c}
+		return &userExampleCache{cache: c, invalidator: cache.NewMemoryInvalidator(c)}
 	}
 
 	return nil // no cache
@@ -151,3 +172,71 @@ func (c *userExampleCache) SetPlaceholder(ctx context.Context, id uint64) error
 func (c *userExampleCache) IsPlaceholderErr(err error) bool {
 	return errors.Is(err, cache.ErrPlaceholder)
 }
+
+// GetOrLoad get from cache, if not cached, load by loader and set cache, the concurrent loads of the same
+// id are merged into one, the record not found result is cached as placeholder
+func (c *userExampleCache) GetOrLoad(ctx context.Context, id uint64, loader func(ctx context.Context) (*model.UserExample, error)) (*model.UserExample, error) {
+	cacheKey := c.GetUserExampleCacheKey(id)
+	return cache.GetOrLoad(ctx, cacheKey, UserExampleExpireTime, loader,
+		cache.WithLoadCache(c.cache),
+		cache.WithLoadName("userExample"),
+		cache.WithNotFoundError(database.ErrRecordNotFound),
+	)
+}
+
+// Invalidate delete the cache of the ids and bump the list generation atomically, it is called after
+// every write, the cached lists are keyed by the list generation, so no stale list is served.
+// The last modified time of the tenant in ctx (see WithTenant) is updated too.
+func (c *userExampleCache) Invalidate(ctx context.Context, ids ...uint64) error {
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, c.GetUserExampleCacheKey(id))
+	}
+	err := c.invalidator.Invalidate(ctx, userExampleCacheResource, keys...)
+	if err != nil {
+		return err
+	}
+	if tenantID := TenantFromContext(ctx); tenantID != "" {
+		return c.invalidator.Invalidate(ctx, tenantResource(userExampleCacheResource, tenantID))
+	}
+	return nil
+}
+
+// ListGeneration get the list generation, use it in the list cache keys, e.g. cache.ListCacheKey
+func (c *userExampleCache) ListGeneration(ctx context.Context) (int64, error) {
+	return c.invalidator.Generation(ctx, userExampleCacheResource)
+}
+
+// ListLastModified get the time of the last write, of the tenant in ctx if set by WithTenant,
+// zero time if unknown
+func (c *userExampleCache) ListLastModified(ctx context.Context) (time.Time, error) {
+	if tenantID := TenantFromContext(ctx); tenantID != "" {
+		return c.invalidator.LastModified(ctx, tenantResource(userExampleCacheResource, tenantID))
+	}
+	return c.invalidator.LastModified(ctx, userExampleCacheResource)
+}
+
+// GetDistinct get the cached distinct values, params identifies the column and the conditions, the key
+// includes the list generation, so the values are not served after a write
+func (c *userExampleCache) GetDistinct(ctx context.Context, params string) (*UserExampleDistinct, error) {
+	generation, err := c.ListGeneration(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var data *UserExampleDistinct
+	err = c.cache.Get(ctx, cache.ListCacheKey(userExampleCacheResource, generation, "distinct:"+params), &data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// SetDistinct write the distinct values to cache, it expires after UserExampleDistinctExpireTime
+func (c *userExampleCache) SetDistinct(ctx context.Context, params string, data *UserExampleDistinct) error {
+	generation, err := c.ListGeneration(ctx)
+	if err != nil {
+		return err
+	}
+	return c.cache.Set(ctx, cache.ListCacheKey(userExampleCacheResource, generation, "distinct:"+params), data,
+		UserExampleDistinctExpireTime)
+}
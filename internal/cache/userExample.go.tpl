@@ -17,6 +17,8 @@ import (
 const (
 	// cache prefix key, must end with a colon
 	{{.TableNameCamelFCL}}CachePrefixKey = "{{.TableNameCamelFCL}}:"
+	// the resource name of the list generation
+	{{.TableNameCamelFCL}}CacheResource = "{{.TableNameCamelFCL}}"
 	// {{.TableNameCamel}}ExpireTime expire time
 	{{.TableNameCamel}}ExpireTime = 5 * time.Minute
 )
@@ -32,30 +34,38 @@ type {{.TableNameCamel}}Cache interface {
 	Del(ctx context.Context, {{.ColumnNameCamelFCL}} {{.GoType}}) error
 	SetPlaceholder(ctx context.Context, {{.ColumnNameCamelFCL}} {{.GoType}}) error
 	IsPlaceholderErr(err error) bool
+	GetOrLoad(ctx context.Context, {{.ColumnNameCamelFCL}} {{.GoType}}, loader func(ctx context.Context) (*model.{{.TableNameCamel}}, error)) (*model.{{.TableNameCamel}}, error)
+	Invalidate(ctx context.Context, {{.ColumnNamePluralCamelFCL}} ...{{.GoType}}) error
+	ListGeneration(ctx context.Context) (int64, error)
 }
 
 // {{.TableNameCamelFCL}}Cache define a cache struct
 type {{.TableNameCamelFCL}}Cache struct {
-	cache cache.Cache
+	cache       cache.Cache
+	invalidator cache.Invalidator
 }
 
-// New{{.TableNameCamel}}Cache new a cache
-func New{{.TableNameCamel}}Cache(cacheType *database.CacheType) {{.TableNameCamel}}Cache {
-	jsonEncoding := encoding.JSONEncoding{}
+// New{{.TableNameCamel}}Cache new a cache, the codec of cached values is json by default,
+// e.g. encoding.NewCacheCodec(encoding.CodecMsgPack, encoding.WithCompression(encoding.CompressionSnappy))
+func New{{.TableNameCamel}}Cache(cacheType *database.CacheType, codec ...encoding.Encoding) {{.TableNameCamel}}Cache {
+	var valueEncoding encoding.Encoding = encoding.JSONEncoding{}
+	if len(codec) > 0 && codec[0] != nil {
+		valueEncoding = codec[0]
+	}
 	cachePrefix := ""
 
 	cType := strings.ToLower(cacheType.CType)
 	switch cType {
 	case "redis":
-		c := cache.NewRedisCache(cacheType.Rdb, cachePrefix, jsonEncoding, func() interface{} {
+		c := cache.NewRedisUniversalCache(cacheType.Rdb, cachePrefix, valueEncoding, func() interface{} {
 			return &model.{{.TableNameCamel}}{}
 		})
-		return &{{.TableNameCamelFCL}}Cache{cache: c}
+		return &{{.TableNameCamelFCL}}Cache{cache: c, invalidator: cache.NewRedisInvalidator(cacheType.Rdb, cachePrefix)}
 	case "memory":
-		c := cache.NewMemoryCache(cachePrefix, jsonEncoding, func() interface{} {
+		c := cache.NewMemoryCache(cachePrefix, valueEncoding, func() interface{} {
 			return &model.{{.TableNameCamel}}{}
 		})
-		return &{{.TableNameCamelFCL}}Cache{cache: c}
+		return &{{.TableNameCamelFCL}}Cache{cache: c, invalidator: cache.NewMemoryInvalidator(c)}
 	}
 
 	return nil // no cache
@@ -151,3 +161,29 @@ func (c *{{.TableNameCamelFCL}}Cache) SetPlaceholder(ctx context.Context, {{.Col
 func (c *{{.TableNameCamelFCL}}Cache) IsPlaceholderErr(err error) bool {
 	return errors.Is(err, cache.ErrPlaceholder)
 }
+
+// GetOrLoad get from cache, if not cached, load by loader and set cache, the concurrent loads of the same
+// {{.ColumnNameCamelFCL}} are merged into one, the record not found result is cached as placeholder
+func (c *{{.TableNameCamelFCL}}Cache) GetOrLoad(ctx context.Context, {{.ColumnNameCamelFCL}} {{.GoType}}, loader func(ctx context.Context) (*model.{{.TableNameCamel}}, error)) (*model.{{.TableNameCamel}}, error) {
+	cacheKey := c.Get{{.TableNameCamel}}CacheKey({{.ColumnNameCamelFCL}})
+	return cache.GetOrLoad(ctx, cacheKey, {{.TableNameCamel}}ExpireTime, loader,
+		cache.WithLoadCache(c.cache),
+		cache.WithLoadName("{{.TableNameCamelFCL}}"),
+		cache.WithNotFoundError(database.ErrRecordNotFound),
+	)
+}
+
+// Invalidate delete the cache of the {{.ColumnNamePluralCamelFCL}} and bump the list generation atomically, it is called after
+// every write, the cached lists are keyed by the list generation, so no stale list is served
+func (c *{{.TableNameCamelFCL}}Cache) Invalidate(ctx context.Context, {{.ColumnNamePluralCamelFCL}} ...{{.GoType}}) error {
+	keys := make([]string, 0, len({{.ColumnNamePluralCamelFCL}}))
+	for _, {{.ColumnNameCamelFCL}} := range {{.ColumnNamePluralCamelFCL}} {
+		keys = append(keys, c.Get{{.TableNameCamel}}CacheKey({{.ColumnNameCamelFCL}}))
+	}
+	return c.invalidator.Invalidate(ctx, {{.TableNameCamelFCL}}CacheResource, keys...)
+}
+
+// ListGeneration get the list generation, use it in the list cache keys, e.g. cache.ListCacheKey
+func (c *{{.TableNameCamelFCL}}Cache) ListGeneration(ctx context.Context) (int64, error) {
+	return c.invalidator.Generation(ctx, {{.TableNameCamelFCL}}CacheResource)
+}
@@ -1,11 +1,13 @@
 package cache
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/go-dev-frame/sponge/pkg/encoding"
 	"github.com/go-dev-frame/sponge/pkg/gotest"
 	"github.com/go-dev-frame/sponge/pkg/utils"
 
@@ -131,6 +133,88 @@ func Test_userExampleCache_SetCacheWithNotFound(t *testing.T) {
 	t.Log(b)
 }
 
+func Test_userExampleCache_GetOrLoad(t *testing.T) {
+	c := newUserExampleCache()
+	defer c.Close()
+
+	record := c.TestDataSlice[0].(*model.UserExample)
+	loads := 0
+	loader := func(ctx context.Context) (*model.UserExample, error) {
+		loads++
+		return record, nil
+	}
+	got, err := c.ICache.(UserExampleCache).GetOrLoad(c.Ctx, record.ID, loader)
+	assert.NoError(t, err)
+	assert.Equal(t, record.ID, got.ID)
+	got, err = c.ICache.(UserExampleCache).GetOrLoad(c.Ctx, record.ID, loader)
+	assert.NoError(t, err)
+	assert.Equal(t, record.ID, got.ID)
+	assert.Equal(t, 1, loads)
+
+	// the not found result is cached
+	notFound := func(ctx context.Context) (*model.UserExample, error) {
+		loads++
+		return nil, database.ErrRecordNotFound
+	}
+	_, err = c.ICache.(UserExampleCache).GetOrLoad(c.Ctx, 100, notFound)
+	assert.ErrorIs(t, err, database.ErrRecordNotFound)
+	_, err = c.ICache.(UserExampleCache).GetOrLoad(c.Ctx, 100, notFound)
+	assert.ErrorIs(t, err, database.ErrRecordNotFound)
+	assert.Equal(t, 2, loads)
+}
+
+func Test_userExampleCache_Invalidate(t *testing.T) {
+	c := newUserExampleCache()
+	defer c.Close()
+
+	record := c.TestDataSlice[0].(*model.UserExample)
+	err := c.ICache.(UserExampleCache).Set(c.Ctx, record.ID, record, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.ICache.(UserExampleCache).Invalidate(c.Ctx, record.ID)
+	assert.NoError(t, err)
+	_, err = c.ICache.(UserExampleCache).Get(c.Ctx, record.ID)
+	assert.Error(t, err)
+	gen, err := c.ICache.(UserExampleCache).ListGeneration(c.Ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), gen)
+
+	// only bump the list generation
+	err = c.ICache.(UserExampleCache).Invalidate(c.Ctx)
+	assert.NoError(t, err)
+	gen, _ = c.ICache.(UserExampleCache).ListGeneration(c.Ctx)
+	assert.Equal(t, int64(2), gen)
+}
+
+func Test_userExampleCache_ListLastModified(t *testing.T) {
+	c := newUserExampleCache()
+	defer c.Close()
+
+	iCache := c.ICache.(UserExampleCache)
+	lm, err := iCache.ListLastModified(c.Ctx)
+	assert.NoError(t, err)
+	assert.True(t, lm.IsZero())
+
+	// a write of the tenant t1 updates the global and the tenant time
+	ctx := WithTenant(c.Ctx, "t1")
+	assert.NoError(t, iCache.Invalidate(ctx, 1))
+	lm, err = iCache.ListLastModified(c.Ctx)
+	assert.NoError(t, err)
+	assert.False(t, lm.IsZero())
+	lm, err = iCache.ListLastModified(ctx)
+	assert.NoError(t, err)
+	assert.False(t, lm.IsZero())
+
+	// the other tenants are not changed
+	lm, err = iCache.ListLastModified(WithTenant(c.Ctx, "t2"))
+	assert.NoError(t, err)
+	assert.True(t, lm.IsZero())
+
+	assert.Equal(t, "", TenantFromContext(WithTenant(context.Background(), "")))
+}
+
 func TestNewUserExampleCache(t *testing.T) {
 	c := NewUserExampleCache(&database.CacheType{
 		CType: "",
@@ -145,3 +229,48 @@ func TestNewUserExampleCache(t *testing.T) {
 	})
 	assert.NotNil(t, c)
 }
+
+func Test_userExampleCache_Codec(t *testing.T) {
+	c := newUserExampleCache()
+	defer c.Close()
+
+	codec := encoding.NewCacheCodec(encoding.CodecMsgPack, encoding.WithCompression(encoding.CompressionSnappy))
+	c.ICache = NewUserExampleCache(&database.CacheType{
+		CType: "redis",
+		Rdb:   c.RedisClient,
+	}, codec)
+
+	record := c.TestDataSlice[0].(*model.UserExample)
+	err := c.ICache.(UserExampleCache).Set(c.Ctx, record.ID, record, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := c.ICache.(UserExampleCache).Get(c.Ctx, record.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, record.ID, got.ID)
+}
+
+func Test_userExampleCache_Distinct(t *testing.T) {
+	c := newUserExampleCache()
+	defer c.Close()
+	iCache := c.ICache.(UserExampleCache)
+
+	_, err := iCache.GetDistinct(c.Ctx, "status:abc")
+	assert.Error(t, err)
+
+	data := &UserExampleDistinct{Values: []interface{}{"a", "b"}}
+	err = iCache.SetDistinct(c.Ctx, "status:abc", data)
+	assert.NoError(t, err)
+	got, err := iCache.GetDistinct(c.Ctx, "status:abc")
+	assert.NoError(t, err)
+	assert.Equal(t, data, got)
+
+	// the cached values are not served after a write
+	err = iCache.Invalidate(c.Ctx, 1)
+	assert.NoError(t, err)
+	_, err = iCache.GetDistinct(c.Ctx, "status:abc")
+	assert.Error(t, err)
+}
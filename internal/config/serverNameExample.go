@@ -29,17 +29,25 @@ func Set(conf *Config) {
 }
 
 type Config struct {
-	App        App          `yaml:"app" json:"app"`
-	Consul     Consul       `yaml:"consul" json:"consul"`
-	Database   Database     `yaml:"database" json:"database"`
-	Etcd       Etcd         `yaml:"etcd" json:"etcd"`
-	Grpc       Grpc         `yaml:"grpc" json:"grpc"`
-	GrpcClient []GrpcClient `yaml:"grpcClient" json:"grpcClient"`
-	HTTP       HTTP         `yaml:"http" json:"http"`
-	Jaeger     Jaeger       `yaml:"jaeger" json:"jaeger"`
-	Logger     Logger       `yaml:"logger" json:"logger"`
-	NacosRd    NacosRd      `yaml:"nacosRd" json:"nacosRd"`
-	Redis      Redis        `yaml:"redis" json:"redis"`
+	App          App           `yaml:"app" json:"app"`
+	ConfirmToken ConfirmToken  `yaml:"confirmToken" json:"confirmToken"`
+	Consul       Consul        `yaml:"consul" json:"consul"`
+	Database     Database      `yaml:"database" json:"database"`
+	Etcd         Etcd          `yaml:"etcd" json:"etcd"`
+	FeatureFlags []FeatureFlag `yaml:"featureFlags" json:"featureFlags"`
+	Grpc         Grpc          `yaml:"grpc" json:"grpc"`
+	GrpcClient   []GrpcClient  `yaml:"grpcClient" json:"grpcClient"`
+	HTTP         HTTP          `yaml:"http" json:"http"`
+	Jaeger       Jaeger        `yaml:"jaeger" json:"jaeger"`
+	Logger       Logger        `yaml:"logger" json:"logger"`
+	NacosRd      NacosRd       `yaml:"nacosRd" json:"nacosRd"`
+	Quota        Quota         `yaml:"quota" json:"quota"`
+	Redis        Redis         `yaml:"redis" json:"redis"`
+}
+
+type ConfirmToken struct {
+	Secret string `yaml:"secret" json:"secret"`
+	TTL    int    `yaml:"ttl" json:"ttl"`
 }
 
 type Consul struct {
@@ -50,6 +58,19 @@ type Etcd struct {
 	Addrs []string `yaml:"addrs" json:"addrs"`
 }
 
+type FeatureFlag struct {
+	Enabled    bool   `yaml:"enabled" json:"enabled"`
+	Name       string `yaml:"name" json:"name"`
+	Percentage int    `yaml:"percentage" json:"percentage"`
+}
+
+type Quota struct {
+	Enable       bool           `yaml:"enable" json:"enable"`
+	Limit        int            `yaml:"limit" json:"limit"`
+	TenantColumn string         `yaml:"tenantColumn" json:"tenantColumn"`
+	TenantLimits map[string]int `yaml:"tenantLimits" json:"tenantLimits"`
+}
+
 type Jaeger struct {
 	AgentHost string `yaml:"agentHost" json:"agentHost"`
 	AgentPort int    `yaml:"agentPort" json:"agentPort"`
@@ -78,6 +99,7 @@ type ServerSecure struct {
 
 type App struct {
 	CacheType             string  `yaml:"cacheType" json:"cacheType"`
+	DrainPeriod           int     `yaml:"drainPeriod" json:"drainPeriod"`
 	EnableCircuitBreaker  bool    `yaml:"enableCircuitBreaker" json:"enableCircuitBreaker"`
 	EnableHTTPProfile     bool    `yaml:"enableHTTPProfile" json:"enableHTTPProfile"`
 	EnableLimit           bool    `yaml:"enableLimit" json:"enableLimit"`
@@ -129,10 +151,21 @@ type Postgresql struct {
 }
 
 type Redis struct {
-	DialTimeout  int    `yaml:"dialTimeout" json:"dialTimeout"`
-	Dsn          string `yaml:"dsn" json:"dsn"`
-	ReadTimeout  int    `yaml:"readTimeout" json:"readTimeout"`
-	WriteTimeout int    `yaml:"writeTimeout" json:"writeTimeout"`
+	Addrs           []string `yaml:"addrs" json:"addrs"`
+	ClientName      string   `yaml:"clientName" json:"clientName"`
+	DB              int      `yaml:"db" json:"db"`
+	DialTimeout     int      `yaml:"dialTimeout" json:"dialTimeout"`
+	Dsn             string   `yaml:"dsn" json:"dsn"`
+	EnableTLS       bool     `yaml:"enableTLS" json:"enableTLS"`
+	MasterName      string   `yaml:"masterName" json:"masterName"`
+	MinIdleConns    int      `yaml:"minIdleConns" json:"minIdleConns"`
+	Mode            string   `yaml:"mode" json:"mode"`
+	Password        string   `yaml:"password" json:"password"`
+	PoolSize        int      `yaml:"poolSize" json:"poolSize"`
+	ReadFromReplica bool     `yaml:"readFromReplica" json:"readFromReplica"`
+	ReadTimeout     int      `yaml:"readTimeout" json:"readTimeout"`
+	Username        string   `yaml:"username" json:"username"`
+	WriteTimeout    int      `yaml:"writeTimeout" json:"writeTimeout"`
 }
 
 type Database struct {
@@ -155,9 +188,10 @@ type Grpc struct {
 }
 
 type Logger struct {
-	Format string `yaml:"format" json:"format"`
-	IsSave bool   `yaml:"isSave" json:"isSave"`
-	Level  string `yaml:"level" json:"level"`
+	Format       string            `yaml:"format" json:"format"`
+	IsSave       bool              `yaml:"isSave" json:"isSave"`
+	Level        string            `yaml:"level" json:"level"`
+	ModuleLevels map[string]string `yaml:"moduleLevels" json:"moduleLevels"`
 }
 
 type NacosRd struct {
@@ -166,7 +200,15 @@ type NacosRd struct {
 	Port        int    `yaml:"port" json:"port"`
 }
 
+type Admin struct {
+	AllowIPs []string `yaml:"allowIPs" json:"allowIPs"`
+	APIKey   string   `yaml:"apiKey" json:"apiKey"`
+	Port     int      `yaml:"port" json:"port"`
+}
+
 type HTTP struct {
-	Port    int `yaml:"port" json:"port"`
-	Timeout int `yaml:"timeout" json:"timeout"`
+	Admin        Admin  `yaml:"admin" json:"admin"`
+	Port         int    `yaml:"port" json:"port"`
+	ResponseMode string `yaml:"responseMode" json:"responseMode"`
+	Timeout      int    `yaml:"timeout" json:"timeout"`
 }
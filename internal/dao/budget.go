@@ -0,0 +1,21 @@
+package dao
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/go-dev-frame/sponge/pkg/utils"
+)
+
+// budgetSession returns the session of db whose context has the timeout of the fraction of the remaining deadline
+// budget of the request, e.g. set by middleware.RequestBudget, so the query is not left running after the client
+// is gone. utils.ErrBudgetExhausted is returned if the budget is exhausted, call cancel after the queries of the
+// session.
+func budgetSession(ctx context.Context, db *gorm.DB) (*gorm.DB, context.CancelFunc, error) {
+	callCtx, cancel, err := utils.CallContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return db.WithContext(callCtx), cancel, nil
+}
@@ -0,0 +1,54 @@
+package dao
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-dev-frame/sponge/pkg/sgorm/query"
+	"github.com/go-dev-frame/sponge/pkg/sgorm/sqlite"
+	"github.com/go-dev-frame/sponge/pkg/utils"
+
+	"github.com/go-dev-frame/sponge/internal/model"
+)
+
+func Test_budgetSession(t *testing.T) {
+	db, err := sqlite.Init(filepath.Join(t.TempDir(), "budget.db"))
+	require.NoError(t, err)
+	defer sqlite.Close(db)
+
+	// no deadline
+	session, cancel, err := budgetSession(context.Background(), db)
+	require.NoError(t, err)
+	cancel()
+	_, ok := session.Statement.Context.Deadline()
+	assert.False(t, ok)
+
+	// the deadline of the query is earlier than the deadline of the request
+	ctx, ctxCancel := context.WithTimeout(context.Background(), time.Second)
+	defer ctxCancel()
+	requestDeadline, _ := ctx.Deadline()
+	session, cancel, err = budgetSession(ctx, db)
+	require.NoError(t, err)
+	defer cancel()
+	deadline, ok := session.Statement.Context.Deadline()
+	assert.True(t, ok)
+	assert.True(t, deadline.Before(requestDeadline))
+
+	// the budget is exhausted, the query is not issued
+	require.NoError(t, db.AutoMigrate(&model.UserExample{}))
+	d := &userExampleDao{db: db}
+	expired, expiredCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer expiredCancel()
+	_, err = d.GetByID(expired, 1)
+	assert.ErrorIs(t, err, utils.ErrBudgetExhausted)
+	_, _, err = d.GetByColumns(expired, &query.Params{Limit: 10})
+	assert.ErrorIs(t, err, utils.ErrBudgetExhausted)
+
+	_, _, err = d.GetByColumns(ctx, &query.Params{Limit: 10})
+	assert.NoError(t, err)
+}
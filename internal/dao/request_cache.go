@@ -0,0 +1,81 @@
+package dao
+
+import (
+	"context"
+	"strconv"
+	"sync"
+)
+
+// the max number of the records memoized in one request, the records beyond it are not memoized
+const maxRequestCacheEntries = 256
+
+type requestCacheCtxKey struct{}
+
+// requestCache memoize the records got by id within one request, so the same record fetched by different code
+// paths of a handler is queried once, it is safe for the goroutines of the request.
+type requestCache struct {
+	mu      sync.Mutex
+	records map[string]interface{} // resource:id --> record
+	closed  bool
+}
+
+// WithRequestCache enable the request-scoped memoization of GetByID for the dao calls with the returned context,
+// e.g. by the middleware handler.RequestCache of the routes. The memoized records are never shared by the other
+// requests, they are forgotten after the records are written with the same context, and are cleared by
+// ClearRequestCache at the end of the request.
+func WithRequestCache(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(requestCacheCtxKey{}).(*requestCache); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, requestCacheCtxKey{}, &requestCache{records: map[string]interface{}{}})
+}
+
+// ClearRequestCache clear the memoized records of the context and disable memoizing, e.g. at the end of the request,
+// so that the goroutines still running with the context get the records from the cache or database.
+func ClearRequestCache(ctx context.Context) {
+	if rc, ok := ctx.Value(requestCacheCtxKey{}).(*requestCache); ok {
+		rc.mu.Lock()
+		rc.records = nil
+		rc.closed = true
+		rc.mu.Unlock()
+	}
+}
+
+func requestCacheKey(resource string, id uint64) string {
+	return resource + ":" + strconv.FormatUint(id, 10)
+}
+
+func getRequestCache(ctx context.Context, key string) (interface{}, bool) {
+	rc, ok := ctx.Value(requestCacheCtxKey{}).(*requestCache)
+	if !ok {
+		return nil, false
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	record, ok := rc.records[key]
+	return record, ok
+}
+
+func setRequestCache(ctx context.Context, key string, record interface{}) {
+	rc, ok := ctx.Value(requestCacheCtxKey{}).(*requestCache)
+	if !ok {
+		return
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if !rc.closed && len(rc.records) < maxRequestCacheEntries {
+		rc.records[key] = record
+	}
+}
+
+func deleteRequestCache(ctx context.Context, keys ...string) {
+	rc, ok := ctx.Value(requestCacheCtxKey{}).(*requestCache)
+	if !ok {
+		return
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	for _, key := range keys {
+		delete(rc.records, key)
+	}
+}
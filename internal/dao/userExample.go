@@ -2,17 +2,19 @@ package dao
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
-	"golang.org/x/sync/singleflight"
 	"gorm.io/gorm"
 
-	"github.com/go-dev-frame/sponge/pkg/logger"
 	"github.com/go-dev-frame/sponge/pkg/sgorm/query"
-	"github.com/go-dev-frame/sponge/pkg/utils"
 
 	"github.com/go-dev-frame/sponge/internal/cache"
-	"github.com/go-dev-frame/sponge/internal/database"
 	"github.com/go-dev-frame/sponge/internal/model"
 )
 
@@ -22,56 +24,87 @@ var _ UserExampleDao = (*userExampleDao)(nil)
 type UserExampleDao interface {
 	Create(ctx context.Context, table *model.UserExample) error
 	DeleteByID(ctx context.Context, id uint64) error
+	DeleteExistingByID(ctx context.Context, id uint64) (bool, error)
 	UpdateByID(ctx context.Context, table *model.UserExample) error
+	// delete the templates code start
+	UpdateStatusByID(ctx context.Context, table *model.UserExample, from int) error
+	// delete the templates code end
 	GetByID(ctx context.Context, id uint64) (*model.UserExample, error)
 	GetByColumns(ctx context.Context, params *query.Params) ([]*model.UserExample, int64, error)
+	GetLastModified(ctx context.Context) (time.Time, error)
+	GetDistinct(ctx context.Context, column string, columns []query.Column, prefix string, limit int) ([]interface{}, error)
 
 	CreateByTx(ctx context.Context, tx *gorm.DB, table *model.UserExample) (uint64, error)
 	DeleteByTx(ctx context.Context, tx *gorm.DB, id uint64) error
 	UpdateByTx(ctx context.Context, tx *gorm.DB, table *model.UserExample) error
 }
 
+// the resource name of the request cache keys
+const userExampleResource = "userExample"
+
 type userExampleDao struct {
 	db    *gorm.DB
 	cache cache.UserExampleCache // if nil, the cache is not used.
-	sfg   *singleflight.Group    // if cache is nil, the sfg is not used.
 }
 
 // NewUserExampleDao creating the dao interface
 func NewUserExampleDao(db *gorm.DB, xCache cache.UserExampleCache) UserExampleDao {
 	if xCache == nil {
-		return &userExampleDao{db: db}
+		return withUserExampleRepo(&userExampleDao{db: db})
 	}
-	return &userExampleDao{
+	return withUserExampleRepo(&userExampleDao{
 		db:    db,
 		cache: xCache,
-		sfg:   new(singleflight.Group),
-	}
+	})
 }
 
-func (d *userExampleDao) deleteCache(ctx context.Context, id uint64) error {
+// invalidate delete the cache of the ids and bump the list generation, every write method must call it,
+// an empty ids only bumps the list generation, e.g. after creating a record
+func (d *userExampleDao) invalidate(ctx context.Context, ids ...uint64) error {
+	if len(ids) > 0 {
+		keys := make([]string, 0, len(ids))
+		for _, id := range ids {
+			keys = append(keys, requestCacheKey(userExampleResource, id))
+		}
+		deleteRequestCache(ctx, keys...)
+	}
 	if d.cache != nil {
-		return d.cache.Del(ctx, id)
+		return d.cache.Invalidate(ctx, ids...)
 	}
 	return nil
 }
 
 // Create a record, insert the record and the id value is written back to the table
 func (d *userExampleDao) Create(ctx context.Context, table *model.UserExample) error {
-	return d.db.WithContext(ctx).Create(table).Error
+	err := d.db.WithContext(ctx).Create(table).Error
+	if err != nil {
+		return err
+	}
+
+	// bump the list generation
+	_ = d.invalidate(ctx)
+
+	return nil
 }
 
 // DeleteByID delete a record by id
 func (d *userExampleDao) DeleteByID(ctx context.Context, id uint64) error {
-	err := d.db.WithContext(ctx).Where("id = ?", id).Delete(&model.UserExample{}).Error
-	if err != nil {
-		return err
+	_, err := d.DeleteExistingByID(ctx, id)
+	return err
+}
+
+// DeleteExistingByID delete a record by id, the returned value is false if there is no record deleted,
+// e.g. the record does not exist or it has been deleted by a concurrent request
+func (d *userExampleDao) DeleteExistingByID(ctx context.Context, id uint64) (bool, error) {
+	result := d.db.WithContext(ctx).Where("id = ?", id).Delete(&model.UserExample{})
+	if result.Error != nil {
+		return false, result.Error
 	}
 
 	// delete cache
-	_ = d.deleteCache(ctx, id)
+	_ = d.invalidate(ctx, id)
 
-	return nil
+	return result.RowsAffected > 0, nil
 }
 
 // UpdateByID update a record by id
@@ -79,7 +112,7 @@ func (d *userExampleDao) UpdateByID(ctx context.Context, table *model.UserExampl
 	err := d.updateDataByID(ctx, d.db, table)
 
 	// delete cache
-	_ = d.deleteCache(ctx, table.ID)
+	_ = d.invalidate(ctx, table.ID)
 
 	return err
 }
@@ -89,6 +122,11 @@ func (d *userExampleDao) updateDataByID(ctx context.Context, db *gorm.DB, table
 		return errors.New("id cannot be 0")
 	}
 
+	return db.WithContext(ctx).Model(table).Updates(userExampleUpdateData(table)).Error
+}
+
+// the non-zero fields of the table to update
+func userExampleUpdateData(table *model.UserExample) map[string]interface{} {
 	update := map[string]interface{}{}
 	// todo generate the update fields code to here
 	// delete the templates code start
@@ -118,61 +156,47 @@ func (d *userExampleDao) updateDataByID(ctx context.Context, db *gorm.DB, table
 	}
 	// delete the templates code end
 
-	return db.WithContext(ctx).Model(table).Updates(update).Error
+	return update
 }
 
-// GetByID get a record by id
+// GetByID get a record by id, the record is memoized within the request if the context is set by WithRequestCache
 func (d *userExampleDao) GetByID(ctx context.Context, id uint64) (*model.UserExample, error) {
-	// no cache
-	if d.cache == nil {
-		record := &model.UserExample{}
-		err := d.db.WithContext(ctx).Where("id = ?", id).First(record).Error
-		return record, err
+	key := requestCacheKey(userExampleResource, id)
+	if record, ok := getRequestCache(ctx, key); ok {
+		copied := *record.(*model.UserExample) // the callers may modify the record
+		return &copied, nil
 	}
 
-	// get from cache
-	record, err := d.cache.Get(ctx, id)
-	if err == nil {
-		return record, nil
-	}
-
-	// get from database
-	if errors.Is(err, database.ErrCacheNotFound) {
-		// for the same id, prevent high concurrent simultaneous access to database
-		val, err, _ := d.sfg.Do(utils.Uint64ToStr(id), func() (interface{}, error) { //nolint
-			table := &model.UserExample{}
-			err = d.db.WithContext(ctx).Where("id = ?", id).First(table).Error
-			if err != nil {
-				if errors.Is(err, database.ErrRecordNotFound) {
-					// set placeholder cache to prevent cache penetration, default expiration time 10 minutes
-					if err = d.cache.SetPlaceholder(ctx, id); err != nil {
-						logger.Warn("cache.SetPlaceholder error", logger.Err(err), logger.Any("id", id))
-					}
-					return nil, database.ErrRecordNotFound
-				}
-				return nil, err
-			}
-			// set cache
-			if err = d.cache.Set(ctx, id, table, cache.UserExampleExpireTime); err != nil {
-				logger.Warn("cache.Set error", logger.Err(err), logger.Any("id", id))
-			}
-			return table, nil
-		})
-		if err != nil {
-			return nil, err
-		}
-		table, ok := val.(*model.UserExample)
-		if !ok {
-			return nil, database.ErrRecordNotFound
-		}
-		return table, nil
+	record, err := d.getByID(ctx, id)
+	if err != nil {
+		return record, err
 	}
+	copied := *record
+	setRequestCache(ctx, key, &copied)
+	return record, nil
+}
 
-	if d.cache.IsPlaceholderErr(err) {
-		return nil, database.ErrRecordNotFound
+func (d *userExampleDao) getByID(ctx context.Context, id uint64) (*model.UserExample, error) {
+	// no cache
+	if d.cache == nil {
+		return d.getFromDB(ctx, id)
 	}
 
-	return nil, err
+	// get from cache, if not cached, get from database and set cache
+	return d.cache.GetOrLoad(ctx, id, func(ctx context.Context) (*model.UserExample, error) {
+		return d.getFromDB(ctx, id)
+	})
+}
+
+func (d *userExampleDao) getFromDB(ctx context.Context, id uint64) (*model.UserExample, error) {
+	record := &model.UserExample{}
+	db, cancel, err := budgetSession(ctx, d.db)
+	if err != nil {
+		return record, err
+	}
+	defer cancel()
+	err = db.Where("id = ?", id).First(record).Error
+	return record, err
 }
 
 // GetByColumns get paging records by column information.
@@ -182,10 +206,21 @@ func (d *userExampleDao) GetByColumns(ctx context.Context, params *query.Params)
 	if err != nil {
 		return nil, 0, errors.New("query params error: " + err.Error())
 	}
+	if len(params.Sorts) > 0 { // the structured sort fields are checked by the whitelist of the columns
+		if _, err = params.ConvertToOrder(query.WithWhitelistNames(model.UserExampleColumnNames)); err != nil {
+			return nil, 0, errors.New("query params error: " + err.Error())
+		}
+	}
+
+	db, cancel, err := budgetSession(ctx, d.db)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cancel()
 
 	var total int64
 	if params.Sort != "ignore count" { // determine if count is required
-		err = d.db.WithContext(ctx).Model(&model.UserExample{}).Where(queryStr, args...).Count(&total).Error
+		err = db.Model(&model.UserExample{}).Where(queryStr, args...).Count(&total).Error
 		if err != nil {
 			return nil, 0, err
 		}
@@ -196,7 +231,7 @@ func (d *userExampleDao) GetByColumns(ctx context.Context, params *query.Params)
 
 	records := []*model.UserExample{}
 	order, limit, offset := params.ConvertToPage()
-	err = d.db.WithContext(ctx).Order(order).Limit(limit).Offset(offset).Where(queryStr, args...).Find(&records).Error
+	err = db.Order(order).Limit(limit).Offset(offset).Where(queryStr, args...).Find(&records).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -204,10 +239,85 @@ func (d *userExampleDao) GetByColumns(ctx context.Context, params *query.Params)
 	return records, total, err
 }
 
+// GetLastModified get the time of the last write of the table, it is tracked by the cache alongside the
+// list generation, zero time if the cache is not used or no write is recorded yet
+func (d *userExampleDao) GetLastModified(ctx context.Context) (time.Time, error) {
+	if d.cache == nil {
+		return time.Time{}, nil
+	}
+	return d.cache.ListLastModified(ctx)
+}
+
+// GetDistinct get at most limit distinct values of the column of the records matching the columns, ordered by
+// the value, only the values starting with prefix are returned if prefix is not empty, e.g. the typeahead of the
+// filter dropdowns. The column and the columns of the conditions must be in model.UserExampleColumnNames.
+// The values are cached for a short time by the column and the hash of the conditions.
+func (d *userExampleDao) GetDistinct(ctx context.Context, column string, columns []query.Column, prefix string, limit int) ([]interface{}, error) {
+	if !model.UserExampleColumnNames[column] {
+		return nil, errors.New("query params error: column '" + column + "' is not allowed")
+	}
+	queryStr, args, err := (&query.Params{Columns: columns}).ConvertToGormConditions(query.WithWhitelistNames(model.UserExampleColumnNames))
+	if err != nil {
+		return nil, errors.New("query params error: " + err.Error())
+	}
+
+	params := userExampleDistinctParams(ctx, column, columns, prefix, limit)
+	if d.cache != nil {
+		if data, err := d.cache.GetDistinct(ctx, params); err == nil && data != nil {
+			return data.Values, nil
+		}
+	}
+
+	db := d.db.WithContext(ctx).Model(&model.UserExample{})
+	if queryStr != "" {
+		db = db.Where(queryStr, args...)
+	}
+	if prefix != "" {
+		db = db.Where(column+" LIKE ? ESCAPE '!'", userExampleLikeEscaper.Replace(prefix)+"%")
+	}
+	var values []interface{}
+	err = db.Distinct(column).Order(column).Limit(limit).Pluck(column, &values).Error
+	if err != nil {
+		return nil, err
+	}
+
+	data := &cache.UserExampleDistinct{Values: make([]interface{}, 0, len(values))}
+	for _, v := range values {
+		if b, ok := v.([]byte); ok { // the text of some drivers, e.g. mysql
+			v = string(b)
+		}
+		data.Values = append(data.Values, v)
+	}
+
+	if d.cache != nil {
+		_ = d.cache.SetDistinct(ctx, params, data)
+	}
+	return data.Values, nil
+}
+
+// the cache params of the distinct values, the column and the hash of the conditions
+func userExampleDistinctParams(ctx context.Context, column string, columns []query.Column, prefix string, limit int) string {
+	b, _ := json.Marshal(columns)
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "%s\n%s\n%d\n%s", cache.TenantFromContext(ctx), prefix, limit, b)
+	return column + ":" + hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+// escape the wildcards of like with the escape character '!', which is the same for all the databases,
+// unlike '\', which is not the default of sqlite and must be escaped in the strings of mysql
+var userExampleLikeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
 // CreateByTx create a record in the database using the provided transaction
 func (d *userExampleDao) CreateByTx(ctx context.Context, tx *gorm.DB, table *model.UserExample) (uint64, error) {
 	err := tx.WithContext(ctx).Create(table).Error
-	return table.ID, err
+	if err != nil {
+		return table.ID, err
+	}
+
+	// bump the list generation
+	_ = d.invalidate(ctx)
+
+	return table.ID, nil
 }
 
 // DeleteByTx delete a record by id in the database using the provided transaction
@@ -218,7 +328,7 @@ func (d *userExampleDao) DeleteByTx(ctx context.Context, tx *gorm.DB, id uint64)
 	}
 
 	// delete cache
-	_ = d.deleteCache(ctx, id)
+	_ = d.invalidate(ctx, id)
 
 	return nil
 }
@@ -228,7 +338,7 @@ func (d *userExampleDao) UpdateByTx(ctx context.Context, tx *gorm.DB, table *mod
 	err := d.updateDataByID(ctx, tx, table)
 
 	// delete cache
-	_ = d.deleteCache(ctx, table.ID)
+	_ = d.invalidate(ctx, table.ID)
 
 	return err
 }
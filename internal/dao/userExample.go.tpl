@@ -4,15 +4,11 @@ import (
 	"context"
 	"errors"
 
-	"golang.org/x/sync/singleflight"
 	"gorm.io/gorm"
 
-	"github.com/go-dev-frame/sponge/pkg/logger"
 	"github.com/go-dev-frame/sponge/pkg/sgorm/query"
-	"github.com/go-dev-frame/sponge/pkg/utils"
 
 	"github.com/go-dev-frame/sponge/internal/cache"
-	"github.com/go-dev-frame/sponge/internal/database"
 	"github.com/go-dev-frame/sponge/internal/model"
 )
 
@@ -34,7 +30,6 @@ type {{.TableNameCamel}}Dao interface {
 type {{.TableNameCamelFCL}}Dao struct {
 	db    *gorm.DB
 	cache cache.{{.TableNameCamel}}Cache // if nil, the cache is not used.
-	sfg   *singleflight.Group    // if cache is nil, the sfg is not used.
 }
 
 // New{{.TableNameCamel}}Dao creating the dao interface
@@ -45,20 +40,29 @@ func New{{.TableNameCamel}}Dao(db *gorm.DB, xCache cache.{{.TableNameCamel}}Cach
 	return &{{.TableNameCamelFCL}}Dao{
 		db:    db,
 		cache: xCache,
-		sfg:   new(singleflight.Group),
 	}
 }
 
-func (d *{{.TableNameCamelFCL}}Dao) deleteCache(ctx context.Context, {{.ColumnNameCamelFCL}} {{.GoType}}) error {
+// invalidate delete the cache of the {{.ColumnNamePluralCamelFCL}} and bump the list generation, every write method must call it,
+// an empty {{.ColumnNamePluralCamelFCL}} only bumps the list generation, e.g. after creating a record
+func (d *{{.TableNameCamelFCL}}Dao) invalidate(ctx context.Context, {{.ColumnNamePluralCamelFCL}} ...{{.GoType}}) error {
 	if d.cache != nil {
-		return d.cache.Del(ctx, {{.ColumnNameCamelFCL}})
+		return d.cache.Invalidate(ctx, {{.ColumnNamePluralCamelFCL}}...)
 	}
 	return nil
 }
 
 // Create a record, insert the record and the {{.ColumnNameCamelFCL}} value is written back to the table
 func (d *{{.TableNameCamelFCL}}Dao) Create(ctx context.Context, table *model.{{.TableNameCamel}}) error {
-	return d.db.WithContext(ctx).Create(table).Error
+	err := d.db.WithContext(ctx).Create(table).Error
+	if err != nil {
+		return err
+	}
+
+	// bump the list generation
+	_ = d.invalidate(ctx)
+
+	return nil
 }
 
 // DeleteBy{{.ColumnNameCamel}} delete a record by {{.ColumnNameCamelFCL}}
@@ -69,7 +73,7 @@ func (d *{{.TableNameCamelFCL}}Dao) DeleteBy{{.ColumnNameCamel}}(ctx context.Con
 	}
 
 	// delete cache
-	_ = d.deleteCache(ctx, {{.ColumnNameCamelFCL}})
+	_ = d.invalidate(ctx, {{.ColumnNameCamelFCL}})
 
 	return nil
 }
@@ -79,7 +83,7 @@ func (d *{{.TableNameCamelFCL}}Dao) UpdateBy{{.ColumnNameCamel}}(ctx context.Con
 	err := d.updateDataBy{{.ColumnNameCamel}}(ctx, d.db, table)
 
 	// delete cache
-	_ = d.deleteCache(ctx, table.{{.ColumnNameCamel}})
+	_ = d.invalidate(ctx, table.{{.ColumnNameCamel}})
 
 	return err
 }
@@ -134,51 +138,12 @@ func (d *{{.TableNameCamelFCL}}Dao) GetBy{{.ColumnNameCamel}}(ctx context.Contex
 		return record, err
 	}
 
-	// get from cache
-	record, err := d.cache.Get(ctx, {{.ColumnNameCamelFCL}})
-	if err == nil {
-		return record, nil
-	}
-
-	// get from database
-	if errors.Is(err, database.ErrCacheNotFound) {
-		// for the same {{.ColumnNameCamelFCL}}, prevent high concurrent simultaneous access to database
-		{{if .IsStringType}}val, err, _ := d.sfg.Do({{.ColumnNameCamelFCL}}, func() (interface{}, error) {
-{{else}}		val, err, _ := d.sfg.Do(utils.{{.GoTypeFCU}}ToStr({{.ColumnNameCamelFCL}}), func() (interface{}, error) {
-{{end}}
-			table := &model.{{.TableNameCamel}}{}
-			err = d.db.WithContext(ctx).Where("{{.ColumnName}} = ?", {{.ColumnNameCamelFCL}}).First(table).Error
-			if err != nil {
-				// set placeholder cache to prevent cache penetration, default expiration time 10 minutes
-				if errors.Is(err, database.ErrRecordNotFound) {
-					if err = d.cache.SetPlaceholder(ctx, {{.ColumnNameCamelFCL}}); err != nil {
-						logger.Warn("cache.SetPlaceholder error", logger.Err(err), logger.Any("{{.ColumnNameCamelFCL}}", {{.ColumnNameCamelFCL}}))
-					}
-					return nil, database.ErrRecordNotFound
-				}
-				return nil, err
-			}
-			// set cache
-			if err = d.cache.Set(ctx, {{.ColumnNameCamelFCL}}, table, cache.{{.TableNameCamel}}ExpireTime); err != nil {
-				logger.Warn("cache.Set error", logger.Err(err), logger.Any("{{.ColumnNameCamelFCL}}", {{.ColumnNameCamelFCL}}))
-			}
-			return table, nil
-		})
-		if err != nil {
-			return nil, err
-		}
-		table, ok := val.(*model.{{.TableNameCamel}})
-		if !ok {
-			return nil, database.ErrRecordNotFound
-		}
-		return table, nil
-	}
-
-	if d.cache.IsPlaceholderErr(err) {
-		return nil, database.ErrRecordNotFound
-	}
-
-	return nil, err
+	// get from cache, if not cached, get from database and set cache
+	return d.cache.GetOrLoad(ctx, {{.ColumnNameCamelFCL}}, func(ctx context.Context) (*model.{{.TableNameCamel}}, error) {
+		record := &model.{{.TableNameCamel}}{}
+		err := d.db.WithContext(ctx).Where("{{.ColumnName}} = ?", {{.ColumnNameCamelFCL}}).First(record).Error
+		return record, err
+	})
 }
 
 // GetByColumns get paging records by column information.
@@ -216,7 +181,14 @@ func (d *{{.TableNameCamelFCL}}Dao) GetByColumns(ctx context.Context, params *qu
 // CreateByTx create a record in the database using the provided transaction
 func (d *{{.TableNameCamelFCL}}Dao) CreateByTx(ctx context.Context, tx *gorm.DB, table *model.{{.TableNameCamel}}) ({{.GoType}}, error) {
 	err := tx.WithContext(ctx).Create(table).Error
-	return table.{{.ColumnNameCamel}}, err
+	if err != nil {
+		return table.{{.ColumnNameCamel}}, err
+	}
+
+	// bump the list generation
+	_ = d.invalidate(ctx)
+
+	return table.{{.ColumnNameCamel}}, nil
 }
 
 // DeleteByTx delete a record by {{.ColumnNameCamelFCL}} in the database using the provided transaction
@@ -227,7 +199,7 @@ func (d *{{.TableNameCamelFCL}}Dao) DeleteByTx(ctx context.Context, tx *gorm.DB,
 	}
 
 	// delete cache
-	_ = d.deleteCache(ctx, {{.ColumnNameCamelFCL}})
+	_ = d.invalidate(ctx, {{.ColumnNameCamelFCL}})
 
 	return nil
 }
@@ -237,7 +209,7 @@ func (d *{{.TableNameCamelFCL}}Dao) UpdateByTx(ctx context.Context, tx *gorm.DB,
 	err := d.updateDataBy{{.ColumnNameCamel}}(ctx, tx, table)
 
 	// delete cache
-	_ = d.deleteCache(ctx, table.{{.ColumnNameCamel}})
+	_ = d.invalidate(ctx, table.{{.ColumnNameCamel}})
 
 	return err
 }
@@ -0,0 +1,56 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/go-dev-frame/sponge/internal/model"
+)
+
+var _ UserExampleActivityDao = (*userExampleActivityDao)(nil)
+
+// UserExampleActivityDao defining the dao interface of the audit entries of the userExample records
+type UserExampleActivityDao interface {
+	Create(ctx context.Context, table *model.UserExampleActivity) error
+	GetByUserExampleID(ctx context.Context, userExampleID uint64, tenantID string, page int, limit int) ([]*model.UserExampleActivity, int64, error)
+	DeleteBefore(ctx context.Context, t time.Time) (int64, error)
+}
+
+type userExampleActivityDao struct {
+	db *gorm.DB
+}
+
+// NewUserExampleActivityDao creating the dao interface
+func NewUserExampleActivityDao(db *gorm.DB) UserExampleActivityDao {
+	return &userExampleActivityDao{db: db}
+}
+
+// Create a record, insert the record and the id value is written back to the table
+func (d *userExampleActivityDao) Create(ctx context.Context, table *model.UserExampleActivity) error {
+	return d.db.WithContext(ctx).Create(table).Error
+}
+
+// GetByUserExampleID get a page of the entries of the userExample record of the tenant and the total, the newest first,
+// the entries of the same time are ordered by the id, page starts from 0
+func (d *userExampleActivityDao) GetByUserExampleID(ctx context.Context, userExampleID uint64, tenantID string, page int, limit int) ([]*model.UserExampleActivity, int64, error) {
+	db := d.db.WithContext(ctx).Model(&model.UserExampleActivity{}).Where("user_example_id = ? AND tenant_id = ?", userExampleID, tenantID)
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	records := []*model.UserExampleActivity{}
+	if total == 0 {
+		return records, 0, nil
+	}
+	err := db.Order("created_at DESC").Order("id DESC").Offset(page * limit).Limit(limit).Find(&records).Error
+	return records, total, err
+}
+
+// DeleteBefore delete the entries created before t, and return the number of the deleted entries
+func (d *userExampleActivityDao) DeleteBefore(ctx context.Context, t time.Time) (int64, error) {
+	result := d.db.WithContext(ctx).Where("created_at < ?", t).Delete(&model.UserExampleActivity{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,56 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/go-dev-frame/sponge/internal/model"
+)
+
+var _ {{.TableNameCamel}}ActivityDao = (*{{.TableNameCamelFCL}}ActivityDao)(nil)
+
+// {{.TableNameCamel}}ActivityDao defining the dao interface of the audit entries of the {{.TableNameCamelFCL}} records
+type {{.TableNameCamel}}ActivityDao interface {
+	Create(ctx context.Context, table *model.{{.TableNameCamel}}Activity) error
+	GetBy{{.TableNameCamel}}{{.ColumnNameCamel}}(ctx context.Context, {{.TableNameCamelFCL}}{{.ColumnNameCamel}} {{.GoType}}, tenantID string, page int, limit int) ([]*model.{{.TableNameCamel}}Activity, int64, error)
+	DeleteBefore(ctx context.Context, t time.Time) (int64, error)
+}
+
+type {{.TableNameCamelFCL}}ActivityDao struct {
+	db *gorm.DB
+}
+
+// New{{.TableNameCamel}}ActivityDao creating the dao interface
+func New{{.TableNameCamel}}ActivityDao(db *gorm.DB) {{.TableNameCamel}}ActivityDao {
+	return &{{.TableNameCamelFCL}}ActivityDao{db: db}
+}
+
+// Create a record, insert the record and the id value is written back to the table
+func (d *{{.TableNameCamelFCL}}ActivityDao) Create(ctx context.Context, table *model.{{.TableNameCamel}}Activity) error {
+	return d.db.WithContext(ctx).Create(table).Error
+}
+
+// GetBy{{.TableNameCamel}}{{.ColumnNameCamel}} get a page of the entries of the {{.TableNameCamelFCL}} record of the tenant and the total, the newest first,
+// the entries of the same time are ordered by the id, page starts from 0
+func (d *{{.TableNameCamelFCL}}ActivityDao) GetBy{{.TableNameCamel}}{{.ColumnNameCamel}}(ctx context.Context, {{.TableNameCamelFCL}}{{.ColumnNameCamel}} {{.GoType}}, tenantID string, page int, limit int) ([]*model.{{.TableNameCamel}}Activity, int64, error) {
+	db := d.db.WithContext(ctx).Model(&model.{{.TableNameCamel}}Activity{}).Where("record_{{.ColumnName}} = ? AND tenant_id = ?", {{.TableNameCamelFCL}}{{.ColumnNameCamel}}, tenantID)
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	records := []*model.{{.TableNameCamel}}Activity{}
+	if total == 0 {
+		return records, 0, nil
+	}
+	err := db.Order("created_at DESC").Order("id DESC").Offset(page * limit).Limit(limit).Find(&records).Error
+	return records, total, err
+}
+
+// DeleteBefore delete the entries created before t, and return the number of the deleted entries
+func (d *{{.TableNameCamelFCL}}ActivityDao) DeleteBefore(ctx context.Context, t time.Time) (int64, error) {
+	result := d.db.WithContext(ctx).Where("created_at < ?", t).Delete(&model.{{.TableNameCamel}}Activity{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,56 @@
+package dao
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-dev-frame/sponge/pkg/sgorm/sqlite"
+
+	"github.com/go-dev-frame/sponge/internal/model"
+)
+
+func Test_userExampleActivityDao(t *testing.T) {
+	db, err := sqlite.Init(filepath.Join(t.TempDir(), "activity.db"))
+	require.NoError(t, err)
+	defer sqlite.Close(db)
+	require.NoError(t, db.AutoMigrate(&model.UserExampleActivity{}))
+
+	d := NewUserExampleActivityDao(db)
+	ctx := context.Background()
+	now := time.Now()
+	for i, createdAt := range []time.Time{now.Add(-2 * time.Hour), now, now.Add(-time.Hour), now, now.Add(-48 * time.Hour)} {
+		require.NoError(t, d.Create(ctx, &model.UserExampleActivity{UserExampleID: 1, Action: "updated",
+			Actor: string(rune('a' + i)), CreatedAt: createdAt}))
+	}
+	require.NoError(t, d.Create(ctx, &model.UserExampleActivity{UserExampleID: 2, Action: "created", CreatedAt: now}))
+	require.NoError(t, d.Create(ctx, &model.UserExampleActivity{UserExampleID: 1, TenantID: "t2", Action: "created", CreatedAt: now}))
+
+	// the newest first, the entries of the same time are ordered by the id
+	var actors []string
+	for page := 0; page < 3; page++ {
+		records, total, err := d.GetByUserExampleID(ctx, 1, "", page, 2)
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), total)
+		for _, record := range records {
+			actors = append(actors, record.Actor)
+		}
+	}
+	assert.Equal(t, []string{"d", "b", "c", "a", "e"}, actors)
+
+	records, total, err := d.GetByUserExampleID(ctx, 1, "t1", 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), total)
+	assert.Empty(t, records)
+
+	n, err := d.DeleteBefore(ctx, now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	_, total, err = d.GetByUserExampleID(ctx, 1, "", 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), total)
+}
@@ -0,0 +1,75 @@
+package dao
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/go-dev-frame/sponge/internal/model"
+)
+
+var _ UserExampleFileDao = (*userExampleFileDao)(nil)
+
+// UserExampleFileDao defining the dao interface of the files associated with the userExample records
+type UserExampleFileDao interface {
+	Create(ctx context.Context, table *model.UserExampleFile) error
+	DeleteByID(ctx context.Context, id uint64) error
+	GetByID(ctx context.Context, id uint64) (*model.UserExampleFile, error)
+	GetByUserExampleID(ctx context.Context, userExampleID uint64, tenantID string) ([]*model.UserExampleFile, error)
+	GetUsage(ctx context.Context, userExampleID uint64) (count int64, size int64, err error)
+	CountByKey(ctx context.Context, key string) (int64, error)
+}
+
+type userExampleFileDao struct {
+	db *gorm.DB
+}
+
+// NewUserExampleFileDao creating the dao interface
+func NewUserExampleFileDao(db *gorm.DB) UserExampleFileDao {
+	return &userExampleFileDao{db: db}
+}
+
+// Create a record, insert the record and the id value is written back to the table
+func (d *userExampleFileDao) Create(ctx context.Context, table *model.UserExampleFile) error {
+	return d.db.WithContext(ctx).Create(table).Error
+}
+
+// DeleteByID delete a record by id
+func (d *userExampleFileDao) DeleteByID(ctx context.Context, id uint64) error {
+	return d.db.WithContext(ctx).Where("id = ?", id).Delete(&model.UserExampleFile{}).Error
+}
+
+// GetByID get a record by id
+func (d *userExampleFileDao) GetByID(ctx context.Context, id uint64) (*model.UserExampleFile, error) {
+	record := &model.UserExampleFile{}
+	err := d.db.WithContext(ctx).Where("id = ?", id).First(record).Error
+	return record, err
+}
+
+// GetByUserExampleID get the files of the userExample record of the tenant, ordered by the upload time
+func (d *userExampleFileDao) GetByUserExampleID(ctx context.Context, userExampleID uint64, tenantID string) ([]*model.UserExampleFile, error) {
+	records := []*model.UserExampleFile{}
+	err := d.db.WithContext(ctx).Where("user_example_id = ? AND tenant_id = ?", userExampleID, tenantID).
+		Order("id ASC").Find(&records).Error
+	return records, err
+}
+
+// GetUsage get the number and the total size of the files of the userExample record
+func (d *userExampleFileDao) GetUsage(ctx context.Context, userExampleID uint64) (int64, int64, error) {
+	usage := struct {
+		Count int64
+		Size  int64
+	}{}
+	err := d.db.WithContext(ctx).Model(&model.UserExampleFile{}).
+		Select("COUNT(*) AS count, COALESCE(SUM(size), 0) AS size").
+		Where("user_example_id = ?", userExampleID).Scan(&usage).Error
+	return usage.Count, usage.Size, err
+}
+
+// CountByKey count the files sharing the storage key, the content can only be deleted from the storage
+// when no file refers to it, the struct condition quotes the column key, which is reserved by mysql
+func (d *userExampleFileDao) CountByKey(ctx context.Context, key string) (int64, error) {
+	var total int64
+	err := d.db.WithContext(ctx).Model(&model.UserExampleFile{}).Where(&model.UserExampleFile{Key: key}).Count(&total).Error
+	return total, err
+}
@@ -0,0 +1,115 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/go-dev-frame/sponge/internal/model"
+)
+
+var _ UserExampleImportJobDao = (*userExampleImportJobDao)(nil)
+
+// ErrUserExampleImportJobClaimed the job is claimed by another worker, e.g. the worker of another replica takes
+// over the job whose progress is not updated in time, the progress of the previous owner is rejected
+var ErrUserExampleImportJobClaimed = errors.New("the import job is claimed by another worker")
+
+// UserExampleImportJobDao defining the dao interface of the jobs importing the userExample records
+type UserExampleImportJobDao interface {
+	Create(ctx context.Context, table *model.UserExampleImportJob) error
+	GetByID(ctx context.Context, id uint64, tenantID string) (*model.UserExampleImportJob, error)
+	GetUnfinished(ctx context.Context, statuses []string, staleBefore time.Time, limit int) ([]*model.UserExampleImportJob, error)
+	Claim(ctx context.Context, table *model.UserExampleImportJob, owner string, staleBefore time.Time) (bool, error)
+	UpdateProgress(ctx context.Context, table *model.UserExampleImportJob) error
+	UpdateProgressInTx(ctx context.Context, table *model.UserExampleImportJob, fn func(tx *gorm.DB) error) error
+}
+
+type userExampleImportJobDao struct {
+	db *gorm.DB
+}
+
+// NewUserExampleImportJobDao creating the dao interface
+func NewUserExampleImportJobDao(db *gorm.DB) UserExampleImportJobDao {
+	return &userExampleImportJobDao{db: db}
+}
+
+// Create a record, insert the record and the id value is written back to the table
+func (d *userExampleImportJobDao) Create(ctx context.Context, table *model.UserExampleImportJob) error {
+	return d.db.WithContext(ctx).Create(table).Error
+}
+
+// GetByID get the job of the tenant by id, the rows are not loaded
+func (d *userExampleImportJobDao) GetByID(ctx context.Context, id uint64, tenantID string) (*model.UserExampleImportJob, error) {
+	record := &model.UserExampleImportJob{}
+	err := d.db.WithContext(ctx).Omit("rows").Where("id = ? AND tenant_id = ?", id, tenantID).First(record).Error
+	return record, err
+}
+
+// GetUnfinished get the jobs of the statuses which are not claimed, or whose progress is not updated by the owner
+// since staleBefore, the oldest first
+func (d *userExampleImportJobDao) GetUnfinished(ctx context.Context, statuses []string, staleBefore time.Time, limit int) ([]*model.UserExampleImportJob, error) {
+	records := []*model.UserExampleImportJob{}
+	err := d.db.WithContext(ctx).Where("status IN ? AND (owner = '' OR updated_at < ?)", statuses, staleBefore).
+		Order("id ASC").Limit(limit).Find(&records).Error
+	return records, err
+}
+
+// Claim the job got by GetUnfinished for the owner by a conditional update, false is returned if the job is
+// changed after it is got, e.g. it is claimed by the worker of another replica
+func (d *userExampleImportJobDao) Claim(ctx context.Context, table *model.UserExampleImportJob, owner string, staleBefore time.Time) (bool, error) {
+	now := time.Now()
+	result := d.db.WithContext(ctx).Model(&model.UserExampleImportJob{}).
+		Where("id = ? AND status = ? AND owner = ? AND (owner = '' OR updated_at < ?)", table.ID, table.Status, table.Owner, staleBefore).
+		Updates(map[string]interface{}{"owner": owner, "updated_at": now})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	table.Owner, table.UpdatedAt = owner, now
+	return true, nil
+}
+
+// UpdateProgress update the status, the counters and the errors of the job by its owner, the zero values are
+// written, the updated time is the time of the last progress, ErrUserExampleImportJobClaimed is returned if the
+// job is claimed by another worker
+func (d *userExampleImportJobDao) UpdateProgress(ctx context.Context, table *model.UserExampleImportJob) error {
+	return d.updateProgress(ctx, d.db, table)
+}
+
+// UpdateProgressInTx call fn and update the progress of the job in one transaction, e.g. create the record of a
+// row by tx in fn, so the record and the progress are committed or rolled back together
+func (d *userExampleImportJobDao) UpdateProgressInTx(ctx context.Context, table *model.UserExampleImportJob, fn func(tx *gorm.DB) error) error {
+	return d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return d.updateProgress(ctx, tx, table)
+	})
+}
+
+func (d *userExampleImportJobDao) updateProgress(ctx context.Context, db *gorm.DB, table *model.UserExampleImportJob) error {
+	result := db.WithContext(ctx).Model(table).Where("owner = ?", table.Owner).
+		Select("status", "processed", "succeeded", "failed", "errors", "updated_at").Updates(table)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	// the affected rows of mysql are 0 if the values are not changed
+	var count int64
+	err := db.WithContext(ctx).Model(&model.UserExampleImportJob{}).Where("id = ? AND owner = ?", table.ID, table.Owner).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrUserExampleImportJobClaimed
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/go-dev-frame/sponge/pkg/sgorm/sqlite"
+
+	"github.com/go-dev-frame/sponge/internal/database"
+	"github.com/go-dev-frame/sponge/internal/model"
+)
+
+func Test_userExampleImportJobDao(t *testing.T) {
+	db, err := sqlite.Init(filepath.Join(t.TempDir(), "import.db"))
+	require.NoError(t, err)
+	defer sqlite.Close(db)
+	require.NoError(t, db.AutoMigrate(&model.UserExampleImportJob{}))
+
+	d := NewUserExampleImportJobDao(db)
+	ctx := context.Background()
+	jobs := []*model.UserExampleImportJob{
+		{TenantID: "t1", Status: "pending", Total: 3, Valid: 2, Rows: `[{"line":2},{"line":3}]`},
+		{TenantID: "t1", Status: "succeeded", Total: 1, Valid: 1},
+		{TenantID: "t2", Status: "running", Total: 1, Valid: 1, Rows: `[{"line":2}]`},
+	}
+	for _, job := range jobs {
+		require.NoError(t, d.Create(ctx, job))
+	}
+
+	// the rows are not loaded by id, the job of the other tenant is not found
+	job, err := d.GetByID(ctx, jobs[0].ID, "t1")
+	require.NoError(t, err)
+	assert.Equal(t, 3, job.Total)
+	assert.Empty(t, job.Rows)
+	_, err = d.GetByID(ctx, jobs[0].ID, "t2")
+	assert.ErrorIs(t, err, database.ErrRecordNotFound)
+
+	staleBefore := time.Now().Add(-time.Minute)
+	records, err := d.GetUnfinished(ctx, []string{"pending", "running"}, staleBefore, 10)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, jobs[0].ID, records[0].ID)
+	assert.Equal(t, jobs[0].Rows, records[0].Rows)
+	assert.Equal(t, jobs[2].ID, records[1].ID)
+
+	// the job is claimed by one owner only, the claimed job is not got until its progress is stale
+	claimed, err := d.Claim(ctx, records[0], "w1", staleBefore)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	claimed, err = d.Claim(ctx, &model.UserExampleImportJob{Model: records[0].Model, Status: "pending"}, "w2", staleBefore)
+	require.NoError(t, err)
+	assert.False(t, claimed)
+	unclaimed, err := d.GetUnfinished(ctx, []string{"pending", "running"}, staleBefore, 10)
+	require.NoError(t, err)
+	require.Len(t, unclaimed, 1)
+	assert.Equal(t, jobs[2].ID, unclaimed[0].ID)
+	unclaimed, err = d.GetUnfinished(ctx, []string{"pending"}, time.Now().Add(time.Second), 10)
+	require.NoError(t, err)
+	require.Len(t, unclaimed, 1)
+	assert.Equal(t, "w1", unclaimed[0].Owner)
+
+	// the record and the progress are rolled back together, the progress of the other owner is rejected
+	records[0].Processed = 1
+	err = d.UpdateProgressInTx(ctx, records[0], func(tx *gorm.DB) error {
+		return errors.New("create error")
+	})
+	assert.Error(t, err)
+	job, err = d.GetByID(ctx, jobs[0].ID, "t1")
+	require.NoError(t, err)
+	assert.Equal(t, 0, job.Processed)
+	require.NoError(t, d.UpdateProgressInTx(ctx, records[0], func(tx *gorm.DB) error { return nil }))
+	require.NoError(t, d.UpdateProgress(ctx, records[0])) // not changed
+	other := *records[0]
+	other.Owner = "w2"
+	assert.ErrorIs(t, d.UpdateProgress(ctx, &other), ErrUserExampleImportJobClaimed)
+
+	// the zero values are written, the rows are kept
+	records[0].Status, records[0].Processed, records[0].Succeeded, records[0].Failed, records[0].Errors = "failed", 1, 0, 2, "[]"
+	require.NoError(t, d.UpdateProgress(ctx, records[0]))
+	job, err = d.GetByID(ctx, jobs[0].ID, "t1")
+	require.NoError(t, err)
+	assert.Equal(t, "failed", job.Status)
+	assert.Equal(t, 1, job.Processed)
+	assert.Equal(t, 2, job.Failed)
+	assert.Equal(t, "[]", job.Errors)
+	records, err = d.GetUnfinished(ctx, []string{"pending"}, time.Now().Add(time.Second), 10)
+	require.NoError(t, err)
+	assert.Empty(t, records)
+}
@@ -0,0 +1,8 @@
+//go:build !repo
+
+package dao
+
+// the dao over gorm directly, build with the tag repo to use the generic repository, see userExample_repo.go
+func withUserExampleRepo(d *userExampleDao) UserExampleDao {
+	return d
+}
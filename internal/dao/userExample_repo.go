@@ -0,0 +1,101 @@
+//go:build repo
+
+package dao
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-dev-frame/sponge/pkg/repo"
+	"github.com/go-dev-frame/sponge/pkg/sgorm/query"
+
+	"github.com/go-dev-frame/sponge/internal/model"
+)
+
+var _ UserExampleDao = (*userExampleRepoDao)(nil)
+
+// userExampleRepoDao the dao of the basic methods over the generic repository, the cache is used in the same way
+// as userExampleDao, the other methods are the ones of userExampleDao. Build with the tag repo to use it, e.g.
+// go build -tags repo, the repository can be replaced by the other backends of repo.Repo.
+type userExampleRepoDao struct {
+	*userExampleDao
+	repo repo.Repo[model.UserExample, uint64]
+}
+
+func withUserExampleRepo(d *userExampleDao) UserExampleDao {
+	return &userExampleRepoDao{
+		userExampleDao: d,
+		repo:           repo.NewGormRepo[model.UserExample, uint64](d.db, repo.WithWhitelistNames(model.UserExampleColumnNames)),
+	}
+}
+
+// Create a record, insert the record and the id value is written back to the table
+func (d *userExampleRepoDao) Create(ctx context.Context, table *model.UserExample) error {
+	err := d.repo.Create(ctx, table)
+	if err != nil {
+		return err
+	}
+
+	// bump the list generation
+	_ = d.invalidate(ctx)
+
+	return nil
+}
+
+// DeleteByID delete a record by id
+func (d *userExampleRepoDao) DeleteByID(ctx context.Context, id uint64) error {
+	err := d.repo.DeleteByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// delete cache
+	_ = d.invalidate(ctx, id)
+
+	return nil
+}
+
+// UpdateByID update a record by id
+func (d *userExampleRepoDao) UpdateByID(ctx context.Context, table *model.UserExample) error {
+	if table.ID < 1 {
+		return errors.New("id cannot be 0")
+	}
+	err := d.repo.UpdateByID(ctx, table.ID, userExampleUpdateData(table))
+
+	// delete cache
+	_ = d.invalidate(ctx, table.ID)
+
+	return err
+}
+
+// GetByID get a record by id, the record is memoized within the request if the context is set by WithRequestCache
+func (d *userExampleRepoDao) GetByID(ctx context.Context, id uint64) (*model.UserExample, error) {
+	key := requestCacheKey(userExampleResource, id)
+	if record, ok := getRequestCache(ctx, key); ok {
+		copied := *record.(*model.UserExample) // the callers may modify the record
+		return &copied, nil
+	}
+
+	var record *model.UserExample
+	var err error
+	if d.cache == nil {
+		record, err = d.repo.GetByID(ctx, id)
+	} else {
+		// get from cache, if not cached, get from database and set cache
+		record, err = d.cache.GetOrLoad(ctx, id, func(ctx context.Context) (*model.UserExample, error) {
+			return d.repo.GetByID(ctx, id)
+		})
+	}
+	if err != nil {
+		return record, err
+	}
+	copied := *record
+	setRequestCache(ctx, key, &copied)
+	return record, nil
+}
+
+// GetByColumns get paging records by column information.
+// For more details, please refer to https://go-sponge.com/component/custom-page-query.html
+func (d *userExampleRepoDao) GetByColumns(ctx context.Context, params *query.Params) ([]*model.UserExample, int64, error) {
+	return d.repo.List(ctx, params)
+}
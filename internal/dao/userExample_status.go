@@ -0,0 +1,36 @@
+package dao
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-dev-frame/sponge/pkg/statemachine"
+
+	"github.com/go-dev-frame/sponge/internal/model"
+)
+
+// UpdateStatusByID change the status of a record from the expected status to table.Status, the other non-zero
+// fields of the table are updated in the same conditional update, statemachine.ErrConflict is returned and none
+// of the fields is written if the status is not the expected one any more, e.g. it was changed by a concurrent request
+func (d *userExampleDao) UpdateStatusByID(ctx context.Context, table *model.UserExample, from int) error {
+	if table.ID < 1 {
+		return errors.New("id cannot be 0")
+	}
+
+	update := userExampleUpdateData(table)
+	update["status"] = table.Status
+	result := d.db.WithContext(ctx).Model(&model.UserExample{}).
+		Where("id = ? AND status = ?", table.ID, from).
+		Updates(update)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	// delete cache
+	_ = d.invalidate(ctx, table.ID)
+
+	if result.RowsAffected == 0 {
+		return statemachine.ErrConflict
+	}
+	return nil
+}
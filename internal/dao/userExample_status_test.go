@@ -0,0 +1,51 @@
+package dao
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-dev-frame/sponge/pkg/statemachine"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-dev-frame/sponge/internal/model"
+)
+
+func Test_userExampleDao_UpdateStatusByID(t *testing.T) {
+	d := newUserExampleDao()
+	defer d.Close()
+	iDao := d.IDao.(UserExampleDao)
+
+	table := &model.UserExample{Status: 4}
+	table.ID = 1
+
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("UPDATE .*").
+		WithArgs(4, d.AnyTime, uint64(1), 2).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	d.SQLMock.ExpectCommit()
+	err := iDao.UpdateStatusByID(d.Ctx, table, 2)
+	assert.NoError(t, err)
+
+	// the status was changed by another request
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("UPDATE .*").
+		WithArgs(4, d.AnyTime, uint64(1), 2).
+		WillReturnResult(sqlmock.NewResult(1, 0))
+	d.SQLMock.ExpectCommit()
+	err = iDao.UpdateStatusByID(d.Ctx, table, 2)
+	assert.ErrorIs(t, err, statemachine.ErrConflict)
+
+	// db error
+	err = iDao.UpdateStatusByID(d.Ctx, table, 2)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, statemachine.ErrConflict)
+
+	// zero id error
+	err = iDao.UpdateStatusByID(d.Ctx, &model.UserExample{Status: 4}, 2)
+	assert.Error(t, err)
+
+	err = d.SQLMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
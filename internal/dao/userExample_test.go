@@ -77,6 +77,33 @@ func Test_userExampleDao_DeleteByID(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func Test_userExampleDao_DeleteExistingByID(t *testing.T) {
+	d := newUserExampleDao()
+	defer d.Close()
+	testData := d.TestData.(*model.UserExample)
+
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("UPDATE .*").
+		WithArgs(d.AnyTime, testData.ID).
+		WillReturnResult(sqlmock.NewResult(int64(testData.ID), 1))
+	d.SQLMock.ExpectCommit()
+	deleted, err := d.IDao.(UserExampleDao).DeleteExistingByID(d.Ctx, testData.ID)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+
+	// the record has been deleted
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("UPDATE .*").
+		WithArgs(d.AnyTime, testData.ID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	d.SQLMock.ExpectCommit()
+	deleted, err = d.IDao.(UserExampleDao).DeleteExistingByID(d.Ctx, testData.ID)
+	assert.NoError(t, err)
+	assert.False(t, deleted)
+
+	assert.NoError(t, d.SQLMock.ExpectationsWereMet())
+}
+
 func Test_userExampleDao_UpdateByID(t *testing.T) {
 	d := newUserExampleDao()
 	defer d.Close()
@@ -151,6 +178,64 @@ func Test_userExampleDao_GetByID(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func Test_userExampleDao_GetByID_RequestCache(t *testing.T) {
+	d := newUserExampleDao()
+	defer d.Close()
+	iDao := NewUserExampleDao(d.DB, nil) // the database is queried without the request cache
+	ctx := WithRequestCache(context.Background())
+	expectQuery := func(id uint64) {
+		d.SQLMock.ExpectQuery("SELECT .*").WithArgs(id).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+	}
+
+	// the second lookup within the request hits the memo
+	expectQuery(1)
+	record, err := iDao.GetByID(ctx, 1)
+	assert.NoError(t, err)
+	record.ID = 2 // modified by the caller
+	record, err = iDao.GetByID(ctx, 1)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, record.ID)
+	assert.NoError(t, d.SQLMock.ExpectationsWereMet())
+
+	// the other request and the context without the request cache
+	expectQuery(1)
+	_, err = iDao.GetByID(WithRequestCache(context.Background()), 1)
+	assert.NoError(t, err)
+	expectQuery(1)
+	_, err = iDao.GetByID(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.NoError(t, d.SQLMock.ExpectationsWereMet())
+
+	// the record is forgotten after it is written
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("UPDATE .*").WillReturnResult(sqlmock.NewResult(1, 1))
+	d.SQLMock.ExpectCommit()
+	table := &model.UserExample{}
+	table.ID = 1
+	assert.NoError(t, iDao.UpdateByID(ctx, table))
+	expectQuery(1)
+	_, err = iDao.GetByID(ctx, 1)
+	assert.NoError(t, err)
+	assert.NoError(t, d.SQLMock.ExpectationsWereMet())
+
+	// cleared at the end of the request
+	ClearRequestCache(ctx)
+	expectQuery(1)
+	_, err = iDao.GetByID(ctx, 1)
+	assert.NoError(t, err)
+	assert.NoError(t, d.SQLMock.ExpectationsWereMet())
+
+	// bounded
+	ctx = WithRequestCache(context.Background())
+	for i := 0; i < maxRequestCacheEntries+1; i++ {
+		setRequestCache(ctx, requestCacheKey(userExampleResource, uint64(i)), &model.UserExample{})
+	}
+	_, ok := getRequestCache(ctx, requestCacheKey(userExampleResource, maxRequestCacheEntries))
+	assert.False(t, ok)
+	assert.Equal(t, ctx, WithRequestCache(ctx))
+}
+
 func Test_userExampleDao_GetByColumns(t *testing.T) {
 	d := newUserExampleDao()
 	defer d.Close()
@@ -196,6 +281,72 @@ func Test_userExampleDao_GetByColumns(t *testing.T) {
 	t.Log(err)
 }
 
+func Test_userExampleDao_GetLastModified(t *testing.T) {
+	d := newUserExampleDao()
+	defer d.Close()
+	iDao := d.IDao.(UserExampleDao)
+
+	lm, err := iDao.GetLastModified(d.Ctx)
+	assert.NoError(t, err)
+	assert.True(t, lm.IsZero())
+
+	d.SQLMock.ExpectBegin()
+	d.SQLMock.ExpectExec("UPDATE .*").
+		WithArgs(d.AnyTime, uint64(1)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	d.SQLMock.ExpectCommit()
+	err = iDao.DeleteByID(d.Ctx, 1)
+	assert.NoError(t, err)
+	lm, err = iDao.GetLastModified(d.Ctx)
+	assert.NoError(t, err)
+	assert.False(t, lm.IsZero())
+
+	// no cache
+	lm, err = NewUserExampleDao(d.DB, nil).GetLastModified(d.Ctx)
+	assert.NoError(t, err)
+	assert.True(t, lm.IsZero())
+}
+
+func Test_userExampleDao_GetDistinct(t *testing.T) {
+	d := newUserExampleDao()
+	defer d.Close()
+	iDao := d.IDao.(UserExampleDao)
+	columns := []query.Column{{Name: "id", Exp: ">", Value: 1}}
+
+	rows := sqlmock.NewRows([]string{"id"}).AddRow(2).AddRow(3)
+	d.SQLMock.ExpectQuery("SELECT DISTINCT .*id.* ORDER BY id LIMIT 2").
+		WithArgs(1).
+		WillReturnRows(rows)
+
+	values, err := iDao.GetDistinct(d.Ctx, "id", columns, "", 2)
+	assert.NoError(t, err)
+	assert.Len(t, values, 2)
+	assert.NoError(t, d.SQLMock.ExpectationsWereMet())
+
+	// the cached values, the database is not queried
+	values, err = iDao.GetDistinct(d.Ctx, "id", columns, "", 2)
+	assert.NoError(t, err)
+	assert.Len(t, values, 2)
+
+	// delete the templates code start
+	// no cache, the values starting with the prefix, the wildcards of the prefix are escaped
+	rows = sqlmock.NewRows([]string{"name"}).AddRow("foo_a").AddRow("foo_b")
+	d.SQLMock.ExpectQuery("SELECT DISTINCT .*name.* LIKE .* ESCAPE '!' .* ORDER BY name LIMIT 2").
+		WithArgs(2, "foo!_%").
+		WillReturnRows(rows)
+	values, err = NewUserExampleDao(d.DB, nil).GetDistinct(d.Ctx, "name", []query.Column{{Name: "status", Value: 2}}, "foo_", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"foo_a", "foo_b"}, values)
+	assert.NoError(t, d.SQLMock.ExpectationsWereMet())
+	// delete the templates code end
+
+	// the column is not in the whitelist
+	_, err = iDao.GetDistinct(d.Ctx, "city", nil, "", 100)
+	assert.Error(t, err)
+	_, err = iDao.GetDistinct(d.Ctx, "id", []query.Column{{Name: "city", Value: "foo"}}, "", 100)
+	assert.Error(t, err)
+}
+
 func Test_userExampleDao_CreateByTx(t *testing.T) {
 	d := newUserExampleDao()
 	defer d.Close()
@@ -248,3 +399,80 @@ func Test_userExampleDao_UpdateByTx(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func Test_userExampleDao_Invalidation(t *testing.T) {
+	d := newUserExampleDao()
+	defer d.Close()
+	testData := d.TestData.(*model.UserExample)
+	iDao := d.IDao.(UserExampleDao)
+	key := "userExample:" + utils.Uint64ToStr(testData.ID)
+	generation := func() int64 {
+		gen, _ := d.Cache.ICache.(cache.UserExampleCache).ListGeneration(d.Ctx)
+		return gen
+	}
+	expectInsert := func() {
+		d.SQLMock.ExpectBegin()
+		d.SQLMock.ExpectExec("INSERT INTO .*").
+			WithArgs(d.GetAnyArgs(testData)...).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		d.SQLMock.ExpectCommit()
+	}
+	expectUpdate := func() {
+		d.SQLMock.ExpectBegin()
+		d.SQLMock.ExpectExec("UPDATE .*").
+			WithArgs(d.AnyTime, testData.ID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		d.SQLMock.ExpectCommit()
+	}
+
+	// every write method must have a case, the cache of the written records is deleted and the list generation is bumped
+	d.CheckInvalidation(t, (*UserExampleDao)(nil), generation,
+		gotest.WriteCase{Method: "Create", Call: func() error {
+			expectInsert()
+			return iDao.Create(d.Ctx, testData)
+		}},
+		gotest.WriteCase{Method: "DeleteByID", Keys: []string{key}, Call: func() error {
+			expectUpdate()
+			return iDao.DeleteByID(d.Ctx, testData.ID)
+		}},
+		gotest.WriteCase{Method: "DeleteExistingByID", Keys: []string{key}, Call: func() error {
+			expectUpdate()
+			_, err := iDao.DeleteExistingByID(d.Ctx, testData.ID)
+			return err
+		}},
+		gotest.WriteCase{Method: "UpdateByID", Keys: []string{key}, Call: func() error {
+			expectUpdate()
+			return iDao.UpdateByID(d.Ctx, testData)
+		}},
+		// delete the templates code start
+		gotest.WriteCase{Method: "UpdateStatusByID", Keys: []string{key}, Call: func() error {
+			d.SQLMock.ExpectBegin()
+			d.SQLMock.ExpectExec("UPDATE .*").
+				WithArgs(4, d.AnyTime, testData.ID, 2).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			d.SQLMock.ExpectCommit()
+			table := &model.UserExample{Status: 4}
+			table.ID = testData.ID
+			return iDao.UpdateStatusByID(d.Ctx, table, 2)
+		}},
+		// delete the templates code end
+		gotest.WriteCase{Method: "CreateByTx", Call: func() error {
+			expectInsert()
+			_, err := iDao.CreateByTx(d.Ctx, d.DB, testData)
+			return err
+		}},
+		gotest.WriteCase{Method: "DeleteByTx", Keys: []string{key}, Call: func() error {
+			expectUpdate()
+			return iDao.DeleteByTx(d.Ctx, d.DB, testData.ID)
+		}},
+		gotest.WriteCase{Method: "UpdateByTx", Keys: []string{key}, Call: func() error {
+			expectUpdate()
+			return iDao.UpdateByTx(d.Ctx, d.DB, testData)
+		}},
+	)
+
+	err := d.SQLMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,6 +1,8 @@
 package database
 
 import (
+	"context"
+	"crypto/tls"
 	"sync"
 	"time"
 
@@ -16,7 +18,7 @@ var (
 )
 
 var (
-	redisCli     *goredis.Client
+	redisCli     goredis.UniversalClient
 	redisCliOnce sync.Once
 
 	cacheType     *CacheType
@@ -25,8 +27,8 @@ var (
 
 // CacheType cache type
 type CacheType struct {
-	CType string          // cache type  memory or redis
-	Rdb   *goredis.Client // if CType=redis, Rdb cannot be empty
+	CType string                  // cache type  memory or redis
+	Rdb   goredis.UniversalClient // if CType=redis, Rdb cannot be empty, it is *goredis.Client or *goredis.ClusterClient
 }
 
 // InitCache initial cache
@@ -51,7 +53,7 @@ func GetCacheType() *CacheType {
 	return cacheType
 }
 
-// InitRedis connect redis
+// InitRedis connect redis, if addrs is set, connect redis of single, sentinel or cluster mode, otherwise connect by dsn
 func InitRedis() {
 	redisCfg := config.Get().Redis
 	opts := []goredis.Option{
@@ -59,19 +61,41 @@ func InitRedis() {
 		goredis.WithReadTimeout(time.Duration(redisCfg.ReadTimeout) * time.Second),
 		goredis.WithWriteTimeout(time.Duration(redisCfg.WriteTimeout) * time.Second),
 	}
+	if redisCfg.EnableTLS {
+		opts = append(opts, goredis.WithTLSConfig(&tls.Config{MinVersion: tls.VersionTLS12}))
+	}
 	if config.Get().App.EnableTrace {
 		opts = append(opts, goredis.WithTracing(tracer.GetProvider()))
 	}
 
 	var err error
+	if len(redisCfg.Addrs) > 0 {
+		redisCli, err = goredis.InitUniversal(&goredis.Config{
+			Mode:            redisCfg.Mode,
+			Addrs:           redisCfg.Addrs,
+			MasterName:      redisCfg.MasterName,
+			Username:        redisCfg.Username,
+			Password:        redisCfg.Password,
+			DB:              redisCfg.DB,
+			ClientName:      redisCfg.ClientName,
+			PoolSize:        redisCfg.PoolSize,
+			MinIdleConns:    redisCfg.MinIdleConns,
+			ReadFromReplica: redisCfg.ReadFromReplica,
+		}, opts...)
+		if err != nil {
+			panic("goredis.InitUniversal error: " + err.Error())
+		}
+		return
+	}
+
 	redisCli, err = goredis.Init(redisCfg.Dsn, opts...)
 	if err != nil {
 		panic("goredis.Init error: " + err.Error())
 	}
 }
 
-// GetRedisCli get redis client
-func GetRedisCli() *goredis.Client {
+// GetRedisCli get redis client, it is *goredis.Client in single and sentinel mode, *goredis.ClusterClient in cluster mode
+func GetRedisCli() goredis.UniversalClient {
 	if redisCli == nil {
 		redisCliOnce.Do(func() {
 			InitRedis()
@@ -83,5 +107,10 @@ func GetRedisCli() *goredis.Client {
 
 // CloseRedis close redis
 func CloseRedis() error {
-	return goredis.Close(redisCli)
+	return goredis.CloseUniversal(redisCli)
+}
+
+// RedisHealthCheck the health check of redis for the readiness probe
+func RedisHealthCheck() func(ctx context.Context) error {
+	return goredis.HealthCheck(GetRedisCli(), 3*time.Second)
 }
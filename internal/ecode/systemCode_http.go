@@ -24,6 +24,7 @@ var (
 	AccessDenied       = errcode.AccessDenied
 	MethodNotAllowed   = errcode.MethodNotAllowed
 	ServiceUnavailable = errcode.ServiceUnavailable
+	GatewayTimeout     = errcode.GatewayTimeout
 
 	Canceled           = errcode.Canceled
 	Unknown            = errcode.Unknown
@@ -11,11 +11,12 @@ var (
 	userExampleName     = "userExample"
 	userExampleBaseCode = errcode.HCode(userExampleNO)
 
-	ErrCreateUserExample     = errcode.NewError(userExampleBaseCode+1, "failed to create "+userExampleName)
-	ErrDeleteByIDUserExample = errcode.NewError(userExampleBaseCode+2, "failed to delete "+userExampleName)
-	ErrUpdateByIDUserExample = errcode.NewError(userExampleBaseCode+3, "failed to update "+userExampleName)
-	ErrGetByIDUserExample    = errcode.NewError(userExampleBaseCode+4, "failed to get "+userExampleName+" details")
-	ErrListUserExample       = errcode.NewError(userExampleBaseCode+5, "failed to list of "+userExampleName)
+	ErrCreateUserExample       = errcode.NewError(userExampleBaseCode+1, "failed to create "+userExampleName)
+	ErrDeleteByIDUserExample   = errcode.NewError(userExampleBaseCode+2, "failed to delete "+userExampleName)
+	ErrUpdateByIDUserExample   = errcode.NewError(userExampleBaseCode+3, "failed to update "+userExampleName)
+	ErrGetByIDUserExample      = errcode.NewError(userExampleBaseCode+4, "failed to get "+userExampleName+" details")
+	ErrListUserExample         = errcode.NewError(userExampleBaseCode+5, "failed to list of "+userExampleName)
+	ErrListActivityUserExample = errcode.NewError(userExampleBaseCode+6, "failed to list activities of "+userExampleName)
 
 	// error codes are globally unique, adding 1 to the previous error code
 )
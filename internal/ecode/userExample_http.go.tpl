@@ -16,6 +16,7 @@ var (
 	ErrUpdateBy{{.ColumnNameCamel}}{{.TableNameCamel}} = errcode.NewError({{.TableNameCamelFCL}}BaseCode+3, "failed to update "+{{.TableNameCamelFCL}}Name)
 	ErrGetBy{{.ColumnNameCamel}}{{.TableNameCamel}}    = errcode.NewError({{.TableNameCamelFCL}}BaseCode+4, "failed to get "+{{.TableNameCamelFCL}}Name+" details")
 	ErrList{{.TableNameCamel}}       = errcode.NewError({{.TableNameCamelFCL}}BaseCode+5, "failed to list of "+{{.TableNameCamelFCL}}Name)
+	ErrListActivity{{.TableNameCamel}} = errcode.NewError({{.TableNameCamelFCL}}BaseCode+6, "failed to list activities of "+{{.TableNameCamelFCL}}Name)
 
 	// error codes are globally unique, adding 1 to the previous error code
 )
@@ -0,0 +1,21 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
+	"github.com/go-dev-frame/sponge/pkg/gin/response"
+	"github.com/go-dev-frame/sponge/pkg/logger"
+	"github.com/go-dev-frame/sponge/pkg/utils"
+)
+
+// respond 504 with the code of ecode.GatewayTimeout if the deadline budget of the request is exhausted before the
+// dao completes, e.g. the client has given up waiting, see middleware.RequestBudget
+func isBudgetExhausted(c *gin.Context, err error, msg string) bool {
+	if !utils.IsBudgetExhausted(err) {
+		return false
+	}
+	logger.Warn(msg, logger.Err(err), middleware.GCtxRequestIDField(c))
+	response.Error(c, utils.ErrBudgetExhausted)
+	return true
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
+	"github.com/go-dev-frame/sponge/pkg/sgorm/query"
+	"github.com/go-dev-frame/sponge/pkg/utils"
+
+	"github.com/go-dev-frame/sponge/internal/dao"
+	"github.com/go-dev-frame/sponge/internal/ecode"
+	"github.com/go-dev-frame/sponge/internal/model"
+)
+
+// budgetUserExampleDao the query derives its timeout from the budget and runs until it expires
+type budgetUserExampleDao struct {
+	dao.UserExampleDao
+	requestDeadline time.Time
+	callDeadline    time.Time
+}
+
+func (d *budgetUserExampleDao) GetByID(ctx context.Context, _ uint64) (*model.UserExample, error) {
+	d.requestDeadline, _ = ctx.Deadline()
+	callCtx, cancel, err := utils.CallContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	d.callDeadline, _ = callCtx.Deadline()
+	<-callCtx.Done()
+	return nil, callCtx.Err()
+}
+
+func (d *budgetUserExampleDao) GetLastModified(_ context.Context) (time.Time, error) {
+	return time.Time{}, nil
+}
+
+func (d *budgetUserExampleDao) GetByColumns(_ context.Context, _ *query.Params) ([]*model.UserExample, int64, error) {
+	return nil, 0, utils.ErrBudgetExhausted
+}
+
+func Test_userExampleHandler_Budget(t *testing.T) {
+	d := &budgetUserExampleDao{}
+	h := &userExampleHandler{iDao: d}
+
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(middleware.RequestBudget(middleware.WithBudgetMaxTimeout(time.Second)))
+	r.GET("/userExample/:id", h.GetByID)
+	r.POST("/userExample/list", h.List)
+
+	// the deadline shrinks through the layers
+	req := httptest.NewRequest(http.MethodGet, "/userExample/1", nil)
+	req.Header.Set(middleware.HeaderRequestTimeoutKey, "200ms")
+	start := time.Now()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assertErrorCode(t, w, ecode.GatewayTimeout)
+	assert.WithinDuration(t, start.Add(200*time.Millisecond), d.requestDeadline, 20*time.Millisecond)
+	assert.True(t, d.callDeadline.Before(d.requestDeadline))
+	assert.True(t, time.Since(start) < 200*time.Millisecond, time.Since(start))
+
+	req = httptest.NewRequest(http.MethodPost, "/userExample/list", strings.NewReader(`{"page":0,"limit":10}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assertErrorCode(t, w, ecode.GatewayTimeout)
+}
@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"sync"
+
+	"github.com/go-dev-frame/sponge/pkg/utils"
+)
+
+var (
+	eventBus     *utils.EventBus
+	eventBusOnce sync.Once
+)
+
+// EventBus the in-process event bus of the record events, the topic is <resource>.<action>, e.g. userExample.updated,
+// the real-time features subscribe to it, e.g. the websocket hub of userExample subscribes to userExample.*
+func EventBus() *utils.EventBus {
+	eventBusOnce.Do(func() {
+		var err error
+		eventBus, err = utils.NewEventBus(utils.WithEventBusName("handler"))
+		if err != nil { // only the bridge fails to start
+			panic(err)
+		}
+	})
+	return eventBus
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/go-dev-frame/sponge/pkg/featureflag"
+	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
+	"github.com/go-dev-frame/sponge/pkg/gin/response"
+
+	"github.com/go-dev-frame/sponge/internal/config"
+	"github.com/go-dev-frame/sponge/internal/ecode"
+)
+
+// the feature flags of the routes, all the flags are disabled until SetFeatureFlags is called
+var featureFlags = featureflag.New(nil)
+
+// SetFeatureFlags replace the feature flags by the configuration, it is called on initialization and when the
+// config in nacos is changed, the requests after the call are evaluated by the new flags.
+func SetFeatureFlags(flags []config.FeatureFlag) {
+	ffs := make([]featureflag.Flag, 0, len(flags))
+	for _, flag := range flags {
+		ffs = append(ffs, featureflag.Flag{
+			Name:       flag.Name,
+			Enabled:    flag.Enabled,
+			Percentage: flag.Percentage,
+		})
+	}
+	featureFlags.Set(ffs)
+}
+
+// RequireFlag the middleware responding 404 if the feature flag is disabled for the request, the route looks
+// like it does not exist before it is launched, e.g.
+//
+//	g.GET("/userExample/cursor", handler.RequireFlag("userExample.cursorList"), h.CursorList)
+func RequireFlag(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !FlagEnabled(c, name) {
+			response.Out(c, ecode.NotFound)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// FlagEnabled evaluate the feature flag for the request in the handler, the percentage rollout is keyed by
+// the tenant id, or the user id if there is no tenant, the requests without jwt claims are not in the rollout.
+func FlagEnabled(c *gin.Context, name string) bool {
+	return featureFlags.Enabled(name, flagKey(c))
+}
+
+func flagKey(c *gin.Context) string {
+	if tenantID := GetTenantID(c); tenantID != "" {
+		return tenantID
+	}
+	if claims, ok := middleware.GetClaims(c); ok {
+		return claims.UID
+	}
+	return ""
+}
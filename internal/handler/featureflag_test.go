@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-dev-frame/sponge/pkg/featureflag"
+	"github.com/go-dev-frame/sponge/pkg/jwt"
+
+	"github.com/go-dev-frame/sponge/internal/config"
+)
+
+func TestRequireFlag(t *testing.T) {
+	defer SetFeatureFlags(nil)
+
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if uid := c.GetHeader("X-Uid"); uid != "" {
+			c.Set("claims", &jwt.Claims{UID: uid, Fields: map[string]interface{}{tenantIDField: c.GetHeader("X-Tenant-Id")}})
+		}
+	})
+	r.GET("/cursor", RequireFlag("userExample.cursorList"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	r.GET("/list", func(c *gin.Context) {
+		if FlagEnabled(c, "userExample.cursorList") {
+			c.String(http.StatusOK, "cursor")
+			return
+		}
+		c.String(http.StatusOK, "page")
+	})
+	serve := func(path string, uid string, tenantID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Uid", uid)
+		req.Header.Set("X-Tenant-Id", tenantID)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// the flag is not configured
+	assert.Equal(t, http.StatusNotFound, serve("/cursor", "1", "").Code)
+	assert.Equal(t, "page", serve("/list", "1", "").Body.String())
+
+	// the flag is enabled live
+	SetFeatureFlags([]config.FeatureFlag{{Name: "userExample.cursorList", Enabled: true}})
+	assert.Equal(t, http.StatusOK, serve("/cursor", "1", "").Code)
+	assert.Equal(t, http.StatusOK, serve("/cursor", "", "").Code)
+	assert.Equal(t, "cursor", serve("/list", "1", "").Body.String())
+
+	// the flag is disabled live
+	SetFeatureFlags([]config.FeatureFlag{{Name: "userExample.cursorList", Enabled: false}})
+	assert.Equal(t, http.StatusNotFound, serve("/cursor", "1", "").Code)
+
+	// the percentage rollout by the tenant id, or the user id if there is no tenant
+	SetFeatureFlags([]config.FeatureFlag{{Name: "userExample.cursorList", Enabled: true, Percentage: 30}})
+	for i := 0; i < 20; i++ {
+		id := strconv.Itoa(i)
+		expected := http.StatusNotFound
+		if featureflag.Bucket("userExample.cursorList", "t"+id) < 30 {
+			expected = http.StatusOK
+		}
+		assert.Equal(t, expected, serve("/cursor", "1", "t"+id).Code)
+
+		expected = http.StatusNotFound
+		if featureflag.Bucket("userExample.cursorList", id) < 30 {
+			expected = http.StatusOK
+		}
+		assert.Equal(t, expected, serve("/cursor", id, "").Code)
+	}
+	assert.Equal(t, http.StatusNotFound, serve("/cursor", "", "").Code)
+}
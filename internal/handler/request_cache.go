@@ -0,0 +1,20 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/go-dev-frame/sponge/internal/dao"
+)
+
+// RequestCache the middleware memoizing the records got by id within the request, opt-in per route, e.g. the
+// handlers fetching the same record by different code paths, see dao.WithRequestCache.
+// The memoized records are cleared at the end of the request.
+func RequestCache() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := dao.WithRequestCache(c.Request.Context())
+		c.Request = c.Request.WithContext(ctx)
+		defer dao.ClearRequestCache(ctx)
+
+		c.Next()
+	}
+}
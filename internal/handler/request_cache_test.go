@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
+	"github.com/go-dev-frame/sponge/pkg/gotest"
+
+	"github.com/go-dev-frame/sponge/internal/dao"
+)
+
+func TestRequestCache(t *testing.T) {
+	d := gotest.NewDao(nil, nil)
+	defer d.Close()
+	iDao := dao.NewUserExampleDao(d.DB, nil)
+
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	// the record is fetched twice by the different code paths of the handler
+	handle := func(c *gin.Context) {
+		for i := 0; i < 2; i++ {
+			if _, err := iDao.GetByID(middleware.WrapCtx(c), 1); err != nil {
+				c.Status(http.StatusInternalServerError)
+				return
+			}
+		}
+		c.Status(http.StatusOK)
+	}
+	r.GET("/cached", RequestCache(), handle)
+	r.GET("/uncached", handle)
+	serve := func(path string) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Code
+	}
+	expectQuery := func() {
+		d.SQLMock.ExpectQuery("SELECT .*").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	}
+
+	// one query per request
+	for i := 0; i < 2; i++ {
+		expectQuery()
+		assert.Equal(t, http.StatusOK, serve("/cached"))
+		assert.NoError(t, d.SQLMock.ExpectationsWereMet())
+	}
+
+	// the routes without the middleware
+	expectQuery()
+	expectQuery()
+	assert.Equal(t, http.StatusOK, serve("/uncached"))
+	assert.NoError(t, d.SQLMock.ExpectationsWereMet())
+}
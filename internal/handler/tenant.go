@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
+
+	"github.com/go-dev-frame/sponge/internal/cache"
+)
+
+// the key of the tenant id in the custom fields of the jwt claims
+const tenantIDField = "tenantID"
+
+// GetTenantID get the tenant id from the jwt claims, it is empty if there is no tenant
+func GetTenantID(c *gin.Context) string {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		return ""
+	}
+	tenantID, _ := claims.GetString(tenantIDField)
+	return tenantID
+}
+
+// GetCallerKey get the key of the caller by the tenant id and the Authorization header, e.g. the vary of
+// middleware.Dedup, so the responses depending on the tenant or the permissions of the user are not shared
+// with the other callers
+func GetCallerKey(c *gin.Context) string {
+	return GetTenantID(c) + "|" + c.GetHeader("Authorization")
+}
+
+// wrapTenantCtx wrap the context of the request with the tenant id, the writes through it update the
+// last modified time of the tenant as well as the resource-global one
+func wrapTenantCtx(c *gin.Context) context.Context {
+	return cache.WithTenant(middleware.WrapCtx(c), GetTenantID(c))
+}
@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-dev-frame/sponge/pkg/jwt"
+)
+
+func newTenantContext(tenantID string, authorization string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	if authorization != "" {
+		c.Request.Header.Set("Authorization", authorization)
+	}
+	if tenantID != "" {
+		c.Set("claims", &jwt.Claims{UID: "100", Fields: map[string]interface{}{tenantIDField: tenantID}})
+	}
+	return c
+}
+
+func TestGetTenantID(t *testing.T) {
+	assert.Equal(t, "t1", GetTenantID(newTenantContext("t1", "")))
+	assert.Equal(t, "", GetTenantID(newTenantContext("", "")))
+}
+
+func TestGetCallerKey(t *testing.T) {
+	key := GetCallerKey(newTenantContext("t1", "Bearer token1"))
+	assert.Equal(t, key, GetCallerKey(newTenantContext("t1", "Bearer token1")))
+
+	// the users of the same tenant and the tenants of the same user are different callers
+	assert.NotEqual(t, key, GetCallerKey(newTenantContext("t1", "Bearer token2")))
+	assert.NotEqual(t, key, GetCallerKey(newTenantContext("t2", "Bearer token1")))
+	assert.NotEqual(t, GetCallerKey(newTenantContext("t1", "")), GetCallerKey(newTenantContext("", "")))
+}
@@ -2,13 +2,16 @@ package handler
 
 import (
 	"errors"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/copier"
 
 	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
 	"github.com/go-dev-frame/sponge/pkg/gin/response"
+	"github.com/go-dev-frame/sponge/pkg/gin/validator"
 	"github.com/go-dev-frame/sponge/pkg/logger"
+	"github.com/go-dev-frame/sponge/pkg/sgorm/query"
 	"github.com/go-dev-frame/sponge/pkg/utils"
 
 	"github.com/go-dev-frame/sponge/internal/cache"
@@ -28,10 +31,34 @@ type UserExampleHandler interface {
 	UpdateByID(c *gin.Context)
 	GetByID(c *gin.Context)
 	List(c *gin.Context)
+	// delete the templates code start
+	Archive(c *gin.Context)
+	Unarchive(c *gin.Context)
+	// delete the templates code end
+	Distinct(c *gin.Context)
+	Activity(c *gin.Context)
+	Aggregate(c *gin.Context)
 }
 
 type userExampleHandler struct {
 	iDao dao.UserExampleDao
+
+	// reject the unknown json fields of create and update requests with 400, default false
+	strictJSON bool
+
+	// the soft quota of the records per tenant, nil means disabled, it is enabled by the quota configuration
+	quota *userExampleQuota
+
+	// the store of the activity entries of the writes, nil means disabled, e.g. activityDao: UserExampleActivityDao()
+	activityDao dao.UserExampleActivityDao
+
+	// the tolerated clock skew of the last modified time of List, see checkNotModified
+	lastModifiedSkew time.Duration
+	// the clock of checkNotModified, nil means time.Now
+	now func() time.Time
+
+	// the includes of Aggregate by name, nil means the default includes of the related resources, see defaultIncludes
+	includes map[string]UserExampleInclude
 }
 
 // NewUserExampleHandler creating the handler interface
@@ -41,6 +68,8 @@ func NewUserExampleHandler() UserExampleHandler {
 			database.GetDB(), // todo show db driver name here
 			cache.NewUserExampleCache(database.GetCacheType()),
 		),
+		quota:            mustUserExampleQuota(),
+		lastModifiedSkew: defaultUserExampleLastModifiedSkew,
 	}
 }
 
@@ -56,28 +85,38 @@ func NewUserExampleHandler() UserExampleHandler {
 // @Security BearerAuth
 func (h *userExampleHandler) Create(c *gin.Context) {
 	form := &types.CreateUserExampleRequest{}
-	err := c.ShouldBindJSON(form)
-	if err != nil {
-		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
-		response.Error(c, ecode.InvalidParams)
+	if isAbort := h.bindJSON(c, form); isAbort {
 		return
 	}
 
 	userExample := &model.UserExample{}
-	err = copier.Copy(userExample, form)
+	err := copier.Copy(userExample, form)
 	if err != nil {
 		response.Error(c, ecode.ErrCreateUserExample)
 		return
 	}
 	// Note: if copier.Copy cannot assign a value to a field, add it here
 
-	ctx := middleware.WrapCtx(c)
-	err = h.iDao.Create(ctx, userExample)
+	tenantID := GetTenantID(c)
+	if isAbort := h.reserveQuota(c, tenantID, 1); isAbort {
+		return
+	}
+
+	ctx := wrapTenantCtx(c)
+	err = h.quota.setTenant(ctx, userExample, tenantID)
+	if err == nil {
+		err = h.iDao.Create(ctx, userExample)
+	}
 	if err != nil {
+		h.releaseQuota(c, tenantID, 1)
 		logger.Error("Create error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
 		response.Output(c, ecode.InternalServerError.ToHTTPCode())
 		return
 	}
+	h.recordActivity(c, ctx, userExampleActionCreated, nil, userExample)
+	// delete the templates code start
+	publishUserExampleEvent(c, userExampleActionCreated, userExample.ID)
+	// delete the templates code end
 
 	response.Success(c, gin.H{"id": userExample.ID})
 }
@@ -99,13 +138,23 @@ func (h *userExampleHandler) DeleteByID(c *gin.Context) {
 		return
 	}
 
-	ctx := middleware.WrapCtx(c)
-	err := h.iDao.DeleteByID(ctx, id)
+	ctx := wrapTenantCtx(c)
+	before := h.activitySnapshot(c, ctx, id)
+	deleted, err := h.iDao.DeleteExistingByID(ctx, id)
 	if err != nil {
 		logger.Error("DeleteByID error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
 		response.Output(c, ecode.InternalServerError.ToHTTPCode())
 		return
 	}
+	if deleted {
+		h.releaseQuota(c, GetTenantID(c), 1)
+	}
+	h.recordActivity(c, ctx, userExampleActionDeleted, before, nil)
+	// delete the templates code start
+	if deleted {
+		publishUserExampleEvent(c, userExampleActionDeleted, id)
+	}
+	// delete the templates code end
 
 	response.Success(c)
 }
@@ -129,29 +178,48 @@ func (h *userExampleHandler) UpdateByID(c *gin.Context) {
 	}
 
 	form := &types.UpdateUserExampleByIDRequest{}
-	err := c.ShouldBindJSON(form)
-	if err != nil {
-		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
-		response.Error(c, ecode.InvalidParams)
+	if isAbort := h.bindJSON(c, form); isAbort {
 		return
 	}
 	form.ID = id
 
 	userExample := &model.UserExample{}
-	err = copier.Copy(userExample, form)
+	err := copier.Copy(userExample, form)
 	if err != nil {
 		response.Error(c, ecode.ErrUpdateByIDUserExample)
 		return
 	}
 	// Note: if copier.Copy cannot assign a value to a field, add it here
 
-	ctx := middleware.WrapCtx(c)
+	ctx := wrapTenantCtx(c)
+	before := h.activitySnapshot(c, ctx, id)
+	// delete the templates code start
+	// the status is changed by the state machine together with the other fields in one conditional update,
+	// illegal transitions and concurrent changes of the status are rejected with 409
+	if form.Status != 0 {
+		isUpdated, isAbort := h.changeStatus(c, ctx, userExample, 0)
+		if isAbort {
+			return
+		}
+		if isUpdated {
+			h.recordActivity(c, ctx, userExampleActionUpdated, before, h.activitySnapshot(c, ctx, id))
+			publishUserExampleEvent(c, userExampleActionUpdated, id)
+			response.Success(c)
+			return
+		}
+		userExample.Status = 0 // the status is unchanged
+	}
+	// delete the templates code end
 	err = h.iDao.UpdateByID(ctx, userExample)
 	if err != nil {
 		logger.Error("UpdateByID error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
 		response.Output(c, ecode.InternalServerError.ToHTTPCode())
 		return
 	}
+	h.recordActivity(c, ctx, userExampleActionUpdated, before, h.activitySnapshot(c, ctx, id))
+	// delete the templates code start
+	publishUserExampleEvent(c, userExampleActionUpdated, id)
+	// delete the templates code end
 
 	response.Success(c)
 }
@@ -176,10 +244,12 @@ func (h *userExampleHandler) GetByID(c *gin.Context) {
 	ctx := middleware.WrapCtx(c)
 	userExample, err := h.iDao.GetByID(ctx, id)
 	if err != nil {
-		if errors.Is(err, database.ErrRecordNotFound) {
+		switch {
+		case errors.Is(err, database.ErrRecordNotFound):
 			logger.Warn("GetByID not found", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
 			response.Error(c, ecode.NotFound)
-		} else {
+		case isBudgetExhausted(c, err, "GetByID budget exhausted"):
+		default:
 			logger.Error("GetByID error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
 			response.Output(c, ecode.InternalServerError.ToHTTPCode())
 		}
@@ -199,27 +269,34 @@ func (h *userExampleHandler) GetByID(c *gin.Context) {
 
 // List of records by query parameters
 // @Summary list of userExamples by query parameters
-// @Description list of userExamples by paging and conditions
+// @Description list of userExamples by paging and conditions, 304 if not modified since If-Modified-Since
 // @Tags userExample
 // @accept json
 // @Produce json
 // @Param data body types.Params true "query parameters"
+// @Param If-Modified-Since header string false "the Last-Modified of the previous response"
 // @Success 200 {object} types.ListUserExamplesReply{}
+// @Success 304 "not modified"
 // @Router /api/v1/userExample/list [post]
 // @Security BearerAuth
 func (h *userExampleHandler) List(c *gin.Context) {
-	form := &types.ListUserExamplesRequest{}
-	err := c.ShouldBindJSON(form)
+	params, err := query.BindParams(c)
 	if err != nil {
-		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+		logger.Warn("query.BindParams error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
 		response.Error(c, ecode.InvalidParams)
 		return
 	}
 
-	ctx := middleware.WrapCtx(c)
-	userExamples, total, err := h.iDao.GetByColumns(ctx, &form.Params)
+	ctx := wrapTenantCtx(c)
+	if isNotModified := h.checkNotModified(c, ctx); isNotModified {
+		return
+	}
+	userExamples, total, err := h.iDao.GetByColumns(ctx, params)
 	if err != nil {
-		logger.Error("GetByColumns error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
+		if isBudgetExhausted(c, err, "GetByColumns budget exhausted") {
+			return
+		}
+		logger.Error("GetByColumns error", logger.Err(err), logger.Any("params", params), middleware.GCtxRequestIDField(c))
 		response.Output(c, ecode.InternalServerError.ToHTTPCode())
 		return
 	}
@@ -230,10 +307,7 @@ func (h *userExampleHandler) List(c *gin.Context) {
 		return
 	}
 
-	response.Success(c, gin.H{
-		"userExamples": data,
-		"total":        total,
-	})
+	response.Success(c, response.NewPageResult(data, total, params.Page, params.Limit))
 }
 
 func getUserExampleIDFromPath(c *gin.Context) (string, uint64, bool) {
@@ -247,6 +321,27 @@ func getUserExampleIDFromPath(c *gin.Context) (string, uint64, bool) {
 	return idStr, id, false
 }
 
+// bindJSON bind the json body of the request, unknown fields are rejected if strictJSON is true
+func (h *userExampleHandler) bindJSON(c *gin.Context, form interface{}) bool {
+	var err error
+	if h.strictJSON {
+		err = validator.ShouldBindJSONStrict(c, form)
+	} else {
+		err = c.ShouldBindJSON(form)
+	}
+	if err != nil {
+		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+		var unknownErr *validator.UnknownFieldsError
+		if errors.As(err, &unknownErr) {
+			response.Error(c, unknownErr) // 400 listing the unknown fields
+			return true
+		}
+		response.Error(c, ecode.InvalidParams)
+		return true
+	}
+	return false
+}
+
 func convertUserExample(userExample *model.UserExample) (*types.UserExampleObjDetail, error) {
 	data := &types.UserExampleObjDetail{}
 	err := copier.Copy(data, userExample)
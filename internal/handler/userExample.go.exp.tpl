@@ -2,6 +2,7 @@ package handler
 
 import (
 	"errors"
+	"fmt"
 	"math"
 
 	"github.com/gin-gonic/gin"
@@ -9,7 +10,9 @@ import (
 
 	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
 	"github.com/go-dev-frame/sponge/pkg/gin/response"
+	"github.com/go-dev-frame/sponge/pkg/gin/validator"
 	"github.com/go-dev-frame/sponge/pkg/logger"
+	"github.com/go-dev-frame/sponge/pkg/sgorm/query"
 	"github.com/go-dev-frame/sponge/pkg/utils"
 
 	"github.com/go-dev-frame/sponge/internal/cache"
@@ -38,6 +41,9 @@ type {{.TableNameCamel}}Handler interface {
 
 type {{.TableNameCamelFCL}}Handler struct {
 	iDao dao.{{.TableNameCamel}}Dao
+
+	// reject the unknown json fields of create and update requests with 400, default false
+	strictJSON bool
 }
 
 // New{{.TableNameCamel}}Handler creating the handler interface
@@ -62,15 +68,12 @@ func New{{.TableNameCamel}}Handler() {{.TableNameCamel}}Handler {
 // @Security BearerAuth
 func (h *{{.TableNameCamelFCL}}Handler) Create(c *gin.Context) {
 	form := &types.Create{{.TableNameCamel}}Request{}
-	err := c.ShouldBindJSON(form)
-	if err != nil {
-		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
-		response.Error(c, ecode.InvalidParams)
+	if isAbort := h.bindJSON(c, form); isAbort {
 		return
 	}
 
 	{{.TableNameCamelFCL}} := &model.{{.TableNameCamel}}{}
-	err = copier.Copy({{.TableNameCamelFCL}}, form)
+	err := copier.Copy({{.TableNameCamelFCL}}, form)
 	if err != nil {
 		response.Error(c, ecode.ErrCreate{{.TableNameCamel}})
 		return
@@ -135,16 +138,13 @@ func (h *{{.TableNameCamelFCL}}Handler) UpdateBy{{.ColumnNameCamel}}(c *gin.Cont
 	}
 
 	form := &types.Update{{.TableNameCamel}}By{{.ColumnNameCamel}}Request{}
-	err := c.ShouldBindJSON(form)
-	if err != nil {
-		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
-		response.Error(c, ecode.InvalidParams)
+	if isAbort := h.bindJSON(c, form); isAbort {
 		return
 	}
 	form.{{.ColumnNameCamel}} = {{.ColumnNameCamelFCL}}
 
 	{{.TableNameCamelFCL}} := &model.{{.TableNameCamel}}{}
-	err = copier.Copy({{.TableNameCamelFCL}}, form)
+	err := copier.Copy({{.TableNameCamelFCL}}, form)
 	if err != nil {
 		response.Error(c, ecode.ErrUpdateBy{{.ColumnNameCamel}}{{.TableNameCamel}})
 		return
@@ -214,18 +214,17 @@ func (h *{{.TableNameCamelFCL}}Handler) GetBy{{.ColumnNameCamel}}(c *gin.Context
 // @Router /api/v1/{{.TableNameCamelFCL}}/list [post]
 // @Security BearerAuth
 func (h *{{.TableNameCamelFCL}}Handler) List(c *gin.Context) {
-	form := &types.List{{.TableNamePluralCamel}}Request{}
-	err := c.ShouldBindJSON(form)
+	params, err := query.BindParams(c)
 	if err != nil {
-		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+		logger.Warn("query.BindParams error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
 		response.Error(c, ecode.InvalidParams)
 		return
 	}
 
 	ctx := middleware.WrapCtx(c)
-	{{.TableNamePluralCamelFCL}}, total, err := h.iDao.GetByColumns(ctx, &form.Params)
+	{{.TableNamePluralCamelFCL}}, total, err := h.iDao.GetByColumns(ctx, params)
 	if err != nil {
-		logger.Error("GetByColumns error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
+		logger.Error("GetByColumns error", logger.Err(err), logger.Any("params", params), middleware.GCtxRequestIDField(c))
 		response.Output(c, ecode.InternalServerError.ToHTTPCode())
 		return
 	}
@@ -236,10 +235,7 @@ func (h *{{.TableNameCamelFCL}}Handler) List(c *gin.Context) {
 		return
 	}
 
-	response.Success(c, gin.H{
-		"{{.TableNamePluralCamelFCL}}": data,
-		"total":        total,
-	})
+	response.Success(c, response.NewPageResult(data, total, params.Page, params.Limit))
 }
 
 // DeleteBy{{.ColumnNamePluralCamel}} delete records by batch {{.ColumnNameCamelFCL}}
@@ -405,9 +401,12 @@ func (h *{{.TableNameCamelFCL}}Handler) ListByLast{{.ColumnNameCamel}}(c *gin.Co
 		return
 	}
 
-	response.Success(c, gin.H{
-		"{{.TableNamePluralCamelFCL}}": data,
-	})
+	nextCursor := ""
+	if len(data) == limit {
+		nextCursor = fmt.Sprintf("%v", data[len(data)-1].{{.ColumnNameCamel}})
+	}
+
+	response.Success(c, response.NewCursorPageResult(data, nextCursor))
 }
 
 func get{{.TableNameCamel}}{{.ColumnNameCamel}}FromPath(c *gin.Context) ({{.GoType}}, bool) {
@@ -428,6 +427,27 @@ func get{{.TableNameCamel}}{{.ColumnNameCamel}}FromPath(c *gin.Context) ({{.GoTy
 {{end}}
 }
 
+// bindJSON bind the json body of the request, unknown fields are rejected if strictJSON is true
+func (h *{{.TableNameCamelFCL}}Handler) bindJSON(c *gin.Context, form interface{}) bool {
+	var err error
+	if h.strictJSON {
+		err = validator.ShouldBindJSONStrict(c, form)
+	} else {
+		err = c.ShouldBindJSON(form)
+	}
+	if err != nil {
+		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+		var unknownErr *validator.UnknownFieldsError
+		if errors.As(err, &unknownErr) {
+			response.Error(c, unknownErr) // 400 listing the unknown fields
+			return true
+		}
+		response.Error(c, ecode.InvalidParams)
+		return true
+	}
+	return false
+}
+
 func convert{{.TableNameCamel}}({{.TableNameCamelFCL}} *model.{{.TableNameCamel}}) (*types.{{.TableNameCamel}}ObjDetail, error) {
 	data := &types.{{.TableNameCamel}}ObjDetail{}
 	err := copier.Copy(data, {{.TableNameCamelFCL}})
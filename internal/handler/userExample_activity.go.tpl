@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
+	"github.com/go-dev-frame/sponge/pkg/gin/response"
+	"github.com/go-dev-frame/sponge/pkg/gocron"
+	"github.com/go-dev-frame/sponge/pkg/logger"
+
+	"github.com/go-dev-frame/sponge/internal/dao"
+	"github.com/go-dev-frame/sponge/internal/database"
+	"github.com/go-dev-frame/sponge/internal/ecode"
+	"github.com/go-dev-frame/sponge/internal/model"
+	"github.com/go-dev-frame/sponge/internal/types"
+)
+
+// the actions of the activity entries
+const (
+	{{.TableNameCamelFCL}}ActionCreated = "created"
+	{{.TableNameCamelFCL}}ActionUpdated = "updated"
+	{{.TableNameCamelFCL}}ActionDeleted = "deleted"
+)
+
+const (
+	default{{.TableNameCamel}}ActivityLimit     = 10
+	default{{.TableNameCamel}}ActivityRetention = 90 * 24 * time.Hour
+)
+
+// the fields maintained by the database are not the changes
+var {{.TableNameCamelFCL}}ActivityIgnoredFields = map[string]bool{
+	"{{.ColumnNameCamelFCL}}": true,
+	"createdAt": true,
+	"updatedAt": true,
+}
+
+// {{.TableNameCamel}}ActivityDao the store of the activity entries of the {{.TableNameCamelFCL}} records, it is enabled in the
+// handler by setting the activityDao field, and the expired entries are deleted periodically by
+//
+//	gocron.Run({{.TableNameCamel}}ActivityCleanupTask({{.TableNameCamel}}ActivityDao(), 0))
+func {{.TableNameCamel}}ActivityDao() dao.{{.TableNameCamel}}ActivityDao {
+	return dao.New{{.TableNameCamel}}ActivityDao(database.GetDB())
+}
+
+// {{.TableNameCamel}}ActivityCleanupTask the periodic task of deleting the activity entries older than the retention,
+// the default retention is 90 days if it is not greater than 0
+func {{.TableNameCamel}}ActivityCleanupTask(activityDao dao.{{.TableNameCamel}}ActivityDao, retention time.Duration) *gocron.Task {
+	if retention <= 0 {
+		retention = default{{.TableNameCamel}}ActivityRetention
+	}
+	return &gocron.Task{
+		Name:     "{{.TableNameCamelFCL}}-activity-cleanup",
+		TimeSpec: gocron.EveryHour(1),
+		Fn: func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			n, err := activityDao.DeleteBefore(ctx, time.Now().Add(-retention))
+			if err != nil {
+				logger.Warn("activity cleanup error", logger.String("resource", "{{.TableNameCamelFCL}}"), logger.Err(err))
+				return
+			}
+			logger.Info("activity cleaned up", logger.String("resource", "{{.TableNameCamelFCL}}"), logger.Int64("deleted", n))
+		},
+	}
+}
+
+// Activity list the activity of a record
+// @Summary list {{.TableNameCamelFCL}} activity
+// @Description list the writes of {{.TableNameCamelFCL}} by {{.ColumnNameCamelFCL}}, the newest first, each entry has the actor, the action and
+// @Description the changed fields, the sensitive values are redacted by the rules of the logger, 404 if the activity is disabled
+// @Tags {{.TableNameCamelFCL}}
+// @Produce json
+// @Param {{.ColumnNameCamelFCL}} path string true "{{.ColumnNameCamelFCL}}"
+// @Param page query int false "page number, starts from 0"
+// @Param limit query int false "lines per page, default 10, max 100"
+// @Success 200 {object} types.List{{.TableNameCamel}}ActivitiesReply{}
+// @Router /api/v1/{{.TableNameCamelFCL}}/{{{.ColumnNameCamelFCL}}}/activity [get]
+// @Security BearerAuth
+func (h *{{.TableNameCamelFCL}}Handler) Activity(c *gin.Context) {
+	{{.ColumnNameCamelFCL}}, isAbort := get{{.TableNameCamel}}{{.ColumnNameCamel}}FromPath(c)
+	if isAbort {
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+	if h.activityDao == nil {
+		response.Error(c, ecode.NotFound)
+		return
+	}
+	form := &types.List{{.TableNameCamel}}ActivitiesRequest{}
+	if err := c.ShouldBindQuery(form); err != nil {
+		logger.Warn("ShouldBindQuery error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+	if form.Limit == 0 {
+		form.Limit = default{{.TableNameCamel}}ActivityLimit
+	}
+
+	records, total, err := h.activityDao.GetBy{{.TableNameCamel}}{{.ColumnNameCamel}}(middleware.WrapCtx(c), {{.ColumnNameCamelFCL}}, GetTenantID(c), form.Page, form.Limit)
+	if err != nil {
+		logger.Error("GetBy{{.TableNameCamel}}{{.ColumnNameCamel}} error", logger.Err(err), logger.Any("{{.ColumnNameCamelFCL}}", {{.ColumnNameCamelFCL}}), middleware.GCtxRequestIDField(c))
+		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		return
+	}
+
+	data := make([]*types.{{.TableNameCamel}}ActivityObjDetail, 0, len(records))
+	for _, record := range records {
+		detail := &types.{{.TableNameCamel}}ActivityObjDetail{
+			ID:        record.ID,
+			Actor:     record.Actor,
+			Action:    record.Action,
+			Changes:   []types.{{.TableNameCamel}}ActivityChange{},
+			CreatedAt: record.CreatedAt,
+		}
+		if record.Changes != "" {
+			if err = json.Unmarshal([]byte(record.Changes), &detail.Changes); err != nil {
+				logger.Error("Unmarshal changes error", logger.Err(err), logger.Any("id", record.ID), middleware.GCtxRequestIDField(c))
+				response.Error(c, ecode.ErrListActivity{{.TableNameCamel}})
+				return
+			}
+		}
+		data = append(data, detail)
+	}
+
+	response.Success(c, response.NewPageResult(data, total, form.Page, form.Limit))
+}
+
+// get the record before or after the write for the activity, nil if the activity is disabled or the record
+// is not found, the write is not failed by the activity
+func (h *{{.TableNameCamelFCL}}Handler) activitySnapshot(c *gin.Context, ctx context.Context, {{.ColumnNameCamelFCL}} {{.GoType}}) *model.{{.TableNameCamel}} {
+	if h.activityDao == nil {
+		return nil
+	}
+	record, err := h.iDao.GetBy{{.ColumnNameCamel}}(ctx, {{.ColumnNameCamelFCL}})
+	if err != nil {
+		logger.Warn("activity GetBy{{.ColumnNameCamel}} error", logger.Err(err), logger.Any("{{.ColumnNameCamelFCL}}", {{.ColumnNameCamelFCL}}), middleware.GCtxRequestIDField(c))
+		return nil
+	}
+	return record
+}
+
+// record the write of the record by the actor of the request, before is nil if the record is created, after is
+// nil if the record is deleted, the update without any change is not recorded, the error is only logged
+func (h *{{.TableNameCamelFCL}}Handler) recordActivity(c *gin.Context, ctx context.Context, action string, before, after *model.{{.TableNameCamel}}) {
+	if h.activityDao == nil {
+		return
+	}
+	record := after
+	if record == nil {
+		record = before
+	}
+	if record == nil || (action == {{.TableNameCamelFCL}}ActionUpdated && (before == nil || after == nil)) {
+		return
+	}
+
+	changes, err := diff{{.TableNameCamel}}(before, after)
+	if err != nil {
+		logger.Warn("activity diff error", logger.Err(err), logger.Any("{{.ColumnNameCamelFCL}}", record.{{.ColumnNameCamel}}), middleware.GCtxRequestIDField(c))
+		return
+	}
+	if action == {{.TableNameCamelFCL}}ActionUpdated && len(changes) == 0 {
+		return
+	}
+	data, err := json.Marshal(changes)
+	if err != nil {
+		logger.Warn("activity Marshal error", logger.Err(err), logger.Any("{{.ColumnNameCamelFCL}}", record.{{.ColumnNameCamel}}), middleware.GCtxRequestIDField(c))
+		return
+	}
+
+	actor := ""
+	if claims, ok := middleware.GetClaims(c); ok {
+		actor = claims.UID
+	}
+	err = h.activityDao.Create(ctx, &model.{{.TableNameCamel}}Activity{
+		{{.TableNameCamel}}{{.ColumnNameCamel}}: record.{{.ColumnNameCamel}},
+		TenantID:      GetTenantID(c),
+		Actor:         actor,
+		Action:        action,
+		Changes:       string(data),
+	})
+	if err != nil {
+		logger.Warn("activity Create error", logger.Err(err), logger.Any("{{.ColumnNameCamelFCL}}", record.{{.ColumnNameCamel}}), middleware.GCtxRequestIDField(c))
+	}
+}
+
+// the changed fields of the json names ordered by the name, the zero values are compared with the nil record,
+// and the old and new values are redacted by the rules of the logger separately, e.g. the partial redaction
+// of both values is kept
+func diff{{.TableNameCamel}}(before, after *model.{{.TableNameCamel}}) ([]types.{{.TableNameCamel}}ActivityChange, error) {
+	oldFields, err := to{{.TableNameCamel}}ActivityFields(before)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := to{{.TableNameCamel}}ActivityFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	oldValues, newValues := map[string]interface{}{}, map[string]interface{}{}
+	for field, newValue := range newFields {
+		if oldValue := oldFields[field]; !{{.TableNameCamelFCL}}ActivityIgnoredFields[field] && !reflect.DeepEqual(oldValue, newValue) {
+			oldValues[field], newValues[field] = oldValue, newValue
+		}
+	}
+	if len(newValues) == 0 {
+		return nil, nil
+	}
+	oldValues, _ = logger.RedactValue(oldValues).(map[string]interface{})
+	newValues, _ = logger.RedactValue(newValues).(map[string]interface{})
+
+	changes := make([]types.{{.TableNameCamel}}ActivityChange, 0, len(newValues))
+	for field := range newValues {
+		change := types.{{.TableNameCamel}}ActivityChange{Field: field}
+		if before != nil {
+			change.Old = oldValues[field]
+		}
+		if after != nil {
+			change.New = newValues[field]
+		}
+		changes = append(changes, change)
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes, nil
+}
+
+// the fields of the json names of the record, the nil record has the zero values
+func to{{.TableNameCamel}}ActivityFields(record *model.{{.TableNameCamel}}) (map[string]interface{}, error) {
+	if record == nil {
+		record = &model.{{.TableNameCamel}}{}
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	err = json.Unmarshal(data, &fields)
+	return fields, err
+}
@@ -0,0 +1,230 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/go-dev-frame/sponge/pkg/jwt"
+	"github.com/go-dev-frame/sponge/pkg/logger"
+
+	"github.com/go-dev-frame/sponge/internal/dao"
+	"github.com/go-dev-frame/sponge/internal/database"
+	"github.com/go-dev-frame/sponge/internal/ecode"
+	"github.com/go-dev-frame/sponge/internal/model"
+	"github.com/go-dev-frame/sponge/internal/types"
+)
+
+// the records are saved in the map, the update writes the non-zero fields like the gorm dao
+type fakeRecordsUserExampleDao struct {
+	dao.UserExampleDao
+	mu      sync.Mutex
+	lastID  uint64
+	records map[uint64]model.UserExample
+}
+
+func (d *fakeRecordsUserExampleDao) Create(_ context.Context, table *model.UserExample) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.lastID++
+	table.ID = d.lastID
+	d.records[table.ID] = *table
+	return nil
+}
+
+func (d *fakeRecordsUserExampleDao) CreateByTx(ctx context.Context, _ *gorm.DB, table *model.UserExample) (uint64, error) {
+	err := d.Create(ctx, table)
+	return table.ID, err
+}
+
+func (d *fakeRecordsUserExampleDao) UpdateByID(_ context.Context, table *model.UserExample) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	record := d.records[table.ID]
+	if table.Name != "" {
+		record.Name = table.Name
+	}
+	if table.Password != "" {
+		record.Password = table.Password
+	}
+	if table.Phone != "" {
+		record.Phone = table.Phone
+	}
+	if table.Age != 0 {
+		record.Age = table.Age
+	}
+	d.records[table.ID] = record
+	return nil
+}
+
+func (d *fakeRecordsUserExampleDao) DeleteExistingByID(_ context.Context, id uint64) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, ok := d.records[id]
+	delete(d.records, id)
+	return ok, nil
+}
+
+func (d *fakeRecordsUserExampleDao) GetByID(_ context.Context, id uint64) (*model.UserExample, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	record, ok := d.records[id]
+	if !ok {
+		return nil, database.ErrRecordNotFound
+	}
+	return &record, nil
+}
+
+type fakeUserExampleActivityDao struct {
+	mu         sync.Mutex
+	activities []*model.UserExampleActivity
+}
+
+func (d *fakeUserExampleActivityDao) Create(_ context.Context, table *model.UserExampleActivity) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	table.ID = uint64(len(d.activities) + 1)
+	table.CreatedAt = time.Now()
+	d.activities = append(d.activities, table)
+	return nil
+}
+
+func (d *fakeUserExampleActivityDao) GetByUserExampleID(_ context.Context, userExampleID uint64, tenantID string, page int, limit int) ([]*model.UserExampleActivity, int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var records []*model.UserExampleActivity
+	for _, activity := range d.activities {
+		if activity.UserExampleID == userExampleID && activity.TenantID == tenantID {
+			records = append(records, activity)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ID > records[j].ID })
+	total := int64(len(records))
+	start, end := page*limit, (page+1)*limit
+	if start > len(records) {
+		start = len(records)
+	}
+	if end > len(records) {
+		end = len(records)
+	}
+	return records[start:end], total, nil
+}
+
+func (d *fakeUserExampleActivityDao) DeleteBefore(_ context.Context, t time.Time) (int64, error) {
+	return 0, nil
+}
+
+type userExampleActivitiesResult struct {
+	Code int `json:"code"`
+	Data struct {
+		Items   []types.UserExampleActivityObjDetail `json:"items"`
+		Total   int64                                `json:"total"`
+		Page    int                                  `json:"page"`
+		Limit   int                                  `json:"limit"`
+		HasNext bool                                 `json:"hasNext"`
+	} `json:"data"`
+}
+
+func Test_userExampleHandler_Activity(t *testing.T) {
+	_, err := logger.Init(logger.WithRedaction(logger.WithRedactKeys("password"), logger.WithPartialRedactKeys("phone")))
+	require.NoError(t, err)
+	defer func() { _, _ = logger.Init() }()
+
+	activityDao := &fakeUserExampleActivityDao{}
+	h := &userExampleHandler{
+		iDao:        &fakeRecordsUserExampleDao{records: map[uint64]model.UserExample{}},
+		activityDao: activityDao,
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	g := r.Group("/userExample", func(c *gin.Context) {
+		c.Set("claims", &jwt.Claims{UID: "100", Fields: map[string]interface{}{tenantIDField: c.GetHeader("X-Tenant-Id")}})
+	})
+	g.POST("", h.Create)
+	g.PUT("/:id", h.UpdateByID)
+	g.DELETE("/:id", h.DeleteByID)
+	g.GET("/:id/activity", h.Activity)
+	serve := func(method string, path string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	list := func(path string) *userExampleActivitiesResult {
+		w := serve(http.MethodGet, path, "")
+		result := &userExampleActivitiesResult{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), result), w.Body.String())
+		return result
+	}
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/userExample",
+		`{"name":"foo","password":"e10adc3949ba59abbe56e057f20f883e","email":"foo@bar.com","phone":"+8613800138000","avatar":"a.png","age":10,"gender":1}`).Code)
+	assert.Equal(t, http.StatusOK, serve(http.MethodPut, "/userExample/1",
+		`{"name":"bar","password":"c33367701511b4f6020ec61ded352059","phone":"+8613800139999","age":10}`).Code)
+	assert.Equal(t, http.StatusOK, serve(http.MethodPut, "/userExample/1", `{"age":10}`).Code) // not changed
+	assert.Equal(t, http.StatusOK, serve(http.MethodDelete, "/userExample/1", "").Code)
+
+	// the newest first
+	result := list("/userExample/1/activity")
+	assert.Equal(t, 0, result.Code)
+	assert.Equal(t, int64(3), result.Data.Total)
+	require.Len(t, result.Data.Items, 3)
+	for i, action := range []string{userExampleActionDeleted, userExampleActionUpdated, userExampleActionCreated} {
+		assert.Equal(t, action, result.Data.Items[i].Action)
+		assert.Equal(t, "100", result.Data.Items[i].Actor)
+	}
+
+	// the diff of the update, the sensitive values are redacted
+	assert.Equal(t, []types.UserExampleActivityChange{
+		{Field: "name", Old: "foo", New: "bar"},
+		{Field: "password", Old: "******", New: "******"},
+		{Field: "phone", Old: "**********8000", New: "**********9999"},
+	}, result.Data.Items[1].Changes)
+	created := map[string]types.UserExampleActivityChange{}
+	for _, change := range result.Data.Items[2].Changes {
+		assert.Nil(t, change.Old)
+		created[change.Field] = change
+	}
+	assert.Equal(t, "foo", created["name"].New)
+	assert.Equal(t, "******", created["password"].New)
+	assert.NotContains(t, created, "loginAt") // the zero values are not changed
+	for _, change := range result.Data.Items[0].Changes {
+		assert.Nil(t, change.New)
+	}
+
+	// the pages in the same order
+	result = list("/userExample/1/activity?page=0&limit=2")
+	assert.Equal(t, 2, result.Data.Limit)
+	assert.True(t, result.Data.HasNext)
+	require.Len(t, result.Data.Items, 2)
+	assert.Equal(t, userExampleActionDeleted, result.Data.Items[0].Action)
+	assert.Equal(t, userExampleActionUpdated, result.Data.Items[1].Action)
+	result = list("/userExample/1/activity?page=1&limit=2")
+	assert.False(t, result.Data.HasNext)
+	require.Len(t, result.Data.Items, 1)
+	assert.Equal(t, userExampleActionCreated, result.Data.Items[0].Action)
+
+	// the entries of the other tenants are not listed
+	req := httptest.NewRequest(http.MethodGet, "/userExample/1/activity", nil)
+	req.Header.Set("X-Tenant-Id", "t1")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Contains(t, w.Body.String(), `"total":0`)
+
+	assertErrorCode(t, serve(http.MethodGet, "/userExample/1/activity?limit=101", ""), ecode.InvalidParams)
+	h.activityDao = nil
+	assertErrorCode(t, serve(http.MethodGet, "/userExample/1/activity", ""), ecode.NotFound)
+}
@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/copier"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
+	"github.com/go-dev-frame/sponge/pkg/gin/response"
+	"github.com/go-dev-frame/sponge/pkg/logger"
+
+	"github.com/go-dev-frame/sponge/internal/cache"
+	"github.com/go-dev-frame/sponge/internal/database"
+	"github.com/go-dev-frame/sponge/internal/ecode"
+	"github.com/go-dev-frame/sponge/internal/model"
+	"github.com/go-dev-frame/sponge/internal/types"
+)
+
+const (
+	// the maximum number of the includes resolved concurrently per request
+	maxUserExampleIncludeWorkers = 4
+	// the timeout of the include if it is not set
+	defaultUserExampleIncludeTimeout = 2 * time.Second
+)
+
+var (
+	errUserExampleActivityDisabled = errors.New("activity is disabled")
+	errUserExampleIncludeTimeout   = errors.New("timeout")
+)
+
+// UserExampleResolver resolve the related resource of the userExample record for the aggregate endpoint, ctx has
+// the tenant of the request, see cache.TenantFromContext, the resolver should return when ctx is done.
+type UserExampleResolver func(ctx context.Context, record *model.UserExample) (interface{}, error)
+
+// UserExampleInclude the include of the aggregate endpoint, Timeout is the timeout of the resolver, the default is 2s
+type UserExampleInclude struct {
+	Resolver UserExampleResolver
+	Timeout  time.Duration
+}
+
+// the includes of the related resources, they reuse the daos of the handler, the disabled dao is the error of
+// the include instead of the request
+func (h *userExampleHandler) defaultIncludes() map[string]UserExampleInclude {
+	return map[string]UserExampleInclude{
+		"activityCount": {Resolver: func(ctx context.Context, record *model.UserExample) (interface{}, error) {
+			if h.activityDao == nil {
+				return nil, errUserExampleActivityDisabled
+			}
+			_, total, err := h.activityDao.GetByUserExampleID(ctx, record.ID, cache.TenantFromContext(ctx), 0, 1)
+			return total, err
+		}},
+		"latestActivity": {Resolver: func(ctx context.Context, record *model.UserExample) (interface{}, error) {
+			if h.activityDao == nil {
+				return nil, errUserExampleActivityDisabled
+			}
+			records, _, err := h.activityDao.GetByUserExampleID(ctx, record.ID, cache.TenantFromContext(ctx), 0, 1)
+			if err != nil || len(records) == 0 {
+				return nil, err
+			}
+			return types.UserExampleActivityObjDetail{
+				ID:        records[0].ID,
+				Actor:     records[0].Actor,
+				Action:    records[0].Action,
+				CreatedAt: records[0].CreatedAt,
+			}, nil
+		}},
+	}
+}
+
+// Aggregate get a record with the related resources in one request
+// @Summary aggregate userExample with related resources
+// @Description get userExample by id with the includes resolved concurrently, each include has the data or the
+// @Description error inline, one include failing or timing out does not fail the others, 400 if an include is unknown
+// @Tags userExample
+// @accept json
+// @Produce json
+// @Param id path string true "id"
+// @Param data body types.AggregateUserExampleRequest true "names of the includes"
+// @Success 200 {object} types.AggregateUserExampleReply{}
+// @Router /api/v1/userExample/{id}/aggregate [post]
+// @Security BearerAuth
+func (h *userExampleHandler) Aggregate(c *gin.Context) {
+	_, id, isAbort := getUserExampleIDFromPath(c)
+	if isAbort {
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+	form := &types.AggregateUserExampleRequest{}
+	if isAbort = h.bindJSON(c, form); isAbort {
+		return
+	}
+
+	includes := h.includes
+	if includes == nil {
+		includes = h.defaultIncludes()
+	}
+	names, unknown := userExampleIncludeNames(form.Includes, includes)
+	if len(unknown) > 0 {
+		logger.Warn("unknown includes", logger.Any("includes", unknown), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.InvalidParams.RewriteMsg("unknown includes: "+strings.Join(unknown, ", ")))
+		return
+	}
+
+	ctx := wrapTenantCtx(c)
+	record, err := h.iDao.GetByID(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrRecordNotFound):
+			logger.Warn("GetByID not found", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.NotFound)
+		case isBudgetExhausted(c, err, "Aggregate budget exhausted"):
+		default:
+			logger.Error("GetByID error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+			response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		}
+		return
+	}
+
+	data := &types.UserExampleObjDetail{}
+	err = copier.Copy(data, record)
+	if err != nil {
+		response.Error(c, ecode.ErrGetByIDUserExample)
+		return
+	}
+	// Note: if copier.Copy cannot assign a value to a field, add it here
+
+	results := resolveUserExampleIncludes(ctx, record, names, includes)
+	for name, result := range results {
+		if result.Error != "" {
+			logger.Warn("resolve include error", logger.String("include", name), logger.String("err", result.Error),
+				logger.Any("id", id), middleware.GCtxRequestIDField(c))
+		}
+	}
+
+	response.Success(c, gin.H{"userExample": data, "includes": results})
+}
+
+// the distinct names of the includes in order, and the unknown names
+func userExampleIncludeNames(names []string, includes map[string]UserExampleInclude) ([]string, []string) {
+	var known, unknown []string
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		if _, ok := includes[name]; ok {
+			known = append(known, name)
+		} else {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return known, unknown
+}
+
+// resolve the includes concurrently by the bounded workers, each include has its own timeout, the result of the
+// include is the error if the resolver fails, panics or times out, the other includes are not affected
+func resolveUserExampleIncludes(ctx context.Context, record *model.UserExample, names []string,
+	includes map[string]UserExampleInclude) map[string]types.UserExampleIncludeResult {
+	results := make(map[string]types.UserExampleIncludeResult, len(names))
+	mu := sync.Mutex{}
+
+	g := errgroup.Group{}
+	g.SetLimit(maxUserExampleIncludeWorkers)
+	for _, name := range names {
+		name, include := name, includes[name]
+		g.Go(func() error {
+			result := types.UserExampleIncludeResult{}
+			data, err := resolveUserExampleInclude(ctx, record, include)
+			if err != nil {
+				result.Error = err.Error()
+			} else {
+				result.Data = data
+			}
+			mu.Lock()
+			results[name] = result
+			mu.Unlock()
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	return results
+}
+
+// the resolver runs in its own goroutine, so the include returns at the timeout even if the resolver ignores ctx
+func resolveUserExampleInclude(ctx context.Context, record *model.UserExample, include UserExampleInclude) (interface{}, error) {
+	timeout := include.Timeout
+	if timeout <= 0 {
+		timeout = defaultUserExampleIncludeTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		data interface{}
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer func() {
+			if e := recover(); e != nil {
+				done <- result{err: fmt.Errorf("panic: %v", e)}
+			}
+		}()
+		data, err := include.Resolver(ctx, record)
+		done <- result{data: data, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.data, r.err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, errUserExampleIncludeTimeout
+		}
+		return nil, ctx.Err()
+	}
+}
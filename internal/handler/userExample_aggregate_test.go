@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-dev-frame/sponge/pkg/jwt"
+
+	"github.com/go-dev-frame/sponge/internal/cache"
+	"github.com/go-dev-frame/sponge/internal/ecode"
+	"github.com/go-dev-frame/sponge/internal/model"
+	"github.com/go-dev-frame/sponge/internal/types"
+)
+
+type userExampleAggregateResult struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+	Data struct {
+		UserExample types.UserExampleObjDetail                `json:"userExample"`
+		Includes    map[string]types.UserExampleIncludeResult `json:"includes"`
+	} `json:"data"`
+}
+
+func newAggregateRouter(h *userExampleHandler) func(path string, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	g := r.Group("/userExample", func(c *gin.Context) {
+		c.Set("claims", &jwt.Claims{UID: "100", Fields: map[string]interface{}{tenantIDField: c.GetHeader("X-Tenant-Id")}})
+	})
+	g.POST("/:id/aggregate", h.Aggregate)
+
+	return func(path string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Tenant-Id", "t1")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+}
+
+func decodeAggregateResult(t *testing.T, w *httptest.ResponseRecorder) *userExampleAggregateResult {
+	require.Equal(t, http.StatusOK, w.Code)
+	result := &userExampleAggregateResult{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), result), w.Body.String())
+	require.Equal(t, 0, result.Code, w.Body.String())
+	return result
+}
+
+func Test_userExampleHandler_Aggregate(t *testing.T) {
+	var running, maxRunning int32
+	started := make(chan struct{}, 10)
+	wait := func(ctx context.Context, record *model.UserExample) (interface{}, error) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		started <- struct{}{}
+		time.Sleep(100 * time.Millisecond)
+		return record.Name + "@" + cache.TenantFromContext(ctx), nil
+	}
+
+	h := &userExampleHandler{
+		iDao: &fakeRecordsUserExampleDao{records: map[uint64]model.UserExample{1: {Name: "foo"}}},
+		includes: map[string]UserExampleInclude{
+			"a": {Resolver: wait},
+			"b": {Resolver: wait},
+			"c": {Resolver: wait},
+			"failed": {Resolver: func(context.Context, *model.UserExample) (interface{}, error) {
+				return nil, errors.New("orders unavailable")
+			}},
+			"panicked": {Resolver: func(context.Context, *model.UserExample) (interface{}, error) {
+				panic("bug")
+			}},
+			// the resolver ignores ctx, the include returns at the timeout
+			"slow": {Timeout: 50 * time.Millisecond, Resolver: func(context.Context, *model.UserExample) (interface{}, error) {
+				time.Sleep(time.Second)
+				return "late", nil
+			}},
+		},
+	}
+	post := newAggregateRouter(h)
+
+	// the includes are resolved concurrently, one include timing out or failing does not fail the response
+	start := time.Now()
+	result := decodeAggregateResult(t, post("/userExample/1/aggregate", `{"includes":["a","b","c","a","slow","failed","panicked"]}`))
+	elapsed := time.Since(start)
+	assert.Equal(t, "foo", result.Data.UserExample.Name)
+	assert.Equal(t, map[string]types.UserExampleIncludeResult{
+		"a":        {Data: "foo@t1"},
+		"b":        {Data: "foo@t1"},
+		"c":        {Data: "foo@t1"},
+		"slow":     {Error: "timeout"},
+		"failed":   {Error: "orders unavailable"},
+		"panicked": {Error: "panic: bug"},
+	}, result.Data.Includes)
+	assert.Equal(t, 3, len(started)) // "a" is resolved once
+	assert.Equal(t, int32(3), atomic.LoadInt32(&maxRunning))
+	assert.Less(t, elapsed, 250*time.Millisecond, "the includes are not resolved sequentially")
+
+	// the unknown includes are rejected before resolving any include
+	w := post("/userExample/1/aggregate", `{"includes":["a","orders","user"]}`)
+	assertErrorCode(t, w, ecode.InvalidParams)
+	assert.Contains(t, w.Body.String(), "unknown includes: orders, user")
+	assert.Equal(t, 3, len(started))
+
+	// the invalid requests
+	assertErrorCode(t, post("/userExample/1/aggregate", `{"includes":[]}`), ecode.InvalidParams)
+	assertErrorCode(t, post("/userExample/0/aggregate", `{"includes":["a"]}`), ecode.InvalidParams)
+	assertErrorCode(t, post("/userExample/2/aggregate", `{"includes":["a"]}`), ecode.NotFound)
+}
+
+func Test_resolveUserExampleIncludes_Workers(t *testing.T) {
+	var running, maxRunning int32
+	resolver := func(context.Context, *model.UserExample) (interface{}, error) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		return 1, nil
+	}
+	includes := map[string]UserExampleInclude{}
+	var names []string
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		includes[name] = UserExampleInclude{Resolver: resolver}
+		names = append(names, name)
+	}
+
+	results := resolveUserExampleIncludes(context.Background(), &model.UserExample{}, names, includes)
+	assert.Len(t, results, len(names))
+	assert.Equal(t, int32(maxUserExampleIncludeWorkers), atomic.LoadInt32(&maxRunning))
+}
+
+func Test_userExampleHandler_Aggregate_DefaultIncludes(t *testing.T) {
+	activityDao := &fakeUserExampleActivityDao{}
+	for _, action := range []string{userExampleActionCreated, userExampleActionUpdated} {
+		_ = activityDao.Create(context.Background(), &model.UserExampleActivity{UserExampleID: 1, TenantID: "t1", Actor: "100", Action: action})
+	}
+	_ = activityDao.Create(context.Background(), &model.UserExampleActivity{UserExampleID: 1, TenantID: "t2", Action: userExampleActionDeleted})
+	iDao := &fakeRecordsUserExampleDao{records: map[uint64]model.UserExample{}}
+	require.NoError(t, iDao.Create(context.Background(), &model.UserExample{Name: "foo"}))
+
+	post := newAggregateRouter(&userExampleHandler{iDao: iDao, activityDao: activityDao})
+	result := decodeAggregateResult(t, post("/userExample/1/aggregate", `{"includes":["activityCount","latestActivity"]}`))
+	assert.Equal(t, float64(2), result.Data.Includes["activityCount"].Data)
+	latest, ok := result.Data.Includes["latestActivity"].Data.(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, userExampleActionUpdated, latest["action"])
+
+	// the disabled activity is the error of the include
+	post = newAggregateRouter(&userExampleHandler{iDao: iDao})
+	result = decodeAggregateResult(t, post("/userExample/1/aggregate", `{"includes":["activityCount"]}`))
+	assert.Equal(t, "activity is disabled", result.Data.Includes["activityCount"].Error)
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
+	"github.com/go-dev-frame/sponge/pkg/gin/response"
+	"github.com/go-dev-frame/sponge/pkg/logger"
+
+	"github.com/go-dev-frame/sponge/internal/ecode"
+	"github.com/go-dev-frame/sponge/internal/model"
+	"github.com/go-dev-frame/sponge/internal/types"
+)
+
+const (
+	// the maximum number of the distinct values per request
+	maxUserExampleDistinctLimit = 100
+	// the maximum number of the characters of the prefix
+	maxUserExampleDistinctPrefixLen = 64
+)
+
+// the columns in the whitelist whose values must not be exposed
+var userExampleDistinctExcludedColumns = map[string]bool{
+	"password": true,
+}
+
+// Distinct values of a column
+// @Summary distinct values of userExample column
+// @Description distinct values of a column of the userExamples matching the conditions, e.g. the filter dropdowns,
+// @Description the values start with the prefix if it is not empty, at most 100 values are returned, truncated is true if there are more
+// @Tags userExample
+// @accept json
+// @Produce json
+// @Param data body types.DistinctUserExampleRequest true "column and conditions"
+// @Success 200 {object} types.DistinctUserExampleReply{}
+// @Router /api/v1/userExample/distinct [post]
+// @Security BearerAuth
+func (h *userExampleHandler) Distinct(c *gin.Context) {
+	form := &types.DistinctUserExampleRequest{}
+	if isAbort := h.bindJSON(c, form); isAbort {
+		return
+	}
+	if !model.UserExampleColumnNames[form.Column] || userExampleDistinctExcludedColumns[form.Column] {
+		logger.Warn("distinct column is not allowed", logger.String("column", form.Column), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.InvalidParams.RewriteMsg("column '"+form.Column+"' is not allowed"))
+		return
+	}
+	for _, column := range form.Conditions {
+		if !model.UserExampleColumnNames[column.Name] {
+			logger.Warn("condition column is not allowed", logger.String("column", column.Name), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.InvalidParams.RewriteMsg("column '"+column.Name+"' is not allowed"))
+			return
+		}
+	}
+	prefix := strings.TrimSpace(form.Prefix)
+	if utf8.RuneCountInString(prefix) > maxUserExampleDistinctPrefixLen {
+		logger.Warn("distinct prefix is too long", logger.String("prefix", prefix), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.InvalidParams.RewriteMsg("prefix exceeds "+strconv.Itoa(maxUserExampleDistinctPrefixLen)+" characters"))
+		return
+	}
+	limit := form.Limit
+	if limit <= 0 || limit > maxUserExampleDistinctLimit {
+		limit = maxUserExampleDistinctLimit
+	}
+
+	// one more value is got to know whether the values are truncated
+	values, err := h.iDao.GetDistinct(wrapTenantCtx(c), form.Column, form.Conditions, prefix, limit+1)
+	if err != nil {
+		logger.Error("GetDistinct error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
+		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		return
+	}
+	truncated := len(values) > limit
+	if truncated {
+		values = values[:limit]
+	}
+
+	response.Success(c, gin.H{"values": values, "truncated": truncated})
+}
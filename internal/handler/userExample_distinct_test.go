@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-dev-frame/sponge/pkg/gotest"
+
+	"github.com/go-dev-frame/sponge/internal/dao"
+	"github.com/go-dev-frame/sponge/internal/ecode"
+)
+
+func Test_userExampleHandler_Distinct(t *testing.T) {
+	d := gotest.NewDao(nil, nil)
+	defer d.Close()
+	h := &userExampleHandler{iDao: dao.NewUserExampleDao(d.DB, nil)}
+
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.POST("/userExample/distinct", h.Distinct)
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/userExample/distinct", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	nameRows := func(n int) *sqlmock.Rows {
+		rows := sqlmock.NewRows([]string{"name"})
+		for i := 0; i < n; i++ {
+			rows.AddRow(fmt.Sprintf("foo%03d", i))
+		}
+		return rows
+	}
+
+	// the default and the maximum limit is 100, one more value is queried to know whether they are truncated
+	d.SQLMock.ExpectQuery("SELECT DISTINCT .*name.* ORDER BY name LIMIT 101").WithArgs(1.0).WillReturnRows(nameRows(101))
+	w := post(`{"column":"name","conditions":[{"name":"status","value":1}]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"truncated":true`)
+	assert.Contains(t, w.Body.String(), `"foo099"`)
+	assert.NotContains(t, w.Body.String(), `"foo100"`)
+
+	d.SQLMock.ExpectQuery("SELECT DISTINCT .*name.* LIMIT 101").WillReturnRows(nameRows(3))
+	w = post(`{"column":"name","limit":1000}`)
+	assert.Contains(t, w.Body.String(), `"truncated":false`)
+
+	d.SQLMock.ExpectQuery("SELECT DISTINCT .*name.* LIMIT 3").WillReturnRows(nameRows(2))
+	w = post(`{"column":"name","limit":2}`)
+	assert.Contains(t, w.Body.String(), `"values":["foo000","foo001"]`)
+	assert.Contains(t, w.Body.String(), `"truncated":false`)
+
+	// the prefix is trimmed and its wildcards are escaped
+	d.SQLMock.ExpectQuery("SELECT DISTINCT .*name.* LIKE .* LIMIT 11").WithArgs("b!%a%").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("b%ar"))
+	w = post(`{"column":"name","prefix":" b%a ","limit":10}`)
+	assert.Contains(t, w.Body.String(), `"values":["b%ar"]`)
+	assert.NoError(t, d.SQLMock.ExpectationsWereMet())
+
+	w = post(`{"column":"name","prefix":"` + strings.Repeat("a", maxUserExampleDistinctPrefixLen+1) + `"}`)
+	assertErrorCode(t, w, ecode.InvalidParams)
+
+	// the columns are not allowed
+	w = post(`{"column":"city"}`)
+	assertErrorCode(t, w, ecode.InvalidParams)
+	w = post(`{"column":"password"}`)
+	assertErrorCode(t, w, ecode.InvalidParams)
+	w = post(`{"column":"name","conditions":[{"name":"city","value":"foo"}]}`)
+	assertErrorCode(t, w, ecode.InvalidParams)
+	w = post(`{"conditions":[]}`)
+	assertErrorCode(t, w, ecode.InvalidParams)
+
+	// the query error
+	d.SQLMock.ExpectQuery("SELECT DISTINCT .*").WillReturnError(fmt.Errorf("db error"))
+	w = post(`{"column":"name"}`)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
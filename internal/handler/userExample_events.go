@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
+	"github.com/go-dev-frame/sponge/pkg/jwt"
+	"github.com/go-dev-frame/sponge/pkg/logger"
+	"github.com/go-dev-frame/sponge/pkg/utils"
+	"github.com/go-dev-frame/sponge/pkg/ws"
+
+	"github.com/go-dev-frame/sponge/internal/model"
+)
+
+var (
+	userExampleEventHub  *ws.Hub
+	userExampleEventOnce sync.Once
+)
+
+const userExampleEventBuffer = 1024
+
+// UserExampleEventHub the websocket hub of the userExample record events, the clients subscribe to the events
+// with the conditions of the columns in model.UserExampleColumnNames, the hub receives the events of the topics
+// userExample.* from EventBus, which are published by the handler after the record is created, updated or deleted.
+func UserExampleEventHub() *ws.Hub {
+	userExampleEventOnce.Do(func() {
+		userExampleEventHub = ws.NewHub(
+			ws.WithHubResource("userExample", model.UserExampleColumnNames),
+			// the token is the same as middleware.Auth()
+			ws.WithHubAuthenticate(func(token string) (string, error) {
+				claims, err := jwt.ValidateToken(token)
+				if err != nil {
+					return "", err
+				}
+				return claims.UID, nil
+			}),
+			ws.WithHubLogger(logger.Get()),
+		)
+
+		events, _ := EventBus().Subscribe("userExample.*", userExampleEventBuffer, utils.WithSubscribeName("userExample.websocket"))
+		go func(hub *ws.Hub) {
+			for event := range events {
+				if e, ok := event.(*ws.Event); ok {
+					hub.Publish(e)
+				}
+			}
+		}(userExampleEventHub)
+	})
+	return userExampleEventHub
+}
+
+// publish the event of the userExample record to EventBus, the data is only the id, the clients get the record
+// by id with their own permissions, the error is only logged
+func publishUserExampleEvent(c *gin.Context, action string, id uint64) {
+	event := &ws.Event{Resource: "userExample", Action: action, Data: map[string]interface{}{"id": id}}
+	err := EventBus().Publish("userExample."+action, event)
+	if err != nil {
+		logger.Warn("publish event error", logger.Err(err), logger.String("action", action), logger.Any("id", id),
+			middleware.GCtxRequestIDField(c))
+	}
+}
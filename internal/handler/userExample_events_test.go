@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-dev-frame/sponge/pkg/jwt"
+	"github.com/go-dev-frame/sponge/pkg/ws"
+)
+
+func TestUserExampleEventHub(t *testing.T) {
+	srv := httptest.NewServer(UserExampleEventHub())
+	defer srv.Close()
+	_, token, err := jwt.GenerateToken("100")
+	require.NoError(t, err)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"?token="+token, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	readMessage := func() *ws.Message {
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		msg := &ws.Message{}
+		require.NoError(t, conn.ReadJSON(msg))
+		return msg
+	}
+	require.NoError(t, conn.WriteJSON(&ws.Message{Type: ws.MsgTypeSubscribe, ID: "5", Resource: "userExample",
+		Conditions: map[string]interface{}{"id": 5}}))
+	require.Equal(t, ws.MsgTypeSubscribed, readMessage().Type)
+
+	// the events published by the handler are forwarded to the hub by EventBus
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	publishUserExampleEvent(c, userExampleActionUpdated, 6)
+	publishUserExampleEvent(c, userExampleActionDeleted, 5)
+	msg := readMessage()
+	assert.Equal(t, ws.MsgTypeEvent, msg.Type)
+	assert.Equal(t, []string{"5"}, msg.IDs)
+	assert.Equal(t, userExampleActionDeleted, msg.Event.Action)
+	assert.EqualValues(t, 5, msg.Event.Data["id"])
+}
@@ -0,0 +1,349 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/copier"
+
+	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
+	"github.com/go-dev-frame/sponge/pkg/gin/response"
+	"github.com/go-dev-frame/sponge/pkg/gofile"
+	"github.com/go-dev-frame/sponge/pkg/logger"
+	"github.com/go-dev-frame/sponge/pkg/utils"
+
+	"github.com/go-dev-frame/sponge/internal/cache"
+	"github.com/go-dev-frame/sponge/internal/dao"
+	"github.com/go-dev-frame/sponge/internal/database"
+	"github.com/go-dev-frame/sponge/internal/ecode"
+	"github.com/go-dev-frame/sponge/internal/model"
+	"github.com/go-dev-frame/sponge/internal/types"
+)
+
+// the default quotas of the files per userExample record
+const (
+	defaultUserExampleMaxFiles     = 20
+	defaultUserExampleMaxFilesSize = 100 << 20
+	defaultUserExampleMaxFileSize  = 10 << 20
+)
+
+var _ UserExampleFileHandler = (*userExampleFileHandler)(nil)
+
+// UserExampleFileHandler defining the handler interface of the files associated with the userExample records
+type UserExampleFileHandler interface {
+	Upload(c *gin.Context)
+	List(c *gin.Context)
+	Download(c *gin.Context)
+	Delete(c *gin.Context)
+}
+
+type userExampleFileHandler struct {
+	iDao    dao.UserExampleDao
+	fileDao dao.UserExampleFileDao
+	// invalidate the cache of the parent record after its files are changed, nil if the cache is not used
+	cache cache.UserExampleCache
+
+	storage  gofile.Storage
+	uploader *gofile.Uploader
+
+	// the quotas of the files per record, the number of the files and the total size in bytes
+	maxFiles     int64
+	maxFilesSize int64
+}
+
+// NewUserExampleFileHandler creating the handler interface
+func NewUserExampleFileHandler() UserExampleFileHandler {
+	storage, err := gofile.NewLocalStorage("uploads/userExample") // todo use the storage of the config, e.g. gofile.NewS3Storage
+	if err != nil {
+		panic(err)
+	}
+	xCache := cache.NewUserExampleCache(database.GetCacheType())
+	return newUserExampleFileHandler(
+		dao.NewUserExampleDao(database.GetDB(), xCache), // todo show db driver name here
+		dao.NewUserExampleFileDao(database.GetDB()),
+		xCache,
+		storage,
+	)
+}
+
+func newUserExampleFileHandler(iDao dao.UserExampleDao, fileDao dao.UserExampleFileDao, xCache cache.UserExampleCache,
+	storage gofile.Storage) *userExampleFileHandler {
+	return &userExampleFileHandler{
+		iDao:         iDao,
+		fileDao:      fileDao,
+		cache:        xCache,
+		storage:      storage,
+		uploader:     gofile.NewUploader(storage, gofile.WithMaxSize(defaultUserExampleMaxFileSize)),
+		maxFiles:     defaultUserExampleMaxFiles,
+		maxFilesSize: defaultUserExampleMaxFilesSize,
+	}
+}
+
+// Upload a file of a record
+// @Summary upload userExample file
+// @Description upload a file of userExample by multipart form, the type is sniffed from the content and must match the extension,
+// @Description 403 if the number or the total size of the files of the record exceeds the quota
+// @Tags userExample
+// @accept multipart/form-data
+// @Produce json
+// @Param id path string true "id"
+// @Param file formData file true "file"
+// @Success 200 {object} types.UploadUserExampleFileReply{}
+// @Router /api/v1/userExample/{id}/files [post]
+// @Security BearerAuth
+func (h *userExampleFileHandler) Upload(c *gin.Context) {
+	_, id, isAbort := getUserExampleIDFromPath(c)
+	if isAbort {
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+	fh, err := c.FormFile("file")
+	if err != nil {
+		logger.Warn("FormFile error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+
+	ctx := wrapTenantCtx(c)
+	if isAbort = h.checkRecord(c, ctx, id); isAbort {
+		return
+	}
+	if isAbort = h.checkQuota(c, ctx, id, fh.Size); isAbort {
+		return
+	}
+
+	uploaded, err := h.uploader.SaveMultipart(ctx, fh)
+	if err != nil {
+		if errors.Is(err, gofile.ErrFileTooLarge) || errors.Is(err, gofile.ErrFileTypeNotAllowed) ||
+			errors.Is(err, gofile.ErrInvalidFilename) {
+			logger.Warn("SaveMultipart rejected", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.InvalidParams.RewriteMsg(err.Error()))
+			return
+		}
+		logger.Error("SaveMultipart error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		return
+	}
+
+	file := &model.UserExampleFile{
+		UserExampleID: id,
+		TenantID:      GetTenantID(c),
+		Key:           uploaded.Key,
+		Filename:      uploaded.Filename,
+		Size:          uploaded.Size,
+		ContentType:   uploaded.ContentType,
+		Hash:          uploaded.Hash,
+	}
+	err = h.fileDao.Create(ctx, file)
+	if err != nil {
+		h.deleteContent(c, ctx, uploaded.Key)
+		logger.Error("Create file error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		return
+	}
+	h.invalidate(ctx, id)
+
+	data := &types.UserExampleFileObjDetail{}
+	_ = copier.Copy(data, file)
+	response.Success(c, gin.H{"file": data})
+}
+
+// List the files of a record
+// @Summary list userExample files
+// @Description list the metadata of the files of userExample by id
+// @Tags userExample
+// @accept json
+// @Produce json
+// @Param id path string true "id"
+// @Success 200 {object} types.ListUserExampleFilesReply{}
+// @Router /api/v1/userExample/{id}/files [get]
+// @Security BearerAuth
+func (h *userExampleFileHandler) List(c *gin.Context) {
+	_, id, isAbort := getUserExampleIDFromPath(c)
+	if isAbort {
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+
+	ctx := wrapTenantCtx(c)
+	if isAbort = h.checkRecord(c, ctx, id); isAbort {
+		return
+	}
+	files, err := h.fileDao.GetByUserExampleID(ctx, id, GetTenantID(c))
+	if err != nil {
+		logger.Error("GetByUserExampleID error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		return
+	}
+
+	data := make([]*types.UserExampleFileObjDetail, 0, len(files))
+	for _, file := range files {
+		detail := &types.UserExampleFileObjDetail{}
+		_ = copier.Copy(detail, file)
+		data = append(data, detail)
+	}
+	response.Success(c, gin.H{"files": data})
+}
+
+// Download a file of a record
+// @Summary download userExample file
+// @Description download the file of userExample, the range requests are supported if the storage is seekable, e.g. the local storage
+// @Tags userExample
+// @Produce octet-stream
+// @Param id path string true "id"
+// @Param fileID path string true "file id"
+// @Param Range header string false "e.g. bytes=0-1023"
+// @Success 200 {file} file
+// @Success 206 {file} file
+// @Router /api/v1/userExample/{id}/files/{fileID} [get]
+// @Security BearerAuth
+func (h *userExampleFileHandler) Download(c *gin.Context) {
+	ctx := wrapTenantCtx(c)
+	file, isAbort := h.getFile(c, ctx)
+	if isAbort {
+		return
+	}
+
+	rc, err := h.storage.Get(ctx, file.Key)
+	if err != nil {
+		logger.Error("storage Get error", logger.Err(err), logger.Any("fileID", file.ID), middleware.GCtxRequestIDField(c))
+		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		return
+	}
+	defer rc.Close() //nolint
+
+	c.Header("Content-Type", file.ContentType)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.Filename}))
+	c.Header("ETag", strconv.Quote(file.Hash))
+	if rs, ok := rc.(io.ReadSeeker); ok {
+		http.ServeContent(c.Writer, c.Request, "", file.CreatedAt, rs)
+		return
+	}
+
+	// the storage is not seekable, e.g. s3, the whole content is responded and the range is ignored
+	c.Header("Accept-Ranges", "none")
+	c.Header("Content-Length", strconv.FormatInt(file.Size, 10))
+	c.Status(http.StatusOK)
+	if c.Request.Method != http.MethodHead {
+		_, _ = io.Copy(c.Writer, rc)
+	}
+}
+
+// Delete a file of a record
+// @Summary delete userExample file
+// @Description delete the file of userExample, the content is removed from the storage if no other file refers to it
+// @Tags userExample
+// @accept json
+// @Produce json
+// @Param id path string true "id"
+// @Param fileID path string true "file id"
+// @Success 200 {object} types.DeleteUserExampleFileReply{}
+// @Router /api/v1/userExample/{id}/files/{fileID} [delete]
+// @Security BearerAuth
+func (h *userExampleFileHandler) Delete(c *gin.Context) {
+	ctx := wrapTenantCtx(c)
+	file, isAbort := h.getFile(c, ctx)
+	if isAbort {
+		return
+	}
+
+	err := h.fileDao.DeleteByID(ctx, file.ID)
+	if err != nil {
+		logger.Error("DeleteByID file error", logger.Err(err), logger.Any("fileID", file.ID), middleware.GCtxRequestIDField(c))
+		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		return
+	}
+	h.deleteContent(c, ctx, file.Key)
+	h.invalidate(ctx, file.UserExampleID)
+
+	response.Success(c)
+}
+
+// check the record exists, respond 404 if not
+func (h *userExampleFileHandler) checkRecord(c *gin.Context, ctx context.Context, id uint64) (isAbort bool) {
+	_, err := h.iDao.GetByID(ctx, id)
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, database.ErrRecordNotFound) {
+		logger.Warn("GetByID not found", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.NotFound)
+	} else {
+		logger.Error("GetByID error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+	}
+	return true
+}
+
+// check the quotas of the record before saving the file of size bytes, respond 403 with the current usage and
+// the limits if exceeded. The quotas are soft, the concurrent uploads of the same record may exceed them slightly.
+func (h *userExampleFileHandler) checkQuota(c *gin.Context, ctx context.Context, id uint64, size int64) (isAbort bool) {
+	count, total, err := h.fileDao.GetUsage(ctx, id)
+	if err != nil {
+		logger.Error("GetUsage error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		return true
+	}
+	if count+1 > h.maxFiles || total+size > h.maxFilesSize {
+		response.Out(c, ecode.Forbidden.RewriteMsg("quota_exceeded"), gin.H{
+			"files": count, "maxFiles": h.maxFiles, "size": total, "maxSize": h.maxFilesSize,
+		})
+		return true
+	}
+	return false
+}
+
+// get the file of the path, respond 404 if the file does not belong to the record of the path or the tenant,
+// so the ids of the other tenants can't be probed
+func (h *userExampleFileHandler) getFile(c *gin.Context, ctx context.Context) (*model.UserExampleFile, bool) {
+	_, id, isAbort := getUserExampleIDFromPath(c)
+	if isAbort {
+		response.Error(c, ecode.InvalidParams)
+		return nil, true
+	}
+	fileIDStr := c.Param("fileID")
+	fileID, err := utils.StrToUint64E(fileIDStr)
+	if err != nil || fileID == 0 {
+		logger.Warn("StrToUint64E error: ", logger.String("fileIDStr", fileIDStr), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.InvalidParams)
+		return nil, true
+	}
+
+	file, err := h.fileDao.GetByID(ctx, fileID)
+	if err != nil && !errors.Is(err, database.ErrRecordNotFound) {
+		logger.Error("GetByID file error", logger.Err(err), logger.Any("fileID", fileID), middleware.GCtxRequestIDField(c))
+		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		return nil, true
+	}
+	if err != nil || file.UserExampleID != id || file.TenantID != GetTenantID(c) {
+		logger.Warn("file not found", logger.Err(err), logger.Any("id", id), logger.Any("fileID", fileID),
+			middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.NotFound)
+		return nil, true
+	}
+	return file, false
+}
+
+// delete the content from the storage if no file refers to the key, the error is logged only,
+// the orphaned content does not affect the files
+func (h *userExampleFileHandler) deleteContent(c *gin.Context, ctx context.Context, key string) {
+	n, err := h.fileDao.CountByKey(ctx, key)
+	if err == nil && n == 0 {
+		err = h.storage.Delete(ctx, key)
+	}
+	if err != nil {
+		logger.Warn("delete file content error", logger.Err(err), logger.String("key", key), middleware.GCtxRequestIDField(c))
+	}
+}
+
+// invalidate the cache of the record, the files are a part of its representation
+func (h *userExampleFileHandler) invalidate(ctx context.Context, id uint64) {
+	if h.cache != nil {
+		_ = h.cache.Invalidate(ctx, id)
+	}
+}
@@ -0,0 +1,283 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-dev-frame/sponge/pkg/errcode"
+	"github.com/go-dev-frame/sponge/pkg/gofile"
+	"github.com/go-dev-frame/sponge/pkg/httpcli"
+	"github.com/go-dev-frame/sponge/pkg/jwt"
+
+	"github.com/go-dev-frame/sponge/internal/dao"
+	"github.com/go-dev-frame/sponge/internal/database"
+	"github.com/go-dev-frame/sponge/internal/ecode"
+	"github.com/go-dev-frame/sponge/internal/model"
+)
+
+// only the record of id 1 exists
+type fakeUserExampleDao struct {
+	dao.UserExampleDao
+}
+
+func (d *fakeUserExampleDao) GetByID(_ context.Context, id uint64) (*model.UserExample, error) {
+	if id != 1 {
+		return nil, database.ErrRecordNotFound
+	}
+	record := &model.UserExample{}
+	record.ID = id
+	return record, nil
+}
+
+type fakeUserExampleFileDao struct {
+	mu     sync.Mutex
+	lastID uint64
+	files  map[uint64]*model.UserExampleFile
+}
+
+func (d *fakeUserExampleFileDao) Create(_ context.Context, table *model.UserExampleFile) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.lastID++
+	table.ID = d.lastID
+	d.files[table.ID] = table
+	return nil
+}
+
+func (d *fakeUserExampleFileDao) DeleteByID(_ context.Context, id uint64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.files, id)
+	return nil
+}
+
+func (d *fakeUserExampleFileDao) GetByID(_ context.Context, id uint64) (*model.UserExampleFile, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	file, ok := d.files[id]
+	if !ok {
+		return nil, database.ErrRecordNotFound
+	}
+	return file, nil
+}
+
+func (d *fakeUserExampleFileDao) GetByUserExampleID(_ context.Context, userExampleID uint64, tenantID string) ([]*model.UserExampleFile, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var files []*model.UserExampleFile
+	for id := uint64(1); id <= d.lastID; id++ {
+		if file, ok := d.files[id]; ok && file.UserExampleID == userExampleID && file.TenantID == tenantID {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+func (d *fakeUserExampleFileDao) GetUsage(_ context.Context, userExampleID uint64) (int64, int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var count, size int64
+	for _, file := range d.files {
+		if file.UserExampleID == userExampleID {
+			count++
+			size += file.Size
+		}
+	}
+	return count, size, nil
+}
+
+func (d *fakeUserExampleFileDao) CountByKey(_ context.Context, key string) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var count int64
+	for _, file := range d.files {
+		if file.Key == key {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// the tenant of the request is set by the header X-Tenant-Id for testing
+func newUserExampleFileRouter(t *testing.T) (*gin.Engine, *userExampleFileHandler) {
+	storage, err := gofile.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := newUserExampleFileHandler(&fakeUserExampleDao{}, &fakeUserExampleFileDao{files: map[uint64]*model.UserExampleFile{}},
+		nil, storage)
+
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	g := r.Group("/userExample/:id/files", func(c *gin.Context) {
+		c.Set("claims", &jwt.Claims{UID: "100", Fields: map[string]interface{}{tenantIDField: c.GetHeader("X-Tenant-Id")}})
+	})
+	g.POST("", h.Upload)
+	g.GET("", h.List)
+	g.GET("/:fileID", h.Download)
+	g.DELETE("/:fileID", h.Delete)
+	return r, h
+}
+
+func uploadFile(r *gin.Engine, id string, tenantID string, filename string, content string) *httptest.ResponseRecorder {
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	fw, _ := mw.CreateFormFile("file", filename)
+	_, _ = fw.Write([]byte(content))
+	_ = mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/userExample/"+id+"/files", body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return serveFileRequest(r, req, tenantID)
+}
+
+func serveFileRequest(r *gin.Engine, req *http.Request, tenantID string) *httptest.ResponseRecorder {
+	req.Header.Set("X-Tenant-Id", tenantID)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func getUploadedFileID(t *testing.T, w *httptest.ResponseRecorder) string {
+	result := struct {
+		Code int `json:"code"`
+		Data struct {
+			File struct {
+				ID uint64 `json:"id"`
+			} `json:"file"`
+		} `json:"data"`
+	}{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, 0, result.Code)
+	return strconv.FormatUint(result.Data.File.ID, 10)
+}
+
+// the errors of response.Error are responded with the status 200 and the error code in the body
+func assertErrorCode(t *testing.T, w *httptest.ResponseRecorder, e *errcode.Error) {
+	result := &httpcli.StdResult{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), result))
+	assert.Equal(t, e.Code(), result.Code)
+}
+
+func Test_userExampleFileHandler_Upload(t *testing.T) {
+	r, _ := newUserExampleFileRouter(t)
+
+	w := uploadFile(r, "1", "t1", "../../hello.txt", "hello world")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"filename":"hello.txt"`)
+	assert.Contains(t, w.Body.String(), `"contentType":"text/plain; charset=utf-8"`)
+	assert.NotContains(t, w.Body.String(), `"key"`)
+
+	// the validation of the upload
+	w = uploadFile(r, "1", "t1", "app.exe", "MZ")
+	assertErrorCode(t, w, ecode.InvalidParams)
+	w = uploadFile(r, "1", "t1", "fake.png", "hello world")
+	assertErrorCode(t, w, ecode.InvalidParams)
+	w = uploadFile(r, "2", "t1", "hello.txt", "hello world") // the record does not exist
+	assertErrorCode(t, w, ecode.NotFound)
+	w = uploadFile(r, "abc", "t1", "hello.txt", "hello world")
+	assertErrorCode(t, w, ecode.InvalidParams)
+	req := httptest.NewRequest(http.MethodPost, "/userExample/1/files", nil)
+	w = serveFileRequest(r, req, "t1")
+	assertErrorCode(t, w, ecode.InvalidParams)
+
+	// list the files of the tenant only
+	w = uploadFile(r, "1", "t2", "other.txt", "hello other")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = serveFileRequest(r, httptest.NewRequest(http.MethodGet, "/userExample/1/files", nil), "t1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "hello.txt")
+	assert.NotContains(t, w.Body.String(), "other.txt")
+	w = serveFileRequest(r, httptest.NewRequest(http.MethodGet, "/userExample/2/files", nil), "t1")
+	assertErrorCode(t, w, ecode.NotFound)
+}
+
+func Test_userExampleFileHandler_Download(t *testing.T) {
+	r, _ := newUserExampleFileRouter(t)
+	w := uploadFile(r, "1", "t1", "hello.txt", "hello world")
+	fileID := getUploadedFileID(t, w)
+
+	w = serveFileRequest(r, httptest.NewRequest(http.MethodGet, "/userExample/1/files/"+fileID, nil), "t1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "hello world", w.Body.String())
+	assert.Equal(t, `attachment; filename=hello.txt`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "bytes", w.Header().Get("Accept-Ranges"))
+
+	// range request
+	req := httptest.NewRequest(http.MethodGet, "/userExample/1/files/"+fileID, nil)
+	req.Header.Set("Range", "bytes=6-")
+	w = serveFileRequest(r, req, "t1")
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "world", w.Body.String())
+	assert.Equal(t, "bytes 6-10/11", w.Header().Get("Content-Range"))
+
+	req = httptest.NewRequest(http.MethodGet, "/userExample/1/files/"+fileID, nil)
+	req.Header.Set("Range", "bytes=100-200")
+	w = serveFileRequest(r, req, "t1")
+	assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, w.Code)
+
+	// the ownership, the file belongs to another record or tenant is not found
+	w = serveFileRequest(r, httptest.NewRequest(http.MethodGet, "/userExample/1/files/"+fileID, nil), "t2")
+	assertErrorCode(t, w, ecode.NotFound)
+	w = serveFileRequest(r, httptest.NewRequest(http.MethodGet, "/userExample/2/files/"+fileID, nil), "t1")
+	assertErrorCode(t, w, ecode.NotFound)
+	w = serveFileRequest(r, httptest.NewRequest(http.MethodGet, "/userExample/1/files/9", nil), "t1")
+	assertErrorCode(t, w, ecode.NotFound)
+	w = serveFileRequest(r, httptest.NewRequest(http.MethodGet, "/userExample/1/files/abc", nil), "t1")
+	assertErrorCode(t, w, ecode.InvalidParams)
+}
+
+func Test_userExampleFileHandler_Delete(t *testing.T) {
+	r, h := newUserExampleFileRouter(t)
+
+	// the same content is shared by two files
+	fileID1 := getUploadedFileID(t, uploadFile(r, "1", "t1", "a.txt", "hello world"))
+	fileID2 := getUploadedFileID(t, uploadFile(r, "1", "t1", "b.txt", "hello world"))
+
+	// the file of another tenant can't be deleted
+	w := serveFileRequest(r, httptest.NewRequest(http.MethodDelete, "/userExample/1/files/"+fileID1, nil), "t2")
+	assertErrorCode(t, w, ecode.NotFound)
+
+	w = serveFileRequest(r, httptest.NewRequest(http.MethodDelete, "/userExample/1/files/"+fileID1, nil), "t1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = serveFileRequest(r, httptest.NewRequest(http.MethodGet, "/userExample/1/files/"+fileID1, nil), "t1")
+	assertErrorCode(t, w, ecode.NotFound)
+
+	// the content is kept for the other file
+	w = serveFileRequest(r, httptest.NewRequest(http.MethodGet, "/userExample/1/files/"+fileID2, nil), "t1")
+	assert.Equal(t, "hello world", w.Body.String())
+
+	// the content is deleted with the last file
+	file, _ := h.fileDao.GetByID(context.Background(), 2)
+	w = serveFileRequest(r, httptest.NewRequest(http.MethodDelete, "/userExample/1/files/"+fileID2, nil), "t1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	_, err := h.storage.Get(context.Background(), file.Key)
+	assert.ErrorIs(t, err, gofile.ErrObjectNotFound)
+}
+
+func Test_userExampleFileHandler_Quota(t *testing.T) {
+	r, h := newUserExampleFileRouter(t)
+	h.maxFiles = 2
+	h.maxFilesSize = 20
+
+	assert.Equal(t, http.StatusOK, uploadFile(r, "1", "t1", "a.txt", "hello").Code)
+	w := uploadFile(r, "1", "t1", "b.txt", "hello world, hello world")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "quota_exceeded")
+	assert.Contains(t, w.Body.String(), `"maxSize":20`)
+
+	assert.Equal(t, http.StatusOK, uploadFile(r, "1", "t1", "b.txt", "world").Code)
+	w = uploadFile(r, "1", "t1", "c.txt", "!")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), `"files":2`)
+}
@@ -0,0 +1,696 @@
+package handler
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/jinzhu/copier"
+	"gorm.io/gorm"
+
+	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
+	"github.com/go-dev-frame/sponge/pkg/gin/response"
+	"github.com/go-dev-frame/sponge/pkg/gocron"
+	"github.com/go-dev-frame/sponge/pkg/krand"
+	"github.com/go-dev-frame/sponge/pkg/logger"
+	"github.com/go-dev-frame/sponge/pkg/quota"
+	"github.com/go-dev-frame/sponge/pkg/utils"
+
+	"github.com/go-dev-frame/sponge/internal/cache"
+	"github.com/go-dev-frame/sponge/internal/dao"
+	"github.com/go-dev-frame/sponge/internal/database"
+	"github.com/go-dev-frame/sponge/internal/ecode"
+	"github.com/go-dev-frame/sponge/internal/model"
+	"github.com/go-dev-frame/sponge/internal/types"
+)
+
+// the statuses of the import jobs
+const (
+	userExampleImportPending   = "pending"
+	userExampleImportRunning   = "running"
+	userExampleImportCompleted = "completed"
+	userExampleImportFailed    = "failed"
+)
+
+const (
+	defaultUserExampleImportMaxFileSize   = 10 << 20
+	defaultUserExampleImportMaxRows       = 10000
+	defaultUserExampleImportPreviewErrors = 20
+	defaultUserExampleImportBatchSize     = 100
+	// the job is taken over by another worker if its progress is not updated in the time
+	defaultUserExampleImportStaleTimeout = 2 * time.Minute
+)
+
+var _ UserExampleImportHandler = (*userExampleImportHandler)(nil)
+
+// UserExampleImportHandler defining the handler interface of importing the userExample records from csv files
+type UserExampleImportHandler interface {
+	Import(c *gin.Context)
+	GetJob(c *gin.Context)
+	DownloadErrors(c *gin.Context)
+}
+
+type userExampleImportHandler struct {
+	jobDao dao.UserExampleImportJobDao
+	worker *userExampleImportWorker
+
+	// the limits of the csv file
+	maxFileSize int64
+	maxRows     int
+	// the number of the errors of the invalid rows in the response
+	previewErrors int
+}
+
+// NewUserExampleImportHandler creating the handler interface
+func NewUserExampleImportHandler() UserExampleImportHandler {
+	w := defaultUserExampleImportWorker()
+	return newUserExampleImportHandler(w.jobDao, w)
+}
+
+func newUserExampleImportHandler(jobDao dao.UserExampleImportJobDao, worker *userExampleImportWorker) *userExampleImportHandler {
+	return &userExampleImportHandler{
+		jobDao:        jobDao,
+		worker:        worker,
+		maxFileSize:   defaultUserExampleImportMaxFileSize,
+		maxRows:       defaultUserExampleImportMaxRows,
+		previewErrors: defaultUserExampleImportPreviewErrors,
+	}
+}
+
+// Import the records from a csv file
+// @Summary import userExamples
+// @Description import userExamples from the csv file of the multipart form, the rows are validated by the same rules as create,
+// @Description the preview mode returns the summary and the first errors without writing, otherwise the valid rows are
+// @Description created by an async job, the progress is got by the id of the job
+// @Tags userExample
+// @accept multipart/form-data
+// @Produce json
+// @Param file formData file true "csv file, the first line is the header"
+// @Param preview formData bool false "validate only"
+// @Param mapping formData string false "json of the mapping of the fields to the csv headers, e.g. {\"name\":\"Full Name\"}"
+// @Success 200 {object} types.ImportUserExamplesReply{}
+// @Router /api/v1/userExample/import [post]
+// @Security BearerAuth
+func (h *userExampleImportHandler) Import(c *gin.Context) {
+	form := &types.ImportUserExamplesRequest{}
+	if err := c.ShouldBind(form); err != nil {
+		logger.Warn("ShouldBind error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+	headers, err := userExampleImportHeaders(form.Mapping)
+	if err != nil {
+		logger.Warn("import mapping error", logger.Err(err), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.InvalidParams.RewriteMsg(err.Error()))
+		return
+	}
+	fh, err := c.FormFile("file")
+	if err != nil {
+		logger.Warn("FormFile error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+	if fh.Size > h.maxFileSize {
+		response.Error(c, ecode.InvalidParams.RewriteMsg(fmt.Sprintf("the file is larger than %d bytes", h.maxFileSize)))
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		logger.Error("Open error", logger.Err(err), middleware.GCtxRequestIDField(c))
+		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		return
+	}
+	defer f.Close() //nolint
+
+	rows, rowErrs, total, err := h.parse(f, headers)
+	if err != nil {
+		logger.Warn("import parse error", logger.Err(err), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.InvalidParams.RewriteMsg(err.Error()))
+		return
+	}
+	result := &types.ImportUserExamplesResult{
+		Total:   total,
+		Valid:   len(rows),
+		Invalid: total - len(rows),
+		Errors:  rowErrs,
+	}
+	if len(result.Errors) > h.previewErrors {
+		result.Errors = result.Errors[:h.previewErrors]
+	}
+	if form.Preview {
+		response.Success(c, result)
+		return
+	}
+
+	job, err := newUserExampleImportJob(c, rows, rowErrs, total)
+	if err != nil {
+		logger.Error("newUserExampleImportJob error", logger.Err(err), middleware.GCtxRequestIDField(c))
+		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		return
+	}
+	err = h.jobDao.Create(middleware.WrapCtx(c), job)
+	if err != nil {
+		logger.Error("Create import job error", logger.Err(err), middleware.GCtxRequestIDField(c))
+		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		return
+	}
+	h.worker.kick()
+
+	result.JobID = job.ID
+	response.Success(c, result)
+}
+
+// GetJob get the progress of an import job
+// @Summary get userExample import job
+// @Description get the status and the progress of the import job by id
+// @Tags userExample
+// @Produce json
+// @Param jobID path string true "id of the job"
+// @Success 200 {object} types.GetUserExampleImportJobReply{}
+// @Router /api/v1/userExample/import/{jobID} [get]
+// @Security BearerAuth
+func (h *userExampleImportHandler) GetJob(c *gin.Context) {
+	job, isAbort := h.getJob(c)
+	if isAbort {
+		return
+	}
+
+	response.Success(c, gin.H{"job": &types.UserExampleImportJobObjDetail{
+		ID:        job.ID,
+		Status:    job.Status,
+		Total:     job.Total,
+		Valid:     job.Valid,
+		Processed: job.Processed,
+		Succeeded: job.Succeeded,
+		Failed:    job.Failed,
+		CreatedAt: job.CreatedAt,
+		UpdatedAt: job.UpdatedAt,
+	}})
+}
+
+// DownloadErrors download the error report of an import job
+// @Summary download userExample import errors
+// @Description download the csv report of the invalid rows and the rows failed to create of the import job
+// @Tags userExample
+// @Produce text/csv
+// @Param jobID path string true "id of the job"
+// @Success 200 {file} file
+// @Router /api/v1/userExample/import/{jobID}/errors [get]
+// @Security BearerAuth
+func (h *userExampleImportHandler) DownloadErrors(c *gin.Context) {
+	job, isAbort := h.getJob(c)
+	if isAbort {
+		return
+	}
+	rowErrs, err := unmarshalUserExampleImportErrors(job.Errors)
+	if err != nil {
+		logger.Error("unmarshal import errors error", logger.Err(err), logger.Any("jobID", job.ID), middleware.GCtxRequestIDField(c))
+		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="userExample-import-%d-errors.csv"`, job.ID))
+	c.Status(http.StatusOK)
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write([]string{"line", "field", "error"})
+	for _, rowErr := range rowErrs {
+		_ = w.Write([]string{strconv.Itoa(rowErr.Line), rowErr.Field, rowErr.Error})
+	}
+	w.Flush()
+}
+
+func (h *userExampleImportHandler) getJob(c *gin.Context) (*model.UserExampleImportJob, bool) {
+	jobID, err := utils.StrToUint64E(c.Param("jobID"))
+	if err != nil || jobID == 0 {
+		logger.Warn("StrToUint64E error: ", logger.String("jobID", c.Param("jobID")), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.InvalidParams)
+		return nil, true
+	}
+
+	job, err := h.jobDao.GetByID(middleware.WrapCtx(c), jobID, GetTenantID(c))
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			logger.Warn("GetByID not found", logger.Err(err), logger.Any("jobID", jobID), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.NotFound)
+		} else {
+			logger.Error("GetByID error", logger.Err(err), logger.Any("jobID", jobID), middleware.GCtxRequestIDField(c))
+			response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		}
+		return nil, true
+	}
+	return job, false
+}
+
+// -------------------------------------------------------------------------------------------
+
+// the valid row of the csv file saved in the job
+type userExampleImportRow struct {
+	Line int                             `json:"line"`
+	Data *types.CreateUserExampleRequest `json:"data"`
+}
+
+// the field of CreateUserExampleRequest and its csv header
+type userExampleImportField struct {
+	index  int    // index of the struct field
+	name   string // json name
+	header string
+}
+
+var userExampleImportType = reflect.TypeOf(types.CreateUserExampleRequest{})
+
+// the fields of the csv file, the headers are the json names of the fields or the headers of the mapping
+func userExampleImportHeaders(mapping string) ([]userExampleImportField, error) {
+	headers := map[string]string{}
+	if mapping != "" {
+		if err := json.Unmarshal([]byte(mapping), &headers); err != nil {
+			return nil, fmt.Errorf("invalid mapping: %v", err)
+		}
+	}
+
+	fields := make([]userExampleImportField, 0, userExampleImportType.NumField())
+	for i := 0; i < userExampleImportType.NumField(); i++ {
+		name := strings.Split(userExampleImportType.Field(i).Tag.Get("json"), ",")[0]
+		header := name
+		if v, ok := headers[name]; ok {
+			header = v
+			delete(headers, name)
+		}
+		fields = append(fields, userExampleImportField{index: i, name: name, header: header})
+	}
+	if len(headers) > 0 {
+		names := make([]string, 0, len(headers))
+		for name := range headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("invalid mapping: unknown fields %v", names)
+	}
+	return fields, nil
+}
+
+// parse the rows of the csv file one by one, the rows are validated by the binding rules of
+// CreateUserExampleRequest, the same as Create, the malformed and invalid rows are reported by the errors,
+// an error is returned if the file itself is invalid, e.g. missing the headers or too many rows
+func (h *userExampleImportHandler) parse(r io.Reader, fields []userExampleImportField) (
+	[]*userExampleImportRow, []types.UserExampleImportRowError, int, error) {
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("invalid header: %v", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff") // BOM of the files saved by excel
+		}
+		columns[strings.TrimSpace(name)] = i
+	}
+	indexes := make([]int, len(fields))
+	for i, field := range fields {
+		index, ok := columns[field.header]
+		if !ok {
+			return nil, nil, 0, fmt.Errorf("missing column '%s'", field.header)
+		}
+		indexes[i] = index
+	}
+	reader.FieldsPerRecord = len(header)
+
+	var (
+		rows    []*userExampleImportRow
+		rowErrs []types.UserExampleImportRowError
+		total   int
+	)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		total++
+		if total > h.maxRows {
+			return nil, nil, 0, fmt.Errorf("the file has more than %d rows", h.maxRows)
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, nil, 0, err
+			}
+			rowErrs = append(rowErrs, types.UserExampleImportRowError{Line: parseErr.StartLine, Error: parseErr.Err.Error()})
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+
+		form, errs := parseUserExampleImportRecord(record, fields, indexes)
+		if len(errs) > 0 {
+			for _, e := range errs {
+				e.Line = line
+				rowErrs = append(rowErrs, e)
+			}
+			continue
+		}
+		rows = append(rows, &userExampleImportRow{Line: line, Data: form})
+	}
+	return rows, rowErrs, total, nil
+}
+
+func parseUserExampleImportRecord(record []string, fields []userExampleImportField, indexes []int) (
+	*types.CreateUserExampleRequest, []types.UserExampleImportRowError) {
+	form := &types.CreateUserExampleRequest{}
+	v := reflect.ValueOf(form).Elem()
+	var errs []types.UserExampleImportRowError
+	for i, field := range fields {
+		value := strings.TrimSpace(record[indexes[i]])
+		fv := v.Field(field.index)
+		switch fv.Kind() {
+		case reflect.String:
+			fv.SetString(value)
+		case reflect.Int, reflect.Int64:
+			if value == "" {
+				continue
+			}
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				errs = append(errs, types.UserExampleImportRowError{Field: field.name, Error: fmt.Sprintf("'%s' is not an integer", value)})
+				continue
+			}
+			fv.SetInt(n)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	err := binding.Validator.ValidateStruct(form)
+	if err == nil {
+		return form, nil
+	}
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return nil, []types.UserExampleImportRowError{{Error: err.Error()}}
+	}
+	for _, fe := range validationErrs {
+		name := fe.Field()
+		for _, field := range fields {
+			if userExampleImportType.Field(field.index).Name == fe.StructField() {
+				name = field.name
+			}
+		}
+		rule := fe.Tag()
+		if fe.Param() != "" {
+			rule += "=" + fe.Param()
+		}
+		errs = append(errs, types.UserExampleImportRowError{Field: name, Error: "the value does not match the rule '" + rule + "'"})
+	}
+	return nil, errs
+}
+
+func newUserExampleImportJob(c *gin.Context, rows []*userExampleImportRow, rowErrs []types.UserExampleImportRowError, total int) (*model.UserExampleImportJob, error) {
+	rowsData, err := json.Marshal(rows)
+	if err != nil {
+		return nil, err
+	}
+	errsData, err := json.Marshal(rowErrs)
+	if err != nil {
+		return nil, err
+	}
+	actor := ""
+	if claims, ok := middleware.GetClaims(c); ok {
+		actor = claims.UID
+	}
+	return &model.UserExampleImportJob{
+		TenantID: GetTenantID(c),
+		Actor:    actor,
+		Status:   userExampleImportPending,
+		Total:    total,
+		Valid:    len(rows),
+		Failed:   total - len(rows),
+		Rows:     string(rowsData),
+		Errors:   string(errsData),
+	}, nil
+}
+
+func unmarshalUserExampleImportErrors(data string) ([]types.UserExampleImportRowError, error) {
+	rowErrs := []types.UserExampleImportRowError{}
+	if data == "" {
+		return rowErrs, nil
+	}
+	err := json.Unmarshal([]byte(data), &rowErrs)
+	return rowErrs, err
+}
+
+// -------------------------------------------------------------------------------------------
+
+var (
+	userExampleImportWorkerInstance *userExampleImportWorker
+	userExampleImportWorkerOnce     sync.Once
+)
+
+// UserExampleImportTask the periodic task of processing the unfinished import jobs, the jobs are processed right
+// after they are committed, the task picks up the jobs interrupted by the restart or the crash of another replica, e.g.
+//
+//	gocron.Run(UserExampleImportTask())
+func UserExampleImportTask() *gocron.Task {
+	w := defaultUserExampleImportWorker()
+	return &gocron.Task{
+		Name:     "userExample-import",
+		TimeSpec: gocron.EveryMinute(1),
+		Fn: func() {
+			w.run(context.Background())
+		},
+	}
+}
+
+// the worker shared by the handler and the periodic task, so a job is never processed concurrently
+func defaultUserExampleImportWorker() *userExampleImportWorker {
+	userExampleImportWorkerOnce.Do(func() {
+		userExampleImportWorkerInstance = newUserExampleImportWorker(
+			dao.NewUserExampleDao(
+				database.GetDB(), // todo show db driver name here
+				cache.NewUserExampleCache(database.GetCacheType()),
+			),
+			dao.NewUserExampleImportJobDao(database.GetDB()),
+		)
+		userExampleImportWorkerInstance.quota = mustUserExampleQuota()
+	})
+	return userExampleImportWorkerInstance
+}
+
+// the worker creating the records of the import jobs, a job is processed by the worker claiming it only, so the
+// replicas never process the same job, each record is created together with the progress in one transaction,
+// so the job interrupted by the restart is resumed from the next row without creating the records twice
+type userExampleImportWorker struct {
+	iDao   dao.UserExampleDao
+	jobDao dao.UserExampleImportJobDao
+	// the id of the worker claiming the jobs, unique per process
+	owner string
+	// the quota is reserved for the rows of a batch
+	batchSize int
+	// the job is taken over if its progress is not updated in the time, e.g. the owner is crashed
+	staleTimeout time.Duration
+
+	// the soft quota of the records per tenant, the rows exceeding the quota are failed, nil means disabled
+	quota *userExampleQuota
+
+	// the jobs are processed one by one in the process
+	running atomic.Bool
+}
+
+func newUserExampleImportWorker(iDao dao.UserExampleDao, jobDao dao.UserExampleImportJobDao) *userExampleImportWorker {
+	hostname, _ := os.Hostname()
+	return &userExampleImportWorker{
+		iDao:         iDao,
+		jobDao:       jobDao,
+		owner:        hostname + "-" + krand.NewStringID(),
+		batchSize:    defaultUserExampleImportBatchSize,
+		staleTimeout: defaultUserExampleImportStaleTimeout,
+	}
+}
+
+// process the unfinished jobs in the background, it returns at once if the worker is running
+func (w *userExampleImportWorker) kick() {
+	go w.run(context.Background())
+}
+
+// process the unfinished jobs claimed by the worker until there is none, the oldest first
+func (w *userExampleImportWorker) run(ctx context.Context) {
+	if !w.running.CompareAndSwap(false, true) {
+		return
+	}
+	defer w.running.Store(false)
+
+	for {
+		jobs, err := w.jobDao.GetUnfinished(ctx, []string{userExampleImportPending, userExampleImportRunning},
+			time.Now().Add(-w.staleTimeout), 10)
+		if err != nil {
+			logger.Warn("GetUnfinished import jobs error", logger.Err(err))
+			return
+		}
+		if len(jobs) == 0 {
+			return
+		}
+		for _, job := range jobs {
+			claimed, err := w.jobDao.Claim(ctx, job, w.owner, time.Now().Add(-w.staleTimeout))
+			if err != nil {
+				logger.Warn("Claim import job error", logger.Err(err), logger.Any("jobID", job.ID))
+				return
+			}
+			if !claimed { // claimed by the worker of another replica
+				continue
+			}
+			if err = w.process(ctx, job); err != nil {
+				// retried by the next run, or taken over by another replica after the job is stale
+				logger.Warn("process import job error", logger.Err(err), logger.Any("jobID", job.ID))
+				return
+			}
+		}
+	}
+}
+
+func (w *userExampleImportWorker) process(ctx context.Context, job *model.UserExampleImportJob) error {
+	rows := []*userExampleImportRow{}
+	rowErrs, err := unmarshalUserExampleImportErrors(job.Errors)
+	if err == nil {
+		err = json.Unmarshal([]byte(job.Rows), &rows)
+	}
+	if err != nil {
+		logger.Error("unmarshal import job error", logger.Err(err), logger.Any("jobID", job.ID))
+		job.Status = userExampleImportFailed
+		return w.jobDao.UpdateProgress(ctx, job)
+	}
+
+	job.Status = userExampleImportRunning
+	ctx = cache.WithTenant(ctx, job.TenantID)
+	// the number of the errors saved in job.Errors, the errors are marshaled only if there are new ones
+	savedErrs := len(rowErrs)
+	setErrors := func(job *model.UserExampleImportJob) error {
+		if len(rowErrs) == savedErrs {
+			return nil
+		}
+		data, err := json.Marshal(rowErrs)
+		job.Errors = string(data)
+		return err
+	}
+
+	for job.Processed < len(rows) {
+		end := job.Processed + w.batchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		batch := rows[job.Processed:end]
+		reserved, err := w.reserveQuota(ctx, job.TenantID, len(batch))
+		if err != nil {
+			return err
+		}
+		for i, row := range batch {
+			if i >= reserved {
+				job.Processed++
+				job.Failed++
+				rowErrs = append(rowErrs, types.UserExampleImportRowError{Line: row.Line, Error: "quota_exceeded"})
+				continue
+			}
+
+			progress := *job
+			progress.Processed++
+			progress.Succeeded++
+			err = setErrors(&progress)
+			if err != nil {
+				w.quota.release(ctx, job.TenantID, int64(reserved-i))
+				return err
+			}
+			isCreated, err := w.createRow(ctx, &progress, row)
+			if err != nil {
+				w.quota.release(ctx, job.TenantID, int64(reserved-i))
+				return err
+			}
+			if isCreated {
+				*job = progress
+				savedErrs = len(rowErrs)
+				continue
+			}
+			w.quota.release(ctx, job.TenantID, 1)
+			job.Processed++
+			job.Failed++
+			rowErrs = append(rowErrs, types.UserExampleImportRowError{Line: row.Line, Error: ecode.ErrCreateUserExample.Msg()})
+		}
+
+		// save the progress of the failed rows
+		if err = setErrors(job); err != nil {
+			return err
+		}
+		if err = w.jobDao.UpdateProgress(ctx, job); err != nil {
+			return err
+		}
+		savedErrs = len(rowErrs)
+	}
+
+	job.Status = userExampleImportCompleted
+	return w.jobDao.UpdateProgress(ctx, job)
+}
+
+// create the record of the row and save the progress of the job in one transaction, false is returned if the
+// record fails to create, the error is returned if the progress fails to save, e.g. the job is claimed by
+// another worker, the record is not created in both cases
+func (w *userExampleImportWorker) createRow(ctx context.Context, progress *model.UserExampleImportJob, row *userExampleImportRow) (bool, error) {
+	userExample := &model.UserExample{}
+	err := copier.Copy(userExample, row.Data)
+	if err == nil {
+		err = w.quota.setTenant(ctx, userExample, progress.TenantID)
+	}
+	if err != nil {
+		logger.Warn("import Copy error", logger.Err(err), logger.Any("jobID", progress.ID), logger.Int("line", row.Line))
+		return false, nil
+	}
+
+	var createErr error
+	err = w.jobDao.UpdateProgressInTx(ctx, progress, func(tx *gorm.DB) error {
+		_, createErr = w.iDao.CreateByTx(ctx, tx, userExample)
+		return createErr
+	})
+	if createErr != nil {
+		logger.Warn("import Create error", logger.Err(createErr), logger.Any("jobID", progress.ID), logger.Int("line", row.Line))
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// reserve the quota of n records of a batch for the tenant, the returned value is the number of the records
+// reserved, it is less than n if the quota is exceeded, the rest of the quota is reserved in that case
+func (w *userExampleImportWorker) reserveQuota(ctx context.Context, tenantID string, n int) (int, error) {
+	err := w.quota.reserve(ctx, tenantID, int64(n))
+	if err == nil {
+		return n, nil
+	}
+	var e *quota.ExceededError
+	if !errors.As(err, &e) {
+		return 0, err
+	}
+
+	rest := int(e.Limit - e.Current)
+	if rest <= 0 {
+		return 0, nil
+	}
+	err = w.quota.reserve(ctx, tenantID, int64(rest))
+	if err != nil {
+		if errors.As(err, &e) { // the quota is reserved by the concurrent requests
+			return 0, nil
+		}
+		return 0, err
+	}
+	return rest, nil
+}
@@ -0,0 +1,363 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/go-dev-frame/sponge/pkg/jwt"
+
+	"github.com/go-dev-frame/sponge/internal/dao"
+	"github.com/go-dev-frame/sponge/internal/database"
+	"github.com/go-dev-frame/sponge/internal/ecode"
+	"github.com/go-dev-frame/sponge/internal/model"
+	"github.com/go-dev-frame/sponge/internal/types"
+)
+
+// the jobs are saved in the map, the progress of each update is recorded
+type fakeUserExampleImportJobDao struct {
+	mu        sync.Mutex
+	jobs      map[uint64]model.UserExampleImportJob
+	processed []int
+}
+
+func (d *fakeUserExampleImportJobDao) Create(_ context.Context, table *model.UserExampleImportJob) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	table.ID = uint64(len(d.jobs) + 1)
+	table.CreatedAt = time.Now()
+	d.jobs[table.ID] = *table
+	return nil
+}
+
+func (d *fakeUserExampleImportJobDao) GetByID(_ context.Context, id uint64, tenantID string) (*model.UserExampleImportJob, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	job, ok := d.jobs[id]
+	if !ok || job.TenantID != tenantID {
+		return nil, database.ErrRecordNotFound
+	}
+	job.Rows = ""
+	return &job, nil
+}
+
+func (d *fakeUserExampleImportJobDao) GetUnfinished(_ context.Context, statuses []string, staleBefore time.Time, limit int) ([]*model.UserExampleImportJob, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var records []*model.UserExampleImportJob
+	for id := uint64(1); id <= uint64(len(d.jobs)) && len(records) < limit; id++ {
+		job := d.jobs[id]
+		if job.Owner != "" && !job.UpdatedAt.Before(staleBefore) {
+			continue
+		}
+		for _, status := range statuses {
+			if job.Status == status {
+				records = append(records, &job)
+			}
+		}
+	}
+	return records, nil
+}
+
+func (d *fakeUserExampleImportJobDao) Claim(_ context.Context, table *model.UserExampleImportJob, owner string, staleBefore time.Time) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	job := d.jobs[table.ID]
+	if job.Status != table.Status || job.Owner != table.Owner || (job.Owner != "" && !job.UpdatedAt.Before(staleBefore)) {
+		return false, nil
+	}
+	job.Owner, job.UpdatedAt = owner, time.Now()
+	d.jobs[table.ID] = job
+	table.Owner, table.UpdatedAt = job.Owner, job.UpdatedAt
+	return true, nil
+}
+
+func (d *fakeUserExampleImportJobDao) UpdateProgress(_ context.Context, table *model.UserExampleImportJob) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	job := d.jobs[table.ID]
+	if job.Owner != table.Owner {
+		return dao.ErrUserExampleImportJobClaimed
+	}
+	job.Status, job.Processed, job.Succeeded, job.Failed, job.Errors = table.Status, table.Processed, table.Succeeded, table.Failed, table.Errors
+	job.UpdatedAt = time.Now()
+	d.jobs[table.ID] = job
+	d.processed = append(d.processed, table.Processed)
+	return nil
+}
+
+// fn is not called if the job is claimed by another worker, like the rollback of the transaction
+func (d *fakeUserExampleImportJobDao) UpdateProgressInTx(ctx context.Context, table *model.UserExampleImportJob, fn func(tx *gorm.DB) error) error {
+	d.mu.Lock()
+	owner := d.jobs[table.ID].Owner
+	d.mu.Unlock()
+	if owner != table.Owner {
+		return dao.ErrUserExampleImportJobClaimed
+	}
+	if err := fn(nil); err != nil {
+		return err
+	}
+	return d.UpdateProgress(ctx, table)
+}
+
+func (d *fakeUserExampleImportJobDao) get(id uint64) model.UserExampleImportJob {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.jobs[id]
+}
+
+// the records of the name are failed to create
+type failingUserExampleDao struct {
+	fakeRecordsUserExampleDao
+	failName string
+}
+
+func (d *failingUserExampleDao) Create(ctx context.Context, table *model.UserExample) error {
+	if table.Name == d.failName {
+		return errors.New("duplicate entry")
+	}
+	return d.fakeRecordsUserExampleDao.Create(ctx, table)
+}
+
+func (d *failingUserExampleDao) CreateByTx(ctx context.Context, _ *gorm.DB, table *model.UserExample) (uint64, error) {
+	err := d.Create(ctx, table)
+	return table.ID, err
+}
+
+type userExampleImportResult struct {
+	Code int                            `json:"code"`
+	Msg  string                         `json:"msg"`
+	Data types.ImportUserExamplesResult `json:"data"`
+}
+
+func newUserExampleImportServer(h *userExampleImportHandler) *gin.Engine {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	g := r.Group("/userExample/import", func(c *gin.Context) {
+		c.Set("claims", &jwt.Claims{UID: "100", Fields: map[string]interface{}{tenantIDField: c.GetHeader("X-Tenant-Id")}})
+	})
+	g.POST("", h.Import)
+	g.GET("/:jobID", h.GetJob)
+	g.GET("/:jobID/errors", h.DownloadErrors)
+	return r
+}
+
+func postUserExampleImport(r *gin.Engine, content string, fields map[string]string) *httptest.ResponseRecorder {
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	for k, v := range fields {
+		_ = mw.WriteField(k, v)
+	}
+	if content != "" {
+		fw, _ := mw.CreateFormFile("file", "userExamples.csv")
+		_, _ = fw.Write([]byte(content))
+	}
+	_ = mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/userExample/import", body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("X-Tenant-Id", "t1")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+const userExampleImportHeader = "Full Name,email,password,phone,avatar,age,gender\n"
+
+func userExampleImportLine(name string) string {
+	return name + ",foo@bar.com,e10adc3949ba59abbe56e057f20f883e,+8613800138000,a.png,10,1\n"
+}
+
+func Test_userExampleImportHandler_Preview(t *testing.T) {
+	jobDao := &fakeUserExampleImportJobDao{jobs: map[uint64]model.UserExampleImportJob{}}
+	iDao := &fakeRecordsUserExampleDao{records: map[uint64]model.UserExample{}}
+	h := newUserExampleImportHandler(jobDao, newUserExampleImportWorker(iDao, jobDao))
+	r := newUserExampleImportServer(h)
+	mapping := map[string]string{"mapping": `{"name":"Full Name"}`, "preview": "true"}
+
+	content := "\ufeff" + userExampleImportHeader +
+		userExampleImportLine("foo") + // line 2
+		"bar,foo@bar.com,e10adc3949ba59abbe56e057f20f883e,+8613800138000,a.png,abc,1\n" + // not an integer
+		"bar,foo@bar.com\n" + // wrong number of fields
+		"b\"ar,foo@bar.com,e10adc3949ba59abbe56e057f20f883e,+8613800138000,a.png,10,1\n" + // bare quote
+		"b,not-email,e10adc3949ba59abbe56e057f20f883e,+8613800138000,a.png,10,1\n" + // invalid fields
+		userExampleImportLine(`"baz, qux"`) // line 7
+	w := postUserExampleImport(r, content, mapping)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	result := &userExampleImportResult{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), result))
+	assert.Equal(t, 6, result.Data.Total)
+	assert.Equal(t, 2, result.Data.Valid)
+	assert.Equal(t, 4, result.Data.Invalid)
+	assert.Zero(t, result.Data.JobID)
+	var lines, fields []string
+	for _, rowErr := range result.Data.Errors {
+		lines = append(lines, fmt.Sprint(rowErr.Line))
+		fields = append(fields, rowErr.Field)
+	}
+	assert.Equal(t, []string{"3", "4", "5", "6", "6"}, lines)
+	assert.Equal(t, []string{"age", "", "", "name", "email"}, fields)
+	assert.Equal(t, "the value does not match the rule 'min=2'", result.Data.Errors[3].Error)
+
+	// nothing is written in the preview mode
+	assert.Empty(t, jobDao.jobs)
+	assert.Empty(t, iDao.records)
+
+	// the number of the errors in the response is limited
+	h.previewErrors = 2
+	w = postUserExampleImport(r, content, mapping)
+	result = &userExampleImportResult{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), result))
+	assert.Equal(t, 4, result.Data.Invalid)
+	assert.Len(t, result.Data.Errors, 2)
+
+	// the invalid files
+	h.maxRows = 3
+	for name, tt := range map[string]struct {
+		content string
+		fields  map[string]string
+	}{
+		"no file":         {"", mapping},
+		"empty file":      {" ", mapping},
+		"missing column":  {userExampleImportHeader + userExampleImportLine("foo"), nil},
+		"unknown mapping": {userExampleImportHeader, map[string]string{"mapping": `{"nickname":"Full Name"}`}},
+		"invalid mapping": {userExampleImportHeader, map[string]string{"mapping": `{"name"`}},
+		"too many rows":   {userExampleImportHeader + strings.Repeat(userExampleImportLine("foo"), 4), mapping},
+	} {
+		w = postUserExampleImport(r, tt.content, tt.fields)
+		t.Log(name, w.Body.String())
+		assertErrorCode(t, w, ecode.InvalidParams)
+	}
+}
+
+func Test_userExampleImportHandler_Commit(t *testing.T) {
+	jobDao := &fakeUserExampleImportJobDao{jobs: map[uint64]model.UserExampleImportJob{}}
+	iDao := &failingUserExampleDao{fakeRecordsUserExampleDao: fakeRecordsUserExampleDao{records: map[uint64]model.UserExample{}}, failName: "dup"}
+	worker := newUserExampleImportWorker(iDao, jobDao)
+	worker.batchSize = 2
+	h := newUserExampleImportHandler(jobDao, worker)
+	r := newUserExampleImportServer(h)
+	serve := func(path string, tenantID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Tenant-Id", tenantID)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	content := userExampleImportHeader + userExampleImportLine("foo1") + userExampleImportLine("foo2") +
+		"x,foo@bar.com,e10adc3949ba59abbe56e057f20f883e,+8613800138000,a.png,10,1\n" + // line 4, invalid
+		userExampleImportLine("dup") + userExampleImportLine("foo3") + userExampleImportLine("foo4") // line 5 is failed to create
+	w := postUserExampleImport(r, content, map[string]string{"mapping": `{"name":"Full Name"}`})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	result := &userExampleImportResult{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), result))
+	assert.Equal(t, 5, result.Data.Valid)
+	require.NotZero(t, result.Data.JobID)
+
+	// the job is processed in the background
+	jobPath := fmt.Sprintf("/userExample/import/%d", result.Data.JobID)
+	assert.Eventually(t, func() bool {
+		return jobDao.get(result.Data.JobID).Status == userExampleImportCompleted
+	}, time.Second*3, time.Millisecond*10)
+
+	// the progress is saved with each created record and after each batch
+	assert.Equal(t, []int{1, 2, 2, 4, 4, 5, 5, 5}, jobDao.processed)
+	w = serve(jobPath, "t1")
+	require.Equal(t, http.StatusOK, w.Code)
+	jobResult := &types.GetUserExampleImportJobReply{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), jobResult))
+	job := jobResult.Data.Job
+	assert.Equal(t, userExampleImportCompleted, job.Status)
+	assert.Equal(t, []int{6, 5, 5, 4, 2}, []int{job.Total, job.Valid, job.Processed, job.Succeeded, job.Failed})
+	assert.Len(t, iDao.records, 4)
+
+	// the report of the invalid rows and the rows failed to create
+	w = serve(jobPath+"/errors", "t1")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+	records, err := csv.NewReader(w.Body).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"line", "field", "error"},
+		{"4", "name", "the value does not match the rule 'min=2'"},
+		{"5", "", "failed to create userExample"},
+	}, records)
+
+	// the job of the other tenant is not found
+	assertErrorCode(t, serve(jobPath, "t2"), ecode.NotFound)
+	assertErrorCode(t, serve(jobPath+"/errors", "t2"), ecode.NotFound)
+	assertErrorCode(t, serve("/userExample/import/abc", "t1"), ecode.InvalidParams)
+
+	// the interrupted job is resumed from the saved progress
+	jobDao.processed = nil
+	job2 := &model.UserExampleImportJob{TenantID: "t1", Status: userExampleImportRunning, Total: 3, Valid: 3, Processed: 2, Succeeded: 2,
+		Rows: `[{"line":2,"data":{"name":"r1"}},{"line":3,"data":{"name":"r2"}},{"line":4,"data":{"name":"r3"}}]`}
+	require.NoError(t, jobDao.Create(context.Background(), job2))
+	worker.run(context.Background())
+	assert.Equal(t, []int{3, 3, 3}, jobDao.processed)
+	assert.Equal(t, 3, jobDao.get(job2.ID).Succeeded)
+	assert.Len(t, iDao.records, 5)
+
+	// the job of the invalid rows is failed
+	job3 := &model.UserExampleImportJob{Status: userExampleImportPending, Rows: "{"}
+	require.NoError(t, jobDao.Create(context.Background(), job3))
+	worker.run(context.Background())
+	assert.Equal(t, userExampleImportFailed, jobDao.get(job3.ID).Status)
+}
+
+func Test_userExampleImportWorker_Claim(t *testing.T) {
+	jobDao := &fakeUserExampleImportJobDao{jobs: map[uint64]model.UserExampleImportJob{}}
+	iDao := &fakeRecordsUserExampleDao{records: map[uint64]model.UserExample{}}
+	worker := newUserExampleImportWorker(iDao, jobDao)
+	ctx := context.Background()
+	rows := `[{"line":2,"data":{"name":"r1"}},{"line":3,"data":{"name":"r2"}}]`
+
+	// the job processed by the worker of another replica is skipped until its progress is stale
+	job := &model.UserExampleImportJob{Status: userExampleImportRunning, Owner: "other", Total: 2, Valid: 2, Rows: rows}
+	job.UpdatedAt = time.Now()
+	require.NoError(t, jobDao.Create(ctx, job))
+	worker.run(ctx)
+	assert.Empty(t, iDao.records)
+	assert.Equal(t, "other", jobDao.get(job.ID).Owner)
+
+	worker.staleTimeout = 0
+	worker.run(ctx)
+	assert.Equal(t, worker.owner, jobDao.get(job.ID).Owner)
+	assert.Equal(t, userExampleImportCompleted, jobDao.get(job.ID).Status)
+	assert.Len(t, iDao.records, 2)
+
+	// the worker stops after the job is taken over, the record of the rejected progress is not created
+	job2 := &model.UserExampleImportJob{Status: userExampleImportPending, Total: 2, Valid: 2, Rows: rows}
+	require.NoError(t, jobDao.Create(ctx, job2))
+	claimed, err := jobDao.Claim(ctx, job2, worker.owner, time.Now())
+	require.NoError(t, err)
+	require.True(t, claimed)
+	claimed, err = jobDao.Claim(ctx, &model.UserExampleImportJob{Model: job2.Model, Status: userExampleImportPending}, "other", time.Now())
+	require.NoError(t, err)
+	assert.False(t, claimed)
+
+	jobDao.mu.Lock()
+	stale := jobDao.jobs[job2.ID]
+	stale.Owner = "other"
+	jobDao.jobs[job2.ID] = stale
+	jobDao.mu.Unlock()
+	err = worker.process(ctx, job2)
+	assert.ErrorIs(t, err, dao.ErrUserExampleImportJobClaimed)
+	assert.Len(t, iDao.records, 2)
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
+	"github.com/go-dev-frame/sponge/pkg/logger"
+)
+
+// the tolerated clock skew between the replicas recording the writes, default of lastModifiedSkew
+const defaultUserExampleLastModifiedSkew = 2 * time.Second
+
+// checkNotModified set the Last-Modified header of the list response and report whether the client's
+// If-Modified-Since is still current, in which case 304 is responded and the database is not queried.
+//
+// The last modified time is per resource (and per tenant), not per filter, so a write to any record
+// changes it for every filtered list, which is conservative but correct. The tenant time assumes the
+// records of a tenant are only written by the requests of that tenant, see wrapTenantCtx.
+//
+// The header has a precision of one second and the times are recorded by the clocks of different
+// replicas, so the header is only sent once the second of the last write has passed by more than
+// lastModifiedSkew, until then a later write could still be recorded with an earlier or equal time.
+// A malformed If-Modified-Since header is ignored.
+func (h *userExampleHandler) checkNotModified(c *gin.Context, ctx context.Context) (isNotModified bool) {
+	lastModified, err := h.iDao.GetLastModified(ctx)
+	if err != nil {
+		logger.Warn("GetLastModified error", logger.Err(err), middleware.GCtxRequestIDField(c))
+		return false
+	}
+	if lastModified.IsZero() {
+		return false
+	}
+
+	now := time.Now
+	if h.now != nil {
+		now = h.now
+	}
+	lastModified = lastModified.Truncate(time.Second)
+	if now().Sub(lastModified) <= time.Second+h.lastModifiedSkew {
+		return false
+	}
+	c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+
+	ims, err := http.ParseTime(c.GetHeader("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	if lastModified.After(ims) {
+		return false
+	}
+
+	c.AbortWithStatus(http.StatusNotModified)
+	return true
+}
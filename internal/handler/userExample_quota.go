@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
+	"github.com/go-dev-frame/sponge/pkg/gin/response"
+	"github.com/go-dev-frame/sponge/pkg/gocron"
+	"github.com/go-dev-frame/sponge/pkg/logger"
+	"github.com/go-dev-frame/sponge/pkg/quota"
+
+	"github.com/go-dev-frame/sponge/internal/config"
+	"github.com/go-dev-frame/sponge/internal/database"
+	"github.com/go-dev-frame/sponge/internal/ecode"
+	"github.com/go-dev-frame/sponge/internal/model"
+)
+
+// the default column of the tenant id of the records counted by the quota
+const defaultUserExampleTenantColumn = "tenant_id"
+
+var (
+	userExampleQuotaInstance *userExampleQuota
+	userExampleQuotaErr      error
+	userExampleQuotaOnce     sync.Once
+)
+
+// userExampleQuota the soft quota of the userExample records per tenant, the records are counted by the tenant
+// column, which is set to the tenant of the request when a record is created
+type userExampleQuota struct {
+	*quota.Quota
+	tenantField *schema.Field
+}
+
+// newUserExampleQuota create the quota by the configuration, nil is returned if it is disabled, an error is
+// returned if the tenant column does not exist in the table
+func newUserExampleQuota(db *gorm.DB, client redis.UniversalClient, cfg config.Quota) (*userExampleQuota, error) {
+	if !cfg.Enable {
+		return nil, nil
+	}
+	if cfg.Limit < 0 {
+		return nil, fmt.Errorf("invalid quota limit %d", cfg.Limit)
+	}
+
+	column := cfg.TenantColumn
+	if column == "" {
+		column = defaultUserExampleTenantColumn
+	}
+	s, err := schema.Parse(&model.UserExample{}, &sync.Map{}, db.NamingStrategy)
+	if err != nil {
+		return nil, err
+	}
+	field := s.LookUpField(column)
+	if field == nil || field.DBName == "" {
+		return nil, fmt.Errorf("the tenant column '%s' of the quota does not exist in the table %s", column, s.Table)
+	}
+
+	q, err := quota.New(client, "userExample",
+		func(ctx context.Context, tenant string) (int64, error) {
+			if limit, ok := cfg.TenantLimits[tenant]; ok {
+				return int64(limit), nil
+			}
+			return int64(cfg.Limit), nil
+		},
+		func(ctx context.Context, tenant string) (int64, error) {
+			var total int64
+			err := db.WithContext(ctx).Model(&model.UserExample{}).
+				Where(field.DBName+" = ?", tenant).Count(&total).Error
+			return total, err
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &userExampleQuota{Quota: q, tenantField: field}, nil
+}
+
+// the quota shared by the handler, the import worker and the reconciliation task, nil if it is disabled
+func defaultUserExampleQuota() (*userExampleQuota, error) {
+	userExampleQuotaOnce.Do(func() {
+		cfg := config.Get().Quota
+		if !cfg.Enable {
+			return
+		}
+		userExampleQuotaInstance, userExampleQuotaErr = newUserExampleQuota(database.GetDB(), database.GetRedisCli(), cfg)
+	})
+	return userExampleQuotaInstance, userExampleQuotaErr
+}
+
+// the quota of the handler, the quota is disabled if it fails to create, so the writes are not blocked by
+// a wrong configuration
+func mustUserExampleQuota() *userExampleQuota {
+	q, err := defaultUserExampleQuota()
+	if err != nil {
+		logger.Error("create userExample quota error, the quota is disabled", logger.Err(err))
+		return nil
+	}
+	return q
+}
+
+// UserExampleQuotaReconcileTask the periodic task of recounting the usage from the database, nil is returned if
+// the quota is disabled, e.g.
+//
+//	task, err := UserExampleQuotaReconcileTask()
+//	if err == nil && task != nil {
+//		gocron.Run(task)
+//	}
+func UserExampleQuotaReconcileTask() (*gocron.Task, error) {
+	q, err := defaultUserExampleQuota()
+	if err != nil || q == nil {
+		return nil, err
+	}
+	return q.ReconcileTask(gocron.EveryMinute(10)), nil
+}
+
+// set the tenant column of the record to the tenant, so it is counted by the quota
+func (q *userExampleQuota) setTenant(ctx context.Context, record *model.UserExample, tenantID string) error {
+	if q == nil || tenantID == "" {
+		return nil
+	}
+	return q.tenantField.Set(ctx, reflect.ValueOf(record).Elem(), tenantID)
+}
+
+// reserve n records for the tenant, an error of *quota.ExceededError is returned if the quota is exceeded
+func (q *userExampleQuota) reserve(ctx context.Context, tenantID string, n int64) error {
+	if q == nil || tenantID == "" {
+		return nil
+	}
+	return q.Reserve(ctx, tenantID, n)
+}
+
+// release n records of the tenant after they are deleted or failed to create, the error is logged by the quota,
+// the counter is healed by reconciliation
+func (q *userExampleQuota) release(ctx context.Context, tenantID string, n int64) {
+	if q == nil || tenantID == "" || n < 1 {
+		return
+	}
+	_ = q.Release(ctx, tenantID, n)
+}
+
+// reserve n records for the tenant, respond 403 with the current usage and limit if the quota is exceeded
+func (h *userExampleHandler) reserveQuota(c *gin.Context, tenantID string, n int64) (isAbort bool) {
+	err := h.quota.reserve(middleware.WrapCtx(c), tenantID, n)
+	if err == nil {
+		return false
+	}
+	var e *quota.ExceededError
+	if errors.As(err, &e) {
+		response.Out(c, ecode.Forbidden.RewriteMsg("quota_exceeded"), gin.H{"current": e.Current, "limit": e.Limit})
+		return true
+	}
+	logger.Error("reserve quota error", logger.Err(err), logger.String("tenantID", tenantID), middleware.GCtxRequestIDField(c))
+	response.Output(c, ecode.InternalServerError.ToHTTPCode())
+	return true
+}
+
+// release n records of the tenant after they are deleted or failed to create
+func (h *userExampleHandler) releaseQuota(c *gin.Context, tenantID string, n int64) {
+	h.quota.release(middleware.WrapCtx(c), tenantID, n)
+}
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-dev-frame/sponge/pkg/gotest"
+	"github.com/go-dev-frame/sponge/pkg/jwt"
+
+	"github.com/go-dev-frame/sponge/internal/config"
+	"github.com/go-dev-frame/sponge/internal/model"
+	"github.com/go-dev-frame/sponge/internal/types"
+)
+
+// the table has no tenant column, the name column is used as the tenant column in the tests
+func newTestUserExampleQuota(t *testing.T, limits map[string]int) (*userExampleQuota, sqlmock.Sqlmock) {
+	d := gotest.NewDao(nil, nil)
+	c := gotest.NewCache(nil)
+	t.Cleanup(func() {
+		d.Close()
+		c.Close()
+	})
+
+	q, err := newUserExampleQuota(d.DB, c.RedisClient, config.Quota{Enable: true, TenantColumn: "name", Limit: 1, TenantLimits: limits})
+	require.NoError(t, err)
+	require.NotNil(t, q)
+	return q, d.SQLMock
+}
+
+func expectUserExampleCount(mock sqlmock.Sqlmock, tenantID string, count int) {
+	mock.ExpectQuery("SELECT count.*").WithArgs(tenantID).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(count))
+}
+
+func Test_newUserExampleQuota(t *testing.T) {
+	d := gotest.NewDao(nil, nil)
+	defer d.Close()
+	c := gotest.NewCache(nil)
+	defer c.Close()
+
+	// disabled
+	q, err := newUserExampleQuota(d.DB, c.RedisClient, config.Quota{Limit: 10})
+	assert.NoError(t, err)
+	assert.Nil(t, q)
+
+	// the default tenant column does not exist in the table
+	_, err = newUserExampleQuota(d.DB, c.RedisClient, config.Quota{Enable: true, Limit: 10})
+	assert.ErrorContains(t, err, "tenant_id")
+	_, err = newUserExampleQuota(d.DB, c.RedisClient, config.Quota{Enable: true, TenantColumn: "foo", Limit: 10})
+	assert.Error(t, err)
+
+	// invalid limit
+	_, err = newUserExampleQuota(d.DB, c.RedisClient, config.Quota{Enable: true, TenantColumn: "name", Limit: -1})
+	assert.Error(t, err)
+
+	// the limit of the tenant overrides the default limit
+	q, err = newUserExampleQuota(d.DB, c.RedisClient, config.Quota{Enable: true, TenantColumn: "name", Limit: 10, TenantLimits: map[string]int{"t1": 20}})
+	require.NoError(t, err)
+	limit, err := q.Limit(context.Background(), "t1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(20), limit)
+	limit, err = q.Limit(context.Background(), "t2")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), limit)
+}
+
+func Test_userExampleHandler_Quota(t *testing.T) {
+	q, mock := newTestUserExampleQuota(t, nil)
+	iDao := &fakeRecordsUserExampleDao{records: map[uint64]model.UserExample{}}
+	h := &userExampleHandler{iDao: iDao, quota: q}
+
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	g := r.Group("/userExample", func(c *gin.Context) {
+		c.Set("claims", &jwt.Claims{UID: "100", Fields: map[string]interface{}{tenantIDField: c.GetHeader("X-Tenant-Id")}})
+	})
+	g.POST("", h.Create)
+	g.DELETE("/:id", h.DeleteByID)
+	serve := func(method string, path string, tenantID string) *httptest.ResponseRecorder {
+		body := `{"name":"foo","password":"e10adc3949ba59abbe56e057f20f883e","email":"foo@bar.com","phone":"+8613800138000","avatar":"a.png","age":10,"gender":1}`
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Tenant-Id", tenantID)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	expectUserExampleCount(mock, "t1", 0)
+	w := serve(http.MethodPost, "/userExample", "t1")
+	assert.Contains(t, w.Body.String(), `"id":1`)
+	assert.Equal(t, "t1", iDao.records[1].Name) // the tenant column is set
+
+	w = serve(http.MethodPost, "/userExample", "t1")
+	assert.Contains(t, w.Body.String(), "quota_exceeded")
+	assert.Contains(t, w.Body.String(), `"limit":1`)
+
+	// the quota is not released if there is no record deleted
+	assert.Equal(t, http.StatusOK, serve(http.MethodDelete, "/userExample/100", "t1").Code)
+	w = serve(http.MethodPost, "/userExample", "t1")
+	assert.Contains(t, w.Body.String(), "quota_exceeded")
+
+	// the quota is released after the record is deleted
+	assert.Equal(t, http.StatusOK, serve(http.MethodDelete, "/userExample/1", "t1").Code)
+	w = serve(http.MethodPost, "/userExample", "t1")
+	assert.Contains(t, w.Body.String(), `"id":2`)
+
+	// the requests without tenant are not limited
+	for i := 0; i < 2; i++ {
+		w = serve(http.MethodPost, "/userExample", "")
+		assert.NotContains(t, w.Body.String(), "quota_exceeded")
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_userExampleImportWorker_Quota(t *testing.T) {
+	q, mock := newTestUserExampleQuota(t, map[string]int{"t1": 3})
+	iDao := &fakeRecordsUserExampleDao{records: map[uint64]model.UserExample{}}
+	jobDao := &fakeUserExampleImportJobDao{jobs: map[uint64]model.UserExampleImportJob{}}
+	worker := newUserExampleImportWorker(iDao, jobDao)
+	worker.batchSize = 2
+	worker.quota = q
+
+	rows := []*userExampleImportRow{}
+	for i := 0; i < 5; i++ {
+		rows = append(rows, &userExampleImportRow{Line: i + 2, Data: &types.CreateUserExampleRequest{Name: fmt.Sprintf("foo%d", i)}})
+	}
+	data, err := json.Marshal(rows)
+	require.NoError(t, err)
+	job := &model.UserExampleImportJob{TenantID: "t1", Rows: string(data), Errors: "[]"}
+	require.NoError(t, jobDao.Create(context.Background(), job))
+
+	// the first batch is created, the rest of the quota is reserved for the second batch
+	expectUserExampleCount(mock, "t1", 0)
+	require.NoError(t, worker.process(context.Background(), job))
+	assert.Equal(t, userExampleImportCompleted, job.Status)
+	assert.Equal(t, []int{5, 3, 2}, []int{job.Processed, job.Succeeded, job.Failed})
+	assert.Len(t, iDao.records, 3)
+	rowErrs, err := unmarshalUserExampleImportErrors(job.Errors)
+	require.NoError(t, err)
+	assert.Equal(t, []types.UserExampleImportRowError{
+		{Line: 5, Error: "quota_exceeded"},
+		{Line: 6, Error: "quota_exceeded"},
+	}, rowErrs)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
+	"github.com/go-dev-frame/sponge/pkg/gin/response"
+	"github.com/go-dev-frame/sponge/pkg/logger"
+	"github.com/go-dev-frame/sponge/pkg/statemachine"
+
+	"github.com/go-dev-frame/sponge/internal/database"
+	"github.com/go-dev-frame/sponge/internal/ecode"
+	"github.com/go-dev-frame/sponge/internal/model"
+)
+
+// the lifecycle status of userExample, see the status field of model.UserExample
+const (
+	userExampleStatusInactive  = 1
+	userExampleStatusActivated = 2
+	userExampleStatusBlocked   = 3
+	userExampleStatusArchived  = 4
+)
+
+// the allowed status transitions of userExample, archived records can only be unarchived to activated
+var userExampleStatus = statemachine.New("userExample", map[int][]int{
+	userExampleStatusInactive:  {userExampleStatusActivated, userExampleStatusArchived},
+	userExampleStatusActivated: {userExampleStatusBlocked, userExampleStatusArchived},
+	userExampleStatusBlocked:   {userExampleStatusActivated, userExampleStatusArchived},
+	userExampleStatusArchived:  {userExampleStatusActivated},
+})
+
+// Archive a record
+// @Summary archive userExample
+// @Description archive userExample by id, 409 if the current status can't be archived or it is changed concurrently
+// @Tags userExample
+// @accept json
+// @Produce json
+// @Param id path string true "id"
+// @Success 200 {object} types.UpdateUserExampleByIDReply{}
+// @Router /api/v1/userExample/{id}/archive [post]
+// @Security BearerAuth
+func (h *userExampleHandler) Archive(c *gin.Context) {
+	h.transitStatus(c, 0, userExampleStatusArchived)
+}
+
+// Unarchive a record
+// @Summary unarchive userExample
+// @Description unarchive userExample by id, the status becomes activated, 409 if it is not archived or it is changed concurrently
+// @Tags userExample
+// @accept json
+// @Produce json
+// @Param id path string true "id"
+// @Success 200 {object} types.UpdateUserExampleByIDReply{}
+// @Router /api/v1/userExample/{id}/unarchive [post]
+// @Security BearerAuth
+func (h *userExampleHandler) Unarchive(c *gin.Context) {
+	h.transitStatus(c, userExampleStatusArchived, userExampleStatusActivated)
+}
+
+func (h *userExampleHandler) transitStatus(c *gin.Context, from int, to int) {
+	_, id, isAbort := getUserExampleIDFromPath(c)
+	if isAbort {
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+
+	ctx := wrapTenantCtx(c)
+	before := h.activitySnapshot(c, ctx, id)
+	userExample := &model.UserExample{Status: to}
+	userExample.ID = id
+	if _, isAbort := h.changeStatus(c, ctx, userExample, from); isAbort {
+		return
+	}
+	h.recordActivity(c, ctx, userExampleActionUpdated, before, h.activitySnapshot(c, ctx, id))
+
+	response.Success(c)
+}
+
+// changeStatus check the transition from the current status to the status of the table, and write it together
+// with the other non-zero fields of the table in one conditional update, so a concurrent transition of the same
+// record is rejected with 409 instead of overwritten, and the fields are not written if the status is rejected.
+// If from is not 0, the current status must be from, e.g. only the archived records can be unarchived.
+// isUpdated is false if the status is not changed, in which case nothing is written.
+func (h *userExampleHandler) changeStatus(c *gin.Context, ctx context.Context, table *model.UserExample, from int) (isUpdated bool, isAbort bool) {
+	id, to := table.ID, table.Status
+	record, err := h.iDao.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			logger.Warn("GetByID not found", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.NotFound)
+		} else {
+			logger.Error("GetByID error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+			response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		}
+		return false, true
+	}
+
+	switch {
+	case from != 0 && record.Status != from:
+		err = &statemachine.TransitionError{Resource: userExampleStatus.Resource(), From: record.Status, To: to,
+			Allowed: userExampleStatus.Next(record.Status)}
+	case record.Status == to:
+		return false, false
+	default:
+		err = userExampleStatus.Check(record.Status, to)
+	}
+	if err == nil {
+		err = h.iDao.UpdateStatusByID(ctx, table, record.Status)
+	}
+	if err != nil {
+		h.respondStatusError(c, ctx, id, to, err)
+		return false, true
+	}
+	return true, false
+}
+
+// respond 409 with the allowed next states for the illegal transitions and the conflicts of the conditional update
+func (h *userExampleHandler) respondStatusError(c *gin.Context, ctx context.Context, id uint64, to int, err error) {
+	var transitionErr *statemachine.TransitionError
+	if errors.As(err, &transitionErr) {
+		logger.Warn("illegal status transition", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+		response.Out(c, ecode.Conflict.RewriteMsg("illegal_status_transition"),
+			gin.H{"status": transitionErr.From, "to": to, "allowed": transitionErr.Allowed})
+		return
+	}
+	if errors.Is(err, statemachine.ErrConflict) {
+		logger.Warn("status conflict", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+		data := gin.H{"to": to}
+		if record, e := h.iDao.GetByID(ctx, id); e == nil {
+			data["status"] = record.Status
+			data["allowed"] = userExampleStatus.Next(record.Status)
+		}
+		response.Out(c, ecode.Conflict.RewriteMsg("status_conflict"), data)
+		return
+	}
+	logger.Error("UpdateStatusByID error", logger.Err(err), logger.Any("id", id), middleware.GCtxRequestIDField(c))
+	response.Output(c, ecode.InternalServerError.ToHTTPCode())
+}
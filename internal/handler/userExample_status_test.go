@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-dev-frame/sponge/internal/database"
+	"github.com/go-dev-frame/sponge/internal/ecode"
+	"github.com/go-dev-frame/sponge/internal/model"
+)
+
+func Test_userExampleHandler_StatusTransition(t *testing.T) {
+	h := newUserExampleHandler()
+	defer h.Close()
+	testData := h.TestData.(*model.UserExample)
+
+	do := func(method string, url string, body string) (int, string) {
+		req, _ := http.NewRequest(method, url, bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data)
+	}
+	// the record is loaded from the database, the cache is deleted by every status change
+	expectGet := func(status int) {
+		h.MockDao.SQLMock.ExpectQuery("SELECT .*").
+			WithArgs(testData.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(testData.ID, status))
+	}
+	expectUpdateStatus := func(from int, to int, rowsAffected int64) {
+		h.MockDao.SQLMock.ExpectBegin()
+		h.MockDao.SQLMock.ExpectExec("UPDATE .*").
+			WithArgs(to, h.MockDao.AnyTime, testData.ID, from).
+			WillReturnResult(sqlmock.NewResult(int64(testData.ID), rowsAffected))
+		h.MockDao.SQLMock.ExpectCommit()
+	}
+
+	// legal: activated -> archived
+	expectGet(userExampleStatusActivated)
+	expectUpdateStatus(userExampleStatusActivated, userExampleStatusArchived, 1)
+	code, _ := do(http.MethodPost, h.GetRequestURL("Archive", testData.ID), "")
+	assert.Equal(t, http.StatusOK, code)
+
+	// legal: archived -> activated
+	expectGet(userExampleStatusArchived)
+	expectUpdateStatus(userExampleStatusArchived, userExampleStatusActivated, 1)
+	code, _ = do(http.MethodPost, h.GetRequestURL("Unarchive", testData.ID), "")
+	assert.Equal(t, http.StatusOK, code)
+
+	// illegal: only the archived records can be unarchived, the blocked record stays cached
+	expectGet(userExampleStatusBlocked)
+	code, body := do(http.MethodPost, h.GetRequestURL("Unarchive", testData.ID), "")
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Contains(t, body, `"allowed":[2,4]`)
+
+	// illegal: blocked -> inactive by update
+	code, body = do(http.MethodPut, h.GetRequestURL("UpdateByID", testData.ID), `{"status":1}`)
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Contains(t, body, "illegal_status_transition")
+	assert.Contains(t, body, `"allowed":[2,4]`)
+
+	// legal: blocked -> activated by update, the other fields are updated in the same conditional update
+	h.MockDao.SQLMock.ExpectBegin()
+	h.MockDao.SQLMock.ExpectExec("UPDATE .*").
+		WithArgs("foo", userExampleStatusActivated, h.MockDao.AnyTime, testData.ID, userExampleStatusBlocked).
+		WillReturnResult(sqlmock.NewResult(int64(testData.ID), 1))
+	h.MockDao.SQLMock.ExpectCommit()
+	code, _ = do(http.MethodPut, h.GetRequestURL("UpdateByID", testData.ID), `{"name":"foo","status":2}`)
+	assert.Equal(t, http.StatusOK, code)
+
+	// the write of the status and the fields fails, neither of them is written
+	expectGet(userExampleStatusBlocked)
+	h.MockDao.SQLMock.ExpectBegin()
+	h.MockDao.SQLMock.ExpectExec("UPDATE .*").
+		WithArgs("bar", userExampleStatusActivated, h.MockDao.AnyTime, testData.ID, userExampleStatusBlocked).
+		WillReturnError(errors.New("write error"))
+	h.MockDao.SQLMock.ExpectRollback()
+	code, _ = do(http.MethodPut, h.GetRequestURL("UpdateByID", testData.ID), `{"name":"bar","status":2}`)
+	assert.Equal(t, http.StatusInternalServerError, code)
+
+	// the status is not changed, only the fields are updated, the blocked record stays cached after the failure
+	h.MockDao.SQLMock.ExpectBegin()
+	h.MockDao.SQLMock.ExpectExec("UPDATE .*").
+		WithArgs("bar", h.MockDao.AnyTime, testData.ID).
+		WillReturnResult(sqlmock.NewResult(int64(testData.ID), 1))
+	h.MockDao.SQLMock.ExpectCommit()
+	code, _ = do(http.MethodPut, h.GetRequestURL("UpdateByID", testData.ID), `{"name":"bar","status":3}`)
+	assert.Equal(t, http.StatusOK, code)
+
+	// race: the status is changed by a concurrent request after it is read, the conditional update affects no rows
+	expectGet(userExampleStatusActivated)
+	expectUpdateStatus(userExampleStatusActivated, userExampleStatusArchived, 0)
+	expectGet(userExampleStatusBlocked)
+	code, body = do(http.MethodPost, h.GetRequestURL("Archive", testData.ID), "")
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Contains(t, body, "status_conflict")
+	assert.Contains(t, body, `"status":3`)
+
+	// not found
+	h.MockDao.SQLMock.ExpectQuery("SELECT .*").WithArgs(2).WillReturnError(database.ErrRecordNotFound)
+	_, body = do(http.MethodPost, h.GetRequestURL("Archive", 2), "")
+	assert.Contains(t, body, ecode.NotFound.Msg())
+
+	assert.NoError(t, h.MockDao.SQLMock.ExpectationsWereMet())
+}
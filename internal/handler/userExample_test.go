@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"bytes"
+	"context"
 	"net/http"
 	"testing"
 	"time"
@@ -75,6 +77,20 @@ func newUserExampleHandler() *gotest.Handler {
 			Path:        "/userExample/list",
 			HandlerFunc: iHandler.List,
 		},
+		// delete the templates code start
+		{
+			FuncName:    "Archive",
+			Method:      http.MethodPost,
+			Path:        "/userExample/:id/archive",
+			HandlerFunc: iHandler.Archive,
+		},
+		{
+			FuncName:    "Unarchive",
+			Method:      http.MethodPost,
+			Path:        "/userExample/:id/unarchive",
+			HandlerFunc: iHandler.Unarchive,
+		},
+		// delete the templates code end
 	}
 
 	h.GoRunHTTPServer(testFns)
@@ -245,7 +261,8 @@ func Test_userExampleHandler_List(t *testing.T) {
 		t.Fatalf("%+v", result)
 	}
 
-	// nil params error test
+	// nil params, the default params are used
+	h.MockDao.SQLMock.ExpectQuery("SELECT .*").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
 	err = httpcli.Post(result, h.GetRequestURL("List"), nil)
 	assert.NoError(t, err)
 
@@ -258,6 +275,72 @@ func Test_userExampleHandler_List(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func Test_userExampleHandler_List_IfModifiedSince(t *testing.T) {
+	h := newUserExampleHandler()
+	defer h.Close()
+	testData := h.TestData.(*model.UserExample)
+	iDao := h.MockDao.IDao.(dao.UserExampleDao)
+	// the second of the writes has passed, lastModifiedSkew is 0
+	h.IHandler.(*userExampleHandler).now = func() time.Time { return time.Now().Add(time.Second * 2) }
+
+	list := func(ifModifiedSince string) *http.Response {
+		body := []byte(`{"page":0,"limit":10,"sort":"ignore count"}`)
+		req, _ := http.NewRequest(http.MethodPost, h.GetRequestURL("List"), bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if ifModifiedSince != "" {
+			req.Header.Set("If-Modified-Since", ifModifiedSince)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		return resp
+	}
+	expectList := func() {
+		h.MockDao.SQLMock.ExpectQuery("SELECT .*").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(testData.ID))
+	}
+	write := func() {
+		h.MockDao.SQLMock.ExpectBegin()
+		h.MockDao.SQLMock.ExpectExec("UPDATE .*").
+			WithArgs(h.MockDao.AnyTime, testData.ID).
+			WillReturnResult(sqlmock.NewResult(int64(testData.ID), 1))
+		h.MockDao.SQLMock.ExpectCommit()
+		assert.NoError(t, iDao.DeleteByID(context.Background(), testData.ID))
+	}
+
+	// no write is recorded yet
+	expectList()
+	resp := list("")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Last-Modified"))
+
+	write()
+	expectList()
+	resp = list("")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	lastModified := resp.Header.Get("Last-Modified")
+	assert.NotEmpty(t, lastModified)
+
+	// unchanged, the database is not queried
+	resp = list(lastModified)
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+	// malformed header is ignored
+	expectList()
+	resp = list("yesterday")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// the copy of the client is older than the last write
+	lastModifiedTime, err := http.ParseTime(lastModified)
+	assert.NoError(t, err)
+	expectList()
+	resp = list(lastModifiedTime.Add(-time.Second).Format(http.TimeFormat))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	assert.NoError(t, h.MockDao.SQLMock.ExpectationsWereMet())
+}
+
 func TestNewUserExampleHandler(t *testing.T) {
 	defer func() {
 		recover()
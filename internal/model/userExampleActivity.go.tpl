@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+)
+
+// {{.TableNameCamel}}Activity the audit entry of a write to a {{.TableNameCamelFCL}} record, the entries are listed by the record
+// in the reverse order of the time, and deleted after the retention period
+type {{.TableNameCamel}}Activity struct {
+	ID            uint64    `gorm:"column:id;AUTO_INCREMENT;primary_key" json:"id"`
+	{{.TableNameCamel}}{{.ColumnNameCamel}} {{.GoType}} `gorm:"column:record_{{.ColumnName}};NOT NULL;index:idx_user_example_activity,priority:1" json:"{{.TableNameCamelFCL}}{{.ColumnNameCamel}}"` // {{.ColumnNameCamelFCL}} of the {{.TableNameCamelFCL}} record
+	TenantID      string    `gorm:"column:tenant_id;NOT NULL" json:"tenantID"`                                                       // tenant of the record, empty if there is no tenant
+	Actor         string    `gorm:"column:actor;NOT NULL" json:"actor"`                                                              // uid of the jwt claims, empty if there is no claims
+	Action        string    `gorm:"column:action;NOT NULL" json:"action"`                                                            // created, updated or deleted
+	Changes       string    `gorm:"column:changes;type:text" json:"changes"`                                                         // json of the redacted changed fields
+	CreatedAt     time.Time `gorm:"column:created_at;index:idx_user_example_activity,priority:2;index" json:"createdAt"`             // time of the write
+}
+
+// TableName get table name, the table of the {{.TableNameCamelFCL}} records with the suffix _activity
+func (table *{{.TableNameCamel}}Activity) TableName() string {
+	return new({{.TableNameCamel}}).TableName() + "_activity"
+}
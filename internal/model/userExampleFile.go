@@ -0,0 +1,24 @@
+package model
+
+import (
+	"github.com/go-dev-frame/sponge/pkg/sgorm"
+)
+
+// UserExampleFile the metadata of the file associated with a userExample record, the content is saved
+// in the storage by the key, the same content uploaded more than once shares the key
+type UserExampleFile struct {
+	sgorm.Model `gorm:"embedded"`
+
+	UserExampleID uint64 `gorm:"column:user_example_id;NOT NULL;index" json:"userExampleID"` // id of the userExample record
+	TenantID      string `gorm:"column:tenant_id;NOT NULL" json:"tenantID"`                  // tenant of the record, empty if there is no tenant
+	Key           string `gorm:"column:key;NOT NULL;index" json:"-"`                         // storage key, it is never exposed
+	Filename      string `gorm:"column:filename;NOT NULL" json:"filename"`                   // sanitized original filename
+	Size          int64  `gorm:"column:size;NOT NULL" json:"size"`                           // size in bytes
+	ContentType   string `gorm:"column:content_type;NOT NULL" json:"contentType"`            // sniffed content type
+	Hash          string `gorm:"column:hash;NOT NULL" json:"hash"`                           // hex encoded sha256 of the content
+}
+
+// TableName get table name
+func (table *UserExampleFile) TableName() string {
+	return "user_example_file"
+}
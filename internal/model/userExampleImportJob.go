@@ -0,0 +1,30 @@
+package model
+
+import (
+	"github.com/go-dev-frame/sponge/pkg/sgorm"
+)
+
+// UserExampleImportJob the job of importing the userExample records from a csv file, the valid rows are saved
+// in the job and created by the worker which claims the job, the progress is saved with each created record,
+// so the job is resumed from the last saved row after the restart, or by another replica if the progress of the
+// owner is not updated in time
+type UserExampleImportJob struct {
+	sgorm.Model `gorm:"embedded"`
+
+	TenantID  string `gorm:"column:tenant_id;NOT NULL;index" json:"tenantID"` // tenant of the records, empty if there is no tenant
+	Actor     string `gorm:"column:actor;NOT NULL" json:"actor"`              // uid of the jwt claims, empty if there is no claims
+	Status    string `gorm:"column:status;NOT NULL;index" json:"status"`      // pending, running, succeeded or failed
+	Owner     string `gorm:"column:owner;NOT NULL" json:"-"`                  // worker claiming the job, empty if it is not claimed
+	Total     int    `gorm:"column:total;NOT NULL" json:"total"`              // number of the data rows of the file
+	Valid     int    `gorm:"column:valid;NOT NULL" json:"valid"`              // number of the rows passing the validation
+	Processed int    `gorm:"column:processed;NOT NULL" json:"processed"`      // number of the valid rows processed by the worker
+	Succeeded int    `gorm:"column:succeeded;NOT NULL" json:"succeeded"`      // number of the records created
+	Failed    int    `gorm:"column:failed;NOT NULL" json:"failed"`            // number of the invalid rows and the rows failed to create
+	Rows      string `gorm:"column:rows;type:longtext" json:"-"`              // json of the valid rows
+	Errors    string `gorm:"column:errors;type:longtext" json:"-"`            // json of the errors of the rows, the report of the job
+}
+
+// TableName get table name
+func (table *UserExampleImportJob) TableName() string {
+	return "user_example_import_job"
+}
@@ -0,0 +1,19 @@
+package routers
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
+	"github.com/go-dev-frame/sponge/pkg/logger"
+)
+
+// WithDeprecated mark the route or group as deprecated, the Deprecation, Sunset and Link headers are added to
+// the responses, the first use per client is logged once a day, and the hits are counted by route template, e.g.
+//
+//	c.setGroupPath("/api/v1/userExample", WithDeprecated(sunset, "https://example.com/docs/v2/userExample"))
+//	g.GET("/:id", WithDeprecated(sunset, link), h.GetByID)
+func WithDeprecated(sunset time.Time, link string) gin.HandlerFunc {
+	return middleware.Deprecated(sunset, link, middleware.WithDeprecatedLogger(logger.Get()))
+}
@@ -0,0 +1,142 @@
+package routers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/go-dev-frame/sponge/pkg/gin/response"
+)
+
+// handleMethods mirror the GET routes to HEAD and respond 405 with the Allow header for the wrong method of an
+// existing path instead of 404, it is called after all routes are registered.
+func handleMethods(r *gin.Engine) {
+	registerHeadRoutes(r)
+	r.HandleMethodNotAllowed = true
+	r.NoMethod(methodNotAllowed(r))
+}
+
+// register a HEAD route for every GET route which has no HEAD route, the request is served by the GET route
+// of the same engine, so the middlewares of the GET route (e.g. auth) are called once, and the body is discarded.
+func registerHeadRoutes(r *gin.Engine) {
+	heads := map[string]bool{}
+	var paths []string
+	for _, route := range r.Routes() {
+		switch route.Method {
+		case http.MethodHead:
+			heads[route.Path] = true
+		case http.MethodGet:
+			paths = append(paths, route.Path)
+		}
+	}
+
+	// the global middlewares are called by the GET route, they are excluded from the HEAD routes
+	global := r.Handlers
+	r.Handlers = nil
+	defer func() { r.Handlers = global }()
+	for _, p := range paths {
+		if !heads[p] {
+			r.HEAD(p, serveHead(r))
+		}
+	}
+}
+
+func serveHead(r *gin.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := c.Request.Clone(c.Request.Context())
+		req.Method = http.MethodGet
+		w := &headResponseWriter{header: c.Writer.Header()}
+		r.ServeHTTP(w, req)
+
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		if w.header.Get("Content-Length") == "" && w.size > 0 {
+			w.header.Set("Content-Length", strconv.Itoa(w.size))
+		}
+		c.Status(w.status)
+		c.Writer.WriteHeaderNow()
+	}
+}
+
+// the response writer of HEAD, the headers are shared with the original response, the body is counted and discarded
+type headResponseWriter struct {
+	header http.Header
+	status int
+	size   int
+}
+
+func (w *headResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *headResponseWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *headResponseWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	w.size += len(b)
+	return len(b), nil
+}
+
+func methodNotAllowed(r *gin.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if methods := allowedMethods(r.Routes(), c.Request.URL.Path); len(methods) > 0 {
+			c.Header("Allow", strings.Join(methods, ", "))
+		}
+		response.Output(c, http.StatusMethodNotAllowed)
+	}
+}
+
+// the methods of the routes matching the path, sorted
+func allowedMethods(routes gin.RoutesInfo, path string) []string {
+	seen := map[string]bool{}
+	var methods []string
+	for _, route := range routes {
+		if !seen[route.Method] && matchPath(route.Path, path) {
+			seen[route.Method] = true
+			methods = append(methods, route.Method)
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// whether the path matches the path of gin, e.g. /api/v1/userExample/:id or /swagger/*any
+func matchPath(pattern string, path string) bool {
+	for {
+		if pattern == "" {
+			return path == ""
+		}
+		if pattern[0] == '*' {
+			return true
+		}
+		if pattern[0] != ':' {
+			if path == "" || pattern[0] != path[0] {
+				return false
+			}
+			pattern, path = pattern[1:], path[1:]
+			continue
+		}
+
+		// the parameter matches a non-empty segment
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		if end == 0 {
+			return false
+		}
+		path = path[end:]
+		if end = strings.IndexByte(pattern, '/'); end < 0 {
+			end = len(pattern)
+		}
+		pattern = pattern[end:]
+	}
+}
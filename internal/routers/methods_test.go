@@ -0,0 +1,111 @@
+package routers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-dev-frame/sponge/pkg/gin/response"
+)
+
+type getMock struct{ mock }
+
+func (u getMock) GetByID(c *gin.Context) {
+	c.Header("X-User-Example-ID", c.Param("id"))
+	response.Success(c, gin.H{"id": c.Param("id")})
+}
+
+func Test_handleMethods(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	globalCalls, groupCalls := 0, 0
+	r.Use(func(c *gin.Context) { globalCalls++ })
+	r.GET("/swagger/*any", func(c *gin.Context) { c.String(http.StatusOK, "swagger") })
+	userExampleRouter(r.Group("/api/v1", func(c *gin.Context) { groupCalls++ }), &getMock{})
+	handleMethods(r)
+
+	do := func(method string, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	// the wrong method of an existing path
+	tests := []struct {
+		method string
+		path   string
+		allow  string
+	}{
+		{http.MethodPatch, "/api/v1/userExample/1", "DELETE, GET, HEAD, PUT"},
+		{http.MethodGet, "/api/v1/userExample/1/archive", "POST"},
+		{http.MethodDelete, "/api/v1/userExample/1/activity", "GET, HEAD"},
+		{http.MethodPut, "/api/v1/userExample/", "POST"},
+		{http.MethodPost, "/swagger/index.html", "GET, HEAD"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			w := do(tt.method, tt.path)
+			assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+			assert.Equal(t, tt.allow, w.Header().Get("Allow"))
+			result := &response.Result{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), result))
+			assert.Equal(t, http.StatusMethodNotAllowed, result.Code)
+			assert.Equal(t, "Method Not Allowed", result.Msg)
+		})
+	}
+
+	// the path which does not exist is still 404
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/api/v1/foo").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPatch, "/api/v1/userExample/1/foo").Code)
+
+	// HEAD returns the headers of GET without the body, the middlewares are called once
+	get := do(http.MethodGet, "/api/v1/userExample/1")
+	globalCalls, groupCalls = 0, 0
+	head := do(http.MethodHead, "/api/v1/userExample/1")
+	assert.Equal(t, http.StatusOK, head.Code)
+	assert.Empty(t, head.Body.Bytes())
+	assert.Equal(t, "1", head.Header().Get("X-User-Example-ID"))
+	assert.Equal(t, get.Header().Get("Content-Type"), head.Header().Get("Content-Type"))
+	assert.Equal(t, strconv.Itoa(get.Body.Len()), head.Header().Get("Content-Length"))
+	assert.Equal(t, 1, globalCalls)
+	assert.Equal(t, 1, groupCalls)
+
+	// by the server, the body is not sent
+	server := httptest.NewServer(r)
+	defer server.Close()
+	resp, err := http.Head(server.URL + "/swagger/index.html")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, body)
+	assert.Equal(t, int64(len("swagger")), resp.ContentLength)
+}
+
+func Test_matchPath(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"/api/v1/userExample/:id", "/api/v1/userExample/1", true},
+		{"/api/v1/userExample/:id", "/api/v1/userExample/", false},
+		{"/api/v1/userExample/:id", "/api/v1/userExample/1/activity", false},
+		{"/api/v1/userExample/:id/activity", "/api/v1/userExample/1/activity", true},
+		{"/api/v1/userExample/list", "/api/v1/userExample/list", true},
+		{"/api/v1/userExample/list", "/api/v1/userExample/lis", false},
+		{"/swagger/*any", "/swagger/", true},
+		{"/swagger/*any", "/swagger/a/b", true},
+		{"/swagger/*any", "/swagger", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, matchPath(tt.pattern, tt.path), tt.pattern+" "+tt.path)
+	}
+}
@@ -0,0 +1,338 @@
+package routers
+
+import (
+	"net/http"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Meta the metadata of a route recorded by Handle, it is rendered to the OpenAPI document of /debug/openapi.json
+type Meta struct {
+	Summary     string
+	Description string
+	Tags        []string
+	// the request type, e.g. types.CreateUserExampleRequest{}, it is the request body, or the query
+	// parameters of GET and DELETE, nil means no request
+	Req interface{}
+	// the response type, e.g. types.CreateUserExampleReply{}, nil means no response body
+	Resp interface{}
+}
+
+type routeMeta struct {
+	method string
+	path   string // path of gin, e.g. /api/v1/userExample/:id
+	meta   Meta
+}
+
+// the registry of the routes registered by Handle, the key is method and path
+var routeRegistry = struct {
+	mu     sync.RWMutex
+	routes map[string]routeMeta
+}{routes: map[string]routeMeta{}}
+
+// Handle register the route to the group and record its metadata, the OpenAPI document of /debug/openapi.json is
+// rendered from the recorded routes, so the paths, methods and schemas are always the same as what is mounted,
+// the middlewares are called before the handler, e.g.
+//
+//	Handle(g, "POST", "/", h.Create, Meta{Summary: "create userExample", Req: types.CreateUserExampleRequest{}})
+func Handle(g *gin.RouterGroup, method string, relativePath string, handler gin.HandlerFunc, meta Meta, middlewares ...gin.HandlerFunc) {
+	method = strings.ToUpper(method)
+	g.Handle(method, relativePath, append(middlewares, handler)...)
+
+	fullPath := joinPaths(g.BasePath(), relativePath)
+	routeRegistry.mu.Lock()
+	routeRegistry.routes[method+" "+fullPath] = routeMeta{method: method, path: fullPath, meta: meta}
+	routeRegistry.mu.Unlock()
+}
+
+// the same as the joining of gin, the trailing slash of the relative path is kept
+func joinPaths(absolutePath string, relativePath string) string {
+	if relativePath == "" {
+		return absolutePath
+	}
+	finalPath := path.Join(absolutePath, relativePath)
+	if strings.HasSuffix(relativePath, "/") && !strings.HasSuffix(finalPath, "/") {
+		return finalPath + "/"
+	}
+	return finalPath
+}
+
+// -------------------------------------------------------------------------------------------
+
+type openAPIDoc struct {
+	OpenAPI string                           `json:"openapi"`
+	Info    openAPIInfo                      `json:"info"`
+	Paths   map[string]map[string]*openAPIOp `json:"paths"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIOp struct {
+	Summary     string                      `json:"summary,omitempty"`
+	Description string                      `json:"description,omitempty"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Parameters  []*openAPIParam             `json:"parameters,omitempty"`
+	RequestBody *openAPIBody                `json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+}
+
+type openAPIParam struct {
+	Name     string         `json:"name"`
+	In       string         `json:"in"`
+	Required bool           `json:"required,omitempty"`
+	Schema   *openAPISchema `json:"schema"`
+}
+
+type openAPIBody struct {
+	Required bool                        `json:"required,omitempty"`
+	Content  map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIMediaType struct {
+	Schema *openAPISchema `json:"schema"`
+}
+
+type openAPISchema struct {
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Properties           map[string]*openAPISchema `json:"properties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+	Items                *openAPISchema            `json:"items,omitempty"`
+	AdditionalProperties *openAPISchema            `json:"additionalProperties,omitempty"`
+	MinLength            *int                      `json:"minLength,omitempty"`
+	MaxLength            *int                      `json:"maxLength,omitempty"`
+	MinItems             *int                      `json:"minItems,omitempty"`
+	MaxItems             *int                      `json:"maxItems,omitempty"`
+	Minimum              *float64                  `json:"minimum,omitempty"`
+	Maximum              *float64                  `json:"maximum,omitempty"`
+}
+
+// openAPIHandler respond the minimal OpenAPI 3 document of the routes registered by Handle, it does not replace
+// the swagger document generated from the annotations, which has the descriptions and the examples.
+func openAPIHandler(title string, version string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, newOpenAPIDoc(title, version))
+	}
+}
+
+func newOpenAPIDoc(title string, version string) *openAPIDoc {
+	doc := &openAPIDoc{
+		OpenAPI: "3.0.3",
+		Info:    openAPIInfo{Title: title, Version: version},
+		Paths:   map[string]map[string]*openAPIOp{},
+	}
+
+	routeRegistry.mu.RLock()
+	defer routeRegistry.mu.RUnlock()
+	for _, route := range routeRegistry.routes {
+		p, pathParams := toOpenAPIPath(route.path)
+		if doc.Paths[p] == nil {
+			doc.Paths[p] = map[string]*openAPIOp{}
+		}
+		doc.Paths[p][strings.ToLower(route.method)] = newOpenAPIOp(route.method, pathParams, route.meta)
+	}
+	return doc
+}
+
+// convert the path of gin to the path of OpenAPI, e.g. /userExample/:id to /userExample/{id}
+func toOpenAPIPath(ginPath string) (string, []string) {
+	var params []string
+	segments := strings.Split(ginPath, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			name := segment[1:]
+			params = append(params, name)
+			segments[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+func newOpenAPIOp(method string, pathParams []string, meta Meta) *openAPIOp {
+	op := &openAPIOp{
+		Summary:     meta.Summary,
+		Description: meta.Description,
+		Tags:        meta.Tags,
+		Responses:   map[string]*openAPIResponse{"200": {Description: "OK"}},
+	}
+	for _, name := range pathParams {
+		op.Parameters = append(op.Parameters, &openAPIParam{Name: name, In: "path", Required: true, Schema: &openAPISchema{Type: "string"}})
+	}
+
+	if meta.Req != nil {
+		if method == http.MethodGet || method == http.MethodDelete {
+			op.Parameters = append(op.Parameters, toQueryParams(reflect.TypeOf(meta.Req))...)
+		} else {
+			op.RequestBody = &openAPIBody{
+				Required: true,
+				Content: map[string]openAPIMediaType{
+					"application/json": {Schema: newSchema(reflect.TypeOf(meta.Req), "json", map[reflect.Type]bool{})},
+				},
+			}
+		}
+	}
+	if meta.Resp != nil {
+		op.Responses["200"].Content = map[string]openAPIMediaType{
+			"application/json": {Schema: newSchema(reflect.TypeOf(meta.Resp), "json", map[reflect.Type]bool{})},
+		}
+	}
+	return op
+}
+
+// the query parameters of the request type, the name is the form tag of the field
+func toQueryParams(t reflect.Type) []*openAPIParam {
+	s := newSchema(t, "form", map[reflect.Type]bool{})
+	required := map[string]bool{}
+	for _, name := range s.Required {
+		required[name] = true
+	}
+
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	params := make([]*openAPIParam, 0, len(names))
+	for _, name := range names {
+		params = append(params, &openAPIParam{Name: name, In: "query", Required: required[name], Schema: s.Properties[name]})
+	}
+	return params
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// newSchema create the schema of the type by reflection, the names of the fields are got from the tag nameTag,
+// and the required, min, max, gte and lte rules of the binding tag are converted to the constraints.
+func newSchema(t reflect.Type, nameTag string, seen map[reflect.Type]bool) *openAPISchema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &openAPISchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &openAPISchema{Type: "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return &openAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &openAPISchema{Type: "number"}
+	case reflect.String:
+		return &openAPISchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &openAPISchema{Type: "string", Format: "byte"}
+		}
+		return &openAPISchema{Type: "array", Items: newSchema(t.Elem(), nameTag, seen)}
+	case reflect.Map:
+		return &openAPISchema{Type: "object", AdditionalProperties: newSchema(t.Elem(), nameTag, seen)}
+	case reflect.Struct:
+		if t == timeType {
+			return &openAPISchema{Type: "string", Format: "date-time"}
+		}
+		if seen[t] { // recursive type
+			return &openAPISchema{Type: "object"}
+		}
+		seen[t] = true
+		defer delete(seen, t)
+
+		s := &openAPISchema{Type: "object", Properties: map[string]*openAPISchema{}}
+		addFields(s, t, nameTag, seen)
+		return s
+	}
+
+	return &openAPISchema{} // interface{}, any type
+}
+
+func addFields(s *openAPISchema, t reflect.Type, nameTag string, seen map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get(nameTag), ",")
+		if name == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+
+		// the fields of the embedded struct are flattened, e.g. query.Params
+		ft := field.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if field.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			addFields(s, ft, nameTag, seen)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		fs := newSchema(field.Type, nameTag, seen)
+		if addBindingRules(fs, field.Tag.Get("binding")) {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = fs
+	}
+}
+
+// add the constraints of the binding rules to the schema, returns whether the field is required
+func addBindingRules(s *openAPISchema, binding string) (required bool) {
+	for _, rule := range strings.Split(binding, ",") {
+		key, value, _ := strings.Cut(rule, "=")
+		if key == "required" {
+			required = true
+			continue
+		}
+		if key != "min" && key != "max" && key != "gte" && key != "lte" {
+			continue
+		}
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			continue
+		}
+
+		isMin := key == "min" || key == "gte"
+		switch s.Type {
+		case "string":
+			if isMin {
+				s.MinLength = intPtr(int(n))
+			} else {
+				s.MaxLength = intPtr(int(n))
+			}
+		case "array":
+			if isMin {
+				s.MinItems = intPtr(int(n))
+			} else {
+				s.MaxItems = intPtr(int(n))
+			}
+		case "integer", "number":
+			if isMin {
+				s.Minimum = &n
+			} else {
+				s.Maximum = &n
+			}
+		}
+	}
+	return required
+}
+
+func intPtr(n int) *int {
+	return &n
+}
@@ -0,0 +1,149 @@
+package routers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func getOpenAPIDoc(t *testing.T, r *gin.Engine) *openAPIDoc {
+	r.GET("/debug/openapi.json", openAPIHandler("serverNameExample", "v1.0.0"))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	doc := &openAPIDoc{}
+	err := json.Unmarshal(w.Body.Bytes(), doc)
+	assert.NoError(t, err)
+	return doc
+}
+
+func resetRouteRegistry() {
+	routeRegistry.mu.Lock()
+	routeRegistry.routes = map[string]routeMeta{}
+	routeRegistry.mu.Unlock()
+}
+
+func TestOpenAPI_userExample(t *testing.T) {
+	resetRouteRegistry()
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	userExampleRouter(r.Group("/api/v1"), &mock{})
+	doc := getOpenAPIDoc(t, r)
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+	assert.Equal(t, "serverNameExample", doc.Info.Title)
+
+	// the document has the same paths and methods as the mounted routes
+	mounted := map[string]bool{}
+	for _, route := range r.Routes() {
+		if !strings.HasPrefix(route.Path, "/api/v1/userExample") {
+			continue
+		}
+		p, _ := toOpenAPIPath(route.Path)
+		mounted[strings.ToLower(route.Method)+" "+p] = true
+	}
+	documented := map[string]bool{}
+	for p, ops := range doc.Paths {
+		for method := range ops {
+			documented[method+" "+p] = true
+		}
+	}
+	assert.Equal(t, mounted, documented)
+	assert.Len(t, documented, 10)
+	assert.True(t, documented["post /api/v1/userExample/"])
+	assert.True(t, documented["get /api/v1/userExample/{id}/activity"])
+	assert.True(t, documented["post /api/v1/userExample/{id}/archive"])
+	assert.True(t, documented["post /api/v1/userExample/distinct"])
+	assert.True(t, documented["post /api/v1/userExample/{id}/aggregate"])
+
+	// the path and query parameters of the activity
+	op := doc.Paths["/api/v1/userExample/{id}/activity"]["get"]
+	var names []string
+	for _, param := range op.Parameters {
+		names = append(names, param.In+" "+param.Name)
+	}
+	assert.ElementsMatch(t, []string{"path id", "query page", "query limit"}, names)
+
+	// path parameters
+	op = doc.Paths["/api/v1/userExample/{id}"]["get"]
+	assert.Equal(t, "get userExample detail", op.Summary)
+	assert.Equal(t, []string{"userExample"}, op.Tags)
+	assert.Len(t, op.Parameters, 1)
+	assert.Equal(t, "id", op.Parameters[0].Name)
+	assert.Equal(t, "path", op.Parameters[0].In)
+	assert.Nil(t, op.RequestBody)
+	detail := op.Responses["200"].Content["application/json"].Schema.Properties["data"].Properties["userExample"]
+	assert.Equal(t, "date-time", detail.Properties["createdAt"].Format)
+	assert.Equal(t, "int64", detail.Properties["id"].Format)
+
+	// request body with the binding rules
+	op = doc.Paths["/api/v1/userExample/"]["post"]
+	req := op.RequestBody.Content["application/json"].Schema
+	assert.Equal(t, "object", req.Type)
+	assert.Len(t, req.Properties, 7)
+	assert.Equal(t, 2, *req.Properties["name"].MinLength)
+	assert.Equal(t, "integer", req.Properties["gender"].Type)
+	assert.Equal(t, float64(0), *req.Properties["gender"].Minimum)
+	assert.Equal(t, float64(2), *req.Properties["gender"].Maximum)
+
+	// the fields of the embedded query.Params are flattened
+	op = doc.Paths["/api/v1/userExample/list"]["post"]
+	req = op.RequestBody.Content["application/json"].Schema
+	assert.Equal(t, float64(0), *req.Properties["page"].Minimum)
+	assert.Equal(t, float64(1), *req.Properties["limit"].Minimum)
+	assert.Equal(t, "array", req.Properties["columns"].Type)
+	assert.Equal(t, "string", req.Properties["columns"].Items.Properties["name"].Type)
+	items := op.Responses["200"].Content["application/json"].Schema.Properties["data"].Properties["items"]
+	assert.Equal(t, "array", items.Type)
+	assert.Equal(t, "object", items.Items.Type)
+}
+
+type openAPITestRequest struct {
+	Name   string    `json:"name" form:"name" binding:"required,max=10"`
+	Tags   []string  `json:"tags" form:"tags" binding:"max=3"`
+	Parent *struct{} `json:"parent,omitempty" form:"-"`
+	Any    interface{}
+	secret string
+}
+
+func TestHandle(t *testing.T) {
+	resetRouteRegistry()
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	g := r.Group("/api/v2/test")
+	Handle(g, "get", "/*path", func(c *gin.Context) {}, Meta{Req: openAPITestRequest{}})
+	Handle(g, "POST", "", func(c *gin.Context) {}, Meta{Req: &openAPITestRequest{}},
+		func(c *gin.Context) { c.AbortWithStatus(http.StatusForbidden) })
+
+	// the middlewares are called before the handler
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v2/test", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	doc := getOpenAPIDoc(t, r)
+	assert.Len(t, doc.Paths, 2)
+
+	// query parameters of GET
+	op := doc.Paths["/api/v2/test/{path}"]["get"]
+	assert.Len(t, op.Parameters, 4) // path, Any, name, tags
+	assert.Equal(t, "path", op.Parameters[0].In)
+	assert.Equal(t, "Any", op.Parameters[1].Name)
+	assert.Equal(t, "name", op.Parameters[2].Name)
+	assert.True(t, op.Parameters[2].Required)
+	assert.Equal(t, 10, *op.Parameters[2].Schema.MaxLength)
+	assert.Equal(t, 3, *op.Parameters[3].Schema.MaxItems)
+	assert.Nil(t, op.Responses["200"].Content)
+
+	// request body of POST
+	op = doc.Paths["/api/v2/test"]["post"]
+	req := op.RequestBody.Content["application/json"].Schema
+	assert.Equal(t, []string{"name"}, req.Required)
+	assert.Len(t, req.Properties, 4)
+	assert.Equal(t, "object", req.Properties["parent"].Type)
+	assert.Equal(t, "", req.Properties["Any"].Type)
+}
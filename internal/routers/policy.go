@@ -0,0 +1,189 @@
+package routers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MiddlewareClass the class of the middlewares checked by the route policy, e.g. auth
+type MiddlewareClass string
+
+// the classes of the middlewares
+const (
+	ClassRecovery  MiddlewareClass = "recovery"
+	ClassRequestID MiddlewareClass = "requestID"
+	ClassAuth      MiddlewareClass = "auth"
+	ClassAnonymous MiddlewareClass = "anonymous" // see AllowAnonymous
+)
+
+// the classes of the middlewares tagged by TagMiddleware, the key is the code pointer of the function
+var middlewareClasses = struct {
+	mu      sync.RWMutex
+	classes map[uintptr]MiddlewareClass
+}{classes: map[uintptr]MiddlewareClass{}}
+
+func init() {
+	TagMiddleware(ClassAnonymous, allowAnonymous)
+}
+
+// TagMiddleware tag the class of the middleware for the route policy, the middleware is returned as it is, e.g.
+//
+//	g.Use(TagMiddleware(ClassAuth, middleware.Auth()))
+//
+// The middleware is identified by the code of its function, so all the middlewares returned by the same
+// constructor are tagged by tagging one of them, e.g. middleware.Auth() with the different options.
+func TagMiddleware(class MiddlewareClass, h gin.HandlerFunc) gin.HandlerFunc {
+	middlewareClasses.mu.Lock()
+	middlewareClasses.classes[reflect.ValueOf(h).Pointer()] = class
+	middlewareClasses.mu.Unlock()
+	return h
+}
+
+// AllowAnonymous mark the route or the group as public, the auth rule of the route policy is skipped for it, so
+// the exceptions are visible in code review, e.g.
+//
+//	Handle(g, "POST", "/login", h.Login, Meta{Summary: "login"}, AllowAnonymous())
+func AllowAnonymous() gin.HandlerFunc {
+	return allowAnonymous
+}
+
+// the marker does nothing, the next handler is called by gin
+func allowAnonymous(*gin.Context) {}
+
+// RouteRule the rule of the route policy, the routes matched by the rule must include the middlewares of the
+// required classes, the globals of the engine, the middlewares of the groups and the route are all counted.
+type RouteRule struct {
+	Name       string
+	PathPrefix string // the prefix of the path of the routes, empty means all the routes
+	// only the routes of the methods changing the resources are matched, i.e. not GET, HEAD or OPTIONS
+	MutationOnly bool
+	Require      []MiddlewareClass
+	// the routes marked by AllowAnonymous are skipped
+	Overridable bool
+}
+
+// the rules of the routes of the engines, every route must include the recovery and the request id, and the
+// mutation APIs must include the authentication unless they are marked by AllowAnonymous
+func defaultRouteRules() []RouteRule {
+	return []RouteRule{
+		{Name: "recovery and request id", Require: []MiddlewareClass{ClassRecovery, ClassRequestID}},
+		{Name: "auth of mutation APIs", PathPrefix: "/api/", MutationOnly: true, Require: []MiddlewareClass{ClassAuth}, Overridable: true},
+	}
+}
+
+func (rule *RouteRule) match(method string, path string) bool {
+	if rule.MutationOnly {
+		switch method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return false
+		}
+	}
+	return strings.HasPrefix(path, rule.PathPrefix)
+}
+
+// checkRoutePolicy check all the routes of the engine by the rules, it is called after all the router functions
+// are executed and before the server listens, the error lists the routes violating the rules.
+func checkRoutePolicy(r *gin.Engine, rules ...RouteRule) error {
+	routes, err := routeHandlers(r)
+	if err != nil {
+		return err
+	}
+
+	var violations []string
+	for key, handlers := range routes {
+		method, path, _ := strings.Cut(key, " ")
+		classes := map[MiddlewareClass]bool{}
+		middlewareClasses.mu.RLock()
+		for _, h := range handlers {
+			if class, ok := middlewareClasses.classes[h]; ok {
+				classes[class] = true
+			}
+		}
+		middlewareClasses.mu.RUnlock()
+
+		for _, rule := range rules {
+			if !rule.match(method, path) || (rule.Overridable && classes[ClassAnonymous]) {
+				continue
+			}
+			var missing []string
+			for _, class := range rule.Require {
+				if !classes[class] {
+					missing = append(missing, string(class))
+				}
+			}
+			if len(missing) > 0 {
+				violations = append(violations, fmt.Sprintf("%s: missing %s (rule '%s')", key, strings.Join(missing, ", "), rule.Name))
+			}
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+
+	sort.Strings(violations)
+	return fmt.Errorf("%d route policy violations, add the middlewares or mark the public routes by AllowAnonymous():\n    %s",
+		len(violations), strings.Join(violations, "\n    "))
+}
+
+var errUnknownRouteTree = errors.New("the route trees of gin are unknown")
+
+// the code pointers of the handlers of the routes, the key is method and path, they are read from the route trees
+// of gin by reflection, since gin.RouteInfo has the last handler only
+func routeHandlers(r *gin.Engine) (map[string][]uintptr, error) {
+	trees := reflect.ValueOf(r).Elem().FieldByName("trees")
+	if trees.Kind() != reflect.Slice {
+		return nil, errUnknownRouteTree
+	}
+
+	routes := map[string][]uintptr{}
+	for i := 0; i < trees.Len(); i++ {
+		tree := trees.Index(i)
+		method, root := tree.FieldByName("method"), tree.FieldByName("root")
+		if method.Kind() != reflect.String || root.Kind() != reflect.Ptr {
+			return nil, errUnknownRouteTree
+		}
+		if err := walkRouteNode(method.String(), root, routes); err != nil {
+			return nil, err
+		}
+	}
+
+	// all the routes of gin are found
+	for _, route := range r.Routes() {
+		if _, ok := routes[route.Method+" "+route.Path]; !ok {
+			return nil, fmt.Errorf("%w, route '%s %s' is not found", errUnknownRouteTree, route.Method, route.Path)
+		}
+	}
+	return routes, nil
+}
+
+func walkRouteNode(method string, node reflect.Value, routes map[string][]uintptr) error {
+	if node.IsNil() {
+		return nil
+	}
+	node = node.Elem()
+	handlers, fullPath, children := node.FieldByName("handlers"), node.FieldByName("fullPath"), node.FieldByName("children")
+	if handlers.Kind() != reflect.Slice || fullPath.Kind() != reflect.String || children.Kind() != reflect.Slice {
+		return errUnknownRouteTree
+	}
+
+	if handlers.Len() > 0 {
+		pointers := make([]uintptr, 0, handlers.Len())
+		for i := 0; i < handlers.Len(); i++ {
+			pointers = append(pointers, handlers.Index(i).Pointer())
+		}
+		routes[method+" "+fullPath.String()] = pointers
+	}
+	for i := 0; i < children.Len(); i++ {
+		if err := walkRouteNode(method, children.Index(i), routes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
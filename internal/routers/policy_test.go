@@ -0,0 +1,76 @@
+package routers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
+)
+
+func newPolicyEngine() *gin.Engine {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(TagMiddleware(ClassRecovery, gin.Recovery()))
+	r.Use(TagMiddleware(ClassRequestID, middleware.RequestID()))
+	r.GET("/health", func(c *gin.Context) {})
+	return r
+}
+
+func Test_checkRoutePolicy(t *testing.T) {
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+
+	// the compliant group, the auth is in the group
+	r := newPolicyEngine()
+	g := r.Group("/api/v1/user", TagMiddleware(ClassAuth, middleware.Auth()))
+	Handle(g, "POST", "", ok, Meta{Summary: "create user"})
+	Handle(g, "DELETE", "/:id", ok, Meta{Summary: "delete user"})
+	g.PUT("/:id", ok)
+	r.Group("/api/v1/public").GET("/:id", ok) // GET is not the mutation
+	assert.NoError(t, checkRoutePolicy(r, defaultRouteRules()...))
+
+	// the violating route
+	r.POST("/api/v1/order", ok)
+	err := checkRoutePolicy(r, defaultRouteRules()...)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1 route policy violations")
+	assert.Contains(t, err.Error(), "POST /api/v1/order: missing auth (rule 'auth of mutation APIs')")
+
+	// the marker override of the route
+	r = newPolicyEngine()
+	Handle(r.Group("/api/v1/auth"), "POST", "/login", ok, Meta{Summary: "login"}, AllowAnonymous())
+	assert.NoError(t, checkRoutePolicy(r, defaultRouteRules()...))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", nil))
+	assert.Equal(t, http.StatusOK, w.Code) // the marker calls the handler
+
+	// the marker does not override the rule of recovery and request id
+	r = gin.New()
+	r.GET("/health", ok, AllowAnonymous())
+	r.PATCH("/api/v1/user/:id", TagMiddleware(ClassRecovery, gin.Recovery()), AllowAnonymous(), ok)
+	err = checkRoutePolicy(r, defaultRouteRules()...)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "2 route policy violations")
+	assert.Contains(t, err.Error(), "GET /health: missing recovery, requestID")
+	assert.Contains(t, err.Error(), "PATCH /api/v1/user/:id: missing requestID")
+	assert.NotContains(t, err.Error(), "missing auth")
+}
+
+// the example routers are compliant, their mutation routes are marked by AllowAnonymous
+func Test_checkRoutePolicy_ExampleRouters(t *testing.T) {
+	r := newPolicyEngine()
+	g := r.Group("/api/v1")
+	userExampleRouter(g, &mock{})
+	userExampleFileRouter(g, &fileMock{})
+	userExampleImportRouter(g, &importMock{})
+	assert.NoError(t, checkRoutePolicy(r, defaultRouteRules()...))
+
+	// the new mutation route without auth fails the check
+	g.POST("/order", func(c *gin.Context) {})
+	assert.EqualError(t, checkRoutePolicy(r, defaultRouteRules()...), "1 route policy violations, add the middlewares "+
+		"or mark the public routes by AllowAnonymous():\n    POST /api/v1/order: missing auth (rule 'auth of mutation APIs')")
+}
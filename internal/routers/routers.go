@@ -15,7 +15,9 @@ import (
 	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
 	"github.com/go-dev-frame/sponge/pkg/gin/middleware/metrics"
 	"github.com/go-dev-frame/sponge/pkg/gin/prof"
+	"github.com/go-dev-frame/sponge/pkg/gin/response"
 	"github.com/go-dev-frame/sponge/pkg/logger"
+	"github.com/go-dev-frame/sponge/pkg/stat"
 
 	"github.com/go-dev-frame/sponge/docs"
 	"github.com/go-dev-frame/sponge/internal/config"
@@ -26,13 +28,33 @@ var (
 	// if you have other group routes you can define them here
 	// example:
 	//     apiV2RouterFns []func(r *gin.RouterGroup)
+
+	adminRouterFns []func(r *gin.RouterGroup) // admin router functions, registered to the admin engine
 )
 
+// addAPIV1RouterFns add the router functions of the public APIs of a module, e.g. in init() of the module
+func addAPIV1RouterFns(fns ...func(r *gin.RouterGroup)) {
+	apiV1RouterFns = append(apiV1RouterFns, fns...)
+}
+
+// addAdminRouterFns add the router functions of the admin APIs of a module, they are registered to the admin
+// engine with the path prefix /admin if the admin port is set, otherwise they are registered to the public
+// engine with the same path prefix and the access control of the admin engine.
+func addAdminRouterFns(fns ...func(r *gin.RouterGroup)) {
+	adminRouterFns = append(adminRouterFns, fns...)
+}
+
+// the admin APIs listen on the separate port
+func isAdminListener() bool {
+	return config.Get().HTTP.Admin.Port > 0
+}
+
 // NewRouter create a new router
 func NewRouter() *gin.Engine {
 	r := gin.New()
 
-	r.Use(gin.Recovery())
+	// the middlewares are tagged for the route policy, see checkRoutePolicy
+	r.Use(TagMiddleware(ClassRecovery, gin.Recovery()))
 	r.Use(middleware.Cors())
 
 	if config.Get().HTTP.Timeout > 0 {
@@ -40,8 +62,19 @@ func NewRouter() *gin.Engine {
 		r.Use(middleware.Timeout(time.Second * time.Duration(config.Get().HTTP.Timeout)))
 	}
 
+	// deadline budget middleware, the timeout of the header X-Request-Timeout of the client is bounded by the server timeout
+	budgetOpts := []middleware.BudgetOption{}
+	if config.Get().HTTP.Timeout > 0 {
+		budgetOpts = append(budgetOpts, middleware.WithBudgetMaxTimeout(time.Second*time.Duration(config.Get().HTTP.Timeout)))
+	}
+	r.Use(middleware.RequestBudget(budgetOpts...))
+
 	// request id middleware
-	r.Use(middleware.RequestID())
+	r.Use(TagMiddleware(ClassRequestID, middleware.RequestID()))
+
+	// response mode of errors: envelope, statusCode or problem(RFC 7807)
+	response.SetMode(config.Get().HTTP.ResponseMode)
+	response.SetRequestIDFunc(middleware.GCtxRequestID)
 
 	// logger middleware, to print simple messages, replace middleware.Logging with middleware.SimpleLog
 	r.Use(middleware.Logging(
@@ -73,12 +106,13 @@ func NewRouter() *gin.Engine {
 		r.Use(middleware.Tracing(config.Get().App.Name))
 	}
 
-	// profile performance analysis
-	if config.Get().App.EnableHTTPProfile {
-		prof.Register(r, prof.WithIOWaitTime())
+	// profile performance analysis, they are moved to the admin engine if the admin port is set
+	if config.Get().App.EnableHTTPProfile && !isAdminListener() {
+		registerDebugRouters(r)
 	}
 
 	r.GET("/health", handlerfunc.CheckHealth)
+	r.GET("/ready", handlerfunc.CheckReady)
 	r.GET("/ping", handlerfunc.Ping)
 	r.GET("/codes", handlerfunc.ListCodes)
 
@@ -96,9 +130,76 @@ func NewRouter() *gin.Engine {
 	// example:
 	//    registerRouters(r, "/api/v2", apiV2RouteFns, middleware.Auth())
 
+	if !isAdminListener() {
+		registerRouters(r, "/admin", adminRouterFns, adminHandlers()...)
+	}
+
+	// the routes without the required middlewares fail the startup, e.g. the mutation APIs without auth
+	mustCheckRoutePolicy(r)
+
+	// HEAD of the GET routes, 405 for the wrong methods
+	handleMethods(r)
+
+	return r
+}
+
+// NewAdminRouter create a new router of the admin and debug APIs, it listens on the admin port, which is not
+// exposed to the internet, the accesses are limited by the allowed ips and the api key.
+func NewAdminRouter() *gin.Engine {
+	r := gin.New()
+
+	r.Use(TagMiddleware(ClassRecovery, gin.Recovery()))
+	r.Use(TagMiddleware(ClassRequestID, middleware.RequestID()))
+	r.Use(middleware.Logging(
+		middleware.WithLog(logger.Get()),
+		middleware.WithRequestIDFromContext(),
+		middleware.WithIgnoreRoutes("/health", "/ready", "/ping"), // ignore path
+	))
+
+	// the health endpoints are not limited, e.g. the probes of kubernetes
+	r.GET("/health", handlerfunc.CheckHealth)
+	r.GET("/ready", handlerfunc.CheckReady)
+	r.GET("/ping", handlerfunc.Ping)
+
+	r.Use(adminHandlers()...)
+	if config.Get().App.EnableHTTPProfile {
+		registerDebugRouters(r)
+	}
+	registerRouters(r, "/admin", adminRouterFns)
+	mustCheckRoutePolicy(r)
+	handleMethods(r)
+
 	return r
 }
 
+// the access control of the admin APIs
+func adminHandlers() []gin.HandlerFunc {
+	cfg := config.Get().HTTP.Admin
+	handlers := []gin.HandlerFunc{middleware.IPAllowList(cfg.AllowIPs...)}
+	if cfg.APIKey != "" {
+		handlers = append(handlers, TagMiddleware(ClassAuth, middleware.APIKey(cfg.APIKey)))
+	}
+	return handlers
+}
+
+func registerDebugRouters(r *gin.Engine) {
+	prof.Register(r, prof.WithIOWaitTime(), prof.WithProfiles()) // the captures of the auto profiler are listed by /debug/profiles
+	// change the log level at runtime, e.g. PUT {"level":"debug","duration":"10m"}
+	r.GET("/debug/loglevel", gin.WrapF(logger.LevelHandler))
+	r.PUT("/debug/loglevel", gin.WrapF(logger.LevelHandler))
+	// the last samples of the process metrics, e.g. GET /debug/stat?n=10
+	r.GET("/debug/stat", gin.WrapF(stat.Handler))
+	// the OpenAPI document of the routes registered by Handle
+	r.GET("/debug/openapi.json", openAPIHandler(config.Get().App.Name, config.Get().App.Version))
+}
+
+// the startup fails with the routes violating the route policy
+func mustCheckRoutePolicy(r *gin.Engine) {
+	if err := checkRoutePolicy(r, defaultRouteRules()...); err != nil {
+		panic(err)
+	}
+}
+
 func registerRouters(r *gin.Engine, groupPath string, routerFns []func(*gin.RouterGroup), handlers ...gin.HandlerFunc) {
 	rg := r.Group(groupPath, handlers...)
 	for _, fn := range routerFns {
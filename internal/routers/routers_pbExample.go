@@ -12,8 +12,10 @@ import (
 	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
 	"github.com/go-dev-frame/sponge/pkg/gin/middleware/metrics"
 	"github.com/go-dev-frame/sponge/pkg/gin/prof"
+	"github.com/go-dev-frame/sponge/pkg/gin/response"
 	"github.com/go-dev-frame/sponge/pkg/gin/swagger"
 	"github.com/go-dev-frame/sponge/pkg/logger"
+	"github.com/go-dev-frame/sponge/pkg/stat"
 
 	"github.com/go-dev-frame/sponge/docs"
 	"github.com/go-dev-frame/sponge/internal/config"
@@ -32,7 +34,7 @@ var (
 func NewRouter_pbExample() *gin.Engine { //nolint
 	r := gin.New()
 
-	r.Use(gin.Recovery())
+	r.Use(TagMiddleware(ClassRecovery, gin.Recovery()))
 	r.Use(middleware.Cors())
 
 	if config.Get().HTTP.Timeout > 0 {
@@ -41,7 +43,11 @@ func NewRouter_pbExample() *gin.Engine { //nolint
 	}
 
 	// request id middleware
-	r.Use(middleware.RequestID())
+	r.Use(TagMiddleware(ClassRequestID, middleware.RequestID()))
+
+	// response mode of errors: envelope, statusCode or problem(RFC 7807)
+	response.SetMode(config.Get().HTTP.ResponseMode)
+	response.SetRequestIDFunc(middleware.GCtxRequestID)
 
 	// logger middleware, to print simple messages, replace middleware.Logging with middleware.SimpleLog
 	r.Use(middleware.Logging(
@@ -77,12 +83,13 @@ func NewRouter_pbExample() *gin.Engine { //nolint
 		r.Use(middleware.Tracing(config.Get().App.Name))
 	}
 
-	// profile performance analysis
-	if config.Get().App.EnableHTTPProfile {
-		prof.Register(r, prof.WithIOWaitTime())
+	// profile performance analysis, they are moved to the admin engine if the admin port is set
+	if config.Get().App.EnableHTTPProfile && config.Get().HTTP.Admin.Port == 0 {
+		registerDebugRouters_pbExample(r)
 	}
 
 	r.GET("/health", handlerfunc.CheckHealth)
+	r.GET("/ready", handlerfunc.CheckReady)
 	r.GET("/ping", handlerfunc.Ping)
 	r.GET("/codes", handlerfunc.ListCodes)
 
@@ -104,9 +111,56 @@ func NewRouter_pbExample() *gin.Engine { //nolint
 		fn(r, c.groupPathMiddlewares, c.singlePathMiddlewares)
 	}
 
+	// the routes without the required middlewares fail the startup, e.g. the mutation APIs without auth
+	mustCheckRoutePolicy(r)
+
+	// HEAD of the GET routes, 405 for the wrong methods
+	handleMethods(r)
+
 	return r
 }
 
+// NewAdminRouter_pbExample create a new router of the debug APIs, it listens on the admin port, which is not
+// exposed to the internet, the accesses are limited by the allowed ips and the api key.
+func NewAdminRouter_pbExample() *gin.Engine { //nolint
+	r := gin.New()
+
+	r.Use(TagMiddleware(ClassRecovery, gin.Recovery()))
+	r.Use(TagMiddleware(ClassRequestID, middleware.RequestID()))
+	r.Use(middleware.Logging(
+		middleware.WithLog(logger.Get()),
+		middleware.WithRequestIDFromContext(),
+		middleware.WithIgnoreRoutes("/health", "/ready", "/ping"), // ignore path
+	))
+
+	// the health endpoints are not limited, e.g. the probes of kubernetes
+	r.GET("/health", handlerfunc.CheckHealth)
+	r.GET("/ready", handlerfunc.CheckReady)
+	r.GET("/ping", handlerfunc.Ping)
+
+	cfg := config.Get().HTTP.Admin
+	r.Use(middleware.IPAllowList(cfg.AllowIPs...))
+	if cfg.APIKey != "" {
+		r.Use(middleware.APIKey(cfg.APIKey))
+	}
+	if config.Get().App.EnableHTTPProfile {
+		registerDebugRouters_pbExample(r)
+	}
+	mustCheckRoutePolicy(r)
+	handleMethods(r)
+
+	return r
+}
+
+func registerDebugRouters_pbExample(r *gin.Engine) { //nolint
+	prof.Register(r, prof.WithIOWaitTime(), prof.WithProfiles()) // the captures of the auto profiler are listed by /debug/profiles
+	// change the log level at runtime, e.g. PUT {"level":"debug","duration":"10m"}
+	r.GET("/debug/loglevel", gin.WrapF(logger.LevelHandler))
+	r.PUT("/debug/loglevel", gin.WrapF(logger.LevelHandler))
+	// the last samples of the process metrics, e.g. GET /debug/stat?n=10
+	r.GET("/debug/stat", gin.WrapF(stat.Handler))
+}
+
 type middlewareConfig struct {
 	groupPathMiddlewares  map[string][]gin.HandlerFunc // middleware functions corresponding to route group
 	singlePathMiddlewares map[string][]gin.HandlerFunc // middleware functions corresponding to a single route
@@ -2,6 +2,8 @@ package routers
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -36,6 +38,46 @@ func TestNewRouter_pbExample(t *testing.T) {
 	})
 }
 
+func TestNewAdminRouter_pbExample(t *testing.T) {
+	err := config.Init(configs.Path("serverNameExample.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Get().App.EnableMetrics = false
+	config.Get().App.EnableHTTPProfile = true
+	config.Get().HTTP.Admin.Port = 8081
+	config.Get().HTTP.Admin.APIKey = "foo"
+	// the swagger of the public router is registered once, see TestNewRouter_pbExample
+	env := config.Get().App.Env
+	config.Get().App.Env = "prod"
+	defer func() { config.Get().HTTP.Admin.Port, config.Get().App.Env = 0, env }()
+
+	gin.SetMode(gin.ReleaseMode)
+	public := httptest.NewServer(NewRouter_pbExample())
+	defer public.Close()
+	admin := httptest.NewServer(NewAdminRouter_pbExample())
+	defer admin.Close()
+
+	request := func(url string, apiKey string) int {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		if apiKey != "" {
+			req.Header.Set("X-Api-Key", apiKey)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.NoError(t, err) {
+			return 0
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// the debug routes are moved to the admin port
+	assert.Equal(t, http.StatusNotFound, request(public.URL+"/debug/loglevel", "foo"))
+	assert.Equal(t, http.StatusOK, request(admin.URL+"/debug/loglevel", "foo"))
+	assert.Equal(t, http.StatusUnauthorized, request(admin.URL+"/debug/loglevel", "bar"))
+	assert.Equal(t, http.StatusOK, request(admin.URL+"/health", ""))
+}
+
 func Test_middlewareConfig(t *testing.T) {
 	c := newMiddlewareConfig()
 
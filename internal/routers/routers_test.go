@@ -2,6 +2,8 @@ package routers
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -50,6 +52,85 @@ func TestNewRouter2(t *testing.T) {
 	})
 }
 
+func TestNewAdminRouter(t *testing.T) {
+	err := config.Init(configs.Path("serverNameExample.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Get().App.EnableMetrics = false
+	config.Get().App.EnableHTTPProfile = true
+	config.Get().HTTP.Admin.Port = 8081
+	config.Get().HTTP.Admin.APIKey = "foo"
+	defer func() { config.Get().HTTP.Admin.Port = 0 }()
+
+	// the routers of the public APIs need the database
+	oldAPIV1Fns, oldAdminFns := apiV1RouterFns, adminRouterFns
+	defer func() { apiV1RouterFns, adminRouterFns = oldAPIV1Fns, oldAdminFns }()
+	apiV1RouterFns = nil
+	addAPIV1RouterFns(func(r *gin.RouterGroup) {
+		r.GET("/userExample/:id", func(c *gin.Context) { c.String(http.StatusOK, c.Param("id")) })
+	})
+	addAdminRouterFns(func(r *gin.RouterGroup) {
+		r.POST("/cache/flush", func(c *gin.Context) { c.String(http.StatusOK, "flushed") })
+	})
+
+	gin.SetMode(gin.ReleaseMode)
+	public := httptest.NewServer(NewRouter())
+	defer public.Close()
+	admin := httptest.NewServer(NewAdminRouter())
+	defer admin.Close()
+
+	request := func(method string, url string, apiKey string) int {
+		req, _ := http.NewRequest(method, url, nil)
+		if apiKey != "" {
+			req.Header.Set("X-Api-Key", apiKey)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.NoError(t, err) {
+			return 0
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// the admin and debug routes are not served by the public port
+	assert.Equal(t, http.StatusNotFound, request(http.MethodPost, public.URL+"/admin/cache/flush", "foo"))
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, public.URL+"/debug/loglevel", "foo"))
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, public.URL+"/health", ""))
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, public.URL+"/api/v1/userExample/1", ""))
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, admin.URL+"/api/v1/userExample/1", "foo"))
+
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, admin.URL+"/admin/cache/flush", "foo"))
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, admin.URL+"/debug/loglevel", "foo"))
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodPost, admin.URL+"/admin/cache/flush", "bar"))
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, admin.URL+"/health", ""))
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, admin.URL+"/ping", ""))
+
+	// the client ip is not allowed
+	config.Get().HTTP.Admin.AllowIPs = []string{"10.0.0.0/8"}
+	admin2 := httptest.NewServer(NewAdminRouter())
+	defer admin2.Close()
+	assert.Equal(t, http.StatusForbidden, request(http.MethodPost, admin2.URL+"/admin/cache/flush", "foo"))
+	// the allowed ip in the X-Forwarded-For header of the client is not trusted
+	req, _ := http.NewRequest(http.MethodGet, admin2.URL+"/debug/loglevel", nil)
+	req.Header.Set("X-Api-Key", "foo")
+	req.Header.Set("X-Forwarded-For", "10.0.0.1")
+	if resp, err := http.DefaultClient.Do(req); assert.NoError(t, err) {
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	}
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, admin2.URL+"/health", ""))
+
+	// the admin routes share the public port with the same access control if the admin port is not set
+	config.Get().HTTP.Admin.Port = 0
+	config.Get().HTTP.Admin.AllowIPs = nil
+	shared := httptest.NewServer(NewRouter())
+	defer shared.Close()
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, shared.URL+"/admin/cache/flush", "foo"))
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodPost, shared.URL+"/admin/cache/flush", ""))
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, shared.URL+"/debug/loglevel", ""))
+}
+
 type mock struct{}
 
 func (u mock) Create(c *gin.Context)     { return }
@@ -57,9 +138,42 @@ func (u mock) DeleteByID(c *gin.Context) { return }
 func (u mock) UpdateByID(c *gin.Context) { return }
 func (u mock) GetByID(c *gin.Context)    { return }
 func (u mock) List(c *gin.Context)       { return }
+func (u mock) Archive(c *gin.Context)    { return }
+func (u mock) Unarchive(c *gin.Context)  { return }
+func (u mock) Distinct(c *gin.Context)   { return }
+func (u mock) Activity(c *gin.Context)   { return }
+func (u mock) Aggregate(c *gin.Context)  { return }
 
 func Test_userExampleRouter(t *testing.T) {
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.Default()
 	userExampleRouter(r.Group("/"), &mock{})
 }
+
+type fileMock struct{}
+
+func (u fileMock) Upload(c *gin.Context)   { return }
+func (u fileMock) List(c *gin.Context)     { return }
+func (u fileMock) Download(c *gin.Context) { return }
+func (u fileMock) Delete(c *gin.Context)   { return }
+
+func Test_userExampleFileRouter(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.Default()
+	userExampleFileRouter(r.Group("/"), &fileMock{})
+	assert.Len(t, r.Routes(), 4)
+}
+
+type importMock struct{}
+
+func (u importMock) Import(c *gin.Context)         { return }
+func (u importMock) GetJob(c *gin.Context)         { return }
+func (u importMock) DownloadErrors(c *gin.Context) { return }
+
+func Test_userExampleImportRouter(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.Default()
+	userExampleImportRouter(r.Group("/"), &importMock{})
+	userExampleRouter(r.Group("/"), &mock{}) // the static path import and the path parameter id are not conflicted
+	assert.Len(t, r.Routes(), 13)
+}
@@ -3,7 +3,10 @@ package routers
 import (
 	"github.com/gin-gonic/gin"
 
+	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
+
 	"github.com/go-dev-frame/sponge/internal/handler"
+	"github.com/go-dev-frame/sponge/internal/types"
 )
 
 func init() {
@@ -16,14 +19,44 @@ func userExampleRouter(group *gin.RouterGroup, h handler.UserExampleHandler) {
 	g := group.Group("/userExample")
 
 	// All the following routes use jwt authentication, you also can use middleware.Auth(middleware.WithExtraVerify(fn))
-	//g.Use(middleware.Auth())
+	//g.Use(TagMiddleware(ClassAuth, middleware.Auth()))
+	// the example mutation routes are marked public by AllowAnonymous(), remove the marks after the authentication
+	// is enabled, the mutation routes without the authentication or the mark fail the startup, see checkRoutePolicy
 
 	// If jwt authentication is not required for all routes, authentication middleware can be added
 	// separately for only certain routes. In this case, g.Use(middleware.Auth()) above should not be used.
 
-	g.POST("/", h.Create)          // [post] /api/v1/userExample
-	g.DELETE("/:id", h.DeleteByID) // [delete] /api/v1/userExample/:id
-	g.PUT("/:id", h.UpdateByID)    // [put] /api/v1/userExample/:id
-	g.GET("/:id", h.GetByID)       // [get] /api/v1/userExample/:id
-	g.POST("/list", h.List)        // [post] /api/v1/userExample/list
+	// the concurrent identical requests of the same caller (tenant and user) share one execution
+	dedup := middleware.Dedup(middleware.WithDedupVary(handler.GetCallerKey))
+	// the records got by id are memoized within the request by handler.RequestCache(), opt-in per route, e.g.
+	// the handlers fetching the same record by different code paths:
+	//Handle(g, "GET", "/:id/summary", h.Summary, Meta{Summary: "userExample summary", Tags: tags}, handler.RequestCache())
+	// the routes in the dark launch respond 404 until the feature flag in the config is enabled for the tenant (user):
+	//Handle(g, "GET", "/cursor", h.CursorList, Meta{Summary: "list userExamples by cursor", Tags: tags}, handler.RequireFlag("userExample.cursorList"))
+
+	// the routes registered by Handle are listed by the OpenAPI document of /debug/openapi.json
+	tags := []string{"userExample"}
+	Handle(g, "POST", "/", h.Create, Meta{Summary: "create userExample", Tags: tags, // [post] /api/v1/userExample
+		Req: types.CreateUserExampleRequest{}, Resp: types.CreateUserExampleReply{}}, AllowAnonymous())
+	Handle(g, "DELETE", "/:id", h.DeleteByID, Meta{Summary: "delete userExample", Tags: tags, // [delete] /api/v1/userExample/:id
+		Resp: types.DeleteUserExampleByIDReply{}}, AllowAnonymous())
+	Handle(g, "PUT", "/:id", h.UpdateByID, Meta{Summary: "update userExample", Tags: tags, // [put] /api/v1/userExample/:id
+		Req: types.UpdateUserExampleByIDRequest{}, Resp: types.UpdateUserExampleByIDReply{}}, AllowAnonymous())
+	Handle(g, "GET", "/:id", h.GetByID, Meta{Summary: "get userExample detail", Tags: tags, // [get] /api/v1/userExample/:id
+		Resp: types.GetUserExampleByIDReply{}}, dedup)
+	Handle(g, "POST", "/list", h.List, Meta{Summary: "list of userExamples by query parameters", Tags: tags, // [post] /api/v1/userExample/list
+		Req: types.ListUserExamplesRequest{}, Resp: types.ListUserExamplesReply{}}, AllowAnonymous())
+	Handle(g, "POST", "/distinct", h.Distinct, Meta{Summary: "distinct values of userExample column", Tags: tags, // [post] /api/v1/userExample/distinct
+		Req: types.DistinctUserExampleRequest{}, Resp: types.DistinctUserExampleReply{}}, AllowAnonymous())
+
+	// delete the templates code start
+	Handle(g, "POST", "/:id/archive", h.Archive, Meta{Summary: "archive userExample", Tags: tags, // [post] /api/v1/userExample/:id/archive
+		Resp: types.UpdateUserExampleByIDReply{}}, AllowAnonymous())
+	Handle(g, "POST", "/:id/unarchive", h.Unarchive, Meta{Summary: "unarchive userExample", Tags: tags, // [post] /api/v1/userExample/:id/unarchive
+		Resp: types.UpdateUserExampleByIDReply{}}, AllowAnonymous())
+	// delete the templates code end
+	Handle(g, "GET", "/:id/activity", h.Activity, Meta{Summary: "list userExample activity", Tags: tags, // [get] /api/v1/userExample/:id/activity
+		Req: types.ListUserExampleActivitiesRequest{}, Resp: types.ListUserExampleActivitiesReply{}})
+	Handle(g, "POST", "/:id/aggregate", h.Aggregate, Meta{Summary: "aggregate userExample with related resources", Tags: tags, // [post] /api/v1/userExample/:id/aggregate
+		Req: types.AggregateUserExampleRequest{}, Resp: types.AggregateUserExampleReply{}}, AllowAnonymous())
 }
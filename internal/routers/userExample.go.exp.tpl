@@ -3,6 +3,8 @@ package routers
 import (
 	"github.com/gin-gonic/gin"
 
+	"github.com/go-dev-frame/sponge/pkg/gin/middleware"
+
 	"github.com/go-dev-frame/sponge/internal/handler"
 )
 
@@ -16,19 +18,24 @@ func {{.TableNameCamelFCL}}Router(group *gin.RouterGroup, h handler.{{.TableName
 	g := group.Group("/{{.TableNameCamelFCL}}")
 
 	// All the following routes use jwt authentication, you also can use middleware.Auth(middleware.WithExtraVerify(fn))
-	//g.Use(middleware.Auth())
+	//g.Use(TagMiddleware(ClassAuth, middleware.Auth()))
+	// the example mutation routes are marked public by AllowAnonymous(), remove the marks after the authentication
+	// is enabled, the mutation routes without the authentication or the mark fail the startup, see checkRoutePolicy
 
 	// If jwt authentication is not required for all routes, authentication middleware can be added
 	// separately for only certain routes. In this case, g.Use(middleware.Auth()) above should not be used.
 
-	g.POST("/", h.Create)          // [post] /api/v1/{{.TableNameCamelFCL}}
-	g.DELETE("/:{{.ColumnNameCamelFCL}}", h.DeleteBy{{.ColumnNameCamel}}) // [delete] /api/v1/{{.TableNameCamelFCL}}/:{{.ColumnNameCamelFCL}}
-	g.PUT("/:{{.ColumnNameCamelFCL}}", h.UpdateBy{{.ColumnNameCamel}})    // [put] /api/v1/{{.TableNameCamelFCL}}/:{{.ColumnNameCamelFCL}}
-	g.GET("/:{{.ColumnNameCamelFCL}}", h.GetBy{{.ColumnNameCamel}})       // [get] /api/v1/{{.TableNameCamelFCL}}/:{{.ColumnNameCamelFCL}}
-	g.POST("/list", h.List)        // [post] /api/v1/{{.TableNameCamelFCL}}/list
+	// the concurrent identical requests of the same caller (tenant and user) share one execution
+	dedup := middleware.Dedup(middleware.WithDedupVary(handler.GetCallerKey))
+
+	g.POST("/", AllowAnonymous(), h.Create)          // [post] /api/v1/{{.TableNameCamelFCL}}
+	g.DELETE("/:{{.ColumnNameCamelFCL}}", AllowAnonymous(), h.DeleteBy{{.ColumnNameCamel}}) // [delete] /api/v1/{{.TableNameCamelFCL}}/:{{.ColumnNameCamelFCL}}
+	g.PUT("/:{{.ColumnNameCamelFCL}}", AllowAnonymous(), h.UpdateBy{{.ColumnNameCamel}})    // [put] /api/v1/{{.TableNameCamelFCL}}/:{{.ColumnNameCamelFCL}}
+	g.GET("/:{{.ColumnNameCamelFCL}}", dedup, h.GetBy{{.ColumnNameCamel}})       // [get] /api/v1/{{.TableNameCamelFCL}}/:{{.ColumnNameCamelFCL}}
+	g.POST("/list", AllowAnonymous(), h.List)        // [post] /api/v1/{{.TableNameCamelFCL}}/list
 
-	g.POST("/delete/{{.ColumnNamePluralCamelFCL}}", h.DeleteBy{{.ColumnNamePluralCamel}})   // [post] /api/v1/{{.TableNameCamelFCL}}/delete/{{.ColumnNamePluralCamelFCL}}
-	g.POST("/condition", h.GetByCondition) // [post] /api/v1/{{.TableNameCamelFCL}}/condition
-	g.POST("/list/{{.ColumnNamePluralCamelFCL}}", h.ListBy{{.ColumnNamePluralCamel}})       // [post] /api/v1/{{.TableNameCamelFCL}}/list/{{.ColumnNamePluralCamelFCL}}
+	g.POST("/delete/{{.ColumnNamePluralCamelFCL}}", AllowAnonymous(), h.DeleteBy{{.ColumnNamePluralCamel}})   // [post] /api/v1/{{.TableNameCamelFCL}}/delete/{{.ColumnNamePluralCamelFCL}}
+	g.POST("/condition", AllowAnonymous(), h.GetByCondition) // [post] /api/v1/{{.TableNameCamelFCL}}/condition
+	g.POST("/list/{{.ColumnNamePluralCamelFCL}}", AllowAnonymous(), h.ListBy{{.ColumnNamePluralCamel}})       // [post] /api/v1/{{.TableNameCamelFCL}}/list/{{.ColumnNamePluralCamelFCL}}
 	g.GET("/list", h.ListByLast{{.ColumnNameCamel}})         // [get] /api/v1/{{.TableNameCamelFCL}}/list
 }
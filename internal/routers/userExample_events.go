@@ -0,0 +1,15 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/go-dev-frame/sponge/internal/handler"
+)
+
+func init() {
+	apiV1RouterFns = append(apiV1RouterFns, func(group *gin.RouterGroup) {
+		// websocket, subscribe to the created, updated and deleted events of userExample
+		Handle(group, "GET", "/userExample/events", gin.WrapH(handler.UserExampleEventHub()), // [get] /api/v1/userExample/events
+			Meta{Summary: "subscribe to the events of userExample by websocket", Tags: []string{"userExample"}})
+	})
+}
@@ -0,0 +1,31 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/go-dev-frame/sponge/internal/handler"
+	"github.com/go-dev-frame/sponge/internal/types"
+)
+
+func init() {
+	apiV1RouterFns = append(apiV1RouterFns, func(group *gin.RouterGroup) {
+		userExampleFileRouter(group, handler.NewUserExampleFileHandler())
+	})
+}
+
+func userExampleFileRouter(group *gin.RouterGroup, h handler.UserExampleFileHandler) {
+	g := group.Group("/userExample/:id/files")
+
+	// the files belong to the record and the tenant of the jwt claims, use the same authentication as userExample
+	//g.Use(TagMiddleware(ClassAuth, middleware.Auth()))
+	g.Use(AllowAnonymous()) // the example routes are public, remove it after the authentication is enabled
+
+	tags := []string{"userExample"}
+	Handle(g, "POST", "", h.Upload, Meta{Summary: "upload userExample file", Tags: tags, // [post] /api/v1/userExample/:id/files
+		Resp: types.UploadUserExampleFileReply{}})
+	Handle(g, "GET", "", h.List, Meta{Summary: "list userExample files", Tags: tags, // [get] /api/v1/userExample/:id/files
+		Resp: types.ListUserExampleFilesReply{}})
+	Handle(g, "GET", "/:fileID", h.Download, Meta{Summary: "download userExample file", Tags: tags}) // [get] /api/v1/userExample/:id/files/:fileID
+	Handle(g, "DELETE", "/:fileID", h.Delete, Meta{Summary: "delete userExample file", Tags: tags,   // [delete] /api/v1/userExample/:id/files/:fileID
+		Resp: types.DeleteUserExampleFileReply{}})
+}
@@ -0,0 +1,29 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/go-dev-frame/sponge/internal/handler"
+	"github.com/go-dev-frame/sponge/internal/types"
+)
+
+func init() {
+	apiV1RouterFns = append(apiV1RouterFns, func(group *gin.RouterGroup) {
+		userExampleImportRouter(group, handler.NewUserExampleImportHandler())
+	})
+}
+
+func userExampleImportRouter(group *gin.RouterGroup, h handler.UserExampleImportHandler) {
+	g := group.Group("/userExample/import")
+
+	// the records are imported to the tenant of the jwt claims, use the same authentication as userExample
+	//g.Use(TagMiddleware(ClassAuth, middleware.Auth()))
+	g.Use(AllowAnonymous()) // the example routes are public, remove it after the authentication is enabled
+
+	tags := []string{"userExample"}
+	Handle(g, "POST", "", h.Import, Meta{Summary: "import userExamples", Tags: tags, // [post] /api/v1/userExample/import
+		Req: types.ImportUserExamplesRequest{}, Resp: types.ImportUserExamplesReply{}})
+	Handle(g, "GET", "/:jobID", h.GetJob, Meta{Summary: "get userExample import job", Tags: tags, // [get] /api/v1/userExample/import/:jobID
+		Resp: types.GetUserExampleImportJobReply{}})
+	Handle(g, "GET", "/:jobID/errors", h.DownloadErrors, Meta{Summary: "download userExample import errors", Tags: tags}) // [get] /api/v1/userExample/import/:jobID/errors
+}
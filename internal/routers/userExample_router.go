@@ -59,16 +59,21 @@ func userExampleServiceRouter(
 // or you can mix them, pay attention to the duplication of middleware when mixing them,
 // it is recommended to set the middleware of a single route in preference
 func userExampleMiddlewares(c *middlewareConfig) {
-	_ = c
-
 	// set up group route middleware, group path is left prefix rules,
 	// if the left prefix is hit, the middleware will take effect, e.g. group route /api/v1, route /api/v1/userExample/:id  will take effect
-	// c.setGroupPath("/api/v1/userExample", middleware.Auth())
+	// c.setGroupPath("/api/v1/userExample", TagMiddleware(ClassAuth, middleware.Auth()))
 
 	// set up single route middleware, just uncomment the code and fill in the middlewares, nothing else needs to be changed
-	//c.setSinglePath("POST", "/api/v1/userExample", middleware.Auth())
-	//c.setSinglePath("DELETE", "/api/v1/userExample/:id", middleware.Auth())
-	//c.setSinglePath("PUT", "/api/v1/userExample/:id", middleware.Auth())
-	//c.setSinglePath("GET", "/api/v1/userExample/:id", middleware.Auth())
-	//c.setSinglePath("POST", "/api/v1/userExample/list", middleware.Auth())
+	//c.setSinglePath("GET", "/api/v1/userExample/:id", TagMiddleware(ClassAuth, middleware.Auth()))
+
+	// the mutation routes are marked public by AllowAnonymous(), replace the marks with the authentication, e.g.
+	// TagMiddleware(ClassAuth, middleware.Auth()), the mutation routes without the authentication or the mark fail
+	// the startup, see checkRoutePolicy
+	c.setSinglePath("POST", "/api/v1/userExample", AllowAnonymous())
+	c.setSinglePath("DELETE", "/api/v1/userExample/:id", AllowAnonymous())
+	c.setSinglePath("PUT", "/api/v1/userExample/:id", AllowAnonymous())
+	c.setSinglePath("POST", "/api/v1/userExample/list", AllowAnonymous())
+
+	// mark the deprecated group or route, the clients get the Deprecation, Sunset and Link headers
+	//c.setGroupPath("/api/v1/userExample", WithDeprecated(time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC), "https://example.com/docs/v2"))
 }
@@ -20,13 +20,17 @@ import (
 	"github.com/go-dev-frame/sponge/pkg/logger"
 	"github.com/go-dev-frame/sponge/pkg/prof"
 	"github.com/go-dev-frame/sponge/pkg/servicerd/registry"
+	"github.com/go-dev-frame/sponge/pkg/stat"
 
 	"github.com/go-dev-frame/sponge/internal/config"
 	"github.com/go-dev-frame/sponge/internal/ecode"
 	"github.com/go-dev-frame/sponge/internal/service"
 )
 
-var _ app.IServer = (*grpcServer)(nil)
+var (
+	_ app.IServer      = (*grpcServer)(nil)
+	_ app.Deregisterer = (*grpcServer)(nil)
+)
 
 var (
 	defaultTokenAppID  = "grpc"
@@ -42,16 +46,18 @@ type grpcServer struct {
 	httpServer                      *http.Server
 	registerMetricsMuxAndMethodFunc func() error
 
-	iRegistry registry.Registry
-	instance  *registry.ServiceInstance
+	iRegistry    registry.Registry
+	instance     *registry.ServiceInstance
+	registration *registry.Registration
 }
 
 // Start grpc service
 func (s *grpcServer) Start() error {
 	// registration Services
 	if s.iRegistry != nil {
+		s.registration = registry.NewRegistration(s.iRegistry, s.instance)
 		ctx, _ := context.WithTimeout(context.Background(), 5*time.Second) //nolint
-		if err := s.iRegistry.Register(ctx, s.instance); err != nil {
+		if err := s.registration.Register(ctx); err != nil {
 			return err
 		}
 	}
@@ -87,13 +93,10 @@ func (s *grpcServer) Start() error {
 
 // Stop grpc service
 func (s *grpcServer) Stop() error {
-	if s.iRegistry != nil {
+	if s.IsRegistered() { // not yet deregistered by the app
 		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-		go func() {
-			_ = s.iRegistry.Deregister(ctx, s.instance)
-			cancel()
-		}()
-		<-ctx.Done()
+		_ = s.Deregister(ctx)
+		cancel()
 	}
 
 	s.server.GracefulStop()
@@ -108,6 +111,16 @@ func (s *grpcServer) Stop() error {
 	return nil
 }
 
+// IsRegistered returns whether the grpc service is registered
+func (s *grpcServer) IsRegistered() bool {
+	return s.registration.IsRegistered()
+}
+
+// Deregister grpc service, it is idempotent
+func (s *grpcServer) Deregister(ctx context.Context) error {
+	return s.registration.Deregister(ctx)
+}
+
 // String comment
 func (s *grpcServer) String() string {
 	return "grpc service address " + s.addr
@@ -180,7 +193,11 @@ func (s *grpcServer) unaryServerOptions() grpc.ServerOption {
 
 	// metrics interceptor
 	if config.Get().App.EnableMetrics {
-		unaryServerInterceptors = append(unaryServerInterceptors, interceptor.UnaryServerMetrics())
+		var metricsOpts []metrics.Option
+		if c := stat.GetCollector(); c != nil {
+			metricsOpts = append(metricsOpts, metrics.WithCollectors(c)) // export the process metrics of stat
+		}
+		unaryServerInterceptors = append(unaryServerInterceptors, interceptor.UnaryServerMetrics(metricsOpts...))
 		s.registerMetricsMuxAndMethodFunc = s.registerMetricsMuxAndMethod()
 	}
 
@@ -295,7 +312,11 @@ func (s *grpcServer) registerProfMux() {
 	if s.mux == nil {
 		s.mux = http.NewServeMux()
 	}
-	prof.Register(s.mux, prof.WithIOWaitTime())
+	prof.Register(s.mux, prof.WithIOWaitTime(), prof.WithProfiles()) // the captures of the auto profiler are listed by /debug/profiles
+	// change the log level at runtime, e.g. PUT {"level":"debug","duration":"10m"}
+	s.mux.HandleFunc("/debug/loglevel", logger.LevelHandler)
+	// the last samples of the process metrics, e.g. GET /debug/stat?n=10
+	s.mux.HandleFunc("/debug/stat", stat.Handler)
 }
 
 func (s *grpcServer) addHTTPRouter() {
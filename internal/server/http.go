@@ -14,21 +14,26 @@ import (
 	"github.com/go-dev-frame/sponge/internal/routers"
 )
 
-var _ app.IServer = (*httpServer)(nil)
+var (
+	_ app.IServer      = (*httpServer)(nil)
+	_ app.Deregisterer = (*httpServer)(nil)
+)
 
 type httpServer struct {
 	addr   string
 	server *http.Server
 
-	instance  *registry.ServiceInstance
-	iRegistry registry.Registry
+	instance     *registry.ServiceInstance
+	iRegistry    registry.Registry
+	registration *registry.Registration
 }
 
 // Start http service
 func (s *httpServer) Start() error {
 	if s.iRegistry != nil {
+		s.registration = registry.NewRegistration(s.iRegistry, s.instance)
 		ctx, _ := context.WithTimeout(context.Background(), 5*time.Second) //nolint
-		if err := s.iRegistry.Register(ctx, s.instance); err != nil {
+		if err := s.registration.Register(ctx); err != nil {
 			return err
 		}
 	}
@@ -41,19 +46,26 @@ func (s *httpServer) Start() error {
 
 // Stop http service
 func (s *httpServer) Stop() error {
-	if s.iRegistry != nil {
+	if s.IsRegistered() { // not yet deregistered by the app
 		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-		go func() {
-			_ = s.iRegistry.Deregister(ctx, s.instance)
-			cancel()
-		}()
-		<-ctx.Done()
+		_ = s.Deregister(ctx)
+		cancel()
 	}
 
 	ctx, _ := context.WithTimeout(context.Background(), 3*time.Second) //nolint
 	return s.server.Shutdown(ctx)
 }
 
+// IsRegistered returns whether the http service is registered
+func (s *httpServer) IsRegistered() bool {
+	return s.registration.IsRegistered()
+}
+
+// Deregister http service, it is idempotent
+func (s *httpServer) Deregister(ctx context.Context) error {
+	return s.registration.Deregister(ctx)
+}
+
 // String comment
 func (s *httpServer) String() string {
 	return "http service address " + s.addr
@@ -87,6 +99,31 @@ func NewHTTPServer(addr string, opts ...HTTPOption) app.IServer {
 	}
 }
 
+// NewAdminHTTPServer creates a new http server of the admin and debug APIs, it listens on the separate port and
+// is stopped independently of the public http server, and it is never registered to the service registry.
+func NewAdminHTTPServer(addr string, opts ...HTTPOption) app.IServer {
+	o := defaultHTTPOptions()
+	o.apply(opts...)
+
+	if o.isProd {
+		gin.SetMode(gin.ReleaseMode)
+	} else {
+		gin.SetMode(gin.DebugMode)
+	}
+
+	router := routers.NewAdminRouter()
+	server := &http.Server{
+		Addr:           addr,
+		Handler:        router,
+		MaxHeaderBytes: 1 << 20,
+	}
+
+	return &httpServer{
+		addr:   addr,
+		server: server,
+	}
+}
+
 // delete the templates code start
 
 // NewHTTPServer_pbExample creates a new web server
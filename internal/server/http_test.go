@@ -47,6 +47,12 @@ func TestHTTPServer(t *testing.T) {
 		cancel()
 	})
 
+	utils.SafeRunWithTimeout(time.Second, func(cancel context.CancelFunc) {
+		server := NewAdminHTTPServer(addr, WithHTTPIsProd(true))
+		assert.NotNil(t, server)
+		cancel()
+	})
+
 	utils.SafeRunWithTimeout(time.Second*2, func(cancel context.CancelFunc) {
 		server := NewHTTPServer_pbExample(addr,
 			WithHTTPIsProd(true),
@@ -0,0 +1,40 @@
+package types
+
+import (
+	"time"
+)
+
+// ListUserExampleActivitiesRequest request params
+type ListUserExampleActivitiesRequest struct {
+	Page  int `json:"page" form:"page" binding:"gte=0"`           // page number, starts from 0
+	Limit int `json:"limit" form:"limit" binding:"gte=0,lte=100"` // lines per page, default 10
+}
+
+// UserExampleActivityChange the old and new values of a changed field, the sensitive values are redacted
+type UserExampleActivityChange struct {
+	Field string      `json:"field"` // json name of the field
+	Old   interface{} `json:"old"`   // value before the write, null if the record is created
+	New   interface{} `json:"new"`   // value after the write, null if the record is deleted
+}
+
+// UserExampleActivityObjDetail detail
+type UserExampleActivityObjDetail struct {
+	ID        uint64                      `json:"id"`        // id
+	Actor     string                      `json:"actor"`     // uid of the user who wrote the record
+	Action    string                      `json:"action"`    // created, updated or deleted
+	Changes   []UserExampleActivityChange `json:"changes"`   // changed fields ordered by the name
+	CreatedAt time.Time                   `json:"createdAt"` // time of the write
+}
+
+// ListUserExampleActivitiesReply only for api docs
+type ListUserExampleActivitiesReply struct {
+	Code int    `json:"code"` // return code
+	Msg  string `json:"msg"`  // return information description
+	Data struct {
+		Items   []UserExampleActivityObjDetail `json:"items"`   // entries, the newest first
+		Total   int64                          `json:"total"`   // total number of entries
+		Page    int                            `json:"page"`    // page number, starts from 0
+		Limit   int                            `json:"limit"`   // lines per page
+		HasNext bool                           `json:"hasNext"` // whether there is a next page
+	} `json:"data"` // return data
+}
@@ -0,0 +1,22 @@
+package types
+
+// AggregateUserExampleRequest request params
+type AggregateUserExampleRequest struct {
+	Includes []string `json:"includes" binding:"required,min=1"` // names of the includes, e.g. activityCount, latestActivity
+}
+
+// UserExampleIncludeResult the result of an include, either data or error
+type UserExampleIncludeResult struct {
+	Data  interface{} `json:"data,omitempty"`  // data of the include
+	Error string      `json:"error,omitempty"` // error of the include, e.g. timeout
+}
+
+// AggregateUserExampleReply only for api docs
+type AggregateUserExampleReply struct {
+	Code int    `json:"code"` // return code
+	Msg  string `json:"msg"`  // return information description
+	Data struct {
+		UserExample UserExampleObjDetail                `json:"userExample"` // the record
+		Includes    map[string]UserExampleIncludeResult `json:"includes"`    // results of the includes, the key is the name
+	} `json:"data"` // return data
+}
@@ -0,0 +1,38 @@
+package types
+
+import (
+	"time"
+)
+
+// UserExampleFileObjDetail detail
+type UserExampleFileObjDetail struct {
+	ID          uint64    `json:"id"`          // id
+	Filename    string    `json:"filename"`    // sanitized original filename
+	Size        int64     `json:"size"`        // size in bytes
+	ContentType string    `json:"contentType"` // sniffed content type
+	Hash        string    `json:"hash"`        // hex encoded sha256 of the content
+	CreatedAt   time.Time `json:"createdAt"`   // upload time
+}
+
+// UploadUserExampleFileReply only for api docs
+type UploadUserExampleFileReply struct {
+	Code int    `json:"code"` // return code
+	Msg  string `json:"msg"`  // return information description
+	Data struct {
+		File UserExampleFileObjDetail `json:"file"`
+	} `json:"data"` // return data
+}
+
+// ListUserExampleFilesReply only for api docs
+type ListUserExampleFilesReply struct {
+	Code int    `json:"code"` // return code
+	Msg  string `json:"msg"`  // return information description
+	Data struct {
+		Files []UserExampleFileObjDetail `json:"files"` // files ordered by the upload time
+	} `json:"data"` // return data
+}
+
+// DeleteUserExampleFileReply only for api docs
+type DeleteUserExampleFileReply struct {
+	Result
+}
@@ -0,0 +1,59 @@
+package types
+
+import (
+	"time"
+)
+
+// ImportUserExamplesRequest request params, the csv file is the form field file
+type ImportUserExamplesRequest struct {
+	// validate the rows and return the summary without writing
+	Preview bool `json:"preview" form:"preview"`
+	// json of the mapping of the fields of CreateUserExampleRequest to the csv headers, e.g. {"name":"Full Name"},
+	// the headers of the fields not in the mapping are the json names of the fields
+	Mapping string `json:"mapping" form:"mapping"`
+}
+
+// UserExampleImportRowError the error of a row of the csv file
+type UserExampleImportRowError struct {
+	Line  int    `json:"line"`  // line number of the csv file, the header is line 1
+	Field string `json:"field"` // json name of the field, empty if the error is not of a field
+	Error string `json:"error"` // error message
+}
+
+// ImportUserExamplesResult the summary of the validation of the csv file
+type ImportUserExamplesResult struct {
+	Total   int                         `json:"total"`           // number of the data rows
+	Valid   int                         `json:"valid"`           // number of the rows passing the validation
+	Invalid int                         `json:"invalid"`         // number of the rows failing the validation
+	Errors  []UserExampleImportRowError `json:"errors"`          // the first errors of the invalid rows
+	JobID   uint64                      `json:"jobID,omitempty"` // id of the import job, empty in the preview mode
+}
+
+// UserExampleImportJobObjDetail detail
+type UserExampleImportJobObjDetail struct {
+	ID        uint64    `json:"id"`        // id
+	Status    string    `json:"status"`    // pending, running, succeeded or failed
+	Total     int       `json:"total"`     // number of the data rows
+	Valid     int       `json:"valid"`     // number of the rows passing the validation
+	Processed int       `json:"processed"` // number of the valid rows processed
+	Succeeded int       `json:"succeeded"` // number of the records created
+	Failed    int       `json:"failed"`    // number of the invalid rows and the rows failed to create
+	CreatedAt time.Time `json:"createdAt"` // create time
+	UpdatedAt time.Time `json:"updatedAt"` // time of the last progress
+}
+
+// ImportUserExamplesReply only for api docs
+type ImportUserExamplesReply struct {
+	Code int                      `json:"code"` // return code
+	Msg  string                   `json:"msg"`  // return information description
+	Data ImportUserExamplesResult `json:"data"` // return data
+}
+
+// GetUserExampleImportJobReply only for api docs
+type GetUserExampleImportJobReply struct {
+	Code int    `json:"code"` // return code
+	Msg  string `json:"msg"`  // return information description
+	Data struct {
+		Job UserExampleImportJobObjDetail `json:"job"`
+	} `json:"data"` // return data
+}
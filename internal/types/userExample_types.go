@@ -34,6 +34,7 @@ type UpdateUserExampleByIDRequest struct {
 	Avatar   string `json:"avatar" binding:""`   // avatar
 	Age      int    `json:"age" binding:""`      // age
 	Gender   int    `json:"gender" binding:""`   // gender, 1:Male, 2:Female, other values:unknown
+	Status   int    `json:"status" binding:""`   // account status, 1:inactive, 2:activated, 3:blocked, 4:archived, 0:unchanged
 }
 
 // UserExampleObjDetail detail
@@ -45,7 +46,7 @@ type UserExampleObjDetail struct {
 	Avatar    string    `json:"avatar"`    // avatar
 	Age       int       `json:"age"`       // age
 	Gender    int       `json:"gender"`    // gender, 1:Male, 2:Female, other values:unknown
-	Status    int       `json:"status"`    // account status, 1:inactive, 2:activated, 3:blocked
+	Status    int       `json:"status"`    // account status, 1:inactive, 2:activated, 3:blocked, 4:archived
 	LoginAt   int64     `json:"loginAt"`   // login timestamp
 	CreatedAt time.Time `json:"createdAt"` // create time
 	UpdatedAt time.Time `json:"updatedAt"` // update time
@@ -91,6 +92,29 @@ type ListUserExamplesReply struct {
 	Code int    `json:"code"` // return code
 	Msg  string `json:"msg"`  // return information description
 	Data struct {
-		UserExamples []UserExampleObjDetail `json:"userExamples"`
+		Items      []UserExampleObjDetail `json:"items"`      // records
+		Total      int64                  `json:"total"`      // total number of records, offset pagination
+		Page       int                    `json:"page"`       // page number, starts from 0, offset pagination
+		Limit      int                    `json:"limit"`      // lines per page, offset pagination
+		NextCursor string                 `json:"nextCursor"` // cursor of the next page, cursor pagination
+		HasNext    bool                   `json:"hasNext"`    // whether there is a next page
+	} `json:"data"` // return data
+}
+
+// DistinctUserExampleRequest request params
+type DistinctUserExampleRequest struct {
+	Column     string         `json:"column" binding:"required"` // column name, e.g. city
+	Conditions []query.Column `json:"conditions"`                // conditions of the records, the same as the columns of list
+	Prefix     string         `json:"prefix"`                    // only the values starting with prefix, e.g. typeahead, at most 64 characters
+	Limit      int            `json:"limit"`                     // maximum number of values, default 100, at most 100
+}
+
+// DistinctUserExampleReply only for api docs
+type DistinctUserExampleReply struct {
+	Code int    `json:"code"` // return code
+	Msg  string `json:"msg"`  // return information description
+	Data struct {
+		Values    []interface{} `json:"values"`    // distinct values ordered by the value
+		Truncated bool          `json:"truncated"` // whether there are more values than the limit
 	} `json:"data"` // return data
 }
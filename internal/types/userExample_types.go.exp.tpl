@@ -96,7 +96,12 @@ type List{{.TableNamePluralCamel}}Reply struct {
 	Code int    `json:"code"` // return code
 	Msg  string `json:"msg"`  // return information description
 	Data struct {
-		{{.TableNamePluralCamel}} []{{.TableNameCamel}}ObjDetail `json:"{{.TableNamePluralCamelFCL}}"`
+		Items      []{{.TableNameCamel}}ObjDetail `json:"items"`      // records
+		Total      int64  `json:"total"`      // total number of records, offset pagination
+		Page       int    `json:"page"`       // page number, starts from 0, offset pagination
+		Limit      int    `json:"limit"`      // lines per page, offset pagination
+		NextCursor string `json:"nextCursor"` // cursor of the next page, cursor pagination
+		HasNext    bool   `json:"hasNext"`    // whether there is a next page
 	} `json:"data"` // return data
 }
 
@@ -7,9 +7,11 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"golang.org/x/sync/errgroup"
 
+	"github.com/go-dev-frame/sponge/pkg/logger"
 	"github.com/go-dev-frame/sponge/pkg/prof"
 )
 
@@ -20,6 +22,16 @@ type IServer interface {
 	String() string
 }
 
+// Deregisterer is implemented by the servers registered with a service registry, before the servers
+// are stopped, they are deregistered and the app waits for the drain period, so that the clients that
+// still cache the address in discovery have time to stop routing requests to them.
+type Deregisterer interface {
+	// IsRegistered returns whether the server is registered
+	IsRegistered() bool
+	// Deregister the server, it is idempotent
+	Deregister(ctx context.Context) error
+}
+
 // Close app close
 type Close func() error
 
@@ -27,13 +39,18 @@ type Close func() error
 type App struct {
 	servers []IServer
 	closes  []Close
+	opts    *options
 }
 
 // New create an app
-func New(servers []IServer, closes []Close) *App {
+func New(servers []IServer, closes []Close, opts ...Option) *App {
+	o := defaultOptions()
+	o.apply(opts...)
+
 	return &App{
 		servers: servers,
 		closes:  closes,
+		opts:    o,
 	}
 }
 
@@ -89,12 +106,69 @@ func (a *App) watch(ctx context.Context) error {
 	}
 }
 
-// stopping services and releasing resources
+// stopping services and releasing resources, the shutdown phases are in order:
+// deregister the servers from the registry, wait for the drain period, stop the servers and release resources.
 func (a *App) stop() error {
+	if a.deregister() && a.opts.drainPeriod > 0 {
+		a.drain()
+	}
+
+	start := time.Now()
 	for _, closeFn := range a.closes {
 		if err := closeFn(); err != nil {
+			a.observe(PhaseStop, start, err)
 			return err
 		}
 	}
+	a.observe(PhaseStop, start, nil)
 	return nil
 }
+
+// deregister the registered servers, returns true if any server is deregistered
+func (a *App) deregister() bool {
+	start := time.Now()
+	deregistered := false
+	var lastErr error
+	for _, server := range a.servers {
+		d, ok := server.(Deregisterer)
+		if !ok || !d.IsRegistered() {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), a.opts.deregisterTimeout)
+		err := d.Deregister(ctx)
+		cancel()
+		if err != nil {
+			// the server is stopped anyway, the registry removes it when the heartbeat expires
+			logger.Warn("deregister server error", logger.String("server", server.String()), logger.Err(err))
+			lastErr = err
+			continue
+		}
+		deregistered = true
+	}
+
+	if deregistered || lastErr != nil {
+		a.observe(PhaseDeregister, start, lastErr)
+	}
+	return deregistered
+}
+
+// wait for the in-flight requests routed by the stale discovery caches
+func (a *App) drain() {
+	logger.Info("draining before stopping servers", logger.String("drainPeriod", a.opts.drainPeriod.String()))
+	start := time.Now()
+	<-a.opts.after(a.opts.drainPeriod)
+	a.observe(PhaseDrain, start, nil)
+}
+
+func (a *App) observe(phase string, start time.Time, err error) {
+	elapsed := time.Since(start)
+	if err != nil {
+		logger.Warn("shutdown phase error", logger.String("phase", phase), logger.String("elapsed", elapsed.String()), logger.Err(err))
+	} else {
+		logger.Info("shutdown phase done", logger.String("phase", phase), logger.String("elapsed", elapsed.String()))
+	}
+	if a.opts.observer != nil {
+		a.opts.observer(phase, elapsed, err)
+	}
+}
@@ -105,3 +105,105 @@ func TestAppError(t *testing.T) {
 	time.Sleep(time.Second)
 	t.Log(a.stop())
 }
+
+type registeredServer struct {
+	name       string
+	registered bool
+	events     *[]string
+	err        error
+}
+
+func (s *registeredServer) Start() error   { return nil }
+func (s *registeredServer) String() string { return s.name }
+
+func (s *registeredServer) Stop() error {
+	*s.events = append(*s.events, "stop "+s.name)
+	return nil
+}
+
+func (s *registeredServer) IsRegistered() bool { return s.registered }
+
+func (s *registeredServer) Deregister(ctx context.Context) error {
+	if !s.registered {
+		return nil
+	}
+	*s.events = append(*s.events, "deregister "+s.name)
+	if s.err != nil {
+		return s.err
+	}
+	s.registered = false
+	return nil
+}
+
+// fakeClock records the waiting periods and fires immediately
+type fakeClock struct {
+	events *[]string
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	*c.events = append(*c.events, "drain "+d.String())
+	ch := make(chan time.Time, 1)
+	ch <- time.Now().Add(d)
+	return ch
+}
+
+func newDrainApp(events *[]string, servers []IServer, opts ...Option) *App {
+	var closes []Close
+	for _, s := range servers {
+		closes = append(closes, s.Stop)
+	}
+	a := New(servers, closes, append(opts, WithShutdownObserver(func(phase string, elapsed time.Duration, err error) {
+		*events = append(*events, "phase "+phase)
+	}))...)
+	a.opts.after = (&fakeClock{events: events}).After
+	return a
+}
+
+func TestAppDrain(t *testing.T) {
+	var events []string
+	s1 := &registeredServer{name: "http", registered: true, events: &events}
+	s2 := &registeredServer{name: "grpc", registered: true, events: &events}
+	a := newDrainApp(&events, []IServer{s1, s2, &httpServer2{}})
+
+	assert.NoError(t, a.stop())
+	assert.Equal(t, []string{
+		"deregister http", "deregister grpc", "phase deregister",
+		"drain 5s", "phase drain",
+		"stop http", "stop grpc", "phase stop",
+	}, events)
+
+	// idempotent, no deregistration and drain the second time
+	events = nil
+	assert.NoError(t, a.stop())
+	assert.Equal(t, []string{"stop http", "stop grpc", "phase stop"}, events)
+}
+
+func TestAppDrainOptions(t *testing.T) {
+	// never registered, no drain
+	var events []string
+	s := &registeredServer{name: "http", events: &events}
+	a := newDrainApp(&events, []IServer{s})
+	assert.NoError(t, a.stop())
+	assert.Equal(t, []string{"stop http", "phase stop"}, events)
+
+	// custom drain period
+	events = nil
+	s = &registeredServer{name: "http", registered: true, events: &events}
+	a = newDrainApp(&events, []IServer{s}, WithDrainPeriod(10*time.Second), WithDeregisterTimeout(time.Second))
+	assert.NoError(t, a.stop())
+	assert.Equal(t, []string{"deregister http", "phase deregister", "drain 10s", "phase drain", "stop http", "phase stop"}, events)
+
+	// drain disabled
+	events = nil
+	s = &registeredServer{name: "http", registered: true, events: &events}
+	a = newDrainApp(&events, []IServer{s}, WithDrainPeriod(0))
+	assert.NoError(t, a.stop())
+	assert.Equal(t, []string{"deregister http", "phase deregister", "stop http", "phase stop"}, events)
+
+	// deregistration error, the server is stopped without draining
+	events = nil
+	s = &registeredServer{name: "http", registered: true, events: &events, err: errors.New("registry unavailable")}
+	a = newDrainApp(&events, []IServer{s})
+	assert.NoError(t, a.stop())
+	assert.Equal(t, []string{"deregister http", "phase deregister", "stop http", "phase stop"}, events)
+}
@@ -0,0 +1,179 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// the phases of shutdown, in order
+const (
+	PhaseDeregister = "deregister"
+	PhaseDrain      = "drain"
+	PhaseStop       = "stop"
+)
+
+// Option set the app options.
+type Option func(*options)
+
+type options struct {
+	drainPeriod       time.Duration
+	deregisterTimeout time.Duration
+	observer          func(phase string, elapsed time.Duration, err error)
+
+	// the options of Runner
+	entries         []entry
+	closes          []Close
+	startTimeout    time.Duration
+	stopTimeout     time.Duration
+	serverStartWait time.Duration
+	beforeStart     []func() error
+	afterStop       []func(err error)
+
+	after  func(d time.Duration) <-chan time.Time // clock, replaced in tests
+	notify func(c chan<- os.Signal)               // system signals, replaced in tests
+}
+
+func (o *options) apply(opts ...Option) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+func defaultOptions() *options {
+	return &options{
+		drainPeriod:       5 * time.Second,
+		deregisterTimeout: 3 * time.Second,
+		startTimeout:      10 * time.Second,
+		stopTimeout:       10 * time.Second,
+		serverStartWait:   200 * time.Millisecond,
+		after:             time.After,
+		notify: func(c chan<- os.Signal) {
+			signal.Notify(c, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGTRAP)
+		},
+	}
+}
+
+// WithDrainPeriod set the period to wait after the servers are deregistered and before they are stopped,
+// default 5s, 0 means no waiting. It only takes effect if any server is registered, see Deregisterer.
+func WithDrainPeriod(d time.Duration) Option {
+	return func(o *options) {
+		if d >= 0 {
+			o.drainPeriod = d
+		}
+	}
+}
+
+// WithDeregisterTimeout set the timeout of deregistering each server, default 3s
+func WithDeregisterTimeout(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.deregisterTimeout = d
+		}
+	}
+}
+
+// WithShutdownObserver set the function called when each shutdown phase is finished, e.g. recording metrics
+func WithShutdownObserver(fn func(phase string, elapsed time.Duration, err error)) Option {
+	return func(o *options) {
+		o.observer = fn
+	}
+}
+
+// WithServers add the servers to the Runner in order, the blocking Start of the server is considered
+// successful if it does not return an error in the wait time, see WithServerStartWait.
+func WithServers(servers ...IServer) Option {
+	return func(o *options) {
+		for _, s := range servers {
+			o.entries = append(o.entries, entry{kind: KindServer, component: &serverComponent{IServer: s}})
+		}
+	}
+}
+
+// WithRegistrars add the registrars to the Runner in order, they are usually declared after the servers,
+// and they are deregistered first on shutdown, then the app waits for the drain period.
+func WithRegistrars(registrars ...Registrar) Option {
+	return func(o *options) {
+		for _, r := range registrars {
+			name := fmt.Sprintf("registrar-%d", len(o.entries))
+			if s, ok := r.(fmt.Stringer); ok {
+				name = s.String()
+			}
+			o.entries = append(o.entries, entry{kind: KindRegistrar, component: &registrarComponent{Registrar: r, name: name}})
+		}
+	}
+}
+
+// WithWorkers add the background workers to the Runner in order, the context of Run is canceled on shutdown.
+func WithWorkers(workers ...Worker) Option {
+	return func(o *options) {
+		for _, w := range workers {
+			o.entries = append(o.entries, entry{kind: KindWorker, component: &workerComponent{Worker: w}})
+		}
+	}
+}
+
+// WithComponents add the custom components to the Runner in order
+func WithComponents(components ...Component) Option {
+	return func(o *options) {
+		for _, c := range components {
+			o.entries = append(o.entries, entry{kind: KindComponent, component: c})
+		}
+	}
+}
+
+// WithCloses add the functions to release resources after the components of the Runner are stopped
+func WithCloses(closes ...Close) Option {
+	return func(o *options) {
+		o.closes = append(o.closes, closes...)
+	}
+}
+
+// WithStartTimeout set the timeout of starting each component of the Runner, default 10s
+func WithStartTimeout(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.startTimeout = d
+		}
+	}
+}
+
+// WithStopTimeout set the timeout of stopping each component of the Runner, default 10s
+func WithStopTimeout(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.stopTimeout = d
+		}
+	}
+}
+
+// WithServerStartWait set the time to wait for the server to return a start error, e.g. the port is in use,
+// default 200ms.
+func WithServerStartWait(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.serverStartWait = d
+		}
+	}
+}
+
+// WithBeforeStart add the hook called before the components of the Runner are started,
+// the Runner is not started if it returns an error.
+func WithBeforeStart(fn func() error) Option {
+	return func(o *options) {
+		if fn != nil {
+			o.beforeStart = append(o.beforeStart, fn)
+		}
+	}
+}
+
+// WithAfterStop add the hook called after the components of the Runner are stopped, err is the error of stopping.
+func WithAfterStop(fn func(err error)) Option {
+	return func(o *options) {
+		if fn != nil {
+			o.afterStop = append(o.afterStop, fn)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-dev-frame/sponge/pkg/logger"
+	"github.com/go-dev-frame/sponge/pkg/prof"
+)
+
+// Component is started and stopped by the Runner in the declared order.
+type Component interface {
+	// Start the component, it returns after the component is started or ctx is done.
+	Start(ctx context.Context) error
+	// Stop the component, it returns after the component is stopped or ctx is done.
+	Stop(ctx context.Context) error
+	String() string
+}
+
+// Registrar register the service to the registry, e.g. registry.Registration.
+type Registrar interface {
+	Register(ctx context.Context) error
+	Deregister(ctx context.Context) error
+}
+
+// Worker is a background worker, e.g. a message consumer, Run blocks until ctx is canceled.
+type Worker interface {
+	Run(ctx context.Context) error
+	String() string
+}
+
+type workerFunc struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+func (w *workerFunc) Run(ctx context.Context) error { return w.run(ctx) }
+func (w *workerFunc) String() string                { return w.name }
+
+// NewWorker create a worker by the function
+func NewWorker(name string, run func(ctx context.Context) error) Worker {
+	return &workerFunc{name: name, run: run}
+}
+
+// the kinds of the components
+const (
+	KindServer    = "server"
+	KindRegistrar = "registrar"
+	KindWorker    = "worker"
+	KindComponent = "component"
+)
+
+type entry struct {
+	kind      string
+	component Component
+}
+
+// Runner start the servers, registrars and workers in the declared order, if any of them fails to start,
+// the started ones are stopped in reverse order. After started, it blocks until a system signal is received
+// or a server or worker exits with error, and then stops the components in reverse order.
+type Runner struct {
+	opts *options
+
+	failCh chan error // the runtime errors of the servers and workers
+	stopCh chan struct{}
+	once   sync.Once
+}
+
+// NewRunner create a runner, the components are declared by WithServers, WithRegistrars, WithWorkers and WithComponents.
+func NewRunner(opts ...Option) *Runner {
+	o := defaultOptions()
+	o.apply(opts...)
+
+	r := &Runner{
+		opts:   o,
+		failCh: make(chan error, len(o.entries)),
+		stopCh: make(chan struct{}),
+	}
+	for _, e := range o.entries {
+		switch c := e.component.(type) {
+		case *serverComponent:
+			c.failCh, c.wait = r.failCh, o.serverStartWait
+		case *workerComponent:
+			c.failCh = r.failCh
+		}
+	}
+	return r
+}
+
+// Run start the components and block until shutdown, returns the error of starting or stopping.
+func (r *Runner) Run() error {
+	for _, fn := range r.opts.beforeStart {
+		if err := fn(); err != nil {
+			return fmt.Errorf("before start error: %w", err)
+		}
+	}
+
+	sig := make(chan os.Signal, 1)
+	r.opts.notify(sig)
+	defer signal.Stop(sig)
+
+	started, err := r.start()
+	if err != nil {
+		logger.Warn("rolling back the started components", logger.Int("count", len(started)))
+		return errors.Join(err, r.shutdown(started, false))
+	}
+
+	profile := prof.NewProfile()
+	for {
+		select {
+		case err = <-r.failCh: // a server or worker exits with error
+			logger.Error("component exited, stopping app", logger.Err(err))
+			return errors.Join(err, r.shutdown(started, true))
+
+		case <-r.stopCh:
+			return r.shutdown(started, true)
+
+		case sigType := <-sig:
+			logger.Info("received system notification signal", logger.String("signal", sigType.String()))
+			if sigType == syscall.SIGTRAP {
+				profile.StartOrStop() // start or stop sampling profile
+				continue
+			}
+			return r.shutdown(started, true)
+		}
+	}
+}
+
+// Stop the running runner, it is idempotent
+func (r *Runner) Stop() {
+	r.once.Do(func() { close(r.stopCh) })
+}
+
+func (r *Runner) start() ([]entry, error) {
+	var started []entry
+	begin := time.Now()
+	for _, e := range r.opts.entries {
+		start := time.Now()
+		ctx, cancel := context.WithTimeout(context.Background(), r.opts.startTimeout)
+		err := e.component.Start(ctx)
+		cancel()
+		if err != nil {
+			logger.Error("start component error", logger.String("kind", e.kind), logger.String("name", e.component.String()),
+				logger.String("elapsed", time.Since(start).String()), logger.Err(err))
+			return started, fmt.Errorf("start %s %s error: %w", e.kind, e.component.String(), err)
+		}
+		logger.Info("component started", logger.String("kind", e.kind), logger.String("name", e.component.String()),
+			logger.String("elapsed", time.Since(start).String()))
+		started = append(started, e)
+	}
+	logger.Info("all components started", logger.Int("count", len(started)), logger.String("elapsed", time.Since(begin).String()))
+	return started, nil
+}
+
+// shutdown in order: deregister the registrars, wait for the drain period, stop the other components
+// in reverse order, release resources and call the after stop hooks, there is no draining when rolling back.
+func (r *Runner) shutdown(started []entry, drain bool) error {
+	var errs []error
+
+	var deregistered bool
+	start := time.Now()
+	for i := len(started) - 1; i >= 0; i-- {
+		if started[i].kind == KindRegistrar {
+			err := r.stopComponent(started[i])
+			errs = append(errs, err)
+			deregistered = deregistered || err == nil
+		}
+	}
+	if deregistered {
+		r.observe(PhaseDeregister, start, nil)
+		if drain && r.opts.drainPeriod > 0 {
+			logger.Info("draining before stopping servers", logger.String("drainPeriod", r.opts.drainPeriod.String()))
+			start = time.Now()
+			<-r.opts.after(r.opts.drainPeriod)
+			r.observe(PhaseDrain, start, nil)
+		}
+	}
+
+	start = time.Now()
+	for i := len(started) - 1; i >= 0; i-- {
+		if started[i].kind != KindRegistrar {
+			errs = append(errs, r.stopComponent(started[i]))
+		}
+	}
+	for _, closeFn := range r.opts.closes {
+		errs = append(errs, closeFn())
+	}
+	err := errors.Join(errs...)
+	r.observe(PhaseStop, start, err)
+
+	for _, fn := range r.opts.afterStop {
+		fn(err)
+	}
+	return err
+}
+
+func (r *Runner) stopComponent(e entry) error {
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), r.opts.stopTimeout)
+	defer cancel()
+	if err := e.component.Stop(ctx); err != nil {
+		logger.Warn("stop component error", logger.String("kind", e.kind), logger.String("name", e.component.String()),
+			logger.String("elapsed", time.Since(start).String()), logger.Err(err))
+		return fmt.Errorf("stop %s %s error: %w", e.kind, e.component.String(), err)
+	}
+	logger.Info("component stopped", logger.String("kind", e.kind), logger.String("name", e.component.String()),
+		logger.String("elapsed", time.Since(start).String()))
+	return nil
+}
+
+func (r *Runner) observe(phase string, start time.Time, err error) {
+	if r.opts.observer != nil {
+		r.opts.observer(phase, time.Since(start), err)
+	}
+}
+
+// serverComponent adapt the blocking IServer, the server is considered started if Start does not return
+// an error in the wait time.
+type serverComponent struct {
+	IServer
+	wait   time.Duration
+	failCh chan<- error
+}
+
+func (s *serverComponent) Start(ctx context.Context) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.IServer.Start()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		_ = s.IServer.Stop() // the server may be started later
+		return ctx.Err()
+	case <-time.After(s.wait):
+	}
+
+	go func() {
+		if err := <-errCh; err != nil {
+			s.failCh <- fmt.Errorf("server %s exited: %w", s.String(), err)
+		}
+	}()
+	return nil
+}
+
+func (s *serverComponent) Stop(ctx context.Context) error {
+	return runWithContext(ctx, s.IServer.Stop)
+}
+
+type registrarComponent struct {
+	Registrar
+	name string
+}
+
+func (r *registrarComponent) Start(ctx context.Context) error { return r.Register(ctx) }
+func (r *registrarComponent) Stop(ctx context.Context) error  { return r.Deregister(ctx) }
+func (r *registrarComponent) String() string                  { return r.name }
+
+type workerComponent struct {
+	Worker
+	failCh chan<- error
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func (w *workerComponent) Start(_ context.Context) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	w.done = make(chan struct{})
+	go func() {
+		defer close(w.done)
+		if err := w.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			w.failCh <- fmt.Errorf("worker %s exited: %w", w.String(), err)
+		}
+	}()
+	return nil
+}
+
+func (w *workerComponent) Stop(ctx context.Context) error {
+	w.cancel()
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func runWithContext(ctx context.Context, fn func() error) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- fn()
+	}()
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
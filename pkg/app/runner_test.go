@@ -0,0 +1,248 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type eventLog struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (l *eventLog) add(event string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, event)
+}
+
+func (l *eventLog) get() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string{}, l.events...)
+}
+
+// blockingServer blocks in Start until it is stopped, like http.Server.ListenAndServe
+type blockingServer struct {
+	name     string
+	log      *eventLog
+	startErr error
+
+	once   sync.Once
+	stopCh chan struct{}
+}
+
+func newBlockingServer(name string, log *eventLog, startErr error) *blockingServer {
+	return &blockingServer{name: name, log: log, startErr: startErr, stopCh: make(chan struct{})}
+}
+
+func (s *blockingServer) Start() error {
+	s.log.add("start " + s.name)
+	if s.startErr != nil {
+		return s.startErr
+	}
+	<-s.stopCh
+	return nil
+}
+
+func (s *blockingServer) Stop() error {
+	s.log.add("stop " + s.name)
+	s.once.Do(func() { close(s.stopCh) })
+	return nil
+}
+
+func (s *blockingServer) String() string { return s.name }
+
+type testRegistrar struct {
+	log *eventLog
+}
+
+func (r *testRegistrar) Register(ctx context.Context) error {
+	r.log.add("register")
+	return nil
+}
+
+func (r *testRegistrar) Deregister(ctx context.Context) error {
+	r.log.add("deregister")
+	return nil
+}
+
+func newTestWorker(name string, log *eventLog) Worker {
+	return NewWorker(name, func(ctx context.Context) error {
+		log.add("run " + name)
+		<-ctx.Done()
+		log.add("exit " + name)
+		return ctx.Err()
+	})
+}
+
+func newTestRunner(log *eventLog, sig os.Signal, opts ...Option) *Runner {
+	opts = append(opts,
+		WithServerStartWait(50*time.Millisecond),
+		WithBeforeStart(func() error {
+			log.add("before start")
+			return nil
+		}),
+		WithAfterStop(func(err error) {
+			log.add("after stop")
+		}),
+		WithCloses(func() error {
+			log.add("close")
+			return nil
+		}),
+		WithShutdownObserver(func(phase string, elapsed time.Duration, err error) {
+			log.add("phase " + phase)
+		}),
+	)
+	r := NewRunner(opts...)
+	r.opts.after = (&fakeClock{events: &[]string{}}).After
+	r.opts.notify = func(c chan<- os.Signal) {
+		if sig != nil {
+			c <- sig // received after all components are started
+		}
+	}
+	return r
+}
+
+func TestRunner_Rollback(t *testing.T) {
+	log := &eventLog{}
+	r := newTestRunner(log, nil,
+		WithServers(newBlockingServer("http", log, nil)),
+		WithRegistrars(&testRegistrar{log: log}),
+		WithServers(newBlockingServer("grpc", log, errors.New("address already in use"))),
+		WithWorkers(newTestWorker("consumer", log)),
+	)
+
+	err := r.Run()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "start server grpc error: address already in use")
+	assert.Equal(t, []string{
+		"before start",
+		"start http", "register", "start grpc",
+		"deregister", "phase deregister", // no draining when rolling back
+		"stop http", "close", "phase stop",
+		"after stop",
+	}, log.get())
+}
+
+func TestRunner_SignalShutdown(t *testing.T) {
+	log := &eventLog{}
+	r := newTestRunner(log, syscall.SIGTERM,
+		WithServers(newBlockingServer("http", log, nil), newBlockingServer("grpc", log, nil)),
+		WithRegistrars(&testRegistrar{log: log}),
+		WithWorkers(newTestWorker("consumer", log)),
+		WithDrainPeriod(3*time.Second),
+	)
+
+	assert.NoError(t, r.Run())
+	events := log.get()
+	// the worker starts running in the background
+	assert.Contains(t, events, "run consumer")
+	events = removeEvent(events, "run consumer")
+	assert.Equal(t, []string{
+		"before start",
+		"start http", "start grpc", "register",
+		"deregister", "phase deregister", "phase drain",
+		"exit consumer", "stop grpc", "stop http", "close", "phase stop",
+		"after stop",
+	}, events)
+}
+
+func TestRunner_ComponentExited(t *testing.T) {
+	log := &eventLog{}
+	r := newTestRunner(log, nil,
+		WithServers(newBlockingServer("http", log, nil)),
+		WithWorkers(NewWorker("consumer", func(ctx context.Context) error {
+			time.Sleep(100 * time.Millisecond)
+			return errors.New("connection lost")
+		})),
+	)
+
+	err := r.Run()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "worker consumer exited: connection lost")
+	assert.Equal(t, []string{"before start", "start http", "stop http", "close", "phase stop", "after stop"}, log.get())
+}
+
+type slowComponent struct {
+	startDelay time.Duration
+	stopDelay  time.Duration
+}
+
+func (c *slowComponent) Start(ctx context.Context) error {
+	select {
+	case <-time.After(c.startDelay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *slowComponent) Stop(ctx context.Context) error {
+	select {
+	case <-time.After(c.stopDelay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *slowComponent) String() string { return "slow" }
+
+func TestRunner_Timeout(t *testing.T) {
+	log := &eventLog{}
+	r := newTestRunner(log, nil,
+		WithServers(newBlockingServer("http", log, nil)),
+		WithComponents(&slowComponent{startDelay: time.Second}),
+		WithStartTimeout(100*time.Millisecond),
+	)
+	err := r.Run()
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, log.get(), "stop http")
+
+	// stop timeout
+	log = &eventLog{}
+	r = newTestRunner(log, nil,
+		WithComponents(&slowComponent{stopDelay: time.Second}),
+		WithStopTimeout(100*time.Millisecond),
+	)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		r.Stop()
+		r.Stop() // idempotent
+	}()
+	err = r.Run()
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "stop component slow error")
+}
+
+func TestRunner_BeforeStartError(t *testing.T) {
+	log := &eventLog{}
+	r := NewRunner(
+		WithServers(newBlockingServer("http", log, nil)),
+		WithBeforeStart(func() error { return errors.New("config invalid") }),
+		WithBeforeStart(nil),
+		WithAfterStop(nil),
+	)
+	err := r.Run()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "config invalid")
+	assert.Empty(t, log.get())
+}
+
+func removeEvent(events []string, event string) []string {
+	var out []string
+	for _, e := range events {
+		if e != event {
+			out = append(out, e)
+		}
+	}
+	return out
+}
@@ -0,0 +1,161 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Invalidator delete the cache keys of the written records and bump the list generation of the resource atomically,
+// the list generation is a part of the list cache keys (see ListCacheKey), so the cached lists are never served
+// after a write, the stale lists are expired by ttl.
+type Invalidator interface {
+	// Invalidate delete the keys and bump the list generation of the resource, keys may be empty,
+	// e.g. a created record only changes the lists.
+	Invalidate(ctx context.Context, resource string, keys ...string) error
+	// Generation get the current list generation of the resource.
+	Generation(ctx context.Context, resource string) (int64, error)
+	// LastModified get the time of the last Invalidate of the resource, zero time if it has never been invalidated.
+	LastModified(ctx context.Context, resource string) (time.Time, error)
+}
+
+// ListCacheKey the cache key of a list of the resource, params identifies the list, e.g. the query conditions.
+func ListCacheKey(resource string, generation int64, params string) string {
+	return resource + ":list:" + strconv.FormatInt(generation, 10) + ":" + params
+}
+
+func generationKey(resource string) string {
+	return resource + ":list:generation"
+}
+
+func lastModifiedKey(resource string) string {
+	return resource + ":list:lastModified"
+}
+
+// KEYS[1] is the generation key, KEYS[2] is the last modified key, the other keys are deleted,
+// ARGV[1] is the last modified time in unix milliseconds
+var invalidateScript = redis.NewScript(`
+for i = 3, #KEYS do
+	redis.call('DEL', KEYS[i])
+end
+redis.call('SET', KEYS[2], ARGV[1])
+return redis.call('INCR', KEYS[1])
+`)
+
+type redisInvalidator struct {
+	client    redis.UniversalClient
+	keyPrefix string
+}
+
+// NewRedisInvalidator create a redis invalidator, keyPrefix must be the same as the key prefix of the cache,
+// the keys and the generation are changed by a lua script, for redis cluster, the resource and the keys
+// must be in the same hash slot, e.g. use hash tags.
+func NewRedisInvalidator(client redis.UniversalClient, keyPrefix string) Invalidator {
+	return &redisInvalidator{client: client, keyPrefix: keyPrefix}
+}
+
+// Invalidate delete the keys and bump the list generation of the resource in one lua script
+func (r *redisInvalidator) Invalidate(ctx context.Context, resource string, keys ...string) error {
+	cacheKeys := make([]string, 0, len(keys)+2)
+	genKey, err := BuildCacheKey(r.keyPrefix, generationKey(resource))
+	if err != nil {
+		return err
+	}
+	lmKey, err := BuildCacheKey(r.keyPrefix, lastModifiedKey(resource))
+	if err != nil {
+		return err
+	}
+	cacheKeys = append(cacheKeys, genKey, lmKey)
+	for _, key := range keys {
+		cacheKey, err := BuildCacheKey(r.keyPrefix, key)
+		if err != nil {
+			return fmt.Errorf("BuildCacheKey error: %v, key=%s", err, key)
+		}
+		cacheKeys = append(cacheKeys, cacheKey)
+	}
+
+	err = invalidateScript.Run(ctx, r.client, cacheKeys, time.Now().UnixMilli()).Err()
+	if err != nil {
+		return fmt.Errorf("invalidate error: %v, keys=%+v", err, cacheKeys)
+	}
+	return nil
+}
+
+// Generation get the list generation of the resource, 0 if it has never been invalidated
+func (r *redisInvalidator) Generation(ctx context.Context, resource string) (int64, error) {
+	genKey, err := BuildCacheKey(r.keyPrefix, generationKey(resource))
+	if err != nil {
+		return 0, err
+	}
+	gen, err := r.client.Get(ctx, genKey).Int64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return gen, nil
+}
+
+// LastModified get the time of the last Invalidate of the resource, zero time if it has never been invalidated
+func (r *redisInvalidator) LastModified(ctx context.Context, resource string) (time.Time, error) {
+	lmKey, err := BuildCacheKey(r.keyPrefix, lastModifiedKey(resource))
+	if err != nil {
+		return time.Time{}, err
+	}
+	ms, err := r.client.Get(ctx, lmKey).Int64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	return time.UnixMilli(ms), nil
+}
+
+type memoryInvalidator struct {
+	mu            sync.RWMutex
+	cache         Cache
+	generations   map[string]int64
+	lastModifieds map[string]time.Time
+}
+
+// NewMemoryInvalidator create an invalidator of the in-process cache, the generations are kept in memory.
+func NewMemoryInvalidator(c Cache) Invalidator {
+	return &memoryInvalidator{
+		cache:         c,
+		generations:   make(map[string]int64),
+		lastModifieds: make(map[string]time.Time),
+	}
+}
+
+// Invalidate delete the keys and bump the list generation of the resource while holding the lock
+func (m *memoryInvalidator) Invalidate(ctx context.Context, resource string, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.generations[resource]++
+	m.lastModifieds[resource] = time.Now()
+	if len(keys) == 0 {
+		return nil
+	}
+	return m.cache.Del(ctx, keys...)
+}
+
+// Generation get the list generation of the resource
+func (m *memoryInvalidator) Generation(_ context.Context, resource string) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.generations[resource], nil
+}
+
+// LastModified get the time of the last Invalidate of the resource
+func (m *memoryInvalidator) LastModified(_ context.Context, resource string) (time.Time, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.lastModifieds[resource], nil
+}
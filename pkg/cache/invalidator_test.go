@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-dev-frame/sponge/pkg/encoding"
+)
+
+func TestListCacheKey(t *testing.T) {
+	assert.Equal(t, "user:list:3:page=1", ListCacheKey("user", 3, "page=1"))
+}
+
+func TestRedisInvalidator(t *testing.T) {
+	s := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	c := NewRedisCache(client, "test", encoding.JSONEncoding{}, func() interface{} { return &redisUser{} })
+	inv := NewRedisInvalidator(client, "test")
+	ctx := context.Background()
+
+	gen, err := inv.Generation(ctx, "user")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), gen)
+
+	listKey := ListCacheKey("user", gen, "page=1")
+	assert.NoError(t, c.Set(ctx, "user:1", &redisUser{ID: 1}, time.Hour))
+	assert.NoError(t, c.Set(ctx, "user:2", &redisUser{ID: 2}, time.Hour))
+	assert.NoError(t, c.Set(ctx, listKey, &redisUser{ID: 1}, time.Hour))
+
+	// the per-id keys are deleted and the generation is bumped
+	assert.NoError(t, inv.Invalidate(ctx, "user", "user:1", "user:2"))
+	assert.False(t, s.Exists("test:user:1"))
+	assert.False(t, s.Exists("test:user:2"))
+	gen, err = inv.Generation(ctx, "user")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), gen)
+	assert.NotEqual(t, listKey, ListCacheKey("user", gen, "page=1"))
+
+	// only the generation
+	assert.NoError(t, inv.Invalidate(ctx, "user"))
+	gen, _ = inv.Generation(ctx, "user")
+	assert.Equal(t, int64(2), gen)
+
+	// the last modified time is set by every invalidate
+	lm, err := inv.LastModified(ctx, "user")
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), lm, time.Second)
+
+	// the other resources are not changed
+	gen, _ = inv.Generation(ctx, "order")
+	assert.Equal(t, int64(0), gen)
+	lm, err = inv.LastModified(ctx, "order")
+	assert.NoError(t, err)
+	assert.True(t, lm.IsZero())
+
+	s.SetError("server error")
+	assert.Error(t, inv.Invalidate(ctx, "user", "user:1"))
+	_, err = inv.Generation(ctx, "user")
+	assert.Error(t, err)
+	_, err = inv.LastModified(ctx, "user")
+	assert.Error(t, err)
+}
+
+func TestMemoryInvalidator(t *testing.T) {
+	c := NewMemoryCache("test", encoding.JSONEncoding{}, func() interface{} { return &memoryUser{} })
+	inv := NewMemoryInvalidator(c)
+	ctx := context.Background()
+
+	assert.NoError(t, c.Set(ctx, "user:1", &memoryUser{ID: 1}, time.Hour))
+	time.Sleep(10 * time.Millisecond)
+
+	assert.NoError(t, inv.Invalidate(ctx, "user", "user:1"))
+	err := c.Get(ctx, "user:1", &memoryUser{})
+	assert.Error(t, err)
+	gen, err := inv.Generation(ctx, "user")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), gen)
+
+	assert.NoError(t, inv.Invalidate(ctx, "user"))
+	gen, _ = inv.Generation(ctx, "user")
+	assert.Equal(t, int64(2), gen)
+
+	lm, err := inv.LastModified(ctx, "user")
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), lm, time.Second)
+	lm, _ = inv.LastModified(ctx, "order")
+	assert.True(t, lm.IsZero())
+}
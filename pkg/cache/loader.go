@@ -0,0 +1,181 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/go-dev-frame/sponge/pkg/logger"
+)
+
+// ErrNotFound is the default error of the loader when the record is not found, the result is cached as placeholder.
+var ErrNotFound = errors.New("cache: not found")
+
+// the results of GetOrLoad
+const (
+	LoadResultHit         = "hit"
+	LoadResultMiss        = "miss"
+	LoadResultNegativeHit = "negative_hit"
+	LoadResultError       = "error" // failed to read the cache
+)
+
+// LoadCounter counts the results of GetOrLoad by name and result, register it to expose the metrics,
+// e.g. prometheus.MustRegister(cache.LoadCounter)
+var LoadCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "cache",
+		Name:      "load_total",
+		Help:      "Total number of the cache reads of GetOrLoad by result.",
+	}, []string{"name", "result"},
+)
+
+// the singleflight group of each cache
+var loadGroups sync.Map
+
+// LoadOption set the GetOrLoad options.
+type LoadOption func(*loadOptions)
+
+type loadOptions struct {
+	cache       Cache
+	name        string
+	notFoundErr error
+	notFoundTTL time.Duration
+	jitter      float64
+}
+
+func defaultLoadOptions() *loadOptions {
+	return &loadOptions{
+		name:        "default",
+		notFoundErr: ErrNotFound,
+		notFoundTTL: time.Minute,
+		jitter:      0.1,
+	}
+}
+
+func (o *loadOptions) apply(opts ...LoadOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithLoadCache set the cache, default DefaultClient.
+func WithLoadCache(c Cache) LoadOption {
+	return func(o *loadOptions) {
+		o.cache = c
+	}
+}
+
+// WithLoadName set the name label of the metrics, default "default".
+func WithLoadName(name string) LoadOption {
+	return func(o *loadOptions) {
+		if name != "" {
+			o.name = name
+		}
+	}
+}
+
+// WithNotFoundError set the error of the loader when the record is not found, e.g. gorm.ErrRecordNotFound,
+// the error is matched with errors.Is and returned when the placeholder is hit, default ErrNotFound.
+func WithNotFoundError(err error) LoadOption {
+	return func(o *loadOptions) {
+		if err != nil {
+			o.notFoundErr = err
+		}
+	}
+}
+
+// WithNotFoundTTL set the expiration of the not found placeholder, default 1 minute.
+func WithNotFoundTTL(ttl time.Duration) LoadOption {
+	return func(o *loadOptions) {
+		if ttl > 0 {
+			o.notFoundTTL = ttl
+		}
+	}
+}
+
+// WithTTLJitter set the ratio of the random extra expiration to avoid synchronized expiry, e.g. 0.1 means
+// the expiration is in [ttl, ttl*1.1), default 0.1, 0 means no jitter.
+func WithTTLJitter(ratio float64) LoadOption {
+	return func(o *loadOptions) {
+		if ratio >= 0 {
+			o.jitter = ratio
+		}
+	}
+}
+
+// GetOrLoad read the value from the cache, if not cached, the value is loaded by loader and cached with ttl,
+// the concurrent loads of the same key are merged into one, if the loader returns the not found error,
+// a placeholder is cached with a short ttl and the not found error is returned until it expires.
+func GetOrLoad[T any](ctx context.Context, key string, ttl time.Duration, loader func(ctx context.Context) (T, error), opts ...LoadOption) (T, error) {
+	o := defaultLoadOptions()
+	o.apply(opts...)
+	c := o.cache
+	if c == nil {
+		c = DefaultClient
+	}
+
+	var val T
+	if c == nil {
+		return val, errors.New("cache: no cache client")
+	}
+
+	err := c.Get(ctx, key, &val)
+	switch {
+	case err == nil:
+		LoadCounter.WithLabelValues(o.name, LoadResultHit).Inc()
+		return val, nil
+	case errors.Is(err, ErrPlaceholder):
+		LoadCounter.WithLabelValues(o.name, LoadResultNegativeHit).Inc()
+		return val, o.notFoundErr
+	case errors.Is(err, CacheNotFound):
+		LoadCounter.WithLabelValues(o.name, LoadResultMiss).Inc()
+	default:
+		// the cache is unavailable, load from the source
+		LoadCounter.WithLabelValues(o.name, LoadResultError).Inc()
+		logger.Warn("cache.Get error", logger.Err(err), logger.String("key", key))
+	}
+
+	group, _ := loadGroups.LoadOrStore(c, new(singleflight.Group))
+	v, err, _ := group.(*singleflight.Group).Do(key, func() (interface{}, error) {
+		value, err := loader(ctx)
+		if err != nil {
+			if errors.Is(err, o.notFoundErr) {
+				// set placeholder cache to prevent cache penetration
+				if err := setNotFound(ctx, c, key, o.withJitter(o.notFoundTTL)); err != nil {
+					logger.Warn("cache.SetCacheWithNotFound error", logger.Err(err), logger.String("key", key))
+				}
+			}
+			return nil, err
+		}
+		if err := c.Set(ctx, key, value, o.withJitter(ttl)); err != nil {
+			logger.Warn("cache.Set error", logger.Err(err), logger.String("key", key))
+		}
+		return value, nil
+	})
+	if err != nil {
+		return val, err
+	}
+	return v.(T), nil
+}
+
+func (o *loadOptions) withJitter(ttl time.Duration) time.Duration {
+	if o.jitter <= 0 || ttl <= 0 {
+		return ttl
+	}
+	return ttl + time.Duration(rand.Float64()*o.jitter*float64(ttl)) //nolint
+}
+
+// the caches of this package support the expiration of the placeholder
+func setNotFound(ctx context.Context, c Cache, key string, ttl time.Duration) error {
+	if s, ok := c.(interface {
+		setNotFound(ctx context.Context, key string, expiration time.Duration) error
+	}); ok {
+		return s.setNotFound(ctx, key, ttl)
+	}
+	return c.SetCacheWithNotFound(ctx, key)
+}
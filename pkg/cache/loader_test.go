@@ -0,0 +1,170 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-dev-frame/sponge/pkg/encoding"
+)
+
+func newLoadCache(t *testing.T) (Cache, *miniredis.Miniredis) {
+	s, err := miniredis.Run()
+	assert.NoError(t, err)
+	t.Cleanup(s.Close)
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	return NewRedisCache(client, "test", encoding.JSONEncoding{}, func() interface{} { return &redisUser{} }), s
+}
+
+func loadCount(name string, result string) int {
+	return int(testutil.ToFloat64(LoadCounter.WithLabelValues(name, result)))
+}
+
+// the increments of the counters since the call
+func loadCounter(name string) func(result string) int {
+	base := map[string]int{}
+	for _, result := range []string{LoadResultHit, LoadResultMiss, LoadResultNegativeHit, LoadResultError} {
+		base[result] = loadCount(name, result)
+	}
+	return func(result string) int {
+		return loadCount(name, result) - base[result]
+	}
+}
+
+func TestGetOrLoad(t *testing.T) {
+	c, s := newLoadCache(t)
+	ctx := context.Background()
+	counts := loadCounter("TestGetOrLoad")
+	opts := []LoadOption{WithLoadCache(c), WithLoadName("TestGetOrLoad"), WithTTLJitter(0.1)}
+
+	var calls int32
+	loader := func(ctx context.Context) (*redisUser, error) {
+		atomic.AddInt32(&calls, 1)
+		return &redisUser{ID: 1, Name: "foo"}, nil
+	}
+
+	user, err := GetOrLoad(ctx, "user:1", time.Minute, loader, opts...)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", user.Name)
+	assert.Equal(t, 1, counts(LoadResultMiss))
+
+	// cached with the jittered ttl
+	ttl := s.TTL("test:user:1")
+	assert.True(t, ttl >= time.Minute && ttl < 66*time.Second, ttl)
+
+	user, err = GetOrLoad(ctx, "user:1", time.Minute, loader, opts...)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", user.Name)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	assert.Equal(t, 1, counts(LoadResultHit))
+
+	// the loader error is returned and not cached
+	loadErr := errors.New("db unavailable")
+	_, err = GetOrLoad(ctx, "user:2", time.Minute, func(ctx context.Context) (*redisUser, error) {
+		return nil, loadErr
+	}, opts...)
+	assert.ErrorIs(t, err, loadErr)
+	assert.False(t, s.Exists("test:user:2"))
+
+	// the cache is unavailable, the value is loaded from the source
+	s.SetError("server error")
+	user, err = GetOrLoad(ctx, "user:1", time.Minute, loader, opts...)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", user.Name)
+	assert.Equal(t, 1, counts(LoadResultError))
+	s.SetError("")
+
+	// no cache client
+	defaultClient := DefaultClient
+	DefaultClient = nil
+	defer func() { DefaultClient = defaultClient }()
+	_, err = GetOrLoad(ctx, "user:1", time.Minute, loader)
+	assert.Error(t, err)
+}
+
+func TestGetOrLoadSingleflight(t *testing.T) {
+	c, _ := newLoadCache(t)
+	ctx := context.Background()
+	counts := loadCounter("TestGetOrLoadSingleflight")
+
+	var calls int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (*redisUser, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return &redisUser{ID: 1, Name: "foo"}, nil
+	}
+
+	const n = 50
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			user, err := GetOrLoad(ctx, "user:1", time.Minute, loader, WithLoadCache(c), WithLoadName("TestGetOrLoadSingleflight"))
+			assert.NoError(t, err)
+			assert.Equal(t, "foo", user.Name)
+		}()
+	}
+	// wait until all requests missed the cache and are waiting for the loader
+	assert.Eventually(t, func() bool {
+		return counts(LoadResultMiss) == n
+	}, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestGetOrLoadNotFound(t *testing.T) {
+	c, s := newLoadCache(t)
+	ctx := context.Background()
+	counts := loadCounter("TestGetOrLoadNotFound")
+	errRecordNotFound := errors.New("record not found")
+	opts := []LoadOption{WithLoadCache(c), WithLoadName("TestGetOrLoadNotFound"),
+		WithNotFoundError(errRecordNotFound), WithNotFoundTTL(10 * time.Second), WithTTLJitter(0)}
+
+	var calls int32
+	var found atomic.Bool
+	loader := func(ctx context.Context) (*redisUser, error) {
+		atomic.AddInt32(&calls, 1)
+		if found.Load() {
+			return &redisUser{ID: 1, Name: "foo"}, nil
+		}
+		return nil, errRecordNotFound
+	}
+
+	_, err := GetOrLoad(ctx, "user:1", time.Minute, loader, opts...)
+	assert.ErrorIs(t, err, errRecordNotFound)
+	assert.Equal(t, 10*time.Second, s.TTL("test:user:1"))
+
+	// the negative cache is hit, the loader is not called
+	found.Store(true)
+	_, err = GetOrLoad(ctx, "user:1", time.Minute, loader, opts...)
+	assert.ErrorIs(t, err, errRecordNotFound)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	assert.Equal(t, 1, counts(LoadResultNegativeHit))
+
+	// the negative cache expires
+	s.FastForward(11 * time.Second)
+	user, err := GetOrLoad(ctx, "user:1", time.Minute, loader, opts...)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", user.Name)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	// default not found error
+	_, err = GetOrLoad(ctx, "user:2", time.Minute, func(ctx context.Context) (*redisUser, error) {
+		return nil, ErrNotFound
+	}, WithLoadCache(c))
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.True(t, s.Exists("test:user:2"))
+}
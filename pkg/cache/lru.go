@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"container/list"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// lru is a sharded in-memory LRU cache of bytes with per-entry ttl, the total size of the keys and values
+// is bounded by maxBytes, each shard has a generation that is increased by every invalidation.
+type lru struct {
+	shards []*lruShard
+	now    func() time.Time
+}
+
+type lruShard struct {
+	mu       sync.Mutex
+	ll       *list.List
+	items    map[string]*list.Element
+	bytes    int
+	maxBytes int
+	gen      uint64
+}
+
+type lruEntry struct {
+	key      string
+	value    []byte
+	expireAt time.Time
+}
+
+func newLRU(shards int, maxBytes int) *lru {
+	if shards < 1 {
+		shards = 1
+	}
+	l := &lru{shards: make([]*lruShard, shards), now: time.Now}
+	for i := range l.shards {
+		l.shards[i] = &lruShard{
+			ll:       list.New(),
+			items:    make(map[string]*list.Element),
+			maxBytes: maxBytes / shards,
+		}
+	}
+	return l
+}
+
+func (l *lru) shard(key string) *lruShard {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return l.shards[h.Sum32()%uint32(len(l.shards))]
+}
+
+func (l *lru) get(key string) ([]byte, bool) {
+	s := l.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := e.Value.(*lruEntry)
+	if !l.now().Before(entry.expireAt) {
+		s.remove(e)
+		return nil, false
+	}
+	s.ll.MoveToFront(e)
+	return entry.value, true
+}
+
+// generation of the shard of the key, it is used by setIfGen
+func (l *lru) generation(key string) uint64 {
+	s := l.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.gen
+}
+
+func (l *lru) set(key string, value []byte, ttl time.Duration) bool {
+	s := l.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.set(key, value, l.now().Add(ttl))
+}
+
+// setIfGen set the value if the shard is not invalidated since gen was got, so that a value read before
+// an invalidation doesn't override the invalidation.
+func (l *lru) setIfGen(key string, value []byte, ttl time.Duration, gen uint64) bool {
+	s := l.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.gen != gen {
+		return false
+	}
+	return s.set(key, value, l.now().Add(ttl))
+}
+
+// invalidate remove the key and increase the generation.
+func (l *lru) invalidate(key string) {
+	s := l.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gen++
+	if e, ok := s.items[key]; ok {
+		s.remove(e)
+	}
+}
+
+// clear all entries
+func (l *lru) clear() {
+	for _, s := range l.shards {
+		s.mu.Lock()
+		s.gen++
+		s.ll.Init()
+		s.items = make(map[string]*list.Element)
+		s.bytes = 0
+		s.mu.Unlock()
+	}
+}
+
+func (l *lru) size() (n int, bytes int) {
+	for _, s := range l.shards {
+		s.mu.Lock()
+		n += s.ll.Len()
+		bytes += s.bytes
+		s.mu.Unlock()
+	}
+	return n, bytes
+}
+
+func (s *lruShard) set(key string, value []byte, expireAt time.Time) bool {
+	size := len(key) + len(value)
+	if size > s.maxBytes {
+		return false
+	}
+	if e, ok := s.items[key]; ok {
+		s.remove(e)
+	}
+	s.items[key] = s.ll.PushFront(&lruEntry{key: key, value: value, expireAt: expireAt})
+	s.bytes += size
+	for s.bytes > s.maxBytes {
+		s.remove(s.ll.Back())
+	}
+	return true
+}
+
+func (s *lruShard) remove(e *list.Element) {
+	entry := s.ll.Remove(e).(*lruEntry)
+	delete(s.items, entry.key)
+	s.bytes -= len(entry.key) + len(entry.value)
+}
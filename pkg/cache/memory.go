@@ -223,16 +223,22 @@ func (m *memoryCache) MultiGet(ctx context.Context, keys []string, value interfa
 }
 
 // SetCacheWithNotFound set not found
-func (m *memoryCache) SetCacheWithNotFound(_ context.Context, key string) error {
+func (m *memoryCache) SetCacheWithNotFound(ctx context.Context, key string) error {
+	return m.setNotFound(ctx, key, DefaultNotFoundExpireTime)
+}
+
+// set not found with the expiration
+func (m *memoryCache) setNotFound(_ context.Context, key string, expiration time.Duration) error {
 	cacheKey, err := BuildCacheKey(m.KeyPrefix, key)
 	if err != nil {
 		return fmt.Errorf("BuildCacheKey error: %v, key=%s", err, key)
 	}
 
-	ok := m.client.SetWithTTL(cacheKey, []byte(NotFoundPlaceholder), 0, DefaultNotFoundExpireTime)
+	ok := m.client.SetWithTTL(cacheKey, []byte(NotFoundPlaceholder), 0, expiration)
 	if !ok {
 		return errors.New("SetWithTTL failed")
 	}
+	m.client.Wait()
 
 	return nil
 }
@@ -193,12 +193,17 @@ func (c *redisCache) Del(ctx context.Context, keys ...string) error {
 
 // SetCacheWithNotFound set value for notfound
 func (c *redisCache) SetCacheWithNotFound(ctx context.Context, key string) error {
+	return c.setNotFound(ctx, key, DefaultNotFoundExpireTime)
+}
+
+// set value for notfound with the expiration
+func (c *redisCache) setNotFound(ctx context.Context, key string, expiration time.Duration) error {
 	cacheKey, err := BuildCacheKey(c.KeyPrefix, key)
 	if err != nil {
 		return fmt.Errorf("BuildCacheKey error: %v, key=%s", err, key)
 	}
 
-	return c.client.Set(ctx, cacheKey, NotFoundPlaceholder, DefaultNotFoundExpireTime).Err()
+	return c.client.Set(ctx, cacheKey, NotFoundPlaceholder, expiration).Err()
 }
 
 // BuildCacheKey construct a cache key with a prefix
@@ -236,6 +241,21 @@ func NewRedisClusterCache(client *redis.ClusterClient, keyPrefix string, encode
 	}
 }
 
+// NewRedisUniversalCache new a cache of the redis client of any mode, e.g. the client of goredis.InitUniversal,
+// *redis.ClusterClient uses the cluster cache, *redis.Client (single or sentinel) uses the redis cache.
+func NewRedisUniversalCache(client redis.UniversalClient, keyPrefix string, encode encoding.Encoding, newObject func() interface{}) Cache {
+	switch c := client.(type) {
+	case *redis.ClusterClient:
+		return NewRedisClusterCache(c, keyPrefix, encode, newObject)
+	case *redis.Client:
+		return NewRedisCache(c, keyPrefix, encode, newObject)
+	case nil:
+		return NewRedisCache(nil, keyPrefix, encode, newObject)
+	default:
+		panic(fmt.Sprintf("unsupported redis client type %T", client))
+	}
+}
+
 // Set one value
 func (c *redisClusterCache) Set(ctx context.Context, key string, val interface{}, expiration time.Duration) error {
 	buf, err := encoding.Marshal(c.encoding, val)
@@ -390,10 +410,15 @@ func (c *redisClusterCache) Del(ctx context.Context, keys ...string) error {
 
 // SetCacheWithNotFound set value for notfound
 func (c *redisClusterCache) SetCacheWithNotFound(ctx context.Context, key string) error {
+	return c.setNotFound(ctx, key, DefaultNotFoundExpireTime)
+}
+
+// set value for notfound with the expiration
+func (c *redisClusterCache) setNotFound(ctx context.Context, key string, expiration time.Duration) error {
 	cacheKey, err := BuildCacheKey(c.KeyPrefix, key)
 	if err != nil {
 		return fmt.Errorf("BuildCacheKey error: %v, key=%s", err, key)
 	}
 
-	return c.client.Set(ctx, cacheKey, NotFoundPlaceholder, DefaultNotFoundExpireTime).Err()
+	return c.client.Set(ctx, cacheKey, NotFoundPlaceholder, expiration).Err()
 }
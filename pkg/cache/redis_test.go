@@ -1,9 +1,12 @@
 package cache
 
 import (
+	"context"
 	"testing"
 	"time"
 
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/go-dev-frame/sponge/pkg/encoding"
@@ -218,3 +221,32 @@ func TestBuildCacheKey(t *testing.T) {
 	_, err = BuildCacheKey("foo", "bar")
 	assert.NoError(t, err)
 }
+
+func TestNewRedisUniversalCache(t *testing.T) {
+	s := miniredis.RunT(t)
+	ctx := context.Background()
+	newObject := func() interface{} { return &redisUser{} }
+
+	clients := []redis.UniversalClient{
+		redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{s.Addr()}}),
+	}
+	for _, client := range clients {
+		c := NewRedisUniversalCache(client, "test", encoding.JSONEncoding{}, newObject)
+		err := c.Set(ctx, "user:1", &redisUser{ID: 1, Name: "foo"}, time.Minute)
+		assert.NoError(t, err)
+		val := &redisUser{}
+		err = c.Get(ctx, "user:1", val)
+		assert.NoError(t, err)
+		assert.Equal(t, "foo", val.Name)
+		_ = client.Close()
+	}
+
+	assert.NotNil(t, NewRedisUniversalCache(nil, "test", encoding.JSONEncoding{}, newObject))
+
+	ring := redis.NewRing(&redis.RingOptions{Addrs: map[string]string{"shard1": s.Addr()}})
+	defer ring.Close()
+	assert.Panics(t, func() {
+		NewRedisUniversalCache(ring, "test", encoding.JSONEncoding{}, newObject)
+	})
+}
@@ -0,0 +1,347 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/go-dev-frame/sponge/pkg/encoding"
+	"github.com/go-dev-frame/sponge/pkg/logger"
+)
+
+// the levels of the two-level cache metrics
+const (
+	LevelL1 = "l1"
+	LevelL2 = "l2"
+)
+
+// TwoLevelCounter counts the reads of the two-level cache by name, level and result (hit or miss), the hit ratio
+// of each level is hit/(hit+miss), register it to expose the metrics, e.g. prometheus.MustRegister(cache.TwoLevelCounter)
+var TwoLevelCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "cache",
+		Name:      "two_level_requests_total",
+		Help:      "Total number of the reads of the two-level cache by level and result.",
+	}, []string{"name", "level", "result"},
+)
+
+var _ Cache = (*TwoLevelCache)(nil)
+
+// TwoLevelOption set the two-level cache options.
+type TwoLevelOption func(*twoLevelOptions)
+
+type twoLevelOptions struct {
+	name     string
+	channel  string
+	shards   int
+	maxBytes int
+	l1TTL    time.Duration
+	excludes []string
+}
+
+func defaultTwoLevelOptions() *twoLevelOptions {
+	return &twoLevelOptions{
+		name:     "default",
+		channel:  "cache:invalidate",
+		shards:   16,
+		maxBytes: 64 << 20,
+		l1TTL:    time.Minute,
+	}
+}
+
+func (o *twoLevelOptions) apply(opts ...TwoLevelOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithTwoLevelName set the name label of the metrics, default "default".
+func WithTwoLevelName(name string) TwoLevelOption {
+	return func(o *twoLevelOptions) {
+		if name != "" {
+			o.name = name
+		}
+	}
+}
+
+// WithInvalidateChannel set the redis pub/sub channel of the invalidation, default "cache:invalidate",
+// the replicas sharing the cache must use the same channel.
+func WithInvalidateChannel(channel string) TwoLevelOption {
+	return func(o *twoLevelOptions) {
+		if channel != "" {
+			o.channel = channel
+		}
+	}
+}
+
+// WithL1Size set the number of shards and the max bytes of the keys and values of L1, default 16 and 64MB.
+func WithL1Size(shards int, maxBytes int) TwoLevelOption {
+	return func(o *twoLevelOptions) {
+		if shards > 0 {
+			o.shards = shards
+		}
+		if maxBytes > 0 {
+			o.maxBytes = maxBytes
+		}
+	}
+}
+
+// WithL1TTL set the max ttl of the L1 entries, it bounds the staleness if an invalidation is lost, default 1 minute.
+func WithL1TTL(ttl time.Duration) TwoLevelOption {
+	return func(o *twoLevelOptions) {
+		if ttl > 0 {
+			o.l1TTL = ttl
+		}
+	}
+}
+
+// WithL1Exclude the keys matching the patterns are not cached in L1, e.g. large values,
+// the pattern syntax is path.Match, e.g. "report:*".
+func WithL1Exclude(patterns ...string) TwoLevelOption {
+	return func(o *twoLevelOptions) {
+		o.excludes = append(o.excludes, patterns...)
+	}
+}
+
+// TwoLevelCache is an in-process LRU (L1) in front of the redis cache (L2), the writes and deletes are
+// published to the redis channel, every replica evicts the key from its L1.
+type TwoLevelCache struct {
+	client    *redis.Client
+	keyPrefix string
+	encoding  encoding.Encoding
+	newObject func() interface{}
+	opts      *twoLevelOptions
+
+	l1     *lru
+	id     string // instance id, the invalidations published by itself are ignored
+	pubsub *redis.PubSub
+	done   chan struct{}
+	once   sync.Once
+}
+
+// NewTwoLevelCache create a two-level cache, it subscribes the invalidation channel, call Close to unsubscribe.
+func NewTwoLevelCache(client *redis.Client, keyPrefix string, encode encoding.Encoding, newObject func() interface{}, opts ...TwoLevelOption) (*TwoLevelCache, error) {
+	o := defaultTwoLevelOptions()
+	o.apply(opts...)
+
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	c := &TwoLevelCache{
+		client:    client,
+		keyPrefix: keyPrefix,
+		encoding:  encode,
+		newObject: newObject,
+		opts:      o,
+		l1:        newLRU(o.shards, o.maxBytes),
+		id:        hex.EncodeToString(id),
+		done:      make(chan struct{}),
+	}
+
+	c.pubsub = client.Subscribe(context.Background(), o.channel)
+	// wait for the confirmation, so that no invalidation is missed after returning
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := c.pubsub.Receive(ctx); err != nil {
+		_ = c.pubsub.Close()
+		return nil, fmt.Errorf("subscribe channel %s error: %v", o.channel, err)
+	}
+	go c.watch()
+
+	return c, nil
+}
+
+// evict the keys published by the other replicas
+func (c *TwoLevelCache) watch() {
+	defer close(c.done)
+	for msg := range c.pubsub.Channel() {
+		id, cacheKey, ok := strings.Cut(msg.Payload, "|")
+		if !ok || id == c.id {
+			continue
+		}
+		c.l1.invalidate(cacheKey)
+	}
+}
+
+// Close unsubscribe the invalidation channel.
+func (c *TwoLevelCache) Close() error {
+	var err error
+	c.once.Do(func() {
+		err = c.pubsub.Close()
+		<-c.done
+		c.l1.clear()
+	})
+	return err
+}
+
+// Set one value
+func (c *TwoLevelCache) Set(ctx context.Context, key string, val interface{}, expiration time.Duration) error {
+	buf, err := encoding.Marshal(c.encoding, val)
+	if err != nil {
+		return fmt.Errorf("encoding.Marshal error: %v, key=%s, val=%+v ", err, key, val)
+	}
+	if len(buf) == 0 {
+		buf = NotFoundPlaceholderBytes
+	}
+	return c.set(ctx, key, buf, expiration)
+}
+
+func (c *TwoLevelCache) set(ctx context.Context, key string, buf []byte, expiration time.Duration) error {
+	cacheKey, err := BuildCacheKey(c.keyPrefix, key)
+	if err != nil {
+		return fmt.Errorf("BuildCacheKey error: %v, key=%s", err, key)
+	}
+	err = c.client.Set(ctx, cacheKey, buf, expiration).Err()
+	if err != nil {
+		c.l1.invalidate(cacheKey)
+		return fmt.Errorf("c.client.Set error: %v, cacheKey=%s", err, cacheKey)
+	}
+
+	// the local value is updated, the other replicas are invalidated after the value is written to redis
+	c.l1.invalidate(cacheKey)
+	if c.cacheable(key) {
+		c.l1.set(cacheKey, buf, c.l1TTL(expiration))
+	}
+	c.publish(ctx, cacheKey)
+	return nil
+}
+
+// Get one value
+func (c *TwoLevelCache) Get(ctx context.Context, key string, val interface{}) error {
+	cacheKey, err := BuildCacheKey(c.keyPrefix, key)
+	if err != nil {
+		return fmt.Errorf("BuildCacheKey error: %v, key=%s", err, key)
+	}
+
+	cacheable := c.cacheable(key)
+	if cacheable {
+		if dataBytes, ok := c.l1.get(cacheKey); ok {
+			c.count(LevelL1, LoadResultHit)
+			return c.decode(key, cacheKey, dataBytes, val)
+		}
+		c.count(LevelL1, LoadResultMiss)
+	}
+
+	// the generation is got before reading redis, if the key is invalidated while reading, the value is not cached
+	gen := c.l1.generation(cacheKey)
+	dataBytes, err := c.client.Get(ctx, cacheKey).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			c.count(LevelL2, LoadResultMiss)
+		}
+		return err
+	}
+	c.count(LevelL2, LoadResultHit)
+	if cacheable {
+		c.l1.setIfGen(cacheKey, dataBytes, c.opts.l1TTL, gen)
+	}
+	return c.decode(key, cacheKey, dataBytes, val)
+}
+
+func (c *TwoLevelCache) decode(key string, cacheKey string, dataBytes []byte, val interface{}) error {
+	// prevent Unmarshal from reporting an error if data is empty
+	if len(dataBytes) == 0 || bytes.Equal(dataBytes, NotFoundPlaceholderBytes) {
+		return ErrPlaceholder
+	}
+	err := encoding.Unmarshal(c.encoding, dataBytes, val)
+	if err != nil {
+		return fmt.Errorf("encoding.Unmarshal error: %v, key=%s, cacheKey=%s, type=%T, data=%s ",
+			err, key, cacheKey, val, dataBytes)
+	}
+	return nil
+}
+
+// MultiSet set multiple values
+func (c *TwoLevelCache) MultiSet(ctx context.Context, valueMap map[string]interface{}, expiration time.Duration) error {
+	for key, value := range valueMap {
+		if err := c.Set(ctx, key, value, expiration); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MultiGet get multiple values, valueMap is map[cacheKey]object
+func (c *TwoLevelCache) MultiGet(ctx context.Context, keys []string, value interface{}) error {
+	valueMap := reflect.ValueOf(value)
+	for _, key := range keys {
+		object := c.newObject()
+		if err := c.Get(ctx, key, object); err != nil {
+			continue
+		}
+		cacheKey, _ := BuildCacheKey(c.keyPrefix, key)
+		valueMap.SetMapIndex(reflect.ValueOf(cacheKey), reflect.ValueOf(object))
+	}
+	return nil
+}
+
+// Del delete multiple values
+func (c *TwoLevelCache) Del(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	cacheKeys := make([]string, 0, len(keys))
+	for _, key := range keys {
+		cacheKey, err := BuildCacheKey(c.keyPrefix, key)
+		if err != nil {
+			continue
+		}
+		cacheKeys = append(cacheKeys, cacheKey)
+	}
+	err := c.client.Del(ctx, cacheKeys...).Err()
+	for _, cacheKey := range cacheKeys {
+		c.l1.invalidate(cacheKey)
+		c.publish(ctx, cacheKey)
+	}
+	if err != nil {
+		return fmt.Errorf("c.client.Del error: %v, keys=%+v", err, cacheKeys)
+	}
+	return nil
+}
+
+// SetCacheWithNotFound set value for notfound
+func (c *TwoLevelCache) SetCacheWithNotFound(ctx context.Context, key string) error {
+	return c.setNotFound(ctx, key, DefaultNotFoundExpireTime)
+}
+
+// set value for notfound with the expiration
+func (c *TwoLevelCache) setNotFound(ctx context.Context, key string, expiration time.Duration) error {
+	return c.set(ctx, key, NotFoundPlaceholderBytes, expiration)
+}
+
+func (c *TwoLevelCache) publish(ctx context.Context, cacheKey string) {
+	if err := c.client.Publish(ctx, c.opts.channel, c.id+"|"+cacheKey).Err(); err != nil {
+		logger.Warn("publish cache invalidation error", logger.Err(err), logger.String("key", cacheKey))
+	}
+}
+
+func (c *TwoLevelCache) cacheable(key string) bool {
+	for _, pattern := range c.opts.excludes {
+		if ok, _ := path.Match(pattern, key); ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *TwoLevelCache) l1TTL(expiration time.Duration) time.Duration {
+	if expiration > 0 && expiration < c.opts.l1TTL {
+		return expiration
+	}
+	return c.opts.l1TTL
+}
+
+func (c *TwoLevelCache) count(level string, result string) {
+	TwoLevelCounter.WithLabelValues(c.opts.name, level, result).Inc()
+}
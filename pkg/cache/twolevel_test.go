@@ -0,0 +1,204 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-dev-frame/sponge/pkg/encoding"
+)
+
+func newTwoLevelCache(t *testing.T, s *miniredis.Miniredis, opts ...TwoLevelOption) *TwoLevelCache {
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	c, err := NewTwoLevelCache(client, "test", encoding.JSONEncoding{}, func() interface{} { return &redisUser{} }, opts...)
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func twoLevelCount(name string, level string, result string) int {
+	return int(testutil.ToFloat64(TwoLevelCounter.WithLabelValues(name, level, result)))
+}
+
+func TestTwoLevelCache(t *testing.T) {
+	s := miniredis.RunT(t)
+	c := newTwoLevelCache(t, s, WithTwoLevelName("TestTwoLevelCache"))
+	ctx := context.Background()
+	l1Hits := twoLevelCount("TestTwoLevelCache", LevelL1, LoadResultHit)
+	l2Hits := twoLevelCount("TestTwoLevelCache", LevelL2, LoadResultHit)
+
+	err := c.Set(ctx, "user:1", &redisUser{ID: 1, Name: "foo"}, time.Hour)
+	assert.NoError(t, err)
+	assert.True(t, s.Exists("test:user:1"))
+
+	// L1 hit
+	user := &redisUser{}
+	assert.NoError(t, c.Get(ctx, "user:1", user))
+	assert.Equal(t, "foo", user.Name)
+	assert.Equal(t, 1, twoLevelCount("TestTwoLevelCache", LevelL1, LoadResultHit)-l1Hits)
+
+	// L2 hit, the value is cached in L1
+	_ = s.Set("test:user:2", `{"ID":2,"Name":"bar"}`)
+	assert.NoError(t, c.Get(ctx, "user:2", user))
+	assert.Equal(t, "bar", user.Name)
+	assert.NoError(t, c.Get(ctx, "user:2", user))
+	assert.Equal(t, 1, twoLevelCount("TestTwoLevelCache", LevelL2, LoadResultHit)-l2Hits)
+	assert.Equal(t, 2, twoLevelCount("TestTwoLevelCache", LevelL1, LoadResultHit)-l1Hits)
+
+	// miss
+	err = c.Get(ctx, "user:3", user)
+	assert.ErrorIs(t, err, CacheNotFound)
+
+	// multiple values
+	err = c.MultiSet(ctx, map[string]interface{}{"user:4": &redisUser{ID: 4}, "user:5": &redisUser{ID: 5}}, time.Hour)
+	assert.NoError(t, err)
+	values := map[string]*redisUser{}
+	err = c.MultiGet(ctx, []string{"user:4", "user:5", "user:6"}, values)
+	assert.NoError(t, err)
+	assert.Len(t, values, 2)
+	assert.Equal(t, uint64(4), values["test:user:4"].ID)
+
+	// delete
+	assert.NoError(t, c.Del(ctx, "user:1", "user:2"))
+	assert.ErrorIs(t, c.Get(ctx, "user:1", user), CacheNotFound)
+
+	// not found placeholder
+	assert.NoError(t, c.SetCacheWithNotFound(ctx, "user:1"))
+	assert.ErrorIs(t, c.Get(ctx, "user:1", user), ErrPlaceholder)
+
+	// works with GetOrLoad
+	user, err = GetOrLoad(ctx, "user:7", time.Hour, func(ctx context.Context) (*redisUser, error) {
+		return &redisUser{ID: 7}, nil
+	}, WithLoadCache(c))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(7), user.ID)
+}
+
+func TestTwoLevelCacheInvalidation(t *testing.T) {
+	s := miniredis.RunT(t)
+	c1 := newTwoLevelCache(t, s)
+	c2 := newTwoLevelCache(t, s)
+	ctx := context.Background()
+
+	assert.NoError(t, c1.Set(ctx, "user:1", &redisUser{ID: 1, Name: "foo"}, time.Hour))
+	// the invalidation published by c1 may arrive while reading, then the value is not cached in L1
+	user := &redisUser{}
+	assert.Eventually(t, func() bool {
+		assert.NoError(t, c2.Get(ctx, "user:1", user))
+		_, ok := c2.l1.get("test:user:1")
+		return ok
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, "foo", user.Name)
+
+	// the write of c1 evicts the L1 entry of c2
+	assert.NoError(t, c1.Set(ctx, "user:1", &redisUser{ID: 1, Name: "bar"}, time.Hour))
+	assert.Eventually(t, func() bool {
+		_, ok := c2.l1.get("test:user:1")
+		return !ok
+	}, time.Second, time.Millisecond)
+	assert.NoError(t, c2.Get(ctx, "user:1", user))
+	assert.Equal(t, "bar", user.Name)
+
+	// the delete of c2 evicts the L1 entry of c1
+	assert.NoError(t, c2.Del(ctx, "user:1"))
+	assert.Eventually(t, func() bool {
+		return c1.Get(ctx, "user:1", user) == CacheNotFound
+	}, time.Second, time.Millisecond)
+
+	// the own invalidations are ignored, the L1 entry of the writer is kept
+	assert.NoError(t, c1.Set(ctx, "user:2", &redisUser{ID: 2}, time.Hour))
+	time.Sleep(20 * time.Millisecond)
+	_, ok := c1.l1.get("test:user:2")
+	assert.True(t, ok)
+
+	// no invalidation after closing
+	assert.NoError(t, c2.Close())
+	assert.NoError(t, c2.Close())
+}
+
+func TestTwoLevelCacheGeneration(t *testing.T) {
+	s := miniredis.RunT(t)
+	c := newTwoLevelCache(t, s)
+
+	// a value read from redis before the invalidation is not cached
+	gen := c.l1.generation("test:user:1")
+	c.l1.invalidate("test:user:1")
+	assert.False(t, c.l1.setIfGen("test:user:1", []byte("old"), time.Minute, gen))
+	_, ok := c.l1.get("test:user:1")
+	assert.False(t, ok)
+
+	gen = c.l1.generation("test:user:1")
+	assert.True(t, c.l1.setIfGen("test:user:1", []byte("new"), time.Minute, gen))
+}
+
+func TestTwoLevelCacheExclude(t *testing.T) {
+	s := miniredis.RunT(t)
+	c := newTwoLevelCache(t, s, WithL1Exclude("report:*"), WithL1TTL(time.Second))
+	ctx := context.Background()
+
+	assert.NoError(t, c.Set(ctx, "report:1", &redisUser{ID: 1}, time.Hour))
+	assert.NoError(t, c.Set(ctx, "user:1", &redisUser{ID: 1}, time.Hour))
+	user := &redisUser{}
+	assert.NoError(t, c.Get(ctx, "report:1", user))
+	_, ok := c.l1.get("test:report:1")
+	assert.False(t, ok)
+	_, ok = c.l1.get("test:user:1")
+	assert.True(t, ok)
+
+	// the L1 entry expires
+	c.l1.now = func() time.Time { return time.Now().Add(2 * time.Second) }
+	_, ok = c.l1.get("test:user:1")
+	assert.False(t, ok)
+}
+
+func TestLRU(t *testing.T) {
+	l := newLRU(1, 100)
+	value := []byte(strings.Repeat("a", 21))
+
+	// each entry is 26 bytes, 3 entries are kept
+	for i := 0; i < 5; i++ {
+		assert.True(t, l.set(fmt.Sprintf("key:%d", i), value, time.Minute))
+	}
+	n, size := l.size()
+	assert.Equal(t, 3, n)
+	assert.Equal(t, 78, size)
+
+	// the least recently used are evicted
+	_, ok := l.get("key:0")
+	assert.False(t, ok)
+	_, ok = l.get("key:2")
+	assert.True(t, ok)
+	l.set("key:5", value, time.Minute)
+	_, ok = l.get("key:2")
+	assert.True(t, ok)
+	_, ok = l.get("key:3")
+	assert.False(t, ok)
+
+	// too large
+	assert.False(t, l.set("key:6", make([]byte, 100), time.Minute))
+
+	// replace
+	l.set("key:2", []byte("b"), time.Minute)
+	v, _ := l.get("key:2")
+	assert.Equal(t, []byte("b"), v)
+
+	l.clear()
+	n, size = l.size()
+	assert.Equal(t, 0, n)
+	assert.Equal(t, 0, size)
+
+	// sharded, the bytes are bounded by each shard
+	l = newLRU(4, 4000)
+	for i := 0; i < 1000; i++ {
+		l.set(fmt.Sprintf("key:%d", i), value, time.Minute)
+	}
+	_, size = l.size()
+	assert.True(t, size <= 4000, size)
+}
@@ -0,0 +1,154 @@
+// Package configsource provides the common interface of the configuration centers, e.g. nacos, consul, etcd,
+// and the common logic of retry and local cache fallback used by the implementations.
+package configsource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound the configuration does not exist in the configuration center
+	ErrNotFound = errors.New("config not found")
+	// ErrUnavailable the configuration center is unavailable, e.g. network error, server error
+	ErrUnavailable = errors.New("config source unavailable")
+	// ErrClosed the source is closed
+	ErrClosed = errors.New("config source closed")
+)
+
+// Source the configuration of a key in the configuration center
+type Source interface {
+	// Get the format (json, yaml, toml) and the content of the configuration, the error can be checked
+	// by errors.Is with ErrNotFound and ErrUnavailable.
+	Get(ctx context.Context) (format string, data []byte, err error)
+	// Watch the changes of the configuration in background, onChange is called with the new content when
+	// the configuration is changed after the last Get, the deletion of the key is ignored. Watching is
+	// stopped when ctx is done or the source is closed.
+	Watch(ctx context.Context, onChange func(format string, data []byte)) error
+	// Close stop watching and release the resources, the client passed to the source is not closed.
+	Close() error
+}
+
+// ParseFormat check the configuration format, returns json, yaml or toml, "yml" is converted to "yaml"
+func ParseFormat(format string) (string, error) {
+	format = strings.ToLower(format)
+	switch format {
+	case "json", "yaml", "toml":
+		return format, nil
+	case "yml":
+		return "yaml", nil
+	}
+	return "", fmt.Errorf("config file types 'Format=%s' not supported", format)
+}
+
+// Unavailable wrap err as ErrUnavailable
+func Unavailable(err error) error {
+	if err == nil || errors.Is(err, ErrUnavailable) {
+		return err
+	}
+	return fmt.Errorf("%w: %v", ErrUnavailable, err)
+}
+
+// -------------------------------------------------------------------------------------------
+
+// Fetcher get the configuration with retry, and fall back to the local cache when the configuration center
+// is unavailable, it is used by the implementations of Source.
+type Fetcher struct {
+	format    string
+	o         *options
+	cacheFile string
+}
+
+// NewFetcher create a fetcher, backend and key are used as the name of the cache file, the format is the
+// extension of the key if it is not set by WithFormat.
+func NewFetcher(backend string, key string, opts ...Option) (*Fetcher, error) {
+	o := defaultOptions()
+	o.apply(opts...)
+
+	format := o.format
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(key), ".")
+	}
+	format, err := ParseFormat(format)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Fetcher{format: format, o: o}
+	if o.cacheDir != "" {
+		f.cacheFile = filepath.Join(o.cacheDir, backend, url.PathEscape(key))
+	}
+	return f, nil
+}
+
+// Format the format of the configuration
+func (f *Fetcher) Format() string {
+	return f.format
+}
+
+// RetryInterval the interval of retrying, it is also used as the interval of re-watching
+func (f *Fetcher) RetryInterval() time.Duration {
+	return f.o.retryInterval
+}
+
+// Fetch call get until it succeeds or the error is not ErrUnavailable, at most 1+retries times. If the
+// configuration center is still unavailable, the content of the local cache is returned if it exists.
+func (f *Fetcher) Fetch(ctx context.Context, get func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	var data []byte
+	var err error
+	for i := 0; i <= f.o.retries; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(f.o.retryInterval):
+			}
+		}
+
+		data, err = get(ctx)
+		if err == nil {
+			f.SaveCache(data)
+			return data, nil
+		}
+		if !errors.Is(err, ErrUnavailable) {
+			return nil, err
+		}
+	}
+
+	if cached, ok := f.loadCache(); ok {
+		return cached, nil
+	}
+	return nil, err
+}
+
+// SaveCache save the content to the local cache, it is ignored if the cache dir is not set
+func (f *Fetcher) SaveCache(data []byte) {
+	if f.cacheFile == "" {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(f.cacheFile), 0o700); err != nil {
+		return
+	}
+	tmpFile := f.cacheFile + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0o600); err != nil {
+		return
+	}
+	_ = os.Rename(tmpFile, f.cacheFile)
+}
+
+func (f *Fetcher) loadCache() ([]byte, bool) {
+	if f.cacheFile == "" {
+		return nil, false
+	}
+	data, err := os.ReadFile(f.cacheFile)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
@@ -0,0 +1,96 @@
+package configsource
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFormat(t *testing.T) {
+	for format, want := range map[string]string{"json": "json", "YAML": "yaml", "yml": "yaml", "toml": "toml"} {
+		got, err := ParseFormat(format)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+	_, err := ParseFormat("xml")
+	assert.Error(t, err)
+}
+
+func TestUnavailable(t *testing.T) {
+	assert.NoError(t, Unavailable(nil))
+	err := Unavailable(errors.New("connection refused"))
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.Contains(t, err.Error(), "connection refused")
+	assert.Equal(t, err, Unavailable(err))
+}
+
+func TestNewFetcher(t *testing.T) {
+	f, err := NewFetcher("consul", "config/user.yml")
+	require.NoError(t, err)
+	assert.Equal(t, "yaml", f.Format())
+	assert.Equal(t, time.Second, f.RetryInterval())
+
+	f, err = NewFetcher("consul", "config/user", WithFormat("json"))
+	require.NoError(t, err)
+	assert.Equal(t, "json", f.Format())
+
+	_, err = NewFetcher("consul", "config/user")
+	assert.Error(t, err)
+}
+
+func TestFetcher_Fetch(t *testing.T) {
+	dir := t.TempDir()
+	f, err := NewFetcher("etcd", "/config/user.yml", WithRetry(2, 10*time.Millisecond), WithCacheDir(dir))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// no cache
+	calls := 0
+	_, err = f.Fetch(ctx, func(context.Context) ([]byte, error) {
+		calls++
+		return nil, Unavailable(errors.New("connection refused"))
+	})
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.Equal(t, 3, calls)
+
+	// succeed after retry, the content is cached
+	calls = 0
+	data, err := f.Fetch(ctx, func(context.Context) ([]byte, error) {
+		calls++
+		if calls < 2 {
+			return nil, Unavailable(errors.New("connection refused"))
+		}
+		return []byte("name: foo"), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "name: foo", string(data))
+	assert.Equal(t, 2, calls)
+
+	// fall back to the cache
+	data, err = f.Fetch(ctx, func(context.Context) ([]byte, error) {
+		return nil, Unavailable(errors.New("connection refused"))
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "name: foo", string(data))
+
+	// not found is not retried and doesn't fall back
+	calls = 0
+	_, err = f.Fetch(ctx, func(context.Context) ([]byte, error) {
+		calls++
+		return nil, ErrNotFound
+	})
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, 1, calls)
+
+	// canceled while waiting to retry
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = f.Fetch(ctx, func(context.Context) ([]byte, error) {
+		return nil, Unavailable(errors.New("connection refused"))
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
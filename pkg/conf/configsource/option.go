@@ -0,0 +1,55 @@
+package configsource
+
+import (
+	"time"
+)
+
+// Option set the source options.
+type Option func(*options)
+
+type options struct {
+	format        string
+	retries       int
+	retryInterval time.Duration
+	cacheDir      string
+}
+
+func defaultOptions() *options {
+	return &options{
+		retries:       2,
+		retryInterval: time.Second,
+	}
+}
+
+func (o *options) apply(opts ...Option) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithFormat set the configuration format, json, yaml or toml, default the extension of the key
+func WithFormat(format string) Option {
+	return func(o *options) {
+		o.format = format
+	}
+}
+
+// WithRetry set the retry times and interval when the configuration center is unavailable, default 2 times, 1s
+func WithRetry(retries int, interval time.Duration) Option {
+	return func(o *options) {
+		if retries >= 0 {
+			o.retries = retries
+		}
+		if interval > 0 {
+			o.retryInterval = interval
+		}
+	}
+}
+
+// WithCacheDir set the local cache dir, the configuration is saved after it is got, and the cache is used
+// when the configuration center is unavailable, default no cache.
+func WithCacheDir(dir string) Option {
+	return func(o *options) {
+		o.cacheDir = dir
+	}
+}
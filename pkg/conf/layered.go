@@ -0,0 +1,302 @@
+package conf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+
+	"github.com/go-dev-frame/sponge/pkg/conf/configsource"
+	"github.com/go-dev-frame/sponge/pkg/utils"
+)
+
+// SourceEnv the source name of the keys overridden by the environment variables
+const SourceEnv = "env"
+
+// Fragment loads a configuration fragment, returns the format (yaml, json, toml) and the content.
+type Fragment func() (format string, data []byte, err error)
+
+type fragment struct {
+	name string
+	load Fragment
+}
+
+// LoaderOption set the layered loader options.
+type LoaderOption func(*Loader)
+
+// WithOverlay set the environment name of the overlay file, e.g. "prod" means the file "<base>.prod.yml"
+// in the same directory as the base file "<base>.yml", it is skipped if the file doesn't exist.
+func WithOverlay(env string) LoaderOption {
+	return func(l *Loader) {
+		l.overlayEnv = env
+	}
+}
+
+// WithFragment add a configuration fragment merged after the overlay file, fragments are merged in the
+// order they are added, name is the source name shown in Sources. A nacos fragment is loaded by
+// nacoscli.Fragment, e.g. WithFragment("nacos:dev/user.yml", nacoscli.Fragment(params)).
+func WithFragment(name string, load Fragment) LoaderOption {
+	return func(l *Loader) {
+		if load != nil {
+			l.fragments = append(l.fragments, fragment{name: name, load: load})
+		}
+	}
+}
+
+// WithSource add the configuration of the configuration center as a fragment, e.g. consul, etcd,
+// WithSource("consul:config/user.yml", src), src is created by consulcli.NewSource or etcdcli.NewSource.
+func WithSource(name string, src configsource.Source) LoaderOption {
+	if src == nil {
+		return func(*Loader) {}
+	}
+	return WithFragment(name, func() (string, []byte, error) {
+		return src.Get(context.Background())
+	})
+}
+
+// WithEnvPrefix set the prefix of the environment variables overriding the configuration, e.g. prefix "APP",
+// the key "redis.dialTimeout" is overridden by the environment variable "APP_REDIS_DIALTIMEOUT". Only the keys
+// that exist in the merged configuration can be overridden, and the value is converted to the type of the key.
+func WithEnvPrefix(prefix string) LoaderOption {
+	return func(l *Loader) {
+		l.envPrefix = strings.ToUpper(strings.TrimSuffix(prefix, "_"))
+	}
+}
+
+// WithValidate add the validation hooks, they are called in order after the final configuration is unmarshalled.
+func WithValidate(fns ...func(obj interface{}) error) LoaderOption {
+	return func(l *Loader) {
+		for _, fn := range fns {
+			if fn != nil {
+				l.validates = append(l.validates, fn)
+			}
+		}
+	}
+}
+
+// Loader loads the configuration in layers, the layers are deep-merged in order:
+// base file → environment overlay file → fragments (e.g. nacos, consul, etcd) → environment variables.
+type Loader struct {
+	baseFile   string
+	overlayEnv string
+	fragments  []fragment
+	envPrefix  string
+	validates  []func(obj interface{}) error
+
+	settings map[string]interface{}
+	sources  map[string]string // flattened key → source name
+}
+
+// NewLoader create a layered configuration loader
+func NewLoader(baseFile string, opts ...LoaderOption) *Loader {
+	l := &Loader{baseFile: baseFile}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Load merge the layers and unmarshal the final configuration to obj, then run the validation hooks.
+func (l *Loader) Load(obj interface{}) error {
+	l.settings = make(map[string]interface{})
+	l.sources = make(map[string]string)
+
+	// base file
+	format, data, err := readFile(l.baseFile)
+	if err != nil {
+		return err
+	}
+	if err = l.merge(l.baseFile, format, data); err != nil {
+		return err
+	}
+
+	// environment overlay file
+	if overlayFile := l.overlayFile(); overlayFile != "" {
+		format, data, err = readFile(overlayFile)
+		if err == nil {
+			err = l.merge(overlayFile, format, data)
+		}
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	// fragments
+	for _, f := range l.fragments {
+		format, data, err = f.load()
+		if err != nil {
+			return fmt.Errorf("load fragment %s error: %w", f.name, err)
+		}
+		if err = l.merge(f.name, format, data); err != nil {
+			return err
+		}
+	}
+
+	// environment variables
+	if err = l.mergeEnv(); err != nil {
+		return err
+	}
+
+	v := viper.New()
+	if err = v.MergeConfigMap(l.settings); err != nil {
+		return err
+	}
+	if err = v.Unmarshal(obj); err != nil {
+		return err
+	}
+
+	for _, validate := range l.validates {
+		if err = validate(obj); err != nil {
+			return fmt.Errorf("validate config error: %w", err)
+		}
+	}
+	return nil
+}
+
+// Sources returns the source of each effective key after Load, the key is lowercase and separated by
+// dots, e.g. "redis.dialtimeout", the source is the file name, the fragment name, or SourceEnv.
+func (l *Loader) Sources() map[string]string {
+	sources := make(map[string]string, len(l.sources))
+	for k, v := range l.sources {
+		sources[k] = v
+	}
+	return sources
+}
+
+// SourcesReport returns the sources in the format "key: source" line by line, sorted by key.
+func (l *Loader) SourcesReport() string {
+	keys := make([]string, 0, len(l.sources))
+	for k := range l.sources {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	buf := &strings.Builder{}
+	for _, k := range keys {
+		fmt.Fprintf(buf, "%s: %s\n", k, l.sources[k])
+	}
+	return buf.String()
+}
+
+func (l *Loader) overlayFile() string {
+	if l.overlayEnv == "" {
+		return ""
+	}
+	ext := filepath.Ext(l.baseFile)
+	return strings.TrimSuffix(l.baseFile, ext) + "." + l.overlayEnv + ext
+}
+
+func (l *Loader) merge(source string, format string, data []byte) error {
+	v := viper.New()
+	v.SetConfigType(format)
+	if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("parse %s error: %w", source, err)
+	}
+	src := v.AllSettings()
+	settings, err := utils.DeepMerge(l.settings, src)
+	if err != nil {
+		return fmt.Errorf("merge %s error: %w", source, err)
+	}
+	l.settings = settings
+	updateSources(l.sources, flatten(settings, ""), flatten(src, ""), source)
+	return nil
+}
+
+func (l *Loader) mergeEnv() error {
+	if l.envPrefix == "" {
+		return nil
+	}
+
+	for key, value := range flatten(l.settings, "") {
+		envName := l.envPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+		str, ok := os.LookupEnv(envName)
+		if !ok {
+			continue
+		}
+		newValue, err := coerce(str, value)
+		if err != nil {
+			return fmt.Errorf("environment variable %s error: %w", envName, err)
+		}
+		setSetting(l.settings, key, newValue)
+		l.sources[key] = SourceEnv
+	}
+	return nil
+}
+
+// updateSources set the sources of the keys of src to source, the sources of the keys replaced by the merge are
+// removed, e.g. the value "a.b" is replaced by the value "a"
+func updateSources(sources map[string]string, settings map[string]interface{}, src map[string]interface{}, source string) {
+	for key := range sources {
+		if _, ok := settings[key]; !ok {
+			delete(sources, key)
+		}
+	}
+	for key := range src {
+		sources[key] = source
+	}
+}
+
+// flatten the settings to the keys of the leaf values
+func flatten(settings map[string]interface{}, prefix string) map[string]interface{} {
+	m := make(map[string]interface{})
+	for k, v := range settings {
+		if sub, ok := v.(map[string]interface{}); ok {
+			for sk, sv := range flatten(sub, prefix+k+".") {
+				m[sk] = sv
+			}
+			continue
+		}
+		m[prefix+k] = v
+	}
+	return m
+}
+
+func setSetting(settings map[string]interface{}, key string, value interface{}) {
+	path := strings.Split(key, ".")
+	m := settings
+	for _, k := range path[:len(path)-1] {
+		m, _ = m[k].(map[string]interface{})
+	}
+	m[path[len(path)-1]] = value
+}
+
+// coerce converts the string value of the environment variable to the type of the old value
+func coerce(str string, old interface{}) (interface{}, error) {
+	switch old.(type) {
+	case bool:
+		return strconv.ParseBool(str)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return strconv.ParseInt(str, 10, 64)
+	case float32, float64:
+		return strconv.ParseFloat(str, 64)
+	case []interface{}, []string:
+		var values []interface{}
+		for _, s := range strings.Split(str, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				values = append(values, s)
+			}
+		}
+		return values, nil
+	default:
+		return str, nil
+	}
+}
+
+func readFile(file string) (string, []byte, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", nil, err
+	}
+	format := strings.TrimPrefix(filepath.Ext(file), ".")
+	if format == "yml" {
+		format = "yaml"
+	}
+	return format, data, nil
+}
@@ -0,0 +1,257 @@
+package conf
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-dev-frame/sponge/pkg/conf/configsource"
+)
+
+type layeredConfig struct {
+	App struct {
+		Name    string  `yaml:"name" json:"name"`
+		Env     string  `yaml:"env" json:"env"`
+		Debug   bool    `yaml:"debug" json:"debug"`
+		Port    int     `yaml:"port" json:"port"`
+		Ratio   float64 `yaml:"ratio" json:"ratio"`
+		Secret  string  `yaml:"secret" json:"secret"`
+		Timeout time.Duration
+	} `yaml:"app" json:"app"`
+	Redis struct {
+		Addrs       []string `yaml:"addrs" json:"addrs"`
+		DialTimeout int      `yaml:"dialTimeout" json:"dialTimeout"`
+	} `yaml:"redis" json:"redis"`
+}
+
+const (
+	baseYAML = `
+app:
+  name: "foo"
+  env: "dev"
+  debug: false
+  port: 8080
+  ratio: 0.5
+  secret: ""
+  timeout: 1s
+redis:
+  addrs: ["127.0.0.1:6379"]
+  dialTimeout: 10
+`
+	overlayYAML = `
+app:
+  env: "prod"
+  port: 9090
+`
+	nacosYAML = `
+redis:
+  dialTimeout: 20
+`
+)
+
+func writeLayeredFiles(t *testing.T) string {
+	dir := t.TempDir()
+	baseFile := filepath.Join(dir, "app.yml")
+	assert.NoError(t, os.WriteFile(baseFile, []byte(baseYAML), 0666))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "app.prod.yml"), []byte(overlayYAML), 0666))
+	return baseFile
+}
+
+func nacosFragment() (string, []byte, error) {
+	return "yaml", []byte(nacosYAML), nil
+}
+
+func TestLoader_Precedence(t *testing.T) {
+	baseFile := writeLayeredFiles(t)
+	t.Setenv("LAYERED_APP_PORT", "7070")
+
+	l := NewLoader(baseFile,
+		WithOverlay("prod"),
+		WithFragment("nacos:dev/app.yml", nacosFragment),
+		WithFragment("json", func() (string, []byte, error) {
+			return "json", []byte(`{"redis":{"dialTimeout":30}}`), nil
+		}),
+		WithEnvPrefix("LAYERED_"),
+	)
+	cfg := &layeredConfig{}
+	assert.NoError(t, l.Load(cfg))
+
+	assert.Equal(t, "foo", cfg.App.Name)       // base
+	assert.Equal(t, "prod", cfg.App.Env)       // overlay
+	assert.Equal(t, 7070, cfg.App.Port)        // env > overlay
+	assert.Equal(t, 30, cfg.Redis.DialTimeout) // the last fragment > the first fragment
+	assert.Equal(t, time.Second, cfg.App.Timeout)
+	assert.Equal(t, []string{"127.0.0.1:6379"}, cfg.Redis.Addrs)
+
+	// the overlay file doesn't exist
+	l = NewLoader(baseFile, WithOverlay("test"))
+	cfg = &layeredConfig{}
+	assert.NoError(t, l.Load(cfg))
+	assert.Equal(t, "dev", cfg.App.Env)
+	assert.Equal(t, 8080, cfg.App.Port)
+}
+
+type fakeSource struct {
+	data []byte
+	err  error
+}
+
+func (s *fakeSource) Get(context.Context) (string, []byte, error) {
+	return "yaml", s.data, s.err
+}
+
+func (s *fakeSource) Watch(context.Context, func(format string, data []byte)) error {
+	return nil
+}
+
+func (s *fakeSource) Close() error {
+	return nil
+}
+
+func TestLoader_WithSource(t *testing.T) {
+	baseFile := writeLayeredFiles(t)
+
+	l := NewLoader(baseFile, WithSource("consul:config/app.yml", &fakeSource{data: []byte(nacosYAML)}), WithSource("nil", nil))
+	cfg := &layeredConfig{}
+	assert.NoError(t, l.Load(cfg))
+	assert.Equal(t, 20, cfg.Redis.DialTimeout)
+	assert.Equal(t, "consul:config/app.yml", l.Sources()["redis.dialtimeout"])
+
+	l = NewLoader(baseFile, WithSource("etcd:/config/app.yml", &fakeSource{err: configsource.ErrNotFound}))
+	err := l.Load(&layeredConfig{})
+	assert.ErrorIs(t, err, configsource.ErrNotFound)
+	assert.ErrorContains(t, err, "etcd:/config/app.yml")
+}
+
+func TestLoader_EnvCoercion(t *testing.T) {
+	baseFile := writeLayeredFiles(t)
+	t.Setenv("APP_APP_DEBUG", "true")
+	t.Setenv("APP_APP_PORT", "9000")
+	t.Setenv("APP_APP_RATIO", "0.75")
+	t.Setenv("APP_APP_SECRET", "s3cret")
+	t.Setenv("APP_APP_TIMEOUT", "5s")
+	t.Setenv("APP_REDIS_ADDRS", "127.0.0.1:6380, 127.0.0.1:6381")
+	t.Setenv("APP_REDIS_DIALTIMEOUT", "15")
+	t.Setenv("APP_APP_UNKNOWN", "ignored")
+
+	l := NewLoader(baseFile, WithEnvPrefix("app"))
+	cfg := &layeredConfig{}
+	assert.NoError(t, l.Load(cfg))
+	assert.True(t, cfg.App.Debug)
+	assert.Equal(t, 9000, cfg.App.Port)
+	assert.Equal(t, 0.75, cfg.App.Ratio)
+	assert.Equal(t, "s3cret", cfg.App.Secret)
+	assert.Equal(t, 5*time.Second, cfg.App.Timeout)
+	assert.Equal(t, []string{"127.0.0.1:6380", "127.0.0.1:6381"}, cfg.Redis.Addrs)
+	assert.Equal(t, 15, cfg.Redis.DialTimeout)
+	_, ok := l.Sources()["app.unknown"]
+	assert.False(t, ok)
+
+	// the value can't be converted to the type of the key
+	t.Setenv("APP_APP_PORT", "abc")
+	err := NewLoader(baseFile, WithEnvPrefix("APP")).Load(&layeredConfig{})
+	assert.ErrorContains(t, err, "APP_APP_PORT")
+	t.Setenv("APP_APP_PORT", "9000")
+	t.Setenv("APP_APP_DEBUG", "yes")
+	err = NewLoader(baseFile, WithEnvPrefix("APP")).Load(&layeredConfig{})
+	assert.ErrorContains(t, err, "APP_APP_DEBUG")
+}
+
+func TestLoader_Sources(t *testing.T) {
+	baseFile := writeLayeredFiles(t)
+	overlayFile := filepath.Join(filepath.Dir(baseFile), "app.prod.yml")
+	t.Setenv("LAYERED_APP_SECRET", "s3cret")
+
+	l := NewLoader(baseFile,
+		WithOverlay("prod"),
+		WithFragment("nacos:dev/app.yml", nacosFragment),
+		WithFragment("replace", func() (string, []byte, error) {
+			// a map is replaced by a value
+			return "yaml", []byte("redis: \"\"\n"), nil
+		}),
+		WithEnvPrefix("LAYERED"),
+	)
+	assert.NoError(t, l.Load(&map[string]interface{}{}))
+
+	sources := l.Sources()
+	assert.Equal(t, baseFile, sources["app.name"])
+	assert.Equal(t, overlayFile, sources["app.env"])
+	assert.Equal(t, overlayFile, sources["app.port"])
+	assert.Equal(t, SourceEnv, sources["app.secret"])
+	assert.Equal(t, "replace", sources["redis"])
+	_, ok := sources["redis.dialtimeout"]
+	assert.False(t, ok)
+
+	report := l.SourcesReport()
+	assert.Contains(t, report, "app.env: "+overlayFile+"\n")
+	assert.Contains(t, report, "app.secret: env\n")
+	t.Log(report)
+
+	// a value is replaced by a map
+	l = NewLoader(baseFile,
+		WithFragment("nacos", func() (string, []byte, error) {
+			return "yaml", []byte("app:\n  name:\n    first: bar\n"), nil
+		}),
+	)
+	assert.NoError(t, l.Load(&map[string]interface{}{}))
+	assert.Equal(t, "nacos", l.Sources()["app.name.first"])
+	_, ok = l.Sources()["app.name"]
+	assert.False(t, ok)
+}
+
+func TestLoader_Validate(t *testing.T) {
+	baseFile := writeLayeredFiles(t)
+
+	var called []string
+	l := NewLoader(baseFile,
+		WithOverlay("prod"),
+		WithValidate(
+			func(obj interface{}) error {
+				called = append(called, "first")
+				// the validation runs after the final merge
+				assert.Equal(t, "prod", obj.(*layeredConfig).App.Env)
+				return nil
+			},
+			nil,
+			func(obj interface{}) error {
+				called = append(called, "second")
+				if obj.(*layeredConfig).App.Secret == "" {
+					return errors.New("app.secret is required")
+				}
+				return nil
+			},
+		),
+	)
+	err := l.Load(&layeredConfig{})
+	assert.ErrorContains(t, err, "app.secret is required")
+	assert.Equal(t, []string{"first", "second"}, called)
+}
+
+func TestLoader_Error(t *testing.T) {
+	baseFile := writeLayeredFiles(t)
+
+	// the base file doesn't exist
+	err := NewLoader("notfound.yml").Load(&layeredConfig{})
+	assert.Error(t, err)
+
+	// the overlay file is invalid
+	dir := filepath.Dir(baseFile)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "app.bad.yml"), []byte("app: [\n"), 0666))
+	err = NewLoader(baseFile, WithOverlay("bad")).Load(&layeredConfig{})
+	assert.Error(t, err)
+
+	// the fragment error
+	err = NewLoader(baseFile, WithFragment("nacos", func() (string, []byte, error) {
+		return "", nil, errors.New("connection refused")
+	})).Load(&layeredConfig{})
+	assert.ErrorContains(t, err, "nacos")
+
+	// unmarshal error
+	err = NewLoader(baseFile).Load(nil)
+	assert.Error(t, err)
+}
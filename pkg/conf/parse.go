@@ -91,7 +91,7 @@ func Show(obj interface{}, fields ...string) string {
 		if err != nil {
 			break
 		}
-		fields = append(fields, `"dsn"`, `"password"`, `"pwd"`)
+		fields = append(fields, `"dsn"`, `"password"`, `"pwd"`, `"apiKey"`, `"secret"`)
 
 		out += hideSensitiveFields(line, fields...)
 	}
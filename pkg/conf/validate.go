@@ -0,0 +1,95 @@
+package conf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+)
+
+// MultiError is all the problems of the configuration content.
+type MultiError struct {
+	Errors []error
+}
+
+// Error returns the error message
+func (e *MultiError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+	return "invalid configuration: " + strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the errors
+func (e *MultiError) Unwrap() []error {
+	return e.Errors
+}
+
+// ValidateContent dry-run validate the configuration content before publishing it, the content is parsed per
+// format (json, yaml, toml). If the schema (a struct or pointer to struct) is not nil, the content is decoded
+// to a new value of the schema strictly, the unknown fields are flagged, then the `validate` struct tags are
+// checked. All the problems are returned at once by *MultiError, the schema is not modified.
+func ValidateContent(format string, content []byte, schema interface{}) error {
+	format = strings.ToLower(format)
+	if format == "yml" {
+		format = "yaml"
+	}
+	switch format {
+	case "json", "yaml", "toml":
+	default:
+		return fmt.Errorf("config file types 'Format=%s' not supported", format)
+	}
+
+	v := viper.New()
+	v.SetConfigType(format)
+	if err := v.ReadConfig(bytes.NewReader(content)); err != nil {
+		return &MultiError{Errors: []error{err}}
+	}
+	if schema == nil {
+		return nil
+	}
+
+	typ := reflect.TypeOf(schema)
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return fmt.Errorf("schema must be a struct or pointer to struct, got %T", schema)
+	}
+	obj := reflect.New(typ).Interface()
+
+	var errs []error
+	err := v.Unmarshal(obj, func(c *mapstructure.DecoderConfig) {
+		c.ErrorUnused = true
+	})
+	if err != nil {
+		var decodeErr *mapstructure.Error
+		if !errors.As(err, &decodeErr) {
+			return &MultiError{Errors: []error{err}}
+		}
+		for _, msg := range decodeErr.Errors {
+			errs = append(errs, errors.New(msg))
+		}
+	}
+
+	if err = validator.New().Struct(obj); err != nil {
+		var validationErrs validator.ValidationErrors
+		if !errors.As(err, &validationErrs) {
+			errs = append(errs, err)
+		}
+		for _, fieldErr := range validationErrs {
+			errs = append(errs, fieldErr)
+		}
+	}
+
+	if len(errs) > 0 {
+		return &MultiError{Errors: errs}
+	}
+	return nil
+}
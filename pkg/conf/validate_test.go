@@ -0,0 +1,74 @@
+package conf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type validateConfig struct {
+	App struct {
+		Name string `yaml:"name" json:"name" validate:"required"`
+		Port int    `yaml:"port" json:"port" validate:"min=1,max=65535"`
+		Env  string `yaml:"env" json:"env" validate:"oneof=dev test prod"`
+	} `yaml:"app" json:"app"`
+	Redis struct {
+		Addr string `yaml:"addr" json:"addr"`
+	} `yaml:"redis" json:"redis"`
+}
+
+func TestValidateContent(t *testing.T) {
+	content := []byte(`
+app:
+  name: user
+  port: 8080
+  env: prod
+redis:
+  addr: 127.0.0.1:6379
+`)
+	assert.NoError(t, ValidateContent("yml", content, &validateConfig{}))
+	assert.NoError(t, ValidateContent("yaml", content, nil))
+	assert.NoError(t, ValidateContent("json", []byte(`{"app":{"name":"user","port":8080,"env":"dev"}}`), validateConfig{}))
+	assert.NoError(t, ValidateContent("toml", []byte("[app]\nname = \"user\"\nport = 8080\nenv = \"test\"\n"), &validateConfig{}))
+
+	assert.Error(t, ValidateContent("xml", content, nil))
+	assert.Error(t, ValidateContent("yaml", content, "config"))
+}
+
+func TestValidateContent_Error(t *testing.T) {
+	// malformed yaml
+	err := ValidateContent("yaml", []byte("app:\n  name: user\n port: 8080\n"), nil)
+	var multiErr *MultiError
+	require.True(t, errors.As(err, &multiErr))
+	assert.Len(t, multiErr.Errors, 1)
+	err = ValidateContent("json", []byte(`{"app":`), &validateConfig{})
+	assert.True(t, errors.As(err, &multiErr))
+
+	// unknown keys and tag violations are returned at once
+	content := []byte(`
+app:
+  name: ""
+  port: 70000
+  env: prod
+  debug: true
+redis:
+  addr: 127.0.0.1:6379
+  passwd: "123456"
+cache: redis
+`)
+	config := &validateConfig{}
+	err = ValidateContent("yaml", content, config)
+	require.True(t, errors.As(err, &multiErr))
+	assert.Len(t, multiErr.Errors, 5, err.Error())
+	for _, s := range []string{"debug", "passwd", "cache", "'Name' failed on the 'required' tag", "'Port' failed on the 'max' tag"} {
+		assert.Contains(t, err.Error(), s)
+	}
+	assert.Equal(t, "", config.App.Name) // the schema is not modified
+
+	// the wrong type
+	err = ValidateContent("yaml", []byte("app:\n  name: user\n  port: abc\n  env: dev\n"), &validateConfig{})
+	require.True(t, errors.As(err, &multiErr))
+	assert.Contains(t, err.Error(), "cannot parse 'App.Port' as int")
+}
@@ -0,0 +1,150 @@
+// Package confirmtoken provides the two-phase confirmation of the destructive batch operations, e.g. delete by
+// condition. The first call gets a short-lived signed token binding the exact filter, and the operation is
+// executed only when the token is resubmitted with the same filter before it expires.
+package confirmtoken
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrInvalid the token is malformed or the signature is wrong
+	ErrInvalid = errors.New("invalid confirmation token")
+	// ErrExpired the token is expired
+	ErrExpired = errors.New("confirmation token expired")
+	// ErrMismatch the token is issued for another filter
+	ErrMismatch = errors.New("confirmation token does not match the filter")
+	// ErrUsed the token has been used, each token confirms one execution only
+	ErrUsed = errors.New("confirmation token already used")
+)
+
+type claims struct {
+	FilterHash string `json:"h"`
+	ExpiresAt  int64  `json:"e"` // unix milliseconds
+	Nonce      string `json:"n"`
+}
+
+// Confirmer issue and verify the confirmation tokens, the tokens are signed by HMAC-SHA256 with the secret,
+// the replicas sharing the same secret verify the tokens of each other, but a token can be used once
+// per replica only, as the used tokens are recorded in memory until they expire.
+type Confirmer struct {
+	secret []byte
+	ttl    time.Duration
+
+	mu   sync.Mutex
+	used map[string]time.Time // nonce -> expiry
+}
+
+// New create a confirmer with the secret of signing the tokens
+func New(secret []byte, opts ...Option) *Confirmer {
+	o := defaultOptions()
+	o.apply(opts...)
+
+	return &Confirmer{
+		secret: secret,
+		ttl:    o.ttl,
+		used:   map[string]time.Time{},
+	}
+}
+
+// TTL the time to live of the tokens
+func (c *Confirmer) TTL() time.Duration {
+	return c.ttl
+}
+
+// Issue a token binding the hash of the filter, it expires after the ttl
+func (c *Confirmer) Issue(filter interface{}) (string, error) {
+	filterHash, err := HashFilter(filter)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, 12)
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(&claims{
+		FilterHash: filterHash,
+		ExpiresAt:  time.Now().Add(c.ttl).UnixMilli(),
+		Nonce:      hex.EncodeToString(nonce),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	encoding := base64.RawURLEncoding
+	return encoding.EncodeToString(payload) + "." + encoding.EncodeToString(c.sign(payload)), nil
+}
+
+// Verify check the signature, the expiry and the filter hash of the token, and mark the token used if it is
+// valid, the error can be checked by errors.Is with ErrInvalid, ErrExpired, ErrMismatch and ErrUsed.
+func (c *Confirmer) Verify(token string, filter interface{}) error {
+	payloadStr, sigStr, ok := strings.Cut(token, ".")
+	if !ok {
+		return ErrInvalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(payloadStr)
+	if err != nil {
+		return ErrInvalid
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(sigStr)
+	if err != nil || !hmac.Equal(sig, c.sign(payload)) {
+		return ErrInvalid
+	}
+	cl := &claims{}
+	if err = json.Unmarshal(payload, cl); err != nil {
+		return ErrInvalid
+	}
+
+	now := time.Now()
+	expiresAt := time.UnixMilli(cl.ExpiresAt)
+	if !now.Before(expiresAt) {
+		return ErrExpired
+	}
+	filterHash, err := HashFilter(filter)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(filterHash), []byte(cl.FilterHash)) {
+		return ErrMismatch
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for nonce, expiry := range c.used {
+		if !now.Before(expiry) {
+			delete(c.used, nonce)
+		}
+	}
+	if _, ok := c.used[cl.Nonce]; ok {
+		return ErrUsed
+	}
+	c.used[cl.Nonce] = expiresAt
+	return nil
+}
+
+func (c *Confirmer) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// HashFilter the sha256 of the JSON of the filter in hex, the keys of the maps are sorted by encoding/json,
+// so the same filter always has the same hash.
+func HashFilter(filter interface{}) (string, error) {
+	data, err := json.Marshal(filter)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,95 @@
+package confirmtoken
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type filter struct {
+	Columns []column `json:"columns"`
+}
+
+type column struct {
+	Name  string      `json:"name"`
+	Exp   string      `json:"exp"`
+	Value interface{} `json:"value"`
+}
+
+var secret = []byte("secret")
+
+func TestConfirmer(t *testing.T) {
+	c := New(secret)
+	assert.Equal(t, 60*time.Second, c.TTL())
+
+	f := &filter{Columns: []column{{Name: "status", Exp: "=", Value: 3}}}
+	token, err := c.Issue(f)
+	assert.NoError(t, err)
+
+	// the same filter, executed once
+	err = c.Verify(token, &filter{Columns: []column{{Name: "status", Exp: "=", Value: 3}}})
+	assert.NoError(t, err)
+	err = c.Verify(token, f)
+	assert.ErrorIs(t, err, ErrUsed)
+}
+
+func TestConfirmer_Mismatch(t *testing.T) {
+	c := New(secret)
+	token, err := c.Issue(&filter{Columns: []column{{Name: "status", Exp: "=", Value: 3}}})
+	assert.NoError(t, err)
+
+	// a broader filter with the valid token
+	err = c.Verify(token, &filter{Columns: []column{{Name: "status", Exp: ">=", Value: 3}}})
+	assert.ErrorIs(t, err, ErrMismatch)
+	err = c.Verify(token, &filter{})
+	assert.ErrorIs(t, err, ErrMismatch)
+
+	// the token is not used by the rejected calls
+	err = c.Verify(token, &filter{Columns: []column{{Name: "status", Exp: "=", Value: 3}}})
+	assert.NoError(t, err)
+}
+
+func TestConfirmer_Expired(t *testing.T) {
+	c := New(secret, WithTTL(time.Millisecond*50))
+	f := map[string]interface{}{"status": 3, "age": 10}
+	token, err := c.Issue(f)
+	assert.NoError(t, err)
+
+	time.Sleep(time.Millisecond * 60)
+	err = c.Verify(token, f)
+	assert.ErrorIs(t, err, ErrExpired)
+}
+
+func TestConfirmer_Invalid(t *testing.T) {
+	c := New(secret)
+	f := map[string]interface{}{"status": 3}
+	token, err := c.Issue(f)
+	assert.NoError(t, err)
+
+	// signed by another secret
+	err = New([]byte("another")).Verify(token, f)
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	// tampered
+	payload, sig, _ := strings.Cut(token, ".")
+	for _, tk := range []string{"", "abc", payload + ".", "." + sig, payload + "x." + sig, "!." + sig, payload + ".!"} {
+		assert.ErrorIs(t, c.Verify(tk, f), ErrInvalid, tk)
+	}
+
+	// the filter can't be marshaled
+	_, err = c.Issue(make(chan int))
+	assert.Error(t, err)
+	err = c.Verify(token, make(chan int))
+	assert.Error(t, err)
+}
+
+func TestHashFilter(t *testing.T) {
+	h1, err := HashFilter(map[string]interface{}{"a": 1, "b": "2"})
+	assert.NoError(t, err)
+	h2, err := HashFilter(map[string]interface{}{"b": "2", "a": 1})
+	assert.NoError(t, err)
+	assert.Equal(t, h1, h2)
+	assert.Len(t, h1, 64)
+}
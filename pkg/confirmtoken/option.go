@@ -0,0 +1,33 @@
+package confirmtoken
+
+import (
+	"time"
+)
+
+// Option set the confirmer options.
+type Option func(*options)
+
+type options struct {
+	ttl time.Duration
+}
+
+func defaultOptions() *options {
+	return &options{
+		ttl: 60 * time.Second,
+	}
+}
+
+func (o *options) apply(opts ...Option) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithTTL set the time to live of the confirmation tokens, default 60s
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		if ttl > 0 {
+			o.ttl = ttl
+		}
+	}
+}
@@ -21,10 +21,16 @@ func Init(addr string, opts ...Option) (*api.Client, error) {
 		return nil, fmt.Errorf("consul address cannot be empty")
 	}
 
-	return api.NewClient(&api.Config{
+	config := &api.Config{
 		Address:    addr,
 		Scheme:     o.scheme,
 		WaitTime:   o.waitTime,
 		Datacenter: o.datacenter,
-	})
+		Token:      o.token,
+	}
+	if o.tlsConfig != nil {
+		config.Scheme = "https"
+		config.TLSConfig = *o.tlsConfig
+	}
+	return api.NewClient(config)
 }
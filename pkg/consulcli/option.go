@@ -14,6 +14,7 @@ type options struct {
 	waitTime   time.Duration
 	datacenter string
 	token      string
+	tlsConfig  *api.TLSConfig
 
 	// if you set this parameter, all fields above are invalid
 	config *api.Config
@@ -60,6 +61,13 @@ func WithToken(token string) Option {
 	}
 }
 
+// WithTLS set tls config, the scheme is set to https
+func WithTLS(tlsConfig api.TLSConfig) Option {
+	return func(o *options) {
+		o.tlsConfig = &tlsConfig
+	}
+}
+
 // WithConfig set consul config
 func WithConfig(c *api.Config) Option {
 	return func(o *options) {
@@ -0,0 +1,176 @@
+package consulcli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+
+	"github.com/go-dev-frame/sponge/pkg/conf/configsource"
+)
+
+var _ configsource.Source = (*Source)(nil)
+
+// Source the configuration of a key in the consul kv, the changes are watched by blocking queries.
+type Source struct {
+	kv      *api.KV
+	key     string
+	fetcher *configsource.Fetcher
+
+	mu        sync.Mutex
+	lastIndex uint64
+	lastData  []byte
+
+	closeOnce sync.Once
+	done      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// NewSource create a configuration source of the consul kv, the format is the extension of the key
+// if it is not set by configsource.WithFormat, e.g. "config/user.yml" means yaml.
+func NewSource(client *api.Client, key string, opts ...configsource.Option) (*Source, error) {
+	if client == nil {
+		return nil, errors.New("consul client cannot be nil")
+	}
+	key = strings.TrimPrefix(key, "/")
+	if key == "" {
+		return nil, errors.New("consul key cannot be empty")
+	}
+	fetcher, err := configsource.NewFetcher("consul", key, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Source{
+		kv:      client.KV(),
+		key:     key,
+		fetcher: fetcher,
+		done:    make(chan struct{}),
+	}, nil
+}
+
+// Get the configuration from consul, the local cache is returned if consul is unavailable
+func (s *Source) Get(ctx context.Context) (string, []byte, error) {
+	select {
+	case <-s.done:
+		return "", nil, configsource.ErrClosed
+	default:
+	}
+
+	data, err := s.fetcher.Fetch(ctx, func(ctx context.Context) ([]byte, error) {
+		pair, meta, err := s.kv.Get(s.key, (&api.QueryOptions{}).WithContext(ctx))
+		if err != nil {
+			return nil, classifyError(ctx, err)
+		}
+		if pair == nil {
+			return nil, fmt.Errorf("%w: consul key %s", configsource.ErrNotFound, s.key)
+		}
+		s.mu.Lock()
+		s.lastIndex = meta.LastIndex
+		s.mu.Unlock()
+		return pair.Value, nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	s.mu.Lock()
+	s.lastData = data
+	s.mu.Unlock()
+	return s.fetcher.Format(), data, nil
+}
+
+// Watch the changes of the configuration by the blocking queries in background, the query is retried
+// after the retry interval if consul is unavailable.
+func (s *Source) Watch(ctx context.Context, onChange func(format string, data []byte)) error {
+	if onChange == nil {
+		return errors.New("onChange cannot be nil")
+	}
+	select {
+	case <-s.done:
+		return configsource.ErrClosed
+	default:
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer cancel()
+		s.watch(ctx, onChange)
+	}()
+	go func() {
+		select {
+		case <-s.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return nil
+}
+
+func (s *Source) watch(ctx context.Context, onChange func(format string, data []byte)) {
+	for ctx.Err() == nil {
+		s.mu.Lock()
+		index := s.lastIndex
+		s.mu.Unlock()
+
+		pair, meta, err := s.kv.Get(s.key, (&api.QueryOptions{WaitIndex: index}).WithContext(ctx))
+		if err != nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(s.fetcher.RetryInterval()):
+			}
+			continue
+		}
+
+		s.mu.Lock()
+		// reset the index if it goes backwards, e.g. the consul server is restored from a snapshot
+		if meta.LastIndex < index {
+			s.lastIndex = 0
+		} else {
+			s.lastIndex = meta.LastIndex
+		}
+		changed := pair != nil && !bytes.Equal(pair.Value, s.lastData)
+		if changed {
+			s.lastData = pair.Value
+		}
+		s.mu.Unlock()
+
+		if changed {
+			s.fetcher.SaveCache(pair.Value)
+			onChange(s.fetcher.Format(), pair.Value)
+		}
+	}
+}
+
+// Close stop watching, the consul client is not closed
+func (s *Source) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+	s.wg.Wait()
+	return nil
+}
+
+// classifyError the network errors and server errors are ErrUnavailable, the other errors such as
+// permission denied are returned directly.
+func classifyError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	var statusErr api.StatusError
+	if errors.As(err, &statusErr) {
+		if statusErr.Code >= http.StatusInternalServerError || statusErr.Code == http.StatusTooManyRequests {
+			return configsource.Unavailable(err)
+		}
+		return err
+	}
+	return configsource.Unavailable(err)
+}
@@ -0,0 +1,223 @@
+package consulcli
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-dev-frame/sponge/pkg/conf/configsource"
+)
+
+// kvServer a stub of the consul kv api supporting the blocking queries
+type kvServer struct {
+	mu         sync.Mutex
+	value      []byte // nil means the key doesn't exist
+	index      uint64
+	statusCode int // the error status code returned if it is not 0
+	changed    chan struct{}
+}
+
+func newKVServer(t *testing.T) (*kvServer, *httptest.Server) {
+	s := &kvServer{index: 1, changed: make(chan struct{})}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	return s, srv
+}
+
+func (s *kvServer) set(value string, index uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.value = []byte(value)
+	s.index = index
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *kvServer) setStatusCode(code int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statusCode = code
+}
+
+func (s *kvServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	waitIndex, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64)
+	wait, err := time.ParseDuration(r.URL.Query().Get("wait"))
+	if err != nil {
+		wait = time.Second
+	}
+
+	s.mu.Lock()
+	if code := s.statusCode; code != 0 {
+		s.mu.Unlock()
+		w.WriteHeader(code)
+		return
+	}
+	if waitIndex > 0 && waitIndex == s.index {
+		changed := s.changed
+		s.mu.Unlock()
+		select {
+		case <-changed:
+		case <-time.After(wait):
+		case <-r.Context().Done():
+			return
+		}
+		s.mu.Lock()
+	}
+	value, index := s.value, s.index
+	s.mu.Unlock()
+
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(index, 10))
+	if value == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+	_ = json.NewEncoder(w).Encode([]map[string]interface{}{{"Key": key, "Value": value, "ModifyIndex": index}})
+}
+
+func newTestSource(t *testing.T, srv *httptest.Server, opts ...configsource.Option) *Source {
+	cli, err := Init(strings.TrimPrefix(srv.URL, "http://"), WithWaitTime(time.Second))
+	require.NoError(t, err)
+	opts = append([]configsource.Option{configsource.WithRetry(1, 20*time.Millisecond)}, opts...)
+	src, err := NewSource(cli, "config/user.yml", opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = src.Close() })
+	return src
+}
+
+func TestNewSource(t *testing.T) {
+	_, err := NewSource(nil, "config/user.yml")
+	assert.Error(t, err)
+
+	cli, err := Init("127.0.0.1:8500")
+	require.NoError(t, err)
+	_, err = NewSource(cli, "")
+	assert.Error(t, err)
+	_, err = NewSource(cli, "config/user.xml")
+	assert.Error(t, err)
+}
+
+func TestSource_Get(t *testing.T) {
+	kv, srv := newKVServer(t)
+	src := newTestSource(t, srv, configsource.WithCacheDir(t.TempDir()))
+	ctx := context.Background()
+
+	_, _, err := src.Get(ctx)
+	assert.ErrorIs(t, err, configsource.ErrNotFound)
+
+	kv.set("name: foo", 2)
+	format, data, err := src.Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "yaml", format)
+	assert.Equal(t, "name: foo", string(data))
+
+	// permission denied is neither not found nor unavailable
+	kv.setStatusCode(http.StatusForbidden)
+	_, _, err = src.Get(ctx)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, configsource.ErrNotFound)
+	assert.NotErrorIs(t, err, configsource.ErrUnavailable)
+
+	// fall back to the local cache
+	kv.setStatusCode(http.StatusInternalServerError)
+	_, data, err = src.Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "name: foo", string(data))
+
+	// unavailable without cache
+	src2 := newTestSource(t, srv)
+	_, _, err = src2.Get(ctx)
+	assert.ErrorIs(t, err, configsource.ErrUnavailable)
+
+	srv.Close()
+	_, _, err = src2.Get(ctx)
+	assert.ErrorIs(t, err, configsource.ErrUnavailable)
+
+	_ = src.Close()
+	_, _, err = src.Get(ctx)
+	assert.ErrorIs(t, err, configsource.ErrClosed)
+	assert.ErrorIs(t, src.Watch(ctx, func(string, []byte) {}), configsource.ErrClosed)
+}
+
+func TestSource_Watch(t *testing.T) {
+	kv, srv := newKVServer(t)
+	kv.set("name: foo", 2)
+	src := newTestSource(t, srv)
+	ctx := context.Background()
+
+	_, _, err := src.Get(ctx)
+	require.NoError(t, err)
+	assert.Error(t, src.Watch(ctx, nil))
+
+	changes := make(chan string, 10)
+	err = src.Watch(ctx, func(format string, data []byte) {
+		assert.Equal(t, "yaml", format)
+		changes <- string(data)
+	})
+	require.NoError(t, err)
+
+	kv.set("name: bar", 3)
+	assert.Equal(t, "name: bar", receive(t, changes))
+
+	// the same content is ignored
+	kv.set("name: bar", 4)
+	// resume after consul is unavailable
+	kv.setStatusCode(http.StatusServiceUnavailable)
+	time.Sleep(100 * time.Millisecond)
+	kv.set("name: baz", 5)
+	kv.setStatusCode(0)
+	assert.Equal(t, "name: baz", receive(t, changes))
+
+	// the index goes backwards
+	kv.set("name: qux", 1)
+	assert.Equal(t, "name: qux", receive(t, changes))
+
+	// stop watching after closed
+	require.NoError(t, src.Close())
+	kv.set("name: quux", 6)
+	select {
+	case data := <-changes:
+		t.Fatalf("unexpected change %s", data)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestSource_WatchCancel(t *testing.T) {
+	kv, srv := newKVServer(t)
+	src := newTestSource(t, srv)
+
+	// the current content is a change if Get is not called
+	changes := make(chan string, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, src.Watch(ctx, func(format string, data []byte) { changes <- string(data) }))
+	kv.set("name: foo", 2)
+	assert.Equal(t, "name: foo", receive(t, changes))
+
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	kv.set("name: bar", 3)
+	select {
+	case data := <-changes:
+		t.Fatalf("unexpected change %s", data)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func receive(t *testing.T, ch chan string) string {
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(3 * time.Second):
+		t.Fatal("timeout waiting for the change")
+		return ""
+	}
+}
@@ -0,0 +1,332 @@
+package group
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrGroupClosed the keyed group is closed
+var ErrGroupClosed = errors.New("container.group: group is closed")
+
+// EvictReason the reason of evicting a value
+type EvictReason string
+
+// the reasons of evicting a value
+const (
+	EvictExpired  EvictReason = "expired"  // the ttl is reached
+	EvictIdle     EvictReason = "idle"     // not used in the idle timeout
+	EvictCapacity EvictReason = "capacity" // the least recently used one when the max entries is exceeded
+	EvictDeleted  EvictReason = "deleted"  // deleted by Delete
+	EvictClosed   EvictReason = "closed"   // the group is closed
+)
+
+// Factory create the value of the key
+type Factory[K comparable, V any] func(ctx context.Context, key K) (V, error)
+
+type entry[K comparable, V any] struct {
+	key      K
+	val      V
+	created  time.Time
+	lastUsed time.Time
+}
+
+type call[V any] struct {
+	done chan struct{}
+	val  V
+	err  error
+}
+
+type evicted[K comparable, V any] struct {
+	key    K
+	val    V
+	reason EvictReason
+}
+
+// KeyedGroup is a lazy load container of the per-key resources, e.g. the db handle per tenant, the rate
+// limiter per client. The values are evicted by ttl, idle timeout and the max entries (LRU), and the evicted
+// values are passed to the OnEvict callback, the values implementing io.Closer are closed by default.
+type KeyedGroup[K comparable, V any] struct {
+	factory     Factory[K, V]
+	name        string
+	ttl         time.Duration
+	idleTimeout time.Duration
+	maxEntries  int
+	onEvict     func(K, V, EvictReason)
+	metrics     *groupMetrics
+	now         func() time.Time
+
+	mu      sync.Mutex
+	entries map[K]*list.Element // value is *entry[K, V]
+	lru     *list.List          // the front is the most recently used
+	calls   map[K]*call[V]
+	closed  bool
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewKeyedGroup create a keyed group, the sweeper runs in background if the ttl or idle timeout is set,
+// Close should be called to stop it and release the values.
+func NewKeyedGroup[K comparable, V any](factory Factory[K, V], opts ...KeyedOption) (*KeyedGroup[K, V], error) {
+	if factory == nil {
+		return nil, errors.New("container.group: factory is nil")
+	}
+	o := defaultKeyedOptions()
+	o.apply(opts...)
+
+	onEvict := closeValue[K, V]
+	if o.onEvict != nil {
+		fn, ok := o.onEvict.(func(K, V, EvictReason))
+		if !ok {
+			return nil, fmt.Errorf("container.group: type of the OnEvict callback %T does not match the group", o.onEvict)
+		}
+		onEvict = fn
+	}
+	m, err := getGroupMetrics(o.registerer)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &KeyedGroup[K, V]{
+		factory:     factory,
+		name:        o.name,
+		ttl:         o.ttl,
+		idleTimeout: o.idleTimeout,
+		maxEntries:  o.maxEntries,
+		onEvict:     onEvict,
+		metrics:     m,
+		now:         time.Now,
+		entries:     make(map[K]*list.Element),
+		lru:         list.New(),
+		calls:       make(map[K]*call[V]),
+		stop:        make(chan struct{}),
+	}
+
+	if g.ttl > 0 || g.idleTimeout > 0 {
+		interval := o.sweepInterval
+		if interval <= 0 {
+			interval = sweepInterval(g.ttl, g.idleTimeout)
+		}
+		g.wg.Add(1)
+		go g.sweeper(interval)
+	}
+	return g, nil
+}
+
+func sweepInterval(ttl time.Duration, idleTimeout time.Duration) time.Duration {
+	d := ttl
+	if d <= 0 || (idleTimeout > 0 && idleTimeout < d) {
+		d = idleTimeout
+	}
+	d /= 2
+	if d < time.Second {
+		d = time.Second
+	}
+	return d
+}
+
+// closeValue the default OnEvict callback
+func closeValue[K comparable, V any](_ K, val V, _ EvictReason) {
+	if c, ok := any(val).(io.Closer); ok {
+		_ = c.Close()
+	}
+}
+
+// GetOrCreate get the value of the key, the value is created by the factory if not exists, the concurrent
+// calls of the same key share one factory call, the error is not cached.
+func (g *KeyedGroup[K, V]) GetOrCreate(ctx context.Context, key K) (V, error) {
+	var zero V
+	var evicts []evicted[K, V]
+	defer func() { g.evict(evicts) }()
+
+	g.mu.Lock()
+	if g.closed {
+		g.mu.Unlock()
+		return zero, ErrGroupClosed
+	}
+	if e, ok := g.getLocked(key, &evicts); ok {
+		g.mu.Unlock()
+		return e.val, nil
+	}
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		select {
+		case <-c.done:
+			return c.val, c.err
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		}
+	}
+	c := &call[V]{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	g.create(ctx, key, c, &evicts)
+	return c.val, c.err
+}
+
+func (g *KeyedGroup[K, V]) create(ctx context.Context, key K, c *call[V], evicts *[]evicted[K, V]) {
+	panicked := true
+	defer func() {
+		if panicked {
+			c.err = fmt.Errorf("container.group: factory panic of key %v", key)
+		}
+
+		g.mu.Lock()
+		delete(g.calls, key)
+		if c.err == nil {
+			if g.closed {
+				*evicts = append(*evicts, evicted[K, V]{key: key, val: c.val, reason: EvictClosed})
+				c.val, c.err = *new(V), ErrGroupClosed
+			} else {
+				now := g.now()
+				g.entries[key] = g.lru.PushFront(&entry[K, V]{key: key, val: c.val, created: now, lastUsed: now})
+				for g.maxEntries > 0 && g.lru.Len() > g.maxEntries {
+					*evicts = append(*evicts, g.removeLocked(g.lru.Back(), EvictCapacity))
+				}
+				g.metrics.size.WithLabelValues(g.name).Set(float64(g.lru.Len()))
+			}
+		}
+		g.mu.Unlock()
+		close(c.done)
+	}()
+
+	c.val, c.err = g.factory(ctx, key)
+	panicked = false
+}
+
+// Get get the value of the key without creating it
+func (g *KeyedGroup[K, V]) Get(key K) (V, bool) {
+	var evicts []evicted[K, V]
+	defer func() { g.evict(evicts) }()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if e, ok := g.getLocked(key, &evicts); ok {
+		return e.val, true
+	}
+	var zero V
+	return zero, false
+}
+
+// getLocked get the entry and mark it as used, the expired entry is removed
+func (g *KeyedGroup[K, V]) getLocked(key K, evicts *[]evicted[K, V]) (*entry[K, V], bool) {
+	el, ok := g.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*entry[K, V])
+	now := g.now()
+	if reason, expired := g.expired(e, now); expired {
+		*evicts = append(*evicts, g.removeLocked(el, reason))
+		return nil, false
+	}
+	e.lastUsed = now
+	g.lru.MoveToFront(el)
+	return e, true
+}
+
+func (g *KeyedGroup[K, V]) expired(e *entry[K, V], now time.Time) (EvictReason, bool) {
+	if g.ttl > 0 && now.Sub(e.created) >= g.ttl {
+		return EvictExpired, true
+	}
+	if g.idleTimeout > 0 && now.Sub(e.lastUsed) >= g.idleTimeout {
+		return EvictIdle, true
+	}
+	return "", false
+}
+
+func (g *KeyedGroup[K, V]) removeLocked(el *list.Element, reason EvictReason) evicted[K, V] {
+	e := g.lru.Remove(el).(*entry[K, V])
+	delete(g.entries, e.key)
+	g.metrics.size.WithLabelValues(g.name).Set(float64(g.lru.Len()))
+	return evicted[K, V]{key: e.key, val: e.val, reason: reason}
+}
+
+// evict call the OnEvict callback out of the lock
+func (g *KeyedGroup[K, V]) evict(evicts []evicted[K, V]) {
+	for _, e := range evicts {
+		g.metrics.evictions.WithLabelValues(g.name, string(e.reason)).Inc()
+		g.onEvict(e.key, e.val, e.reason)
+	}
+}
+
+// Delete delete and evict the value of the key
+func (g *KeyedGroup[K, V]) Delete(key K) bool {
+	g.mu.Lock()
+	el, ok := g.entries[key]
+	if !ok {
+		g.mu.Unlock()
+		return false
+	}
+	e := g.removeLocked(el, EvictDeleted)
+	g.mu.Unlock()
+
+	g.evict([]evicted[K, V]{e})
+	return true
+}
+
+// Len the number of the values
+func (g *KeyedGroup[K, V]) Len() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.lru.Len()
+}
+
+// Sweep evict the expired values, it is called by the sweeper periodically
+func (g *KeyedGroup[K, V]) Sweep() {
+	var evicts []evicted[K, V]
+	now := g.now()
+
+	g.mu.Lock()
+	// the idle entries are at the back, but the ttl is not in order of use, so check all of them
+	for el := g.lru.Back(); el != nil; {
+		prev := el.Prev()
+		if reason, expired := g.expired(el.Value.(*entry[K, V]), now); expired {
+			evicts = append(evicts, g.removeLocked(el, reason))
+		}
+		el = prev
+	}
+	g.mu.Unlock()
+
+	g.evict(evicts)
+}
+
+func (g *KeyedGroup[K, V]) sweeper(interval time.Duration) {
+	defer g.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-g.stop:
+			return
+		case <-ticker.C:
+			g.Sweep()
+		}
+	}
+}
+
+// Close stop the sweeper and evict all the values, the values being created are evicted when finished.
+func (g *KeyedGroup[K, V]) Close() error {
+	g.mu.Lock()
+	if g.closed {
+		g.mu.Unlock()
+		return nil
+	}
+	g.closed = true
+	close(g.stop)
+	var evicts []evicted[K, V]
+	for el := g.lru.Back(); el != nil; el = g.lru.Back() {
+		evicts = append(evicts, g.removeLocked(el, EvictClosed))
+	}
+	g.mu.Unlock()
+
+	g.wg.Wait()
+	g.evict(evicts)
+	return nil
+}
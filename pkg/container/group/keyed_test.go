@@ -0,0 +1,261 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type resource struct {
+	key    string
+	closed int32
+}
+
+func (r *resource) Close() error {
+	atomic.AddInt32(&r.closed, 1)
+	return nil
+}
+
+// fakeClock the time of the group controlled by the test
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newResource(_ context.Context, key string) (*resource, error) {
+	return &resource{key: key}, nil
+}
+
+func TestKeyedGroup_Dedup(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	g, err := NewKeyedGroup(func(ctx context.Context, key string) (*resource, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return &resource{key: key}, nil
+	}, WithRegisterer(nil))
+	require.NoError(t, err)
+	defer g.Close() //nolint
+
+	const n = 50
+	results := make([]*resource, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v, err := g.GetOrCreate(context.Background(), "tenant-1")
+			assert.NoError(t, err)
+			results[i] = v
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for _, v := range results {
+		assert.Same(t, results[0], v)
+	}
+	v, ok := g.Get("tenant-1")
+	assert.True(t, ok)
+	assert.Same(t, results[0], v)
+	_, ok = g.Get("tenant-2")
+	assert.False(t, ok)
+}
+
+func TestKeyedGroup_Error(t *testing.T) {
+	var calls int32
+	g, err := NewKeyedGroup(func(ctx context.Context, key int) (string, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return "", errors.New("connection refused")
+		}
+		return strconv.Itoa(key), nil
+	}, WithRegisterer(nil))
+	require.NoError(t, err)
+	defer g.Close() //nolint
+
+	_, err = g.GetOrCreate(context.Background(), 1)
+	assert.Error(t, err)
+	// the error is not cached
+	v, err := g.GetOrCreate(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", v)
+
+	// the waiter returns when ctx is done
+	block := make(chan struct{})
+	g2, err := NewKeyedGroup(func(ctx context.Context, key int) (string, error) {
+		<-block
+		return "", nil
+	}, WithRegisterer(nil))
+	require.NoError(t, err)
+	go func() { _, _ = g2.GetOrCreate(context.Background(), 1) }()
+	time.Sleep(20 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = g2.GetOrCreate(ctx, 1)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	close(block)
+	_ = g2.Close()
+
+	// the panic of the factory is propagated, the waiters are released
+	g3, err := NewKeyedGroup(func(ctx context.Context, key int) (string, error) {
+		panic("boom")
+	}, WithRegisterer(nil))
+	require.NoError(t, err)
+	assert.Panics(t, func() { _, _ = g3.GetOrCreate(context.Background(), 1) })
+	assert.Equal(t, 0, g3.Len())
+
+	_, err = NewKeyedGroup[string, int](nil)
+	assert.Error(t, err)
+	_, err = NewKeyedGroup(newResource, WithOnEvict(func(key int, val int, reason EvictReason) {}))
+	assert.Error(t, err)
+}
+
+func TestKeyedGroup_Expire(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	reg := prometheus.NewRegistry()
+	g, err := NewKeyedGroup(newResource, WithName("tenants"), WithRegisterer(reg),
+		WithTTL(time.Hour), WithIdleTimeout(10*time.Minute), WithSweepInterval(time.Hour))
+	require.NoError(t, err)
+	g.now = clock.Now
+	defer g.Close() //nolint
+	ctx := context.Background()
+
+	a, _ := g.GetOrCreate(ctx, "a")
+	b, _ := g.GetOrCreate(ctx, "b")
+	for i := 0; i < 6; i++ {
+		clock.Add(9 * time.Minute)
+		_, _ = g.GetOrCreate(ctx, "a") // keep a active
+		g.Sweep()
+	}
+	// b is idle, a is still active
+	assert.Equal(t, int32(1), atomic.LoadInt32(&b.closed))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&a.closed))
+	assert.Equal(t, 1, g.Len())
+
+	clock.Add(6 * time.Minute) // a reaches the ttl
+	v, _ := g.GetOrCreate(ctx, "a")
+	assert.NotSame(t, a, v)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&a.closed))
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(g.metrics.evictions.WithLabelValues("tenants", string(EvictIdle))))
+	assert.Equal(t, float64(1), testutil.ToFloat64(g.metrics.evictions.WithLabelValues("tenants", string(EvictExpired))))
+	assert.Equal(t, float64(1), testutil.ToFloat64(g.metrics.size.WithLabelValues("tenants")))
+}
+
+func TestKeyedGroup_Sweeper(t *testing.T) {
+	var evicted int32
+	g, err := NewKeyedGroup(newResource, WithRegisterer(nil), WithIdleTimeout(20*time.Millisecond),
+		WithSweepInterval(10*time.Millisecond),
+		WithOnEvict(func(key string, val *resource, reason EvictReason) {
+			assert.Equal(t, EvictIdle, reason)
+			atomic.AddInt32(&evicted, 1)
+		}))
+	require.NoError(t, err)
+	defer g.Close() //nolint
+
+	v, _ := g.GetOrCreate(context.Background(), "a")
+	require.Eventually(t, func() bool { return g.Len() == 0 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&evicted))
+	// the callback replaces the default one
+	assert.Equal(t, int32(0), atomic.LoadInt32(&v.closed))
+}
+
+func TestKeyedGroup_LRU(t *testing.T) {
+	g, err := NewKeyedGroup(newResource, WithRegisterer(nil), WithMaxEntries(3))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	values := map[string]*resource{}
+	for _, key := range []string{"a", "b", "c"} {
+		values[key], _ = g.GetOrCreate(ctx, key)
+	}
+	_, _ = g.GetOrCreate(ctx, "a") // b is the least recently used
+	values["d"], _ = g.GetOrCreate(ctx, "d")
+	assert.Equal(t, 3, g.Len())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&values["b"].closed))
+	_, ok := g.Get("b")
+	assert.False(t, ok)
+
+	assert.True(t, g.Delete("c"))
+	assert.False(t, g.Delete("c"))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&values["c"].closed))
+
+	require.NoError(t, g.Close())
+	require.NoError(t, g.Close())
+	for key, v := range values {
+		assert.Equal(t, int32(1), atomic.LoadInt32(&v.closed), key)
+	}
+	_, err = g.GetOrCreate(ctx, "a")
+	assert.ErrorIs(t, err, ErrGroupClosed)
+}
+
+func TestKeyedGroup_Concurrency(t *testing.T) {
+	var mu sync.Mutex
+	var created []*resource
+	var evictions int32
+	g, err := NewKeyedGroup(func(ctx context.Context, key int) (*resource, error) {
+		r := &resource{key: strconv.Itoa(key)}
+		mu.Lock()
+		created = append(created, r)
+		mu.Unlock()
+		return r, nil
+	}, WithRegisterer(nil), WithMaxEntries(8), WithIdleTimeout(time.Millisecond), WithSweepInterval(time.Millisecond),
+		WithOnEvict(func(key int, val *resource, reason EvictReason) {
+			atomic.AddInt32(&evictions, 1)
+			_ = val.Close()
+		}))
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				key := (i*31 + j) % 20
+				if j%50 == 0 {
+					g.Delete(key)
+					continue
+				}
+				v, err := g.GetOrCreate(context.Background(), key)
+				if assert.NoError(t, err) {
+					assert.Equal(t, strconv.Itoa(key), v.key)
+				}
+				assert.LessOrEqual(t, g.Len(), 8)
+			}
+		}(i)
+	}
+	wg.Wait()
+	require.NoError(t, g.Close())
+
+	// every created value is evicted and closed exactly once
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, int32(len(created)), atomic.LoadInt32(&evictions))
+	for _, r := range created {
+		assert.Equal(t, int32(1), atomic.LoadInt32(&r.closed))
+	}
+}
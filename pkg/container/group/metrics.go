@@ -0,0 +1,51 @@
+package group
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type groupMetrics struct {
+	size      *prometheus.GaugeVec   // group
+	evictions *prometheus.CounterVec // group, reason
+}
+
+func getGroupMetrics(reg prometheus.Registerer) (*groupMetrics, error) {
+	m := &groupMetrics{
+		size: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "container_group_entries",
+			Help: "Number of the values in the keyed group.",
+		}, []string{"group"}),
+		evictions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "container_group_evictions_total",
+			Help: "Total number of the evicted values by reason.",
+		}, []string{"group", "reason"}),
+	}
+	if reg == nil {
+		return m, nil
+	}
+
+	// the metrics are shared by the groups registered to the same registerer
+	var err error
+	if m.size, err = register(reg, m.size); err != nil {
+		return nil, err
+	}
+	if m.evictions, err = register(reg, m.evictions); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func register[T prometheus.Collector](reg prometheus.Registerer, c T) (T, error) {
+	if err := reg.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(T); ok {
+				return existing, nil
+			}
+		}
+		return c, err
+	}
+	return c, nil
+}
@@ -0,0 +1,87 @@
+package group
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// KeyedOption set the keyed group options.
+type KeyedOption func(*keyedOptions)
+
+type keyedOptions struct {
+	name          string
+	ttl           time.Duration
+	idleTimeout   time.Duration
+	sweepInterval time.Duration
+	maxEntries    int
+	onEvict       interface{} // func(K, V, EvictReason)
+	registerer    prometheus.Registerer
+}
+
+func defaultKeyedOptions() *keyedOptions {
+	return &keyedOptions{
+		name:       "default",
+		registerer: prometheus.DefaultRegisterer,
+	}
+}
+
+func (o *keyedOptions) apply(opts ...KeyedOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithName set the name of the group, used as the label of the metrics, default "default"
+func WithName(name string) KeyedOption {
+	return func(o *keyedOptions) {
+		if name != "" {
+			o.name = name
+		}
+	}
+}
+
+// WithTTL set the max lifetime of a value since it is created, default no limit
+func WithTTL(ttl time.Duration) KeyedOption {
+	return func(o *keyedOptions) {
+		o.ttl = ttl
+	}
+}
+
+// WithIdleTimeout set the max time of a value not being used, default no limit
+func WithIdleTimeout(timeout time.Duration) KeyedOption {
+	return func(o *keyedOptions) {
+		o.idleTimeout = timeout
+	}
+}
+
+// WithSweepInterval set the interval of evicting the expired values in background,
+// default half of the smaller one of ttl and idle timeout, min 1s
+func WithSweepInterval(interval time.Duration) KeyedOption {
+	return func(o *keyedOptions) {
+		o.sweepInterval = interval
+	}
+}
+
+// WithMaxEntries set the max number of the values, the least recently used one is evicted when exceeded,
+// default no limit
+func WithMaxEntries(n int) KeyedOption {
+	return func(o *keyedOptions) {
+		o.maxEntries = n
+	}
+}
+
+// WithOnEvict set the callback of the evicted values, it replaces the default one that closes the value
+// implementing io.Closer, the callback is called once for every evicted value and not under the lock.
+func WithOnEvict[K comparable, V any](fn func(key K, val V, reason EvictReason)) KeyedOption {
+	return func(o *keyedOptions) {
+		o.onEvict = fn
+	}
+}
+
+// WithRegisterer set the registerer of the metrics, default prometheus.DefaultRegisterer, nil means not registered
+func WithRegisterer(reg prometheus.Registerer) KeyedOption {
+	return func(o *keyedOptions) {
+		o.registerer = reg
+	}
+}
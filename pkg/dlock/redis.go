@@ -36,6 +36,17 @@ func NewRedisClusterLock(clusterClient *redis.ClusterClient, key string, options
 	return newLocker(clusterClient, key, options...), nil
 }
 
+// NewRedisUniversalLock creates a new RedisLock of the redis client of any mode, e.g. the client of goredis.InitUniversal.
+func NewRedisUniversalLock(client redis.UniversalClient, key string, options ...redsync.Option) (Locker, error) {
+	if client == nil {
+		return nil, errors.New("redis client is nil")
+	}
+	if key == "" {
+		return nil, errors.New("key is empty")
+	}
+	return newLocker(client, key, options...), nil
+}
+
 func newLocker(delegate redis.UniversalClient, key string, options ...redsync.Option) Locker {
 	pool := goredis.NewPool(delegate)
 	rs := redsync.New(pool)
@@ -7,6 +7,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+
 	"github.com/go-dev-frame/sponge/pkg/goredis"
 )
 
@@ -120,3 +124,25 @@ func testLockAndUnlock(initLocker func() Locker, isBlock bool, t *testing.T) {
 
 	waitGroup.Wait()
 }
+
+func TestNewRedisUniversalLock(t *testing.T) {
+	s := miniredis.RunT(t)
+	clients := []redis.UniversalClient{
+		redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{s.Addr()}}),
+	}
+	for _, client := range clients {
+		locker, err := NewRedisUniversalLock(client, "test_universal_lock")
+		assert.NoError(t, err)
+		ok, err := locker.TryLock(context.Background())
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.NoError(t, locker.Unlock(context.Background()))
+		_ = client.Close()
+	}
+
+	_, err := NewRedisUniversalLock(nil, "test_universal_lock")
+	assert.Error(t, err)
+	_, err = NewRedisUniversalLock(clients[0], "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,307 @@
+package dlock
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrNotLocked the lock is not held by the locker, e.g. it has expired or is held by another locker.
+	ErrNotLocked = errors.New("dlock: lock not held")
+	// ErrLockLost the lock is lost while holding it, e.g. the renewal failed.
+	ErrLockLost = errors.New("dlock: lock lost")
+
+	errAlreadyHeld = errors.New("dlock: the lock is already held by the locker")
+)
+
+var (
+	// delete the key only if the value is the token of the holder
+	unlockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+	// extend the ttl only if the value is the token of the holder
+	renewScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+)
+
+// WatchdogOption set the watchdog lock options.
+type WatchdogOption func(*watchdogOptions)
+
+type watchdogOptions struct {
+	ttl           time.Duration
+	renewInterval time.Duration
+	minBackoff    time.Duration
+	maxBackoff    time.Duration
+}
+
+func defaultWatchdogOptions() *watchdogOptions {
+	return &watchdogOptions{
+		ttl:        30 * time.Second,
+		minBackoff: 10 * time.Millisecond,
+		maxBackoff: 500 * time.Millisecond,
+	}
+}
+
+func (o *watchdogOptions) apply(opts ...WatchdogOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.renewInterval <= 0 || o.renewInterval >= o.ttl {
+		o.renewInterval = o.ttl / 3
+	}
+}
+
+// WithTTL set the ttl of the lock, it is renewed while holding the lock, default 30s.
+func WithTTL(ttl time.Duration) WatchdogOption {
+	return func(o *watchdogOptions) {
+		if ttl > 0 {
+			o.ttl = ttl
+		}
+	}
+}
+
+// WithRenewInterval set the interval of the renewal, it must be less than the ttl, default ttl/3.
+func WithRenewInterval(d time.Duration) WatchdogOption {
+	return func(o *watchdogOptions) {
+		o.renewInterval = d
+	}
+}
+
+// WithRetryBackoff set the min and max backoff between the retries of Lock, default 10ms and 500ms.
+func WithRetryBackoff(minBackoff time.Duration, maxBackoff time.Duration) WatchdogOption {
+	return func(o *watchdogOptions) {
+		if minBackoff > 0 {
+			o.minBackoff = minBackoff
+		}
+		if maxBackoff >= o.minBackoff {
+			o.maxBackoff = maxBackoff
+		}
+	}
+}
+
+// Watchdog creates the watchdog locks of a redis client.
+type Watchdog struct {
+	client redis.UniversalClient
+	opts   []WatchdogOption
+}
+
+// NewWatchdog creates a watchdog with the redis client of any mode, opts are the default options of the locks.
+func NewWatchdog(client redis.UniversalClient, opts ...WatchdogOption) *Watchdog {
+	return &Watchdog{client: client, opts: opts}
+}
+
+// NewLock creates a watchdog lock of the key.
+func (w *Watchdog) NewLock(key string, opts ...WatchdogOption) (*WatchdogLock, error) {
+	return NewWatchdogLock(w.client, key, append(w.opts[:len(w.opts):len(w.opts)], opts...)...)
+}
+
+// Do acquires the lock of the key, blocking until acquired or ctx is done, then calls fn. The lock is renewed
+// while fn is running, and it is released when fn returns or panics. If the lock is lost, the ctx of fn is
+// canceled with the cause ErrLockLost, and Do returns ErrLockLost if fn returns nil.
+func (w *Watchdog) Do(ctx context.Context, key string, ttl time.Duration, fn func(ctx context.Context) error) error {
+	l, err := w.NewLock(key, WithTTL(ttl))
+	if err != nil {
+		return err
+	}
+	if err = l.Lock(ctx); err != nil {
+		return err
+	}
+
+	fnCtx, cancel := context.WithCancelCause(ctx)
+	stopped := make(chan struct{})
+	go func() {
+		select {
+		case <-l.Lost():
+			cancel(ErrLockLost)
+		case <-stopped:
+		}
+	}()
+	defer func() {
+		close(stopped)
+		cancel(nil)
+		unlockCtx, unlockCancel := context.WithTimeout(context.WithoutCancel(ctx), l.opts.renewInterval)
+		defer unlockCancel()
+		_ = l.Unlock(unlockCtx)
+	}()
+
+	err = fn(fnCtx)
+	if err == nil && errors.Is(context.Cause(fnCtx), ErrLockLost) {
+		return ErrLockLost
+	}
+	return err
+}
+
+// WatchdogLock is a redis lock whose ownership is fenced by a random token, only the holder can release it.
+// The lock is renewed in the background while the context passed to Lock or TryLock is alive,
+// so a long task doesn't lose the lock by expiration.
+type WatchdogLock struct {
+	client redis.UniversalClient
+	key    string
+	opts   *watchdogOptions
+
+	mu    sync.Mutex
+	token string        // the token of the holder, empty if not held
+	stop  chan struct{} // closed by Unlock to stop the renewal
+	done  chan struct{} // closed when the renewal exits
+	lost  chan struct{} // closed if the lock is lost
+}
+
+// NewWatchdogLock creates a watchdog lock of the key with the redis client of any mode.
+func NewWatchdogLock(client redis.UniversalClient, key string, opts ...WatchdogOption) (*WatchdogLock, error) {
+	if client == nil {
+		return nil, errors.New("redis client is nil")
+	}
+	if key == "" {
+		return nil, errors.New("key is empty")
+	}
+	o := defaultWatchdogOptions()
+	o.apply(opts...)
+	return &WatchdogLock{client: client, key: key, opts: o}, nil
+}
+
+// TryLock tries to acquire the lock without blocking, the lock is renewed until Unlock is called or ctx is done.
+func (l *WatchdogLock) TryLock(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.token != "" {
+		return false, errAlreadyHeld
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return false, err
+	}
+	ok, err := l.client.SetNX(ctx, l.key, token, l.opts.ttl).Result()
+	if err != nil || !ok {
+		return false, err
+	}
+
+	l.token = token
+	l.stop = make(chan struct{})
+	l.done = make(chan struct{})
+	l.lost = make(chan struct{})
+	go l.renew(ctx, token, l.stop, l.done, l.lost)
+	return true, nil
+}
+
+// Lock blocks until the lock is acquired or ctx is done, retrying with backoff.
+func (l *WatchdogLock) Lock(ctx context.Context) error {
+	backoff := l.opts.minBackoff
+	for {
+		ok, err := l.TryLock(ctx)
+		if ok {
+			return nil
+		}
+		if errors.Is(err, errAlreadyHeld) {
+			return err
+		}
+		// the lock is held by others or the redis error is temporary, retry after the backoff with jitter
+		wait := backoff/2 + rand.N(backoff/2+1)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+		if backoff > l.opts.maxBackoff {
+			backoff = l.opts.maxBackoff
+		}
+	}
+}
+
+// Unlock stops the renewal and releases the lock, it returns ErrNotLocked if the lock is not held by the locker.
+func (l *WatchdogLock) Unlock(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.token == "" {
+		return ErrNotLocked
+	}
+
+	token := l.token
+	close(l.stop)
+	<-l.done
+	l.token = ""
+
+	n, err := unlockScript.Run(ctx, l.client, []string{l.key}, token).Int()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotLocked
+	}
+	return nil
+}
+
+// Lost returns a channel that is closed if the lock is lost while holding it, nil if the lock is never acquired.
+func (l *WatchdogLock) Lost() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lost
+}
+
+// Close releases the lock if it is held.
+func (l *WatchdogLock) Close() error {
+	err := l.Unlock(context.Background())
+	if errors.Is(err, ErrNotLocked) {
+		return nil
+	}
+	return err
+}
+
+// renew the lock every renewInterval, the lock is lost if the token doesn't match,
+// or the renewal keeps failing until the ttl is exceeded.
+func (l *WatchdogLock) renew(ctx context.Context, token string, stop <-chan struct{}, done chan<- struct{}, lost chan<- struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(l.opts.renewInterval)
+	defer ticker.Stop()
+	lastRenewal := time.Now()
+	ttl := l.opts.ttl.Milliseconds()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ctx.Done():
+			// the holder is gone, the lock expires by ttl
+			return
+		case <-ticker.C:
+		}
+
+		renewCtx, cancel := context.WithTimeout(ctx, l.opts.renewInterval)
+		n, err := renewScript.Run(renewCtx, l.client, []string{l.key}, token, ttl).Int()
+		cancel()
+		switch {
+		case err == nil && n == 1:
+			lastRenewal = time.Now()
+		case err == nil || time.Since(lastRenewal) >= l.opts.ttl:
+			close(lost)
+			return
+		}
+	}
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := crand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
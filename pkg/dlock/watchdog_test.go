@@ -0,0 +1,246 @@
+package dlock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func newWatchdogTest(t *testing.T) (*miniredis.Miniredis, redis.UniversalClient) {
+	s := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return s, client
+}
+
+func TestNewWatchdogLock(t *testing.T) {
+	_, client := newWatchdogTest(t)
+
+	_, err := NewWatchdogLock(nil, "foo")
+	assert.Error(t, err)
+	_, err = NewWatchdogLock(client, "")
+	assert.Error(t, err)
+
+	l, err := NewWatchdogLock(client, "foo", WithTTL(time.Second), WithRenewInterval(2*time.Second),
+		WithRetryBackoff(time.Millisecond, 100*time.Millisecond))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second, l.opts.ttl)
+	assert.Equal(t, time.Second/3, l.opts.renewInterval) // greater than ttl, use the default
+	assert.Equal(t, time.Millisecond, l.opts.minBackoff)
+	assert.Equal(t, 100*time.Millisecond, l.opts.maxBackoff)
+
+	var _ Locker = l
+}
+
+func TestWatchdogLock_Renewal(t *testing.T) {
+	s, client := newWatchdogTest(t)
+	ctx := context.Background()
+
+	l, _ := NewWatchdogLock(client, "foo", WithTTL(time.Second), WithRenewInterval(20*time.Millisecond))
+	ok, err := l.TryLock(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// the ttl is extended by the renewal
+	s.FastForward(900 * time.Millisecond)
+	assert.Eventually(t, func() bool { return s.TTL("foo") > 900*time.Millisecond }, time.Second, 10*time.Millisecond)
+	s.FastForward(900 * time.Millisecond)
+	assert.Eventually(t, func() bool { return s.TTL("foo") > 900*time.Millisecond }, time.Second, 10*time.Millisecond)
+	assert.True(t, s.Exists("foo"))
+
+	// already held
+	ok, err = l.TryLock(ctx)
+	assert.Error(t, err)
+	assert.False(t, ok)
+	assert.Error(t, l.Lock(ctx))
+
+	assert.NoError(t, l.Unlock(ctx))
+	assert.False(t, s.Exists("foo"))
+	assert.ErrorIs(t, l.Unlock(ctx), ErrNotLocked)
+	assert.NoError(t, l.Close())
+}
+
+func TestWatchdogLock_StopRenewal(t *testing.T) {
+	s, client := newWatchdogTest(t)
+
+	// the renewal stops when the ctx of the holder is done, the lock expires by the ttl
+	ctx, cancel := context.WithCancel(context.Background())
+	l, _ := NewWatchdogLock(client, "foo", WithTTL(time.Second), WithRenewInterval(20*time.Millisecond))
+	assert.NoError(t, l.Lock(ctx))
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	s.FastForward(time.Second)
+	assert.False(t, s.Exists("foo"))
+
+	// the lock is expired, and acquired by others
+	other, _ := NewWatchdogLock(client, "foo")
+	ok, err := other.TryLock(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.ErrorIs(t, l.Unlock(context.Background()), ErrNotLocked)
+	assert.True(t, s.Exists("foo"))
+	assert.NoError(t, other.Close())
+}
+
+func TestWatchdogLock_Contention(t *testing.T) {
+	_, client := newWatchdogTest(t)
+	ctx := context.Background()
+
+	var (
+		wg      sync.WaitGroup
+		holders int32
+		maxHeld int32
+		count   int32
+	)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l, _ := NewWatchdogLock(client, "foo", WithTTL(time.Second), WithRetryBackoff(time.Millisecond, 10*time.Millisecond))
+			if err := l.Lock(ctx); err != nil {
+				t.Error(err)
+				return
+			}
+			n := atomic.AddInt32(&holders, 1)
+			for {
+				m := atomic.LoadInt32(&maxHeld)
+				if n <= m || atomic.CompareAndSwapInt32(&maxHeld, m, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&count, 1)
+			atomic.AddInt32(&holders, -1)
+			assert.NoError(t, l.Unlock(ctx))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(5), count)
+	assert.Equal(t, int32(1), maxHeld)
+
+	// the lock is held by others, Lock blocks until ctx is done
+	l1, _ := NewWatchdogLock(client, "bar")
+	l2, _ := NewWatchdogLock(client, "bar")
+	assert.NoError(t, l1.Lock(ctx))
+	ok, err := l2.TryLock(ctx)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, l2.Lock(timeoutCtx), context.DeadlineExceeded)
+	assert.NoError(t, l1.Close())
+	assert.NoError(t, l2.Lock(ctx))
+	assert.NoError(t, l2.Close())
+}
+
+func TestWatchdogLock_UnlockByNonOwner(t *testing.T) {
+	s, client := newWatchdogTest(t)
+	ctx := context.Background()
+
+	l, _ := NewWatchdogLock(client, "foo", WithTTL(time.Second))
+	assert.NoError(t, l.Lock(ctx))
+
+	// a locker doesn't hold the lock can't release it
+	other, _ := NewWatchdogLock(client, "foo")
+	assert.ErrorIs(t, other.Unlock(ctx), ErrNotLocked)
+	assert.True(t, s.Exists("foo"))
+
+	// the key is taken over by others, the token doesn't match
+	assert.NoError(t, s.Set("foo", "other token"))
+	assert.ErrorIs(t, l.Unlock(ctx), ErrNotLocked)
+	v, _ := s.Get("foo")
+	assert.Equal(t, "other token", v)
+}
+
+func TestWatchdogLock_Lost(t *testing.T) {
+	s, client := newWatchdogTest(t)
+	ctx := context.Background()
+
+	l, _ := NewWatchdogLock(client, "foo", WithTTL(time.Second), WithRenewInterval(20*time.Millisecond))
+	assert.Nil(t, l.Lost())
+	assert.NoError(t, l.Lock(ctx))
+
+	s.Del("foo")
+	select {
+	case <-l.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("the lost lock is not detected")
+	}
+	assert.ErrorIs(t, l.Unlock(ctx), ErrNotLocked)
+
+	// the renewal keeps failing until the ttl is exceeded
+	l, _ = NewWatchdogLock(client, "foo", WithTTL(100*time.Millisecond), WithRenewInterval(10*time.Millisecond))
+	assert.NoError(t, l.Lock(ctx))
+	s.SetError("server error")
+	select {
+	case <-l.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("the lost lock is not detected")
+	}
+	s.SetError("")
+}
+
+func TestWatchdog_Do(t *testing.T) {
+	s, client := newWatchdogTest(t)
+	ctx := context.Background()
+	w := NewWatchdog(client, WithRenewInterval(20*time.Millisecond))
+
+	// fn is called with the lock held, and the lock is released after fn returns
+	err := w.Do(ctx, "foo", time.Second, func(ctx context.Context) error {
+		assert.True(t, s.Exists("foo"))
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.False(t, s.Exists("foo"))
+
+	// the error of fn is returned
+	errFn := errors.New("fn error")
+	err = w.Do(ctx, "foo", time.Second, func(ctx context.Context) error {
+		return errFn
+	})
+	assert.ErrorIs(t, err, errFn)
+	assert.False(t, s.Exists("foo"))
+
+	// the lock is released when fn panics
+	assert.Panics(t, func() {
+		_ = w.Do(ctx, "foo", time.Second, func(ctx context.Context) error {
+			panic("fn panic")
+		})
+	})
+	assert.False(t, s.Exists("foo"))
+
+	// the ctx of fn is canceled when the lock is lost
+	err = w.Do(ctx, "foo", time.Second, func(ctx context.Context) error {
+		s.Del("foo")
+		select {
+		case <-ctx.Done():
+			assert.ErrorIs(t, context.Cause(ctx), ErrLockLost)
+		case <-time.After(time.Second):
+			t.Error("the ctx of fn is not canceled")
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrLockLost)
+
+	// the lock is held by others
+	l, _ := w.NewLock("foo")
+	assert.NoError(t, l.Lock(ctx))
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	err = w.Do(timeoutCtx, "foo", time.Second, func(ctx context.Context) error {
+		t.Error("fn is called without the lock")
+		return nil
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NoError(t, l.Close())
+
+	// invalid key
+	assert.Error(t, w.Do(ctx, "", time.Second, func(ctx context.Context) error { return nil }))
+}
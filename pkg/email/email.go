@@ -0,0 +1,91 @@
+// Package email is a templated email sender, it supports the SMTP and HTTP API providers, sending synchronously,
+// or queueing with retry, dead-lettering and the rate limit per recipient domain.
+package email
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrNoRecipient the message has no recipient
+	ErrNoRecipient = errors.New("email has no recipient")
+	// ErrNoSender the message has no sender address
+	ErrNoSender = errors.New("email has no sender address")
+	// ErrQueueFull the queue is full
+	ErrQueueFull = errors.New("email queue is full")
+)
+
+// Message is an email, if Template is not empty, the Subject, Text and HTML are rendered by the template with Data.
+type Message struct {
+	ID      string            `json:"id,omitempty"`
+	From    string            `json:"from,omitempty"`
+	To      []string          `json:"to"`
+	Cc      []string          `json:"cc,omitempty"`
+	Bcc     []string          `json:"bcc,omitempty"`
+	ReplyTo string            `json:"replyTo,omitempty"`
+	Subject string            `json:"subject"`
+	Text    string            `json:"text,omitempty"`
+	HTML    string            `json:"html,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+
+	Template string      `json:"-"` // template name, e.g. TemplatePasswordReset
+	Data     interface{} `json:"-"` // template data, e.g. *PasswordResetData
+
+	Attempts  int    `json:"attempts,omitempty"`  // the number of sending attempts
+	LastError string `json:"lastError,omitempty"` // the last error of sending, it is set when dead-lettered
+}
+
+// Recipients returns all the recipients of To, Cc and Bcc
+func (m *Message) Recipients() []string {
+	rcpts := make([]string, 0, len(m.To)+len(m.Cc)+len(m.Bcc))
+	rcpts = append(rcpts, m.To...)
+	rcpts = append(rcpts, m.Cc...)
+	return append(rcpts, m.Bcc...)
+}
+
+func (m *Message) validate() error {
+	if m.From == "" {
+		return ErrNoSender
+	}
+	if len(m.Recipients()) == 0 {
+		return ErrNoRecipient
+	}
+	return nil
+}
+
+// Provider send the email, e.g. SMTP, SendGrid
+type Provider interface {
+	Send(ctx context.Context, msg *Message) error
+	Name() string
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent mark the error as permanent, the message is not retried and it is dead-lettered,
+// e.g. the recipient does not exist.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether the error is permanent
+func IsPermanent(err error) bool {
+	var pe *permanentError
+	return errors.As(err, &pe)
+}
+
+func domainOf(address string) string {
+	if i := strings.LastIndex(address, "@"); i >= 0 {
+		address = address[i+1:]
+	}
+	return strings.ToLower(strings.TrimRight(address, "> "))
+}
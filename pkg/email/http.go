@@ -0,0 +1,117 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// SendGridEndpoint the mail send API of SendGrid
+const SendGridEndpoint = "https://api.sendgrid.com/v3/mail/send"
+
+// HTTPProvider send the email by the HTTP API compatible with SendGrid v3 mail send
+type HTTPProvider struct {
+	endpoint string
+	apiKey   string
+	client   *http.Client
+}
+
+// NewHTTPProvider create a HTTP API provider, the apiKey is sent by the header "Authorization: Bearer <apiKey>",
+// if client is nil, a client with 10s timeout is used.
+func NewHTTPProvider(endpoint string, apiKey string, client *http.Client) *HTTPProvider {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &HTTPProvider{endpoint: endpoint, apiKey: apiKey, client: client}
+}
+
+// Name returns the name of the provider
+func (p *HTTPProvider) Name() string {
+	return "http"
+}
+
+type apiAddress struct {
+	Email string `json:"email"`
+}
+
+type apiContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type apiPersonalization struct {
+	To  []apiAddress `json:"to"`
+	Cc  []apiAddress `json:"cc,omitempty"`
+	Bcc []apiAddress `json:"bcc,omitempty"`
+}
+
+type apiRequest struct {
+	Personalizations []apiPersonalization `json:"personalizations"`
+	From             apiAddress           `json:"from"`
+	ReplyTo          *apiAddress          `json:"reply_to,omitempty"`
+	Subject          string               `json:"subject"`
+	Content          []apiContent         `json:"content"`
+	Headers          map[string]string    `json:"headers,omitempty"`
+}
+
+func toAPIAddresses(addresses []string) []apiAddress {
+	var out []apiAddress
+	for _, a := range addresses {
+		out = append(out, apiAddress{Email: a})
+	}
+	return out
+}
+
+// Send the email, the response of 4xx except 429 is a permanent error
+func (p *HTTPProvider) Send(ctx context.Context, msg *Message) error {
+	req := &apiRequest{
+		Personalizations: []apiPersonalization{{
+			To:  toAPIAddresses(msg.To),
+			Cc:  toAPIAddresses(msg.Cc),
+			Bcc: toAPIAddresses(msg.Bcc),
+		}},
+		From:    apiAddress{Email: msg.From},
+		Subject: msg.Subject,
+		Headers: msg.Headers,
+	}
+	if msg.ReplyTo != "" {
+		req.ReplyTo = &apiAddress{Email: msg.ReplyTo}
+	}
+	if msg.Text != "" {
+		req.Content = append(req.Content, apiContent{Type: "text/plain", Value: msg.Text})
+	}
+	if msg.HTML != "" {
+		req.Content = append(req.Content, apiContent{Type: "text/html", Value: msg.HTML})
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return Permanent(err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return Permanent(err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = fmt.Errorf("email api response status %d: %s", resp.StatusCode, bytes.TrimSpace(data))
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		return Permanent(err)
+	}
+	return err
+}
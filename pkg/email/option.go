@@ -0,0 +1,118 @@
+package email
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+)
+
+// SenderOption set the sender options.
+type SenderOption func(*senderOptions)
+
+type senderOptions struct {
+	from        string
+	templates   *Templates
+	queue       Queue
+	concurrency int
+	maxAttempts int
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	sendTimeout time.Duration
+	domainLimit rate.Limit
+	domainBurst int
+	registerer  prometheus.Registerer
+}
+
+func defaultSenderOptions() *senderOptions {
+	return &senderOptions{
+		concurrency: 1,
+		maxAttempts: 5,
+		minBackoff:  time.Second,
+		maxBackoff:  time.Minute,
+		sendTimeout: 30 * time.Second,
+		domainLimit: rate.Inf,
+		registerer:  prometheus.DefaultRegisterer,
+	}
+}
+
+func (o *senderOptions) apply(opts ...SenderOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithFrom set the default sender address of the messages whose From is empty
+func WithFrom(from string) SenderOption {
+	return func(o *senderOptions) {
+		o.from = from
+	}
+}
+
+// WithTemplates set the templates, default is DefaultTemplates()
+func WithTemplates(t *Templates) SenderOption {
+	return func(o *senderOptions) {
+		o.templates = t
+	}
+}
+
+// WithQueue set the queue of Enqueue, default is NewMemoryQueue(1000), use NewRedisQueue to persist the messages
+func WithQueue(q Queue) SenderOption {
+	return func(o *senderOptions) {
+		o.queue = q
+	}
+}
+
+// WithConcurrency set the number of the goroutines sending the queued messages, default 1
+func WithConcurrency(n int) SenderOption {
+	return func(o *senderOptions) {
+		if n > 0 {
+			o.concurrency = n
+		}
+	}
+}
+
+// WithRetry set the max attempts of sending a queued message and the exponential backoff between the attempts,
+// the message is dead-lettered after the attempts are exhausted, default 5 attempts, 1s and 1m.
+func WithRetry(maxAttempts int, minBackoff time.Duration, maxBackoff time.Duration) SenderOption {
+	return func(o *senderOptions) {
+		if maxAttempts > 0 {
+			o.maxAttempts = maxAttempts
+		}
+		if minBackoff > 0 {
+			o.minBackoff = minBackoff
+		}
+		if maxBackoff >= o.minBackoff {
+			o.maxBackoff = maxBackoff
+		}
+	}
+}
+
+// WithSendTimeout set the timeout of each sending attempt, default 30s
+func WithSendTimeout(timeout time.Duration) SenderOption {
+	return func(o *senderOptions) {
+		if timeout > 0 {
+			o.sendTimeout = timeout
+		}
+	}
+}
+
+// WithDomainRateLimit limit the sending rate per recipient domain, e.g. gmail.com, default unlimited
+func WithDomainRateLimit(perSecond float64, burst int) SenderOption {
+	return func(o *senderOptions) {
+		if perSecond > 0 {
+			o.domainLimit = rate.Limit(perSecond)
+			o.domainBurst = burst
+			if o.domainBurst < 1 {
+				o.domainBurst = 1
+			}
+		}
+	}
+}
+
+// WithRegisterer set the registerer of the metrics, default prometheus.DefaultRegisterer, nil means not registered
+func WithRegisterer(reg prometheus.Registerer) SenderOption {
+	return func(o *senderOptions) {
+		o.registerer = reg
+	}
+}
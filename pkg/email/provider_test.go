@@ -0,0 +1,88 @@
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPProvider(t *testing.T) {
+	var got apiRequest
+	status := http.StatusAccepted
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer key", r.Header.Get("Authorization"))
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"errors":[]}`))
+	}))
+	defer srv.Close()
+
+	p := NewHTTPProvider(srv.URL, "key", nil)
+	assert.Equal(t, "http", p.Name())
+	msg := &Message{From: "noreply@example.com", To: []string{"tom@example.com"}, Bcc: []string{"audit@example.com"},
+		ReplyTo: "support@example.com", Subject: "subject", Text: "text", HTML: "<p>html</p>"}
+	require.NoError(t, p.Send(context.Background(), msg))
+	assert.Equal(t, "tom@example.com", got.Personalizations[0].To[0].Email)
+	assert.Equal(t, "audit@example.com", got.Personalizations[0].Bcc[0].Email)
+	assert.Equal(t, "support@example.com", got.ReplyTo.Email)
+	assert.Equal(t, []apiContent{{"text/plain", "text"}, {"text/html", "<p>html</p>"}}, got.Content)
+
+	status = http.StatusBadRequest
+	err := p.Send(context.Background(), msg)
+	assert.True(t, IsPermanent(err), err)
+	for _, status = range []int{http.StatusTooManyRequests, http.StatusServiceUnavailable} {
+		err = p.Send(context.Background(), msg)
+		assert.Error(t, err)
+		assert.False(t, IsPermanent(err))
+	}
+}
+
+func TestBuildMIME(t *testing.T) {
+	msg := &Message{
+		ID:      "123",
+		From:    "noreply@example.com",
+		To:      []string{"a@example.com", "b@example.com"},
+		Cc:      []string{"c@example.com"},
+		Bcc:     []string{"d@example.com"},
+		Subject: "Héllo",
+		Text:    "text",
+		HTML:    "<p>html</p>",
+		Headers: map[string]string{"X-Tag": "reset"},
+	}
+	data := string(buildMIME(msg))
+	assert.Contains(t, data, "To: a@example.com, b@example.com\r\n")
+	assert.Contains(t, data, "Cc: c@example.com\r\n")
+	assert.NotContains(t, data, "d@example.com") // bcc is not in the header
+	assert.Contains(t, data, "Subject: =?utf-8?q?H=C3=A9llo?=\r\n")
+	assert.Contains(t, data, "Message-ID: <123@example.com>\r\n")
+	assert.Contains(t, data, "X-Tag: reset\r\n")
+	assert.Contains(t, data, "multipart/alternative")
+	assert.Contains(t, data, "Content-Type: text/plain; charset=utf-8")
+	assert.Contains(t, data, "Content-Type: text/html; charset=utf-8")
+
+	msg.Text = ""
+	data = string(buildMIME(msg))
+	assert.False(t, strings.Contains(data, "multipart"))
+	assert.Contains(t, data, "Content-Type: text/html; charset=utf-8")
+
+	assert.Equal(t, []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com"}, msg.Recipients())
+	assert.Equal(t, "example.com", domainOf("Tom <tom@Example.COM>"))
+}
+
+func TestPermanent(t *testing.T) {
+	assert.Nil(t, Permanent(nil))
+	err := Permanent(errors.New("rejected"))
+	assert.True(t, IsPermanent(err))
+	assert.EqualError(t, err, "rejected")
+	assert.False(t, IsPermanent(errors.New("timeout")))
+	p := NewSMTPProvider(SMTPConfig{Host: "localhost"})
+	assert.Equal(t, 587, p.cfg.Port)
+	assert.Equal(t, "smtp", p.Name())
+}
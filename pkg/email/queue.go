@@ -0,0 +1,158 @@
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Queue store the messages to be sent and the dead letters
+type Queue interface {
+	// Push a message to the queue
+	Push(ctx context.Context, msg *Message) error
+	// Pop a message from the queue, it blocks until a message is available or ctx is done
+	Pop(ctx context.Context) (*Message, error)
+	// PushDead push a message that failed to be sent to the dead letter list
+	PushDead(ctx context.Context, msg *Message) error
+	// DeadLetters returns the dead letters
+	DeadLetters(ctx context.Context) ([]*Message, error)
+	// Len returns the number of the messages in the queue
+	Len(ctx context.Context) (int, error)
+}
+
+// ------------------------------------------------------------------------------------------
+
+type memoryQueue struct {
+	ch chan *Message
+
+	mu      sync.Mutex
+	dead    []*Message
+	maxDead int
+}
+
+// NewMemoryQueue create an in-memory queue, Push returns ErrQueueFull if there are size messages in the queue,
+// the dead letter list keeps the latest size messages, the messages are lost when the process exits.
+func NewMemoryQueue(size int) Queue {
+	if size <= 0 {
+		size = 1000
+	}
+	return &memoryQueue{ch: make(chan *Message, size), maxDead: size}
+}
+
+func (q *memoryQueue) Push(_ context.Context, msg *Message) error {
+	select {
+	case q.ch <- msg:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+func (q *memoryQueue) Pop(ctx context.Context) (*Message, error) {
+	select {
+	case msg := <-q.ch:
+		return msg, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (q *memoryQueue) PushDead(_ context.Context, msg *Message) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.dead = append(q.dead, msg)
+	if len(q.dead) > q.maxDead {
+		q.dead = q.dead[len(q.dead)-q.maxDead:]
+	}
+	return nil
+}
+
+func (q *memoryQueue) DeadLetters(_ context.Context) ([]*Message, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]*Message{}, q.dead...), nil
+}
+
+func (q *memoryQueue) Len(_ context.Context) (int, error) {
+	return len(q.ch), nil
+}
+
+// ------------------------------------------------------------------------------------------
+
+type redisQueue struct {
+	client  redis.UniversalClient
+	key     string
+	deadKey string
+}
+
+// NewRedisQueue create a queue persisted in redis list, the messages are kept when the process restarts,
+// the dead letters are in the list <key>:dead.
+func NewRedisQueue(client redis.UniversalClient, key string) Queue {
+	return &redisQueue{client: client, key: key, deadKey: key + ":dead"}
+}
+
+func (q *redisQueue) Push(ctx context.Context, msg *Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return q.client.RPush(ctx, q.key, data).Err()
+}
+
+func (q *redisQueue) Pop(ctx context.Context) (*Message, error) {
+	for {
+		// block for a while to check whether ctx is done
+		result, err := q.client.BLPop(ctx, time.Second, q.key).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				continue
+			}
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, err
+		}
+
+		msg := &Message{}
+		if err = json.Unmarshal([]byte(result[1]), msg); err != nil {
+			return nil, err
+		}
+		return msg, nil
+	}
+}
+
+func (q *redisQueue) PushDead(ctx context.Context, msg *Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return q.client.RPush(ctx, q.deadKey, data).Err()
+}
+
+func (q *redisQueue) DeadLetters(ctx context.Context) ([]*Message, error) {
+	values, err := q.client.LRange(ctx, q.deadKey, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	msgs := make([]*Message, 0, len(values))
+	for _, v := range values {
+		msg := &Message{}
+		if err = json.Unmarshal([]byte(v), msg); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+func (q *redisQueue) Len(ctx context.Context) (int, error) {
+	n, err := q.client.LLen(ctx, q.key).Result()
+	return int(n), err
+}
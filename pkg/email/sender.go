@@ -0,0 +1,267 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+
+	"github.com/go-dev-frame/sponge/pkg/logger"
+	"github.com/go-dev-frame/sponge/pkg/utils"
+)
+
+type senderMetrics struct {
+	sent       *prometheus.CounterVec   // provider, result
+	retries    *prometheus.CounterVec   // provider
+	deadLetter *prometheus.CounterVec   // provider
+	duration   *prometheus.HistogramVec // provider
+}
+
+func newSenderMetrics(reg prometheus.Registerer) (*senderMetrics, error) {
+	m := &senderMetrics{
+		sent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "email_sent_total",
+			Help: "Total number of email sending attempts.",
+		}, []string{"provider", "result"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "email_retries_total",
+			Help: "Total number of email sending retries.",
+		}, []string{"provider"}),
+		deadLetter: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "email_dead_letters_total",
+			Help: "Total number of emails moved to the dead letter list.",
+		}, []string{"provider"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "email_send_duration_seconds",
+			Help:    "Duration of email sending attempts.",
+			Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		}, []string{"provider"}),
+	}
+	if reg == nil {
+		return m, nil
+	}
+
+	// the metrics are shared by the senders registered to the same registerer
+	var err error
+	if m.sent, err = register(reg, m.sent); err != nil {
+		return nil, err
+	}
+	if m.retries, err = register(reg, m.retries); err != nil {
+		return nil, err
+	}
+	if m.deadLetter, err = register(reg, m.deadLetter); err != nil {
+		return nil, err
+	}
+	if m.duration, err = register(reg, m.duration); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func register[T prometheus.Collector](reg prometheus.Registerer, c T) (T, error) {
+	if err := reg.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(T); ok {
+				return existing, nil
+			}
+		}
+		return c, err
+	}
+	return c, nil
+}
+
+// ------------------------------------------------------------------------------------------
+
+// Sender render and send the emails by the provider, Send is synchronous, Enqueue push the message to the
+// queue, and the messages are sent by Run in the background with retry, the message that fails to be sent
+// after the attempts are exhausted is moved to the dead letter list.
+//
+// Sender implements app.Worker, it can be started from the app runner by app.WithWorkers(sender).
+type Sender struct {
+	provider Provider
+	opts     *senderOptions
+	metrics  *senderMetrics
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter // recipient domain --> limiter
+}
+
+// NewSender create a sender
+func NewSender(provider Provider, opts ...SenderOption) (*Sender, error) {
+	if provider == nil {
+		return nil, errors.New("email provider is nil")
+	}
+	o := defaultSenderOptions()
+	o.apply(opts...)
+	if o.templates == nil {
+		o.templates = DefaultTemplates()
+	}
+	if o.queue == nil {
+		o.queue = NewMemoryQueue(1000)
+	}
+
+	m, err := newSenderMetrics(o.registerer)
+	if err != nil {
+		return nil, err
+	}
+	return &Sender{
+		provider: provider,
+		opts:     o,
+		metrics:  m,
+		limiters: map[string]*rate.Limiter{},
+	}, nil
+}
+
+// Send render and send the message synchronously, it is not retried.
+func (s *Sender) Send(ctx context.Context, msg *Message) error {
+	if err := s.prepare(msg); err != nil {
+		return err
+	}
+	return s.send(ctx, msg)
+}
+
+// Enqueue render the message and push it to the queue, it is sent by Run in the background.
+func (s *Sender) Enqueue(msg *Message) error {
+	if err := s.prepare(msg); err != nil {
+		return err
+	}
+	return s.opts.queue.Push(context.Background(), msg)
+}
+
+// DeadLetters returns the messages that failed to be sent
+func (s *Sender) DeadLetters(ctx context.Context) ([]*Message, error) {
+	return s.opts.queue.DeadLetters(ctx)
+}
+
+// Run send the queued messages until ctx is canceled, the message being sent is finished before returning.
+func (s *Sender) Run(ctx context.Context) error {
+	wg := sync.WaitGroup{}
+	for i := 0; i < s.opts.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.loop(ctx)
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// String returns the name of the worker
+func (s *Sender) String() string {
+	return "email sender"
+}
+
+func (s *Sender) loop(ctx context.Context) {
+	for {
+		msg, err := s.opts.queue.Pop(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logger.Warn("pop email from queue error", logger.Err(err))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		// the message is finished even if ctx is canceled while sending
+		s.deliver(context.WithoutCancel(ctx), msg)
+	}
+}
+
+// deliver the queued message with retry, and move it to the dead letter list if it fails
+func (s *Sender) deliver(ctx context.Context, msg *Message) {
+	err := utils.Retry(ctx, func(ctx context.Context) error {
+		if msg.Attempts > 0 {
+			s.metrics.retries.WithLabelValues(s.provider.Name()).Inc()
+		}
+		msg.Attempts++
+		return s.send(ctx, msg)
+	},
+		utils.WithRetryAttempts(s.opts.maxAttempts),
+		utils.WithRetryInterval(s.opts.minBackoff, s.opts.maxBackoff),
+		utils.WithRetryIf(func(err error) bool { return !IsPermanent(err) }),
+	)
+	if err == nil {
+		return
+	}
+
+	msg.LastError = err.Error()
+	s.metrics.deadLetter.WithLabelValues(s.provider.Name()).Inc()
+	logger.Error("email is moved to the dead letter list", logger.String("id", msg.ID), logger.Any("to", msg.To),
+		logger.String("subject", msg.Subject), logger.Int("attempts", msg.Attempts), logger.Err(err))
+	if err = s.opts.queue.PushDead(ctx, msg); err != nil {
+		logger.Error("push email to the dead letter list error", logger.String("id", msg.ID), logger.Err(err))
+	}
+}
+
+func (s *Sender) prepare(msg *Message) error {
+	if msg == nil {
+		return errors.New("email message is nil")
+	}
+	if err := s.opts.templates.Render(msg); err != nil {
+		return err
+	}
+	if msg.From == "" {
+		msg.From = s.opts.from
+	}
+	if msg.ID == "" {
+		msg.ID = utils.NewUUIDv7()
+	}
+	return msg.validate()
+}
+
+func (s *Sender) send(ctx context.Context, msg *Message) error {
+	if err := s.wait(ctx, msg); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.opts.sendTimeout)
+	defer cancel()
+	start := time.Now()
+	err := s.provider.Send(ctx, msg)
+	s.metrics.duration.WithLabelValues(s.provider.Name()).Observe(time.Since(start).Seconds())
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	s.metrics.sent.WithLabelValues(s.provider.Name(), result).Inc()
+	return err
+}
+
+// wait for the rate limiters of the recipient domains
+func (s *Sender) wait(ctx context.Context, msg *Message) error {
+	if s.opts.domainLimit == rate.Inf {
+		return nil
+	}
+	seen := map[string]bool{}
+	for _, rcpt := range msg.Recipients() {
+		domain := domainOf(rcpt)
+		if seen[domain] {
+			continue
+		}
+		seen[domain] = true
+		if err := s.limiter(domain).Wait(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Sender) limiter(domain string) *rate.Limiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l, ok := s.limiters[domain]
+	if !ok {
+		l = rate.NewLimiter(s.opts.domainLimit, s.opts.domainBurst)
+		s.limiters[domain] = l
+	}
+	return l
+}
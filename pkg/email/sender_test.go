@@ -0,0 +1,259 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeProvider struct {
+	mu    sync.Mutex
+	sent  []*Message
+	errs  []error // the errors returned in order, nil if exhausted
+	calls int
+}
+
+func (p *fakeProvider) Send(_ context.Context, msg *Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	if len(p.errs) > 0 {
+		err := p.errs[0]
+		p.errs = p.errs[1:]
+		if err != nil {
+			return err
+		}
+	}
+	cp := *msg
+	p.sent = append(p.sent, &cp)
+	return nil
+}
+
+func (p *fakeProvider) Name() string { return "fake" }
+
+func (p *fakeProvider) getCalls() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
+
+func (p *fakeProvider) getSent() []*Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*Message{}, p.sent...)
+}
+
+func newTestSender(t *testing.T, p Provider, opts ...SenderOption) (*Sender, *prometheus.Registry) {
+	reg := prometheus.NewRegistry()
+	opts = append([]SenderOption{
+		WithFrom("noreply@example.com"),
+		WithRegisterer(reg),
+		WithRetry(3, time.Millisecond, 5*time.Millisecond),
+	}, opts...)
+	s, err := NewSender(p, opts...)
+	require.NoError(t, err)
+	return s, reg
+}
+
+func runSender(t *testing.T, s *Sender) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.ErrorIs(t, s.Run(ctx), context.Canceled)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func TestTemplates(t *testing.T) {
+	msg := &Message{
+		Template: TemplatePasswordReset,
+		Data:     &PasswordResetData{Name: "Tom", Link: "https://example.com/reset?token=a&b=<c>", ExpireMinutes: 30},
+	}
+	require.NoError(t, DefaultTemplates().Render(msg))
+	assert.Equal(t, "Reset your password", msg.Subject)
+	assert.Contains(t, msg.Text, "Hi Tom,")
+	assert.Contains(t, msg.Text, "https://example.com/reset?token=a&b=<c>") // not escaped in text
+	assert.Contains(t, msg.Text, "expires in 30 minutes")
+	assert.Contains(t, msg.HTML, `href="https://example.com/reset?token=a&amp;b=%3cc%3e"`)
+	assert.Empty(t, msg.Template)
+
+	msg = &Message{Template: TemplateNotification, Data: &NotificationData{Name: "Tom", Title: "Order shipped", Content: "<b>ok</b>"}}
+	require.NoError(t, DefaultTemplates().Render(msg))
+	assert.Equal(t, "Order shipped", msg.Subject)
+	assert.Contains(t, msg.HTML, "&lt;b&gt;ok&lt;/b&gt;")
+
+	// custom templates, the html is optional
+	fsys := fstest.MapFS{
+		"tpl/welcome.txt": {Data: []byte(`{{define "subject"}}Welcome {{.name}}{{end}}Hello {{.name}}`)},
+		"tpl/bad.html":    {Data: []byte(`{{.name`)},
+	}
+	_, err := NewTemplates(fsys, "tpl/*")
+	assert.Error(t, err)
+	fsys["tpl/bad.html"] = &fstest.MapFile{Data: []byte(`ok`)}
+	_, err = NewTemplates(fsys, "tpl/*")
+	assert.EqualError(t, err, "template bad has no text file bad.txt")
+	fsys["tpl/bad.txt"] = &fstest.MapFile{Data: []byte(`ok`)}
+	_, err = NewTemplates(fsys, "tpl/*")
+	assert.EqualError(t, err, "template bad has no subject")
+	delete(fsys, "tpl/bad.txt")
+
+	delete(fsys, "tpl/bad.html")
+	tpls, err := NewTemplates(fsys, "tpl/*")
+	require.NoError(t, err)
+	msg = &Message{Template: "welcome", Data: map[string]string{"name": "Tom"}}
+	require.NoError(t, tpls.Render(msg))
+	assert.Equal(t, "Welcome Tom", msg.Subject)
+	assert.Equal(t, "Hello Tom", msg.Text)
+	assert.Empty(t, msg.HTML)
+
+	assert.Error(t, tpls.Render(&Message{Template: "welcome", Data: map[string]string{}})) // missing key
+	assert.Error(t, tpls.Render(&Message{Template: "notfound"}))
+}
+
+func TestSender_Send(t *testing.T) {
+	p := &fakeProvider{errs: []error{errors.New("connection reset")}}
+	s, reg := newTestSender(t, p)
+
+	msg := &Message{To: []string{"tom@example.com"}, Template: TemplateNotification,
+		Data: &NotificationData{Name: "Tom", Title: "Hi", Content: "content"}}
+	assert.Error(t, s.Send(context.Background(), msg)) // synchronous sending is not retried
+	require.NoError(t, s.Send(context.Background(), msg))
+
+	sent := p.getSent()
+	require.Len(t, sent, 1)
+	assert.Equal(t, "noreply@example.com", sent[0].From)
+	assert.Equal(t, "Hi", sent[0].Subject)
+	assert.NotEmpty(t, sent[0].ID)
+	assert.Equal(t, float64(1), testutil.ToFloat64(s.metrics.sent.WithLabelValues("fake", "success")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(s.metrics.sent.WithLabelValues("fake", "failure")))
+
+	assert.ErrorIs(t, s.Send(context.Background(), &Message{Subject: "no recipient"}), ErrNoRecipient)
+	assert.Error(t, s.Enqueue(&Message{To: []string{"tom@example.com"}, Template: "notfound"}))
+
+	// the metrics are shared by the senders of the same registerer
+	_, err := NewSender(p, WithRegisterer(reg))
+	assert.NoError(t, err)
+	_, err = NewSender(nil)
+	assert.Error(t, err)
+}
+
+func TestSender_Retry(t *testing.T) {
+	p := &fakeProvider{errs: []error{errors.New("timeout"), errors.New("timeout")}}
+	s, _ := newTestSender(t, p)
+	runSender(t, s)
+
+	require.NoError(t, s.Enqueue(&Message{To: []string{"tom@example.com"}, Subject: "retry", Text: "text"}))
+	require.Eventually(t, func() bool { return len(p.getSent()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, 3, p.getSent()[0].Attempts)
+	assert.Equal(t, float64(2), testutil.ToFloat64(s.metrics.retries.WithLabelValues("fake")))
+
+	dead, err := s.DeadLetters(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, dead)
+}
+
+func TestSender_DeadLetter(t *testing.T) {
+	p := &fakeProvider{errs: []error{
+		errors.New("timeout"), errors.New("timeout"), errors.New("timeout"), // attempts exhausted
+		Permanent(errors.New("550 mailbox not found")), // not retried
+	}}
+	s, _ := newTestSender(t, p)
+	runSender(t, s)
+
+	require.NoError(t, s.Enqueue(&Message{To: []string{"a@example.com"}, Subject: "exhausted", Text: "text"}))
+	require.Eventually(t, func() bool { return p.getCalls() == 3 }, time.Second, 5*time.Millisecond)
+	require.NoError(t, s.Enqueue(&Message{To: []string{"b@example.com"}, Subject: "permanent", Text: "text"}))
+
+	var dead []*Message
+	require.Eventually(t, func() bool {
+		dead, _ = s.DeadLetters(context.Background())
+		return len(dead) == 2
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "exhausted", dead[0].Subject)
+	assert.Equal(t, 3, dead[0].Attempts)
+	assert.Equal(t, "timeout", dead[0].LastError)
+	assert.Equal(t, "permanent", dead[1].Subject)
+	assert.Equal(t, 1, dead[1].Attempts)
+	assert.Equal(t, float64(2), testutil.ToFloat64(s.metrics.deadLetter.WithLabelValues("fake")))
+	assert.Empty(t, p.getSent())
+}
+
+func TestSender_DomainRateLimit(t *testing.T) {
+	p := &fakeProvider{}
+	s, _ := newTestSender(t, p, WithDomainRateLimit(10, 1))
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, s.Send(context.Background(), &Message{To: []string{"tom@Example.com"}, Subject: "s", Text: "t"}))
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+
+	// the other domains are not limited
+	start = time.Now()
+	require.NoError(t, s.Send(context.Background(), &Message{To: []string{"tom@example.org"}, Subject: "s", Text: "t"}))
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestRedisQueue(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	p := &fakeProvider{errs: []error{Permanent(errors.New("rejected"))}}
+	q := NewRedisQueue(client, "email:queue")
+	s, _ := newTestSender(t, p, WithQueue(q))
+	require.NoError(t, s.Enqueue(&Message{To: []string{"a@example.com"}, Subject: "first", Text: "text"}))
+	require.NoError(t, s.Enqueue(&Message{To: []string{"b@example.com"}, Subject: "second", Text: "text"}))
+	n, err := q.Len(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, n) // persisted before the worker is started
+
+	runSender(t, s)
+	require.Eventually(t, func() bool { return len(p.getSent()) == 1 }, 3*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "second", p.getSent()[0].Subject)
+	dead, err := q.DeadLetters(context.Background())
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	assert.Equal(t, "first", dead[0].Subject)
+	assert.Equal(t, "rejected", dead[0].LastError)
+}
+
+func TestMemoryQueue(t *testing.T) {
+	q := NewMemoryQueue(1)
+	ctx := context.Background()
+	require.NoError(t, q.Push(ctx, &Message{Subject: "1"}))
+	assert.ErrorIs(t, q.Push(ctx, &Message{Subject: "2"}), ErrQueueFull)
+	n, _ := q.Len(ctx)
+	assert.Equal(t, 1, n)
+
+	msg, err := q.Pop(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "1", msg.Subject)
+	ctx2, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = q.Pop(ctx2)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// the latest dead letters are kept
+	_ = q.PushDead(ctx, &Message{Subject: "1"})
+	_ = q.PushDead(ctx, &Message{Subject: "2"})
+	dead, _ := q.DeadLetters(ctx)
+	require.Len(t, dead, 1)
+	assert.Equal(t, "2", dead[0].Subject)
+}
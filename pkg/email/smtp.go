@@ -0,0 +1,191 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTPConfig the config of the SMTP provider
+type SMTPConfig struct {
+	Host     string
+	Port     int // default 587
+	Username string
+	Password string
+	// ImplicitTLS connect with TLS, usually the port is 465, otherwise STARTTLS is used if the server supports it
+	ImplicitTLS bool
+	TLSConfig   *tls.Config
+	Timeout     time.Duration // the timeout of dialing and sending if ctx has no deadline, default 30s
+}
+
+// SMTPProvider send the email by SMTP
+type SMTPProvider struct {
+	cfg SMTPConfig
+}
+
+// NewSMTPProvider create a SMTP provider
+func NewSMTPProvider(cfg SMTPConfig) *SMTPProvider {
+	if cfg.Port == 0 {
+		cfg.Port = 587
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	if cfg.TLSConfig == nil {
+		cfg.TLSConfig = &tls.Config{ServerName: cfg.Host} //nolint
+	}
+	return &SMTPProvider{cfg: cfg}
+}
+
+// Name returns the name of the provider
+func (p *SMTPProvider) Name() string {
+	return "smtp"
+}
+
+// Send the email, the rejection of 5xx reply code is a permanent error
+func (p *SMTPProvider) Send(ctx context.Context, msg *Message) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.cfg.Timeout)
+		defer cancel()
+	}
+
+	addr := net.JoinHostPort(p.cfg.Host, strconv.Itoa(p.cfg.Port))
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+	if p.cfg.ImplicitTLS {
+		conn = tls.Client(conn, p.cfg.TLSConfig)
+	}
+
+	c, err := smtp.NewClient(conn, p.cfg.Host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer c.Close() //nolint
+
+	if err = p.send(c, msg); err != nil {
+		var te *textproto.Error
+		if errors.As(err, &te) && te.Code >= 500 {
+			return Permanent(err)
+		}
+		return err
+	}
+	return c.Quit()
+}
+
+func (p *SMTPProvider) send(c *smtp.Client, msg *Message) error {
+	if !p.cfg.ImplicitTLS {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(p.cfg.TLSConfig); err != nil {
+				return err
+			}
+		}
+	}
+	if p.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", p.cfg.Username, p.cfg.Password, p.cfg.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(msg.From); err != nil {
+		return err
+	}
+	for _, rcpt := range msg.Recipients() {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(buildMIME(msg)); err != nil {
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// buildMIME build the MIME message, the text and html are multipart/alternative if both exist
+func buildMIME(msg *Message) []byte {
+	buf := &bytes.Buffer{}
+	writeHeader := func(key string, value string) {
+		buf.WriteString(key + ": " + value + "\r\n")
+	}
+
+	writeHeader("From", msg.From)
+	writeHeader("To", strings.Join(msg.To, ", "))
+	if len(msg.Cc) > 0 {
+		writeHeader("Cc", strings.Join(msg.Cc, ", "))
+	}
+	if msg.ReplyTo != "" {
+		writeHeader("Reply-To", msg.ReplyTo)
+	}
+	writeHeader("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	writeHeader("Date", time.Now().Format(time.RFC1123Z))
+	if msg.ID != "" {
+		writeHeader("Message-ID", "<"+msg.ID+"@"+domainOf(msg.From)+">")
+	}
+	keys := make([]string, 0, len(msg.Headers))
+	for k := range msg.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		writeHeader(k, msg.Headers[k])
+	}
+	writeHeader("MIME-Version", "1.0")
+
+	switch {
+	case msg.Text != "" && msg.HTML != "":
+		boundary := randomBoundary()
+		writeHeader("Content-Type", `multipart/alternative; boundary="`+boundary+`"`)
+		buf.WriteString("\r\n")
+		writePart(buf, boundary, "text/plain", msg.Text)
+		writePart(buf, boundary, "text/html", msg.HTML)
+		buf.WriteString("--" + boundary + "--\r\n")
+	case msg.HTML != "":
+		writeBody(buf, "text/html", msg.HTML)
+	default:
+		writeBody(buf, "text/plain", msg.Text)
+	}
+	return buf.Bytes()
+}
+
+func writePart(buf *bytes.Buffer, boundary string, contentType string, content string) {
+	buf.WriteString("--" + boundary + "\r\n")
+	writeBody(buf, contentType, content)
+	buf.WriteString("\r\n")
+}
+
+func writeBody(buf *bytes.Buffer, contentType string, content string) {
+	buf.WriteString(fmt.Sprintf("Content-Type: %s; charset=utf-8\r\n", contentType))
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	w := quotedprintable.NewWriter(buf)
+	_, _ = w.Write([]byte(content))
+	_ = w.Close()
+}
+
+func randomBoundary() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,137 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed templates
+var embedTemplates embed.FS
+
+// the names of the embedded templates
+const (
+	TemplatePasswordReset = "password_reset"
+	TemplateNotification  = "notification"
+)
+
+// PasswordResetData the data of the password reset template
+type PasswordResetData struct {
+	Name          string
+	Link          string
+	ExpireMinutes int
+}
+
+// NotificationData the data of the notification template
+type NotificationData struct {
+	Name    string
+	Title   string
+	Content string
+	Link    string // optional
+}
+
+// Templates render the emails, each template consists of the text file <name>.txt and the optional html
+// file <name>.html, the subject is defined in the text file by {{define "subject"}}...{{end}}.
+type Templates struct {
+	texts map[string]*texttemplate.Template
+	htmls map[string]*htmltemplate.Template
+}
+
+// NewTemplates parse the templates of the files matched by the patterns in fsys, e.g. "templates/*".
+func NewTemplates(fsys fs.FS, patterns ...string) (*Templates, error) {
+	t := &Templates{
+		texts: map[string]*texttemplate.Template{},
+		htmls: map[string]*htmltemplate.Template{},
+	}
+	for _, pattern := range patterns {
+		files, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if err = t.parse(fsys, file); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for name, tpl := range t.texts {
+		if tpl.Lookup("subject") == nil {
+			return nil, fmt.Errorf("template %s has no subject", name)
+		}
+	}
+	for name := range t.htmls {
+		if _, ok := t.texts[name]; !ok {
+			return nil, fmt.Errorf("template %s has no text file %s.txt", name, name)
+		}
+	}
+	return t, nil
+}
+
+// DefaultTemplates returns the embedded templates, password_reset and notification
+func DefaultTemplates() *Templates {
+	t, err := NewTemplates(embedTemplates, "templates/*")
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func (t *Templates) parse(fsys fs.FS, file string) error {
+	data, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return err
+	}
+	ext := path.Ext(file)
+	name := strings.TrimSuffix(path.Base(file), ext)
+	switch ext {
+	case ".txt":
+		t.texts[name], err = texttemplate.New(name).Option("missingkey=error").Parse(string(data))
+	case ".html":
+		t.htmls[name], err = htmltemplate.New(name).Option("missingkey=error").Parse(string(data))
+	}
+	if err != nil {
+		return fmt.Errorf("parse template %s error: %w", file, err)
+	}
+	return nil
+}
+
+// Render the subject, text and html of the message by the template msg.Template and msg.Data.
+func (t *Templates) Render(msg *Message) error {
+	if msg.Template == "" {
+		return nil
+	}
+	text, ok := t.texts[msg.Template]
+	if !ok {
+		return fmt.Errorf("template %s not found", msg.Template)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := text.ExecuteTemplate(buf, "subject", msg.Data); err != nil {
+		return fmt.Errorf("render subject of template %s error: %w", msg.Template, err)
+	}
+	subject := strings.TrimSpace(buf.String())
+
+	buf.Reset()
+	if err := text.Execute(buf, msg.Data); err != nil {
+		return fmt.Errorf("render text of template %s error: %w", msg.Template, err)
+	}
+	body := strings.TrimSpace(buf.String())
+
+	var html string
+	if h, ok := t.htmls[msg.Template]; ok {
+		buf.Reset()
+		if err := h.Execute(buf, msg.Data); err != nil {
+			return fmt.Errorf("render html of template %s error: %w", msg.Template, err)
+		}
+		html = buf.String()
+	}
+
+	msg.Subject, msg.Text, msg.HTML = subject, body, html
+	msg.Template, msg.Data = "", nil // rendered
+	return nil
+}
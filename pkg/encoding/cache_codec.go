@@ -0,0 +1,240 @@
+package encoding
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/vmihailenco/msgpack"
+	"google.golang.org/protobuf/proto"
+)
+
+// CodecType serialization format of the cached value
+type CodecType byte
+
+// CompressionType compression algorithm of the cached value
+type CompressionType byte
+
+// the first byte of the header never starts a json document, values without header are treated as legacy json
+const (
+	CodecJSON    CodecType = 0xe1
+	CodecMsgPack CodecType = 0xe2
+	CodecProto   CodecType = 0xe3
+)
+
+// compression types
+const (
+	CompressionNone   CompressionType = 0
+	CompressionSnappy CompressionType = 1
+	CompressionZstd   CompressionType = 2
+)
+
+const (
+	headerSize = 2
+
+	// defaultCompressThreshold values smaller than this are not compressed
+	defaultCompressThreshold = 1024
+)
+
+var (
+	// ErrUnknownCodec the header of the value is not recognized
+	ErrUnknownCodec = errors.New("unknown codec or compression in header")
+
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+	zstdErr     error
+)
+
+// CacheCodecOption set the cache codec options.
+type CacheCodecOption func(*cacheCodecOptions)
+
+type cacheCodecOptions struct {
+	compression       CompressionType
+	compressThreshold int
+}
+
+func (o *cacheCodecOptions) apply(opts ...CacheCodecOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+func defaultCacheCodecOptions() *cacheCodecOptions {
+	return &cacheCodecOptions{
+		compression:       CompressionNone,
+		compressThreshold: defaultCompressThreshold,
+	}
+}
+
+// WithCompression set compression algorithm, default is no compression
+func WithCompression(c CompressionType) CacheCodecOption {
+	return func(o *cacheCodecOptions) {
+		o.compression = c
+	}
+}
+
+// WithCompressThreshold values whose encoded size is at least n bytes are compressed, default 1024
+func WithCompressThreshold(n int) CacheCodecOption {
+	return func(o *cacheCodecOptions) {
+		if n >= 0 {
+			o.compressThreshold = n
+		}
+	}
+}
+
+// CacheCodec is an Encoding for cached values, the encoded value is prefixed with a 2-byte header
+// that identifies the codec and compression, so values written by another codec or before a codec
+// change are still decoded, and values without header are decoded as json.
+type CacheCodec struct {
+	codec             CodecType
+	compression       CompressionType
+	compressThreshold int
+}
+
+// NewCacheCodec create a cache codec, codecType is CodecJSON, CodecMsgPack or CodecProto
+func NewCacheCodec(codecType CodecType, opts ...CacheCodecOption) *CacheCodec {
+	o := defaultCacheCodecOptions()
+	o.apply(opts...)
+
+	if !isValidCodec(codecType) {
+		panic(fmt.Sprintf("unsupported cache codec type 0x%x", byte(codecType)))
+	}
+	if !isValidCompression(o.compression) {
+		panic(fmt.Sprintf("unsupported cache compression type %d", o.compression))
+	}
+
+	return &CacheCodec{
+		codec:             codecType,
+		compression:       o.compression,
+		compressThreshold: o.compressThreshold,
+	}
+}
+
+// Marshal encode value with header
+func (c *CacheCodec) Marshal(v interface{}) ([]byte, error) {
+	data, err := codecMarshal(c.codec, v)
+	if err != nil {
+		return nil, err
+	}
+
+	compression := CompressionNone
+	if c.compression != CompressionNone && len(data) >= c.compressThreshold {
+		data, err = compress(c.compression, data)
+		if err != nil {
+			return nil, err
+		}
+		compression = c.compression
+	}
+
+	buf := make([]byte, headerSize+len(data))
+	buf[0] = byte(c.codec)
+	buf[1] = byte(compression)
+	copy(buf[headerSize:], data)
+	return buf, nil
+}
+
+// Unmarshal decode value according to its header, values without header are decoded as json
+func (c *CacheCodec) Unmarshal(data []byte, v interface{}) error {
+	codec, compression, body, ok := parseHeader(data)
+	if !ok {
+		return json.Unmarshal(data, v)
+	}
+
+	if compression != CompressionNone {
+		var err error
+		body, err = decompress(compression, body)
+		if err != nil {
+			return err
+		}
+	}
+
+	return codecUnmarshal(codec, body, v)
+}
+
+func parseHeader(data []byte) (CodecType, CompressionType, []byte, bool) {
+	if len(data) < headerSize || !isValidCodec(CodecType(data[0])) || !isValidCompression(CompressionType(data[1])) {
+		return 0, 0, nil, false
+	}
+	return CodecType(data[0]), CompressionType(data[1]), data[headerSize:], true
+}
+
+func isValidCodec(t CodecType) bool {
+	return t == CodecJSON || t == CodecMsgPack || t == CodecProto
+}
+
+func isValidCompression(t CompressionType) bool {
+	return t == CompressionNone || t == CompressionSnappy || t == CompressionZstd
+}
+
+func codecMarshal(t CodecType, v interface{}) ([]byte, error) {
+	switch t {
+	case CodecJSON:
+		return json.Marshal(v)
+	case CodecMsgPack:
+		return msgpack.Marshal(v)
+	case CodecProto:
+		m, ok := v.(proto.Message)
+		if !ok {
+			return nil, fmt.Errorf("failed to marshal, message is %T, want proto.Message", v)
+		}
+		return proto.Marshal(m)
+	}
+	return nil, ErrUnknownCodec
+}
+
+func codecUnmarshal(t CodecType, data []byte, v interface{}) error {
+	switch t {
+	case CodecJSON:
+		return json.Unmarshal(data, v)
+	case CodecMsgPack:
+		return msgpack.Unmarshal(data, v)
+	case CodecProto:
+		m, ok := v.(proto.Message)
+		if !ok {
+			return fmt.Errorf("failed to unmarshal, message is %T, want proto.Message", v)
+		}
+		return proto.Unmarshal(data, m)
+	}
+	return ErrUnknownCodec
+}
+
+func initZstd() error {
+	zstdOnce.Do(func() {
+		zstdEncoder, zstdErr = zstd.NewWriter(nil)
+		if zstdErr != nil {
+			return
+		}
+		zstdDecoder, zstdErr = zstd.NewReader(nil)
+	})
+	return zstdErr
+}
+
+func compress(t CompressionType, data []byte) ([]byte, error) {
+	switch t {
+	case CompressionSnappy:
+		return snappy.Encode(nil, data), nil
+	case CompressionZstd:
+		if err := initZstd(); err != nil {
+			return nil, err
+		}
+		return zstdEncoder.EncodeAll(data, nil), nil
+	}
+	return data, nil
+}
+
+func decompress(t CompressionType, data []byte) ([]byte, error) {
+	switch t {
+	case CompressionSnappy:
+		return snappy.Decode(nil, data)
+	case CompressionZstd:
+		if err := initZstd(); err != nil {
+			return nil, err
+		}
+		return zstdDecoder.DecodeAll(data, nil)
+	}
+	return data, nil
+}
@@ -0,0 +1,141 @@
+package encoding
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type cacheObj struct {
+	ID     uint64   `json:"id" msgpack:"id"`
+	Name   string   `json:"name" msgpack:"name"`
+	Remark string   `json:"remark" msgpack:"remark"`
+	Tags   []string `json:"tags" msgpack:"tags"`
+}
+
+func newCacheObj(remarkSize int) *cacheObj {
+	return &cacheObj{
+		ID:     1,
+		Name:   "foo",
+		Remark: strings.Repeat("sponge cache value ", remarkSize/19+1)[:remarkSize],
+		Tags:   []string{"a", "b", "c"},
+	}
+}
+
+var (
+	cacheCodecTypes   = []CodecType{CodecJSON, CodecMsgPack}
+	cacheCompressions = []CompressionType{CompressionNone, CompressionSnappy, CompressionZstd}
+)
+
+func TestCacheCodec(t *testing.T) {
+	for _, ct := range cacheCodecTypes {
+		for _, cp := range cacheCompressions {
+			for _, size := range []int{10, 4096} {
+				t.Run(fmt.Sprintf("%x-%d-%d", byte(ct), cp, size), func(t *testing.T) {
+					c := NewCacheCodec(ct, WithCompression(cp))
+					o1 := newCacheObj(size)
+					data, err := Marshal(c, o1)
+					assert.NoError(t, err)
+					assert.Equal(t, byte(ct), data[0])
+					if size < defaultCompressThreshold {
+						assert.Equal(t, byte(CompressionNone), data[1])
+					} else {
+						assert.Equal(t, byte(cp), data[1])
+					}
+
+					o2 := &cacheObj{}
+					err = Unmarshal(c, data, o2)
+					assert.NoError(t, err)
+					assert.Equal(t, o1, o2)
+				})
+			}
+		}
+	}
+}
+
+func TestCacheCodecProto(t *testing.T) {
+	for _, cp := range cacheCompressions {
+		c := NewCacheCodec(CodecProto, WithCompression(cp), WithCompressThreshold(0))
+		data, err := c.Marshal(wrapperspb.String(strings.Repeat("foo", 100)))
+		assert.NoError(t, err)
+		v := &wrapperspb.StringValue{}
+		err = c.Unmarshal(data, v)
+		assert.NoError(t, err)
+		assert.Equal(t, strings.Repeat("foo", 100), v.Value)
+	}
+
+	c := NewCacheCodec(CodecProto)
+	_, err := c.Marshal(&cacheObj{})
+	assert.Error(t, err)
+	data, _ := c.Marshal(wrapperspb.Int64(1))
+	err = c.Unmarshal(data, &cacheObj{})
+	assert.Error(t, err)
+}
+
+func TestCacheCodecLegacy(t *testing.T) {
+	o1 := newCacheObj(2048)
+	legacy, err := json.Marshal(o1)
+	assert.NoError(t, err)
+
+	// values written before the codec change are headerless json
+	for _, ct := range cacheCodecTypes {
+		for _, cp := range cacheCompressions {
+			c := NewCacheCodec(ct, WithCompression(cp))
+			o2 := &cacheObj{}
+			err = c.Unmarshal(legacy, o2)
+			assert.NoError(t, err)
+			assert.Equal(t, o1, o2)
+		}
+	}
+
+	err = NewCacheCodec(CodecJSON).Unmarshal([]byte(`123`), new(int))
+	assert.NoError(t, err)
+}
+
+func TestCacheCodecChange(t *testing.T) {
+	o1 := newCacheObj(2048)
+	data, err := NewCacheCodec(CodecJSON, WithCompression(CompressionSnappy)).Marshal(o1)
+	assert.NoError(t, err)
+
+	// the value is decoded by the codec recorded in header
+	o2 := &cacheObj{}
+	err = NewCacheCodec(CodecMsgPack, WithCompression(CompressionZstd)).Unmarshal(data, o2)
+	assert.NoError(t, err)
+	assert.Equal(t, o1, o2)
+}
+
+func TestCacheCodecError(t *testing.T) {
+	assert.Panics(t, func() { NewCacheCodec(CodecType(0)) })
+	assert.Panics(t, func() { NewCacheCodec(CodecJSON, WithCompression(CompressionType(9))) })
+
+	c := NewCacheCodec(CodecJSON)
+	err := c.Unmarshal([]byte{byte(CodecJSON), byte(CompressionSnappy), 0xff, 0xff}, &cacheObj{})
+	assert.Error(t, err)
+	err = c.Unmarshal([]byte{byte(CodecJSON), byte(CompressionZstd), 0xff, 0xff}, &cacheObj{})
+	assert.Error(t, err)
+	err = c.Unmarshal([]byte("foo"), &cacheObj{})
+	assert.Error(t, err)
+}
+
+func BenchmarkCacheCodec(b *testing.B) {
+	o := newCacheObj(8192)
+	legacy, _ := json.Marshal(o)
+	for _, ct := range cacheCodecTypes {
+		for _, cp := range cacheCompressions {
+			c := NewCacheCodec(ct, WithCompression(cp))
+			b.Run(fmt.Sprintf("%x-%d", byte(ct), cp), func(b *testing.B) {
+				var data []byte
+				for i := 0; i < b.N; i++ {
+					data, _ = c.Marshal(o)
+					_ = c.Unmarshal(data, &cacheObj{})
+				}
+				b.ReportMetric(float64(len(data)), "bytes")
+				b.ReportMetric(float64(len(data))/float64(len(legacy)), "ratio")
+			})
+		}
+	}
+}
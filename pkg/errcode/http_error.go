@@ -54,6 +54,14 @@ func (e *Error) Err(msg ...string) error {
 	return fmt.Errorf("code = %d, msg = %s, details = %v", e.code, message, e.details)
 }
 
+// Error implements the error interface, the format is the same as Err, it can be parsed by ParseError.
+func (e *Error) Error() string {
+	if len(e.details) == 0 {
+		return fmt.Sprintf("code = %d, msg = %s", e.code, e.msg)
+	}
+	return fmt.Sprintf("code = %d, msg = %s, details = %v", e.code, e.msg, e.details)
+}
+
 // ErrToHTTP convert to standard error add ToHTTPCodeLabel to error message,
 // use it if you need to convert to standard HTTP status code,
 // if there is a parameter 'msg', it will replace the original message.
@@ -163,6 +171,8 @@ func (e *Error) ToHTTPCode() int {
 		return http.StatusNotImplemented
 	case StatusBadGateway.Code():
 		return http.StatusBadGateway
+	case GatewayTimeout.Code():
+		return http.StatusGatewayTimeout
 	}
 
 	return http.StatusInternalServerError
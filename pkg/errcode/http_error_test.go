@@ -36,6 +36,7 @@ var errorsCodes = []*Error{
 	OutOfRange,
 	Unimplemented,
 	StatusBadGateway,
+	GatewayTimeout,
 }
 
 func TestNewError(t *testing.T) {
@@ -27,6 +27,7 @@ var (
 	DataLoss           = NewError(100022, "Data Loss")
 
 	StatusBadGateway = NewError(100023, "Bad Gateway")
+	GatewayTimeout   = NewError(100024, "Gateway Timeout")
 
 	// Deprecated: use Conflict instead
 	AlreadyExists = NewError(100005, "Already Exists")
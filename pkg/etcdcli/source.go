@@ -0,0 +1,233 @@
+package etcdcli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/go-dev-frame/sponge/pkg/conf/configsource"
+)
+
+var _ configsource.Source = (*Source)(nil)
+
+// Source the configuration of a key in etcd, the changes are watched from the revision of the last Get,
+// and the watching is resumed from the last revision after it is interrupted.
+type Source struct {
+	client  *clientv3.Client
+	key     string
+	fetcher *configsource.Fetcher
+
+	mu       sync.Mutex
+	lastRev  int64 // the changes after this revision are watched
+	lastData []byte
+
+	closeOnce sync.Once
+	done      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// NewSource create a configuration source of etcd, the format is the extension of the key
+// if it is not set by configsource.WithFormat, e.g. "/config/user.yml" means yaml.
+func NewSource(client *clientv3.Client, key string, opts ...configsource.Option) (*Source, error) {
+	if client == nil {
+		return nil, errors.New("etcd client cannot be nil")
+	}
+	if key == "" {
+		return nil, errors.New("etcd key cannot be empty")
+	}
+	fetcher, err := configsource.NewFetcher("etcd", key, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Source{
+		client:  client,
+		key:     key,
+		fetcher: fetcher,
+		done:    make(chan struct{}),
+	}, nil
+}
+
+// Get the configuration from etcd, the local cache is returned if etcd is unavailable
+func (s *Source) Get(ctx context.Context) (string, []byte, error) {
+	select {
+	case <-s.done:
+		return "", nil, configsource.ErrClosed
+	default:
+	}
+
+	data, err := s.fetcher.Fetch(ctx, func(ctx context.Context) ([]byte, error) {
+		data, rev, err := s.get(ctx)
+		if err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		s.lastRev = rev
+		s.mu.Unlock()
+		return data, nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	s.mu.Lock()
+	s.lastData = data
+	s.mu.Unlock()
+	return s.fetcher.Format(), data, nil
+}
+
+func (s *Source) get(ctx context.Context) ([]byte, int64, error) {
+	resp, err := s.client.Get(ctx, s.key)
+	if err != nil {
+		return nil, 0, classifyError(ctx, err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, resp.Header.GetRevision(), fmt.Errorf("%w: etcd key %s", configsource.ErrNotFound, s.key)
+	}
+	return resp.Kvs[0].Value, resp.Header.GetRevision(), nil
+}
+
+// Watch the changes of the configuration in background, if the watching is interrupted, it is resumed
+// from the last revision after the retry interval, and if the revision has been compacted, the latest
+// configuration is got again.
+func (s *Source) Watch(ctx context.Context, onChange func(format string, data []byte)) error {
+	if onChange == nil {
+		return errors.New("onChange cannot be nil")
+	}
+	select {
+	case <-s.done:
+		return configsource.ErrClosed
+	default:
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer cancel()
+		s.watch(ctx, onChange)
+	}()
+	go func() {
+		select {
+		case <-s.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return nil
+}
+
+func (s *Source) watch(ctx context.Context, onChange func(format string, data []byte)) {
+	for ctx.Err() == nil {
+		s.mu.Lock()
+		rev := s.lastRev
+		s.mu.Unlock()
+
+		var err error
+		if rev == 0 {
+			// Get is not called or the revision has been compacted
+			err = s.resync(ctx, onChange)
+		} else {
+			err = s.watchFrom(ctx, rev, onChange)
+		}
+		if err == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(s.fetcher.RetryInterval()):
+		}
+	}
+}
+
+// resync get the latest configuration and the revision
+func (s *Source) resync(ctx context.Context, onChange func(format string, data []byte)) error {
+	data, rev, err := s.get(ctx)
+	if err != nil && !errors.Is(err, configsource.ErrNotFound) {
+		return err
+	}
+	s.handle(rev, data, onChange)
+	return nil
+}
+
+// watchFrom watch the changes after rev until the watching is interrupted
+func (s *Source) watchFrom(ctx context.Context, rev int64, onChange func(format string, data []byte)) error {
+	wctx, cancel := context.WithCancel(clientv3.WithRequireLeader(ctx))
+	defer cancel()
+
+	for resp := range s.client.Watch(wctx, s.key, clientv3.WithRev(rev+1)) {
+		if resp.CompactRevision != 0 {
+			s.mu.Lock()
+			s.lastRev = 0
+			s.mu.Unlock()
+			return nil
+		}
+		if err := resp.Err(); err != nil {
+			return err
+		}
+		for _, ev := range resp.Events {
+			var data []byte
+			if ev.Type == mvccpb.PUT {
+				data = ev.Kv.Value
+			}
+			s.handle(ev.Kv.ModRevision, data, onChange)
+		}
+	}
+	return errors.New("etcd watch channel closed")
+}
+
+// handle update the revision, and call onChange if the configuration is changed, data is nil if the key is deleted
+func (s *Source) handle(rev int64, data []byte, onChange func(format string, data []byte)) {
+	s.mu.Lock()
+	if rev > s.lastRev {
+		s.lastRev = rev
+	}
+	changed := data != nil && !bytes.Equal(data, s.lastData)
+	if changed {
+		s.lastData = data
+	}
+	s.mu.Unlock()
+
+	if changed {
+		s.fetcher.SaveCache(data)
+		onChange(s.fetcher.Format(), data)
+	}
+}
+
+// Close stop watching, the etcd client is not closed
+func (s *Source) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+	s.wg.Wait()
+	return nil
+}
+
+// classifyError the network errors and server errors are ErrUnavailable, the other errors such as
+// permission denied are returned directly.
+func classifyError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	code := status.Code(err)
+	var etcdErr rpctypes.EtcdError
+	if errors.As(err, &etcdErr) {
+		code = etcdErr.Code()
+	}
+	switch code {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted, codes.Unknown:
+		return configsource.Unavailable(err)
+	}
+	return err
+}
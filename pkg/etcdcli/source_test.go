@@ -0,0 +1,251 @@
+package etcdcli
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/go-dev-frame/sponge/pkg/conf/configsource"
+)
+
+type fakeKV struct {
+	clientv3.KV
+
+	mu    sync.Mutex
+	value []byte // nil means the key doesn't exist
+	rev   int64
+	err   error
+}
+
+func (kv *fakeKV) set(value string, rev int64, err error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	kv.value, kv.rev, kv.err = []byte(value), rev, err
+	if value == "" {
+		kv.value = nil
+	}
+}
+
+func (kv *fakeKV) Get(_ context.Context, key string, _ ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	if kv.err != nil {
+		return nil, kv.err
+	}
+	resp := &clientv3.GetResponse{Header: &pb.ResponseHeader{Revision: kv.rev}}
+	if kv.value != nil {
+		resp.Kvs = []*mvccpb.KeyValue{{Key: []byte(key), Value: kv.value, ModRevision: kv.rev}}
+	}
+	return resp, nil
+}
+
+// fakeWatch a watch call, the responses sent to in are forwarded to the watch channel, and the watch
+// channel is closed when in is closed or the context is done.
+type fakeWatch struct {
+	rev int64
+	in  chan clientv3.WatchResponse
+}
+
+type fakeWatcher struct {
+	clientv3.Watcher
+	watches chan *fakeWatch
+}
+
+func (w *fakeWatcher) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	fw := &fakeWatch{rev: clientv3.OpGet(key, opts...).Rev(), in: make(chan clientv3.WatchResponse)}
+	out := make(chan clientv3.WatchResponse)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case resp, ok := <-fw.in:
+				if !ok {
+					return
+				}
+				out <- resp
+			}
+		}
+	}()
+	w.watches <- fw
+	return out
+}
+
+func newTestClient() (*clientv3.Client, *fakeKV, *fakeWatcher) {
+	kv := &fakeKV{}
+	watcher := &fakeWatcher{watches: make(chan *fakeWatch, 10)}
+	cli := clientv3.NewCtxClient(context.Background())
+	cli.KV = kv
+	cli.Watcher = watcher
+	return cli, kv, watcher
+}
+
+func putEvent(value string, rev int64) clientv3.WatchResponse {
+	return clientv3.WatchResponse{
+		Header: pb.ResponseHeader{Revision: rev},
+		Events: []*clientv3.Event{{Type: mvccpb.PUT, Kv: &mvccpb.KeyValue{Value: []byte(value), ModRevision: rev}}},
+	}
+}
+
+func nextWatch(t *testing.T, w *fakeWatcher) *fakeWatch {
+	select {
+	case fw := <-w.watches:
+		return fw
+	case <-time.After(3 * time.Second):
+		t.Fatal("timeout waiting for the watch")
+		return nil
+	}
+}
+
+func receive(t *testing.T, ch chan string) string {
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(3 * time.Second):
+		t.Fatal("timeout waiting for the change")
+		return ""
+	}
+}
+
+func TestNewSource(t *testing.T) {
+	_, err := NewSource(nil, "/config/user.yml")
+	assert.Error(t, err)
+
+	cli, _, _ := newTestClient()
+	_, err = NewSource(cli, "")
+	assert.Error(t, err)
+	_, err = NewSource(cli, "/config/user")
+	assert.Error(t, err)
+	src, err := NewSource(cli, "/config/user", configsource.WithFormat("json"))
+	require.NoError(t, err)
+	assert.NoError(t, src.Close())
+}
+
+func TestSource_Get(t *testing.T) {
+	cli, kv, _ := newTestClient()
+	src, err := NewSource(cli, "/config/user.yml", configsource.WithRetry(1, 10*time.Millisecond),
+		configsource.WithCacheDir(t.TempDir()))
+	require.NoError(t, err)
+	defer src.Close()
+	ctx := context.Background()
+
+	_, _, err = src.Get(ctx)
+	assert.ErrorIs(t, err, configsource.ErrNotFound)
+
+	kv.set("name: foo", 10, nil)
+	format, data, err := src.Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "yaml", format)
+	assert.Equal(t, "name: foo", string(data))
+
+	// permission denied is neither not found nor unavailable
+	kv.set("", 0, rpctypes.ErrPermissionDenied)
+	_, _, err = src.Get(ctx)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, configsource.ErrNotFound)
+	assert.NotErrorIs(t, err, configsource.ErrUnavailable)
+
+	// fall back to the local cache
+	kv.set("", 0, status.Error(codes.Unavailable, "connection refused"))
+	_, data, err = src.Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "name: foo", string(data))
+
+	// unavailable without cache
+	src2, err := NewSource(cli, "/config/user.yml", configsource.WithRetry(0, 0))
+	require.NoError(t, err)
+	_, _, err = src2.Get(ctx)
+	assert.ErrorIs(t, err, configsource.ErrUnavailable)
+
+	_ = src.Close()
+	_, _, err = src.Get(ctx)
+	assert.ErrorIs(t, err, configsource.ErrClosed)
+	assert.ErrorIs(t, src.Watch(ctx, func(string, []byte) {}), configsource.ErrClosed)
+}
+
+func TestSource_Watch(t *testing.T) {
+	cli, kv, watcher := newTestClient()
+	src, err := NewSource(cli, "/config/user.yml", configsource.WithRetry(1, 10*time.Millisecond))
+	require.NoError(t, err)
+	defer src.Close()
+	ctx := context.Background()
+
+	kv.set("name: foo", 10, nil)
+	_, _, err = src.Get(ctx)
+	require.NoError(t, err)
+	assert.Error(t, src.Watch(ctx, nil))
+
+	changes := make(chan string, 10)
+	require.NoError(t, src.Watch(ctx, func(format string, data []byte) {
+		assert.Equal(t, "yaml", format)
+		changes <- string(data)
+	}))
+
+	// watch from the revision of Get
+	fw := nextWatch(t, watcher)
+	assert.Equal(t, int64(11), fw.rev)
+	fw.in <- putEvent("name: bar", 12)
+	assert.Equal(t, "name: bar", receive(t, changes))
+	// the deletion and the same content are ignored
+	fw.in <- clientv3.WatchResponse{Events: []*clientv3.Event{{Type: mvccpb.DELETE, Kv: &mvccpb.KeyValue{ModRevision: 13}}}}
+	fw.in <- putEvent("name: bar", 14)
+
+	// resume from the last revision after the watching is interrupted
+	close(fw.in)
+	fw = nextWatch(t, watcher)
+	assert.Equal(t, int64(15), fw.rev)
+	fw.in <- putEvent("name: baz", 16)
+	assert.Equal(t, "name: baz", receive(t, changes))
+
+	// get the latest content if the revision has been compacted
+	kv.set("name: qux", 30, nil)
+	fw.in <- clientv3.WatchResponse{CompactRevision: 20}
+	assert.Equal(t, "name: qux", receive(t, changes))
+	fw = nextWatch(t, watcher)
+	assert.Equal(t, int64(31), fw.rev)
+
+	// stop watching after closed
+	require.NoError(t, src.Close())
+	select {
+	case fw = <-watcher.watches:
+		t.Fatalf("unexpected watch from %d", fw.rev)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSource_WatchWithoutGet(t *testing.T) {
+	cli, kv, watcher := newTestClient()
+	src, err := NewSource(cli, "/config/user.yml", configsource.WithRetry(1, 10*time.Millisecond))
+	require.NoError(t, err)
+	defer src.Close()
+
+	// retry until etcd is available, the current content is a change if Get is not called
+	kv.set("", 0, status.Error(codes.Unavailable, "connection refused"))
+	changes := make(chan string, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, src.Watch(ctx, func(format string, data []byte) { changes <- string(data) }))
+	time.Sleep(30 * time.Millisecond)
+	kv.set("name: foo", 5, nil)
+	assert.Equal(t, "name: foo", receive(t, changes))
+	fw := nextWatch(t, watcher)
+	assert.Equal(t, int64(6), fw.rev)
+
+	// stop watching after ctx is canceled
+	cancel()
+	select {
+	case fw = <-watcher.watches:
+		t.Fatalf("unexpected watch from %d", fw.rev)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,163 @@
+// Package featureflag is the feature flags of the dark launch, the flags are loaded from the configuration and
+// replaced live when the configuration is changed, a flag is enabled for all or a percentage of the keys, e.g.
+// the user ids or the tenant ids, the same key always gets the same result of the same flag.
+package featureflag
+
+import (
+	"bytes"
+	"errors"
+	"hash/fnv"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/viper"
+)
+
+// the results of the evaluations in the metrics
+const (
+	ResultEnabled  = "enabled"
+	ResultDisabled = "disabled"
+	ResultUnknown  = "unknown" // the flag is not configured, it is disabled
+)
+
+// Flag the configuration of a feature flag
+type Flag struct {
+	Name    string `yaml:"name" json:"name"`       // e.g. userExample.cursorList
+	Enabled bool   `yaml:"enabled" json:"enabled"` // false means disabled for all the keys
+	// the percentage of the keys enabled, 1~99 means the rollout by the hash of the key, the others mean all the keys
+	Percentage int `yaml:"percentage" json:"percentage"`
+}
+
+var evaluations = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "featureflag_evaluations_total",
+	Help: "Total number of the evaluations of the feature flags by flag and result.",
+}, []string{"flag", "result"})
+
+// Option set the feature flags options.
+type Option func(*options)
+
+type options struct {
+	registerer prometheus.Registerer
+}
+
+func defaultOptions() *options {
+	return &options{
+		registerer: prometheus.DefaultRegisterer,
+	}
+}
+
+func (o *options) apply(opts ...Option) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithRegisterer set the registerer of the metrics, default prometheus.DefaultRegisterer, nil means no registering
+func WithRegisterer(reg prometheus.Registerer) Option {
+	return func(o *options) {
+		o.registerer = reg
+	}
+}
+
+// Flags the feature flags, it is safe for the concurrent evaluations and replacing
+type Flags struct {
+	flags       atomic.Value // map[string]Flag
+	evaluations *prometheus.CounterVec
+}
+
+// New create the feature flags, the evaluations are counted by the metrics featureflag_evaluations_total{flag, result}
+func New(flags []Flag, opts ...Option) *Flags {
+	o := defaultOptions()
+	o.apply(opts...)
+
+	f := &Flags{evaluations: registerCounterVec(o.registerer, evaluations)}
+	f.Set(flags)
+	return f
+}
+
+// Set replace all the flags, e.g. when the configuration is changed, the flags not in the list are disabled
+func (f *Flags) Set(flags []Flag) {
+	m := make(map[string]Flag, len(flags))
+	for _, flag := range flags {
+		if flag.Name != "" {
+			m[flag.Name] = flag
+		}
+	}
+	f.flags.Store(m)
+}
+
+// Get the configuration of the flag
+func (f *Flags) Get(name string) (Flag, bool) {
+	flag, ok := f.flags.Load().(map[string]Flag)[name]
+	return flag, ok
+}
+
+// Enabled evaluate the flag for the key, e.g. the user id or the tenant id, the key is required by the rollout
+// of the percentage, the empty key is disabled. The unknown flag is disabled.
+func (f *Flags) Enabled(name string, key string) bool {
+	flag, ok := f.Get(name)
+	enabled := ok && flag.enabled(key)
+
+	result := ResultDisabled
+	if !ok {
+		result = ResultUnknown
+	} else if enabled {
+		result = ResultEnabled
+	}
+	f.evaluations.WithLabelValues(name, result).Inc()
+
+	return enabled
+}
+
+func (flag Flag) enabled(key string) bool {
+	if !flag.Enabled {
+		return false
+	}
+	if flag.Percentage <= 0 || flag.Percentage >= 100 {
+		return true
+	}
+	if key == "" {
+		return false
+	}
+	return Bucket(flag.Name, key) < flag.Percentage
+}
+
+// Bucket the bucket 0~99 of the key for the flag, it is stable for the same flag and key, and the buckets of
+// the different flags are independent, so the same keys are not always the first to get the new features.
+func Bucket(name string, key string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % 100)
+}
+
+// Parse parse the flags of the key in the configuration content, e.g. the content got from nacos and the key
+// "featureFlags", the format is json, yaml or toml. There is no flag if the key does not exist.
+func Parse(format string, data []byte, key string) ([]Flag, error) {
+	v := viper.New()
+	v.SetConfigType(format)
+	if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	var flags []Flag
+	if err := v.UnmarshalKey(key, &flags); err != nil {
+		return nil, err
+	}
+	return flags, nil
+}
+
+func registerCounterVec(reg prometheus.Registerer, c *prometheus.CounterVec) *prometheus.CounterVec {
+	if reg == nil {
+		return c
+	}
+	if err := reg.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(*prometheus.CounterVec); ok {
+				return existing
+			}
+		}
+	}
+	return c
+}
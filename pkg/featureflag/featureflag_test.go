@@ -0,0 +1,106 @@
+package featureflag
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlags_Enabled(t *testing.T) {
+	f := New([]Flag{
+		{Name: "on", Enabled: true},
+		{Name: "off", Enabled: false, Percentage: 100},
+		{Name: "half", Enabled: true, Percentage: 50},
+	}, WithRegisterer(prometheus.NewRegistry()))
+
+	assert.True(t, f.Enabled("on", ""))
+	assert.False(t, f.Enabled("off", "1"))
+	assert.False(t, f.Enabled("unknown", "1"))
+	assert.False(t, f.Enabled("half", "")) // the rollout requires the key
+
+	for i := 0; i < 100; i++ {
+		key := strconv.Itoa(i)
+		assert.Equal(t, Bucket("half", key) < 50, f.Enabled("half", key))
+	}
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(f.evaluations.WithLabelValues("on", ResultEnabled)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(f.evaluations.WithLabelValues("unknown", ResultUnknown)))
+}
+
+func TestFlags_Set(t *testing.T) {
+	f := New([]Flag{{Name: "foo", Enabled: false}}, WithRegisterer(nil))
+	assert.False(t, f.Enabled("foo", "1"))
+
+	f.Set([]Flag{{Name: "foo", Enabled: true}})
+	assert.True(t, f.Enabled("foo", "1"))
+
+	f.Set(nil)
+	_, ok := f.Get("foo")
+	assert.False(t, ok)
+	assert.False(t, f.Enabled("foo", "1"))
+}
+
+func TestBucket(t *testing.T) {
+	// stable for the same flag and key
+	assert.Equal(t, Bucket("foo", "tenant-1"), Bucket("foo", "tenant-1"))
+	assert.Equal(t, 91, Bucket("foo", "tenant-1")) // changing the hash moves the keys between the buckets
+
+	// roughly uniform
+	const n = 10000
+	enabled := 0
+	for i := 0; i < n; i++ {
+		b := Bucket("foo", strconv.Itoa(i))
+		require.True(t, b >= 0 && b < 100)
+		if b < 20 {
+			enabled++
+		}
+	}
+	assert.InDelta(t, 0.2, float64(enabled)/n, 0.02)
+
+	// the buckets of the different flags are independent
+	same := 0
+	for i := 0; i < 100; i++ {
+		key := strconv.Itoa(i)
+		if Bucket("foo", key) == Bucket("bar", key) {
+			same++
+		}
+	}
+	assert.Less(t, same, 10)
+}
+
+func TestParse(t *testing.T) {
+	data := []byte(`
+app:
+  name: "foo"
+featureFlags:
+  - name: "userExample.cursorList"
+    enabled: true
+    percentage: 10
+  - name: "bar"
+    enabled: false
+`)
+	flags, err := Parse("yaml", data, "featureFlags")
+	require.NoError(t, err)
+	assert.Equal(t, []Flag{
+		{Name: "userExample.cursorList", Enabled: true, Percentage: 10},
+		{Name: "bar"},
+	}, flags)
+
+	flags, err = Parse("yaml", data, "none")
+	require.NoError(t, err)
+	assert.Empty(t, flags)
+
+	_, err = Parse("yaml", []byte("featureFlags: ["), "featureFlags")
+	assert.Error(t, err)
+}
+
+func TestNew_AlreadyRegistered(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	f1 := New(nil, WithRegisterer(reg))
+	f2 := New(nil, WithRegisterer(reg))
+	assert.Same(t, f1.evaluations, f2.evaluations)
+}
@@ -0,0 +1,89 @@
+package handlerfunc
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/go-dev-frame/sponge/pkg/utils"
+)
+
+// the gates of the readiness, the service is ready after all the gates are opened without error
+var readiness = struct {
+	mu    sync.RWMutex
+	names []string
+	gates map[string]*readinessGate
+}{gates: map[string]*readinessGate{}}
+
+type readinessGate struct {
+	opened bool
+	err    error
+}
+
+// AddReadinessGate add the gate to the readiness checked by CheckReady, the service is not ready until the
+// returned function is called with nil error, e.g. the verification of the database at startup. The function
+// can be called again to change the state of the gate, the gate of the same name is replaced.
+func AddReadinessGate(name string) (open func(err error)) {
+	readiness.mu.Lock()
+	if _, ok := readiness.gates[name]; !ok {
+		readiness.names = append(readiness.names, name)
+	}
+	readiness.gates[name] = &readinessGate{}
+	readiness.mu.Unlock()
+
+	return func(err error) {
+		readiness.mu.Lock()
+		defer readiness.mu.Unlock()
+		if gate, ok := readiness.gates[name]; ok {
+			gate.opened, gate.err = true, err
+		}
+	}
+}
+
+// CheckReadyReply check ready result, Reasons are the gates not ready, the key is the name of the gate
+type CheckReadyReply struct {
+	Status   string            `json:"status"`
+	Hostname string            `json:"hostname"`
+	Reasons  map[string]string `json:"reasons,omitempty"`
+}
+
+// CheckReady check ready, 503 is returned until all the gates added by AddReadinessGate are opened, it is used as
+// the readiness probe, the traffic only arrives after the startup verifications.
+// @Summary check ready
+// @Description check ready
+// @Tags system
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} CheckReadyReply{}
+// @Router /ready [get]
+func CheckReady(c *gin.Context) {
+	reasons := notReadyReasons()
+	if len(reasons) > 0 {
+		c.JSON(http.StatusServiceUnavailable, CheckReadyReply{Status: "DOWN", Hostname: utils.GetHostname(), Reasons: reasons})
+		return
+	}
+	c.JSON(http.StatusOK, CheckReadyReply{Status: "UP", Hostname: utils.GetHostname()})
+}
+
+func notReadyReasons() map[string]string {
+	readiness.mu.RLock()
+	defer readiness.mu.RUnlock()
+
+	var reasons map[string]string
+	for _, name := range readiness.names {
+		gate := readiness.gates[name]
+		if gate.opened && gate.err == nil {
+			continue
+		}
+		if reasons == nil {
+			reasons = map[string]string{}
+		}
+		if gate.opened {
+			reasons[name] = gate.err.Error()
+		} else {
+			reasons[name] = "pending"
+		}
+	}
+	return reasons
+}
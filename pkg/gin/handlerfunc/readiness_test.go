@@ -0,0 +1,59 @@
+package handlerfunc
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func checkReady(t *testing.T) (int, CheckReadyReply) {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.GET("/ready", CheckReady)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+	reply := CheckReadyReply{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &reply))
+	return w.Code, reply
+}
+
+func TestCheckReady(t *testing.T) {
+	code, reply := checkReady(t)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "UP", reply.Status)
+
+	openMongo := AddReadinessGate("mongodb")
+	openCache := AddReadinessGate("cache")
+	defer func() {
+		openMongo(nil)
+		openCache(nil)
+	}()
+	code, reply = checkReady(t)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, map[string]string{"mongodb": "pending", "cache": "pending"}, reply.Reasons)
+
+	openMongo(errors.New("missing index"))
+	openCache(nil)
+	code, reply = checkReady(t)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "DOWN", reply.Status)
+	assert.Equal(t, map[string]string{"mongodb": "missing index"}, reply.Reasons)
+
+	openMongo(nil)
+	code, reply = checkReady(t)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, reply.Reasons)
+
+	// the gate of the same name is replaced
+	AddReadinessGate("cache")
+	code, reply = checkReady(t)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, map[string]string{"cache": "pending"}, reply.Reasons)
+}
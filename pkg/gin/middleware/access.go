@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/go-dev-frame/sponge/pkg/errcode"
+	"github.com/go-dev-frame/sponge/pkg/gin/response"
+)
+
+// IPAllowList allow the requests of the client ips in the list only, the elements are ips or CIDRs, e.g.
+// 127.0.0.1, 10.0.0.0/8, ::1, the other requests get 403 in the standard error format of response.Out.
+// The empty list allows all the requests, the invalid element panics.
+// Note that the ip of the direct peer is checked, see gin.Context.RemoteIP, the X-Forwarded-For and X-Real-IP
+// headers are ignored because they are set by the clients, add the ips of the proxies to the list if the
+// server is behind the proxies.
+func IPAllowList(ips ...string) gin.HandlerFunc {
+	var nets []*net.IPNet
+	for _, ip := range ips {
+		ip = strings.TrimSpace(ip)
+		if ip == "" {
+			continue
+		}
+		if !strings.Contains(ip, "/") {
+			if strings.Contains(ip, ":") {
+				ip += "/128"
+			} else {
+				ip += "/32"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(ip)
+		if err != nil {
+			panic("invalid ip or CIDR of the allow list: " + err.Error())
+		}
+		nets = append(nets, ipNet)
+	}
+
+	return func(c *gin.Context) {
+		if len(nets) == 0 {
+			c.Next()
+			return
+		}
+		if ip := net.ParseIP(c.RemoteIP()); ip != nil {
+			for _, ipNet := range nets {
+				if ipNet.Contains(ip) {
+					c.Next()
+					return
+				}
+			}
+		}
+		response.Out(c, errcode.Forbidden)
+		c.Abort()
+	}
+}
+
+// APIKey allow the requests with one of the keys in the X-Api-Key header only, the other requests get 401 in
+// the standard error format of response.Out, the requests are rejected if there is no key.
+func APIKey(keys ...string) gin.HandlerFunc {
+	var validKeys [][]byte
+	for _, key := range keys {
+		if key != "" {
+			validKeys = append(validKeys, []byte(key))
+		}
+	}
+
+	return func(c *gin.Context) {
+		key := []byte(c.GetHeader("X-Api-Key"))
+		if len(key) > 0 {
+			for _, validKey := range validKeys {
+				if subtle.ConstantTimeCompare(key, validKey) == 1 {
+					c.Next()
+					return
+				}
+			}
+		}
+		response.Out(c, errcode.Unauthorized)
+		c.Abort()
+	}
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-dev-frame/sponge/pkg/gin/response"
+)
+
+func TestIPAllowList(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.GET("/allow", IPAllowList("127.0.0.1", " 10.0.0.0/8", "::1", ""), func(c *gin.Context) { response.Success(c) })
+	r.GET("/all", IPAllowList(), func(c *gin.Context) { response.Success(c) })
+
+	request := func(path string, remoteAddr string, headers ...string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remoteAddr
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request("/allow", "127.0.0.1:1234"))
+	assert.Equal(t, http.StatusOK, request("/allow", "10.1.2.3:1234"))
+	assert.Equal(t, http.StatusOK, request("/allow", "[::1]:1234"))
+	assert.Equal(t, http.StatusForbidden, request("/allow", "192.168.1.1:1234"))
+	assert.Equal(t, http.StatusForbidden, request("/allow", "invalid"))
+	assert.Equal(t, http.StatusOK, request("/all", "192.168.1.1:1234"))
+
+	// the forwarded headers set by the client are not trusted, the engine trusts all proxies by default
+	assert.Equal(t, http.StatusForbidden, request("/allow", "203.0.113.9:1234", "X-Forwarded-For", "127.0.0.1"))
+	assert.Equal(t, http.StatusForbidden, request("/allow", "203.0.113.9:1234", "X-Real-IP", "127.0.0.1"))
+	assert.Equal(t, http.StatusOK, request("/allow", "127.0.0.1:1234", "X-Forwarded-For", "203.0.113.9"))
+
+	assert.Panics(t, func() { IPAllowList("10.0.0.0/33") })
+	assert.Panics(t, func() { IPAllowList("localhost") })
+}
+
+func TestAPIKey(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.GET("/key", APIKey("foo", "bar", ""), func(c *gin.Context) { response.Success(c) })
+	r.GET("/nokey", APIKey(), func(c *gin.Context) { response.Success(c) })
+
+	request := func(path string, apiKey string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if apiKey != "" {
+			req.Header.Set("X-Api-Key", apiKey)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request("/key", "foo"))
+	assert.Equal(t, http.StatusOK, request("/key", "bar"))
+	assert.Equal(t, http.StatusUnauthorized, request("/key", "baz"))
+	assert.Equal(t, http.StatusUnauthorized, request("/key", ""))
+	assert.Equal(t, http.StatusUnauthorized, request("/nokey", ""))
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/go-dev-frame/sponge/pkg/gin/response"
+	"github.com/go-dev-frame/sponge/pkg/utils"
+)
+
+// HeaderRequestTimeoutKey the header of the timeout of the client, e.g. 2s, 500ms, or the seconds 1.5
+const HeaderRequestTimeoutKey = "X-Request-Timeout"
+
+// BudgetOption set the deadline budget options.
+type BudgetOption func(*budgetOptions)
+
+type budgetOptions struct {
+	maxTimeout time.Duration
+}
+
+func defaultBudgetOptions() *budgetOptions {
+	return &budgetOptions{
+		maxTimeout: 30 * time.Second,
+	}
+}
+
+func (o *budgetOptions) apply(opts ...BudgetOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithBudgetMaxTimeout set the max timeout of the request, the timeout of the client is bounded by it, default 30s
+func WithBudgetMaxTimeout(d time.Duration) BudgetOption {
+	return func(o *budgetOptions) {
+		if d > 0 {
+			o.maxTimeout = d
+		}
+	}
+}
+
+// RequestBudget set the deadline of the request context by the header X-Request-Timeout of the client, the timeout
+// is bounded by the max timeout, so the dao and the outbound calls derive their timeouts from the remaining budget
+// by utils.CallContext, instead of working after the client is gone. The requests without the header or with the
+// invalid header are not changed. If the budget is exhausted and the handler has not responded, 504 is responded
+// with the code of errcode.GatewayTimeout, the handler responds the same by response.Error with the error of
+// utils.ErrBudgetExhausted.
+func RequestBudget(opts ...BudgetOption) gin.HandlerFunc {
+	o := defaultBudgetOptions()
+	o.apply(opts...)
+
+	return func(c *gin.Context) {
+		timeout, ok := parseRequestTimeout(c.GetHeader(HeaderRequestTimeoutKey))
+		if !ok {
+			c.Next()
+			return
+		}
+		if timeout > o.maxTimeout {
+			timeout = o.maxTimeout
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if ctx.Err() == context.DeadlineExceeded && !c.Writer.Written() {
+			response.Error(c, utils.ErrBudgetExhausted)
+			c.Abort()
+		}
+	}
+}
+
+// the timeout is a duration, e.g. 2s, or the seconds, e.g. 1.5
+func parseRequestTimeout(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, false
+		}
+		d = time.Duration(seconds * float64(time.Second))
+	}
+	if d <= 0 {
+		return 0, false
+	}
+	return d, true
+}
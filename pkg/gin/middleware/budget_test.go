@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-dev-frame/sponge/pkg/errcode"
+	"github.com/go-dev-frame/sponge/pkg/gin/response"
+	"github.com/go-dev-frame/sponge/pkg/utils"
+)
+
+func newBudgetRequest(timeout string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/budget", nil)
+	if timeout != "" {
+		req.Header.Set(HeaderRequestTimeoutKey, timeout)
+	}
+	return req
+}
+
+func TestRequestBudget(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	var remaining time.Duration
+	var hasDeadline bool
+	r := gin.New()
+	r.GET("/budget", RequestBudget(WithBudgetMaxTimeout(time.Second)), func(c *gin.Context) {
+		var deadline time.Time
+		deadline, hasDeadline = c.Request.Context().Deadline()
+		remaining = time.Until(deadline)
+		response.Success(c)
+	})
+
+	tests := []struct {
+		timeout     string
+		hasDeadline bool
+		max         time.Duration
+	}{
+		{"200ms", true, 200 * time.Millisecond},
+		{"0.2", true, 200 * time.Millisecond},
+		{"10s", true, time.Second}, // bounded by the max timeout
+		{"", false, 0},
+		{"abc", false, 0},
+		{"-1s", false, 0},
+	}
+	for _, tt := range tests {
+		w := serve(r, newBudgetRequest(tt.timeout))
+		assert.Equal(t, http.StatusOK, w.Code, tt.timeout)
+		assert.Equal(t, tt.hasDeadline, hasDeadline, tt.timeout)
+		if tt.hasDeadline {
+			assert.True(t, remaining <= tt.max && remaining > tt.max-100*time.Millisecond, tt.timeout, remaining)
+		}
+	}
+}
+
+func TestRequestBudget_Exhausted(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(RequestBudget())
+
+	// the handler is not responded before the deadline
+	r.GET("/budget", func(c *gin.Context) {
+		<-c.Request.Context().Done()
+	})
+	// the call of the handler is not issued, the remaining budget is less than the floor
+	r.GET("/call", func(c *gin.Context) {
+		time.Sleep(80 * time.Millisecond)
+		_, _, err := utils.CallContext(c.Request.Context())
+		response.Error(c, err)
+	})
+
+	assertGatewayTimeout := func(w *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+		result := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		assert.Equal(t, float64(errcode.GatewayTimeout.Code()), result["code"])
+	}
+	assertGatewayTimeout(serve(r, newBudgetRequest("50ms")))
+
+	req := newBudgetRequest("100ms")
+	req.URL.Path = "/call"
+	assertGatewayTimeout(serve(r, req))
+}
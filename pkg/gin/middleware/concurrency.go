@@ -0,0 +1,212 @@
+package middleware
+
+import (
+	"errors"
+	"hash/fnv"
+	"strconv"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/go-dev-frame/sponge/pkg/errcode"
+	"github.com/go-dev-frame/sponge/pkg/gin/response"
+)
+
+// ConcurrencyOption set the concurrency limit options.
+type ConcurrencyOption func(*concurrencyOptions)
+
+type concurrencyOptions struct {
+	maxPerKey  int
+	maxTotal   int
+	key        func(c *gin.Context) string
+	keyBuckets int
+	registerer prometheus.Registerer
+}
+
+func defaultConcurrencyOptions() *concurrencyOptions {
+	return &concurrencyOptions{
+		maxPerKey:  10,
+		maxTotal:   1000,
+		key:        concurrencyKey,
+		keyBuckets: 64,
+		registerer: prometheus.DefaultRegisterer,
+	}
+}
+
+func (o *concurrencyOptions) apply(opts ...ConcurrencyOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithConcurrencyMaxPerKey set the max number of the in-flight requests per key, default 10
+func WithConcurrencyMaxPerKey(n int) ConcurrencyOption {
+	return func(o *concurrencyOptions) {
+		if n > 0 {
+			o.maxPerKey = n
+		}
+	}
+}
+
+// WithConcurrencyMaxTotal set the max number of the in-flight requests of all the keys, default 1000,
+// 0 means no global limit
+func WithConcurrencyMaxTotal(n int) ConcurrencyOption {
+	return func(o *concurrencyOptions) {
+		if n >= 0 {
+			o.maxTotal = n
+		}
+	}
+}
+
+// WithConcurrencyKey set the function returning the key of the principal, default the subject or the uid of
+// the jwt claims, the X-Api-Key header or the client ip in order
+func WithConcurrencyKey(fn func(c *gin.Context) string) ConcurrencyOption {
+	return func(o *concurrencyOptions) {
+		if fn != nil {
+			o.key = fn
+		}
+	}
+}
+
+// WithConcurrencyKeyBuckets set the number of the buckets the keys are hashed into for the label of the
+// in-flight gauge, it caps the cardinality of the metrics, default 64
+func WithConcurrencyKeyBuckets(n int) ConcurrencyOption {
+	return func(o *concurrencyOptions) {
+		if n > 0 {
+			o.keyBuckets = n
+		}
+	}
+}
+
+// WithConcurrencyRegisterer set the registerer of the metrics, default prometheus.DefaultRegisterer
+func WithConcurrencyRegisterer(reg prometheus.Registerer) ConcurrencyOption {
+	return func(o *concurrencyOptions) {
+		o.registerer = reg
+	}
+}
+
+var (
+	concurrencyInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gin_concurrency_in_flight_requests",
+		Help: "Number of the in-flight requests limited by the concurrency limit, by the hash bucket of the key.",
+	}, []string{"key_bucket"})
+	concurrencyRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "gin_concurrency_rejected_requests_total",
+		Help: "Total number of the requests rejected by the concurrency limit, by the exceeded limit, key or total.",
+	}, []string{"limit"})
+)
+
+func concurrencyKey(c *gin.Context) string {
+	if claims, ok := GetClaims(c); ok {
+		if claims.Subject != "" {
+			return "sub:" + claims.Subject
+		}
+		if claims.UID != "" {
+			return "uid:" + claims.UID
+		}
+	}
+	if key := c.GetHeader("X-Api-Key"); key != "" {
+		return "apikey:" + key
+	}
+	return "ip:" + c.ClientIP()
+}
+
+type concurrencyLimiter struct {
+	o        *concurrencyOptions
+	inFlight *prometheus.GaugeVec
+	rejected *prometheus.CounterVec
+
+	mu     sync.Mutex
+	total  int
+	counts map[string]int // key -> in-flight requests
+}
+
+// ConcurrencyLimit limit the number of the in-flight requests per principal and of all the principals, the
+// requests beyond the limits are rejected with 429 in the standard error format of response.Out, e.g. a
+// client opening hundreds of long-poll requests. The slot is released when the following handlers return,
+// including the handlers returning for the client disconnecting, so the handlers should respect the context
+// of the request. Use it after the jwt auth middleware so that the key is the principal instead of the ip.
+// The in-flight requests are exposed by the gauge gin_concurrency_in_flight_requests{key_bucket}, the keys
+// are hashed into the buckets to cap the cardinality, and the rejections are counted by
+// gin_concurrency_rejected_requests_total{limit}.
+func ConcurrencyLimit(opts ...ConcurrencyOption) gin.HandlerFunc {
+	o := defaultConcurrencyOptions()
+	o.apply(opts...)
+
+	l := &concurrencyLimiter{
+		o:        o,
+		inFlight: registerGaugeVec(o.registerer, concurrencyInFlight),
+		rejected: registerCounterVec(o.registerer, concurrencyRejected),
+		counts:   make(map[string]int),
+	}
+	return l.handle
+}
+
+// registerGaugeVec register the gauge, the existing one is returned if it is already registered,
+// the gauge is used without being exposed if it fails to register
+func registerGaugeVec(reg prometheus.Registerer, g *prometheus.GaugeVec) *prometheus.GaugeVec {
+	if reg == nil {
+		return g
+	}
+	if err := reg.Register(g); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(*prometheus.GaugeVec); ok {
+				return existing
+			}
+		}
+	}
+	return g
+}
+
+func (l *concurrencyLimiter) handle(c *gin.Context) {
+	key := l.o.key(c)
+	if limit, ok := l.acquire(key); !ok {
+		l.rejected.WithLabelValues(limit).Inc()
+		response.Out(c, errcode.TooManyRequests.RewriteMsg("too many concurrent requests"))
+		c.Abort()
+		return
+	}
+
+	gauge := l.inFlight.WithLabelValues(l.bucket(key))
+	gauge.Inc()
+	defer func() {
+		gauge.Dec()
+		l.release(key)
+	}()
+
+	c.Next()
+}
+
+// acquire a slot of the key, the exceeded limit is returned if there is no free slot
+func (l *concurrencyLimiter) acquire(key string) (limit string, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.o.maxTotal > 0 && l.total >= l.o.maxTotal {
+		return "total", false
+	}
+	if l.counts[key] >= l.o.maxPerKey {
+		return "key", false
+	}
+	l.counts[key]++
+	l.total++
+	return "", true
+}
+
+func (l *concurrencyLimiter) release(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.counts[key] <= 1 {
+		delete(l.counts, key)
+	} else {
+		l.counts[key]--
+	}
+	l.total--
+}
+
+func (l *concurrencyLimiter) bucket(key string) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return strconv.Itoa(int(h.Sum32() % uint32(l.o.keyBuckets)))
+}
@@ -0,0 +1,196 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-dev-frame/sponge/pkg/gin/response"
+	"github.com/go-dev-frame/sponge/pkg/jwt"
+)
+
+// the slow handler blocks until the release channel is closed or the client disconnects
+func newConcurrencyRouter(release chan struct{}, opts ...ConcurrencyOption) *gin.Engine {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.GET("/poll", ConcurrencyLimit(opts...), func(c *gin.Context) {
+		select {
+		case <-release:
+		case <-c.Request.Context().Done():
+			return
+		}
+		response.Success(c)
+	})
+	return r
+}
+
+func newPollRequest(ctx context.Context, apiKey string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/poll", nil).WithContext(ctx)
+	req.Header.Set("X-Api-Key", apiKey)
+	return req
+}
+
+// serve the request in background, the returned channel receives the response after the handler returns
+func serveAsync(r *gin.Engine, req *http.Request) <-chan *httptest.ResponseRecorder {
+	ch := make(chan *httptest.ResponseRecorder, 1)
+	go func() {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		ch <- w
+	}()
+	return ch
+}
+
+func serve(r *gin.Engine, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func inFlightOf(key string) float64 {
+	l := &concurrencyLimiter{o: defaultConcurrencyOptions()}
+	return testutil.ToFloat64(concurrencyInFlight.WithLabelValues(l.bucket(key)))
+}
+
+func TestConcurrencyLimit_PerKey(t *testing.T) {
+	release := make(chan struct{})
+	r := newConcurrencyRouter(release, WithConcurrencyMaxPerKey(2), WithConcurrencyRegisterer(prometheus.NewRegistry()))
+	rejected := testutil.ToFloat64(concurrencyRejected.WithLabelValues("key"))
+
+	ctx := context.Background()
+	first := serveAsync(r, newPollRequest(ctx, "foo"))
+	second := serveAsync(r, newPollRequest(ctx, "foo"))
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, float64(2), inFlightOf("apikey:foo"))
+
+	// the third request of the same key is rejected
+	w := serve(r, newPollRequest(ctx, "foo"))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Body.String(), "too many concurrent requests")
+	assert.Equal(t, rejected+1, testutil.ToFloat64(concurrencyRejected.WithLabelValues("key")))
+
+	// the other key is not affected
+	other := serveAsync(r, newPollRequest(ctx, "bar"))
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	for _, ch := range []<-chan *httptest.ResponseRecorder{first, second, other} {
+		assert.Equal(t, http.StatusOK, (<-ch).Code)
+	}
+
+	// the slots are released after the responses
+	assert.Equal(t, float64(0), inFlightOf("apikey:foo"))
+	w = serve(r, newPollRequest(ctx, "foo"))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestConcurrencyLimit_Total(t *testing.T) {
+	release := make(chan struct{})
+	r := newConcurrencyRouter(release, WithConcurrencyMaxPerKey(5), WithConcurrencyMaxTotal(2),
+		WithConcurrencyRegisterer(prometheus.NewRegistry()))
+
+	ctx := context.Background()
+	chs := []<-chan *httptest.ResponseRecorder{
+		serveAsync(r, newPollRequest(ctx, "foo")),
+		serveAsync(r, newPollRequest(ctx, "bar")),
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	response.SetMode(response.ModeProblem)
+	defer response.SetMode(response.ModeEnvelope)
+	w := serve(r, newPollRequest(ctx, "baz"))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), response.MIMEProblemJSON)
+
+	close(release)
+	for _, ch := range chs {
+		assert.Equal(t, http.StatusOK, (<-ch).Code)
+	}
+	w = serve(r, newPollRequest(ctx, "baz"))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestConcurrencyLimit_ClientDisconnect(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	r := newConcurrencyRouter(release, WithConcurrencyMaxPerKey(1), WithConcurrencyRegisterer(prometheus.NewRegistry()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := serveAsync(r, newPollRequest(ctx, "foo"))
+	time.Sleep(100 * time.Millisecond)
+
+	w := serve(r, newPollRequest(context.Background(), "foo"))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	// the client of the first request disconnects, its slot is released
+	cancel()
+	<-first
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	second := serveAsync(r, newPollRequest(ctx2, "foo"))
+	time.Sleep(100 * time.Millisecond)
+	w = serve(r, newPollRequest(context.Background(), "foo"))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code) // the second request holds the slot
+	cancel2()
+	<-second
+}
+
+func TestConcurrencyLimit_Key(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/poll", nil)
+	c.Request.RemoteAddr = "192.168.1.1:1234"
+	assert.Equal(t, "ip:192.168.1.1", concurrencyKey(c))
+
+	c.Request.Header.Set("X-Api-Key", "foo")
+	assert.Equal(t, "apikey:foo", concurrencyKey(c))
+
+	claims := &jwt.Claims{UID: "100"}
+	c.Set("claims", claims)
+	assert.Equal(t, "uid:100", concurrencyKey(c))
+	claims.Subject = "user-100"
+	assert.Equal(t, "sub:user-100", concurrencyKey(c))
+
+	// the buckets cap the cardinality
+	l := &concurrencyLimiter{o: &concurrencyOptions{keyBuckets: 4}}
+	buckets := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		buckets[l.bucket("ip:192.168.1."+strconv.Itoa(i))] = true
+	}
+	assert.LessOrEqual(t, len(buckets), 4)
+
+	// the metrics are registered once
+	reg := prometheus.NewRegistry()
+	_ = ConcurrencyLimit(WithConcurrencyRegisterer(reg), WithConcurrencyKey(func(c *gin.Context) string { return "" }),
+		WithConcurrencyKeyBuckets(8))
+	_ = ConcurrencyLimit(WithConcurrencyRegisterer(reg))
+	_ = ConcurrencyLimit(WithConcurrencyRegisterer(nil))
+}
+
+func TestConcurrencyLimit_Race(t *testing.T) {
+	release := make(chan struct{})
+	close(release)
+	r := newConcurrencyRouter(release, WithConcurrencyMaxPerKey(3), WithConcurrencyRegisterer(prometheus.NewRegistry()))
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := serve(r, newPollRequest(context.Background(), "foo"))
+			assert.Contains(t, []int{http.StatusOK, http.StatusTooManyRequests}, w.Code)
+		}()
+	}
+	wg.Wait()
+
+	// all the slots are released
+	assert.Equal(t, float64(0), inFlightOf("apikey:foo"))
+	w := serve(r, newPollRequest(context.Background(), "foo"))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,245 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DedupOption set the dedup options.
+type DedupOption func(*dedupOptions)
+
+type dedupOptions struct {
+	vary       func(c *gin.Context) string
+	maxShared  int
+	registerer prometheus.Registerer
+}
+
+func defaultDedupOptions() *dedupOptions {
+	return &dedupOptions{
+		vary: func(c *gin.Context) string {
+			return c.GetHeader(HeaderAuthorizationKey)
+		},
+		maxShared:  100,
+		registerer: prometheus.DefaultRegisterer,
+	}
+}
+
+func (o *dedupOptions) apply(opts ...DedupOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithDedupVary set the function returning the part of the key besides the method and the url, e.g. the tenant id,
+// the requests are identical only if they have the same value, default the Authorization header
+func WithDedupVary(fn func(c *gin.Context) string) DedupOption {
+	return func(o *dedupOptions) {
+		if fn != nil {
+			o.vary = fn
+		}
+	}
+}
+
+// WithDedupMaxShared set the max number of the requests waiting for one in-flight request, the requests
+// beyond it start a new in-flight request, default 100
+func WithDedupMaxShared(n int) DedupOption {
+	return func(o *dedupOptions) {
+		if n > 0 {
+			o.maxShared = n
+		}
+	}
+}
+
+// WithDedupRegisterer set the registerer of the metrics, default prometheus.DefaultRegisterer
+func WithDedupRegisterer(reg prometheus.Registerer) DedupOption {
+	return func(o *dedupOptions) {
+		o.registerer = reg
+	}
+}
+
+var dedupRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "gin_dedup_requests_total",
+	Help: "Total number of the deduplicated read requests by result, executed, shared or bypassed.",
+}, []string{"route", "result"})
+
+type dedupFlight struct {
+	done   chan struct{}
+	shared int // the number of the waiting requests, guarded by deduper.mu
+
+	ok     bool // false if the handler panicked
+	status int
+	header http.Header
+	body   []byte
+}
+
+type deduper struct {
+	o        *dedupOptions
+	requests *prometheus.CounterVec
+
+	mu      sync.Mutex
+	flights map[string]*dedupFlight
+}
+
+// Dedup the concurrent identical GET and HEAD requests share one execution of the following handlers, the
+// response is buffered and written to all of them. The requests are identical if they have the same method,
+// url (including the path parameters and query) and vary value. The requests with "Cache-Control: no-cache"
+// are not shared. The shared execution is not canceled if the client of the request is gone, and it is
+// not suitable for streaming responses.
+func Dedup(opts ...DedupOption) gin.HandlerFunc {
+	o := defaultDedupOptions()
+	o.apply(opts...)
+
+	d := &deduper{
+		o:        o,
+		requests: registerCounterVec(o.registerer, dedupRequests),
+		flights:  make(map[string]*dedupFlight),
+	}
+	return d.handle
+}
+
+// registerCounterVec register the counter, the existing one is returned if it is already registered,
+// the counter is used without being exposed if it fails to register
+func registerCounterVec(reg prometheus.Registerer, c *prometheus.CounterVec) *prometheus.CounterVec {
+	if reg == nil {
+		return c
+	}
+	if err := reg.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(*prometheus.CounterVec); ok {
+				return existing
+			}
+		}
+	}
+	return c
+}
+
+func (d *deduper) handle(c *gin.Context) {
+	route := c.FullPath()
+	method := c.Request.Method
+	if (method != http.MethodGet && method != http.MethodHead) ||
+		strings.Contains(strings.ToLower(c.GetHeader("Cache-Control")), "no-cache") {
+		d.requests.WithLabelValues(route, "bypassed").Inc()
+		c.Next()
+		return
+	}
+
+	key := method + " " + c.Request.URL.RequestURI() + " " + d.o.vary(c)
+	d.mu.Lock()
+	if f, ok := d.flights[key]; ok && f.shared < d.o.maxShared {
+		f.shared++
+		d.mu.Unlock()
+		select {
+		case <-f.done:
+		case <-c.Request.Context().Done():
+			c.Abort()
+			return
+		}
+		if f.ok {
+			d.requests.WithLabelValues(route, "shared").Inc()
+			writeFlight(c, f)
+			c.Abort()
+			return
+		}
+		// the shared execution panicked, execute it separately
+		d.requests.WithLabelValues(route, "executed").Inc()
+		c.Next()
+		return
+	}
+	f := &dedupFlight{done: make(chan struct{})}
+	d.flights[key] = f
+	d.mu.Unlock()
+
+	d.requests.WithLabelValues(route, "executed").Inc()
+	d.execute(c, key, f)
+}
+
+func (d *deduper) execute(c *gin.Context, key string, f *dedupFlight) {
+	req, writer := c.Request, c.Writer
+	w := &dedupWriter{ResponseWriter: writer, status: http.StatusOK}
+	c.Writer = w
+	// the execution is shared, it is not canceled by the client of this request
+	c.Request = req.WithContext(context.WithoutCancel(req.Context()))
+
+	defer func() {
+		c.Request, c.Writer = req, writer
+		d.mu.Lock()
+		if d.flights[key] == f {
+			delete(d.flights, key)
+		}
+		d.mu.Unlock()
+		close(f.done)
+	}()
+
+	c.Next()
+
+	f.ok, f.status, f.header, f.body = true, w.status, writer.Header().Clone(), w.body.Bytes()
+	writer.WriteHeader(f.status)
+	writer.WriteHeaderNow()
+	_, _ = writer.Write(f.body)
+}
+
+// writeFlight write the shared response, the headers already set by the request are kept
+func writeFlight(c *gin.Context, f *dedupFlight) {
+	header := c.Writer.Header()
+	for k, v := range f.header {
+		if _, ok := header[k]; !ok {
+			header[k] = v
+		}
+	}
+	c.Writer.WriteHeader(f.status)
+	c.Writer.WriteHeaderNow()
+	_, _ = c.Writer.Write(f.body)
+}
+
+// dedupWriter buffer the response of the shared execution
+type dedupWriter struct {
+	gin.ResponseWriter
+	status  int
+	written bool
+	body    bytes.Buffer
+}
+
+func (w *dedupWriter) WriteHeader(code int) {
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *dedupWriter) WriteHeaderNow() {
+	w.written = true
+}
+
+func (w *dedupWriter) Write(data []byte) (int, error) {
+	w.written = true
+	return w.body.Write(data)
+}
+
+func (w *dedupWriter) WriteString(s string) (int, error) {
+	w.written = true
+	return w.body.WriteString(s)
+}
+
+func (w *dedupWriter) Status() int {
+	return w.status
+}
+
+func (w *dedupWriter) Size() int {
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *dedupWriter) Written() bool {
+	return w.written
+}
+
+func (w *dedupWriter) Flush() {}
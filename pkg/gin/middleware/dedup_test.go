@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-dev-frame/sponge/pkg/gin/response"
+)
+
+// slowDao a fake dao blocking until released
+type slowDao struct {
+	calls   int32
+	release chan struct{}
+}
+
+func (d *slowDao) GetByID(id string) map[string]string {
+	atomic.AddInt32(&d.calls, 1)
+	<-d.release
+	return map[string]string{"id": id, "name": "foo"}
+}
+
+func newDedupRouter(dao *slowDao, opts ...DedupOption) *gin.Engine {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(gin.Recovery())
+	r.GET("/userExample/:id", Dedup(opts...), func(c *gin.Context) {
+		if c.Query("panic") == "true" {
+			dao.GetByID(c.Param("id"))
+			panic("dao panic")
+		}
+		c.Header("X-Dao", "slow")
+		response.Success(c, gin.H{"userExample": dao.GetByID(c.Param("id"))})
+	})
+	return r
+}
+
+// fire the concurrent requests, and release the dao after they are all waiting
+func fireRequests(r *gin.Engine, dao *slowDao, reqs ...*http.Request) []*httptest.ResponseRecorder {
+	recorders := make([]*httptest.ResponseRecorder, len(reqs))
+	wg := &sync.WaitGroup{}
+	for i, req := range reqs {
+		recorders[i] = httptest.NewRecorder()
+		wg.Add(1)
+		go func(w *httptest.ResponseRecorder, req *http.Request) {
+			defer wg.Done()
+			r.ServeHTTP(w, req)
+		}(recorders[i], req)
+	}
+	time.Sleep(100 * time.Millisecond)
+	close(dao.release)
+	wg.Wait()
+	return recorders
+}
+
+func newGetRequests(n int, url string, header map[string]string) []*http.Request {
+	reqs := make([]*http.Request, n)
+	for i := range reqs {
+		reqs[i] = httptest.NewRequest(http.MethodGet, url, nil)
+		for k, v := range header {
+			reqs[i].Header.Set(k, v)
+		}
+	}
+	return reqs
+}
+
+func TestDedup(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	dao := &slowDao{release: make(chan struct{})}
+	r := newDedupRouter(dao, WithDedupRegisterer(reg))
+
+	const n = 20
+	recorders := fireRequests(r, dao, newGetRequests(n, "/userExample/1", nil)...)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&dao.calls))
+	for _, w := range recorders {
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "slow", w.Header().Get("X-Dao"))
+		assert.Equal(t, recorders[0].Body.String(), w.Body.String())
+	}
+	assert.Contains(t, recorders[0].Body.String(), `"name":"foo"`)
+	assert.Equal(t, float64(1), testutil.ToFloat64(dedupRequests.WithLabelValues("/userExample/:id", "executed")))
+	assert.Equal(t, float64(n-1), testutil.ToFloat64(dedupRequests.WithLabelValues("/userExample/:id", "shared")))
+	mfs, err := reg.Gather()
+	assert.NoError(t, err)
+	assert.Len(t, mfs, 1)
+	dedupRequests.Reset()
+
+	// the different ids, query and vary values are not shared
+	dao = &slowDao{release: make(chan struct{})}
+	r = newDedupRouter(dao, WithDedupRegisterer(nil))
+	reqs := append(newGetRequests(2, "/userExample/1", nil), newGetRequests(2, "/userExample/2", nil)...)
+	reqs = append(reqs, newGetRequests(2, "/userExample/1?fields=name", nil)...)
+	reqs = append(reqs, newGetRequests(2, "/userExample/1", map[string]string{HeaderAuthorizationKey: "Bearer foo"})...)
+	fireRequests(r, dao, reqs...)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&dao.calls))
+	dedupRequests.Reset()
+}
+
+func TestDedup_Bypass(t *testing.T) {
+	dao := &slowDao{release: make(chan struct{})}
+	r := newDedupRouter(dao, WithDedupRegisterer(nil))
+
+	recorders := fireRequests(r, dao, newGetRequests(3, "/userExample/1", map[string]string{"Cache-Control": "no-cache"})...)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&dao.calls))
+	for _, w := range recorders {
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	assert.Equal(t, float64(3), testutil.ToFloat64(dedupRequests.WithLabelValues("/userExample/:id", "bypassed")))
+	dedupRequests.Reset()
+}
+
+func TestDedup_MaxShared(t *testing.T) {
+	dao := &slowDao{release: make(chan struct{})}
+	r := newDedupRouter(dao, WithDedupRegisterer(nil), WithDedupMaxShared(2),
+		WithDedupVary(func(c *gin.Context) string { return c.GetHeader("X-Tenant-Id") }))
+
+	// an execution is shared by at most 2 waiting requests
+	recorders := fireRequests(r, dao, newGetRequests(6, "/userExample/1", map[string]string{"X-Tenant-Id": "t1"})...)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&dao.calls))
+	for _, w := range recorders {
+		assert.Equal(t, recorders[0].Body.String(), w.Body.String())
+	}
+	dedupRequests.Reset()
+}
+
+func TestDedup_Panic(t *testing.T) {
+	dao := &slowDao{release: make(chan struct{})}
+	r := newDedupRouter(dao, WithDedupRegisterer(nil))
+
+	// the waiting requests are executed separately if the shared execution panicked
+	recorders := fireRequests(r, dao, newGetRequests(3, "/userExample/1?panic=true", nil)...)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&dao.calls))
+	for _, w := range recorders {
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	}
+	dedupRequests.Reset()
+}
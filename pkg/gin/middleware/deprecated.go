@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// DeprecatedOption set the deprecated options.
+type DeprecatedOption func(*deprecatedOptions)
+
+type deprecatedOptions struct {
+	log        *zap.Logger
+	clientKey  func(c *gin.Context) string
+	registerer prometheus.Registerer
+	now        func() time.Time
+}
+
+func defaultDeprecatedOptions() *deprecatedOptions {
+	return &deprecatedOptions{
+		log:        defaultLogger,
+		clientKey:  deprecatedClientKey,
+		registerer: prometheus.DefaultRegisterer,
+		now:        time.Now,
+	}
+}
+
+func (o *deprecatedOptions) apply(opts ...DeprecatedOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithDeprecatedLogger set the logger of the first use of the deprecated route per client
+func WithDeprecatedLogger(log *zap.Logger) DeprecatedOption {
+	return func(o *deprecatedOptions) {
+		if log != nil {
+			o.log = log
+		}
+	}
+}
+
+// WithDeprecatedClientKey set the function returning the key of the client, default the X-Api-Key header,
+// the uid of the jwt claims or the client ip in order
+func WithDeprecatedClientKey(fn func(c *gin.Context) string) DeprecatedOption {
+	return func(o *deprecatedOptions) {
+		if fn != nil {
+			o.clientKey = fn
+		}
+	}
+}
+
+// WithDeprecatedRegisterer set the registerer of the metrics, default prometheus.DefaultRegisterer
+func WithDeprecatedRegisterer(reg prometheus.Registerer) DeprecatedOption {
+	return func(o *deprecatedOptions) {
+		o.registerer = reg
+	}
+}
+
+var deprecatedRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "gin_deprecated_route_requests_total",
+	Help: "Total number of the requests of the deprecated routes by route template.",
+}, []string{"method", "route"})
+
+func deprecatedClientKey(c *gin.Context) string {
+	if key := c.GetHeader("X-Api-Key"); key != "" {
+		return "apikey:" + key
+	}
+	if claims, ok := GetClaims(c); ok && claims.UID != "" {
+		return "uid:" + claims.UID
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// Deprecated mark the route or the group as deprecated, the headers `Deprecation: true`, `Sunset: <http-date>`
+// and `Link: <link>; rel="deprecation"` are added to the responses, the sunset is omitted if it is zero, and
+// the link is omitted if it is empty. The first use of the route per client is logged once a day, and the
+// requests are counted by the metrics gin_deprecated_route_requests_total{method, route}.
+func Deprecated(sunset time.Time, link string, opts ...DeprecatedOption) gin.HandlerFunc {
+	o := defaultDeprecatedOptions()
+	o.apply(opts...)
+	requests := registerCounterVec(o.registerer, deprecatedRequests)
+
+	sunsetValue := ""
+	if !sunset.IsZero() {
+		sunsetValue = sunset.UTC().Format(http.TimeFormat)
+	}
+	linkValue := ""
+	if link != "" {
+		linkValue = "<" + link + `>; rel="deprecation"`
+	}
+
+	var (
+		mu     sync.Mutex
+		day    string
+		logged = map[string]struct{}{} // the route and client keys logged today
+	)
+
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		header.Set("Deprecation", "true")
+		if sunsetValue != "" {
+			header.Set("Sunset", sunsetValue)
+		}
+		if linkValue != "" {
+			header.Add("Link", linkValue)
+		}
+
+		route := c.FullPath()
+		requests.WithLabelValues(c.Request.Method, route).Inc()
+
+		clientKey := o.clientKey(c)
+		key := c.Request.Method + " " + route + " " + clientKey
+		today := o.now().Format("2006-01-02")
+		mu.Lock()
+		if today != day {
+			day = today
+			logged = map[string]struct{}{}
+		}
+		_, ok := logged[key]
+		if !ok {
+			logged[key] = struct{}{}
+		}
+		mu.Unlock()
+		if !ok {
+			o.log.Warn("deprecated route is used",
+				zap.String("method", c.Request.Method),
+				zap.String("route", route),
+				zap.String("client", clientKey),
+				zap.String("sunset", sunsetValue),
+			)
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/go-dev-frame/sponge/pkg/gin/response"
+)
+
+func TestDeprecated(t *testing.T) {
+	core, logs := observer.New(zapcore.WarnLevel)
+	now := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	withClock := func(o *deprecatedOptions) { o.now = func() time.Time { return now } }
+	sunset := time.Date(2026, 6, 30, 0, 0, 0, 0, time.FixedZone("CST", 8*3600))
+
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	v1 := r.Group("/api/v1", Deprecated(sunset, "https://example.com/docs/v2",
+		WithDeprecatedLogger(zap.New(core)), WithDeprecatedRegisterer(prometheus.NewRegistry()), withClock))
+	v1.GET("/user/:id", func(c *gin.Context) { response.Success(c) })
+	r.GET("/api/v1/order/:id", Deprecated(time.Time{}, "", WithDeprecatedRegisterer(nil),
+		WithDeprecatedLogger(zap.New(core)), withClock), func(c *gin.Context) { response.Success(c) })
+	r.GET("/api/v2/user/:id", func(c *gin.Context) { response.Success(c) })
+
+	request := func(path string, apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if apiKey != "" {
+			req.Header.Set("X-Api-Key", apiKey)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request("/api/v1/user/1", "foo")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "true", w.Header().Get("Deprecation"))
+	assert.Equal(t, "Mon, 29 Jun 2026 16:00:00 GMT", w.Header().Get("Sunset"))
+	assert.Equal(t, `<https://example.com/docs/v2>; rel="deprecation"`, w.Header().Get("Link"))
+
+	w = request("/api/v1/order/1", "")
+	assert.Equal(t, "true", w.Header().Get("Deprecation"))
+	assert.Empty(t, w.Header().Get("Sunset"))
+	assert.Empty(t, w.Header().Get("Link"))
+
+	w = request("/api/v2/user/1", "")
+	assert.Empty(t, w.Header().Get("Deprecation"))
+
+	// logged once a day per client
+	logs.TakeAll()
+	for i := 0; i < 3; i++ {
+		request("/api/v1/user/1", "bar")
+		request("/api/v1/user/2", "bar") // the same route template
+	}
+	request("/api/v1/user/1", "baz")
+	entries := logs.TakeAll()
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "apikey:bar", entries[0].ContextMap()["client"])
+	assert.Equal(t, "/api/v1/user/:id", entries[0].ContextMap()["route"])
+	assert.Equal(t, "apikey:baz", entries[1].ContextMap()["client"])
+
+	now = now.Add(24 * time.Hour)
+	request("/api/v1/user/1", "bar")
+	request("/api/v1/user/1", "bar")
+	assert.Len(t, logs.TakeAll(), 1)
+
+	// the client ip is the default key without api key and jwt claims
+	request("/api/v1/user/1", "")
+	entries = logs.TakeAll()
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "ip:192.0.2.1", entries[0].ContextMap()["client"])
+
+	// metrics by route template
+	assert.Equal(t, float64(11), testutil.ToFloat64(deprecatedRequests.WithLabelValues(http.MethodGet, "/api/v1/user/:id")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(deprecatedRequests.WithLabelValues(http.MethodGet, "/api/v1/order/:id")))
+	deprecatedRequests.Reset()
+}
@@ -23,6 +23,7 @@ type rateLimitOptions struct {
 	bucket       int
 	cpuThreshold int64
 	cpuQuota     float64
+	limiter      rl.Limiter
 }
 
 func defaultRatelimitOptions() *rateLimitOptions {
@@ -67,16 +68,27 @@ func WithCPUQuota(quota float64) RateLimitOption {
 	}
 }
 
+// WithLimiter use the specified limiter instead of the adaptive limiter, e.g. the redis limiter
+// shared by all the replicas, the other options are ignored.
+func WithLimiter(limiter rl.Limiter) RateLimitOption {
+	return func(o *rateLimitOptions) {
+		o.limiter = limiter
+	}
+}
+
 // RateLimit an adaptive rate limiter middleware
 func RateLimit(opts ...RateLimitOption) gin.HandlerFunc {
 	o := defaultRatelimitOptions()
 	o.apply(opts...)
-	limiter := rl.NewLimiter(
-		rl.WithWindow(o.window),
-		rl.WithBucket(o.bucket),
-		rl.WithCPUThreshold(o.cpuThreshold),
-		rl.WithCPUQuota(o.cpuQuota),
-	)
+	limiter := o.limiter
+	if limiter == nil {
+		limiter = rl.NewLimiter(
+			rl.WithWindow(o.window),
+			rl.WithBucket(o.bucket),
+			rl.WithCPUThreshold(o.cpuThreshold),
+			rl.WithCPUQuota(o.cpuQuota),
+		)
+	}
 
 	return func(c *gin.Context) {
 		done, err := limiter.Allow()
@@ -3,6 +3,7 @@ package middleware
 import (
 	"math/rand"
 	"net/http"
+	"net/http/httptest"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/go-dev-frame/sponge/pkg/gin/response"
 	"github.com/go-dev-frame/sponge/pkg/httpcli"
+	rl "github.com/go-dev-frame/sponge/pkg/shield/ratelimit"
 	"github.com/go-dev-frame/sponge/pkg/utils"
 )
 
@@ -77,3 +79,24 @@ func TestRateLimiter(t *testing.T) {
 			time.Now().Format(time.RFC3339Nano), success, failures)
 	}
 }
+
+type denyLimiter struct{}
+
+func (denyLimiter) Allow() (rl.DoneFunc, error) {
+	return nil, rl.ErrLimitExceed
+}
+
+func TestRateLimit_WithLimiter(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(RateLimit(WithLimiter(denyLimiter{})))
+	r.GET("/hello", func(c *gin.Context) {
+		response.Success(c, "hello")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/hello", nil))
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("expect status %d, actual %d", http.StatusTooManyRequests, w.Code)
+	}
+}
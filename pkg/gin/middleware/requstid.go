@@ -8,6 +8,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/go-dev-frame/sponge/pkg/krand"
+	"github.com/go-dev-frame/sponge/pkg/utils"
 )
 
 var (
@@ -24,12 +25,16 @@ type RequestIDOption func(*requestIDOptions)
 type requestIDOptions struct {
 	contextRequestIDKey string
 	headerXRequestIDKey string
+	generator           func() string
 }
 
 func defaultRequestIDOptions() *requestIDOptions {
 	return &requestIDOptions{
 		contextRequestIDKey: ContextRequestIDKey,
 		headerXRequestIDKey: HeaderXRequestIDKey,
+		generator: func() string {
+			return krand.String(krand.R_All, 10)
+		},
 	}
 }
 
@@ -68,6 +73,17 @@ func WithHeaderRequestIDKey(key string) RequestIDOption {
 	}
 }
 
+// WithRequestIDGenerator set the function that creates a request id when the request header has none,
+// e.g. utils.NewUUIDv7 or func() string { return utils.NewSortableID("req") } for time-sortable ids.
+func WithRequestIDGenerator(fn func() string) RequestIDOption {
+	return func(o *requestIDOptions) {
+		if fn == nil {
+			return
+		}
+		o.generator = fn
+	}
+}
+
 // CtxKeyString for context.WithValue key type
 type CtxKeyString string
 
@@ -82,6 +98,8 @@ func RequestID(opts ...RequestIDOption) gin.HandlerFunc {
 	o := defaultRequestIDOptions()
 	o.apply(opts...)
 	o.setRequestIDKey()
+	// values carried by utils.DetachContext into background work
+	utils.RegisterCarriedKeys(ContextRequestIDKey, RequestHeaderKey)
 
 	return func(c *gin.Context) {
 		// Check for incoming header, use it if exists
@@ -89,7 +107,7 @@ func RequestID(opts ...RequestIDOption) gin.HandlerFunc {
 
 		// Create request id
 		if requestID == "" {
-			requestID = krand.String(krand.R_All, 10)
+			requestID = o.generator()
 			c.Request.Header.Set(HeaderXRequestIDKey, requestID)
 		}
 
@@ -3,6 +3,7 @@ package middleware
 import (
 	"context"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	oteltrace "go.opentelemetry.io/otel/trace"
 )
 
 func runRequestIDHTTPServer(fn func(c *gin.Context)) string {
@@ -109,3 +111,64 @@ func TestRequestIDKeyOptions(t *testing.T) {
 	assert.Equal(t, "my_req_id", ContextRequestIDKey)
 	assert.Equal(t, "My-X-Req-Id", HeaderXRequestIDKey)
 }
+
+func TestRequestIDGenerator(t *testing.T) {
+	o := defaultRequestIDOptions()
+	o.apply(WithRequestIDGenerator(nil)) // invalid settings
+	assert.Len(t, o.generator(), 10)
+
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(RequestID(WithRequestIDGenerator(utils.NewUUIDv7)))
+	r.GET("/ping", func(c *gin.Context) {
+		c.String(200, GCtxRequestID(c))
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/ping", nil)
+	r.ServeHTTP(w, req)
+
+	_, err := utils.ParseUUIDv7Time(w.Body.String())
+	assert.NoError(t, err)
+	assert.Equal(t, w.Body.String(), w.Header().Get(HeaderXRequestIDKey))
+}
+
+func TestDetachContextWithMiddlewareKeys(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(RequestID(), Tracing("demo"))
+
+	type result struct {
+		requestID string
+		header    string
+		traceID   string
+		err       error
+	}
+	ch := make(chan result, 1)
+	r.GET("/ping", func(c *gin.Context) {
+		ctx := utils.DetachContext(WrapCtx(c))
+		go func() {
+			time.Sleep(time.Millisecond * 50) // the response has been written and the request context canceled
+			ch <- result{
+				requestID: CtxRequestID(ctx),
+				header:    GetFromHeader(ctx, "X-Foo"),
+				traceID:   oteltrace.SpanContextFromContext(ctx).TraceID().String(),
+				err:       ctx.Err(),
+			}
+		}()
+		c.String(200, GCtxRequestID(c))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequestWithContext(ctx, "GET", "/ping", nil)
+	req.Header.Set("X-Foo", "bar")
+	r.ServeHTTP(w, req)
+	cancel()
+
+	res := <-ch
+	assert.NoError(t, res.err)
+	assert.Equal(t, w.Body.String(), res.requestID)
+	assert.Equal(t, "bar", res.header)
+	assert.NotEmpty(t, res.traceID)
+}
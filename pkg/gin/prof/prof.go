@@ -6,9 +6,14 @@ import (
 
 	"github.com/felixge/fgprof"
 	"github.com/gin-gonic/gin"
+
+	sprof "github.com/go-dev-frame/sponge/pkg/prof"
 )
 
-var defaultPrefix = "/debug/pprof"
+var (
+	defaultPrefix   = "/debug/pprof"
+	profilesPattern = "/debug/profiles"
+)
 
 // Option set defaultPrefix func
 type Option func(o *options)
@@ -16,6 +21,7 @@ type Option func(o *options)
 type options struct {
 	prefix           string
 	enableIOWaitTime bool
+	enableProfiles   bool
 }
 
 func (o *options) apply(opts ...Option) {
@@ -41,6 +47,14 @@ func WithIOWaitTime() Option {
 	}
 }
 
+// WithProfiles enable the route /debug/profiles to list, download and capture the profiles of the auto profiler,
+// see prof.InitAutoProfiler in pkg/prof.
+func WithProfiles() Option {
+	return func(o *options) {
+		o.enableProfiles = true
+	}
+}
+
 // Register pprof for gin router
 func Register(r *gin.Engine, opts ...Option) {
 	o := &options{prefix: defaultPrefix}
@@ -65,4 +79,9 @@ func Register(r *gin.Engine, opts ...Option) {
 		// Similar to /profile, add IO wait time,  https://github.com/felixge/fgprof
 		group.GET("/profile-io", gin.WrapH(fgprof.Handler()))
 	}
+
+	if o.enableProfiles {
+		r.GET(profilesPattern, gin.WrapF(sprof.ProfilesHandler))
+		r.POST(profilesPattern, gin.WrapF(sprof.ProfilesHandler))
+	}
 }
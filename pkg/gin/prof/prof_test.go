@@ -13,7 +13,7 @@ import (
 
 func TestRegister(t *testing.T) {
 	r := gin.Default()
-	Register(r, WithPrefix(""), WithPrefix("/myServer"), WithIOWaitTime())
+	Register(r, WithPrefix(""), WithPrefix("/myServer"), WithIOWaitTime(), WithProfiles())
 
 	serverAddr, requestAddr := utils.GetLocalHTTPAddrPairs()
 	httpServer := &http.Server{
@@ -33,4 +33,10 @@ func TestRegister(t *testing.T) {
 		t.Fatal(err)
 	}
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Get(requestAddr + "/debug/profiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode) // the auto profiler is not initialized
 }
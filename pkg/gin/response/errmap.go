@@ -0,0 +1,183 @@
+package response
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/go-sql-driver/mysql"
+	"go.mongodb.org/mongo-driver/mongo"
+	"gorm.io/gorm"
+
+	"github.com/go-dev-frame/sponge/pkg/errcode"
+	"github.com/go-dev-frame/sponge/pkg/utils"
+)
+
+// ErrorMapping the http status code, error code and message of the matched error
+type ErrorMapping struct {
+	Status int
+	Code   int
+	Msg    string
+}
+
+type errorMatcher struct {
+	match   func(error) bool
+	mapping *ErrorMapping
+}
+
+var errorMappings = &errorRegistry{}
+
+type errorRegistry struct {
+	mu       sync.RWMutex
+	matchers []*errorMatcher
+}
+
+func (r *errorRegistry) add(m *errorMatcher) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.matchers = append(r.matchers, m)
+}
+
+func (r *errorRegistry) lookup(err error) (*ErrorMapping, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// custom mappings registered later take precedence over the built-in ones
+	for i := len(r.matchers) - 1; i >= 0; i-- {
+		if r.matchers[i].match(err) {
+			return r.matchers[i].mapping, true
+		}
+	}
+	return nil, false
+}
+
+// RegisterErrorMapping register the response of an error, target is an error matched by errors.Is,
+// or a func(error) bool matcher, e.g. MatchAs[*MyError](). The mapping registered later takes precedence.
+// If msg is empty, the message of the error is used.
+func RegisterErrorMapping(target interface{}, status int, code int, msg string) {
+	var match func(error) bool
+	switch t := target.(type) {
+	case func(error) bool:
+		match = t
+	case error:
+		match = func(err error) bool { return errors.Is(err, t) }
+	default:
+		panic(fmt.Sprintf("unsupported error mapping target type %T, want error or func(error) bool", target))
+	}
+
+	errorMappings.add(&errorMatcher{
+		match:   match,
+		mapping: &ErrorMapping{Status: status, Code: code, Msg: msg},
+	})
+}
+
+// MatchAs returns a matcher that reports whether an error in the chain is of type T, it uses errors.As.
+func MatchAs[T error]() func(error) bool {
+	return func(err error) bool {
+		var target T
+		return errors.As(err, &target)
+	}
+}
+
+// LookupErrorMapping returns the registered mapping of the error.
+func LookupErrorMapping(err error) (*ErrorMapping, bool) {
+	if err == nil {
+		return nil, false
+	}
+	return errorMappings.lookup(err)
+}
+
+// ------------------------------------------------------------------------------------------
+
+// BadRequestError is implemented by errors caused by invalid client input, e.g. the query
+// package's column errors, they are mapped to 400.
+type BadRequestError interface {
+	error
+	BadRequest() bool
+}
+
+func isBadRequest(err error) bool {
+	var e BadRequestError
+	return errors.As(err, &e) && e.BadRequest()
+}
+
+// IsDuplicateKeyError reports whether the error is a unique constraint violation of mysql,
+// postgresql, sqlite or mongodb.
+func IsDuplicateKeyError(err error) bool {
+	if errors.Is(err, gorm.ErrDuplicatedKey) || mongo.IsDuplicateKeyError(err) {
+		return true
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == 1062
+	}
+
+	var pgErr interface{ SQLState() string }
+	if errors.As(err, &pgErr) {
+		return pgErr.SQLState() == "23505"
+	}
+
+	return strings.Contains(err.Error(), "UNIQUE constraint failed") // sqlite
+}
+
+func init() {
+	RegisterErrorMapping(MatchAs[validator.ValidationErrors](), http.StatusBadRequest, errcode.InvalidParams.Code(), errcode.InvalidParams.Msg())
+	RegisterErrorMapping(isBadRequest, http.StatusBadRequest, errcode.InvalidParams.Code(), "")
+	RegisterErrorMapping(context.DeadlineExceeded, http.StatusRequestTimeout, errcode.DeadlineExceeded.Code(), errcode.DeadlineExceeded.Msg())
+	RegisterErrorMapping(utils.ErrBudgetExhausted, http.StatusGatewayTimeout, errcode.GatewayTimeout.Code(), errcode.GatewayTimeout.Msg())
+	RegisterErrorMapping(IsDuplicateKeyError, http.StatusConflict, errcode.AlreadyExists.Code(), errcode.AlreadyExists.Msg())
+	RegisterErrorMapping(mongo.ErrNoDocuments, http.StatusNotFound, errcode.NotFound.Code(), errcode.NotFound.Msg())
+	RegisterErrorMapping(gorm.ErrRecordNotFound, http.StatusNotFound, errcode.NotFound.Code(), errcode.NotFound.Msg())
+}
+
+// ------------------------------------------------------------------------------------------
+
+func respErrorJSON(c *gin.Context, err error, data ...interface{}) {
+	var e *errcode.Error
+	if errors.As(err, &e) {
+		switch Mode() {
+		case ModeProblem:
+			errcodeProblem(c, e)
+		case ModeStatusCode:
+			respJSON(c, e.ToHTTPCode(), e.Code(), e.Msg(), data...)
+		default:
+			// status code flat 200, custom error codes in data.code
+			respJSONWith200(c, e.Code(), e.Msg(), data...)
+		}
+		return
+	}
+
+	status, code, msg, detail := resolveError(err)
+	if Mode() == ModeProblem {
+		respErrorProblem(c, err, status, code, msg, detail)
+		return
+	}
+	respJSON(c, status, code, msg, data...)
+}
+
+// resolveError returns the status code, error code, message and detail of the error that is not *errcode.Error,
+// the detail is only used by problem details, the message of unmapped errors is suppressed in production (gin release mode).
+func resolveError(err error) (status int, code int, msg string, detail string) {
+	isRelease := gin.Mode() == gin.ReleaseMode
+
+	if m, ok := LookupErrorMapping(err); ok {
+		if m.Msg == "" {
+			return m.Status, m.Code, err.Error(), ""
+		}
+		if !isRelease {
+			detail = err.Error()
+		}
+		return m.Status, m.Code, m.Msg, detail
+	}
+
+	if isRelease {
+		return http.StatusInternalServerError, errcode.InternalServerError.Code(), errcode.InternalServerError.Msg(), ""
+	}
+	return http.StatusInternalServerError, errcode.InternalServerError.Code(), err.Error(), err.Error()
+}
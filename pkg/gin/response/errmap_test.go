@@ -0,0 +1,138 @@
+package response
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+	"gorm.io/gorm"
+
+	"github.com/go-dev-frame/sponge/pkg/errcode"
+	mgoquery "github.com/go-dev-frame/sponge/pkg/mgo/query"
+)
+
+type pgError struct{ code string }
+
+func (e *pgError) Error() string    { return "pg error " + e.code }
+func (e *pgError) SQLState() string { return e.code }
+
+type columnError struct{ name string }
+
+func (e *columnError) Error() string    { return fmt.Sprintf("field name '%s' is not allowed", e.name) }
+func (e *columnError) BadRequest() bool { return true }
+
+type quotaError struct{}
+
+func (e *quotaError) Error() string { return "quota exceeded" }
+
+func doError(err error) (*httptest.ResponseRecorder, *Result) {
+	gin.SetMode(gin.ReleaseMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/", nil)
+	Error(c, err)
+
+	result := &Result{}
+	_ = json.Unmarshal(w.Body.Bytes(), result)
+	return w, result
+}
+
+func TestErrorBuiltinMappings(t *testing.T) {
+	validationErr := validator.New().Struct(&struct {
+		Name string `validate:"required"`
+	}{})
+	_, mgoColumnErr := (&mgoquery.Params{Columns: []mgoquery.Column{{Name: "password", Value: "foo"}}}).
+		ConvertToMongoFilter(mgoquery.WithWhitelistNames(map[string]bool{"name": true}))
+
+	testData := []struct {
+		name   string
+		err    error
+		status int
+		code   int
+	}{
+		{"gorm not found", fmt.Errorf("GetByID: %w", gorm.ErrRecordNotFound), http.StatusNotFound, errcode.NotFound.Code()},
+		{"mongo not found", mongo.ErrNoDocuments, http.StatusNotFound, errcode.NotFound.Code()},
+		{"gorm duplicated", gorm.ErrDuplicatedKey, http.StatusConflict, errcode.AlreadyExists.Code()},
+		{"mysql duplicated", &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}, http.StatusConflict, errcode.AlreadyExists.Code()},
+		{"postgresql duplicated", fmt.Errorf("create: %w", &pgError{code: "23505"}), http.StatusConflict, errcode.AlreadyExists.Code()},
+		{"sqlite duplicated", errors.New("UNIQUE constraint failed: user.name"), http.StatusConflict, errcode.AlreadyExists.Code()},
+		{"mongo duplicated", mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}}, http.StatusConflict, errcode.AlreadyExists.Code()},
+		{"deadline exceeded", fmt.Errorf("query: %w", context.DeadlineExceeded), http.StatusRequestTimeout, errcode.DeadlineExceeded.Code()},
+		{"column error", &columnError{name: "password"}, http.StatusBadRequest, errcode.InvalidParams.Code()},
+		{"mgo column error", fmt.Errorf("GetByColumns: %w", mgoColumnErr), http.StatusBadRequest, errcode.InvalidParams.Code()},
+		{"validation error", validationErr, http.StatusBadRequest, errcode.InvalidParams.Code()},
+		{"errcode error", errcode.Unauthorized, http.StatusOK, errcode.Unauthorized.Code()},
+		{"mysql other error", &mysql.MySQLError{Number: 1045}, http.StatusInternalServerError, errcode.InternalServerError.Code()},
+		{"postgresql other error", &pgError{code: "42P01"}, http.StatusInternalServerError, errcode.InternalServerError.Code()},
+		{"unmapped error", errors.New("connection refused"), http.StatusInternalServerError, errcode.InternalServerError.Code()},
+	}
+
+	for _, tt := range testData {
+		t.Run(tt.name, func(t *testing.T) {
+			w, result := doError(tt.err)
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.code, result.Code)
+		})
+	}
+
+	// nil error is success
+	w, result := doError(nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 0, result.Code)
+}
+
+func TestErrorMessage(t *testing.T) {
+	// message of the column error is returned
+	_, result := doError(&columnError{name: "password"})
+	assert.Equal(t, "field name 'password' is not allowed", result.Msg)
+
+	// message of unmapped errors is suppressed in production mode
+	_, result = doError(errors.New("dial tcp 10.0.0.1:3306: connection refused"))
+	assert.Equal(t, errcode.InternalServerError.Msg(), result.Msg)
+
+	gin.SetMode(gin.DebugMode)
+	defer gin.SetMode(gin.ReleaseMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	Error(c, errors.New("dial tcp 10.0.0.1:3306: connection refused"))
+	assert.Contains(t, w.Body.String(), "connection refused")
+}
+
+func TestRegisterErrorMapping(t *testing.T) {
+	errQuota := errors.New("tenant quota")
+	RegisterErrorMapping(errQuota, http.StatusTooManyRequests, errcode.LimitExceed.Code(), "")
+	RegisterErrorMapping(MatchAs[*quotaError](), http.StatusForbidden, errcode.Forbidden.Code(), errcode.Forbidden.Msg())
+
+	w, result := doError(fmt.Errorf("create: %w", errQuota))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, errcode.LimitExceed.Code(), result.Code)
+	assert.Equal(t, "create: tenant quota", result.Msg)
+
+	w, result = doError(fmt.Errorf("create: %w", &quotaError{}))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, errcode.Forbidden.Msg(), result.Msg)
+
+	// later registration overrides the built-in mapping
+	RegisterErrorMapping(MatchAs[*pgError](), http.StatusBadGateway, errcode.StatusBadGateway.Code(), "")
+	w, _ = doError(&pgError{code: "23505"})
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+
+	m, ok := LookupErrorMapping(&quotaError{})
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusForbidden, m.Status)
+	_, ok = LookupErrorMapping(nil)
+	assert.False(t, ok)
+
+	assert.Panics(t, func() {
+		RegisterErrorMapping("not an error", http.StatusBadRequest, 0, "")
+	})
+}
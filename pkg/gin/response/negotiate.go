@@ -0,0 +1,199 @@
+package response
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/gin-gonic/gin/render"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/go-dev-frame/sponge/pkg/errcode"
+)
+
+const (
+	// MIMEJSON json content type
+	MIMEJSON = binding.MIMEJSON
+	// MIMEProtobuf protobuf content type
+	MIMEProtobuf = binding.MIMEPROTOBUF
+	// MIMEMsgpack msgpack content type
+	MIMEMsgpack = binding.MIMEMSGPACK2
+	// MIMEMsgpackX msgpack content type, the legacy form
+	MIMEMsgpackX = binding.MIMEMSGPACK
+)
+
+// ErrNotProtoMessage the data can't be encoded as protobuf
+var ErrNotProtoMessage = errors.New("data is not a proto.Message")
+
+var offeredFormats = []string{MIMEJSON, MIMEProtobuf, MIMEMsgpack, MIMEMsgpackX}
+
+// NegotiatedFormat returns the response content type chosen from the request's Accept header,
+// json is returned if the Accept header is empty or none of the offered types is acceptable.
+func NegotiatedFormat(c *gin.Context) string {
+	format := c.NegotiateFormat(offeredFormats...)
+	if format == MIMEMsgpackX {
+		return MIMEMsgpack
+	}
+	if format == "" {
+		return MIMEJSON
+	}
+	return format
+}
+
+func acceptJSON(c *gin.Context) bool {
+	accept := c.GetHeader("Accept")
+	return accept == "" || c.NegotiateFormat(MIMEJSON) != ""
+}
+
+func respNegotiated(c *gin.Context, httpCode int, code int, msg string, data ...interface{}) {
+	var firstData interface{}
+	if len(data) > 0 {
+		firstData = data[0]
+	}
+
+	switch NegotiatedFormat(c) {
+	case MIMEProtobuf:
+		var pm proto.Message
+		if firstData != nil {
+			var ok bool
+			pm, ok = firstData.(proto.Message)
+			if !ok {
+				if acceptJSON(c) {
+					break // fall back to json
+				}
+				respJSONWithStatusCode(c, http.StatusNotAcceptable, ErrNotProtoMessage.Error())
+				return
+			}
+		}
+		body, err := MarshalProtoEnvelope(code, msg, pm)
+		if err != nil {
+			fmt.Printf("proto encode error, err = %s\n", err.Error())
+			respJSONWithStatusCode(c, http.StatusInternalServerError, errcode.InternalServerError.Msg())
+			return
+		}
+		c.Data(httpCode, MIMEProtobuf, body)
+		return
+
+	case MIMEMsgpack:
+		c.Render(httpCode, render.MsgPack{Data: newResp(code, msg, firstData)})
+		return
+	}
+
+	writeJSON(c, httpCode, newResp(code, msg, firstData))
+}
+
+// NegotiateSuccess return success like Success, the body is encoded as json, protobuf or msgpack
+// according to the request's Accept header. The protobuf body is the envelope described in
+// MarshalProtoEnvelope, data must be a proto.Message, otherwise 406 is returned if json is not acceptable.
+func NegotiateSuccess(c *gin.Context, data ...interface{}) {
+	respNegotiated(c, http.StatusOK, 0, "ok", data...)
+}
+
+// NegotiateError return error like Error, the body is encoded according to the request's Accept header.
+func NegotiateError(c *gin.Context, err *errcode.Error, data ...interface{}) {
+	respNegotiated(c, http.StatusOK, err.Code(), err.Msg(), data...)
+}
+
+// ShouldBindBody bind the request body according to the Content-Type, supports json (default),
+// protobuf (obj must be a proto.Message) and msgpack.
+func ShouldBindBody(c *gin.Context, obj interface{}) error {
+	switch c.ContentType() {
+	case MIMEProtobuf:
+		if _, ok := obj.(proto.Message); !ok {
+			return ErrNotProtoMessage
+		}
+		return c.ShouldBindWith(obj, binding.ProtoBuf)
+	case MIMEMsgpack, MIMEMsgpackX:
+		return c.ShouldBindWith(obj, binding.MsgPack)
+	default:
+		return c.ShouldBindWith(obj, binding.JSON)
+	}
+}
+
+// ------------------------------------------------------------------------------------------
+
+// protobuf envelope field numbers, it is wire compatible with:
+//
+//	message Result {
+//	  int32 code = 1;
+//	  string msg = 2;
+//	  YourMessage data = 3;
+//	}
+const (
+	envelopeCodeField protowire.Number = 1
+	envelopeMsgField  protowire.Number = 2
+	envelopeDataField protowire.Number = 3
+)
+
+// MarshalProtoEnvelope encode the code, msg and data as a protobuf envelope, the same fields as
+// the json envelope, data may be nil. Clients decode it with any message that declares
+// code=1 (int32), msg=2 (string) and data=3 (the concrete message type).
+func MarshalProtoEnvelope(code int, msg string, data proto.Message) ([]byte, error) {
+	var b []byte
+	if code != 0 {
+		b = protowire.AppendTag(b, envelopeCodeField, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(int32(code)))
+	}
+	if msg != "" {
+		b = protowire.AppendTag(b, envelopeMsgField, protowire.BytesType)
+		b = protowire.AppendString(b, msg)
+	}
+	if data != nil {
+		d, err := proto.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		b = protowire.AppendTag(b, envelopeDataField, protowire.BytesType)
+		b = protowire.AppendBytes(b, d)
+	}
+	return b, nil
+}
+
+// UnmarshalProtoEnvelope decode the protobuf envelope, data is filled if it is not nil.
+func UnmarshalProtoEnvelope(b []byte, data proto.Message) (code int, msg string, err error) {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return 0, "", protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		switch {
+		case num == envelopeCodeField && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return 0, "", protowire.ParseError(n)
+			}
+			code = int(int32(v))
+			b = b[n:]
+		case num == envelopeMsgField && typ == protowire.BytesType:
+			v, n := protowire.ConsumeString(b)
+			if n < 0 {
+				return 0, "", protowire.ParseError(n)
+			}
+			msg = v
+			b = b[n:]
+		case num == envelopeDataField && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return 0, "", protowire.ParseError(n)
+			}
+			if data != nil {
+				if err = proto.Unmarshal(v, data); err != nil {
+					return 0, "", err
+				}
+			}
+			b = b[n:]
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return 0, "", protowire.ParseError(n)
+			}
+			b = b[n:]
+		}
+	}
+	return code, msg, nil
+}
@@ -0,0 +1,160 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/go-dev-frame/sponge/pkg/errcode"
+)
+
+type negotiateUser struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func newNegotiateRouter() *gin.Engine {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.GET("/proto", func(c *gin.Context) { NegotiateSuccess(c, wrapperspb.String("hello")) })
+	r.GET("/struct", func(c *gin.Context) { NegotiateSuccess(c, &negotiateUser{Name: "foo", Age: 10}) })
+	r.GET("/error", func(c *gin.Context) { NegotiateError(c, errcode.NotFound) })
+	r.POST("/bind/proto", func(c *gin.Context) {
+		req := &wrapperspb.StringValue{}
+		if err := ShouldBindBody(c, req); err != nil {
+			Output(c, http.StatusBadRequest)
+			return
+		}
+		NegotiateSuccess(c, req)
+	})
+	r.POST("/bind/struct", func(c *gin.Context) {
+		req := &negotiateUser{}
+		if err := ShouldBindBody(c, req); err != nil {
+			Output(c, http.StatusBadRequest)
+			return
+		}
+		NegotiateSuccess(c, req)
+	})
+	return r
+}
+
+func doNegotiate(r *gin.Engine, method, path, accept, contentType string, body []byte) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, bytes.NewReader(body))
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestNegotiateJSON(t *testing.T) {
+	r := newNegotiateRouter()
+
+	for _, accept := range []string{"", MIMEJSON, "text/html"} {
+		w := doNegotiate(r, http.MethodGet, "/struct", accept, "", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, strings.HasPrefix(w.Header().Get("Content-Type"), MIMEJSON))
+		result := &Result{Data: &negotiateUser{}}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), result))
+		assert.Equal(t, "foo", result.Data.(*negotiateUser).Name)
+	}
+
+	w := doNegotiate(r, http.MethodPost, "/bind/struct", MIMEJSON, MIMEJSON, []byte(`{"name":"bar","age":1}`))
+	assert.Contains(t, w.Body.String(), `"name":"bar"`)
+}
+
+func TestNegotiateProtobuf(t *testing.T) {
+	r := newNegotiateRouter()
+
+	w := doNegotiate(r, http.MethodGet, "/proto", MIMEProtobuf, "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, MIMEProtobuf, w.Header().Get("Content-Type"))
+	data := &wrapperspb.StringValue{}
+	code, msg, err := UnmarshalProtoEnvelope(w.Body.Bytes(), data)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "ok", msg)
+	assert.Equal(t, "hello", data.Value)
+
+	w = doNegotiate(r, http.MethodGet, "/error", MIMEProtobuf, "", nil)
+	code, msg, err = UnmarshalProtoEnvelope(w.Body.Bytes(), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, errcode.NotFound.Code(), code)
+	assert.Equal(t, errcode.NotFound.Msg(), msg)
+
+	// request body is protobuf
+	body, _ := proto.Marshal(wrapperspb.String("ping"))
+	w = doNegotiate(r, http.MethodPost, "/bind/proto", MIMEProtobuf, MIMEProtobuf, body)
+	data = &wrapperspb.StringValue{}
+	_, _, err = UnmarshalProtoEnvelope(w.Body.Bytes(), data)
+	assert.NoError(t, err)
+	assert.Equal(t, "ping", data.Value)
+
+	// request body is protobuf, but the object is not a proto.Message
+	w = doNegotiate(r, http.MethodPost, "/bind/struct", "", MIMEProtobuf, body)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestNegotiateProtobufMismatch(t *testing.T) {
+	r := newNegotiateRouter()
+
+	w := doNegotiate(r, http.MethodGet, "/struct", MIMEProtobuf, "", nil)
+	assert.Equal(t, http.StatusNotAcceptable, w.Code)
+
+	// json is acceptable too
+	w = doNegotiate(r, http.MethodGet, "/struct", MIMEProtobuf+", "+MIMEJSON, "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.HasPrefix(w.Header().Get("Content-Type"), MIMEJSON))
+}
+
+func TestNegotiateMsgpack(t *testing.T) {
+	r := newNegotiateRouter()
+	mh := &codec.MsgpackHandle{}
+
+	for _, accept := range []string{MIMEMsgpack, MIMEMsgpackX} {
+		w := doNegotiate(r, http.MethodGet, "/struct", accept, "", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, strings.HasPrefix(w.Header().Get("Content-Type"), MIMEMsgpack))
+
+		result := &Result{Data: &negotiateUser{}}
+		assert.NoError(t, codec.NewDecoderBytes(w.Body.Bytes(), mh).Decode(result))
+		assert.Equal(t, "ok", result.Msg)
+		assert.Equal(t, "foo", result.Data.(*negotiateUser).Name)
+		assert.Equal(t, 10, result.Data.(*negotiateUser).Age)
+	}
+
+	// request body is msgpack
+	var body []byte
+	assert.NoError(t, codec.NewEncoderBytes(&body, mh).Encode(&negotiateUser{Name: "bar", Age: 2}))
+	w := doNegotiate(r, http.MethodPost, "/bind/struct", MIMEMsgpack, MIMEMsgpack, body)
+	result := &Result{Data: &negotiateUser{}}
+	assert.NoError(t, codec.NewDecoderBytes(w.Body.Bytes(), mh).Decode(result))
+	assert.Equal(t, "bar", result.Data.(*negotiateUser).Name)
+}
+
+func TestProtoEnvelope(t *testing.T) {
+	b, err := MarshalProtoEnvelope(-1, "err", wrapperspb.Int64(100))
+	assert.NoError(t, err)
+	data := &wrapperspb.Int64Value{}
+	code, msg, err := UnmarshalProtoEnvelope(b, data)
+	assert.NoError(t, err)
+	assert.Equal(t, -1, code)
+	assert.Equal(t, "err", msg)
+	assert.Equal(t, int64(100), data.Value)
+
+	_, _, err = UnmarshalProtoEnvelope([]byte{0xff}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,115 @@
+package response
+
+import "encoding/json"
+
+// PageResult is the standard data of list responses shared by http and grpc-gateway handlers,
+// offset pagination returns items, total, page, limit and hasNext,
+// cursor pagination returns items, nextCursor and hasNext.
+type PageResult[T any] struct {
+	Items      []T    `json:"items"`
+	Total      int64  `json:"total"`
+	Page       int    `json:"page"`
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"nextCursor"`
+	HasNext    bool   `json:"hasNext"`
+
+	cursor bool
+}
+
+type offsetPage[T any] struct {
+	Items   []T   `json:"items"`
+	Total   int64 `json:"total"`
+	Page    int   `json:"page"`
+	Limit   int   `json:"limit"`
+	HasNext bool  `json:"hasNext"`
+}
+
+type cursorPage[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	HasNext    bool   `json:"hasNext"`
+}
+
+// NewPageResult create an offset pagination result, page starts from 0.
+func NewPageResult[T any](items []T, total int64, page int, limit int) *PageResult[T] {
+	if items == nil {
+		items = []T{}
+	}
+	return &PageResult[T]{
+		Items:   items,
+		Total:   total,
+		Page:    page,
+		Limit:   limit,
+		HasNext: limit > 0 && int64(page+1)*int64(limit) < total,
+	}
+}
+
+// NewCursorPageResult create a cursor pagination result, an empty nextCursor means there is no next page.
+func NewCursorPageResult[T any](items []T, nextCursor string) *PageResult[T] {
+	if items == nil {
+		items = []T{}
+	}
+	return &PageResult[T]{
+		Items:      items,
+		NextCursor: nextCursor,
+		HasNext:    nextCursor != "",
+		cursor:     true,
+	}
+}
+
+// IsCursor reports whether the result uses cursor pagination.
+func (p *PageResult[T]) IsCursor() bool {
+	return p.cursor
+}
+
+// MarshalJSON only the fields of the pagination style are output.
+func (p PageResult[T]) MarshalJSON() ([]byte, error) {
+	items := p.Items
+	if items == nil {
+		items = []T{}
+	}
+
+	if p.cursor {
+		return json.Marshal(&cursorPage[T]{
+			Items:      items,
+			NextCursor: p.NextCursor,
+			HasNext:    p.HasNext,
+		})
+	}
+
+	return json.Marshal(&offsetPage[T]{
+		Items:   items,
+		Total:   p.Total,
+		Page:    p.Page,
+		Limit:   p.Limit,
+		HasNext: p.HasNext,
+	})
+}
+
+// UnmarshalJSON the pagination style is detected by the total field, which only offset pagination has.
+func (p *PageResult[T]) UnmarshalJSON(data []byte) error {
+	v := &struct {
+		Items      []T    `json:"items"`
+		Total      *int64 `json:"total"`
+		Page       int    `json:"page"`
+		Limit      int    `json:"limit"`
+		NextCursor string `json:"nextCursor"`
+		HasNext    bool   `json:"hasNext"`
+	}{}
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+
+	*p = PageResult[T]{
+		Items:      v.Items,
+		Page:       v.Page,
+		Limit:      v.Limit,
+		NextCursor: v.NextCursor,
+		HasNext:    v.HasNext,
+		cursor:     v.Total == nil,
+	}
+	if v.Total != nil {
+		p.Total = *v.Total
+	}
+	return nil
+}
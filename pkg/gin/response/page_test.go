@@ -0,0 +1,75 @@
+package response
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type pageItem struct {
+	ID   uint64 `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestNewPageResult(t *testing.T) {
+	items := []*pageItem{{ID: 1, Name: "foo"}, {ID: 2, Name: "bar"}}
+
+	p := NewPageResult(items, 5, 0, 2)
+	assert.True(t, p.HasNext)
+	assert.False(t, p.IsCursor())
+	data, err := json.Marshal(p)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"items":[{"id":1,"name":"foo"},{"id":2,"name":"bar"}],"total":5,"page":0,"limit":2,"hasNext":true}`, string(data))
+
+	// last page
+	p = NewPageResult(items[:1], 5, 2, 2)
+	assert.False(t, p.HasNext)
+
+	// empty result, items is an empty array rather than null, total is not omitted
+	data, err = json.Marshal(NewPageResult[*pageItem](nil, 0, 0, 10))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"items":[],"total":0,"page":0,"limit":10,"hasNext":false}`, string(data))
+
+	// round trip
+	out := &PageResult[*pageItem]{}
+	data, _ = json.Marshal(NewPageResult(items, 5, 1, 2))
+	assert.NoError(t, json.Unmarshal(data, out))
+	assert.False(t, out.IsCursor())
+	assert.Equal(t, int64(5), out.Total)
+	assert.Equal(t, 1, out.Page)
+	assert.Equal(t, "bar", out.Items[1].Name)
+}
+
+func TestNewCursorPageResult(t *testing.T) {
+	items := []*pageItem{{ID: 9, Name: "foo"}, {ID: 8, Name: "bar"}}
+
+	p := NewCursorPageResult(items, "8")
+	assert.True(t, p.HasNext)
+	assert.True(t, p.IsCursor())
+	data, err := json.Marshal(p)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"items":[{"id":9,"name":"foo"},{"id":8,"name":"bar"}],"nextCursor":"8","hasNext":true}`, string(data))
+
+	// no next page
+	data, err = json.Marshal(NewCursorPageResult[*pageItem](nil, ""))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"items":[],"hasNext":false}`, string(data))
+
+	// round trip
+	out := &PageResult[*pageItem]{}
+	data, _ = json.Marshal(p)
+	assert.NoError(t, json.Unmarshal(data, out))
+	assert.True(t, out.IsCursor())
+	assert.Equal(t, "8", out.NextCursor)
+	assert.Len(t, out.Items, 2)
+
+	assert.Error(t, json.Unmarshal([]byte(`[1]`), out))
+}
+
+func TestPageResultInEnvelope(t *testing.T) {
+	resp := newResp(0, "ok", NewPageResult([]*pageItem{{ID: 1}}, 1, 0, 10))
+	data, err := json.Marshal(resp)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"code":0,"msg":"ok","data":{"items":[{"id":1,"name":""}],"total":1,"page":0,"limit":10,"hasNext":false}}`, string(data))
+}
@@ -0,0 +1,207 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+
+	"github.com/go-dev-frame/sponge/pkg/errcode"
+)
+
+// MIMEProblemJSON content type of RFC 7807 problem details
+const MIMEProblemJSON = "application/problem+json"
+
+var problemContentType = []string{MIMEProblemJSON + "; charset=utf-8"}
+
+// Problem RFC 7807 problem details, the error code and field errors are extension members
+type Problem struct {
+	Type     string          `json:"type"`
+	Title    string          `json:"title"`
+	Status   int             `json:"status"`
+	Detail   string          `json:"detail,omitempty"`
+	Instance string          `json:"instance,omitempty"`
+	Code     int             `json:"code,omitempty"`
+	Errors   []*ProblemField `json:"errors,omitempty"`
+}
+
+// ProblemField a field level error of the request
+type ProblemField struct {
+	Field  string `json:"field"`
+	Tag    string `json:"tag,omitempty"`
+	Detail string `json:"detail"`
+}
+
+var problemTypes = &problemTypeRegistry{
+	base:  "/problems/",
+	types: map[int]string{},
+}
+
+type problemTypeRegistry struct {
+	mu    sync.RWMutex
+	base  string
+	types map[int]string
+}
+
+// SetProblemTypeBase set the prefix of the problem type, the type of an error code is prefix + code,
+// e.g. https://example.com/problems/20001, default is /problems/
+func SetProblemTypeBase(base string) {
+	problemTypes.mu.Lock()
+	defer problemTypes.mu.Unlock()
+	if base != "" && !strings.HasSuffix(base, "/") {
+		base += "/"
+	}
+	problemTypes.base = base
+}
+
+// RegisterProblemType set the problem type of an error code, it takes precedence over the type base
+func RegisterProblemType(code int, typeURI string) {
+	problemTypes.mu.Lock()
+	defer problemTypes.mu.Unlock()
+	problemTypes.types[code] = typeURI
+}
+
+// ProblemType returns the stable problem type of the error code, errors without code are about:blank
+func ProblemType(code int) string {
+	problemTypes.mu.RLock()
+	defer problemTypes.mu.RUnlock()
+	if t, ok := problemTypes.types[code]; ok {
+		return t
+	}
+	if code == 0 || problemTypes.base == "" {
+		return "about:blank"
+	}
+	return problemTypes.base + strconv.Itoa(code)
+}
+
+var requestIDFunc = func(c *gin.Context) string {
+	if id := c.GetString("request_id"); id != "" {
+		return id
+	}
+	return c.GetHeader("X-Request-Id")
+}
+
+// SetRequestIDFunc set the function that gets the request id, which is used for the problem instance,
+// e.g. middleware.GCtxRequestID
+func SetRequestIDFunc(fn func(c *gin.Context) string) {
+	if fn != nil {
+		requestIDFunc = fn
+	}
+}
+
+func problemInstance(c *gin.Context) string {
+	if id := requestIDFunc(c); id != "" {
+		return "urn:request-id:" + id
+	}
+	if c.Request != nil && c.Request.URL != nil {
+		return c.Request.URL.Path
+	}
+	return ""
+}
+
+func writeProblem(c *gin.Context, p *Problem) {
+	c.Writer.Header()["Content-Type"] = problemContentType
+	c.Writer.WriteHeader(p.Status)
+	err := json.NewEncoder(c.Writer).Encode(p)
+	if err != nil {
+		fmt.Printf("json encode error, err = %s\n", err.Error())
+	}
+}
+
+// respProblem write the problem of an http status code without error code
+func respProblem(c *gin.Context, status int, msg string) {
+	p := &Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Instance: problemInstance(c),
+	}
+	if msg != p.Title {
+		p.Detail = msg
+	}
+	writeProblem(c, p)
+}
+
+// respErrorProblem write the problem of an error, detail is empty if the message of the error is suppressed
+func respErrorProblem(c *gin.Context, err error, status int, code int, msg string, detail string) {
+	p := &Problem{
+		Type:     ProblemType(code),
+		Title:    msg,
+		Status:   status,
+		Detail:   detail,
+		Instance: problemInstance(c),
+		Code:     code,
+		Errors:   problemFields(err),
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(status)
+	}
+	if p.Detail == p.Title {
+		p.Detail = ""
+	}
+	writeProblem(c, p)
+}
+
+// the field errors of validator and the errors that have Field and Tag methods, e.g. query.BindError
+type fieldError interface {
+	error
+	Field() string
+	Tag() string
+}
+
+func problemFields(err error) []*ProblemField {
+	var fields []*ProblemField
+
+	var ve validator.ValidationErrors
+	if errors.As(err, &ve) {
+		for _, fe := range ve {
+			fields = append(fields, &ProblemField{Field: fe.Field(), Tag: fe.Tag(), Detail: fe.Error()})
+		}
+		return fields
+	}
+
+	var walk func(err error)
+	walk = func(err error) {
+		switch e := err.(type) {
+		case nil:
+			return
+		case fieldError:
+			fields = append(fields, &ProblemField{Field: e.Field(), Tag: e.Tag(), Detail: e.Error()})
+		case interface{ Unwrap() []error }:
+			for _, ue := range e.Unwrap() {
+				walk(ue)
+			}
+		case interface{ Unwrap() error }:
+			walk(e.Unwrap())
+		}
+	}
+	walk(err)
+
+	return fields
+}
+
+// ProblemError return RFC 7807 problem details whatever the response mode is, if err is nil, return success.
+func ProblemError(c *gin.Context, err error) {
+	if err == nil {
+		Success(c)
+		return
+	}
+	var e *errcode.Error
+	if errors.As(err, &e) {
+		errcodeProblem(c, e)
+		return
+	}
+	status, code, msg, detail := resolveError(err)
+	respErrorProblem(c, err, status, code, msg, detail)
+}
+
+// errcodeProblem write the problem of errcode.Error
+func errcodeProblem(c *gin.Context, e *errcode.Error) {
+	respErrorProblem(c, e, e.ToHTTPCode(), e.Code(), e.Msg(), strings.Join(e.Details(), ", "))
+}
@@ -0,0 +1,155 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"github.com/go-dev-frame/sponge/pkg/errcode"
+)
+
+type bindFieldError struct{ field, tag string }
+
+func (e *bindFieldError) Error() string { return "invalid " + e.field }
+func (e *bindFieldError) Field() string { return e.field }
+func (e *bindFieldError) Tag() string   { return e.tag }
+
+type bindErrors []error
+
+func (e bindErrors) Error() string    { return "bind error" }
+func (e bindErrors) BadRequest() bool { return true }
+func (e bindErrors) Unwrap() []error  { return e }
+
+func doProblem(fn func(c *gin.Context)) (*httptest.ResponseRecorder, *Problem) {
+	SetMode(ModeProblem)
+	defer SetMode(ModeEnvelope)
+
+	gin.SetMode(gin.ReleaseMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/user/1", nil)
+	c.Set("request_id", "req-123")
+	fn(c)
+
+	p := &Problem{}
+	_ = json.Unmarshal(w.Body.Bytes(), p)
+	return w, p
+}
+
+func TestProblemErrcode(t *testing.T) {
+	w, p := doProblem(func(c *gin.Context) { Error(c, errcode.NotFound) })
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "application/problem+json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, ProblemType(errcode.NotFound.Code()), p.Type)
+	assert.Equal(t, fmt.Sprintf("/problems/%d", errcode.NotFound.Code()), p.Type)
+	assert.Equal(t, errcode.NotFound.Msg(), p.Title)
+	assert.Equal(t, http.StatusNotFound, p.Status)
+	assert.Equal(t, "urn:request-id:req-123", p.Instance)
+	assert.Equal(t, errcode.NotFound.Code(), p.Code)
+
+	// raw members
+	w, _ = doProblem(func(c *gin.Context) { Out(c, errcode.Unauthorized) })
+	members := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &members))
+	for _, key := range []string{"type", "title", "status", "instance", "code"} {
+		assert.Contains(t, members, key)
+	}
+	assert.NotContains(t, members, "data")
+
+	// http status code only
+	w, p = doProblem(func(c *gin.Context) { Output(c, http.StatusServiceUnavailable) })
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "about:blank", p.Type)
+	assert.Equal(t, http.StatusText(http.StatusServiceUnavailable), p.Title)
+	assert.Equal(t, 0, p.Code)
+
+	// success is not a problem
+	w, _ = doProblem(func(c *gin.Context) { Output(c, http.StatusOK) })
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":200`)
+}
+
+func TestProblemMappedError(t *testing.T) {
+	w, p := doProblem(func(c *gin.Context) { Error(c, fmt.Errorf("get: %w", gorm.ErrRecordNotFound)) })
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, errcode.NotFound.Msg(), p.Title)
+	assert.Empty(t, p.Detail) // suppressed in release mode
+
+	// unmapped error
+	w, p = doProblem(func(c *gin.Context) { Error(c, errors.New("dial tcp: connection refused")) })
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, errcode.InternalServerError.Msg(), p.Title)
+	assert.NotContains(t, w.Body.String(), "connection refused")
+
+	// custom problem type
+	RegisterProblemType(errcode.Conflict.Code(), "https://example.com/problems/conflict")
+	_, p = doProblem(func(c *gin.Context) { Error(c, errcode.Conflict) })
+	assert.Equal(t, "https://example.com/problems/conflict", p.Type)
+
+	SetProblemTypeBase("https://example.com/errors")
+	defer SetProblemTypeBase("/problems/")
+	assert.Equal(t, "https://example.com/errors/20001", ProblemType(20001))
+	assert.Equal(t, "about:blank", ProblemType(0))
+
+	// the problem is returned in other modes too
+	gin.SetMode(gin.ReleaseMode)
+	w = httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/user/1", nil)
+	ProblemError(c, errcode.Forbidden)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), `"instance":"/api/v1/user/1"`)
+}
+
+func TestProblemValidationErrors(t *testing.T) {
+	validationErr := validator.New().Struct(&struct {
+		Name  string `validate:"required"`
+		Email string `validate:"email"`
+	}{Email: "foo"})
+
+	w, p := doProblem(func(c *gin.Context) { Error(c, validationErr) })
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Len(t, p.Errors, 2)
+	assert.Equal(t, "Name", p.Errors[0].Field)
+	assert.Equal(t, "required", p.Errors[0].Tag)
+	assert.Equal(t, "Email", p.Errors[1].Field)
+	assert.Equal(t, "email", p.Errors[1].Tag)
+	assert.NotEmpty(t, p.Errors[1].Detail)
+
+	// the shape of the validation extension
+	members := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &members))
+	fields := members["errors"].([]interface{})
+	assert.Len(t, fields[0], 3) // field, tag, detail
+
+	// binding errors, e.g. query.BindError
+	bindErr := bindErrors{&bindFieldError{field: "page", tag: "number"}, &bindFieldError{field: "filter", tag: "syntax"}}
+	w, p = doProblem(func(c *gin.Context) { Error(c, fmt.Errorf("bind: %w", bindErr)) })
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, []*ProblemField{
+		{Field: "page", Tag: "number", Detail: "invalid page"},
+		{Field: "filter", Tag: "syntax", Detail: "invalid filter"},
+	}, p.Errors)
+}
+
+func TestSetMode(t *testing.T) {
+	assert.Equal(t, ModeEnvelope, Mode())
+	assert.Panics(t, func() { SetMode("unknown") })
+
+	SetMode(ModeStatusCode)
+	defer SetMode("")
+	gin.SetMode(gin.ReleaseMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	Error(c, errcode.NotFound)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), fmt.Sprintf(`"code":%d`, errcode.NotFound.Code()))
+}
@@ -5,12 +5,46 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sync/atomic"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/go-dev-frame/sponge/pkg/errcode"
 )
 
+// response modes of errors
+const (
+	// ModeEnvelope the status code of *errcode.Error is flat 200, the error code is in data.code, it is the default mode
+	ModeEnvelope = "envelope"
+	// ModeStatusCode the status code is converted from the error code, the error code is in data.code
+	ModeStatusCode = "statusCode"
+	// ModeProblem errors are RFC 7807 application/problem+json
+	ModeProblem = "problem"
+)
+
+var responseMode atomic.Value
+
+// SetMode set the response mode of errors, mode is ModeEnvelope, ModeStatusCode or ModeProblem,
+// empty means ModeEnvelope.
+func SetMode(mode string) {
+	switch mode {
+	case "":
+		mode = ModeEnvelope
+	case ModeEnvelope, ModeStatusCode, ModeProblem:
+	default:
+		panic(fmt.Sprintf("unknown response mode '%s', want %s, %s or %s", mode, ModeEnvelope, ModeStatusCode, ModeProblem))
+	}
+	responseMode.Store(mode)
+}
+
+// Mode returns the response mode of errors
+func Mode() string {
+	if mode, ok := responseMode.Load().(string); ok {
+		return mode
+	}
+	return ModeEnvelope
+}
+
 // Result output data format
 type Result struct {
 	Code int         `json:"code"`
@@ -53,13 +87,21 @@ func writeJSON(c *gin.Context, code int, res interface{}) {
 }
 
 func respJSONWithStatusCode(c *gin.Context, code int, msg string, data ...interface{}) {
+	if code >= http.StatusBadRequest && Mode() == ModeProblem {
+		respProblem(c, code, msg)
+		return
+	}
+	respJSON(c, code, code, msg, data...)
+}
+
+func respJSON(c *gin.Context, httpCode int, code int, msg string, data ...interface{}) {
 	var firstData interface{}
 	if len(data) > 0 {
 		firstData = data[0]
 	}
 	resp := newResp(code, msg, firstData)
 
-	writeJSON(c, code, resp)
+	writeJSON(c, httpCode, resp)
 }
 
 // Output return standard HTTP status codes and message, parameter code is HTTP status code
@@ -94,6 +136,10 @@ func Output(c *gin.Context, code int, data ...interface{}) {
 // Out returns the standard HTTP status code and message, parameter err is errcode.Error
 func Out(c *gin.Context, err *errcode.Error, data ...interface{}) {
 	code := err.ToHTTPCode()
+	if code >= http.StatusBadRequest && Mode() == ModeProblem {
+		errcodeProblem(c, err)
+		return
+	}
 	switch code {
 	case http.StatusOK:
 		respJSONWithStatusCode(c, http.StatusOK, "ok", data...)
@@ -115,6 +161,8 @@ func Out(c *gin.Context, err *errcode.Error, data ...interface{}) {
 		respJSONWithStatusCode(c, http.StatusTooManyRequests, err.Msg(), data...)
 	case http.StatusServiceUnavailable:
 		respJSONWithStatusCode(c, http.StatusServiceUnavailable, err.Msg(), data...)
+	case http.StatusGatewayTimeout:
+		respJSONWithStatusCode(c, http.StatusGatewayTimeout, err.Msg(), data...)
 
 	default:
 		respJSONWithStatusCode(c, http.StatusNotExtended, err.Msg(), data...)
@@ -137,7 +185,13 @@ func Success(c *gin.Context, data ...interface{}) {
 	respJSONWith200(c, 0, "ok", data...)
 }
 
-// Error return error
-func Error(c *gin.Context, err *errcode.Error, data ...interface{}) {
-	respJSONWith200(c, err.Code(), err.Msg(), data...)
+// Error return error, if err is *errcode.Error, the status code is flat 200 and the custom error code
+// is in data.code, other errors are mapped by the errors registered by RegisterErrorMapping, e.g. not found,
+// duplicate key, unmapped errors return 500. The output format depends on the response mode, see SetMode.
+func Error(c *gin.Context, err error, data ...interface{}) {
+	if err == nil {
+		Success(c, data...)
+		return
+	}
+	respErrorJSON(c, err, data...)
 }
@@ -0,0 +1,181 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// ndjsonContentType content type of newline delimited json
+	ndjsonContentType = "application/x-ndjson; charset=utf-8"
+
+	// TrailerRowsWritten trailer header reporting the number of rows written by a stream response
+	TrailerRowsWritten = "X-Rows-Written"
+	// TrailerStreamError trailer header reporting the error that interrupted a stream response
+	TrailerStreamError = "X-Stream-Error"
+
+	defaultFlushRows = 100
+)
+
+// IterateFunc iterate the rows to be written, it calls yield for each row and stops
+// when yield returns an error (e.g. the client disconnected).
+type IterateFunc func(yield func(v interface{}) error) error
+
+// StreamOption set the stream options.
+type StreamOption func(*streamOptions)
+
+type streamOptions struct {
+	flushRows int
+	status    int
+}
+
+func defaultStreamOptions() *streamOptions {
+	return &streamOptions{
+		flushRows: defaultFlushRows,
+		status:    http.StatusOK,
+	}
+}
+
+func (o *streamOptions) apply(opts ...StreamOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithFlushRows set the number of rows written between flushes, default 100
+func WithFlushRows(n int) StreamOption {
+	return func(o *streamOptions) {
+		if n > 0 {
+			o.flushRows = n
+		}
+	}
+}
+
+// WithStreamStatus set the http status code of the stream response, default 200
+func WithStreamStatus(code int) StreamOption {
+	return func(o *streamOptions) {
+		if code > 0 {
+			o.status = code
+		}
+	}
+}
+
+type streamWriter struct {
+	c       *gin.Context
+	o       *streamOptions
+	started bool
+	rows    int64
+
+	contentType string
+	prefix      []byte // written before the first row
+	separator   []byte // written between rows
+	suffix      []byte // written after the last row
+	lineEnd     []byte // written after each row
+}
+
+func (w *streamWriter) start() {
+	if w.started {
+		return
+	}
+	w.started = true
+
+	header := w.c.Writer.Header()
+	header.Set("Content-Type", w.contentType)
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Add("Trailer", TrailerRowsWritten)
+	header.Add("Trailer", TrailerStreamError)
+	w.c.Writer.WriteHeader(w.o.status)
+	_, _ = w.c.Writer.Write(w.prefix)
+}
+
+func (w *streamWriter) yield(v interface{}) error {
+	if err := w.c.Request.Context().Err(); err != nil {
+		return err // client disconnected
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	w.start()
+	if w.rows > 0 {
+		_, _ = w.c.Writer.Write(w.separator)
+	}
+	if _, err = w.c.Writer.Write(data); err != nil {
+		return err
+	}
+	_, _ = w.c.Writer.Write(w.lineEnd)
+
+	w.rows++
+	if w.rows%int64(w.o.flushRows) == 0 {
+		w.c.Writer.Flush()
+	}
+	return nil
+}
+
+func (w *streamWriter) run(iterate IterateFunc) (int64, error) {
+	err := iterate(w.yield)
+	if err == nil {
+		err = w.c.Request.Context().Err()
+	}
+	if err != nil && !w.started {
+		// nothing has been written, leave the response to the caller
+		return 0, err
+	}
+
+	w.start()
+	if err == nil {
+		// an interrupted array is left unclosed, so the client can't mistake it for a complete result
+		_, _ = w.c.Writer.Write(w.suffix)
+	}
+
+	header := w.c.Writer.Header()
+	header.Set(TrailerRowsWritten, strconv.FormatInt(w.rows, 10))
+	if err != nil {
+		header.Set(TrailerStreamError, err.Error())
+	}
+	w.c.Writer.Flush()
+
+	return w.rows, err
+}
+
+// StreamNDJSON write rows as newline delimited json (one json document per line), flushing periodically,
+// the response is streamed without buffering all rows in memory. The number of rows written is returned
+// and reported in the X-Rows-Written trailer.
+// If iterate returns an error before any row is written, nothing is written to the response,
+// the caller can still respond with an error.
+func StreamNDJSON(c *gin.Context, iterate IterateFunc, opts ...StreamOption) (int64, error) {
+	o := defaultStreamOptions()
+	o.apply(opts...)
+
+	w := &streamWriter{
+		c:           c,
+		o:           o,
+		contentType: ndjsonContentType,
+		lineEnd:     []byte("\n"),
+	}
+	return w.run(iterate)
+}
+
+// StreamJSONArray write rows incrementally as a single valid json array, flushing periodically.
+// The number of rows written is returned and reported in the X-Rows-Written trailer.
+// If iterate returns an error before any row is written, nothing is written to the response,
+// the caller can still respond with an error.
+func StreamJSONArray(c *gin.Context, iterate IterateFunc, opts ...StreamOption) (int64, error) {
+	o := defaultStreamOptions()
+	o.apply(opts...)
+
+	w := &streamWriter{
+		c:           c,
+		o:           o,
+		contentType: jsonContentType[0],
+		prefix:      []byte("["),
+		separator:   []byte(","),
+		suffix:      []byte("]"),
+	}
+	return w.run(iterate)
+}
@@ -0,0 +1,133 @@
+package response
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type streamRow struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func newStreamContext(ctx context.Context) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.ReleaseMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequestWithContext(ctx, http.MethodGet, "/export", nil)
+	return c, w
+}
+
+func iterateRows(n int, onRow func(i int)) IterateFunc {
+	return func(yield func(v interface{}) error) error {
+		for i := 1; i <= n; i++ {
+			if onRow != nil {
+				onRow(i)
+			}
+			if err := yield(&streamRow{ID: i, Name: "foo"}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func TestStreamNDJSON(t *testing.T) {
+	c, w := newStreamContext(context.Background())
+	n, err := StreamNDJSON(c, iterateRows(250, nil), WithFlushRows(10), WithStreamStatus(0))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(250), n)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, ndjsonContentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, "250", w.Result().Trailer.Get(TrailerRowsWritten))
+
+	scanner := bufio.NewScanner(w.Body)
+	lines := 0
+	for scanner.Scan() {
+		row := &streamRow{}
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), row))
+		lines++
+		assert.Equal(t, lines, row.ID)
+	}
+	assert.Equal(t, 250, lines)
+
+	// no rows
+	c, w = newStreamContext(context.Background())
+	n, err = StreamNDJSON(c, iterateRows(0, nil))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+	assert.Equal(t, "", w.Body.String())
+}
+
+func TestStreamJSONArray(t *testing.T) {
+	c, w := newStreamContext(context.Background())
+	n, err := StreamJSONArray(c, iterateRows(3, nil))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+	assert.Equal(t, `[{"id":1,"name":"foo"},{"id":2,"name":"foo"},{"id":3,"name":"foo"}]`, w.Body.String())
+	assert.True(t, strings.HasPrefix(w.Header().Get("Content-Type"), "application/json"))
+
+	var rows []streamRow
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &rows))
+	assert.Len(t, rows, 3)
+
+	// no rows is still a valid array
+	c, w = newStreamContext(context.Background())
+	_, err = StreamJSONArray(c, iterateRows(0, nil))
+	assert.NoError(t, err)
+	assert.Equal(t, "[]", w.Body.String())
+}
+
+func TestStreamDisconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c, w := newStreamContext(ctx)
+	n, err := StreamJSONArray(c, iterateRows(100, func(i int) {
+		if i == 6 {
+			cancel() // client disconnected
+		}
+	}))
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int64(5), n)
+	assert.Equal(t, "5", w.Result().Trailer.Get(TrailerRowsWritten))
+	assert.NotEmpty(t, w.Result().Trailer.Get(TrailerStreamError))
+	assert.False(t, json.Valid(w.Body.Bytes())) // interrupted array is not closed
+
+	ctx, cancel = context.WithCancel(context.Background())
+	c, w = newStreamContext(ctx)
+	n, err = StreamNDJSON(c, iterateRows(100, func(i int) {
+		if i == 3 {
+			cancel()
+		}
+	}))
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int64(2), n)
+	assert.Equal(t, 2, strings.Count(w.Body.String(), "\n"))
+}
+
+func TestStreamIterateError(t *testing.T) {
+	// error before the first row, nothing is written
+	c, w := newStreamContext(context.Background())
+	_, err := StreamNDJSON(c, func(yield func(v interface{}) error) error {
+		return errors.New("query error")
+	})
+	assert.Error(t, err)
+	assert.False(t, c.Writer.Written())
+	Output(c, http.StatusInternalServerError)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	// unsupported value
+	c, _ = newStreamContext(context.Background())
+	_, err = StreamJSONArray(c, func(yield func(v interface{}) error) error {
+		return yield(make(chan int))
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,198 @@
+package validator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// UnknownFieldsError the json fields of the request that do not exist in the struct, it is a bad request error,
+// response.Error returns 400 listing all the unknown fields.
+type UnknownFieldsError struct {
+	Fields []string // json paths, e.g. userName, profile.nickName, items[0].foo
+}
+
+// Error returns the error message
+func (e *UnknownFieldsError) Error() string {
+	return "unknown fields: " + strings.Join(e.Fields, ", ")
+}
+
+// BadRequest the error is caused by the client input
+func (e *UnknownFieldsError) BadRequest() bool {
+	return true
+}
+
+// Unwrap returns an error for each unknown field, the field and tag are used by problem details
+func (e *UnknownFieldsError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		errs = append(errs, &unknownFieldError{field: field})
+	}
+	return errs
+}
+
+type unknownFieldError struct {
+	field string
+}
+
+func (e *unknownFieldError) Error() string { return fmt.Sprintf("unknown field %q", e.field) }
+func (e *unknownFieldError) Field() string { return e.field }
+func (e *unknownFieldError) Tag() string   { return "unknown" }
+
+// ShouldBindJSONStrict binds the json body like gin's ShouldBindJSON, but the fields that do not exist in obj
+// are rejected with *UnknownFieldsError, all the unknown fields including nested objects are listed.
+func ShouldBindJSONStrict(c *gin.Context, obj interface{}) error {
+	if c.Request == nil || c.Request.Body == nil {
+		return errors.New("invalid request")
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(obj); err != nil {
+		if strings.HasPrefix(err.Error(), "json: unknown field ") {
+			// the decoder stops at the first unknown field, decode into a map to name them all
+			var raw interface{}
+			if json.Unmarshal(body, &raw) == nil {
+				if fields := unknownFields(reflect.TypeOf(obj), raw, ""); len(fields) > 0 {
+					sort.Strings(fields)
+					return &UnknownFieldsError{Fields: fields}
+				}
+			}
+		}
+		return err
+	}
+
+	if binding.Validator == nil {
+		return nil
+	}
+	return binding.Validator.ValidateStruct(obj)
+}
+
+// CheckFields check that the json paths exist in obj, e.g. the field mask of a PATCH request,
+// nested fields are separated by dots, e.g. profile.nickName, returns *UnknownFieldsError if not.
+func CheckFields(obj interface{}, paths ...string) error {
+	var fields []string
+	for _, path := range paths {
+		if !hasField(reflect.TypeOf(obj), strings.Split(path, ".")) {
+			fields = append(fields, path)
+		}
+	}
+	if len(fields) > 0 {
+		return &UnknownFieldsError{Fields: fields}
+	}
+	return nil
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// unknownFields walks the decoded json value against the type, the field names are matched
+// case-insensitively like encoding/json.
+func unknownFields(t reflect.Type, v interface{}, prefix string) []string {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		return nil
+	}
+
+	var fields []string
+	switch val := v.(type) {
+	case map[string]interface{}:
+		switch t.Kind() {
+		case reflect.Struct:
+			for key, sub := range val {
+				path := joinPath(prefix, key)
+				ft, ok := lookupField(t, key)
+				if !ok {
+					fields = append(fields, path)
+					continue
+				}
+				fields = append(fields, unknownFields(ft, sub, path)...)
+			}
+		case reflect.Map:
+			for key, sub := range val {
+				fields = append(fields, unknownFields(t.Elem(), sub, joinPath(prefix, key))...)
+			}
+		}
+	case []interface{}:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for i, sub := range val {
+				fields = append(fields, unknownFields(t.Elem(), sub, prefix+"["+strconv.Itoa(i)+"]")...)
+			}
+		}
+	}
+	return fields
+}
+
+func hasField(t reflect.Type, names []string) bool {
+	for _, name := range names {
+		for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return false
+		}
+		ft, ok := lookupField(t, name)
+		if !ok {
+			return false
+		}
+		t = ft
+	}
+	return true
+}
+
+// lookupField returns the type of the struct field whose json name is name, embedded structs are flattened
+func lookupField(t reflect.Type, name string) (reflect.Type, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		jsonName, _, _ := strings.Cut(tag, ",")
+
+		if f.Anonymous && jsonName == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if typ, ok := lookupField(ft, name); ok {
+					return typ, true
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		if jsonName == "" {
+			jsonName = f.Name
+		}
+		if strings.EqualFold(jsonName, name) {
+			return f.Type, true
+		}
+	}
+	return nil, false
+}
+
+func joinPath(prefix string, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
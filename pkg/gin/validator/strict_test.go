@@ -0,0 +1,107 @@
+package validator
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+)
+
+type strictProfile struct {
+	NickName string `json:"nickName"`
+	Age      int    `json:"age"`
+}
+
+type strictBase struct {
+	ID uint64 `json:"id"`
+}
+
+type strictUserRequest struct {
+	strictBase
+	Username  string            `json:"username" binding:"required"`
+	Email     string            `json:"email"`
+	Profile   *strictProfile    `json:"profile"`
+	Items     []strictProfile   `json:"items"`
+	Labels    map[string]string `json:"labels"`
+	Birthday  time.Time         `json:"birthday"`
+	Ignored   string            `json:"-"`
+	CreatedBy string
+}
+
+func bindStrict(body string, obj interface{}) error {
+	gin.SetMode(gin.ReleaseMode)
+	binding.Validator = Init()
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest(http.MethodPost, "/user", strings.NewReader(body))
+	return ShouldBindJSONStrict(c, obj)
+}
+
+func TestShouldBindJSONStrict(t *testing.T) {
+	form := &strictUserRequest{}
+	err := bindStrict(`{"id":1,"username":"foo","email":"foo@bar.com","profile":{"nickName":"f","age":18},
+		"items":[{"nickName":"a"}],"labels":{"k":"v"},"birthday":"2024-01-02T15:04:05Z","createdBy":"admin"}`, form)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), form.ID)
+	assert.Equal(t, "foo", form.Username)
+	assert.Equal(t, 18, form.Profile.Age)
+	assert.Equal(t, "admin", form.CreatedBy)
+
+	// field names are matched case-insensitively like encoding/json
+	assert.NoError(t, bindStrict(`{"UserName":"foo"}`, &strictUserRequest{}))
+
+	// validation
+	err = bindStrict(`{"email":"foo@bar.com"}`, &strictUserRequest{})
+	assert.True(t, errors.As(err, &validator.ValidationErrors{}))
+
+	// invalid json
+	err = bindStrict(`{"username":`, &strictUserRequest{})
+	assert.Error(t, err)
+	assert.False(t, errors.As(err, new(*UnknownFieldsError)))
+}
+
+func TestShouldBindJSONStrict_UnknownFields(t *testing.T) {
+	// single
+	err := bindStrict(`{"user_name":"foo"}`, &strictUserRequest{})
+	unknownErr := &UnknownFieldsError{}
+	assert.True(t, errors.As(err, &unknownErr))
+	assert.Equal(t, []string{"user_name"}, unknownErr.Fields)
+	assert.Equal(t, "unknown fields: user_name", err.Error())
+	assert.True(t, unknownErr.BadRequest())
+
+	// multiple
+	err = bindStrict(`{"username":"foo","user_name":"foo","mail":"foo@bar.com","Ignored":"x"}`, &strictUserRequest{})
+	assert.True(t, errors.As(err, &unknownErr))
+	assert.Equal(t, []string{"Ignored", "mail", "user_name"}, unknownErr.Fields)
+
+	// nested objects
+	err = bindStrict(`{"username":"foo","profile":{"nick":"f"},"items":[{"age":1},{"agee":2}],"labels":{"k":"v"}}`, &strictUserRequest{})
+	assert.True(t, errors.As(err, &unknownErr))
+	assert.Equal(t, []string{"items[1].agee", "profile.nick"}, unknownErr.Fields)
+
+	// the field and tag of each unknown field
+	errs := unknownErr.Unwrap()
+	assert.Len(t, errs, 2)
+	fieldErr := errs[0].(interface {
+		Field() string
+		Tag() string
+	})
+	assert.Equal(t, "items[1].agee", fieldErr.Field())
+	assert.Equal(t, "unknown", fieldErr.Tag())
+	assert.Equal(t, `unknown field "items[1].agee"`, errs[0].Error())
+}
+
+func TestCheckFields(t *testing.T) {
+	assert.NoError(t, CheckFields(&strictUserRequest{}, "username", "id", "profile.nickName", "items.age"))
+
+	err := CheckFields(&strictUserRequest{}, "username", "user_name", "profile.nick", "email.foo")
+	unknownErr := &UnknownFieldsError{}
+	assert.True(t, errors.As(err, &unknownErr))
+	assert.Equal(t, []string{"user_name", "profile.nick", "email.foo"}, unknownErr.Fields)
+}
@@ -0,0 +1,292 @@
+package gobash
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Stream the output stream of the command
+type Stream int
+
+const (
+	// Stdout standard output
+	Stdout Stream = iota + 1
+	// Stderr standard error
+	Stderr
+)
+
+// maxLineSize the longer line is split into multiple lines when streaming
+const maxLineSize = 64 * 1024
+
+// Output a line of the output
+type Output struct {
+	Stream Stream
+	Line   string // without the trailing newline
+}
+
+// CommandResult the result of the command
+type CommandResult struct {
+	ExitCode  int // -1 if the command is killed or not exited normally
+	Duration  time.Duration
+	Stdout    []byte // at most the max output bytes
+	Stderr    []byte // at most the max output bytes
+	Truncated bool   // the stdout or stderr exceeds the max output bytes and is truncated
+}
+
+// RunCommand execute the command and wait for it to exit, the command name must be in system path or a path,
+// the output is streamed line by line to the callbacks or the channel and kept in the result up to the max
+// output bytes. When ctx is done or the timeout is reached, the command and its child processes are killed.
+// The result is returned once the command is started, err is the context error if it is killed by ctx or the
+// timeout, *exec.ExitError if the exit code is not 0, or the error of starting the command.
+func RunCommand(ctx context.Context, name string, args []string, opts ...CommandOption) (*CommandResult, error) {
+	o := defaultCommandOptions()
+	o.apply(opts...)
+
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+
+	cmdName, err := exec.LookPath(name)
+	if err != nil {
+		return nil, err
+	}
+	cmd := newCommand(ctx, cmdName, args, o.umask)
+	cmd.Dir = o.dir
+	cmd.Env = o.environ()
+	cmd.WaitDelay = o.waitDelay
+	setProcessGroup(cmd)
+
+	stdout := &lineWriter{stream: Stdout, o: o, buf: limitedBuffer{max: o.maxOutput}}
+	stderr := &lineWriter{stream: Stderr, o: o, buf: limitedBuffer{max: o.maxOutput}}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+
+	start := time.Now()
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	err = cmd.Wait()
+	stdout.flush()
+	stderr.flush()
+	result := &CommandResult{
+		Duration:  time.Since(start),
+		Stdout:    stdout.buf.Bytes(),
+		Stderr:    stderr.buf.Bytes(),
+		Truncated: stdout.buf.truncated || stderr.buf.truncated,
+		ExitCode:  -1,
+	}
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	}
+
+	if ctx.Err() != nil {
+		return result, ctx.Err()
+	}
+	if errors.Is(err, exec.ErrWaitDelay) {
+		err = nil // the command exited, but a child process still holds the output
+	}
+	return result, err
+}
+
+// lineWriter keep the output in the buffer, and pass every line to the callback and channel
+type lineWriter struct {
+	stream  Stream
+	o       *commandOptions
+	buf     limitedBuffer
+	pending []byte // the incomplete line
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	_, _ = w.buf.Write(p)
+	if w.o.onStdout == nil && w.o.onStderr == nil && w.o.outputCh == nil {
+		return len(p), nil
+	}
+
+	w.pending = append(w.pending, p...)
+	for {
+		i := bytes.IndexByte(w.pending, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(w.pending[:i])
+		w.pending = w.pending[i+1:]
+	}
+	if len(w.pending) >= maxLineSize {
+		w.emit(w.pending) // the long line is split
+		w.pending = w.pending[:0]
+	}
+	return len(p), nil
+}
+
+// flush emit the last line without the trailing newline
+func (w *lineWriter) flush() {
+	if len(w.pending) > 0 {
+		w.emit(w.pending)
+		w.pending = nil
+	}
+}
+
+func (w *lineWriter) emit(line []byte) {
+	text := strings.TrimSuffix(string(line), "\r")
+	switch {
+	case w.stream == Stdout && w.o.onStdout != nil:
+		w.o.onStdout(text)
+	case w.stream == Stderr && w.o.onStderr != nil:
+		w.o.onStderr(text)
+	}
+	if w.o.outputCh != nil {
+		w.o.outputCh <- Output{Stream: w.stream, Line: text}
+	}
+}
+
+// limitedBuffer keep the first max bytes written
+type limitedBuffer struct {
+	bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	rest := b.max - b.Len()
+	if rest < len(p) {
+		b.truncated = true
+		if rest > 0 {
+			b.Buffer.Write(p[:rest])
+		}
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
+
+// -------------------------------------------------------------------------------------------
+
+// CommandOption set the command options.
+type CommandOption func(*commandOptions)
+
+type commandOptions struct {
+	timeout   time.Duration
+	dir       string
+	envAllow  []string
+	env       []string
+	umask     int
+	maxOutput int
+	waitDelay time.Duration
+	onStdout  func(line string)
+	onStderr  func(line string)
+	outputCh  chan<- Output
+}
+
+func defaultCommandOptions() *commandOptions {
+	return &commandOptions{
+		umask:     -1,
+		maxOutput: 1 << 20,
+		waitDelay: time.Second,
+	}
+}
+
+func (o *commandOptions) apply(opts ...CommandOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// environ the inherited environment variables in the allow-list and the specified ones,
+// all the environment variables are inherited if the allow-list is not set.
+func (o *commandOptions) environ() []string {
+	if o.envAllow == nil {
+		if o.env == nil {
+			return nil // inherit all
+		}
+		return append(os.Environ(), o.env...)
+	}
+	var env []string
+	for _, name := range o.envAllow {
+		if v, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+v)
+		}
+	}
+	return append(env, o.env...)
+}
+
+// WithTimeout set the timeout of the command, the command is killed when it is reached, default no timeout
+func WithTimeout(d time.Duration) CommandOption {
+	return func(o *commandOptions) {
+		o.timeout = d
+	}
+}
+
+// WithDir set the working directory, default the current directory
+func WithDir(dir string) CommandOption {
+	return func(o *commandOptions) {
+		o.dir = dir
+	}
+}
+
+// WithEnvAllowList only the environment variables in the list are inherited from the current process,
+// default all are inherited, an empty list means none is inherited
+func WithEnvAllowList(names ...string) CommandOption {
+	return func(o *commandOptions) {
+		o.envAllow = append([]string{}, names...)
+	}
+}
+
+// WithEnv set the additional environment variables, e.g. "GOFLAGS=-mod=mod"
+func WithEnv(env ...string) CommandOption {
+	return func(o *commandOptions) {
+		o.env = append(o.env, env...)
+	}
+}
+
+// WithUmask set the umask of the command, e.g. 0o077, it is ignored on windows
+func WithUmask(mask int) CommandOption {
+	return func(o *commandOptions) {
+		o.umask = mask
+	}
+}
+
+// WithMaxOutput set the max bytes of the stdout and stderr kept in the result respectively, default 1MB,
+// the streaming is not limited
+func WithMaxOutput(n int) CommandOption {
+	return func(o *commandOptions) {
+		if n >= 0 {
+			o.maxOutput = n
+		}
+	}
+}
+
+// WithWaitDelay set the max time of waiting for the output to be closed after the command exits or is killed,
+// e.g. a background child process holds the output, default 1s
+func WithWaitDelay(d time.Duration) CommandOption {
+	return func(o *commandOptions) {
+		o.waitDelay = d
+	}
+}
+
+// WithStdoutFunc set the callback of every line of the stdout
+func WithStdoutFunc(fn func(line string)) CommandOption {
+	return func(o *commandOptions) {
+		o.onStdout = fn
+	}
+}
+
+// WithStderrFunc set the callback of every line of the stderr
+func WithStderrFunc(fn func(line string)) CommandOption {
+	return func(o *commandOptions) {
+		o.onStderr = fn
+	}
+}
+
+// WithOutputChan send every line of the stdout and stderr to the channel, the channel must be received
+// until RunCommand returns, it is not closed by RunCommand
+func WithOutputChan(ch chan<- Output) CommandOption {
+	return func(o *commandOptions) {
+		o.outputCh = ch
+	}
+}
@@ -0,0 +1,100 @@
+package gobash
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func skipOnWindows(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh is required")
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	skipOnWindows(t)
+	var mu sync.Mutex
+	var stdout, stderr []string
+	result, err := RunCommand(context.Background(), "sh", []string{"-c", "echo a; echo b >&2; printf c"},
+		WithStdoutFunc(func(line string) {
+			mu.Lock()
+			defer mu.Unlock()
+			stdout = append(stdout, line)
+		}),
+		WithStderrFunc(func(line string) {
+			mu.Lock()
+			defer mu.Unlock()
+			stderr = append(stderr, line)
+		}),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Equal(t, "a\nc", string(result.Stdout))
+	assert.Equal(t, "b\n", string(result.Stderr))
+	assert.False(t, result.Truncated)
+	assert.Greater(t, result.Duration, time.Duration(0))
+	assert.Equal(t, []string{"a", "c"}, stdout)
+	assert.Equal(t, []string{"b"}, stderr)
+
+	_, err = RunCommand(context.Background(), "not-exists-command", nil)
+	assert.Error(t, err)
+}
+
+func TestRunCommand_ExitCode(t *testing.T) {
+	skipOnWindows(t)
+	result, err := RunCommand(context.Background(), "sh", []string{"-c", "echo failed >&2; exit 3"})
+	var exitErr *exec.ExitError
+	require.True(t, errors.As(err, &exitErr))
+	assert.Equal(t, 3, result.ExitCode)
+	assert.Equal(t, "failed\n", string(result.Stderr))
+}
+
+func TestRunCommand_Truncate(t *testing.T) {
+	skipOnWindows(t)
+	ch := make(chan Output, 1024)
+	result, err := RunCommand(context.Background(), "sh", []string{"-c", "for i in $(seq 1 100); do echo line$i; done"},
+		WithMaxOutput(20), WithOutputChan(ch))
+	require.NoError(t, err)
+	assert.True(t, result.Truncated)
+	assert.Equal(t, "line1\nline2\nline3\nli", string(result.Stdout))
+	// the streaming is not limited
+	close(ch)
+	var lines []string
+	for out := range ch {
+		assert.Equal(t, Stdout, out.Stream)
+		lines = append(lines, out.Line)
+	}
+	assert.Len(t, lines, 100)
+	assert.Equal(t, "line100", lines[99])
+}
+
+func TestRunCommand_Sandbox(t *testing.T) {
+	skipOnWindows(t)
+	t.Setenv("GOBASH_SECRET", "secret")
+	t.Setenv("GOBASH_ALLOWED", "allowed")
+	dir := t.TempDir()
+
+	result, err := RunCommand(context.Background(), "sh", []string{"-c", "pwd; echo $GOBASH_ALLOWED $GOBASH_SECRET $FOO; umask; touch f"},
+		WithDir(dir), WithEnvAllowList("GOBASH_ALLOWED", "PATH"), WithEnv("FOO=bar"), WithUmask(0o077))
+	require.NoError(t, err)
+	realDir, _ := filepath.EvalSymlinks(dir)
+	assert.Equal(t, realDir+"\nallowed bar\n0077\n", string(result.Stdout))
+	info, err := os.Stat(filepath.Join(dir, "f"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// inherit all by default
+	result, err = RunCommand(context.Background(), "sh", []string{"-c", "echo $GOBASH_SECRET"})
+	require.NoError(t, err)
+	assert.Equal(t, "secret\n", string(result.Stdout))
+}
@@ -0,0 +1,29 @@
+//go:build !windows
+// +build !windows
+
+package gobash
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"syscall"
+)
+
+// newCommand create the command, the umask is set by sh before executing the command
+func newCommand(ctx context.Context, name string, args []string, umask int) *exec.Cmd {
+	if umask < 0 {
+		return exec.CommandContext(ctx, name, args...)
+	}
+	shArgs := append([]string{"-c", fmt.Sprintf(`umask %04o && exec "$0" "$@"`, umask), name}, args...)
+	return exec.CommandContext(ctx, "/bin/sh", shArgs...)
+}
+
+// setProcessGroup run the command in a new process group, and kill the group when ctx is done,
+// so that the child processes are killed too
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
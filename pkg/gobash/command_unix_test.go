@@ -0,0 +1,60 @@
+//go:build !windows
+// +build !windows
+
+package gobash
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunCommand_Timeout(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "pid")
+	start := time.Now()
+	// the child process sleeps in background and holds the output
+	result, err := RunCommand(context.Background(), "sh", []string{"-c", "sleep 30 & echo $! > " + pidFile + "; wait"},
+		WithTimeout(200*time.Millisecond))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Equal(t, -1, result.ExitCode)
+
+	// the child process is killed too
+	data, err := os.ReadFile(pidFile)
+	require.NoError(t, err)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return !processAlive(pid)
+	}, 2*time.Second, 20*time.Millisecond)
+
+	// canceled by ctx
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+	_, err = RunCommand(ctx, "sleep", []string{"30"})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// processAlive the killed process may be a zombie until it is reaped by init
+func processAlive(pid int) bool {
+	if syscall.Kill(pid, 0) != nil {
+		return false
+	}
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return true // no procfs
+	}
+	fields := strings.Fields(string(data))
+	return len(fields) < 3 || fields[2] != "Z"
+}
@@ -0,0 +1,18 @@
+//go:build windows
+// +build windows
+
+package gobash
+
+import (
+	"context"
+	"os/exec"
+)
+
+// newCommand create the command, umask is not supported on windows
+func newCommand(ctx context.Context, name string, args []string, _ int) *exec.Cmd {
+	return exec.CommandContext(ctx, name, args...)
+}
+
+// setProcessGroup only the command process is killed when ctx is done on windows, the child processes
+// are not killed, but the output is closed after the wait delay
+func setProcessGroup(_ *exec.Cmd) {}
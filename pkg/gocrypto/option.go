@@ -1,6 +1,10 @@
 package gocrypto
 
-import "crypto"
+import (
+	"crypto"
+
+	"golang.org/x/crypto/bcrypt"
+)
 
 const (
 	modeECB = "ECB"
@@ -208,3 +212,89 @@ func WithRsaHashType(hash crypto.Hash) RsaOption {
 		o.hashType = hash
 	}
 }
+
+// ------------------------------------------------------------------------------------------
+
+// the algorithms of password hash
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+type passwordOptions struct {
+	algorithm    string
+	argon2Params Argon2Params
+	bcryptCost   int
+
+	argon2MaxMemory     uint32
+	argon2MaxIterations uint32
+}
+
+// PasswordOption set the password hash options, they are also the current policy when checking password.
+type PasswordOption func(*passwordOptions)
+
+func (o *passwordOptions) apply(opts ...PasswordOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+func defaultPasswordOptions() *passwordOptions {
+	return &passwordOptions{
+		algorithm:    AlgorithmArgon2id,
+		argon2Params: DefaultArgon2Params(),
+		bcryptCost:   bcrypt.DefaultCost,
+
+		argon2MaxMemory:     maxArgon2Memory,
+		argon2MaxIterations: maxArgon2Iterations,
+	}
+}
+
+// WithArgon2Params set the algorithm to argon2id with the parameters, the zero fields use the default values
+func WithArgon2Params(p Argon2Params) PasswordOption {
+	return func(o *passwordOptions) {
+		d := DefaultArgon2Params()
+		if p.Memory == 0 {
+			p.Memory = d.Memory
+		}
+		if p.Iterations == 0 {
+			p.Iterations = d.Iterations
+		}
+		if p.Parallelism == 0 {
+			p.Parallelism = d.Parallelism
+		}
+		if p.SaltLength == 0 {
+			p.SaltLength = d.SaltLength
+		}
+		if p.KeyLength == 0 {
+			p.KeyLength = d.KeyLength
+		}
+		o.algorithm = AlgorithmArgon2id
+		o.argon2Params = p
+	}
+}
+
+// WithArgon2Limits set the max memory (KiB) and iterations of argon2id accepted by HashPassword and CheckPassword,
+// the encoded hash exceeding them is rejected before hashing, default is 1GiB memory and 32 iterations,
+// the zero value uses the default value. Set them a little above the current policy.
+func WithArgon2Limits(maxMemory uint32, maxIterations uint32) PasswordOption {
+	return func(o *passwordOptions) {
+		if maxMemory > 0 {
+			o.argon2MaxMemory = maxMemory
+		}
+		if maxIterations > 0 {
+			o.argon2MaxIterations = maxIterations
+		}
+	}
+}
+
+// WithBcrypt set the algorithm to bcrypt with the cost, default cost is 10
+func WithBcrypt(cost int) PasswordOption {
+	return func(o *passwordOptions) {
+		if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+			cost = bcrypt.DefaultCost
+		}
+		o.algorithm = AlgorithmBcrypt
+		o.bcryptCost = cost
+	}
+}
@@ -1,10 +1,26 @@
 package gocrypto
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// HashAndSaltPassword hash password with salt
+var (
+	// ErrInvalidHash the encoded password hash is malformed
+	ErrInvalidHash = errors.New("invalid password hash")
+	// ErrUnsupportedHash the algorithm of the encoded password hash is not supported
+	ErrUnsupportedHash = errors.New("unsupported password hash algorithm")
+)
+
+// HashAndSaltPassword hash password with salt by bcrypt, use HashPassword for the argon2id hash
 func HashAndSaltPassword(password string) (string, error) {
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
@@ -13,7 +29,182 @@ func HashAndSaltPassword(password string) (string, error) {
 	return string(hash), err
 }
 
-// VerifyPassword verify password and ciphertext match
+// VerifyPassword verify password and ciphertext match, the ciphertext is the bcrypt or argon2id hash,
+// use CheckPassword if you need to know whether the hash should be upgraded.
 func VerifyPassword(password string, hashed string) bool {
-	return bcrypt.CompareHashAndPassword([]byte(hashed), []byte(password)) == nil
+	ok, _, err := CheckPassword(password, hashed)
+	return ok && err == nil
+}
+
+// HashPassword hash password with a random salt, the default algorithm is argon2id, the result is in PHC string
+// format, e.g. $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>, the bcrypt result is in the $2a$ format.
+func HashPassword(password string, opts ...PasswordOption) (string, error) {
+	o := defaultPasswordOptions()
+	o.apply(opts...)
+
+	if o.algorithm == AlgorithmBcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), o.bcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hash), nil
+	}
+
+	p := o.argon2Params
+	if err := p.validate(o.argon2MaxMemory, o.argon2MaxIterations); err != nil {
+		return "", err
+	}
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	return encodeArgon2id(p, salt, key), nil
+}
+
+// CheckPassword verify password and the encoded hash match, the algorithm and parameters are detected from
+// the encoded hash, both argon2id and the legacy bcrypt ($2a$, $2b$, $2y$) are supported. needsRehash is true
+// if the password matches but the hash is weaker than the current policy set by opts, the caller should hash
+// the password again by HashPassword with the same opts and save it, e.g. after the user logs in successfully.
+// A mismatched password returns false and no error.
+func CheckPassword(password string, encoded string, opts ...PasswordOption) (ok bool, needsRehash bool, err error) {
+	o := defaultPasswordOptions()
+	o.apply(opts...)
+
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		p, salt, key, err := decodeArgon2id(encoded, o.argon2MaxMemory, o.argon2MaxIterations)
+		if err != nil {
+			return false, false, err
+		}
+		other := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+		if subtle.ConstantTimeCompare(key, other) != 1 {
+			return false, false, nil
+		}
+		return true, o.algorithm != AlgorithmArgon2id || p.weakerThan(o.argon2Params), nil
+
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		cost, err := bcrypt.Cost([]byte(encoded))
+		if err != nil {
+			return false, false, fmt.Errorf("%w: %v", ErrInvalidHash, err)
+		}
+		// the comparison of bcrypt is constant time
+		err = bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+		if err != nil {
+			return false, false, fmt.Errorf("%w: %v", ErrInvalidHash, err)
+		}
+		return true, o.algorithm != AlgorithmBcrypt || cost < o.bcryptCost, nil
+	}
+
+	return false, false, ErrUnsupportedHash
+}
+
+// Argon2Params the parameters of argon2id
+type Argon2Params struct {
+	Memory      uint32 // memory in KiB
+	Iterations  uint32 // number of passes over the memory
+	Parallelism uint8  // number of threads
+	SaltLength  uint32 // length of the random salt in bytes
+	KeyLength   uint32 // length of the hash in bytes
+}
+
+// DefaultArgon2Params the default parameters of argon2id, 64MiB memory, 3 iterations, 2 threads,
+// 16 bytes salt and 32 bytes hash.
+func DefaultArgon2Params() Argon2Params {
+	return Argon2Params{
+		Memory:      64 * 1024,
+		Iterations:  3,
+		Parallelism: 2,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+}
+
+// the default limits of the parameters, avoid the huge allocation or long computation of the tampered hash,
+// they can be changed by WithArgon2Limits
+const (
+	maxArgon2Memory     = 1024 * 1024 // 1GiB
+	maxArgon2Iterations = 32
+	minArgon2SaltLength = 8
+	minArgon2KeyLength  = 16
+)
+
+func (p Argon2Params) validate(maxMemory uint32, maxIterations uint32) error {
+	if p.Iterations < 1 || p.Iterations > maxIterations || p.Parallelism < 1 ||
+		p.Memory < 8*uint32(p.Parallelism) || p.Memory > maxMemory ||
+		p.SaltLength < minArgon2SaltLength || p.KeyLength < minArgon2KeyLength {
+		return fmt.Errorf("invalid argon2id parameters m=%d,t=%d,p=%d,salt=%d,key=%d",
+			p.Memory, p.Iterations, p.Parallelism, p.SaltLength, p.KeyLength)
+	}
+	return nil
+}
+
+func (p Argon2Params) weakerThan(policy Argon2Params) bool {
+	return p.Memory < policy.Memory || p.Iterations < policy.Iterations || p.Parallelism < policy.Parallelism ||
+		p.SaltLength < policy.SaltLength || p.KeyLength < policy.KeyLength
+}
+
+func encodeArgon2id(p Argon2Params, salt []byte, key []byte) string {
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+}
+
+func decodeArgon2id(encoded string, maxMemory uint32, maxIterations uint32) (Argon2Params, []byte, []byte, error) {
+	p := Argon2Params{}
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[0] != "" {
+		return p, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || fmt.Sprintf("v=%d", version) != parts[2] {
+		return p, nil, nil, ErrInvalidHash
+	}
+	if version != argon2.Version {
+		return p, nil, nil, fmt.Errorf("%w: argon2 version %d", ErrUnsupportedHash, version)
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil ||
+		fmt.Sprintf("m=%d,t=%d,p=%d", p.Memory, p.Iterations, p.Parallelism) != parts[3] {
+		return p, nil, nil, ErrInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.Strict().DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, ErrInvalidHash
+	}
+	key, err := base64.RawStdEncoding.Strict().DecodeString(parts[5])
+	if err != nil {
+		return p, nil, nil, ErrInvalidHash
+	}
+	p.SaltLength, p.KeyLength = uint32(len(salt)), uint32(len(key))
+	if err = p.validate(maxMemory, maxIterations); err != nil {
+		return p, nil, nil, fmt.Errorf("%w: %v", ErrInvalidHash, err)
+	}
+	return p, salt, key, nil
+}
+
+// CalibrateArgon2 find the argon2id parameters whose hashing takes about the target duration on the current
+// machine, the memory is at most maxMemory KiB, the iterations are increased until the duration is reached.
+// It is usually run once when deploying, and the result is set by WithArgon2Params.
+func CalibrateArgon2(target time.Duration, maxMemory uint32) Argon2Params {
+	p := DefaultArgon2Params()
+	if maxMemory > 0 && maxMemory < p.Memory {
+		p.Memory = maxMemory
+	}
+	if minMemory := 8 * uint32(p.Parallelism); p.Memory < minMemory {
+		p.Memory = minMemory
+	}
+
+	password, salt := []byte("calibrate"), make([]byte, p.SaltLength)
+	for p.Iterations = 1; p.Iterations < maxArgon2Iterations; p.Iterations++ {
+		start := time.Now()
+		argon2.IDKey(password, salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+		if time.Since(start) >= target {
+			break
+		}
+	}
+	return p
 }
@@ -1,6 +1,13 @@
 package gocrypto
 
-import "testing"
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
 
 func TestComparePasswords(t *testing.T) {
 	pwd := "123"
@@ -19,3 +26,220 @@ func TestComparePasswords(t *testing.T) {
 	}
 	t.Log("passwords match")
 }
+
+// the small parameters to speed up the tests
+var testArgon2Params = Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestHashPassword_Argon2id(t *testing.T) {
+	encoded, err := HashPassword("123456", WithArgon2Params(testArgon2Params))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encoded, "$argon2id$v=19$m=1024,t=1,p=1$"), encoded)
+	assert.Len(t, strings.Split(encoded, "$"), 6)
+
+	// random salt
+	encoded2, err := HashPassword("123456", WithArgon2Params(testArgon2Params))
+	require.NoError(t, err)
+	assert.NotEqual(t, encoded, encoded2)
+
+	ok, needsRehash, err := CheckPassword("123456", encoded, WithArgon2Params(testArgon2Params))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, needsRehash)
+	assert.True(t, VerifyPassword("123456", encoded))
+
+	ok, needsRehash, err = CheckPassword("1234567", encoded, WithArgon2Params(testArgon2Params))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.False(t, needsRehash)
+	assert.False(t, VerifyPassword("1234567", encoded))
+
+	// the default parameters
+	encoded, err = HashPassword("123456")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encoded, "$argon2id$v=19$m=65536,t=3,p=2$"), encoded)
+	ok, needsRehash, err = CheckPassword("123456", encoded)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, needsRehash)
+
+	// invalid parameters
+	_, err = HashPassword("123456", WithArgon2Params(Argon2Params{Memory: 1, Parallelism: 1}))
+	assert.Error(t, err)
+}
+
+func TestHashPassword_Bcrypt(t *testing.T) {
+	encoded, err := HashPassword("123456", WithBcrypt(4))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encoded, "$2a$04$"), encoded)
+
+	ok, needsRehash, err := CheckPassword("123456", encoded, WithBcrypt(4))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, needsRehash)
+
+	ok, _, err = CheckPassword("654321", encoded, WithBcrypt(4))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// invalid cost uses the default cost
+	encoded, err = HashPassword("123456", WithBcrypt(100))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encoded, "$2a$10$"), encoded)
+}
+
+func TestCheckPassword_NeedsRehash(t *testing.T) {
+	// the legacy bcrypt hash, the policy is argon2id
+	legacy, err := HashAndSaltPassword("123456")
+	require.NoError(t, err)
+	ok, needsRehash, err := CheckPassword("123456", legacy, WithArgon2Params(testArgon2Params))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, needsRehash)
+
+	// the mismatched password never needs rehash
+	ok, needsRehash, err = CheckPassword("654321", legacy, WithArgon2Params(testArgon2Params))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.False(t, needsRehash)
+
+	// the bcrypt cost is lower than the policy
+	ok, needsRehash, _ = CheckPassword("123456", legacy, WithBcrypt(12))
+	assert.True(t, ok)
+	assert.True(t, needsRehash)
+
+	// the argon2id parameters are lower than the policy
+	weak, err := HashPassword("123456", WithArgon2Params(testArgon2Params))
+	require.NoError(t, err)
+	policies := []Argon2Params{
+		{Memory: 2048, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+		{Memory: 1024, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+		{Memory: 1024, Iterations: 1, Parallelism: 2, SaltLength: 16, KeyLength: 32},
+		{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 32, KeyLength: 32},
+		{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 64},
+	}
+	for _, policy := range policies {
+		ok, needsRehash, err = CheckPassword("123456", weak, WithArgon2Params(policy))
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.True(t, needsRehash, policy)
+	}
+	// the policy is weaker than the hash
+	ok, needsRehash, _ = CheckPassword("123456", weak, WithArgon2Params(Argon2Params{Memory: 512, Iterations: 1, Parallelism: 1}))
+	assert.True(t, ok)
+	assert.False(t, needsRehash)
+
+	// the policy is bcrypt
+	ok, needsRehash, _ = CheckPassword("123456", weak, WithBcrypt(10))
+	assert.True(t, ok)
+	assert.True(t, needsRehash)
+
+	// migration on login
+	ok, needsRehash, _ = CheckPassword("123456", legacy, WithArgon2Params(testArgon2Params))
+	if ok && needsRehash {
+		upgraded, err := HashPassword("123456", WithArgon2Params(testArgon2Params))
+		require.NoError(t, err)
+		ok, needsRehash, err = CheckPassword("123456", upgraded, WithArgon2Params(testArgon2Params))
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.False(t, needsRehash)
+	}
+}
+
+func TestCheckPassword_Tampered(t *testing.T) {
+	encoded, err := HashPassword("123456", WithArgon2Params(testArgon2Params))
+	require.NoError(t, err)
+	parts := strings.Split(encoded, "$")
+
+	replace := func(i int, v string) string {
+		p := append([]string{}, parts...)
+		p[i] = v
+		return strings.Join(p, "$")
+	}
+	flip := func(s string) string {
+		b := []byte(s)
+		if b[0] == 'A' {
+			b[0] = 'B'
+		} else {
+			b[0] = 'A'
+		}
+		return string(b)
+	}
+
+	// the tampered salt or hash doesn't match
+	for _, tampered := range []string{replace(4, flip(parts[4])), replace(5, flip(parts[5]))} {
+		ok, needsRehash, err := CheckPassword("123456", tampered)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.False(t, needsRehash)
+	}
+
+	// the tampered parameters change the hash
+	ok, _, err := CheckPassword("123456", replace(3, "m=2048,t=1,p=1"))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	invalids := []string{
+		replace(2, "v=16"),
+		replace(2, "v=x"),
+		replace(2, "v=19,"),
+		replace(3, "m=1024,t=1"),
+		replace(3, "m=1024,t=0,p=1"),
+		replace(3, "m=1024,t=1,p=0"),
+		replace(3, "m=4,t=1,p=1"),
+		replace(3, "m=99999999,t=1,p=1"),
+		replace(3, "m=2097152,t=1,p=1"), // exceed 1GiB
+		replace(3, "m=1024,t=33,p=1"),
+		replace(3, " m=1024,t=1,p=1"),
+		replace(4, "!!!"),
+		replace(4, "c2FsdA"), // the salt is too short
+		replace(5, parts[5]+"="),
+		replace(5, "a2V5"), // the hash is too short
+		encoded + "$",
+		"$argon2id$v=19$m=1024,t=1,p=1$",
+		"$2a$10$invalid",
+		"$2a$",
+	}
+	for _, invalid := range invalids {
+		ok, needsRehash, err := CheckPassword("123456", invalid)
+		assert.Error(t, err, invalid)
+		assert.False(t, ok)
+		assert.False(t, needsRehash)
+		assert.False(t, VerifyPassword("123456", invalid))
+	}
+
+	for _, unsupported := range []string{"", "123456", "$argon2i$v=19$m=1024,t=1,p=1$c2FsdHNhbHQ$a2V5", "$1$abc"} {
+		_, _, err = CheckPassword("123456", unsupported)
+		assert.ErrorIs(t, err, ErrUnsupportedHash)
+	}
+	_, _, err = CheckPassword("123456", replace(2, "v=16"))
+	assert.ErrorIs(t, err, ErrUnsupportedHash)
+	_, _, err = CheckPassword("123456", replace(4, "!!!"))
+	assert.ErrorIs(t, err, ErrInvalidHash)
+
+	// the custom limits
+	_, _, err = CheckPassword("123456", replace(3, "m=1024,t=2,p=1"), WithArgon2Limits(0, 1))
+	assert.ErrorIs(t, err, ErrInvalidHash)
+	ok, _, err = CheckPassword("123456", replace(3, "m=1024,t=33,p=1"), WithArgon2Limits(0, 64))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	_, err = HashPassword("123456", WithArgon2Params(Argon2Params{Memory: 2048}), WithArgon2Limits(1024, 0))
+	assert.Error(t, err)
+}
+
+func TestCalibrateArgon2(t *testing.T) {
+	p := CalibrateArgon2(20*time.Millisecond, 1024)
+	assert.Equal(t, uint32(1024), p.Memory)
+	assert.GreaterOrEqual(t, p.Iterations, uint32(1))
+	assert.Equal(t, DefaultArgon2Params().Parallelism, p.Parallelism)
+
+	encoded, err := HashPassword("123456", WithArgon2Params(p))
+	require.NoError(t, err)
+	ok, needsRehash, err := CheckPassword("123456", encoded, WithArgon2Params(p))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, needsRehash)
+
+	p = CalibrateArgon2(time.Nanosecond, 1)
+	assert.Equal(t, uint32(16), p.Memory) // at least 8*parallelism
+	assert.Equal(t, uint32(1), p.Iterations)
+}
@@ -153,8 +153,12 @@ func TestFuzzyMatchFiles(t *testing.T) {
 	files := FuzzyMatchFiles("./README.md")
 	assert.Equal(t, 1, len(files))
 
-	files = FuzzyMatchFiles("./*_test.go")
+	// the fixed files, not affected by adding the test files of the package
+	files = FuzzyMatchFiles("./testdata/fuzzy/*_test.txt")
 	assert.Equal(t, 2, len(files))
+
+	files = FuzzyMatchFiles("./testdata/fuzzy/*.go")
+	assert.Equal(t, 0, len(files))
 }
 
 func TestJoin(t *testing.T) {
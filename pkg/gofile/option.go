@@ -62,3 +62,60 @@ func WithNoAbsolutePath() Option {
 		o.noAbsolutePath = true
 	}
 }
+
+// -------------------------------------------------------------------------------------------
+
+type uploadOptions struct {
+	maxSize      int64
+	allowedTypes map[string][]string
+	keyPrefix    string
+	tempDir      string
+}
+
+func defaultUploadOptions() *uploadOptions {
+	return &uploadOptions{
+		maxSize:      10 << 20,
+		allowedTypes: DefaultAllowedTypes,
+	}
+}
+
+// UploadOption set the uploader options.
+type UploadOption func(*uploadOptions)
+
+func (o *uploadOptions) apply(opts ...UploadOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithMaxSize set the max size of the uploaded file in bytes, default 10MB
+func WithMaxSize(size int64) UploadOption {
+	return func(o *uploadOptions) {
+		if size > 0 {
+			o.maxSize = size
+		}
+	}
+}
+
+// WithAllowedTypes set the allow-list, file extension --> content types, default DefaultAllowedTypes
+func WithAllowedTypes(types map[string][]string) UploadOption {
+	return func(o *uploadOptions) {
+		if len(types) > 0 {
+			o.allowedTypes = types
+		}
+	}
+}
+
+// WithKeyPrefix set the prefix of the storage key, e.g. "avatars"
+func WithKeyPrefix(prefix string) UploadOption {
+	return func(o *uploadOptions) {
+		o.keyPrefix = prefix
+	}
+}
+
+// WithTempDir set the directory of the temporary files, default os.TempDir()
+func WithTempDir(dir string) UploadOption {
+	return func(o *uploadOptions) {
+		o.tempDir = dir
+	}
+}
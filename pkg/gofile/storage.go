@@ -0,0 +1,108 @@
+package gofile
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// ErrObjectNotFound the key does not exist in the storage
+	ErrObjectNotFound = errors.New("object not found")
+	// ErrInvalidKey the key is empty, absolute or escapes the root
+	ErrInvalidKey = errors.New("invalid storage key")
+)
+
+// Storage the backend of the uploaded files, the key is a slash separated relative path
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// checkKey the key must be a clean relative path
+func checkKey(key string) error {
+	if key == "" || key == "." || strings.HasPrefix(key, "/") || strings.Contains(key, `\`) || path.Clean(key) != key ||
+		key == ".." || strings.HasPrefix(key, "../") {
+		return ErrInvalidKey
+	}
+	return nil
+}
+
+// LocalStorage store the files in the local directory
+type LocalStorage struct {
+	root string
+}
+
+// NewLocalStorage create a local storage, the root directory is created if not exists
+func NewLocalStorage(root string) (*LocalStorage, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+	return &LocalStorage{root: root}, nil
+}
+
+func (s *LocalStorage) filePath(key string) (string, error) {
+	if err := checkKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
+
+// Put write the file atomically, the content is written to a temporary file and renamed
+func (s *LocalStorage) Put(_ context.Context, key string, r io.Reader, _ int64, _ string) error {
+	file, err := s.filePath(key)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint
+	if _, err = io.Copy(tmp, r); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// Get open the file
+func (s *LocalStorage) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	file, err := s.filePath(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrObjectNotFound
+	}
+	return f, err
+}
+
+// Delete remove the file, it is not an error if the file does not exist
+func (s *LocalStorage) Delete(_ context.Context, key string) error {
+	file, err := s.filePath(key)
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package gofile
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+	"path"
+	"strings"
+	"unicode"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+var (
+	// ErrFileTooLarge the uploaded file exceeds the max size
+	ErrFileTooLarge = errors.New("file too large")
+	// ErrFileTypeNotAllowed the extension is not allowed, or the sniffed content type does not match the extension
+	ErrFileTypeNotAllowed = errors.New("file type not allowed")
+	// ErrInvalidFilename the filename is empty after sanitization
+	ErrInvalidFilename = errors.New("invalid filename")
+)
+
+// sniffLen the number of leading bytes used to sniff the content type
+const sniffLen = 3072
+
+// DefaultAllowedTypes the default allow-list, file extension --> content types that the content must be sniffed as
+var DefaultAllowedTypes = map[string][]string{
+	".jpg":  {"image/jpeg"},
+	".jpeg": {"image/jpeg"},
+	".png":  {"image/png"},
+	".gif":  {"image/gif"},
+	".webp": {"image/webp"},
+	".pdf":  {"application/pdf"},
+	".txt":  {"text/plain"},
+	".csv":  {"text/csv", "text/plain"},
+	".json": {"application/json"},
+}
+
+// UploadedFile the metadata of the stored file
+type UploadedFile struct {
+	Key         string `json:"key"`         // storage key, e.g. avatars/ab/cd/abcd...ef.png
+	Filename    string `json:"filename"`    // sanitized original filename
+	Size        int64  `json:"size"`        // size in bytes
+	ContentType string `json:"contentType"` // sniffed content type
+	Hash        string `json:"hash"`        // hex encoded sha256 of the content
+}
+
+// Uploader verify the uploaded files and save them to the storage by the content hash,
+// the client-provided filename and content type are never used as the storage path or the trusted type.
+type Uploader struct {
+	storage Storage
+
+	maxSize      int64
+	allowedTypes map[string][]string
+	keyPrefix    string
+	tempDir      string
+}
+
+// NewUploader create an uploader of the storage
+func NewUploader(storage Storage, opts ...UploadOption) *Uploader {
+	o := defaultUploadOptions()
+	o.apply(opts...)
+
+	allowedTypes := make(map[string][]string, len(o.allowedTypes))
+	for ext, types := range o.allowedTypes {
+		allowedTypes[strings.ToLower(ext)] = types
+	}
+
+	return &Uploader{
+		storage:      storage,
+		maxSize:      o.maxSize,
+		allowedTypes: allowedTypes,
+		keyPrefix:    strings.Trim(o.keyPrefix, "/"),
+		tempDir:      o.tempDir,
+	}
+}
+
+// SaveMultipart verify and save the multipart file, e.g. from c.FormFile("file")
+func (u *Uploader) SaveMultipart(ctx context.Context, fh *multipart.FileHeader) (*UploadedFile, error) {
+	if fh.Size > u.maxSize {
+		return nil, fmt.Errorf("%w: %d bytes, max %d bytes", ErrFileTooLarge, fh.Size, u.maxSize)
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() //nolint
+	return u.Save(ctx, fh.Filename, f)
+}
+
+// Save verify and save the content, the size is enforced while streaming, the content type is sniffed
+// from the magic bytes and must match the extension of the filename.
+func (u *Uploader) Save(ctx context.Context, filename string, r io.Reader) (*UploadedFile, error) {
+	filename = SanitizeFilename(filename)
+	if filename == "" {
+		return nil, ErrInvalidFilename
+	}
+	ext := strings.ToLower(path.Ext(filename))
+	types, ok := u.allowedTypes[ext]
+	if !ok {
+		return nil, fmt.Errorf("%w: extension %q", ErrFileTypeNotAllowed, ext)
+	}
+
+	// spool to a temporary file, so that the storage only receives the verified content
+	tmp, err := os.CreateTemp(u.tempDir, "upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+
+	h := sha256.New()
+	head := &headBuffer{limit: sniffLen}
+	n, err := io.Copy(io.MultiWriter(tmp, h, head), io.LimitReader(r, u.maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if n > u.maxSize {
+		return nil, fmt.Errorf("%w: max %d bytes", ErrFileTooLarge, u.maxSize)
+	}
+
+	mtype := mimetype.Detect(head.Bytes())
+	if !matchContentType(mtype, types) {
+		return nil, fmt.Errorf("%w: content of %q is %s", ErrFileTypeNotAllowed, filename, mtype.String())
+	}
+
+	sum := hex.EncodeToString(h.Sum(nil))
+	key := path.Join(u.keyPrefix, sum[:2], sum[2:4], sum+ext)
+	if _, err = tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if err = u.storage.Put(ctx, key, tmp, n, mtype.String()); err != nil {
+		return nil, err
+	}
+
+	return &UploadedFile{
+		Key:         key,
+		Filename:    filename,
+		Size:        n,
+		ContentType: mtype.String(),
+		Hash:        sum,
+	}, nil
+}
+
+// matchContentType the sniffed type or one of its parents is in the types,
+// e.g. application/json is a child of text/plain
+func matchContentType(mtype *mimetype.MIME, types []string) bool {
+	for m := mtype; m != nil; m = m.Parent() {
+		for _, t := range types {
+			if m.Is(t) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SanitizeFilename keep the base name of the client-provided filename, the directories, control characters,
+// leading dots and the characters other than letters, digits, '.', '-' and '_' are removed or replaced by '_',
+// an empty string is returned if nothing is left.
+func SanitizeFilename(name string) string {
+	// the client may send either separator regardless of the server os
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+
+	var b strings.Builder
+	lastUnderscore := false
+	for _, r := range name {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)), r == '.', r == '-':
+			b.WriteRune(r)
+			lastUnderscore = false
+		case unicode.IsControl(r):
+			// dropped
+		default:
+			if !lastUnderscore {
+				b.WriteByte('_')
+				lastUnderscore = true
+			}
+		}
+	}
+
+	name = strings.TrimLeft(b.String(), ".")
+	if strings.Trim(name, "._-") == "" {
+		return ""
+	}
+	if len(name) > 200 {
+		ext := path.Ext(name)
+		if len(ext) > 20 {
+			ext = ""
+		}
+		name = name[:200-len(ext)] + ext
+	}
+	return name
+}
+
+// headBuffer keep the first limit bytes written
+type headBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *headBuffer) Write(p []byte) (int, error) {
+	if rest := b.limit - b.Len(); rest > 0 {
+		if len(p) < rest {
+			rest = len(p)
+		}
+		b.Buffer.Write(p[:rest])
+	}
+	return len(p), nil
+}
@@ -0,0 +1,191 @@
+package gofile
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"mime/multipart"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var pngData = append([]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), bytes.Repeat([]byte{0}, 100)...)
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := map[string]string{
+		"avatar.png":                 "avatar.png",
+		"../../etc/passwd":           "passwd",
+		`..\..\windows\win.ini`:      "win.ini",
+		"/abs/path/report 2024.pdf":  "report_2024.pdf",
+		"..":                         "",
+		".htaccess":                  "htaccess",
+		"a\x00b\nc.txt":              "abc.txt",
+		"résumé <script>.pdf":        "r_sum_script_.pdf",
+		"   ":                        "",
+		"dir/":                       "",
+		"name-with_under__score.csv": "name-with_under_score.csv",
+	}
+	for in, want := range tests {
+		assert.Equal(t, want, SanitizeFilename(in), in)
+	}
+
+	long := SanitizeFilename(strings.Repeat("a", 300) + ".png")
+	assert.Len(t, long, 200)
+	assert.True(t, strings.HasSuffix(long, ".png"))
+}
+
+func TestUploader_Save(t *testing.T) {
+	root := t.TempDir()
+	storage, err := NewLocalStorage(root)
+	require.NoError(t, err)
+	u := NewUploader(storage, WithKeyPrefix("avatars"), WithMaxSize(1024))
+	ctx := context.Background()
+
+	f, err := u.Save(ctx, "../../me.PNG", bytes.NewReader(pngData))
+	require.NoError(t, err)
+	sum := sha256.Sum256(pngData)
+	hash := hex.EncodeToString(sum[:])
+	assert.Equal(t, &UploadedFile{
+		Key:         "avatars/" + hash[:2] + "/" + hash[2:4] + "/" + hash + ".png",
+		Filename:    "me.PNG",
+		Size:        int64(len(pngData)),
+		ContentType: "image/png",
+		Hash:        hash,
+	}, f)
+
+	// stored under the root by the hash
+	data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(f.Key)))
+	require.NoError(t, err)
+	assert.Equal(t, pngData, data)
+	rc, err := storage.Get(ctx, f.Key)
+	require.NoError(t, err)
+	data, _ = io.ReadAll(rc)
+	_ = rc.Close()
+	assert.Equal(t, pngData, data)
+
+	// the text file is not in the allowed types of the image
+	_, err = u.Save(ctx, "a.json", strings.NewReader(`{"a":1}`))
+	assert.NoError(t, err)
+	_, err = u.Save(ctx, "a.exe", strings.NewReader("MZ"))
+	assert.ErrorIs(t, err, ErrFileTypeNotAllowed)
+	_, err = u.Save(ctx, "../", strings.NewReader("x"))
+	assert.ErrorIs(t, err, ErrInvalidFilename)
+}
+
+func TestUploader_Spoofed(t *testing.T) {
+	storage, err := NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	u := NewUploader(storage, WithAllowedTypes(map[string][]string{".png": {"image/png"}, ".jpg": {"image/jpeg"}}))
+	ctx := context.Background()
+
+	// the html renamed as the image
+	_, err = u.Save(ctx, "avatar.png", strings.NewReader("<html><script>alert(1)</script></html>"))
+	assert.ErrorIs(t, err, ErrFileTypeNotAllowed)
+	// the png renamed as the jpeg
+	_, err = u.Save(ctx, "avatar.jpg", bytes.NewReader(pngData))
+	assert.ErrorIs(t, err, ErrFileTypeNotAllowed)
+
+	// the content type header of the multipart is ignored
+	fh := newFileHeader(t, "avatar.png", "image/png", []byte("GIF89a..."))
+	_, err = u.SaveMultipart(ctx, fh)
+	assert.ErrorIs(t, err, ErrFileTypeNotAllowed)
+
+	fh = newFileHeader(t, "avatar.png", "text/plain", pngData)
+	f, err := u.SaveMultipart(ctx, fh)
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", f.ContentType)
+}
+
+func TestUploader_MaxSize(t *testing.T) {
+	root := t.TempDir()
+	storage, err := NewLocalStorage(root)
+	require.NoError(t, err)
+	u := NewUploader(storage, WithMaxSize(int64(len(pngData))), WithTempDir(t.TempDir()))
+	ctx := context.Background()
+
+	_, err = u.Save(ctx, "a.png", bytes.NewReader(pngData))
+	assert.NoError(t, err)
+
+	// the size is enforced while streaming, the reader of unknown size is not read to the end
+	r := &countReader{r: io.MultiReader(bytes.NewReader(pngData), zeroReader{})}
+	_, err = u.Save(ctx, "b.png", r)
+	assert.ErrorIs(t, err, ErrFileTooLarge)
+	assert.LessOrEqual(t, r.n, int64(len(pngData))+64*1024)
+
+	fh := newFileHeader(t, "c.png", "image/png", append(pngData, 0))
+	_, err = u.SaveMultipart(ctx, fh)
+	assert.ErrorIs(t, err, ErrFileTooLarge)
+
+	// only the first file is stored
+	files, err := ListFiles(root)
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+}
+
+func TestLocalStorage(t *testing.T) {
+	storage, err := NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	for _, key := range []string{"", "/etc/passwd", "../a", "a/../../b", `a\b`, "a//b", "."} {
+		assert.ErrorIs(t, storage.Put(ctx, key, strings.NewReader("x"), 1, ""), ErrInvalidKey, key)
+	}
+
+	require.NoError(t, storage.Put(ctx, "a/b.txt", strings.NewReader("hello"), 5, "text/plain"))
+	require.NoError(t, storage.Put(ctx, "a/b.txt", strings.NewReader("world"), 5, "text/plain"))
+	rc, err := storage.Get(ctx, "a/b.txt")
+	require.NoError(t, err)
+	data, _ := io.ReadAll(rc)
+	_ = rc.Close()
+	assert.Equal(t, "world", string(data))
+
+	assert.NoError(t, storage.Delete(ctx, "a/b.txt"))
+	assert.NoError(t, storage.Delete(ctx, "a/b.txt"))
+	_, err = storage.Get(ctx, "a/b.txt")
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+}
+
+func newFileHeader(t *testing.T, filename string, contentType string, data []byte) *multipart.FileHeader {
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	h := make(map[string][]string)
+	h["Content-Disposition"] = []string{`form-data; name="file"; filename="` + filename + `"`}
+	h["Content-Type"] = []string{contentType}
+	part, err := w.CreatePart(h)
+	require.NoError(t, err)
+	_, _ = part.Write(data)
+	require.NoError(t, w.Close())
+
+	req := httptest.NewRequest("POST", "/upload", body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	require.NoError(t, req.ParseMultipartForm(1<<20))
+	return req.MultipartForm.File["file"][0]
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+type countReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -1,6 +1,7 @@
 package goredis
 
 import (
+	"context"
 	"crypto/tls"
 	"time"
 
@@ -16,6 +17,8 @@ type options struct {
 	readTimeout  time.Duration
 	writeTimeout time.Duration
 	tlsConfig    *tls.Config
+	pingTimeout  time.Duration
+	onConnects   []func(ctx context.Context, cn *redis.Conn) error
 
 	// Note: this field is only used for Init and InitSingle, and the other parameters will be ignored.
 	singleOptions *redis.Options
@@ -41,6 +44,7 @@ func (o *options) apply(opts ...Option) {
 func defaultOptions() *options {
 	return &options{
 		enableTrace: false, // whether to enable trace, default off
+		pingTimeout: 15 * time.Second,
 	}
 }
 
@@ -107,3 +111,37 @@ func WithClusterOptions(opt *redis.ClusterOptions) Option {
 		o.clusterOptions = opt
 	}
 }
+
+// WithPingTimeout set the timeout of the ping after connecting, default 15s
+func WithPingTimeout(t time.Duration) Option {
+	return func(o *options) {
+		if t > 0 {
+			o.pingTimeout = t
+		}
+	}
+}
+
+// WithOnConnect add a hook called for each new connection, e.g. set the client name, only used for InitUniversal,
+// the hooks are called in order, an error of the hook closes the connection.
+func WithOnConnect(fn func(ctx context.Context, cn *redis.Conn) error) Option {
+	return func(o *options) {
+		if fn != nil {
+			o.onConnects = append(o.onConnects, fn)
+		}
+	}
+}
+
+func (o *options) onConnect() func(ctx context.Context, cn *redis.Conn) error {
+	if len(o.onConnects) == 0 {
+		return nil
+	}
+	hooks := o.onConnects
+	return func(ctx context.Context, cn *redis.Conn) error {
+		for _, hook := range hooks {
+			if err := hook(ctx, cn); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,217 @@
+package goredis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/extra/redisotel/v9"
+	"github.com/redis/go-redis/v9"
+)
+
+// UniversalClient is a redis client of single, sentinel or cluster mode
+type UniversalClient = redis.UniversalClient
+
+// ClusterClient is a redis cluster client
+type ClusterClient = redis.ClusterClient
+
+// redis modes
+const (
+	ModeSingle   = "single"
+	ModeSentinel = "sentinel"
+	ModeCluster  = "cluster"
+)
+
+// Config is the redis config of all modes
+type Config struct {
+	// single, sentinel or cluster, if empty, the mode is detected: sentinel if MasterName is set,
+	// cluster if there are multiple addresses, otherwise single.
+	Mode string
+	// the redis address in single mode, the sentinel addresses in sentinel mode, the seed nodes in cluster mode
+	Addrs []string
+	// the master name in sentinel mode
+	MasterName string
+
+	Username string
+	Password string
+	// the sentinel username and password, default the same as Username and Password
+	SentinelUsername string
+	SentinelPassword string
+	// only used in single and sentinel mode
+	DB int
+	// set by CLIENT SETNAME for each connection
+	ClientName string
+
+	// pool tuning, zero means the default of go-redis
+	PoolSize        int
+	MinIdleConns    int
+	MaxIdleConns    int
+	PoolTimeout     time.Duration
+	ConnMaxIdleTime time.Duration
+	ConnMaxLifetime time.Duration
+
+	// route the read only commands to the replicas, in sentinel mode the client is a *ClusterClient
+	// routing the writes to the master, and the DB must be 0
+	ReadFromReplica bool
+}
+
+// DetectMode get the redis mode of the config
+func (c *Config) DetectMode() string {
+	switch {
+	case c.Mode != "":
+		return c.Mode
+	case c.MasterName != "":
+		return ModeSentinel
+	case len(c.Addrs) > 1:
+		return ModeCluster
+	default:
+		return ModeSingle
+	}
+}
+
+func (c *Config) universalOptions(o *options) *redis.UniversalOptions {
+	uo := &redis.UniversalOptions{
+		Addrs:            c.Addrs,
+		ClientName:       c.ClientName,
+		DB:               c.DB,
+		OnConnect:        o.onConnect(),
+		Username:         c.Username,
+		Password:         c.Password,
+		SentinelUsername: c.SentinelUsername,
+		SentinelPassword: c.SentinelPassword,
+		DialTimeout:      o.dialTimeout,
+		ReadTimeout:      o.readTimeout,
+		WriteTimeout:     o.writeTimeout,
+		PoolSize:         c.PoolSize,
+		PoolTimeout:      c.PoolTimeout,
+		MinIdleConns:     c.MinIdleConns,
+		MaxIdleConns:     c.MaxIdleConns,
+		ConnMaxIdleTime:  c.ConnMaxIdleTime,
+		ConnMaxLifetime:  c.ConnMaxLifetime,
+		TLSConfig:        o.tlsConfig,
+		MasterName:       c.MasterName,
+	}
+	if uo.SentinelUsername == "" && uo.SentinelPassword == "" {
+		uo.SentinelUsername = c.Username
+		uo.SentinelPassword = c.Password
+	}
+	return uo
+}
+
+// newUniversalClient create the client of the mode without connecting
+func newUniversalClient(c *Config, o *options) (redis.UniversalClient, error) {
+	if len(c.Addrs) == 0 {
+		return nil, errors.New("redis addrs cannot be empty")
+	}
+	uo := c.universalOptions(o)
+
+	switch mode := c.DetectMode(); mode {
+	case ModeSingle:
+		if len(c.Addrs) > 1 {
+			return nil, errors.New("single mode only supports one address")
+		}
+		opt := uo.Simple()
+		if o.singleOptions != nil {
+			opt = o.singleOptions
+		}
+		return redis.NewClient(opt), nil
+
+	case ModeSentinel:
+		if c.MasterName == "" {
+			return nil, errors.New("sentinel master name cannot be empty")
+		}
+		opt := uo.Failover()
+		if o.sentinelOptions != nil {
+			opt = o.sentinelOptions
+		}
+		if c.ReadFromReplica {
+			if opt.DB != 0 {
+				return nil, errors.New("reading from replicas in sentinel mode only supports db 0")
+			}
+			opt.RouteRandomly = true
+			return redis.NewFailoverClusterClient(opt), nil
+		}
+		return redis.NewFailoverClient(opt), nil
+
+	case ModeCluster:
+		opt := uo.Cluster()
+		opt.ReadOnly = c.ReadFromReplica
+		if o.clusterOptions != nil {
+			opt = o.clusterOptions
+		}
+		return redis.NewClusterClient(opt), nil
+
+	default:
+		return nil, fmt.Errorf("unknown redis mode %q", mode)
+	}
+}
+
+// InitUniversal connecting to redis of single, sentinel or cluster mode by the config, and ping with the timeout
+// set by WithPingTimeout. The client is *Client in single and sentinel mode, *ClusterClient in cluster mode or
+// reading from replicas in sentinel mode, so the cache and dlock work with the client of all modes.
+func InitUniversal(c *Config, opts ...Option) (UniversalClient, error) {
+	o := defaultOptions()
+	o.apply(opts...)
+
+	rdb, err := newUniversalClient(c, o)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.tracerProvider != nil {
+		err = redisotel.InstrumentTracing(rdb, redisotel.WithTracerProvider(o.tracerProvider))
+		if err != nil {
+			_ = rdb.Close()
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), o.pingTimeout)
+	defer cancel()
+	err = ping(ctx, rdb)
+
+	return rdb, err
+}
+
+// HealthCheck returns the health check function of the client for the readiness probe,
+// in cluster mode all masters are pinged, timeout <= 0 means no timeout other than ctx.
+func HealthCheck(rdb UniversalClient, timeout time.Duration) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if rdb == nil {
+			return errors.New("redis health check: client is nil")
+		}
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		if err := ping(ctx, rdb); err != nil {
+			return fmt.Errorf("redis health check: %w", err)
+		}
+		return nil
+	}
+}
+
+func ping(ctx context.Context, rdb UniversalClient) error {
+	if clusterRdb, ok := rdb.(*redis.ClusterClient); ok {
+		return clusterRdb.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+			return client.Ping(ctx).Err()
+		})
+	}
+	return rdb.Ping(ctx).Err()
+}
+
+// CloseUniversal close the redis client of any mode
+func CloseUniversal(rdb UniversalClient) error {
+	if rdb == nil {
+		return nil
+	}
+
+	err := rdb.Close()
+	if err != nil && !errors.Is(err, redis.ErrClosed) {
+		return err
+	}
+
+	return nil
+}
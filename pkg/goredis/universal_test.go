@@ -0,0 +1,183 @@
+package goredis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_DetectMode(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *Config
+		want string
+	}{
+		{"single", &Config{Addrs: []string{"127.0.0.1:6379"}}, ModeSingle},
+		{"sentinel", &Config{Addrs: []string{"127.0.0.1:26379"}, MasterName: "mymaster"}, ModeSentinel},
+		{"cluster", &Config{Addrs: []string{"127.0.0.1:6380", "127.0.0.1:6381"}}, ModeCluster},
+		{"explicit", &Config{Mode: ModeCluster, Addrs: []string{"127.0.0.1:6380"}}, ModeCluster},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.cfg.DetectMode())
+		})
+	}
+}
+
+func TestNewUniversalClient(t *testing.T) {
+	o := defaultOptions()
+	o.apply(WithDialTimeout(time.Second), WithReadTimeout(2*time.Second), WithWriteTimeout(3*time.Second))
+
+	// single
+	rdb, err := newUniversalClient(&Config{
+		Addrs:      []string{"127.0.0.1:6379"},
+		Password:   "123456",
+		DB:         2,
+		ClientName: "foo",
+		PoolSize:   20,
+	}, o)
+	assert.NoError(t, err)
+	client, ok := rdb.(*redis.Client)
+	assert.True(t, ok)
+	assert.Equal(t, "127.0.0.1:6379", client.Options().Addr)
+	assert.Equal(t, 2, client.Options().DB)
+	assert.Equal(t, "foo", client.Options().ClientName)
+	assert.Equal(t, 20, client.Options().PoolSize)
+	assert.Equal(t, time.Second, client.Options().DialTimeout)
+	_ = rdb.Close()
+
+	// sentinel
+	rdb, err = newUniversalClient(&Config{
+		Addrs:        []string{"127.0.0.1:26379", "127.0.0.1:26380"},
+		MasterName:   "mymaster",
+		Password:     "123456",
+		MinIdleConns: 5,
+	}, o)
+	assert.NoError(t, err)
+	client, ok = rdb.(*redis.Client)
+	assert.True(t, ok)
+	assert.Equal(t, 5, client.Options().MinIdleConns)
+	_ = rdb.Close()
+
+	// sentinel, read from replica
+	rdb, err = newUniversalClient(&Config{
+		Addrs:           []string{"127.0.0.1:26379"},
+		MasterName:      "mymaster",
+		ReadFromReplica: true,
+	}, o)
+	assert.NoError(t, err)
+	clusterClient, ok := rdb.(*redis.ClusterClient)
+	assert.True(t, ok)
+	assert.True(t, clusterClient.Options().RouteRandomly)
+	_ = rdb.Close()
+
+	// cluster, read from replica
+	rdb, err = newUniversalClient(&Config{
+		Addrs:           []string{"127.0.0.1:6380", "127.0.0.1:6381", "127.0.0.1:6382"},
+		Username:        "default",
+		Password:        "123456",
+		ReadFromReplica: true,
+		PoolTimeout:     time.Second,
+	}, o)
+	assert.NoError(t, err)
+	clusterClient, ok = rdb.(*redis.ClusterClient)
+	assert.True(t, ok)
+	assert.Len(t, clusterClient.Options().Addrs, 3)
+	assert.True(t, clusterClient.Options().ReadOnly)
+	assert.Equal(t, time.Second, clusterClient.Options().PoolTimeout)
+	assert.Equal(t, "default", clusterClient.Options().Username)
+	_ = rdb.Close()
+
+	// errors
+	_, err = newUniversalClient(&Config{}, o)
+	assert.Error(t, err)
+	_, err = newUniversalClient(&Config{Mode: ModeSingle, Addrs: []string{"127.0.0.1:6380", "127.0.0.1:6381"}}, o)
+	assert.Error(t, err)
+	_, err = newUniversalClient(&Config{Mode: ModeSentinel, Addrs: []string{"127.0.0.1:26379"}}, o)
+	assert.Error(t, err)
+	_, err = newUniversalClient(&Config{Addrs: []string{"127.0.0.1:26379"}, MasterName: "mymaster", DB: 1, ReadFromReplica: true}, o)
+	assert.Error(t, err)
+	_, err = newUniversalClient(&Config{Mode: "unknown", Addrs: []string{"127.0.0.1:6379"}}, o)
+	assert.Error(t, err)
+}
+
+func TestConfig_universalOptions(t *testing.T) {
+	o := defaultOptions()
+	cfg := &Config{Addrs: []string{"127.0.0.1:26379"}, MasterName: "mymaster", Username: "foo", Password: "bar"}
+
+	// the sentinel credentials default to the redis credentials
+	uo := cfg.universalOptions(o)
+	assert.Equal(t, "foo", uo.SentinelUsername)
+	assert.Equal(t, "bar", uo.SentinelPassword)
+
+	cfg.SentinelPassword = "sentinel"
+	uo = cfg.universalOptions(o)
+	assert.Equal(t, "", uo.SentinelUsername)
+	assert.Equal(t, "sentinel", uo.SentinelPassword)
+}
+
+func TestInitUniversal(t *testing.T) {
+	redisServer, _ := miniredis.Run()
+	defer redisServer.Close()
+	addr := redisServer.Addr()
+
+	connects := 0
+	rdb, err := InitUniversal(&Config{Addrs: []string{addr}},
+		WithPingTimeout(time.Second),
+		WithOnConnect(func(ctx context.Context, cn *redis.Conn) error {
+			connects++
+			return nil
+		}),
+		WithOnConnect(nil), // nil means no set field
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, connects)
+	assert.NoError(t, rdb.Set(context.Background(), "foo", "bar", 0).Err())
+	assert.NoError(t, CloseUniversal(rdb))
+	assert.NoError(t, CloseUniversal(rdb))
+	assert.NoError(t, CloseUniversal(nil))
+
+	// config error
+	_, err = InitUniversal(&Config{})
+	assert.Error(t, err)
+
+	// ping error
+	redisServer.Close()
+	rdb, err = InitUniversal(&Config{Addrs: []string{addr}}, WithPingTimeout(time.Second))
+	assert.Error(t, err)
+	_ = CloseUniversal(rdb)
+}
+
+func TestHealthCheck(t *testing.T) {
+	redisServer, _ := miniredis.Run()
+	defer redisServer.Close()
+	addr := redisServer.Addr()
+	rdb := redis.NewClient(&redis.Options{Addr: addr})
+	defer rdb.Close()
+	ctx := context.Background()
+
+	check := HealthCheck(rdb, time.Second)
+	assert.NoError(t, check(ctx))
+
+	// redis error
+	redisServer.SetError("server error")
+	assert.Error(t, check(ctx))
+	redisServer.SetError("")
+
+	// the redis server is down
+	redisServer.Close()
+	err := HealthCheck(rdb, 0)(ctx)
+	assert.ErrorContains(t, err, "redis health check")
+
+	// nil client
+	assert.Error(t, HealthCheck(nil, time.Second)(ctx))
+
+	// cluster client, no master is reachable
+	clusterRdb := redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{addr}})
+	defer clusterRdb.Close()
+	assert.Error(t, HealthCheck(clusterRdb, time.Second)(ctx))
+}
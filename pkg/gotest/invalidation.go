@@ -0,0 +1,80 @@
+package gotest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// DefaultWritePrefixes the method name prefixes of the dao write methods
+var DefaultWritePrefixes = []string{"Create", "Update", "Delete"}
+
+// WriteCase a call of a dao write method
+type WriteCase struct {
+	Method string       // method name of the dao interface, e.g. "UpdateByID"
+	Call   func() error // call the method, set the sql mock expectations in it
+	Keys   []string     // the redis keys that must be deleted by the call, e.g. "userExample:1"
+}
+
+// CheckInvalidation assert that every write method of the dao invalidates the cache, iface is a nil pointer
+// of the dao interface, e.g. (*UserExampleDao)(nil), a write method (named with DefaultWritePrefixes) without
+// a case fails the test, so a new write method can't skip the invalidation silently. generation gets the
+// list generation of the resource, it must be bumped by every call, and the keys of the case must be deleted.
+func (d *Dao) CheckInvalidation(t *testing.T, iface interface{}, generation func() int64, cases ...WriteCase) {
+	t.Helper()
+
+	caseMap := make(map[string]WriteCase, len(cases))
+	for _, c := range cases {
+		caseMap[c.Method] = c
+	}
+	for _, method := range WriteMethods(iface) {
+		if _, ok := caseMap[method]; !ok {
+			t.Errorf("write method %s has no invalidation case", method)
+		}
+	}
+
+	for _, c := range cases {
+		if d.Cache != nil {
+			for _, key := range c.Keys {
+				if err := d.Cache.redisServer.Set(key, "*"); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+
+		gen := generation()
+		if err := c.Call(); err != nil {
+			t.Errorf("%s error: %v", c.Method, err)
+			continue
+		}
+		assert.Greater(t, generation(), gen, "%s doesn't bump the list generation", c.Method)
+		if d.Cache != nil {
+			for _, key := range c.Keys {
+				assert.False(t, d.Cache.redisServer.Exists(key), "%s doesn't delete the key %s", c.Method, key)
+			}
+		}
+	}
+}
+
+// WriteMethods get the names of the write methods of the interface, iface is a nil pointer of the interface
+func WriteMethods(iface interface{}) []string {
+	typ := reflect.TypeOf(iface)
+	if typ == nil || typ.Kind() != reflect.Ptr || typ.Elem().Kind() != reflect.Interface {
+		panic("iface must be a nil pointer of an interface, e.g. (*UserExampleDao)(nil)")
+	}
+	typ = typ.Elem()
+
+	var methods []string
+	for i := 0; i < typ.NumMethod(); i++ {
+		name := typ.Method(i).Name
+		for _, prefix := range DefaultWritePrefixes {
+			if strings.HasPrefix(name, prefix) {
+				methods = append(methods, name)
+				break
+			}
+		}
+	}
+	return methods
+}
@@ -0,0 +1,44 @@
+package gotest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type writeDao interface {
+	Create(ctx context.Context, id uint64) error
+	DeleteByID(ctx context.Context, id uint64) error
+	UpdateByID(ctx context.Context, id uint64) error
+	GetByID(ctx context.Context, id uint64) error
+}
+
+func TestWriteMethods(t *testing.T) {
+	methods := WriteMethods((*writeDao)(nil))
+	assert.ElementsMatch(t, []string{"Create", "DeleteByID", "UpdateByID"}, methods)
+
+	assert.Panics(t, func() { WriteMethods(writeDao(nil)) })
+	assert.Panics(t, func() { WriteMethods(&struct{}{}) })
+}
+
+func TestDao_CheckInvalidation(t *testing.T) {
+	c := NewCache(map[string]interface{}{})
+	d := NewDao(c, &struct{}{})
+	defer d.Close()
+
+	var gen int64
+	invalidate := func(keys ...string) error {
+		gen++
+		for _, key := range keys {
+			c.redisServer.Del(key)
+		}
+		return nil
+	}
+	d.CheckInvalidation(t, (*writeDao)(nil), func() int64 { return gen },
+		WriteCase{Method: "Create", Call: func() error { return invalidate() }},
+		WriteCase{Method: "DeleteByID", Call: func() error { return invalidate("user:1") }, Keys: []string{"user:1"}},
+		WriteCase{Method: "UpdateByID", Call: func() error { return invalidate("user:1") }, Keys: []string{"user:1"}},
+	)
+	assert.Equal(t, int64(3), gen)
+}
@@ -24,6 +24,7 @@ type ratelimitOptions struct {
 	bucket       int
 	cpuThreshold int64
 	cpuQuota     float64
+	limiter      rl.Limiter
 }
 
 func defaultRatelimitOptions() *ratelimitOptions {
@@ -68,16 +69,31 @@ func WithCPUQuota(quota float64) RatelimitOption {
 	}
 }
 
-// UnaryServerRateLimit server-side unary circuit breaker interceptor
-func UnaryServerRateLimit(opts ...RatelimitOption) grpc.UnaryServerInterceptor {
-	o := defaultRatelimitOptions()
-	o.apply(opts...)
-	limiter := rl.NewLimiter(
+// WithLimiter use the specified limiter instead of the adaptive limiter, e.g. the redis limiter
+// shared by all the replicas, the other options are ignored.
+func WithLimiter(limiter rl.Limiter) RatelimitOption {
+	return func(o *ratelimitOptions) {
+		o.limiter = limiter
+	}
+}
+
+func (o *ratelimitOptions) getLimiter() rl.Limiter {
+	if o.limiter != nil {
+		return o.limiter
+	}
+	return rl.NewLimiter(
 		rl.WithWindow(o.window),
 		rl.WithBucket(o.bucket),
 		rl.WithCPUThreshold(o.cpuThreshold),
 		rl.WithCPUQuota(o.cpuQuota),
 	)
+}
+
+// UnaryServerRateLimit server-side unary circuit breaker interceptor
+func UnaryServerRateLimit(opts ...RatelimitOption) grpc.UnaryServerInterceptor {
+	o := defaultRatelimitOptions()
+	o.apply(opts...)
+	limiter := o.getLimiter()
 
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
 		done, err := limiter.Allow()
@@ -95,12 +111,7 @@ func UnaryServerRateLimit(opts ...RatelimitOption) grpc.UnaryServerInterceptor {
 func StreamServerRateLimit(opts ...RatelimitOption) grpc.StreamServerInterceptor {
 	o := defaultRatelimitOptions()
 	o.apply(opts...)
-	limiter := rl.NewLimiter(
-		rl.WithWindow(o.window),
-		rl.WithBucket(o.bucket),
-		rl.WithCPUThreshold(o.cpuThreshold),
-		rl.WithCPUQuota(o.cpuQuota),
-	)
+	limiter := o.getLimiter()
 
 	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
 		done, err := limiter.Allow()
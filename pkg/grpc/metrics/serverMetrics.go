@@ -32,6 +32,7 @@ var (
 	customizedSummaryMetrics   = []*prometheus.SummaryVec{}
 	customizedGaugeMetrics     = []*prometheus.GaugeVec{}
 	customizedHistogramMetrics = []*prometheus.HistogramVec{}
+	customizedCollectors       = []prometheus.Collector{}
 	grpcConnectionGauge        = prometheus.NewGauge(
 		prometheus.GaugeOpts{
 			Name: "grpc_server_active_connections",
@@ -85,6 +86,17 @@ func WithHistogramMetrics(metrics ...*prometheus.HistogramVec) Option {
 	}
 }
 
+// WithCollectors add custom collectors, e.g. the process metrics collector of stat
+func WithCollectors(cs ...prometheus.Collector) Option {
+	return func(o *options) {
+		for _, c := range cs {
+			if c != nil {
+				customizedCollectors = append(customizedCollectors, c)
+			}
+		}
+	}
+}
+
 func srvRegisterMetrics() {
 	srvOnce.Do(func() {
 		// enable time record
@@ -109,6 +121,9 @@ func srvRegisterMetrics() {
 		for _, metric := range customizedHistogramMetrics {
 			srvReg.MustRegister(metric)
 		}
+		for _, c := range customizedCollectors {
+			srvReg.MustRegister(c)
+		}
 		srvReg.MustRegister(grpcConnectionGauge)
 	})
 }
@@ -57,6 +57,15 @@ func TestWithSummaryMetrics(t *testing.T) {
 	assert.Contains(t, customizedSummaryMetrics, testData)
 }
 
+func TestWithCollectors(t *testing.T) {
+	testData := prometheus.NewGauge(prometheus.GaugeOpts{Name: "demo5"})
+	opt := WithCollectors(testData, nil)
+	o := new(options)
+	o.apply(opt)
+	assert.Contains(t, customizedCollectors, testData)
+	assert.NotContains(t, customizedCollectors, nil)
+}
+
 func Test_defaultMetricsOptions(t *testing.T) {
 	o := defaultMetricsOptions()
 	assert.NotNil(t, o)
@@ -3,6 +3,7 @@ package httpcli
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,6 +12,8 @@ import (
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/go-dev-frame/sponge/pkg/utils"
 )
 
 const defaultTimeout = 30 * time.Second
@@ -24,6 +27,7 @@ type Request struct {
 	bodyJSON      interface{}            // JSON marshal body data
 	timeout       time.Duration          // Client timeout
 	headers       map[string]string
+	ctx           context.Context
 
 	request  *http.Request
 	response *Response
@@ -51,6 +55,7 @@ func (req *Request) Reset() {
 	req.bodyJSON = nil
 	req.timeout = 0
 	req.headers = nil
+	req.ctx = nil
 
 	req.request = nil
 	req.response = nil
@@ -104,6 +109,15 @@ func (req *Request) SetTimeout(t time.Duration) *Request {
 	return req
 }
 
+// SetContext set the context of the request, the request is canceled with the context, and if the context has
+// the deadline, e.g. the deadline budget of the incoming request, the timeout is the fraction of the remaining
+// budget by utils.CallTimeout if it is less than the timeout set, utils.ErrBudgetExhausted is returned without
+// sending if the budget is exhausted
+func (req *Request) SetContext(ctx context.Context) *Request {
+	req.ctx = ctx
+	return req
+}
+
 // SetContentType set ContentType
 func (req *Request) SetContentType(a string) *Request {
 	req.SetHeader("Content-Type", a)
@@ -239,7 +253,17 @@ func (req *Request) push() (*Response, error) {
 }
 
 func (req *Request) send(body io.Reader, buf *bytes.Buffer) (*Response, error) {
-	req.request, req.err = http.NewRequest(req.method, req.url, body)
+	ctx := req.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var budget time.Duration
+	budget, req.err = utils.CallTimeout(ctx)
+	if req.err != nil {
+		return nil, req.err
+	}
+
+	req.request, req.err = http.NewRequestWithContext(ctx, req.method, req.url, body)
 	if req.err != nil {
 		return nil, req.err
 	}
@@ -257,6 +281,9 @@ func (req *Request) send(body io.Reader, buf *bytes.Buffer) (*Response, error) {
 	if req.timeout < 1 {
 		req.timeout = defaultTimeout
 	}
+	if budget > 0 && budget < req.timeout {
+		req.timeout = budget
+	}
 
 	client := http.Client{Timeout: req.timeout}
 	resp := new(Response)
@@ -332,6 +359,7 @@ type options struct {
 	params  map[string]interface{}
 	headers map[string]string
 	timeout time.Duration
+	ctx     context.Context
 }
 
 func (o *options) apply(opts ...Option) {
@@ -365,42 +393,49 @@ func WithTimeout(t time.Duration) Option {
 	}
 }
 
+// WithContext set the context of the request, see Request.SetContext
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
 // Get request, return custom json format
 func Get(result interface{}, urlStr string, opts ...Option) error {
 	o := defaultOptions()
 	o.apply(opts...)
-	return gDo("GET", result, urlStr, o.params, o.headers, o.timeout)
+	return gDo(o.ctx, "GET", result, urlStr, o.params, o.headers, o.timeout)
 }
 
 // Delete request, return custom json format
 func Delete(result interface{}, urlStr string, opts ...Option) error {
 	o := defaultOptions()
 	o.apply(opts...)
-	return gDo("DELETE", result, urlStr, o.params, o.headers, o.timeout)
+	return gDo(o.ctx, "DELETE", result, urlStr, o.params, o.headers, o.timeout)
 }
 
 // Post request, return custom json format
 func Post(result interface{}, urlStr string, body interface{}, opts ...Option) error {
 	o := defaultOptions()
 	o.apply(opts...)
-	return do("POST", result, urlStr, body, o.params, o.headers, o.timeout)
+	return do(o.ctx, "POST", result, urlStr, body, o.params, o.headers, o.timeout)
 }
 
 // Put request, return custom json format
 func Put(result interface{}, urlStr string, body interface{}, opts ...Option) error {
 	o := defaultOptions()
 	o.apply(opts...)
-	return do("PUT", result, urlStr, body, o.params, o.headers, o.timeout)
+	return do(o.ctx, "PUT", result, urlStr, body, o.params, o.headers, o.timeout)
 }
 
 // Patch request, return custom json format
 func Patch(result interface{}, urlStr string, body interface{}, opts ...Option) error {
 	o := defaultOptions()
 	o.apply(opts...)
-	return do("PATCH", result, urlStr, body, o.params, o.headers, o.timeout)
+	return do(o.ctx, "PATCH", result, urlStr, body, o.params, o.headers, o.timeout)
 }
 
-var requestErr = func(err error) error { return fmt.Errorf("request error, err=%v", err) }
+var requestErr = func(err error) error { return fmt.Errorf("request error, err=%w", err) }
 var jsonParseErr = func(err error) error { return fmt.Errorf("json parsing error, err=%v", err) }
 var notOKErr = func(resp *Response) error {
 	body, err := resp.ReadBody()
@@ -413,7 +448,7 @@ var notOKErr = func(resp *Response) error {
 	return fmt.Errorf("statusCode=%d, body=%s", resp.StatusCode, body)
 }
 
-func do(method string, result interface{}, urlStr string, body interface{}, params KV, headers map[string]string, timeout time.Duration) error {
+func do(ctx context.Context, method string, result interface{}, urlStr string, body interface{}, params KV, headers map[string]string, timeout time.Duration) error {
 	if result == nil {
 		return fmt.Errorf("'result' can not be nil")
 	}
@@ -425,6 +460,7 @@ func do(method string, result interface{}, urlStr string, body interface{}, para
 	req.SetHeaders(headers)
 	req.SetBody(body)
 	req.SetTimeout(timeout)
+	req.SetContext(ctx)
 
 	var resp *Response
 	var err error
@@ -453,12 +489,13 @@ func do(method string, result interface{}, urlStr string, body interface{}, para
 	return nil
 }
 
-func gDo(method string, result interface{}, urlStr string, params KV, headers map[string]string, timeout time.Duration) error {
+func gDo(ctx context.Context, method string, result interface{}, urlStr string, params KV, headers map[string]string, timeout time.Duration) error {
 	req := &Request{}
 	req.SetURL(urlStr)
 	req.SetParams(params)
 	req.SetHeaders(headers)
 	req.SetTimeout(timeout)
+	req.SetContext(ctx)
 
 	var resp *Response
 	var err error
@@ -2,9 +2,11 @@ package httpcli
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -720,11 +722,39 @@ func TestError(t *testing.T) {
 	err = notOKErr(resp)
 	assert.Error(t, err)
 
-	err = do(http.MethodPost, nil, "", nil, nil, nil, 0)
+	err = do(context.Background(), http.MethodPost, nil, "", nil, nil, nil, 0)
 	assert.Error(t, err)
-	err = do(http.MethodPost, &StdResult{}, "http://127.0.0.1:0", nil, KV{"foo": "bar"}, nil, 0)
+	err = do(context.Background(), http.MethodPost, &StdResult{}, "http://127.0.0.1:0", nil, KV{"foo": "bar"}, nil, 0)
 	assert.Error(t, err)
 
-	err = gDo(http.MethodGet, nil, "http://127.0.0.1:0", nil, nil, 0)
+	err = gDo(context.Background(), http.MethodGet, nil, "http://127.0.0.1:0", nil, nil, 0)
 	assert.Error(t, err)
 }
+
+func TestWithContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"code":0,"msg":"ok"}`))
+	}))
+	defer server.Close()
+
+	// the timeout is the fraction of the remaining budget
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := Get(&StdResult{}, server.URL, WithContext(ctx), WithTimeout(10*time.Second))
+	assert.Error(t, err)
+	assert.True(t, time.Since(start) < 290*time.Millisecond, time.Since(start))
+
+	// the budget is exhausted, the request is not sent
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = Post(&StdResult{}, server.URL, nil, WithContext(ctx))
+	assert.ErrorIs(t, err, utils.ErrBudgetExhausted)
+	_, err = New().SetURL(server.URL).SetContext(ctx).GET()
+	assert.ErrorIs(t, err, utils.ErrBudgetExhausted)
+}
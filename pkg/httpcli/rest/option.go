@@ -0,0 +1,116 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// ClientOption set the client options.
+type ClientOption func(*clientOptions)
+
+type clientOptions struct {
+	timeout             time.Duration
+	dialTimeout         time.Duration
+	maxIdleConns        int
+	maxIdleConnsPerHost int
+	maxConnsPerHost     int
+	idleConnTimeout     time.Duration
+	httpClient          *http.Client
+
+	headers          map[string]string
+	retryAttempts    int
+	retryInterval    time.Duration
+	retryMaxInterval time.Duration
+	requestIDHeader  string
+	requestIDFn      func(ctx context.Context) string
+}
+
+func (o *clientOptions) apply(opts ...ClientOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+func defaultClientOptions() *clientOptions {
+	return &clientOptions{
+		timeout:             10 * time.Second,
+		dialTimeout:         5 * time.Second,
+		maxIdleConns:        100,
+		maxIdleConnsPerHost: 20,
+		idleConnTimeout:     90 * time.Second,
+
+		retryAttempts:    3,
+		retryInterval:    100 * time.Millisecond,
+		retryMaxInterval: time.Second,
+		requestIDHeader:  "X-Request-Id",
+		requestIDFn:      requestIDFromContext,
+	}
+}
+
+// the request id set by the RequestID middleware, see middleware.WrapCtx
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value("request_id").(string) //nolint
+	return id
+}
+
+// WithTimeout set the timeout of each request attempt, default 10s
+func WithTimeout(d time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.timeout = d
+	}
+}
+
+// WithDialTimeout set the timeout of establishing connections, default 5s
+func WithDialTimeout(d time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.dialTimeout = d
+	}
+}
+
+// WithConnPool set the connection pool, maxIdleConnsPerHost default 20, maxConnsPerHost default 0 means no limit
+func WithConnPool(maxIdleConns int, maxIdleConnsPerHost int, maxConnsPerHost int, idleConnTimeout time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.maxIdleConns = maxIdleConns
+		o.maxIdleConnsPerHost = maxIdleConnsPerHost
+		o.maxConnsPerHost = maxConnsPerHost
+		o.idleConnTimeout = idleConnTimeout
+	}
+}
+
+// WithHTTPClient use a custom http client, the timeout and connection pool options are ignored
+func WithHTTPClient(c *http.Client) ClientOption {
+	return func(o *clientOptions) {
+		o.httpClient = c
+	}
+}
+
+// WithHeaders set the headers of all requests, e.g. Authorization
+func WithHeaders(headers map[string]string) ClientOption {
+	return func(o *clientOptions) {
+		o.headers = headers
+	}
+}
+
+// WithRetry set the retry policy of idempotent requests, attempts includes the first call,
+// 1 means no retry, default 3 attempts, interval 100ms, max interval 1s
+func WithRetry(attempts int, interval time.Duration, maxInterval time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.retryAttempts = attempts
+		o.retryInterval = interval
+		o.retryMaxInterval = maxInterval
+	}
+}
+
+// WithRequestID set how the request id is propagated, header default X-Request-Id,
+// fn gets the request id from the context of the request, e.g. middleware.CtxRequestID
+func WithRequestID(header string, fn func(ctx context.Context) string) ClientOption {
+	return func(o *clientOptions) {
+		if header != "" {
+			o.requestIDHeader = header
+		}
+		if fn != nil {
+			o.requestIDFn = fn
+		}
+	}
+}
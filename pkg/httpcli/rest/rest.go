@@ -0,0 +1,280 @@
+// Package rest is a typed http client for calling the apis of other services, it decodes the
+// standard response envelope {"code":0,"msg":"ok","data":{}} into the data type, retries the
+// idempotent requests, and propagates the request id and trace context.
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+
+	"github.com/go-dev-frame/sponge/pkg/utils"
+)
+
+// APIError the error returned by the server, it is either a non-zero code of the response envelope,
+// or a response status code that is not 2xx.
+type APIError struct {
+	Status int    // http status code
+	Code   int    // error code of the response envelope
+	Msg    string // error message of the response envelope
+}
+
+// Error returns the error message
+func (e *APIError) Error() string {
+	return fmt.Sprintf("api error, status=%d, code=%d, msg=%s", e.Status, e.Code, e.Msg)
+}
+
+// envelope standard response of sponge services
+type envelope struct {
+	Code *int            `json:"code"`
+	Msg  string          `json:"msg"`
+	Data json.RawMessage `json:"data"`
+}
+
+// problem RFC 7807 problem details
+type problem struct {
+	Title  string `json:"title"`
+	Detail string `json:"detail"`
+	Code   int    `json:"code"`
+}
+
+// Client http client of a service
+type Client struct {
+	baseURL         string
+	httpClient      *http.Client
+	headers         map[string]string
+	retryOpts       []utils.RetryOption
+	requestIDHeader string
+	requestIDFn     func(ctx context.Context) string
+}
+
+// NewClient create a client, baseURL is the address of the service, e.g. http://user-service:8080
+func NewClient(baseURL string, opts ...ClientOption) *Client {
+	o := defaultClientOptions()
+	o.apply(opts...)
+
+	httpClient := o.httpClient
+	if httpClient == nil {
+		httpClient = &http.Client{
+			Timeout: o.timeout,
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				DialContext: (&net.Dialer{
+					Timeout:   o.dialTimeout,
+					KeepAlive: 30 * time.Second,
+				}).DialContext,
+				MaxIdleConns:          o.maxIdleConns,
+				MaxIdleConnsPerHost:   o.maxIdleConnsPerHost,
+				MaxConnsPerHost:       o.maxConnsPerHost,
+				IdleConnTimeout:       o.idleConnTimeout,
+				TLSHandshakeTimeout:   10 * time.Second,
+				ExpectContinueTimeout: time.Second,
+			},
+		}
+	}
+
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: httpClient,
+		headers:    o.headers,
+		retryOpts: []utils.RetryOption{
+			utils.WithRetryAttempts(o.retryAttempts),
+			utils.WithRetryInterval(o.retryInterval, o.retryMaxInterval),
+			utils.WithRetryIf(isRetryable),
+		},
+		requestIDHeader: o.requestIDHeader,
+		requestIDFn:     o.requestIDFn,
+	}
+}
+
+// Get send a GET request, the data of the response envelope is decoded into T
+func Get[T any](ctx context.Context, c *Client, path string, query url.Values) (T, error) {
+	return Do[T](ctx, c, http.MethodGet, path, query, nil)
+}
+
+// Delete send a DELETE request, the data of the response envelope is decoded into T
+func Delete[T any](ctx context.Context, c *Client, path string, query url.Values) (T, error) {
+	return Do[T](ctx, c, http.MethodDelete, path, query, nil)
+}
+
+// Post send a POST request with json body, the data of the response envelope is decoded into T
+func Post[T any](ctx context.Context, c *Client, path string, body interface{}) (T, error) {
+	return Do[T](ctx, c, http.MethodPost, path, nil, body)
+}
+
+// Put send a PUT request with json body, the data of the response envelope is decoded into T
+func Put[T any](ctx context.Context, c *Client, path string, body interface{}) (T, error) {
+	return Do[T](ctx, c, http.MethodPut, path, nil, body)
+}
+
+// Patch send a PATCH request with json body, the data of the response envelope is decoded into T
+func Patch[T any](ctx context.Context, c *Client, path string, body interface{}) (T, error) {
+	return Do[T](ctx, c, http.MethodPatch, path, nil, body)
+}
+
+// Do send a request, idempotent requests (GET, HEAD, OPTIONS, PUT, DELETE) are retried on network errors
+// and 502, 503, 504 status codes, the data of the response envelope is decoded into T, a non-zero code of
+// the envelope or a non 2xx status code returns *APIError.
+func Do[T any](ctx context.Context, c *Client, method string, path string, query url.Values, body interface{}) (T, error) {
+	var result T
+
+	var bodyBytes []byte
+	if body != nil {
+		var err error
+		if bodyBytes, err = json.Marshal(body); err != nil {
+			return result, fmt.Errorf("json marshal body error: %v", err)
+		}
+	}
+
+	urlStr := c.baseURL + "/" + strings.TrimLeft(path, "/")
+	if len(query) > 0 {
+		urlStr += "?" + query.Encode()
+	}
+
+	var respBody []byte
+	call := func(ctx context.Context) error {
+		var err error
+		respBody, err = c.send(ctx, method, urlStr, bodyBytes)
+		return err
+	}
+
+	var err error
+	if isIdempotent(method) {
+		err = utils.Retry(ctx, call, c.retryOpts...)
+	} else {
+		err = call(ctx)
+	}
+	if err != nil {
+		return result, err
+	}
+
+	err = decodeEnvelope(respBody, &result)
+	return result, err
+}
+
+func (c *Client) send(ctx context.Context, method string, urlStr string, body []byte) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, urlStr, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+	if c.requestIDFn != nil && c.requestIDHeader != "" {
+		if id := c.requestIDFn(ctx); id != "" {
+			req.Header.Set(c.requestIDHeader, id)
+		}
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() //nolint
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, newStatusError(resp.StatusCode, respBody)
+	}
+	return respBody, nil
+}
+
+// newStatusError parse the error of the envelope or problem details from the response body
+func newStatusError(status int, body []byte) *APIError {
+	e := &APIError{Status: status, Msg: http.StatusText(status)}
+
+	p := &problem{}
+	if json.Unmarshal(body, p) == nil && p.Title != "" {
+		e.Code = p.Code
+		e.Msg = p.Title
+		if p.Detail != "" {
+			e.Msg += ": " + p.Detail
+		}
+		return e
+	}
+
+	env := &envelope{}
+	if json.Unmarshal(body, env) == nil && env.Code != nil {
+		e.Code = *env.Code
+		if env.Msg != "" {
+			e.Msg = env.Msg
+		}
+	}
+	return e
+}
+
+func decodeEnvelope(body []byte, result interface{}) error {
+	env := &envelope{}
+	if err := json.Unmarshal(body, env); err != nil {
+		return fmt.Errorf("json unmarshal response error: %v", err)
+	}
+	if env.Code == nil {
+		return fmt.Errorf("response is not the standard envelope: %s", truncate(body))
+	}
+	if *env.Code != 0 {
+		return &APIError{Status: http.StatusOK, Code: *env.Code, Msg: env.Msg}
+	}
+
+	if len(env.Data) == 0 || string(env.Data) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(env.Data, result); err != nil {
+		return fmt.Errorf("json unmarshal data error: %v", err)
+	}
+	return nil
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.Status {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	return true // network error
+}
+
+func truncate(b []byte) string {
+	if len(b) > 200 {
+		return string(b[:200]) + " ......"
+	}
+	return string(b)
+}
@@ -0,0 +1,171 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type user struct {
+	ID   uint64 `json:"id"`
+	Name string `json:"name"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestGet(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/user/1", r.URL.Path)
+		assert.Equal(t, "name", r.URL.Query().Get("fields"))
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"code": 0, "msg": "ok", "data": map[string]interface{}{"id": 1, "name": "foo"},
+		})
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL + "/")
+	u, err := Get[*user](context.Background(), c, "/api/v1/user/1", url.Values{"fields": {"name"}})
+	assert.NoError(t, err)
+	assert.Equal(t, &user{ID: 1, Name: "foo"}, u)
+}
+
+func TestPost(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Contains(t, []string{http.MethodPost, http.MethodPut}, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		u := &user{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(u))
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"code": 0, "msg": "ok", "data": map[string]interface{}{"id": 10},
+		})
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, WithHeaders(map[string]string{"Authorization": "Bearer token"}))
+	reply, err := Post[map[string]uint64](context.Background(), c, "api/v1/user", &user{Name: "foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), reply["id"])
+
+	// no data
+	_, err = Put[struct{}](context.Background(), c, "api/v1/user/10", &user{Name: "bar"})
+	assert.NoError(t, err)
+}
+
+func TestEnvelopeError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/code":
+			writeJSON(w, http.StatusOK, map[string]interface{}{"code": 20001, "msg": "user not found", "data": struct{}{}})
+		case "/status":
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"code": 100404, "msg": "not found", "data": struct{}{}})
+		case "/problem":
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{"type":"/problems/20003","title":"conflict","status":409,"detail":"name exists","code":20003}`))
+		default:
+			_, _ = w.Write([]byte(`{"id":1}`))
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL)
+	ctx := context.Background()
+
+	_, err := Get[*user](ctx, c, "/code", nil)
+	apiErr := &APIError{}
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, &APIError{Status: http.StatusOK, Code: 20001, Msg: "user not found"}, apiErr)
+
+	_, err = Get[*user](ctx, c, "/status", nil)
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, &APIError{Status: http.StatusNotFound, Code: 100404, Msg: "not found"}, apiErr)
+
+	_, err = Delete[*user](ctx, c, "/problem", nil)
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, &APIError{Status: http.StatusConflict, Code: 20003, Msg: "conflict: name exists"}, apiErr)
+
+	_, err = Get[*user](ctx, c, "/raw", nil)
+	assert.Error(t, err)
+	assert.False(t, errors.As(err, &apiErr))
+}
+
+func TestRetry(t *testing.T) {
+	var count int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"code": 0, "msg": "ok", "data": map[string]interface{}{"id": 1}})
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, WithRetry(3, time.Millisecond, 5*time.Millisecond))
+	u, err := Get[*user](context.Background(), c, "/api/v1/user/1", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), u.ID)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&count))
+
+	// non-idempotent requests are not retried
+	atomic.StoreInt32(&count, 0)
+	_, err = Post[*user](context.Background(), c, "/api/v1/user", &user{})
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+
+	// attempts exhausted
+	atomic.StoreInt32(&count, -10)
+	_, err = Get[*user](context.Background(), c, "/api/v1/user/1", nil)
+	apiErr := &APIError{}
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusBadGateway, apiErr.Status)
+	assert.Equal(t, int32(-7), atomic.LoadInt32(&count))
+}
+
+func TestHeaderPropagation(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"code": 0, "msg": "ok",
+			"data": map[string]string{"requestID": r.Header.Get("X-Request-Id"), "traceparent": r.Header.Get("traceparent")},
+		})
+	}))
+	defer srv.Close()
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID, SpanID: spanID, TraceFlags: trace.FlagsSampled,
+	}))
+	ctx = context.WithValue(ctx, "request_id", "req-123") //nolint
+
+	c := NewClient(srv.URL)
+	reply, err := Get[map[string]string](ctx, c, "/", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "req-123", reply["requestID"])
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", reply["traceparent"])
+
+	// custom request id
+	c = NewClient(srv.URL, WithRequestID("", func(ctx context.Context) string { return "custom-id" }))
+	reply, err = Get[map[string]string](context.Background(), c, "/", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "custom-id", reply["requestID"])
+	assert.Empty(t, reply["traceparent"])
+}
@@ -0,0 +1,487 @@
+// Package bindquery is the parser of the query parameters shared by the query packages of the databases, the
+// parameters are bound from the json body, the bracketed form or query, or the compact filter DSL, the query
+// packages convert the parsed parameters into their own types by thin adapters.
+package bindquery
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// OR the logic of the conditions separated by | in the filter DSL
+const OR = "or"
+
+var (
+	// columns[0][name], columns[0][value][], columns[0][value][1]
+	bracketKeyRegexp = regexp.MustCompile(`^columns\[(\d+)\]\[(\w+)\](\[\d*\])?$`)
+	// sorts[0][name], sorts[0][desc]
+	sortKeyRegexp = regexp.MustCompile(`^sorts\[(\d+)\]\[(\w+)\]$`)
+)
+
+// FieldError a binding error of one parameter
+type FieldError struct {
+	field string
+	value string
+	tag   string // reason of the error, e.g. "number", "unknown", "syntax"
+}
+
+// Field returns the name of the parameter, e.g. columns[0][exp]
+func (e *FieldError) Field() string {
+	return e.field
+}
+
+// Value returns the original value of the parameter
+func (e *FieldError) Value() string {
+	return e.value
+}
+
+// Tag returns the reason of the error
+func (e *FieldError) Tag() string {
+	return e.tag
+}
+
+// Error returns the error message
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("field '%s' is invalid, value '%s' failed on the '%s' rule", e.field, e.value, e.tag)
+}
+
+// BindError binding errors of the query parameters, it is a bad request error.
+type BindError []*FieldError
+
+// Error returns all error messages
+func (e BindError) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fe := range e {
+		msgs = append(msgs, fe.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// BadRequest the error is caused by the client
+func (e BindError) BadRequest() bool {
+	return true
+}
+
+// Unwrap returns the field errors
+func (e BindError) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, fe := range e {
+		errs = append(errs, fe)
+	}
+	return errs
+}
+
+// Column a condition bound from the bracketed form or query, or the filter DSL
+type Column struct {
+	Name  string
+	Exp   string
+	Value interface{} // nil if the value is omitted, otherwise a string
+	Logic string
+	Type  string      // columns[0][type], bound if it is enabled by Binder.ColumnKeys
+	Group interface{} // columns[0][group], bound if it is enabled by Binder.ColumnKeys
+}
+
+// SortField a structured sort bound from the bracketed form or query
+type SortField struct {
+	Name string
+	Desc bool
+}
+
+// Values the parameters bound from the form or query values
+type Values struct {
+	Page    int
+	Limit   int
+	Size    int
+	Sort    string
+	Select  string // bound if it is enabled by Binder.Keys
+	Sorts   []SortField
+	Columns []Column
+}
+
+// Target the params of a query package, it is implemented by the adapter of the query package
+type Target interface {
+	// Params returns the pointer of the params, the json body is decoded into it and it is validated
+	Params() interface{}
+	// Len returns the numbers of the columns and the sorts of the params
+	Len() (columns int, sorts int)
+	// Paging returns the pointers of the limit and the deprecated size of the params
+	Paging() (limit *int, size *int)
+	// SetValues set the params by the values bound from the form or query values
+	SetValues(v *Values)
+}
+
+// Binder bind the query parameters of the request, the common keys are page, limit, size, sort, sorts, filter
+// and columns, the others are enabled by Keys and ColumnKeys.
+type Binder struct {
+	// the extra keys of the parameters, e.g. select
+	Keys map[string]bool
+	// the extra keys of the bracketed columns besides name, exp, value and logic, e.g. type and group
+	ColumnKeys map[string]bool
+	// the maximum number of the columns and the sorts
+	MaxColumns int
+}
+
+// Bind the query parameters of the request into the target, three input styles are supported:
+//
+//	json body: {"page":0,"limit":10,"sort":"-id","columns":[{"name":"age","exp":">","value":18}]}
+//	bracketed form or query: page=0&limit=10&sort=-id&columns[0][name]=age&columns[0][exp]=gt&columns[0][value]=18
+//	compact filter DSL: page=0&limit=10&sort=-id&filter=age:gt:18
+//
+// a BindError is returned if the parameters cannot be parsed.
+func (b *Binder) Bind(c *gin.Context, t Target) error {
+	isJSON := strings.HasPrefix(c.ContentType(), binding.MIMEJSON)
+	if isJSON && c.Request.Body != nil && c.Request.ContentLength != 0 {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return err
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body)) // can be read again
+		if len(bytes.TrimSpace(body)) > 0 {
+			if err = json.Unmarshal(body, t.Params()); err != nil {
+				return BindError{{field: "body", value: Truncate(string(body)), tag: "json"}}
+			}
+			columns, sorts := t.Len()
+			if columns > b.MaxColumns {
+				return BindError{{field: "columns", value: strconv.Itoa(columns), tag: "max"}}
+			}
+			if sorts > b.MaxColumns {
+				return BindError{{field: "sorts", value: strconv.Itoa(sorts), tag: "max"}}
+			}
+			return nil
+		}
+	}
+
+	values := c.Request.URL.Query()
+	if c.Request.Method != http.MethodGet && !isJSON {
+		var err error
+		if strings.HasPrefix(c.ContentType(), binding.MIMEMultipartPOSTForm) {
+			err = c.Request.ParseMultipartForm(32 << 20)
+		} else {
+			err = c.Request.ParseForm()
+		}
+		if err != nil {
+			return BindError{{field: "body", value: "", tag: "form"}}
+		}
+		values = c.Request.Form
+	}
+
+	v, err := b.bindValues(values)
+	if err != nil {
+		return err
+	}
+	t.SetValues(v)
+	return nil
+}
+
+// Normalize the deprecated size into limit, the limit is defaultLimit if it is not set, then the params are
+// validated by binding.Validator, e.g. a validator.ValidationErrors is returned if the params are invalid.
+func (b *Binder) Normalize(t Target, defaultLimit int) error {
+	limit, size := t.Paging()
+	if *limit == 0 && *size > 0 {
+		*limit = *size
+	}
+	*size = 0
+	if *limit == 0 {
+		*limit = defaultLimit
+	}
+
+	if binding.Validator == nil {
+		return nil
+	}
+	return binding.Validator.ValidateStruct(t.Params())
+}
+
+func (b *Binder) bindValues(values map[string][]string) (*Values, error) {
+	p := &Values{}
+	var errs BindError
+	hasBracket := false
+
+	columns := map[int]*bracketColumn{}
+	sorts := map[int]*SortField{}
+	for key, vals := range values {
+		if len(vals) == 0 {
+			continue
+		}
+		val := vals[0]
+		switch key {
+		case "page", "limit", "size":
+			n, err := strconv.Atoi(val)
+			if err != nil {
+				errs = append(errs, &FieldError{field: key, value: val, tag: "number"})
+				continue
+			}
+			switch key {
+			case "page":
+				p.Page = n
+			case "limit":
+				p.Limit = n
+			default:
+				p.Size = n
+			}
+		case "sort":
+			p.Sort = val
+		case "filter":
+		default:
+			if key == "select" && b.Keys["select"] {
+				p.Select = val
+				continue
+			}
+			if strings.HasPrefix(key, "sorts[") {
+				if fe := b.bindSortKey(sorts, key, val); fe != nil {
+					errs = append(errs, fe)
+				}
+				continue
+			}
+			if !strings.HasPrefix(key, "columns[") {
+				continue // ignore other parameters
+			}
+			hasBracket = true
+			if fe := b.bindBracketKey(columns, key, vals); fe != nil {
+				errs = append(errs, fe)
+			}
+		}
+	}
+
+	if filter, ok := values["filter"]; ok && len(filter) > 0 {
+		if hasBracket {
+			errs = append(errs, &FieldError{field: "filter", value: filter[0], tag: "excluded_with=columns"})
+		} else {
+			cols, fe := b.parseFilter(filter[0])
+			if fe != nil {
+				errs = append(errs, fe)
+			}
+			p.Columns = cols
+		}
+	}
+
+	if len(errs) > 0 {
+		sort.Slice(errs, func(i, j int) bool { return errs[i].field < errs[j].field })
+		return nil, errs
+	}
+
+	if len(columns) > 0 {
+		indexes := make([]int, 0, len(columns))
+		for i := range columns {
+			indexes = append(indexes, i)
+		}
+		sort.Ints(indexes)
+		p.Columns = make([]Column, 0, len(indexes))
+		for _, i := range indexes {
+			p.Columns = append(p.Columns, columns[i].toColumn())
+		}
+	}
+	if len(sorts) > 0 {
+		indexes := make([]int, 0, len(sorts))
+		for i := range sorts {
+			indexes = append(indexes, i)
+		}
+		sort.Ints(indexes)
+		p.Sorts = make([]SortField, 0, len(indexes))
+		for _, i := range indexes {
+			p.Sorts = append(p.Sorts, *sorts[i])
+		}
+	}
+
+	return p, nil
+}
+
+type arrayValue struct {
+	index int
+	value string
+}
+
+type bracketColumn struct {
+	Column
+	values []arrayValue
+}
+
+// values[]=a&values[]=b, values[0]=a&values[1]=b are joined with comma, used by in and notin
+func (c *bracketColumn) toColumn() Column {
+	if len(c.values) > 0 {
+		sort.SliceStable(c.values, func(i, j int) bool { return c.values[i].index < c.values[j].index })
+		ss := make([]string, 0, len(c.values))
+		for _, v := range c.values {
+			ss = append(ss, v.value)
+		}
+		c.Value = strings.Join(ss, ",")
+	}
+	return c.Column
+}
+
+func (b *Binder) bindBracketKey(columns map[int]*bracketColumn, key string, vals []string) *FieldError {
+	matches := bracketKeyRegexp.FindStringSubmatch(key)
+	if matches == nil {
+		return &FieldError{field: key, value: vals[0], tag: "syntax"}
+	}
+	index, err := strconv.Atoi(matches[1])
+	if err != nil || index >= b.MaxColumns {
+		return &FieldError{field: key, value: matches[1], tag: "max"}
+	}
+	if _, ok := columns[index]; !ok {
+		if len(columns) >= b.MaxColumns {
+			return &FieldError{field: key, value: matches[1], tag: "max"}
+		}
+		columns[index] = &bracketColumn{}
+	}
+
+	column := columns[index]
+	isArray := matches[3] != ""
+	switch matches[2] {
+	case "name":
+		column.Name = vals[0]
+	case "exp":
+		column.Exp = vals[0]
+	case "logic":
+		column.Logic = vals[0]
+	case "type", "group":
+		if !b.ColumnKeys[matches[2]] {
+			return &FieldError{field: key, value: vals[0], tag: "unknown"}
+		}
+		if matches[2] == "type" {
+			column.Type = vals[0]
+		} else {
+			column.Group = vals[0]
+		}
+	case "value":
+		if !isArray {
+			column.Value = vals[0]
+			return nil
+		}
+		i := -1 // values[] keeps the order of the request
+		if n, err := strconv.Atoi(strings.Trim(matches[3], "[]")); err == nil {
+			i = n
+		}
+		for _, v := range vals {
+			column.values = append(column.values, arrayValue{index: i, value: v})
+		}
+		return nil
+	default:
+		return &FieldError{field: key, value: vals[0], tag: "unknown"}
+	}
+
+	if isArray {
+		return &FieldError{field: key, value: vals[0], tag: "syntax"}
+	}
+	return nil
+}
+
+func (b *Binder) bindSortKey(sorts map[int]*SortField, key string, val string) *FieldError {
+	matches := sortKeyRegexp.FindStringSubmatch(key)
+	if matches == nil {
+		return &FieldError{field: key, value: val, tag: "syntax"}
+	}
+	index, err := strconv.Atoi(matches[1])
+	if err != nil || index >= b.MaxColumns {
+		return &FieldError{field: key, value: matches[1], tag: "max"}
+	}
+	if _, ok := sorts[index]; !ok {
+		sorts[index] = &SortField{}
+	}
+
+	switch matches[2] {
+	case "name":
+		sorts[index].Name = val
+	case "desc":
+		desc, err := strconv.ParseBool(val)
+		if err != nil {
+			return &FieldError{field: key, value: val, tag: "boolean"}
+		}
+		sorts[index].Desc = desc
+	default:
+		return &FieldError{field: key, value: val, tag: "unknown"}
+	}
+	return nil
+}
+
+// ParseFilter parse the compact filter DSL into columns, see the ParseFilter of the query packages
+func (b *Binder) ParseFilter(filter string) ([]Column, error) {
+	columns, fe := b.parseFilter(filter)
+	if fe != nil {
+		return nil, fe
+	}
+	return columns, nil
+}
+
+func (b *Binder) parseFilter(filter string) ([]Column, *FieldError) {
+	var (
+		columns []Column
+		cond    strings.Builder
+		escaped bool
+	)
+
+	addColumn := func(logic string) *FieldError {
+		s := strings.TrimSpace(cond.String())
+		cond.Reset()
+		if s == "" {
+			return &FieldError{field: "filter", value: filter, tag: "syntax"}
+		}
+		if len(columns) >= b.MaxColumns {
+			return &FieldError{field: "filter", value: Truncate(filter), tag: "max"}
+		}
+
+		parts := strings.SplitN(s, ":", 3)
+		column := Column{Name: parts[0], Logic: logic}
+		if len(parts) > 1 {
+			column.Exp = parts[1]
+		}
+		if len(parts) > 2 {
+			column.Value = parts[2]
+		}
+		if column.Name == "" || len(parts) == 1 {
+			return &FieldError{field: "filter", value: s, tag: "syntax"}
+		}
+		columns = append(columns, column)
+		return nil
+	}
+
+	for _, r := range filter {
+		if escaped {
+			cond.WriteRune(r)
+			escaped = false
+			continue
+		}
+		switch r {
+		case '\\':
+			escaped = true
+		case ';':
+			if err := addColumn(""); err != nil {
+				return nil, err
+			}
+		case '|':
+			if err := addColumn(OR); err != nil {
+				return nil, err
+			}
+		default:
+			cond.WriteRune(r)
+		}
+	}
+	if escaped || strings.TrimSpace(cond.String()) == "" {
+		if filter == "" {
+			return nil, nil
+		}
+		return nil, &FieldError{field: "filter", value: filter, tag: "syntax"}
+	}
+	if err := addColumn(""); err != nil {
+		return nil, err
+	}
+
+	return columns, nil
+}
+
+// Truncate the long value in the error message
+func Truncate(s string) string {
+	if len(s) > 64 {
+		return s[:64] + "..."
+	}
+	return s
+}
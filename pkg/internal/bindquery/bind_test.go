@@ -0,0 +1,65 @@
+package bindquery
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testParams struct {
+	Limit   int      `json:"limit"`
+	Size    int      `json:"size"`
+	Select  string   `json:"select"`
+	Columns []Column `json:"columns"`
+}
+
+type testTarget struct {
+	p *testParams
+}
+
+func (t *testTarget) Params() interface{}  { return t.p }
+func (t *testTarget) Len() (int, int)      { return len(t.p.Columns), 0 }
+func (t *testTarget) Paging() (*int, *int) { return &t.p.Limit, &t.p.Size }
+func (t *testTarget) SetValues(v *Values) {
+	t.p.Limit, t.p.Size, t.p.Select, t.p.Columns = v.Limit, v.Size, v.Select, v.Columns
+}
+
+func newTestContext(target string) *gin.Context {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", target, nil)
+	return c
+}
+
+func TestBinder_Keys(t *testing.T) {
+	target := "/?size=5&select=name&columns[0][name]=age&columns[0][type]=int&columns[0][group]=g1"
+
+	// the extra keys are bound if they are enabled
+	b := &Binder{Keys: map[string]bool{"select": true}, ColumnKeys: map[string]bool{"type": true, "group": true}, MaxColumns: 10}
+	tt := &testTarget{p: &testParams{}}
+	require.NoError(t, b.Bind(newTestContext(target), tt))
+	require.NoError(t, b.Normalize(tt, 10))
+	assert.Equal(t, &testParams{Limit: 5, Select: "name", Columns: []Column{{Name: "age", Type: "int", Group: "g1"}}}, tt.p)
+
+	// the select is ignored, the unknown keys of the columns are rejected
+	b = &Binder{MaxColumns: 10}
+	err := b.Bind(newTestContext(target), &testTarget{p: &testParams{}})
+	var bindErr BindError
+	require.True(t, errors.As(err, &bindErr))
+	require.Len(t, bindErr, 2)
+	assert.Equal(t, "columns[0][group]", bindErr[0].Field())
+	assert.Equal(t, "unknown", bindErr[0].Tag())
+	assert.Equal(t, "columns[0][type]", bindErr[1].Field())
+
+	columns, err := b.ParseFilter(`age:gt:18|name::a\;b;deleted_at:isnull`)
+	require.NoError(t, err)
+	assert.Equal(t, []Column{
+		{Name: "age", Exp: "gt", Value: "18", Logic: OR},
+		{Name: "name", Exp: "", Value: "a;b"},
+		{Name: "deleted_at", Exp: "isnull"},
+	}, columns)
+}
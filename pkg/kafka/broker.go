@@ -0,0 +1,200 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/IBM/sarama"
+	"go.uber.org/zap"
+
+	"github.com/go-dev-frame/sponge/pkg/mq"
+)
+
+// Broker adapt the consumer group to mq.Broker, the messages are consumed at least once by mq.Consumer with
+// the bounded concurrency, retry and dead-lettering. The offsets of a partition are marked in order, a finished
+// message is not committed until all the messages before it are finished. The requeued messages are sent to
+// the same topic, and the dead letters are sent to the dead letter topic by the producer.
+type Broker struct {
+	group           *ConsumerGroup
+	topics          []string
+	producer        *SyncProducer
+	deadLetterTopic string
+}
+
+// NewBroker create a broker of the consumer group, the default dead letter topic is <first topic>.dlq
+func NewBroker(group *ConsumerGroup, topics []string, producer *SyncProducer, deadLetterTopic string) *Broker {
+	if deadLetterTopic == "" && len(topics) > 0 {
+		deadLetterTopic = topics[0] + ".dlq"
+	}
+	return &Broker{
+		group:           group,
+		topics:          topics,
+		producer:        producer,
+		deadLetterTopic: deadLetterTopic,
+	}
+}
+
+// Receive join the consumer group, the channel is closed when the session ends, e.g. rebalance, or ctx is done.
+func (b *Broker) Receive(ctx context.Context) (<-chan mq.Delivery, error) {
+	out := make(chan mq.Delivery)
+	handler := &brokerHandler{
+		out:              out,
+		zapLogger:        b.group.zapLogger,
+		autoCommitEnable: b.group.autoCommitEnable,
+	}
+	go func() {
+		defer close(out)
+		if err := b.group.Group.Consume(ctx, b.topics, handler); err != nil && ctx.Err() == nil {
+			b.group.zapLogger.Warn("[kafka broker] consume error", zap.String("group_id", b.group.groupID),
+				zap.Strings("topics", b.topics), zap.Error(err))
+		}
+	}()
+	return out, nil
+}
+
+// Requeue send the message to the topic again
+func (b *Broker) Requeue(_ context.Context, msg *mq.Message) error {
+	return b.send(msg.Topic, msg)
+}
+
+// DeadLetter send the message to the dead letter topic
+func (b *Broker) DeadLetter(_ context.Context, msg *mq.Message) error {
+	return b.send(b.deadLetterTopic, msg)
+}
+
+func (b *Broker) send(topic string, msg *mq.Message) error {
+	pm := &sarama.ProducerMessage{
+		Topic: topic,
+		Value: sarama.ByteEncoder(msg.Body),
+	}
+	if msg.Key != "" {
+		pm.Key = sarama.StringEncoder(msg.Key)
+	}
+	for k, v := range msg.Headers {
+		pm.Headers = append(pm.Headers, sarama.RecordHeader{Key: []byte(k), Value: []byte(v)})
+	}
+	_, _, err := b.producer.SendMessage(pm)
+	return err
+}
+
+type brokerHandler struct {
+	out              chan<- mq.Delivery
+	zapLogger        *zap.Logger
+	autoCommitEnable bool
+}
+
+func (h *brokerHandler) Setup(sess sarama.ConsumerGroupSession) error {
+	h.zapLogger.Info("[kafka broker] consumer group session [setup]", zap.Any("claims", sess.Claims()))
+	return nil
+}
+
+func (h *brokerHandler) Cleanup(sess sarama.ConsumerGroupSession) error {
+	h.zapLogger.Info("[kafka broker] consumer group session [cleanup]", zap.Any("claims", sess.Claims()))
+	return nil
+}
+
+func (h *brokerHandler) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	tracker := &offsetTracker{sess: sess, topic: claim.Topic(), partition: claim.Partition(),
+		done: map[int64]bool{}, autoCommitEnable: h.autoCommitEnable}
+	for {
+		select {
+		case <-sess.Context().Done():
+			return nil
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+			tracker.add(msg.Offset)
+			d := newDelivery(msg, tracker, claim.HighWaterMarkOffset()-msg.Offset-1)
+			select {
+			case h.out <- d:
+			case <-sess.Context().Done():
+				return nil // not marked, the message is consumed again by the next session
+			}
+		}
+	}
+}
+
+// offsetTracker mark the offsets of a partition in order
+type offsetTracker struct {
+	sess             sarama.ConsumerGroupSession
+	topic            string
+	partition        int32
+	autoCommitEnable bool
+
+	mu      sync.Mutex
+	pending []int64 // the offsets received and not marked, in order
+	done    map[int64]bool
+}
+
+func (t *offsetTracker) add(offset int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending = append(t.pending, offset)
+}
+
+// finish the offset, and mark the continuous finished offsets from the oldest
+func (t *offsetTracker) finish(offset int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.done[offset] = true
+	marked := false
+	for len(t.pending) > 0 && t.done[t.pending[0]] {
+		t.sess.MarkOffset(t.topic, t.partition, t.pending[0]+1, "")
+		delete(t.done, t.pending[0])
+		t.pending = t.pending[1:]
+		marked = true
+	}
+	if marked && !t.autoCommitEnable {
+		t.sess.Commit()
+	}
+}
+
+type delivery struct {
+	msg     *mq.Message
+	offset  int64
+	lag     int64
+	tracker *offsetTracker
+}
+
+func newDelivery(msg *sarama.ConsumerMessage, tracker *offsetTracker, lag int64) *delivery {
+	headers := make(map[string]string, len(msg.Headers))
+	for _, h := range msg.Headers {
+		if h != nil {
+			headers[string(h.Key)] = string(h.Value)
+		}
+	}
+	return &delivery{
+		msg: &mq.Message{
+			ID:        fmt.Sprintf("%s/%d/%d", msg.Topic, msg.Partition, msg.Offset),
+			Topic:     msg.Topic,
+			Key:       string(msg.Key),
+			Body:      msg.Value,
+			Headers:   headers,
+			Timestamp: msg.Timestamp,
+		},
+		offset:  msg.Offset,
+		lag:     lag,
+		tracker: tracker,
+	}
+}
+
+func (d *delivery) Message() *mq.Message {
+	return d.msg
+}
+
+func (d *delivery) Lag() int64 {
+	return d.lag
+}
+
+func (d *delivery) Ack() error {
+	d.tracker.finish(d.offset)
+	return nil
+}
+
+// Nack the offset is not marked, the message and the messages after it in the partition are consumed again
+// by the next session, kafka can not redeliver a single message.
+func (d *delivery) Nack(_ bool) error {
+	return nil
+}
@@ -0,0 +1,64 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-dev-frame/sponge/pkg/mq"
+)
+
+var (
+	_ mq.Broker      = (*Broker)(nil)
+	_ mq.LagDelivery = (*delivery)(nil)
+)
+
+type fakeSession struct {
+	marks   []int64
+	commits int
+}
+
+func (s *fakeSession) Claims() map[string][]int32 { return nil }
+func (s *fakeSession) MemberID() string           { return "" }
+func (s *fakeSession) GenerationID() int32        { return 0 }
+func (s *fakeSession) MarkOffset(topic string, partition int32, offset int64, metadata string) {
+	s.marks = append(s.marks, offset)
+}
+func (s *fakeSession) Commit()                                                                  {}
+func (s *fakeSession) ResetOffset(topic string, partition int32, offset int64, metadata string) {}
+func (s *fakeSession) MarkMessage(msg *sarama.ConsumerMessage, metadata string)                 {}
+func (s *fakeSession) Context() context.Context                                                 { return context.Background() }
+
+func TestOffsetTracker(t *testing.T) {
+	sess := &fakeSession{}
+	tracker := &offsetTracker{sess: sess, topic: "orders", done: map[int64]bool{}, autoCommitEnable: true}
+	for offset := int64(10); offset < 14; offset++ {
+		tracker.add(offset)
+	}
+
+	// the offsets are marked in order
+	tracker.finish(12)
+	assert.Empty(t, sess.marks)
+	tracker.finish(10)
+	assert.Equal(t, []int64{11}, sess.marks)
+	tracker.finish(11)
+	assert.Equal(t, []int64{11, 12, 13}, sess.marks)
+	tracker.finish(13)
+	assert.Equal(t, []int64{11, 12, 13, 14}, sess.marks)
+
+	d := newDelivery(&sarama.ConsumerMessage{
+		Topic:     "orders",
+		Partition: 1,
+		Offset:    5,
+		Key:       []byte("key"),
+		Value:     []byte("body"),
+		Headers:   []*sarama.RecordHeader{{Key: []byte(mq.HeaderAttempt), Value: []byte("2")}},
+	}, tracker, 3)
+	assert.Equal(t, "orders/1/5", d.Message().ID)
+	assert.Equal(t, "key", d.Message().Key)
+	assert.Equal(t, 2, d.Message().Attempt())
+	assert.Equal(t, int64(3), d.Lag())
+	assert.NoError(t, d.Nack(true))
+}
@@ -0,0 +1,118 @@
+package krand
+
+import (
+	crand "crypto/rand"
+	"errors"
+	"hash/crc32"
+	"strings"
+)
+
+// Charset the characters used to generate a secure random string
+type Charset string
+
+// predefined charsets
+const (
+	// CharsetAlnum digits and lowercase letters, case-insensitive
+	CharsetAlnum Charset = "0123456789abcdefghijklmnopqrstuvwxyz"
+	// CharsetNoAmbiguous digits and uppercase letters without the easily confused 0, O, 1, I and L
+	CharsetNoAmbiguous Charset = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+	// CharsetHex lowercase hexadecimal
+	CharsetHex Charset = "0123456789abcdef"
+	// CharsetBase62 digits, uppercase and lowercase letters
+	CharsetBase62 Charset = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+	charsetDigits Charset = "0123456789"
+)
+
+// checksumLen the length of the base62 encoded crc32, 62^6 > 2^32
+const checksumLen = 6
+
+// ErrInvalidCharset the charset is empty or longer than 256 characters
+var ErrInvalidCharset = errors.New("krand: charset must contain 1 to 256 characters")
+
+// SecureString generate a random string of n characters from the charset by crypto/rand,
+// every character is chosen uniformly by rejection sampling, there is no modulo bias.
+func SecureString(n int, charset Charset) (string, error) {
+	size := len(charset)
+	if size == 0 || size > 256 {
+		return "", ErrInvalidCharset
+	}
+	if n <= 0 {
+		return "", nil
+	}
+
+	// the random bytes not less than limit are rejected, so that every character has the same probability
+	limit := 256 - 256%size
+	result := make([]byte, 0, n)
+	buf := make([]byte, n+n/4+8)
+	for len(result) < n {
+		if _, err := crand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if int(b) >= limit {
+				continue
+			}
+			result = append(result, charset[int(b)%size])
+			if len(result) == n {
+				break
+			}
+		}
+	}
+	return string(result), nil
+}
+
+// NumericCode generate a numeric code with the specified digits for OTP, e.g. "038142",
+// the leading zeros are kept, every code in [0, 10^digits) has the same probability.
+func NumericCode(digits int) (string, error) {
+	if digits <= 0 {
+		return "", errors.New("krand: digits must be greater than 0")
+	}
+	return SecureString(digits, charsetDigits)
+}
+
+// TokenWithChecksum generate a token in the form of prefix_random_checksum, e.g. "ghp_0aB3...xYz_1Ab9cD",
+// the random part is n base62 characters, the checksum is the base62 encoded crc32 of "prefix_random",
+// it can be checked by ValidateChecksum without querying the database.
+func TokenWithChecksum(prefix string, n int) (string, error) {
+	if n <= 0 {
+		return "", errors.New("krand: length of the random part must be greater than 0")
+	}
+	random, err := SecureString(n, CharsetBase62)
+	if err != nil {
+		return "", err
+	}
+	payload := prefix + "_" + random
+	return payload + "_" + checksum(payload), nil
+}
+
+// ValidateChecksum check that the token is in the form of prefix_random_checksum and the checksum matches,
+// it only checks the format, the token still needs to be verified by the storage.
+func ValidateChecksum(token string) bool {
+	i := strings.LastIndexByte(token, '_')
+	if i < 0 || len(token)-i-1 != checksumLen {
+		return false
+	}
+	payload := token[:i]
+	j := strings.LastIndexByte(payload, '_')
+	if j < 0 || j == len(payload)-1 {
+		return false
+	}
+	for k := j + 1; k < len(payload); k++ {
+		if strings.IndexByte(string(CharsetBase62), payload[k]) < 0 {
+			return false
+		}
+	}
+	return token[i+1:] == checksum(payload)
+}
+
+// checksum the fixed length base62 encoded crc32 of the payload
+func checksum(payload string) string {
+	v := crc32.ChecksumIEEE([]byte(payload))
+	var buf [checksumLen]byte
+	for i := checksumLen - 1; i >= 0; i-- {
+		buf[i] = CharsetBase62[v%62]
+		v /= 62
+	}
+	return string(buf[:])
+}
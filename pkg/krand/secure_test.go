@@ -0,0 +1,125 @@
+package krand
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecureString(t *testing.T) {
+	charsets := []Charset{CharsetAlnum, CharsetNoAmbiguous, CharsetHex, CharsetBase62}
+	for _, charset := range charsets {
+		s, err := SecureString(32, charset)
+		require.NoError(t, err)
+		assert.Len(t, s, 32)
+		for _, c := range s {
+			assert.Contains(t, string(charset), string(c))
+		}
+	}
+
+	s, err := SecureString(0, CharsetHex)
+	assert.NoError(t, err)
+	assert.Empty(t, s)
+
+	_, err = SecureString(10, "")
+	assert.ErrorIs(t, err, ErrInvalidCharset)
+	_, err = SecureString(10, Charset(strings.Repeat("a", 257)))
+	assert.ErrorIs(t, err, ErrInvalidCharset)
+
+	// the ambiguous characters are excluded
+	assert.False(t, strings.ContainsAny(string(CharsetNoAmbiguous), "0O1IL"))
+}
+
+// chiSquare the chi-square statistic of the counts against the uniform distribution
+func chiSquare(counts map[rune]int, size int, total int) float64 {
+	expected := float64(total) / float64(size)
+	var x2 float64
+	observed := 0
+	for _, n := range counts {
+		d := float64(n) - expected
+		x2 += d * d / expected
+		observed++
+	}
+	// the characters never generated
+	x2 += float64(size-observed) * expected
+	return x2
+}
+
+func TestSecureString_Uniform(t *testing.T) {
+	// 256 is not a multiple of 62, the first 8 characters would be 25% more frequent with the modulo bias
+	const total = 200000
+	s, err := SecureString(total, CharsetBase62)
+	require.NoError(t, err)
+	counts := map[rune]int{}
+	for _, c := range s {
+		counts[c]++
+	}
+	// the critical value of 61 degrees of freedom at p=0.0001 is about 109
+	assert.Less(t, chiSquare(counts, len(CharsetBase62), total), 109.0)
+}
+
+func TestNumericCode(t *testing.T) {
+	code, err := NumericCode(6)
+	require.NoError(t, err)
+	assert.Len(t, code, 6)
+	assert.Empty(t, strings.Trim(code, string(charsetDigits)))
+
+	_, err = NumericCode(0)
+	assert.Error(t, err)
+
+	// every digit at every position is uniform
+	const total = 20000
+	counts := [6]map[rune]int{}
+	for i := range counts {
+		counts[i] = map[rune]int{}
+	}
+	for i := 0; i < total; i++ {
+		code, _ = NumericCode(6)
+		for j, c := range code {
+			counts[j][c]++
+		}
+	}
+	for _, c := range counts {
+		// the critical value of 9 degrees of freedom at p=0.0001 is about 33.7
+		assert.Less(t, chiSquare(c, 10, total), 33.7)
+	}
+}
+
+func TestTokenWithChecksum(t *testing.T) {
+	for _, prefix := range []string{"ghp", "sk_live", ""} {
+		token, err := TokenWithChecksum(prefix, 30)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(token, prefix+"_"))
+		assert.Len(t, token, len(prefix)+1+30+1+checksumLen)
+		assert.True(t, ValidateChecksum(token), token)
+
+		// tamper a character of the random part
+		b := []byte(token)
+		i := len(prefix) + 5
+		if b[i] == 'a' {
+			b[i] = 'b'
+		} else {
+			b[i] = 'a'
+		}
+		assert.False(t, ValidateChecksum(string(b)))
+		// tamper the prefix
+		assert.False(t, ValidateChecksum("x"+token))
+	}
+
+	_, err := TokenWithChecksum("ghp", 0)
+	assert.Error(t, err)
+
+	invalids := []string{"", "ghp", "ghp_abc", "ghp_abc_12345", "ghp__" + checksum("ghp_"), "ghp_a-b_" + checksum("ghp_a-b")}
+	for _, token := range invalids {
+		assert.False(t, ValidateChecksum(token), token)
+	}
+	assert.True(t, ValidateChecksum("ghp_abc_"+checksum("ghp_abc")))
+}
+
+func BenchmarkSecureString(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_, _ = SecureString(32, CharsetBase62)
+	}
+}
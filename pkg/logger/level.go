@@ -0,0 +1,326 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// the level of the default logger, it can be changed at runtime
+var levelCtl = newLevelController()
+
+type levelController struct {
+	level   zap.AtomicLevel
+	modules atomic.Value // map[string]zapcore.Level, logger name → level
+
+	mu            sync.Mutex
+	revertTimer   *time.Timer
+	revertAt      time.Time
+	revertLevel   zapcore.Level
+	revertModules map[string]zapcore.Level
+	revertGen     uint64 // the pending revert is ignored if the generation is changed
+}
+
+func newLevelController() *levelController {
+	c := &levelController{level: zap.NewAtomicLevelAt(zapcore.DebugLevel)}
+	c.modules.Store(map[string]zapcore.Level{})
+	return c
+}
+
+func (c *levelController) getModules() map[string]zapcore.Level {
+	modules, _ := c.modules.Load().(map[string]zapcore.Level)
+	return modules
+}
+
+// enabled reports whether the level is enabled by the global level or any module level
+func (c *levelController) enabled(lvl zapcore.Level) bool {
+	if c.level.Enabled(lvl) {
+		return true
+	}
+	for _, l := range c.getModules() {
+		if lvl >= l {
+			return true
+		}
+	}
+	return false
+}
+
+// enabledFor reports whether the level is enabled for the logger name, the module level of the
+// longest matching name is used, e.g. the module "gin" matches the logger names "gin" and "gin.access".
+func (c *levelController) enabledFor(name string, lvl zapcore.Level) bool {
+	modules := c.getModules()
+	for name != "" {
+		if l, ok := modules[name]; ok {
+			return lvl >= l
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return c.level.Enabled(lvl)
+}
+
+func (c *levelController) minLevel() zapcore.Level {
+	minLevel := c.level.Level()
+	for _, l := range c.getModules() {
+		if l < minLevel {
+			minLevel = l
+		}
+	}
+	return minLevel
+}
+
+func (c *levelController) set(lvl zapcore.Level, modules map[string]zapcore.Level) {
+	c.level.SetLevel(lvl)
+	if modules != nil {
+		c.modules.Store(modules)
+	}
+}
+
+// setTemporarily set the levels, and revert to the levels before the first temporary change after d
+func (c *levelController) setTemporarily(lvl zapcore.Level, modules map[string]zapcore.Level, d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.revertTimer == nil {
+		c.revertLevel, c.revertModules = c.level.Level(), c.getModules()
+	} else {
+		c.revertTimer.Stop()
+	}
+	c.revertGen++
+	gen := c.revertGen
+	c.revertTimer = time.AfterFunc(d, func() { c.revert(gen) })
+	c.revertAt = time.Now().Add(d)
+	c.set(lvl, modules)
+}
+
+func (c *levelController) revert(gen uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if gen != c.revertGen || c.revertTimer == nil {
+		return
+	}
+	c.set(c.revertLevel, c.revertModules)
+	c.revertTimer = nil
+	c.revertAt = time.Time{}
+}
+
+func (c *levelController) setPermanently(lvl *zapcore.Level, modules map[string]zapcore.Level) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stopRevert()
+	if lvl == nil {
+		l := c.level.Level()
+		lvl = &l
+	}
+	c.set(*lvl, modules)
+}
+
+func (c *levelController) stopRevert() {
+	if c.revertTimer != nil {
+		c.revertTimer.Stop()
+		c.revertTimer = nil
+		c.revertAt = time.Time{}
+		c.revertGen++
+	}
+}
+
+func (c *levelController) getRevertAt() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.revertAt
+}
+
+// wrap the core, the level of the core is controlled by the controller
+func (c *levelController) wrap(core zapcore.Core) zapcore.Core {
+	return &levelCore{Core: core, ctl: c}
+}
+
+// levelCore filter the entries by the global level and the module levels, the inner core must enable all levels.
+type levelCore struct {
+	zapcore.Core
+	ctl *levelController
+}
+
+func (c *levelCore) Enabled(lvl zapcore.Level) bool {
+	return c.ctl.enabled(lvl)
+}
+
+func (c *levelCore) Level() zapcore.Level {
+	return c.ctl.minLevel()
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), ctl: c.ctl}
+}
+
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.ctl.enabledFor(ent.LoggerName, ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+func parseLevel(levelName string) (zapcore.Level, error) {
+	switch strings.ToUpper(levelName) {
+	case levelDebug:
+		return zapcore.DebugLevel, nil
+	case levelInfo:
+		return zapcore.InfoLevel, nil
+	case levelWarn:
+		return zapcore.WarnLevel, nil
+	case levelError:
+		return zapcore.ErrorLevel, nil
+	}
+	return zapcore.DebugLevel, fmt.Errorf("unknown log level %q, supported levels are debug, info, warn, error", levelName)
+}
+
+func parseModuleLevels(levels map[string]string) (map[string]zapcore.Level, error) {
+	modules := make(map[string]zapcore.Level, len(levels))
+	for name, levelName := range levels {
+		l, err := parseLevel(levelName)
+		if err != nil {
+			return nil, fmt.Errorf("module %s: %w", name, err)
+		}
+		modules[name] = l
+	}
+	return modules, nil
+}
+
+// SetLevel set the log level at runtime, the pending revert of SetLevelTemporarily is canceled.
+func SetLevel(levelName string) error {
+	l, err := parseLevel(levelName)
+	if err != nil {
+		return err
+	}
+	levelCtl.setPermanently(&l, nil)
+	return nil
+}
+
+// SetLevelTemporarily set the log level at runtime, and revert to the level before after d,
+// e.g. SetLevelTemporarily("debug", 10*time.Minute) for debugging online.
+func SetLevelTemporarily(levelName string, d time.Duration) error {
+	if d <= 0 {
+		return SetLevel(levelName)
+	}
+	l, err := parseLevel(levelName)
+	if err != nil {
+		return err
+	}
+	levelCtl.setTemporarily(l, nil, d)
+	return nil
+}
+
+// GetLevel get the current log level
+func GetLevel() string {
+	return levelCtl.level.Level().String()
+}
+
+// SetModuleLevels replace the log levels of the modules at runtime, the key is the logger name,
+// e.g. {"gin": "warn", "gorm": "debug"} for the loggers created by logger.Get().Named("gin"),
+// the log level of the module overrides the global level, an empty map clears the module levels.
+func SetModuleLevels(levels map[string]string) error {
+	modules, err := parseModuleLevels(levels)
+	if err != nil {
+		return err
+	}
+	levelCtl.setPermanently(nil, modules)
+	return nil
+}
+
+// GetModuleLevels get the log levels of the modules
+func GetModuleLevels() map[string]string {
+	modules := levelCtl.getModules()
+	levels := make(map[string]string, len(modules))
+	for name, l := range modules {
+		levels[name] = l.String()
+	}
+	return levels
+}
+
+// LevelRequest the request body of LevelHandler
+type LevelRequest struct {
+	Level    string            `json:"level"`    // global level, empty means unchanged
+	Duration string            `json:"duration"` // revert after the duration, e.g. "10m", empty means permanent
+	Modules  map[string]string `json:"modules"`  // module levels, nil means unchanged, an empty map clears them
+}
+
+// LevelResponse the response body of LevelHandler
+type LevelResponse struct {
+	Level    string            `json:"level"`
+	Modules  map[string]string `json:"modules"`
+	RevertAt string            `json:"revertAt,omitempty"`
+}
+
+// LevelHandler get the log levels by GET, change the log levels by PUT with the body of LevelRequest,
+// e.g. {"level":"debug","duration":"10m"}, register it with the pprof routers which are not public.
+func LevelHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		if err := changeLevel(r); err != nil {
+			writeLevelJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		writeLevelJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	resp := &LevelResponse{Level: GetLevel(), Modules: GetModuleLevels()}
+	if revertAt := levelCtl.getRevertAt(); !revertAt.IsZero() {
+		resp.RevertAt = revertAt.Format(time.RFC3339)
+	}
+	writeLevelJSON(w, http.StatusOK, resp)
+}
+
+func changeLevel(r *http.Request) error {
+	req := &LevelRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+
+	lvl := levelCtl.level.Level()
+	if req.Level != "" {
+		var err error
+		if lvl, err = parseLevel(req.Level); err != nil {
+			return err
+		}
+	}
+	var modules map[string]zapcore.Level
+	if req.Modules != nil {
+		var err error
+		if modules, err = parseModuleLevels(req.Modules); err != nil {
+			return err
+		}
+	}
+
+	if req.Duration == "" {
+		levelCtl.setPermanently(&lvl, modules)
+		return nil
+	}
+	d, err := time.ParseDuration(req.Duration)
+	if err != nil || d <= 0 {
+		return fmt.Errorf("invalid duration %q", req.Duration)
+	}
+	levelCtl.setTemporarily(lvl, modules, d)
+	return nil
+}
+
+func writeLevelJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
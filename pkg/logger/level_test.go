@@ -0,0 +1,178 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func newObservedLogger(ctl *levelController) (*zap.Logger, *observer.ObservedLogs) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	return zap.New(ctl.wrap(core)), logs
+}
+
+func TestLevelController_Switch(t *testing.T) {
+	ctl := newLevelController()
+	log, logs := newObservedLogger(ctl)
+
+	info := zapcore.InfoLevel
+	ctl.setPermanently(&info, nil)
+	log.Debug("debug 1")
+	log.Info("info 1")
+	assert.Equal(t, 1, logs.Len())
+	assert.Equal(t, zapcore.InfoLevel, zapcore.LevelOf(log.Core()))
+
+	debug := zapcore.DebugLevel
+	ctl.setPermanently(&debug, nil)
+	log.Debug("debug 2")
+	assert.Equal(t, 2, logs.Len())
+	assert.Equal(t, "debug 2", logs.All()[1].Message)
+
+	// the logger with fields shares the level
+	child := log.With(zap.String("foo", "bar"))
+	errLevel := zapcore.ErrorLevel
+	ctl.setPermanently(&errLevel, nil)
+	child.Warn("warn 1")
+	child.Error("error 1")
+	assert.Equal(t, 3, logs.Len())
+}
+
+func TestLevelController_Revert(t *testing.T) {
+	ctl := newLevelController()
+	log, logs := newObservedLogger(ctl)
+	info := zapcore.InfoLevel
+	ctl.setPermanently(&info, nil)
+
+	ctl.setTemporarily(zapcore.DebugLevel, map[string]zapcore.Level{"gin": zapcore.ErrorLevel}, 50*time.Millisecond)
+	assert.False(t, ctl.getRevertAt().IsZero())
+	log.Debug("debug 1")
+	assert.Equal(t, 1, logs.Len())
+
+	// the second temporary change extends the revert time, the levels before the first change are restored
+	ctl.setTemporarily(zapcore.WarnLevel, nil, 100*time.Millisecond)
+	time.Sleep(70 * time.Millisecond)
+	assert.Equal(t, zapcore.WarnLevel, ctl.level.Level())
+	assert.Eventually(t, func() bool { return ctl.level.Level() == zapcore.InfoLevel }, time.Second, 10*time.Millisecond)
+	assert.Empty(t, ctl.getModules())
+	assert.True(t, ctl.getRevertAt().IsZero())
+	log.Debug("debug 2")
+	assert.Equal(t, 1, logs.Len())
+
+	// a permanent change cancels the pending revert
+	ctl.setTemporarily(zapcore.DebugLevel, nil, 30*time.Millisecond)
+	errLevel := zapcore.ErrorLevel
+	ctl.setPermanently(&errLevel, nil)
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, zapcore.ErrorLevel, ctl.level.Level())
+}
+
+func TestLevelController_Modules(t *testing.T) {
+	ctl := newLevelController()
+	log, logs := newObservedLogger(ctl)
+
+	warn := zapcore.WarnLevel
+	ctl.setPermanently(&warn, map[string]zapcore.Level{
+		"gin":  zapcore.ErrorLevel,
+		"gorm": zapcore.DebugLevel,
+	})
+	assert.Equal(t, zapcore.DebugLevel, zapcore.LevelOf(log.Core()))
+
+	log.Info("global info")
+	log.Warn("global warn")
+	log.Named("gin").Warn("gin warn")
+	log.Named("gin").Named("access").Warn("gin access warn")
+	log.Named("gin").Error("gin error")
+	log.Named("gorm").Debug("gorm debug")
+	log.Named("gorm").With(zap.Int("rows", 1)).Debug("gorm debug with fields")
+	log.Named("redis").Info("redis info")
+
+	var messages []string
+	for _, entry := range logs.All() {
+		messages = append(messages, entry.Message)
+	}
+	assert.Equal(t, []string{"global warn", "gin error", "gorm debug", "gorm debug with fields"}, messages)
+}
+
+func TestSetLevel(t *testing.T) {
+	_, err := Init(WithLevel("info"), WithModuleLevels(map[string]string{"gin": "warn", "bad": "unknown"}))
+	assert.NoError(t, err)
+	defer func() { _, _ = Init() }()
+	assert.Equal(t, "info", GetLevel())
+	assert.Equal(t, map[string]string{"gin": "warn"}, GetModuleLevels())
+	assert.False(t, Get().Core().Enabled(zapcore.DebugLevel))
+
+	assert.NoError(t, SetLevel("debug"))
+	assert.Equal(t, "debug", GetLevel())
+	assert.True(t, Get().Core().Enabled(zapcore.DebugLevel))
+	assert.Error(t, SetLevel("unknown"))
+
+	assert.NoError(t, SetLevelTemporarily("error", 30*time.Millisecond))
+	assert.Equal(t, "error", GetLevel())
+	assert.Eventually(t, func() bool { return GetLevel() == "debug" }, time.Second, 10*time.Millisecond)
+	assert.NoError(t, SetLevelTemporarily("warn", 0))
+	assert.Equal(t, "warn", GetLevel())
+	assert.Error(t, SetLevelTemporarily("unknown", time.Second))
+
+	assert.NoError(t, SetModuleLevels(map[string]string{"gorm": "debug"}))
+	assert.Equal(t, map[string]string{"gorm": "debug"}, GetModuleLevels())
+	assert.Error(t, SetModuleLevels(map[string]string{"gorm": "unknown"}))
+	assert.NoError(t, SetModuleLevels(map[string]string{}))
+	assert.Empty(t, GetModuleLevels())
+}
+
+func TestLevelHandler(t *testing.T) {
+	_, err := Init(WithLevel("info"))
+	assert.NoError(t, err)
+	defer func() { _, _ = Init() }()
+
+	do := func(method string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/debug/loglevel", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		LevelHandler(w, req)
+		return w
+	}
+
+	w := do(http.MethodGet, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"level":"info","modules":{}}`, w.Body.String())
+
+	w = do(http.MethodPut, `{"level":"debug","duration":"50ms","modules":{"gin":"warn"}}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"revertAt"`)
+	assert.Equal(t, "debug", GetLevel())
+	assert.Equal(t, map[string]string{"gin": "warn"}, GetModuleLevels())
+	assert.Eventually(t, func() bool { return GetLevel() == "info" && len(GetModuleLevels()) == 0 },
+		time.Second, 10*time.Millisecond)
+
+	// permanent, the modules are unchanged
+	w = do(http.MethodPut, `{"level":"warn"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "warn", GetLevel())
+	w = do(http.MethodPut, `{"modules":{"gorm":"debug"}}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "warn", GetLevel())
+	assert.Equal(t, map[string]string{"gorm": "debug"}, GetModuleLevels())
+
+	// errors
+	for _, body := range []string{
+		`{`,
+		`{"level":"unknown"}`,
+		`{"level":"debug","duration":"abc"}`,
+		`{"level":"debug","duration":"-1m"}`,
+		`{"modules":{"gin":"unknown"}}`,
+	} {
+		w = do(http.MethodPut, body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+	assert.Equal(t, "warn", GetLevel())
+
+	w = do(http.MethodPost, `{"level":"debug"}`)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
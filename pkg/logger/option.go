@@ -2,6 +2,7 @@ package logger
 
 import (
 	"strings"
+	"time"
 
 	"go.uber.org/zap/zapcore"
 )
@@ -27,13 +28,20 @@ type options struct {
 	fileConfig *fileOptions
 
 	hooks []func(zapcore.Entry) error
+
+	moduleLevels map[string]zapcore.Level
+
+	redactOpts    []RedactOption
+	samplingTick  time.Duration
+	samplingRules map[zapcore.Level]SamplingRule
 }
 
 func defaultOptions() *options {
 	return &options{
-		level:    defaultLevel,
-		encoding: defaultEncoding,
-		isSave:   defaultIsSave,
+		level:        defaultLevel,
+		encoding:     defaultEncoding,
+		isSave:       defaultIsSave,
+		moduleLevels: map[string]zapcore.Level{},
 	}
 }
 
@@ -87,6 +95,63 @@ func WithHooks(hooks ...func(zapcore.Entry) error) Option {
 	}
 }
 
+// WithModuleLevels set the log levels of the modules, the key is the logger name, e.g. {"gin": "warn"},
+// the invalid level is ignored, they can be changed at runtime by SetModuleLevels.
+func WithModuleLevels(levels map[string]string) Option {
+	return func(o *options) {
+		for name, levelName := range levels {
+			if l, err := parseLevel(levelName); err == nil {
+				o.moduleLevels[name] = l
+			}
+		}
+	}
+}
+
+// WithRedaction set the redaction of the sensitive fields, including the nested fields of the objects, e.g.
+// WithRedaction(WithRedactKeys("password", "token"), WithPartialRedactKeys("phone"))
+func WithRedaction(opts ...RedactOption) Option {
+	return func(o *options) {
+		o.redactOpts = append(o.redactOpts, opts...)
+	}
+}
+
+// WithSampling set the sampling rules of the levels, the key is the level name, e.g. cap the identical
+// info messages to 100 per second: WithSampling(time.Second, map[string]SamplingRule{"info": {First: 100}}),
+// the invalid level is ignored, the counters are got by GetSamplingStats.
+func WithSampling(tick time.Duration, rules map[string]SamplingRule) Option {
+	return func(o *options) {
+		if tick <= 0 {
+			tick = time.Second
+		}
+		o.samplingTick = tick
+		o.samplingRules = make(map[zapcore.Level]SamplingRule, len(rules))
+		for levelName, rule := range rules {
+			if l, err := parseLevel(levelName); err == nil {
+				o.samplingRules[l] = rule
+			}
+		}
+	}
+}
+
+// wrapCore the cores from inside to outside: redaction → level → sampling, the entries dropped by the
+// level or the sampling are not redacted.
+func (o *options) wrapCore(core zapcore.Core) zapcore.Core {
+	r := newRedactor(o.redactOpts...)
+	if !r.isEmpty() {
+		core = &redactCore{Core: core, r: r}
+	}
+	defaultRedactor = r
+	core = levelCtl.wrap(core)
+
+	defaultSamplingStats = &samplingStats{}
+	levels := make([]zapcore.Level, 0, len(o.samplingRules))
+	for lvl := range o.samplingRules {
+		levels = append(levels, lvl)
+	}
+	defaultSamplingLevels = levels
+	return newSamplingCore(core, o.samplingTick, o.samplingRules, defaultSamplingStats)
+}
+
 // ------------------------------------------------------------------------------------------
 
 type fileOptions struct {
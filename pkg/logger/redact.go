@@ -0,0 +1,408 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	redactedValue = "******"
+
+	// the max number of the cached key actions, the keys of the fields are usually constants
+	maxRedactCacheSize = 4096
+)
+
+type redactAction int
+
+const (
+	redactNone redactAction = iota
+	redactFull
+	redactPartial
+)
+
+// RedactOption set the redaction options.
+type RedactOption func(*redactor)
+
+// WithRedactKeys set the keys whose values are replaced by "******", case-insensitive, e.g. "password", "token".
+func WithRedactKeys(keys ...string) RedactOption {
+	return func(r *redactor) {
+		for _, key := range keys {
+			r.keys[strings.ToLower(key)] = redactFull
+		}
+	}
+}
+
+// WithRedactPatterns set the key patterns whose values are replaced by "******", e.g. regexp.MustCompile(`(?i)secret`).
+func WithRedactPatterns(patterns ...*regexp.Regexp) RedactOption {
+	return func(r *redactor) {
+		for _, p := range patterns {
+			if p != nil {
+				r.patterns = append(r.patterns, p)
+			}
+		}
+	}
+}
+
+// WithPartialRedactKeys set the keys whose values are masked except the last 4 characters, case-insensitive,
+// e.g. "phone" 13800138000 is logged as *******8000.
+func WithPartialRedactKeys(keys ...string) RedactOption {
+	return func(r *redactor) {
+		for _, key := range keys {
+			r.keys[strings.ToLower(key)] = redactPartial
+		}
+	}
+}
+
+type redactor struct {
+	keys     map[string]redactAction // lowercase key → action
+	patterns []*regexp.Regexp
+
+	cache     sync.Map // key → redactAction, avoid the lowercase and regexp matching on the hot path
+	cacheSize int64
+}
+
+// the redactor of the default logger
+var defaultRedactor = newRedactor()
+
+// RedactValue redact the keys of the maps and structs by the redaction rules of the default logger, e.g. the
+// values persisted or returned by the api are redacted in the same way as the logs, the value is converted by
+// json, it is returned as it is if there is no redaction rule.
+func RedactValue(v interface{}) interface{} {
+	r := defaultRedactor
+	if r.isEmpty() {
+		return v
+	}
+	return r.redactValue(v)
+}
+
+func newRedactor(opts ...RedactOption) *redactor {
+	r := &redactor{keys: map[string]redactAction{}}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+func (r *redactor) isEmpty() bool {
+	return len(r.keys) == 0 && len(r.patterns) == 0
+}
+
+func (r *redactor) action(key string) redactAction {
+	if v, ok := r.cache.Load(key); ok {
+		return v.(redactAction) //nolint
+	}
+
+	action, ok := r.keys[strings.ToLower(key)]
+	if !ok {
+		for _, p := range r.patterns {
+			if p.MatchString(key) {
+				action = redactFull
+				break
+			}
+		}
+	}
+	if atomic.LoadInt64(&r.cacheSize) < maxRedactCacheSize {
+		if _, loaded := r.cache.LoadOrStore(key, action); !loaded {
+			atomic.AddInt64(&r.cacheSize, 1)
+		}
+	}
+	return action
+}
+
+// redactFields returns the original fields if none of them is changed, so the plain fields are not copied.
+func (r *redactor) redactFields(fields []zapcore.Field) []zapcore.Field {
+	var out []zapcore.Field
+	for i := range fields {
+		f, changed := r.redactField(fields[i])
+		if !changed {
+			if out != nil {
+				out = append(out, f)
+			}
+			continue
+		}
+		if out == nil {
+			out = make([]zapcore.Field, i, len(fields))
+			copy(out, fields[:i])
+		}
+		out = append(out, f)
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
+
+func (r *redactor) redactField(f zapcore.Field) (zapcore.Field, bool) {
+	if f.Type == zapcore.SkipType || f.Type == zapcore.NamespaceType {
+		return f, false
+	}
+
+	switch r.action(f.Key) {
+	case redactFull:
+		return zap.String(f.Key, redactedValue), true
+	case redactPartial:
+		if s, ok := fieldString(f); ok {
+			return zap.String(f.Key, maskPartial(s)), true
+		}
+		return zap.String(f.Key, redactedValue), true
+	}
+
+	// the nested objects
+	switch f.Type {
+	case zapcore.ObjectMarshalerType:
+		return zap.Object(f.Key, &redactObject{ObjectMarshaler: f.Interface.(zapcore.ObjectMarshaler), r: r}), true //nolint
+	case zapcore.InlineMarshalerType:
+		return zap.Inline(&redactObject{ObjectMarshaler: f.Interface.(zapcore.ObjectMarshaler), r: r}), true //nolint
+	case zapcore.ArrayMarshalerType:
+		return zap.Array(f.Key, &redactArray{ArrayMarshaler: f.Interface.(zapcore.ArrayMarshaler), r: r}), true //nolint
+	case zapcore.ReflectType:
+		return zap.Reflect(f.Key, r.redactValue(f.Interface)), true
+	}
+	return f, false
+}
+
+// redactValue redact the keys of the maps and structs, the value is converted by json.
+func (r *redactor) redactValue(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var out interface{}
+	if err = dec.Decode(&out); err != nil {
+		return v
+	}
+	return r.walk(out)
+}
+
+func (r *redactor) walk(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, sub := range val {
+			switch r.action(k) {
+			case redactFull:
+				val[k] = redactedValue
+			case redactPartial:
+				val[k] = maskPartial(fmt.Sprint(sub))
+			default:
+				val[k] = r.walk(sub)
+			}
+		}
+	case []interface{}:
+		for i, sub := range val {
+			val[i] = r.walk(sub)
+		}
+	}
+	return v
+}
+
+func fieldString(f zapcore.Field) (string, bool) {
+	switch f.Type {
+	case zapcore.StringType:
+		return f.String, true
+	case zapcore.ByteStringType:
+		b, _ := f.Interface.([]byte)
+		return string(b), true
+	case zapcore.Int64Type, zapcore.Int32Type, zapcore.Int16Type, zapcore.Int8Type:
+		return strconv.FormatInt(f.Integer, 10), true
+	case zapcore.Uint64Type, zapcore.Uint32Type, zapcore.Uint16Type, zapcore.Uint8Type:
+		return strconv.FormatUint(uint64(f.Integer), 10), true
+	case zapcore.StringerType:
+		if s, ok := f.Interface.(fmt.Stringer); ok {
+			return s.String(), true
+		}
+	}
+	return "", false
+}
+
+// mask all but the last 4 characters
+func maskPartial(s string) string {
+	runes := []rune(s)
+	if len(runes) <= 4 {
+		return strings.Repeat("*", len(runes))
+	}
+	return strings.Repeat("*", len(runes)-4) + string(runes[len(runes)-4:])
+}
+
+// redactCore redact the fields before writing, it must be wrapped by the level core, so the fields are
+// redacted only for the entries that are written.
+type redactCore struct {
+	zapcore.Core
+	r *redactor
+}
+
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{Core: c.Core.With(c.r.redactFields(fields)), r: c.r}
+}
+
+func (c *redactCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *redactCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(ent, c.r.redactFields(fields))
+}
+
+type redactObject struct {
+	zapcore.ObjectMarshaler
+	r *redactor
+}
+
+func (o *redactObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	return o.ObjectMarshaler.MarshalLogObject(&redactEncoder{ObjectEncoder: enc, r: o.r})
+}
+
+type redactArray struct {
+	zapcore.ArrayMarshaler
+	r *redactor
+}
+
+func (a *redactArray) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	return a.ArrayMarshaler.MarshalLogArray(&redactArrayEncoder{ArrayEncoder: enc, r: a.r})
+}
+
+// redactEncoder redact the values of the nested object by the keys
+type redactEncoder struct {
+	zapcore.ObjectEncoder
+	r *redactor
+}
+
+func (e *redactEncoder) addString(key string, action redactAction, value func() string) {
+	if action == redactPartial {
+		e.ObjectEncoder.AddString(key, maskPartial(value()))
+		return
+	}
+	e.ObjectEncoder.AddString(key, redactedValue)
+}
+
+func (e *redactEncoder) AddString(key, value string) {
+	if action := e.r.action(key); action != redactNone {
+		e.addString(key, action, func() string { return value })
+		return
+	}
+	e.ObjectEncoder.AddString(key, value)
+}
+
+func (e *redactEncoder) AddByteString(key string, value []byte) {
+	if action := e.r.action(key); action != redactNone {
+		e.addString(key, action, func() string { return string(value) })
+		return
+	}
+	e.ObjectEncoder.AddByteString(key, value)
+}
+
+func (e *redactEncoder) AddBinary(key string, value []byte) {
+	if e.r.action(key) != redactNone {
+		e.ObjectEncoder.AddString(key, redactedValue)
+		return
+	}
+	e.ObjectEncoder.AddBinary(key, value)
+}
+
+func (e *redactEncoder) AddInt(key string, value int) {
+	e.AddInt64(key, int64(value))
+}
+
+func (e *redactEncoder) AddInt64(key string, value int64) {
+	if action := e.r.action(key); action != redactNone {
+		e.addString(key, action, func() string { return strconv.FormatInt(value, 10) })
+		return
+	}
+	e.ObjectEncoder.AddInt64(key, value)
+}
+
+func (e *redactEncoder) AddInt32(key string, value int32) {
+	e.AddInt64(key, int64(value))
+}
+
+func (e *redactEncoder) AddUint(key string, value uint) {
+	e.AddUint64(key, uint64(value))
+}
+
+func (e *redactEncoder) AddUint64(key string, value uint64) {
+	if action := e.r.action(key); action != redactNone {
+		e.addString(key, action, func() string { return strconv.FormatUint(value, 10) })
+		return
+	}
+	e.ObjectEncoder.AddUint64(key, value)
+}
+
+func (e *redactEncoder) AddUint32(key string, value uint32) {
+	e.AddUint64(key, uint64(value))
+}
+
+func (e *redactEncoder) AddFloat64(key string, value float64) {
+	if e.r.action(key) != redactNone {
+		e.ObjectEncoder.AddString(key, redactedValue)
+		return
+	}
+	e.ObjectEncoder.AddFloat64(key, value)
+}
+
+func (e *redactEncoder) AddDuration(key string, value time.Duration) {
+	if e.r.action(key) != redactNone {
+		e.ObjectEncoder.AddString(key, redactedValue)
+		return
+	}
+	e.ObjectEncoder.AddDuration(key, value)
+}
+
+func (e *redactEncoder) AddObject(key string, obj zapcore.ObjectMarshaler) error {
+	if e.r.action(key) != redactNone {
+		e.ObjectEncoder.AddString(key, redactedValue)
+		return nil
+	}
+	return e.ObjectEncoder.AddObject(key, &redactObject{ObjectMarshaler: obj, r: e.r})
+}
+
+func (e *redactEncoder) AddArray(key string, arr zapcore.ArrayMarshaler) error {
+	if e.r.action(key) != redactNone {
+		e.ObjectEncoder.AddString(key, redactedValue)
+		return nil
+	}
+	return e.ObjectEncoder.AddArray(key, &redactArray{ArrayMarshaler: arr, r: e.r})
+}
+
+func (e *redactEncoder) AddReflected(key string, value interface{}) error {
+	switch e.r.action(key) {
+	case redactFull:
+		e.ObjectEncoder.AddString(key, redactedValue)
+		return nil
+	case redactPartial:
+		e.ObjectEncoder.AddString(key, maskPartial(fmt.Sprint(value)))
+		return nil
+	}
+	return e.ObjectEncoder.AddReflected(key, e.r.redactValue(value))
+}
+
+// redactArrayEncoder redact the objects in the array
+type redactArrayEncoder struct {
+	zapcore.ArrayEncoder
+	r *redactor
+}
+
+func (e *redactArrayEncoder) AppendObject(obj zapcore.ObjectMarshaler) error {
+	return e.ArrayEncoder.AppendObject(&redactObject{ObjectMarshaler: obj, r: e.r})
+}
+
+func (e *redactArrayEncoder) AppendArray(arr zapcore.ArrayMarshaler) error {
+	return e.ArrayEncoder.AppendArray(&redactArray{ArrayMarshaler: arr, r: e.r})
+}
+
+func (e *redactArrayEncoder) AppendReflected(value interface{}) error {
+	return e.ArrayEncoder.AppendReflected(e.r.redactValue(value))
+}
@@ -0,0 +1,180 @@
+package logger
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type testCard struct {
+	CardNo string
+	Bank   string
+}
+
+func (c *testCard) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("cardNo", c.CardNo)
+	enc.AddString("bank", c.Bank)
+	return nil
+}
+
+type testUser struct {
+	Name     string
+	Password string
+	Phone    int64
+	Cards    []*testCard
+}
+
+func (u *testUser) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("name", u.Name)
+	enc.AddString("password", u.Password)
+	enc.AddInt64("phone", u.Phone)
+	_ = enc.AddArray("cards", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+		for _, c := range u.Cards {
+			_ = arr.AppendObject(c)
+		}
+		return nil
+	}))
+	return enc.AddObject("card", u.Cards[0])
+}
+
+func newRedactLogger() (*zap.Logger, *observer.ObservedLogs) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	r := newRedactor(
+		WithRedactKeys("Password", "token"),
+		WithRedactPatterns(regexp.MustCompile(`(?i)secret`), nil),
+		WithPartialRedactKeys("phone", "cardNo"),
+	)
+	return zap.New(&redactCore{Core: core, r: r}), logs
+}
+
+func TestRedact_PlainFields(t *testing.T) {
+	log, logs := newRedactLogger()
+
+	log.Info("plain",
+		zap.String("name", "foo"),
+		zap.String("password", "123456"),
+		zap.String("TOKEN", "abc"),
+		zap.String("clientSecret", "xyz"),
+		zap.String("phone", "13800138000"),
+		zap.Int64("cardNo", 6222021234567890),
+		zap.Int("age", 18),
+		zap.Int("password", 123456),
+		zap.Bool("secretEnabled", true),
+		zap.Namespace("password"),
+	)
+
+	fields := logs.All()[0].ContextMap()
+	assert.Equal(t, "foo", fields["name"])
+	assert.Equal(t, int64(18), fields["age"])
+	assert.Equal(t, map[string]interface{}{}, fields["password"]) // the namespace is not redacted
+	assert.Equal(t, "******", fields["TOKEN"])
+	assert.Equal(t, "******", fields["clientSecret"])
+	assert.Equal(t, "******", fields["secretEnabled"])
+	assert.Equal(t, "*******8000", fields["phone"])
+	assert.Equal(t, "************7890", fields["cardNo"])
+
+	// the fields of With are redacted
+	log.With(zap.String("token", "abc"), zap.String("name", "bar")).Info("with")
+	fields = logs.All()[1].ContextMap()
+	assert.Equal(t, "******", fields["token"])
+	assert.Equal(t, "bar", fields["name"])
+}
+
+func TestRedact_NestedFields(t *testing.T) {
+	log, logs := newRedactLogger()
+
+	user := &testUser{
+		Name:     "foo",
+		Password: "123456",
+		Phone:    13800138000,
+		Cards:    []*testCard{{CardNo: "6222021234567890", Bank: "abc"}},
+	}
+	log.Info("object", zap.Object("user", user), zap.Inline(user))
+	fields := logs.All()[0].ContextMap()
+	u := fields["user"].(map[string]interface{})
+	assert.Equal(t, "foo", u["name"])
+	assert.Equal(t, "******", u["password"])
+	assert.Equal(t, "*******8000", u["phone"])
+	assert.Equal(t, "************7890", u["card"].(map[string]interface{})["cardNo"])
+	assert.Equal(t, "abc", u["card"].(map[string]interface{})["bank"])
+	assert.Equal(t, "************7890", u["cards"].([]interface{})[0].(map[string]interface{})["cardNo"])
+	assert.Equal(t, "******", fields["password"]) // inline
+
+	// the reflected values, e.g. map, struct
+	type profile struct {
+		Phone string `json:"phone"`
+		City  string `json:"city"`
+	}
+	log.Info("reflect",
+		zap.Any("req", map[string]interface{}{
+			"token":   "abc",
+			"profile": &profile{Phone: "13800138000", City: "sz"},
+			"list":    []interface{}{map[string]string{"password": "123456"}, 1},
+			"count":   10,
+		}),
+		zap.Any("secret", profile{}),
+		zap.Reflect("chan", make(chan int)), // can't be converted
+	)
+	fields = logs.All()[1].ContextMap()
+	req := fields["req"].(map[string]interface{})
+	assert.Equal(t, "******", req["token"])
+	assert.Equal(t, "*******8000", req["profile"].(map[string]interface{})["phone"])
+	assert.Equal(t, "sz", req["profile"].(map[string]interface{})["city"])
+	assert.Equal(t, "******", req["list"].([]interface{})[0].(map[string]interface{})["password"])
+	assert.Equal(t, "******", fields["secret"])
+	assert.NotNil(t, fields["chan"])
+}
+
+func TestRedact_Allocs(t *testing.T) {
+	r := newRedactor(WithRedactKeys("password"), WithRedactPatterns(regexp.MustCompile(`(?i)token`)))
+	fields := []zapcore.Field{zap.String("name", "foo"), zap.Int("age", 18), zap.Bool("ok", true)}
+	r.redactFields(fields) // warm up the cache
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = r.redactFields(fields)
+	})
+	assert.Equal(t, float64(0), allocs)
+
+	out := r.redactFields(append(fields, zap.String("accessToken", "abc")))
+	assert.Len(t, out, 4)
+	assert.Equal(t, "foo", out[0].String)
+	assert.Equal(t, "******", out[3].String)
+}
+
+func Test_maskPartial(t *testing.T) {
+	assert.Equal(t, "*******8000", maskPartial("13800138000"))
+	assert.Equal(t, "**3456", maskPartial("中文3456"))
+	assert.Equal(t, "****", maskPartial("1234"))
+	assert.Equal(t, "", maskPartial(""))
+}
+
+func TestInitWithRedactionAndSampling(t *testing.T) {
+	_, err := Init(
+		WithFormat("json"),
+		WithRedaction(WithRedactKeys("password")),
+		WithSampling(0, map[string]SamplingRule{"info": {First: 1}, "unknown": {First: 1}}),
+	)
+	assert.NoError(t, err)
+	defer func() { _, _ = Init() }()
+
+	for i := 0; i < 3; i++ {
+		Info("login", String("password", "123456"))
+	}
+	stats := GetSamplingStats()
+	assert.Len(t, stats, 1)
+	assert.Equal(t, SamplingStat{Logged: 2, Dropped: 2}, stats["info"]) // including the message of Init
+
+	assert.Equal(t, map[string]interface{}{"name": "foo", "password": redactedValue},
+		RedactValue(map[string]interface{}{"name": "foo", "password": "123456"}))
+}
+
+func TestRedactValue(t *testing.T) {
+	_, err := Init()
+	assert.NoError(t, err)
+	v := map[string]interface{}{"password": "123456"}
+	assert.Equal(t, v, RedactValue(v)) // no redaction rule
+}
@@ -0,0 +1,112 @@
+package logger
+
+import (
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// SamplingRule the sampling rule of a level, in each tick, the first entries with the same level and message
+// are logged, then every Thereafter-th entry is logged, e.g. {First: 100, Thereafter: 0} caps the identical
+// messages to 100 per tick, Thereafter <= 0 means the rest are dropped.
+type SamplingRule struct {
+	First      int
+	Thereafter int
+}
+
+// SamplingStat the sampling counters of a level
+type SamplingStat struct {
+	Logged  uint64 `json:"logged"`
+	Dropped uint64 `json:"dropped"`
+}
+
+type samplingCounter struct {
+	logged  atomic.Uint64
+	dropped atomic.Uint64
+}
+
+// counters indexed by level - zapcore.DebugLevel
+type samplingStats [zapcore.FatalLevel - zapcore.DebugLevel + 1]samplingCounter
+
+func (s *samplingStats) counter(lvl zapcore.Level) *samplingCounter {
+	i := int(lvl - zapcore.DebugLevel)
+	if i < 0 || i >= len(s) {
+		return nil
+	}
+	return &s[i]
+}
+
+func (s *samplingStats) hook(ent zapcore.Entry, dec zapcore.SamplingDecision) {
+	c := s.counter(ent.Level)
+	if c == nil {
+		return
+	}
+	if dec&zapcore.LogDropped != 0 {
+		c.dropped.Add(1)
+	}
+	if dec&zapcore.LogSampled != 0 {
+		c.logged.Add(1)
+	}
+}
+
+func (s *samplingStats) get(levels []zapcore.Level) map[string]SamplingStat {
+	stats := make(map[string]SamplingStat, len(levels))
+	for _, lvl := range levels {
+		if c := s.counter(lvl); c != nil {
+			stats[lvl.String()] = SamplingStat{Logged: c.logged.Load(), Dropped: c.dropped.Load()}
+		}
+	}
+	return stats
+}
+
+// the sampling stats of the default logger
+var (
+	defaultSamplingStats  = &samplingStats{}
+	defaultSamplingLevels []zapcore.Level
+)
+
+// GetSamplingStats get the sampling counters of the sampled levels of the default logger since Init,
+// the key is the level name, e.g. "info".
+func GetSamplingStats() map[string]SamplingStat {
+	return defaultSamplingStats.get(defaultSamplingLevels)
+}
+
+// samplingCore route the entries of the sampled levels to the samplers, the other levels are not sampled.
+type samplingCore struct {
+	zapcore.Core
+	samplers map[zapcore.Level]zapcore.Core
+}
+
+func newSamplingCore(core zapcore.Core, tick time.Duration, rules map[zapcore.Level]SamplingRule, stats *samplingStats) zapcore.Core {
+	if len(rules) == 0 {
+		return core
+	}
+	samplers := make(map[zapcore.Level]zapcore.Core, len(rules))
+	for lvl, rule := range rules {
+		if rule.Thereafter < 0 {
+			rule.Thereafter = 0
+		}
+		samplers[lvl] = zapcore.NewSamplerWithOptions(core, tick, rule.First, rule.Thereafter, zapcore.SamplerHook(stats.hook))
+	}
+	return &samplingCore{Core: core, samplers: samplers}
+}
+
+func (c *samplingCore) Level() zapcore.Level {
+	return zapcore.LevelOf(c.Core)
+}
+
+func (c *samplingCore) With(fields []zapcore.Field) zapcore.Core {
+	samplers := make(map[zapcore.Level]zapcore.Core, len(c.samplers))
+	for lvl, s := range c.samplers {
+		samplers[lvl] = s.With(fields) // the counts are shared with the parent
+	}
+	return &samplingCore{Core: c.Core.With(fields), samplers: samplers}
+}
+
+func (c *samplingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if s, ok := c.samplers[ent.Level]; ok {
+		return s.Check(ent, ce)
+	}
+	return c.Core.Check(ent, ce)
+}
@@ -0,0 +1,46 @@
+package logger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSamplingCore(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	stats := &samplingStats{}
+	log := zap.New(newSamplingCore(core, time.Minute, map[zapcore.Level]SamplingRule{
+		zapcore.InfoLevel:  {First: 2},
+		zapcore.DebugLevel: {First: 1, Thereafter: 2},
+		zapcore.ErrorLevel: {First: 1, Thereafter: -1},
+	}, stats))
+	assert.Equal(t, zapcore.DebugLevel, zapcore.LevelOf(log.Core()))
+
+	for i := 0; i < 5; i++ {
+		log.Info("storm")
+		log.Warn("storm") // not sampled
+		log.Debug("storm")
+		log.With(zap.Int("i", i)).Error("storm") // the counts are shared with the logger of fields
+	}
+	log.Info("another message")
+
+	assert.Equal(t, 2, logs.FilterMessage("storm").FilterLevelExact(zapcore.InfoLevel).Len())
+	assert.Equal(t, 5, logs.FilterLevelExact(zapcore.WarnLevel).Len())
+	assert.Equal(t, 3, logs.FilterLevelExact(zapcore.DebugLevel).Len()) // the 1st, 3rd, 5th
+	assert.Equal(t, 1, logs.FilterLevelExact(zapcore.ErrorLevel).Len())
+	assert.Equal(t, 1, logs.FilterMessage("another message").Len())
+
+	s := stats.get([]zapcore.Level{zapcore.InfoLevel, zapcore.DebugLevel, zapcore.ErrorLevel, zapcore.WarnLevel, zapcore.Level(100)})
+	assert.Equal(t, SamplingStat{Logged: 3, Dropped: 3}, s["info"])
+	assert.Equal(t, SamplingStat{Logged: 3, Dropped: 2}, s["debug"])
+	assert.Equal(t, SamplingStat{Logged: 1, Dropped: 4}, s["error"])
+	assert.Equal(t, SamplingStat{}, s["warn"])
+	assert.Len(t, s, 4)
+
+	// no rules
+	assert.Equal(t, core, newSamplingCore(core, time.Second, nil, stats))
+}
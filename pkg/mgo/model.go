@@ -15,10 +15,10 @@ type Model struct {
 	DeletedAt *time.Time         `bson:"deleted_at,omitempty" json:"deletedAt,omitempty"`
 }
 
-// SetModelValue set model fields
+// SetModelValue set the id if it is zero, and the created and updated time if they are zero
 func (p *Model) SetModelValue() {
 	now := time.Now()
-	if !p.ID.IsZero() {
+	if p.ID.IsZero() {
 		p.ID = primitive.NewObjectID()
 	}
 
@@ -44,11 +44,13 @@ func Init(dsn string, opts ...*options.ClientOptions) (*mongo.Database, error) {
 	return Init2(uri, dbName, opts...)
 }
 
-// Init2 connecting to mongo using uri
+// Init2 connecting to mongo using uri, the retryable writes and reads are enabled by default, they can be
+// disabled by the uri, e.g. ?retryWrites=false&retryReads=false, or by the options,
+// e.g. WithOption().SetRetryWrites(false), the later options override the former ones.
 func Init2(uri string, dbName string, opts ...*options.ClientOptions) (*mongo.Database, error) {
 	ctx := context.Background()
 	mongoOpts := []*options.ClientOptions{
-		options.Client().ApplyURI(uri),
+		newClientOptions(uri),
 	}
 	mongoOpts = append(mongoOpts, opts...)
 	client, err := mongo.Connect(ctx, mongoOpts...)
@@ -66,6 +68,15 @@ func Init2(uri string, dbName string, opts ...*options.ClientOptions) (*mongo.Da
 	return db, nil
 }
 
+// the retryable writes and reads are retried once after the failures such as "not master" during the
+// elections of the replica set, the settings of the uri take precedence over the defaults
+func newClientOptions(uri string) *options.ClientOptions {
+	return options.Client().
+		SetRetryWrites(true).
+		SetRetryReads(true).
+		ApplyURI(uri)
+}
+
 // Close mongodb
 func Close(db *mongo.Database) error {
 	return db.Client().Disconnect(context.Background())
@@ -55,9 +55,14 @@ func TestModel_SetModelValue(t *testing.T) {
 	m := new(Model)
 	m.SetModelValue()
 
-	assert.NotNil(t, m.ID)
-	assert.NotNil(t, m.CreatedAt)
-	assert.NotNil(t, m.UpdatedAt)
+	assert.False(t, m.ID.IsZero())
+	assert.False(t, m.CreatedAt.IsZero())
+	assert.False(t, m.UpdatedAt.IsZero())
+
+	// the id is kept
+	id := m.ID
+	m.SetModelValue()
+	assert.Equal(t, id, m.ID)
 }
 
 func TestExcludeDeleted(t *testing.T) {
@@ -0,0 +1,94 @@
+package query
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// the fields of the document of the $facet stage of WithTotalCount, e.g. {data: [...], total: [{total: 100}]}
+const (
+	FacetDataField  = "data"
+	FacetTotalField = "total"
+)
+
+// WithTotalCount the pipeline of ConvertToAggregatePipeline counts the total of the documents matching the filter
+// and gets the documents of the page in one round trip by the $facet stage, the result is decoded by
+// DecodeFacetResult.
+func WithTotalCount() RulerOption {
+	return func(o *rulerOptions) {
+		o.totalCount = true
+	}
+}
+
+// ConvertToAggregatePipeline converted to the aggregation pipeline, $match of the columns, $sort, $skip and
+// $limit of the page, and $project of Select if it is set, they are validated by the options in the same way as
+// ConvertToQuery. The stages of the page are in the $facet stage with the total count if WithTotalCount is set, e.g.
+//
+//	pipeline, err := params.ConvertToAggregatePipeline(query.WithWhitelistNames(names), query.WithTotalCount())
+//	cursor, err := collection.Aggregate(ctx, pipeline)
+//	items, total, err := query.DecodeFacetResult(ctx, cursor)
+func (p *Params) ConvertToAggregatePipeline(opts ...RulerOption) (mongo.Pipeline, error) {
+	o := defaultRulerOptions()
+	o.apply(opts...)
+
+	filter, err := p.convertToMongoFilter(o)
+	if err != nil {
+		return nil, err
+	}
+	page, err := o.checkedPage(p)
+	if err != nil {
+		return nil, err
+	}
+	projection, err := p.convertToProjection(o)
+	if err != nil {
+		return nil, err
+	}
+
+	pageStages := mongo.Pipeline{
+		{{Key: "$sort", Value: page.sort}},
+		{{Key: "$skip", Value: int64(page.Skip())}},
+		{{Key: "$limit", Value: int64(page.limit)}},
+	}
+	if projection != nil {
+		pageStages = append(pageStages, bson.D{{Key: "$project", Value: projection}})
+	}
+
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: filter}}}
+	if !o.totalCount {
+		return append(pipeline, pageStages...), nil
+	}
+	return append(pipeline, bson.D{{Key: "$facet", Value: bson.D{
+		{Key: FacetDataField, Value: pageStages},
+		{Key: FacetTotalField, Value: mongo.Pipeline{{{Key: "$count", Value: FacetTotalField}}}},
+	}}}), nil
+}
+
+type facetResult struct {
+	Data  []bson.M `bson:"data"`
+	Total []struct {
+		Total int64 `bson:"total"`
+	} `bson:"total"`
+}
+
+// DecodeFacetResult decode the documents of the page and the total count from the cursor of the pipeline of
+// WithTotalCount, the cursor is closed, the total is 0 if there is no document matching the filter.
+func DecodeFacetResult(ctx context.Context, cursor *mongo.Cursor) (items []bson.M, total int64, err error) {
+	var results []facetResult
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, 0, err
+	}
+	if len(results) == 0 {
+		return []bson.M{}, 0, nil
+	}
+
+	items = results[0].Data
+	if items == nil {
+		items = []bson.M{}
+	}
+	if len(results[0].Total) > 0 {
+		total = results[0].Total[0].Total
+	}
+	return items, total, nil
+}
@@ -0,0 +1,90 @@
+package query
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestParams_ConvertToAggregatePipeline(t *testing.T) {
+	p := &Params{
+		Page:    1,
+		Limit:   20,
+		Sort:    "-age",
+		Select:  "name,age",
+		Columns: []Column{{Name: "age", Exp: "gt", Value: 18}},
+	}
+	whitelist := WithWhitelistNames(map[string]bool{"name": true, "age": true})
+
+	pipeline, err := p.ConvertToAggregatePipeline(whitelist)
+	require.NoError(t, err)
+	pageStages := mongo.Pipeline{
+		{{Key: "$sort", Value: bson.D{{Key: "age", Value: -1}}}},
+		{{Key: "$skip", Value: int64(20)}},
+		{{Key: "$limit", Value: int64(20)}},
+		{{Key: "$project", Value: bson.M{"name": 1, "age": 1}}},
+	}
+	match := bson.D{{Key: "$match", Value: bson.M{"age": bson.M{"$gt": 18}}}}
+	assert.Equal(t, append(mongo.Pipeline{match}, pageStages...), pipeline)
+
+	// the page stages in the $facet stage with the total count
+	pipeline, err = p.ConvertToAggregatePipeline(whitelist, WithTotalCount())
+	require.NoError(t, err)
+	assert.Equal(t, mongo.Pipeline{match, {{Key: "$facet", Value: bson.D{
+		{Key: "data", Value: pageStages},
+		{Key: "total", Value: mongo.Pipeline{{{Key: "$count", Value: "total"}}}},
+	}}}}, pipeline)
+
+	// no columns and no select
+	pipeline, err = (&Params{Limit: 10}).ConvertToAggregatePipeline()
+	require.NoError(t, err)
+	assert.Equal(t, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: -1}}}},
+		{{Key: "$skip", Value: int64(0)}},
+		{{Key: "$limit", Value: int64(10)}},
+	}, pipeline)
+
+	// the columns, the sort fields and the select fields are validated by the whitelist
+	for _, p := range []*Params{
+		{Limit: 10, Columns: []Column{{Name: "password", Value: "123456"}}},
+		{Limit: 10, Sort: "password"},
+		{Limit: 10, Select: "password"},
+	} {
+		pipeline, err = p.ConvertToAggregatePipeline(whitelist, WithTotalCount())
+		assert.ErrorIs(t, err, ErrNameNotAllowed)
+		assert.Nil(t, pipeline)
+	}
+}
+
+func TestDecodeFacetResult(t *testing.T) {
+	ctx := context.Background()
+	cursor, err := mongo.NewCursorFromDocuments([]interface{}{
+		bson.M{"data": bson.A{bson.M{"name": "foo"}, bson.M{"name": "bar"}}, "total": bson.A{bson.M{"total": int32(12)}}},
+	}, nil, nil)
+	require.NoError(t, err)
+	items, total, err := DecodeFacetResult(ctx, cursor)
+	require.NoError(t, err)
+	assert.Equal(t, []bson.M{{"name": "foo"}, {"name": "bar"}}, items)
+	assert.Equal(t, int64(12), total)
+
+	// no document matching the filter, the total of $count is empty
+	cursor, err = mongo.NewCursorFromDocuments([]interface{}{bson.M{"data": bson.A{}, "total": bson.A{}}}, nil, nil)
+	require.NoError(t, err)
+	items, total, err = DecodeFacetResult(ctx, cursor)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+	assert.NotNil(t, items)
+	assert.Equal(t, int64(0), total)
+
+	cursor, err = mongo.NewCursorFromDocuments(nil, nil, nil)
+	require.NoError(t, err)
+	items, total, err = DecodeFacetResult(ctx, cursor)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+	assert.Equal(t, int64(0), total)
+}
@@ -0,0 +1,111 @@
+package query
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// the representative shapes of the list queries
+func benchOneEqColumns() []Column {
+	return []Column{{Name: "name", Value: "ZhangSan"}}
+}
+
+func benchMixedColumns() []Column {
+	return []Column{
+		{Name: "name", Value: "ZhangSan", Logic: "and"},
+		{Name: "age", Exp: ">=", Value: 18, Logic: "or"},
+		{Name: "gender", Value: 1, Logic: "and"},
+		{Name: "status", Exp: "!=", Value: 3, Logic: "or"},
+		{Name: "email", Exp: "like", Value: "example.com"},
+	}
+}
+
+func benchInColumns() []Column {
+	values := make([]string, 100)
+	for i := range values {
+		values[i] = "name" + strconv.Itoa(i)
+	}
+	return []Column{{Name: "name", Exp: "in", Value: strings.Join(values, ",")}}
+}
+
+func benchLikeColumns() []Column {
+	return []Column{{Name: "email", Exp: "like", Value: "example.com"}}
+}
+
+// the allocation budgets of ConvertToMongoFilter per shape, they are the regression gate,
+// a change that exceeds a budget must lower the allocations or justify raising it here.
+var convertAllocBudgets = []struct {
+	name    string
+	columns func() []Column
+	budget  float64
+}{
+	{name: "1 column eq", columns: benchOneEqColumns, budget: 4},
+	{name: "like", columns: benchLikeColumns, budget: 8},
+	{name: "in list of 100", columns: benchInColumns, budget: 110}, // 100 of them box the values
+	{name: "5 columns mixed and or", columns: benchMixedColumns, budget: 42},
+}
+
+func TestConvertToMongoFilterAllocs(t *testing.T) {
+	for _, tt := range convertAllocBudgets {
+		t.Run(tt.name, func(t *testing.T) {
+			columns := tt.columns()
+			allocs := testing.AllocsPerRun(100, func() {
+				p := &Params{Columns: columns}
+				_, err := p.ConvertToMongoFilter()
+				if err != nil {
+					t.Fatal(err)
+				}
+			})
+			if allocs > tt.budget {
+				t.Errorf("ConvertToMongoFilter allocs = %v, budget %v", allocs, tt.budget)
+			}
+		})
+	}
+}
+
+func TestConvertToPageAllocs(t *testing.T) {
+	p := &Params{Page: 1, Limit: 20, Sort: "-age,name"}
+	allocs := testing.AllocsPerRun(100, func() {
+		p.ConvertToPage()
+	})
+	const budget = 3
+	if allocs > budget {
+		t.Errorf("ConvertToPage allocs = %v, budget %v", allocs, budget)
+	}
+}
+
+func benchmarkConvertToMongoFilter(b *testing.B, columns []Column) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		p := &Params{Columns: columns}
+		_, err := p.ConvertToMongoFilter()
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkConvertToMongoFilter_OneEq(b *testing.B) {
+	benchmarkConvertToMongoFilter(b, benchOneEqColumns())
+}
+
+func BenchmarkConvertToMongoFilter_Mixed(b *testing.B) {
+	benchmarkConvertToMongoFilter(b, benchMixedColumns())
+}
+
+func BenchmarkConvertToMongoFilter_In100(b *testing.B) {
+	benchmarkConvertToMongoFilter(b, benchInColumns())
+}
+
+func BenchmarkConvertToMongoFilter_Like(b *testing.B) {
+	benchmarkConvertToMongoFilter(b, benchLikeColumns())
+}
+
+func BenchmarkConvertToPage(b *testing.B) {
+	p := &Params{Page: 1, Limit: 20, Sort: "-age,name"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		p.ConvertToPage()
+	}
+}
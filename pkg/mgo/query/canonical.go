@@ -0,0 +1,156 @@
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Canonicalize returns the canonical copy of the columns, the logically identical conditions are converted to the
+// same filter, so the plan cache of mongodb and the response cache are hit by the queries whose columns are in
+// different orders, e.g. age > 18 & name = foo and name = foo & age > 18.
+//
+//   - the aliases of the exp and the logic are normalized, e.g. "gte" to ">=", "&&" to "and", the empty exp to "="
+//   - the columns joined by the same logic are sorted by name, exp and value (stable), i.e. the columns joined by and
+//     between two or, or all the columns if they are all joined by or, the columns never cross the or boundary
+//   - the adjacent duplicate columns of the sorted columns are merged, e.g. x & x to x
+//   - the columns of the same group are canonicalized in the group, the order of the groups is kept
+//
+// The sub-columns of elemmatch are not canonicalized. The columns with the unknown logic or the invalid group are
+// returned as they are, so the errors are reported by the conversion. The columns are not modified.
+func Canonicalize(columns []Column) []Column {
+	if len(columns) == 0 {
+		return nil
+	}
+	result := make([]Column, len(columns))
+	copy(result, columns)
+
+	hasGroup := false
+	for i := range result {
+		if result[i].Logic != "" {
+			if _, ok := lookupLogic(result[i].Logic); !ok {
+				return result
+			}
+		}
+		key, err := result[i].groupKey()
+		if err != nil {
+			return result
+		}
+		if key != "" {
+			hasGroup = true
+		}
+	}
+
+	if !hasGroup {
+		return canonicalizeColumns(result)
+	}
+	groups := splitGroups(result)
+	result = result[:0]
+	for _, group := range groups {
+		result = append(result, canonicalizeColumns(group)...)
+	}
+	return result
+}
+
+// canonicalize the columns without groups, the logic of the last column is kept, it is the logic of the group
+func canonicalizeColumns(columns []Column) []Column {
+	for i := range columns {
+		columns[i].Exp, columns[i].Logic = canonicalExp(columns[i].Exp), canonicalLogic(columns[i].Logic)
+	}
+	lastLogic := columns[len(columns)-1].Logic
+
+	// the runs of the columns joined by and, the or logic of a column ends its run
+	var runs [][]Column
+	start := 0
+	for i := range columns {
+		if i == len(columns)-1 || columns[i].Logic == OR {
+			runs = append(runs, columns[start:i+1])
+			start = i + 1
+		}
+	}
+
+	isAllOr := len(runs) > 1
+	for i := range runs {
+		runs[i] = sortColumns(runs[i])
+		isAllOr = isAllOr && len(runs[i]) == 1
+	}
+
+	result := make([]Column, 0, len(columns))
+	if isAllOr { // all the columns are joined by or after merging
+		orColumns := make([]Column, 0, len(runs))
+		for _, run := range runs {
+			orColumns = append(orColumns, run[0])
+		}
+		return joinColumns(result, sortColumns(orColumns), OR, lastLogic)
+	}
+	for i, run := range runs {
+		logic := OR
+		if i == len(runs)-1 {
+			logic = lastLogic
+		}
+		result = joinColumns(result, run, AND, logic)
+	}
+	return result
+}
+
+// the columns are sorted by name, exp and value, the adjacent duplicate columns are merged
+func sortColumns(columns []Column) []Column {
+	sort.SliceStable(columns, func(i, j int) bool {
+		if columns[i].Name != columns[j].Name {
+			return columns[i].Name < columns[j].Name
+		}
+		if columns[i].Exp != columns[j].Exp {
+			return columns[i].Exp < columns[j].Exp
+		}
+		return sortValue(columns[i].Value) < sortValue(columns[j].Value)
+	})
+	merged := columns[:1]
+	for _, column := range columns[1:] {
+		if !isSameColumn(merged[len(merged)-1], column) {
+			merged = append(merged, column)
+		}
+	}
+	return merged
+}
+
+// append the columns joined by the logic to dst, the logic of the last column is set to lastLogic
+func joinColumns(dst []Column, columns []Column, logic string, lastLogic string) []Column {
+	for i, column := range columns {
+		column.Logic = logic
+		if i == len(columns)-1 {
+			column.Logic = lastLogic
+		}
+		dst = append(dst, column)
+	}
+	return dst
+}
+
+// the value of the type is formatted, e.g. 18 and "18" are different
+func sortValue(v interface{}) string {
+	return fmt.Sprintf("%T:%v", v, v)
+}
+
+func isSameColumn(a, b Column) bool {
+	return a.Name == b.Name && a.Exp == b.Exp && strings.EqualFold(a.Type, b.Type) &&
+		reflect.DeepEqual(a.Group, b.Group) && reflect.DeepEqual(a.Value, b.Value)
+}
+
+// the normalized exp, the unknown exp is kept and reported by the conversion
+func canonicalExp(exp string) string {
+	if exp == "" {
+		return eqSymbol
+	}
+	if v, ok := lookupExp(exp); ok {
+		return v
+	}
+	return exp
+}
+
+// the normalized logic, the empty logic is and
+func canonicalLogic(logic string) string {
+	if v, _ := lookupLogic(logic); v == orSymbol1 {
+		return OR
+	}
+	return AND
+}
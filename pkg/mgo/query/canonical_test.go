@@ -0,0 +1,279 @@
+package query
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// the documents of the fixture dataset of the property tests
+var canonicalDocs = []bson.M{
+	{"_id": 1, "name": "foo", "age": 18, "status": "active"},
+	{"_id": 2, "name": "bar", "age": 25, "status": "active"},
+	{"_id": 3, "name": "foo", "age": 30, "status": "trial"},
+	{"_id": 4, "name": "baz", "age": 18, "status": "banned"},
+	{"_id": 5, "name": "bar", "age": 40, "status": "trial"},
+	{"_id": 6, "name": "qux", "age": 25, "status": "active"},
+}
+
+// match the document by the filter of the comparison, in, and and or operators of the converter
+func matchDoc(doc bson.M, filter bson.M) bool {
+	for key, value := range filter {
+		switch key {
+		case "$and":
+			for _, sub := range value.([]bson.M) {
+				if !matchDoc(doc, sub) {
+					return false
+				}
+			}
+		case "$or":
+			ok := false
+			for _, sub := range value.([]bson.M) {
+				ok = ok || matchDoc(doc, sub)
+			}
+			if !ok {
+				return false
+			}
+		default:
+			if !matchValue(doc[key], value) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func matchValue(field interface{}, value interface{}) bool {
+	ops, ok := value.(bson.M)
+	if !ok {
+		return compareValues(field, value) == 0
+	}
+	for op, v := range ops {
+		c := compareValues(field, v)
+		var ok bool
+		switch op {
+		case "$ne":
+			ok = c != 0
+		case "$gt":
+			ok = c > 0
+		case "$gte":
+			ok = c >= 0
+		case "$lt":
+			ok = c < 0
+		case "$lte":
+			ok = c <= 0
+		case "$in":
+			for _, item := range v.([]interface{}) {
+				ok = ok || compareValues(field, item) == 0
+			}
+		default:
+			panic("unsupported operator " + op)
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func compareValues(a, b interface{}) int {
+	return compareStrings(fmt.Sprintf("%09v", a), fmt.Sprintf("%09v", b))
+}
+
+func compareStrings(a, b string) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+func matchIDs(filter bson.M) []int {
+	var ids []int
+	for _, doc := range canonicalDocs {
+		if matchDoc(doc, filter) {
+			ids = append(ids, doc["_id"].(int))
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// the random columns with the aliases of the exps and the logics, the duplicate columns and the groups
+func randomColumns(r *rand.Rand, withGroup bool) []Column {
+	names := []string{"name", "age", "status"}
+	stringValues := []interface{}{"foo", "bar", "active", "trial"}
+	ageExps := []string{"", "=", "eq", "EQ", "!=", "neq", ">", "gt", ">=", "GTE", "<", "lt", "<=", "lte", "in"}
+	stringExps := []string{"", "=", "eq", "!=", "neq", "in"}
+	logics := []string{"", "and", "&", "&&", "AND", "or", "|", "||", "OR"}
+
+	n := 1 + r.Intn(6)
+	columns := make([]Column, 0, n)
+	for i := 0; i < n; i++ {
+		if i > 0 && r.Intn(4) == 0 {
+			column := columns[r.Intn(len(columns))]
+			column.Logic = logics[r.Intn(len(logics))]
+			columns = append(columns, column)
+			continue
+		}
+		column := Column{Name: names[r.Intn(len(names))], Logic: logics[r.Intn(len(logics))]}
+		if column.Name == "age" {
+			column.Exp = ageExps[r.Intn(len(ageExps))]
+			column.Value = []int{18, 25, 30, 40}[r.Intn(4)]
+			if column.Exp == "in" {
+				column.Value = []interface{}{18, []int{25, 30, 40}[r.Intn(3)]}
+			}
+		} else {
+			column.Exp = stringExps[r.Intn(len(stringExps))]
+			column.Value = stringValues[r.Intn(len(stringValues))]
+			if column.Exp == "in" {
+				column.Value = []interface{}{"foo", stringValues[r.Intn(len(stringValues))]}
+			}
+		}
+		if withGroup && r.Intn(2) == 0 {
+			column.Group = 1 + r.Intn(2)
+		}
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+func TestCanonicalize(t *testing.T) {
+	columns := []Column{
+		{Name: "name", Value: "foo", Logic: "&&"},
+		{Name: "age", Exp: "GTE", Value: 18},
+		{Name: "name", Exp: "eq", Value: "foo", Logic: "||"},
+		{Name: "status", Exp: "in", Value: "active,trial", Logic: "|"},
+		{Name: "age", Exp: "lt", Value: 30},
+	}
+	want := []Column{
+		{Name: "age", Exp: ">=", Value: 18, Logic: AND},
+		{Name: "name", Exp: "=", Value: "foo", Logic: OR},
+		{Name: "status", Exp: "in", Value: "active,trial", Logic: OR},
+		{Name: "age", Exp: "<", Value: 30, Logic: AND},
+	}
+	assert.Equal(t, want, Canonicalize(columns))
+	assert.Equal(t, want, Canonicalize(want))
+	assert.Equal(t, "name", columns[0].Name) // not modified
+
+	// all the columns are joined by or
+	assert.Equal(t, []Column{
+		{Name: "age", Exp: "=", Value: 18, Logic: OR},
+		{Name: "name", Exp: "=", Value: "foo", Logic: AND},
+	}, Canonicalize([]Column{
+		{Name: "name", Value: "foo", Logic: "or"},
+		{Name: "age", Value: 18, Logic: "or"},
+		{Name: "name", Value: "foo"},
+	}))
+
+	// the columns of the group, the logic of the group is kept
+	assert.Equal(t, []Column{
+		{Name: "age", Exp: "=", Value: 18, Logic: AND, Group: 1},
+		{Name: "name", Exp: "=", Value: "foo", Logic: OR, Group: 1},
+		{Name: "status", Exp: "=", Value: "active", Logic: AND},
+	}, Canonicalize([]Column{
+		{Name: "name", Value: "foo", Group: 1},
+		{Name: "status", Value: "active"},
+		{Name: "age", Value: 18, Logic: "or", Group: 1},
+	}))
+
+	// the unknown logic and the invalid group are reported by the conversion
+	invalid := []Column{{Name: "name", Value: "foo", Logic: "xor"}, {Name: "age", Value: 18}}
+	assert.Equal(t, invalid, Canonicalize(invalid))
+	invalid = []Column{{Name: "name", Value: "foo", Group: true}, {Name: "age", Value: 18}}
+	assert.Equal(t, invalid, Canonicalize(invalid))
+	assert.Nil(t, Canonicalize(nil))
+}
+
+func TestParams_ConvertToMongoFilter_Canonicalize(t *testing.T) {
+	a := &Params{Columns: []Column{
+		{Name: "name", Value: "foo"},
+		{Name: "age", Exp: "gt", Value: 18, Logic: "||"},
+		{Name: "status", Value: "active"},
+	}}
+	b := &Params{Columns: []Column{
+		{Name: "age", Exp: ">", Value: 18, Logic: "and"},
+		{Name: "name", Exp: "eq", Value: "foo", Logic: "or"},
+		{Name: "status", Value: "active"},
+	}}
+	filterA, err := a.ConvertToMongoFilter(WithCanonicalize())
+	require.NoError(t, err)
+	filterB, err := b.ConvertToMongoFilter(WithCanonicalize())
+	require.NoError(t, err)
+	assert.Equal(t, filterA, filterB)
+	assert.Equal(t, bson.M{"$or": []bson.M{
+		{"$and": []bson.M{{"age": bson.M{"$gt": 18}}, {"name": "foo"}}},
+		{"status": "active"},
+	}}, filterA)
+
+	// the column across the or boundary is not moved
+	c := &Params{Columns: []Column{
+		{Name: "status", Value: "active", Logic: "or"},
+		{Name: "age", Exp: ">", Value: 18},
+		{Name: "name", Value: "foo"},
+	}}
+	filterC, err := c.ConvertToMongoFilter(WithCanonicalize())
+	require.NoError(t, err)
+	assert.NotEqual(t, filterA, filterC)
+
+	// the error of the canonical columns
+	_, err = (&Params{Columns: []Column{{Name: "name", Value: "foo"}, {Name: "$where", Value: "1"}}}).
+		ConvertToMongoFilter(WithCanonicalize())
+	var e *Error
+	require.ErrorAs(t, err, &e)
+	assert.Equal(t, 0, e.Index)
+}
+
+// the property tests, the canonical columns match the same documents of the fixture dataset as the original
+// columns, and the canonicalization is idempotent
+func TestCanonicalize_Semantics(t *testing.T) {
+	r := rand.New(rand.NewSource(20241016))
+	for i := 0; i < 2000; i++ {
+		columns := randomColumns(r, i%2 == 1)
+		p := &Params{Columns: columns}
+		filter, err := p.ConvertToMongoFilter()
+		require.NoError(t, err, columns)
+		canonicalFilter, err := p.ConvertToMongoFilter(WithCanonicalize())
+		require.NoError(t, err, columns)
+
+		assert.Equal(t, matchIDs(filter), matchIDs(canonicalFilter), "%+v\n%v\n%v", columns, filter, canonicalFilter)
+		canonical := Canonicalize(columns)
+		assert.Equal(t, canonical, Canonicalize(canonical), columns)
+	}
+}
+
+// the property tests, the columns shuffled between the or boundaries are converted to the same filter
+func TestCanonicalize_Permutation(t *testing.T) {
+	r := rand.New(rand.NewSource(20241017))
+	for i := 0; i < 1000; i++ {
+		columns := randomColumns(r, false)
+		shuffled := make([]Column, len(columns))
+		copy(shuffled, columns)
+
+		// the columns are shuffled in the runs joined by and, the logics are kept by position
+		start := 0
+		for j := range shuffled {
+			if j < len(shuffled)-1 && canonicalLogic(shuffled[j].Logic) != OR {
+				continue
+			}
+			run := shuffled[start : j+1]
+			r.Shuffle(len(run), func(x, y int) {
+				run[x].Logic, run[y].Logic = run[y].Logic, run[x].Logic
+				run[x], run[y] = run[y], run[x]
+			})
+			start = j + 1
+		}
+
+		want, err := (&Params{Columns: columns}).ConvertToMongoFilter(WithCanonicalize())
+		require.NoError(t, err)
+		got, err := (&Params{Columns: shuffled}).ConvertToMongoFilter(WithCanonicalize())
+		require.NoError(t, err)
+		assert.Equal(t, want, got, "%+v\n%+v", columns, shuffled)
+	}
+}
@@ -0,0 +1,273 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// the expressions of the mongo operators in the description
+var operatorExps = map[string]string{
+	"$eq":     "=",
+	"$ne":     "!=",
+	"$gt":     ">",
+	"$gte":    ">=",
+	"$lt":     "<",
+	"$lte":    "<=",
+	"$regex":  "LIKE",
+	"$in":     "IN",
+	"$nin":    "NOT IN",
+	"$exists": "EXISTS",
+	"$all":    "ALL",
+}
+
+// filterNode the internal representation of the filter, both the mongo filter and the description are
+// generated from it, so they never disagree.
+type filterNode struct {
+	logic    string // "$and" or "$or" of the group, empty means a column
+	children []*filterNode
+
+	name  string      // column name
+	value interface{} // mongo value of the column, e.g. 18, bson.M{"$gte": 18}
+}
+
+// node the filter node of the converted column
+func (c *Column) node() *filterNode {
+	return &filterNode{name: c.Name, value: c.Value}
+}
+
+func (n *filterNode) toMongo() bson.M {
+	if n == nil {
+		return bson.M{}
+	}
+	if n.logic == "" {
+		return bson.M{n.name: n.value}
+	}
+	conditions := make([]bson.M, 0, len(n.children))
+	for _, child := range n.children {
+		conditions = append(conditions, child.toMongo())
+	}
+	return bson.M{n.logic: conditions}
+}
+
+func (n *filterNode) describe(isNested bool) string {
+	if n == nil {
+		return ""
+	}
+	if n.logic == "" {
+		return describeColumn(n.name, n.value)
+	}
+
+	sep := " AND "
+	if n.logic == "$or" {
+		sep = " OR "
+	}
+	parts := make([]string, 0, len(n.children))
+	for _, child := range n.children {
+		parts = append(parts, child.describe(true))
+	}
+	s := strings.Join(parts, sep)
+	if isNested && len(parts) > 1 {
+		s = "(" + s + ")"
+	}
+	return s
+}
+
+func describeColumn(name string, value interface{}) string {
+	if name == "$expr" {
+		if s, ok := describeExpr(value); ok {
+			return s
+		}
+	}
+	if m, ok := toBsonM(value); ok {
+		if regex, ok := m["$regex"]; ok {
+			return fmt.Sprintf("%s LIKE %s", name, describeValue(regex))
+		}
+		if regex, ok := m["$not"].(primitive.Regex); ok {
+			return fmt.Sprintf("%s NOT LIKE %s", name, regex.Pattern)
+		}
+		if inner, ok := toBsonM(m["$elemMatch"]); ok && len(m) == 1 {
+			return fmt.Sprintf("%s ELEM MATCH (%s)", name, nodeFromMongo(inner).describe(false))
+		}
+		if isNullValue(m, "$in") {
+			return name + " IS NULL"
+		}
+		if isNullValue(m, "$ne") {
+			return name + " IS NOT NULL"
+		}
+		if len(m) == 2 && m["$gte"] != nil && m["$lte"] != nil {
+			return fmt.Sprintf("%s BETWEEN %s AND %s", name, describeValue(m["$gte"]), describeValue(m["$lte"]))
+		}
+		if len(m) == 1 {
+			for op, v := range m {
+				if exp, ok := operatorExps[op]; ok {
+					return fmt.Sprintf("%s %s %s", name, exp, describeValue(v))
+				}
+			}
+		}
+	}
+	return fmt.Sprintf("%s = %s", name, describeValue(value))
+}
+
+// the value of isnull and notnull, {$in: [null]} or {$ne: null}
+func isNullValue(m bson.M, op string) bool {
+	v, ok := m[op]
+	if !ok || len(m) != 1 {
+		return false
+	}
+	if op == "$ne" {
+		return v == nil
+	}
+	switch values := v.(type) {
+	case []interface{}:
+		return len(values) == 1 && values[0] == nil
+	case primitive.A:
+		return len(values) == 1 && values[0] == nil
+	}
+	return false
+}
+
+// the $expr of ieq and ineq, e.g. {$eq: [{$toLower: "$name"}, {$literal: "foo"}]} is described as lower(name) = foo
+func describeExpr(value interface{}) (string, bool) {
+	m, ok := toBsonM(value)
+	if !ok || len(m) != 1 {
+		return "", false
+	}
+	for op, v := range m {
+		var args []interface{}
+		switch a := v.(type) {
+		case []interface{}:
+			args = a
+		case primitive.A:
+			args = a
+		}
+		exp, ok := operatorExps[op]
+		if !ok || len(args) != 2 {
+			return "", false
+		}
+		field, ok := toBsonM(args[0])
+		if !ok {
+			return "", false
+		}
+		name, ok := field["$toLower"].(string)
+		if !ok {
+			return "", false
+		}
+		literal := args[1]
+		if lm, ok := toBsonM(literal); ok {
+			literal = lm["$literal"]
+		}
+		return fmt.Sprintf("lower(%s) %s %s", strings.TrimPrefix(name, "$"), exp, describeValue(literal)), true
+	}
+	return "", false
+}
+
+func describeValue(value interface{}) string {
+	switch v := value.(type) {
+	case primitive.ObjectID:
+		return fmt.Sprintf("ObjectID(%s)", v.Hex())
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case []interface{}:
+		return describeValues(v)
+	case primitive.A:
+		return describeValues(v)
+	case []string:
+		values := make([]interface{}, 0, len(v))
+		for _, s := range v {
+			values = append(values, s)
+		}
+		return describeValues(values)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func describeValues(values []interface{}) string {
+	ss := make([]string, 0, len(values))
+	for _, v := range values {
+		ss = append(ss, describeValue(v))
+	}
+	return "[" + strings.Join(ss, ", ") + "]"
+}
+
+func toBsonM(v interface{}) (bson.M, bool) {
+	switch m := v.(type) {
+	case bson.M:
+		return m, true
+	case map[string]interface{}:
+		return m, true
+	}
+	return nil, false
+}
+
+// DescribeFilter render the human-readable description of the columns with the same grouping logic as
+// ConvertToMongoFilter, e.g. (age >= 18 AND status IN [active, trial]) OR vip = true
+func DescribeFilter(columns []Column) string {
+	node, err := buildFilterNode(columns, defaultRulerOptions())
+	if err != nil {
+		return fmt.Sprintf("invalid filter: %v", err)
+	}
+	return node.describe(false)
+}
+
+// DescribeMongoFilter render the human-readable description of the mongo filter, e.g. the filter converted by
+// ConvertToMongoFilter, the fields of the same document are sorted by name and joined with AND
+func DescribeMongoFilter(filter bson.M) string {
+	return nodeFromMongo(filter).describe(false)
+}
+
+func nodeFromMongo(filter bson.M) *filterNode {
+	if len(filter) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(filter))
+	for k := range filter {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	nodes := []*filterNode{}
+	for _, key := range keys {
+		value := filter[key]
+		if key != "$and" && key != "$or" {
+			nodes = append(nodes, &filterNode{name: key, value: value})
+			continue
+		}
+		group := &filterNode{logic: key}
+		for _, cond := range toConditions(value) {
+			if child := nodeFromMongo(cond); child != nil {
+				group.children = append(group.children, child)
+			}
+		}
+		nodes = append(nodes, group)
+	}
+
+	if len(nodes) == 1 {
+		return nodes[0]
+	}
+	return &filterNode{logic: "$and", children: nodes}
+}
+
+func toConditions(value interface{}) []bson.M {
+	var values []interface{}
+	switch v := value.(type) {
+	case []bson.M:
+		return v
+	case []interface{}:
+		values = v
+	case primitive.A:
+		values = v
+	}
+	conditions := []bson.M{}
+	for _, v := range values {
+		if m, ok := toBsonM(v); ok {
+			conditions = append(conditions, m)
+		}
+	}
+	return conditions
+}
@@ -0,0 +1,86 @@
+package query
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestDescribeFilter(t *testing.T) {
+	columns := []Column{
+		{Name: "age", Exp: ">=", Value: 18},
+		{Name: "status", Exp: "in", Value: "active,trial", Logic: "||"},
+		{Name: "vip", Value: true},
+	}
+	columns[0].Logic = "&"
+	assert.Equal(t, "(age >= 18 AND status IN [active, trial]) OR vip = true", DescribeFilter(columns))
+	// the columns are not modified
+	assert.Equal(t, 18, columns[0].Value)
+	assert.Equal(t, "&", columns[0].Logic)
+
+	assert.Equal(t, "", DescribeFilter(nil))
+	assert.Contains(t, DescribeFilter([]Column{{Name: "age", Exp: "?", Value: 1}}), "invalid filter")
+
+	assert.Equal(t, "", DescribeMongoFilter(bson.M{}))
+	assert.Equal(t, "(vip = true OR score IN [1, 2]) AND age > 18 AND name = foo", DescribeMongoFilter(bson.M{
+		"name": "foo",
+		"age":  bson.M{"$gt": 18},
+		"$or":  bson.A{bson.M{"vip": true}, map[string]interface{}{"score": bson.M{"$in": bson.A{1, 2}}}},
+	}))
+}
+
+// the description of all the logic permutations of the columns, and the description of the converted mongo
+// filter must be the same
+func TestDescribeFilter_Golden(t *testing.T) {
+	allColumns := []Column{
+		{Name: "age", Exp: "gte", Value: 18},
+		{Name: "status", Exp: "in", Value: "active,trial"},
+		{Name: "vip", Value: true},
+		{Name: "name", Exp: "like", Value: "foo.bar"},
+		{Name: "id", Value: "65ce48483f11aff697e30d6d"},
+		{Name: "level", Exp: "nin", Value: "1,2"},
+	}
+
+	buf := &strings.Builder{}
+	for n := 1; n <= 5; n++ {
+		for mask := 0; mask < 1<<(n-1); mask++ {
+			columns := make([]Column, n)
+			copy(columns, allColumns[:n])
+			logics := make([]string, 0, n-1)
+			for i := 0; i < n-1; i++ {
+				columns[i].Logic = "and"
+				if mask&(1<<i) != 0 {
+					columns[i].Logic = "or"
+				}
+				logics = append(logics, columns[i].Logic)
+			}
+
+			desc := DescribeFilter(columns)
+			filter, err := (&Params{Columns: columns}).ConvertToMongoFilter()
+			require.NoError(t, err)
+			assert.Equal(t, desc, DescribeMongoFilter(filter), "logics %v", logics)
+			_, _ = fmt.Fprintf(buf, "columns=%d logics=[%s]\n%s\n\n", n, strings.Join(logics, " "), desc)
+		}
+	}
+	// the logic of the last column is ignored
+	_, _ = fmt.Fprintf(buf, "columns=3 logics=[or or and]\n%s\n", DescribeFilter([]Column{
+		{Name: "a", Value: 1, Logic: "or"}, {Name: "b", Value: 2, Logic: "or"}, {Name: "level", Exp: "nin", Value: "1,2", Logic: "and"}}))
+
+	golden := filepath.Join("testdata", "describe_filter.golden")
+	if *update {
+		require.NoError(t, os.MkdirAll("testdata", 0o755))
+		require.NoError(t, os.WriteFile(golden, []byte(buf.String()), 0o644))
+	}
+	want, err := os.ReadFile(golden)
+	require.NoError(t, err)
+	assert.Equal(t, string(want), buf.String())
+}
@@ -0,0 +1,79 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+)
+
+// the errors of the invalid conditions, they are wrapped by *Error, e.g. errors.Is(err, ErrNameNotAllowed),
+// the handlers can respond 400 for them, see IsInvalid
+var (
+	ErrEmptyColumns      = errors.New("empty columns")
+	ErrEmptyName         = errors.New("empty name")
+	ErrNilValue          = errors.New("nil value")
+	ErrNameNotAllowed    = errors.New("name not allowed")
+	ErrUnsupportedExp    = errors.New("unsupported exp")
+	ErrUnknownLogic      = errors.New("unknown logic")
+	ErrUnknownType       = errors.New("unknown type")
+	ErrInvalidGroup      = errors.New("invalid group")
+	ErrInvalidValue      = errors.New("invalid value")
+	ErrTooManyColumns    = errors.New("too many columns")
+	ErrTooManyValues     = errors.New("too many values")
+	ErrInvalidProjection = errors.New("invalid projection")
+	ErrUnknownAggregate  = errors.New("unknown aggregate func")
+	ErrInvalidAggregate  = errors.New("invalid aggregate")
+	ErrSortConflict      = errors.New("sort conflict")
+)
+
+// Error the error of the invalid conditions, it wraps one of the sentinel errors and the cause if there is one,
+// e.g. *ColumnError of the value which is not the expected type, so errors.Is and errors.As work for both.
+type Error struct {
+	Index int    // index of the column in the columns, -1 if the error is not of a column, e.g. ErrEmptyColumns
+	Name  string // name of the column, the name of the sub-column of elemmatch is prefixed by the column name
+	Err   error  // the sentinel error, e.g. ErrNameNotAllowed
+	Cause error  // the underlying error, nil if there is none
+
+	msg string
+}
+
+// Error returns the error message
+func (e *Error) Error() string {
+	return e.msg
+}
+
+// BadRequest the error is caused by the client, it is responded with 400, see response.BadRequestError
+func (e *Error) BadRequest() bool {
+	return true
+}
+
+// Unwrap returns the sentinel error and the cause
+func (e *Error) Unwrap() []error {
+	if e.Cause == nil {
+		return []error{e.Err}
+	}
+	return []error{e.Err, e.Cause}
+}
+
+// IsInvalid whether the error is caused by the invalid conditions of the client instead of the server, e.g.
+//
+//	filter, err := params.ConvertToMongoFilter(opts...)
+//	if query.IsInvalid(err) {
+//		response.Error(c, ecode.InvalidParams)
+//	}
+func IsInvalid(err error) bool {
+	var e *Error
+	return errors.As(err, &e)
+}
+
+func newError(sentinel error, name string, format string, args ...interface{}) *Error {
+	return &Error{Index: -1, Name: name, Err: sentinel, msg: fmt.Sprintf(format, args...)}
+}
+
+// set the index of the column of the error if it is not set, the error of the other type is returned as it is
+func withIndex(err error, index int) error {
+	var e *Error
+	if errors.As(err, &e) && e.Index < 0 {
+		e.Index = index
+	}
+	return err
+}
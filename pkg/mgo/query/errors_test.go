@@ -0,0 +1,157 @@
+package query
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParams_ConvertToMongoFilter_TypedError(t *testing.T) {
+	valid := Column{Name: "age", Value: 1}
+	tests := []struct {
+		name     string
+		columns  []Column
+		opts     []RulerOption
+		sentinel error
+		index    int
+		column   string
+		msg      string
+	}{
+		{"empty name", []Column{{Value: 1}}, nil, ErrEmptyName, 0, "", "field 'name' cannot be empty"},
+		{"nil value", []Column{valid, {Name: "name", Exp: "gt"}}, nil, ErrNilValue, 1, "name", "field 'value' cannot be nil"},
+		{"operator name", []Column{valid, valid, {Name: "$where", Value: 1}}, nil, ErrNameNotAllowed, 2, "$where", "field name '$where' is not allowed"},
+		{"not in whitelist", []Column{valid, {Name: "password", Value: 1}}, []RulerOption{WithWhitelistNames(map[string]bool{"age": true})},
+			ErrNameNotAllowed, 1, "password", "field name 'password' is not allowed"},
+		{"unsupported exp", []Column{{Name: "age", Exp: "foo", Value: 1}}, nil, ErrUnsupportedExp, 0, "age", "unsupported exp type 'foo'"},
+		{"regex not allowed", []Column{{Name: "name", Exp: "regex", Value: "^a"}}, []RulerOption{WithAllowRegexExp(false)},
+			ErrUnsupportedExp, 0, "name", "column 'name': exp type 'regex' is not allowed"},
+		{"unknown logic", []Column{valid, {Name: "name", Value: 1, Logic: "xor"}, valid}, nil, ErrUnknownLogic, 1, "name", "unknown logic type 'xor'"},
+		{"unknown type", []Column{{Name: "age", Value: 1, Type: "foo"}}, nil, ErrUnknownType, 0, "age", "column 'age': unknown type 'foo'"},
+		{"invalid group", []Column{valid, {Name: "name", Value: 1, Group: []int{1}}}, nil, ErrInvalidGroup, 1, "name",
+			"column 'name': invalid group '[1]', it should be an int or a string"},
+		{"document value", []Column{valid, valid, valid, {Name: "role", Value: map[string]interface{}{"$ne": 1}}}, nil,
+			ErrInvalidValue, 3, "role", "column 'role': the document value 'map[$ne:1]' is not allowed"},
+		{"invalid between", []Column{{Name: "age", Exp: "between", Value: "1"}}, nil, ErrInvalidValue, 0, "age",
+			"column 'age': between value '1' should contain exactly two parts"},
+		{"invalid regex", []Column{{Name: "name", Exp: "regex", Value: "("}}, nil, ErrInvalidValue, 0, "name", ""},
+		{"the index in the columns of the groups", []Column{{Name: "a", Value: 1, Group: 1}, {Name: "b", Value: 1},
+			{Name: "c", Value: 1, Group: 1}, {Name: "d", Exp: "foo", Value: 1, Group: 1}}, nil, ErrUnsupportedExp, 3, "d", ""},
+		{"the name of the sub-column", []Column{valid, {Name: "items", Exp: "elemmatch", Value: []Column{{Name: "qty", Value: 1, Type: "foo"}}}},
+			nil, ErrUnknownType, 1, "items.qty", "column 'qty': unknown type 'foo'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Params{Columns: tt.columns}
+			_, err := p.ConvertToMongoFilter(tt.opts...)
+			require.Error(t, err)
+			assert.ErrorIs(t, err, tt.sentinel)
+			assert.True(t, IsInvalid(err))
+			var e *Error
+			require.True(t, errors.As(err, &e))
+			assert.Equal(t, tt.index, e.Index)
+			assert.Equal(t, tt.column, e.Name)
+			if tt.msg != "" {
+				assert.Equal(t, tt.msg, err.Error())
+			}
+
+			// the same error by Conditions
+			c := &Conditions{Columns: tt.columns}
+			_, err = c.ConvertToMongo(tt.opts...)
+			assert.ErrorIs(t, err, tt.sentinel)
+		})
+	}
+
+	// the cause of the value is wrapped
+	p := &Params{Columns: []Column{valid, {Name: "age", Exp: "in", Value: "1,x", Type: TypeInt}}}
+	_, err := p.ConvertToMongoFilter()
+	assert.ErrorIs(t, err, ErrInvalidValue)
+	var columnErr *ColumnError
+	require.True(t, errors.As(err, &columnErr))
+	assert.Equal(t, "x", columnErr.Value)
+	var e *Error
+	require.True(t, errors.As(err, &e))
+	assert.Equal(t, 1, e.Index)
+
+	// the error of the validate function is returned as it is
+	errValidate := errors.New("validate error")
+	_, err = p.ConvertToMongoFilter(WithValidateFn(func(columns []Column) error { return errValidate }))
+	assert.Equal(t, errValidate, err)
+	assert.False(t, IsInvalid(err))
+	assert.False(t, IsInvalid(nil))
+}
+
+func TestConditions_CheckValid_Error(t *testing.T) {
+	tests := []struct {
+		name     string
+		columns  []Column
+		sentinel error
+		index    int
+	}{
+		{"empty columns", nil, ErrEmptyColumns, -1},
+		{"empty name", []Column{{Name: "age", Value: 1}, {Value: 1}}, ErrEmptyName, 1},
+		{"nil value", []Column{{Name: "age", Exp: "lt"}}, ErrNilValue, 0},
+		{"unknown exp", []Column{{Name: "age", Value: 1}, {Name: "age", Value: 1}, {Name: "age", Exp: "foo", Value: 1}}, ErrUnsupportedExp, 2},
+		{"unknown logic", []Column{{Name: "age", Value: 1, Logic: "xor"}}, ErrUnknownLogic, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Conditions{Columns: tt.columns}
+			err := c.CheckValid()
+			assert.ErrorIs(t, err, tt.sentinel)
+			var e *Error
+			require.True(t, errors.As(err, &e))
+			assert.Equal(t, tt.index, e.Index)
+		})
+	}
+}
+
+func TestParams_ConvertToMongoFilter_Limits(t *testing.T) {
+	columns := func(n int) []Column {
+		cs := make([]Column, n)
+		for i := range cs {
+			cs[i] = Column{Name: "age", Value: i}
+		}
+		return cs
+	}
+	values := func(n int) []int {
+		vs := make([]int, n)
+		for i := range vs {
+			vs[i] = i
+		}
+		return vs
+	}
+
+	// default limits
+	_, err := (&Params{Columns: columns(20)}).ConvertToMongoFilter()
+	assert.NoError(t, err)
+	_, err = (&Params{Columns: columns(21)}).ConvertToMongoFilter()
+	assert.ErrorIs(t, err, ErrTooManyColumns)
+	assert.True(t, IsInvalid(err))
+	_, err = (&Params{Columns: []Column{{Name: "age", Exp: In, Value: values(1000)}}}).ConvertToMongoFilter()
+	assert.NoError(t, err)
+	_, err = (&Params{Columns: []Column{{Name: "age", Value: 1}, {Name: "age", Exp: "NIN", Value: values(1001)}}}).ConvertToMongoFilter()
+	assert.ErrorIs(t, err, ErrTooManyValues)
+	var e *Error
+	require.True(t, errors.As(err, &e))
+	assert.Equal(t, 1, e.Index)
+
+	// custom limits, the values of the string are counted by the commas
+	_, err = (&Params{Columns: columns(3)}).ConvertToMongoFilter(WithMaxColumns(2))
+	assert.ErrorIs(t, err, ErrTooManyColumns)
+	_, err = (&Params{Columns: []Column{{Name: "tags", Exp: All, Value: "a,b,c"}}}).ConvertToMongoFilter(WithMaxInValues(2))
+	assert.ErrorIs(t, err, ErrTooManyValues)
+	_, err = (&Params{Columns: []Column{{Name: "tags", Exp: All, Value: "a,b"}}}).ConvertToMongoFilter(WithMaxInValues(2))
+	assert.NoError(t, err)
+
+	// the sub-columns of elemmatch
+	_, err = (&Params{Columns: []Column{{Name: "items", Exp: ElemMatch, Value: []Column{
+		{Name: "qty", Exp: In, Value: values(3)},
+	}}}}).ConvertToMongoFilter(WithMaxInValues(2))
+	assert.ErrorIs(t, err, ErrTooManyValues)
+
+	// 0 means unlimited
+	_, err = (&Params{Columns: append(columns(30), Column{Name: "age", Exp: In, Value: values(2000)})}).ConvertToMongoFilter(WithMaxColumns(0), WithMaxInValues(0))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,182 @@
+package query
+
+import (
+	"encoding/json"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// the functions of Aggregate
+const (
+	AggregateCount = "count"
+	AggregateSum   = "sum"
+	AggregateAvg   = "avg"
+	AggregateMin   = "min"
+	AggregateMax   = "max"
+)
+
+var aggregateOperators = map[string]string{
+	AggregateCount: "$sum",
+	AggregateSum:   "$sum",
+	AggregateAvg:   "$avg",
+	AggregateMin:   "$min",
+	AggregateMax:   "$max",
+}
+
+// GroupBy the fields of the group key, it is unmarshalled from a string separated by comma or a list of strings,
+// e.g. "status", "status,type" or ["status", "type"]
+type GroupBy []string
+
+// UnmarshalJSON unmarshal the string or the list of strings
+func (g *GroupBy) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*g = nil
+		for _, name := range strings.Split(strings.Replace(s, " ", "", -1), ",") {
+			if name != "" {
+				*g = append(*g, name)
+			}
+		}
+		return nil
+	}
+
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+	*g = names
+	return nil
+}
+
+// Aggregate the accumulator of the group, Func is one of count, sum, avg, min and max, Field is ignored by count.
+// As is the name of the output field, the default is "count" for count, otherwise func_field, e.g. sum_amount.
+type Aggregate struct {
+	Field string `json:"field,omitempty" form:"field"`
+	Func  string `json:"func" form:"func"`
+	As    string `json:"as,omitempty" form:"as"`
+}
+
+// AggregateParams the parameters of the reporting queries, the documents matching Columns are grouped by GroupBy
+// and accumulated by Aggregates, e.g. the count of orders per status where created_at > X
+type AggregateParams struct {
+	Columns    []Column    `json:"columns,omitempty" form:"columns"`
+	GroupBy    GroupBy     `json:"groupBy,omitempty" form:"groupBy"`
+	Aggregates []Aggregate `json:"aggregates,omitempty" form:"aggregates"`
+}
+
+// ConvertToGroupPipeline converted to the aggregation pipeline, $match of the columns and $group, the columns are
+// validated by the options in the same way as ConvertToMongoFilter, the fields of GroupBy and Aggregates are
+// converted by the field name converter and checked by the whitelist.
+// The _id of the group is the value of the field if there is one field of GroupBy, the document of the fields if
+// there are more, the dots of the fields are replaced by underscores, e.g. profile.city to profile_city, and it is
+// null if GroupBy is empty, e.g.
+//
+//	// {"columns": [{"name": "created_at", "exp": ">", "value": "2024-01-01 00:00:00"}], "groupBy": "status", "aggregates": [{"func": "count"}]}
+//	pipeline, err := params.ConvertToGroupPipeline(query.WithWhitelistNames(names))
+//	cursor, err := collection.Aggregate(ctx, pipeline) // e.g. {_id: "paid", count: 3}
+func (a *AggregateParams) ConvertToGroupPipeline(opts ...RulerOption) (mongo.Pipeline, error) {
+	o := defaultRulerOptions()
+	o.apply(opts...)
+
+	filter, err := (&Params{Columns: a.Columns}).convertToMongoFilter(o)
+	if err != nil {
+		return nil, err
+	}
+	id, err := a.groupID(o)
+	if err != nil {
+		return nil, err
+	}
+	group := bson.D{{Key: oidName, Value: id}}
+	accumulators, err := a.accumulators(o)
+	if err != nil {
+		return nil, err
+	}
+	group = append(group, accumulators...)
+
+	return mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: group}},
+	}, nil
+}
+
+func (a *AggregateParams) groupID(o *rulerOptions) (interface{}, error) {
+	if o.maxColumns > 0 && len(a.GroupBy) > o.maxColumns {
+		return nil, newError(ErrTooManyColumns, "", "the number of group by fields %d exceeds the limit %d", len(a.GroupBy), o.maxColumns)
+	}
+
+	id := make(bson.D, 0, len(a.GroupBy))
+	seen := make(map[string]bool, len(a.GroupBy))
+	for _, name := range a.GroupBy {
+		field, err := o.aggregateField(name, "group by")
+		if err != nil {
+			return nil, err
+		}
+		if seen[field] {
+			continue
+		}
+		seen[field] = true
+		id = append(id, bson.E{Key: strings.Replace(field, ".", "_", -1), Value: "$" + field})
+	}
+
+	switch len(id) {
+	case 0:
+		return nil, nil
+	case 1:
+		return id[0].Value, nil
+	}
+	return id, nil
+}
+
+func (a *AggregateParams) accumulators(o *rulerOptions) (bson.D, error) {
+	if o.maxColumns > 0 && len(a.Aggregates) > o.maxColumns {
+		return nil, newError(ErrTooManyColumns, "", "the number of aggregates %d exceeds the limit %d", len(a.Aggregates), o.maxColumns)
+	}
+
+	accumulators := make(bson.D, 0, len(a.Aggregates))
+	names := make(map[string]bool, len(a.Aggregates))
+	for _, agg := range a.Aggregates {
+		fn := strings.ToLower(strings.TrimSpace(agg.Func))
+		operator, ok := aggregateOperators[fn]
+		if !ok {
+			return nil, newError(ErrUnknownAggregate, agg.Field, "unknown aggregate func '%s'", agg.Func)
+		}
+
+		var value interface{} = 1
+		as := AggregateCount
+		if fn != AggregateCount {
+			field, err := o.aggregateField(agg.Field, "aggregate")
+			if err != nil {
+				return nil, err
+			}
+			value = "$" + field
+			as = fn + "_" + strings.Replace(field, ".", "_", -1)
+		}
+		if agg.As != "" {
+			as = agg.As
+		}
+		if as == oidName || strings.Contains(as, ".") || strings.HasPrefix(as, "$") {
+			return nil, newError(ErrInvalidAggregate, agg.Field, "aggregate output field '%s' is not allowed", as)
+		}
+		if names[as] {
+			return nil, newError(ErrInvalidAggregate, agg.Field, "aggregate output field '%s' is duplicated", as)
+		}
+		names[as] = true
+		accumulators = append(accumulators, bson.E{Key: as, Value: bson.D{{Key: operator, Value: value}}})
+	}
+	return accumulators, nil
+}
+
+// the field of the group is converted by name and checked in the same way as the names of the columns
+func (o *rulerOptions) aggregateField(name string, kind string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", newError(ErrEmptyName, name, "%s field cannot be empty", kind)
+	}
+	name = o.fieldName(name)
+	if (o.whitelistNames != nil && !o.whitelistNames[name]) || isOperatorName(name) {
+		return "", newError(ErrNameNotAllowed, name, "%s field '%s' is not allowed", kind, name)
+	}
+	return name, nil
+}
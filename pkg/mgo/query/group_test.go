@@ -0,0 +1,113 @@
+package query
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestGroupBy_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		data string
+		want GroupBy
+	}{
+		{`{"groupBy": "status"}`, GroupBy{"status"}},
+		{`{"groupBy": "status, type,"}`, GroupBy{"status", "type"}},
+		{`{"groupBy": ["status", "type"]}`, GroupBy{"status", "type"}},
+		{`{"groupBy": ""}`, nil},
+		{`{}`, nil},
+	}
+	for _, tt := range tests {
+		params := &AggregateParams{}
+		require.NoError(t, json.Unmarshal([]byte(tt.data), params), tt.data)
+		assert.Equal(t, tt.want, params.GroupBy, tt.data)
+	}
+
+	assert.Error(t, json.Unmarshal([]byte(`{"groupBy": 1}`), &AggregateParams{}))
+}
+
+func TestAggregateParams_ConvertToGroupPipeline(t *testing.T) {
+	whitelist := WithWhitelistNames(map[string]bool{"status": true, "type": true, "amount": true, "created_at": true, "profile.city": true})
+
+	// count of orders per status where created_at > X
+	params := &AggregateParams{}
+	err := json.Unmarshal([]byte(`{
+		"columns": [{"name": "created_at", "exp": ">", "value": 100}],
+		"groupBy": "status",
+		"aggregates": [{"func": "count"}, {"func": "SUM", "field": "amount"}, {"func": "avg", "field": "amount", "as": "avgAmount"}]
+	}`), params)
+	require.NoError(t, err)
+	pipeline, err := params.ConvertToGroupPipeline(whitelist)
+	require.NoError(t, err)
+	assert.Equal(t, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"created_at": bson.M{"$gt": float64(100)}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$status"},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "sum_amount", Value: bson.D{{Key: "$sum", Value: "$amount"}}},
+			{Key: "avgAmount", Value: bson.D{{Key: "$avg", Value: "$amount"}}},
+		}}},
+	}, pipeline)
+
+	// the document of the fields, the fields are converted by name
+	params = &AggregateParams{
+		GroupBy:    GroupBy{"status", "profile.city", "status"},
+		Aggregates: []Aggregate{{Func: "min", Field: "createdAt"}, {Func: "max", Field: "amount"}},
+	}
+	pipeline, err = params.ConvertToGroupPipeline(whitelist, WithFieldNameConverter(CamelToSnake))
+	require.NoError(t, err)
+	assert.Equal(t, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{{Key: "status", Value: "$status"}, {Key: "profile_city", Value: "$profile.city"}}},
+			{Key: "min_created_at", Value: bson.D{{Key: "$min", Value: "$created_at"}}},
+			{Key: "max_amount", Value: bson.D{{Key: "$max", Value: "$amount"}}},
+		}}},
+	}, pipeline)
+
+	// the whole collection
+	pipeline, err = (&AggregateParams{Aggregates: []Aggregate{{Func: "count"}}}).ConvertToGroupPipeline()
+	require.NoError(t, err)
+	assert.Equal(t, bson.D{{Key: "_id", Value: nil}, {Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}}}, pipeline[1][0].Value)
+}
+
+func TestAggregateParams_ConvertToGroupPipeline_Error(t *testing.T) {
+	whitelist := WithWhitelistNames(map[string]bool{"status": true, "amount": true})
+
+	tests := []struct {
+		name    string
+		params  *AggregateParams
+		wantErr error
+	}{
+		{"group by not allowed", &AggregateParams{GroupBy: GroupBy{"password"}}, ErrNameNotAllowed},
+		{"group by empty", &AggregateParams{GroupBy: GroupBy{" "}}, ErrEmptyName},
+		{"aggregate field not allowed", &AggregateParams{Aggregates: []Aggregate{{Func: "sum", Field: "salary"}}}, ErrNameNotAllowed},
+		{"aggregate field empty", &AggregateParams{Aggregates: []Aggregate{{Func: "sum"}}}, ErrEmptyName},
+		{"unknown func", &AggregateParams{Aggregates: []Aggregate{{Func: "median", Field: "amount"}}}, ErrUnknownAggregate},
+		{"empty func", &AggregateParams{Aggregates: []Aggregate{{Field: "amount"}}}, ErrUnknownAggregate},
+		{"duplicated output", &AggregateParams{Aggregates: []Aggregate{{Func: "count"}, {Func: "sum", Field: "amount", As: "count"}}}, ErrInvalidAggregate},
+		{"output _id", &AggregateParams{Aggregates: []Aggregate{{Func: "count", As: "_id"}}}, ErrInvalidAggregate},
+		{"output operator", &AggregateParams{Aggregates: []Aggregate{{Func: "count", As: "$where"}}}, ErrInvalidAggregate},
+		{"column not allowed", &AggregateParams{Columns: []Column{{Name: "password", Value: "x"}}}, ErrNameNotAllowed},
+	}
+	for _, tt := range tests {
+		pipeline, err := tt.params.ConvertToGroupPipeline(whitelist)
+		assert.ErrorIs(t, err, tt.wantErr, tt.name)
+		assert.True(t, IsInvalid(err), tt.name)
+		assert.Nil(t, pipeline, tt.name)
+	}
+
+	// the operator names are rejected without the whitelist
+	_, err := (&AggregateParams{GroupBy: GroupBy{"$where"}}).ConvertToGroupPipeline()
+	assert.ErrorIs(t, err, ErrNameNotAllowed)
+	_, err = (&AggregateParams{Aggregates: []Aggregate{{Func: "max", Field: "profile.$gt"}}}).ConvertToGroupPipeline()
+	assert.ErrorIs(t, err, ErrNameNotAllowed)
+	_, err = (&AggregateParams{GroupBy: GroupBy{"a", "b", "c"}}).ConvertToGroupPipeline(WithMaxColumns(2))
+	assert.ErrorIs(t, err, ErrTooManyColumns)
+	_, err = (&AggregateParams{Aggregates: []Aggregate{{Func: "count"}, {Func: "sum", Field: "a"}, {Func: "avg", Field: "a"}}}).ConvertToGroupPipeline(WithMaxColumns(2))
+	assert.ErrorIs(t, err, ErrTooManyColumns)
+}
@@ -0,0 +1,110 @@
+package query
+
+import (
+	"strings"
+)
+
+// MergeAnd merge the conditions a and b with and, e.g. the conditions of the request and the conditions added by
+// the server. The and binds tighter than the or in the columns, e.g. x | y & z is x | (y & z), so if a or b has
+// the or logic, the result is distributed to keep the precedence, e.g. (x | y) & z is merged to x & z | y & z.
+// The columns of the same group are kept together as one column, and the groups of the result are renumbered.
+// The last column logic of a is fixed to and, the nil or empty conditions are ignored, the result is validated by
+// CheckValid and ConvertToMongo in the same way as the other conditions.
+func MergeAnd(a, b *Conditions) *Conditions {
+	aColumns, bColumns := getColumns(a), getColumns(b)
+	if len(aColumns) == 0 || len(bColumns) == 0 {
+		columns := make([]Column, 0, len(aColumns)+len(bColumns))
+		columns = append(columns, aColumns...)
+		return &Conditions{Columns: append(columns, bColumns...)}
+	}
+
+	aGroups, bGroups := splitOrGroups(splitGroups(aColumns)), splitOrGroups(splitGroups(bColumns))
+	groups := make([][][]Column, 0, len(aGroups)*len(bGroups))
+	for _, aGroup := range aGroups {
+		// the copy of the last group of a joined to b by and
+		seam := make([]Column, len(aGroup[len(aGroup)-1]))
+		copy(seam, aGroup[len(aGroup)-1])
+		seam[len(seam)-1].Logic = AND
+		for _, bGroup := range bGroups {
+			group := make([][]Column, 0, len(aGroup)+len(bGroup))
+			group = append(group, aGroup[:len(aGroup)-1]...)
+			group = append(group, seam)
+			group = append(group, bGroup...)
+			groups = append(groups, group)
+		}
+	}
+	return &Conditions{Columns: joinOrGroups(groups)}
+}
+
+// MergeOr merge the conditions a and b with or, the last column logic of a is fixed to or, the or has the
+// lowest precedence, so the groups of a and b are kept, e.g. x & y | z is merged to x & y | z.
+// The nil or empty conditions are ignored.
+func MergeOr(a, b *Conditions) *Conditions {
+	aColumns, bColumns := getColumns(a), getColumns(b)
+	if len(aColumns) == 0 || len(bColumns) == 0 {
+		columns := make([]Column, 0, len(aColumns)+len(bColumns))
+		columns = append(columns, aColumns...)
+		return &Conditions{Columns: append(columns, bColumns...)}
+	}
+
+	// the groups of a and b are renumbered, so the same group ids of a and b are not mixed
+	groups := [][][]Column{splitGroups(aColumns), splitGroups(bColumns)}
+	return &Conditions{Columns: joinOrGroups(groups)}
+}
+
+func getColumns(c *Conditions) []Column {
+	if c == nil {
+		return nil
+	}
+	return c.Columns
+}
+
+// split the groups of the columns to the groups joined by or, the logic of a group is the logic of its last
+// column, the logic of the last group is ignored, the unknown logic is kept and reported by the validation
+func splitOrGroups(groups [][]Column) [][][]Column {
+	var orGroups [][][]Column
+	start := 0
+	for i := 0; i < len(groups)-1; i++ {
+		last := groups[i][len(groups[i])-1]
+		if logicMap[strings.ToLower(last.Logic)] == orSymbol1 {
+			orGroups = append(orGroups, groups[start:i+1])
+			start = i + 1
+		}
+	}
+	if start < len(groups) {
+		orGroups = append(orGroups, groups[start:])
+	}
+	return orGroups
+}
+
+// join the or groups with or to the new columns, the columns of the groups are not modified, and the groups of
+// more than one column or with a group id are renumbered in the order of the result
+func joinOrGroups(orGroups [][][]Column) []Column {
+	l := 0
+	for _, orGroup := range orGroups {
+		for _, group := range orGroup {
+			l += len(group)
+		}
+	}
+
+	columns := make([]Column, 0, l)
+	groupID := 0
+	for i, orGroup := range orGroups {
+		for _, group := range orGroup {
+			start := len(columns)
+			columns = append(columns, group...)
+			if len(group) > 1 || group[0].Group != nil {
+				if _, err := group[0].groupKey(); err == nil {
+					groupID++
+					for k := start; k < len(columns); k++ {
+						columns[k].Group = groupID
+					}
+				}
+			}
+		}
+		if i < len(orGroups)-1 {
+			columns[len(columns)-1].Logic = OR
+		}
+	}
+	return columns
+}
@@ -0,0 +1,132 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestMergeAnd(t *testing.T) {
+	// a ends with an explicit or
+	a := &Conditions{Columns: []Column{
+		{Name: "age", Exp: ">=", Value: 18},
+		{Name: "gender", Value: 1, Logic: "or"},
+	}}
+	b := &Conditions{Columns: []Column{{Name: "status", Value: 2}}}
+	c := MergeAnd(a, b)
+	assert.Equal(t, "or", a.Columns[1].Logic) // a is not modified
+	assert.NoError(t, c.CheckValid())
+	got, err := c.ConvertToMongo()
+	assert.NoError(t, err)
+	want := bson.M{"$and": []bson.M{{"age": bson.M{"$gte": 18}}, {"gender": 1}, {"status": 2}}}
+	assert.Equal(t, want, got)
+	assert.Equal(t, "age >= 18 AND gender = 1 AND status = 2", DescribeFilter(c.Columns))
+
+	// a has or in the middle, the precedence is kept by distributing
+	a = &Conditions{Columns: []Column{
+		{Name: "age", Exp: ">=", Value: 18, Logic: "||"},
+		{Name: "vip", Value: true},
+	}}
+	b = &Conditions{Columns: []Column{
+		{Name: "status", Value: 2, Logic: "&"},
+		{Name: "tenant", Value: "t1"},
+	}}
+	c = MergeAnd(a, b)
+	assert.Len(t, c.Columns, 6)
+	assert.Equal(t, "(age >= 18 AND status = 2 AND tenant = t1) OR (vip = true AND status = 2 AND tenant = t1)",
+		DescribeFilter(c.Columns))
+
+	// both sides have or
+	b = &Conditions{Columns: []Column{
+		{Name: "status", Value: 2, Logic: "|"},
+		{Name: "status", Value: 3},
+	}}
+	c = MergeAnd(a, b)
+	assert.Len(t, c.Columns, 8)
+	assert.Equal(t, "(age >= 18 AND status = 2) OR (age >= 18 AND status = 3) OR (vip = true AND status = 2) OR (vip = true AND status = 3)",
+		DescribeFilter(c.Columns))
+
+	// either side is empty
+	c = MergeAnd(a, nil)
+	assert.Equal(t, a.Columns, c.Columns)
+	c = MergeAnd(&Conditions{}, b)
+	assert.Equal(t, b.Columns, c.Columns)
+	c = MergeAnd(nil, nil)
+	assert.Error(t, c.CheckValid())
+
+	// the invalid logic is reported by the validation of the merged result
+	a = &Conditions{Columns: []Column{{Name: "age", Value: 18, Logic: "xor"}, {Name: "vip", Value: true}}}
+	c = MergeAnd(a, b)
+	assert.Error(t, c.CheckValid())
+	_, err = c.ConvertToMongo()
+	assert.Error(t, err)
+}
+
+func TestMergeOr(t *testing.T) {
+	a := &Conditions{Columns: []Column{
+		{Name: "age", Exp: ">=", Value: 18, Logic: "&"},
+		{Name: "vip", Value: true}, // the last logic defaults to and
+	}}
+	b := &Conditions{Columns: []Column{
+		{Name: "status", Value: 2, Logic: "and"},
+		{Name: "tenant", Value: "t1"},
+	}}
+	c := MergeOr(a, b)
+	assert.Equal(t, "", a.Columns[1].Logic) // a is not modified
+	assert.NoError(t, c.CheckValid())
+	got, err := c.ConvertToMongo()
+	assert.NoError(t, err)
+	want := bson.M{"$or": []bson.M{
+		{"$and": []bson.M{{"age": bson.M{"$gte": 18}}, {"vip": true}}},
+		{"$and": []bson.M{{"status": 2}, {"tenant": "t1"}}},
+	}}
+	assert.Equal(t, want, got)
+
+	// a ends with an explicit or
+	a.Columns[1].Logic = "or"
+	c = MergeOr(a, &Conditions{Columns: []Column{{Name: "status", Value: 2}}})
+	assert.Equal(t, "(age >= 18 AND vip = true) OR status = 2", DescribeFilter(c.Columns))
+
+	// either side is empty
+	c = MergeOr(a, nil)
+	assert.Equal(t, a.Columns, c.Columns)
+	c = MergeOr(&Conditions{}, b)
+	assert.Equal(t, b.Columns, c.Columns)
+	c = MergeOr(nil, nil)
+	assert.Error(t, c.CheckValid())
+}
+
+func TestMerge_Group(t *testing.T) {
+	// a and (b or c) merged with (b or c) of the same group id
+	a := &Conditions{Columns: []Column{
+		{Name: "a", Value: 1},
+		{Name: "b", Value: 2, Logic: "or", Group: 1},
+		{Name: "c", Value: 3, Group: 1},
+	}}
+	b := &Conditions{Columns: []Column{
+		{Name: "d", Value: 4, Logic: "or", Group: 1},
+		{Name: "e", Value: 5, Group: 1},
+	}}
+	c := MergeAnd(a, b)
+	assert.Equal(t, 1, a.Columns[1].Group) // a is not modified
+	assert.Equal(t, "a = 1 AND (b = 2 OR c = 3) AND (d = 4 OR e = 5)", DescribeFilter(c.Columns))
+	c = MergeOr(a, b)
+	assert.Equal(t, "(a = 1 AND (b = 2 OR c = 3)) OR (d = 4 OR e = 5)", DescribeFilter(c.Columns))
+
+	// the group is distributed as one column
+	a = &Conditions{Columns: []Column{
+		{Name: "a", Value: 1, Logic: "or"},
+		{Name: "b", Value: 2, Logic: "or", Group: "x"},
+		{Name: "c", Value: 3, Group: "x"},
+	}}
+	b = &Conditions{Columns: []Column{{Name: "tenant", Value: "t1"}}}
+	c = MergeAnd(a, b)
+	assert.Equal(t, "(a = 1 AND tenant = t1) OR ((b = 2 OR c = 3) AND tenant = t1)", DescribeFilter(c.Columns))
+	got, err := c.ConvertToMongo()
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{"$or": []bson.M{
+		{"$and": []bson.M{{"a": 1}, {"tenant": "t1"}}},
+		{"$and": []bson.M{{"$or": []bson.M{{"b": 2}, {"c": 3}}}, {"tenant": "t1"}}},
+	}}, got)
+}
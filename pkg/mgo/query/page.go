@@ -92,16 +92,21 @@ func NewPage(page int, limit int, columnNames string) *Page {
 //	columnNames="-name" means sort by name descending,
 //	columnNames="name,age" means sort by name in ascending order, otherwise sort by age in ascending order,
 //	columnNames="-name,-age" means sort by name descending before sorting by age descending.
+//
+// the empty column names are skipped, e.g. "name,,age," is the same as "name,age", if all the column names
+// are empty, it is sorted by id backwards.
 func getSort(columnNames string) bson.D {
-	d := bson.D{}
 	columnNames = strings.Replace(columnNames, " ", "", -1)
 	if columnNames == "" {
-		d = bson.D{{oidName, -1}} //nolint
-		return d
+		return bson.D{{oidName, -1}} //nolint
 	}
 
 	names := strings.Split(columnNames, ",")
+	d := make(bson.D, 0, len(names))
 	for _, name := range names {
+		if name == "" {
+			continue
+		}
 		if name[0] == '-' && len(name) > 1 {
 			col := name[1:]
 			if col == "id" {
@@ -115,6 +120,9 @@ func getSort(columnNames string) bson.D {
 			d = append(d, bson.E{name, 1}) //nolint
 		}
 	}
+	if len(d) == 0 {
+		return bson.D{{oidName, -1}} //nolint
+	}
 
 	return d
 }
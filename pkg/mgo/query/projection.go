@@ -0,0 +1,95 @@
+package query
+
+import (
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ConvertToProjection converted Select to the projection of mongodb, e.g. "name,profile.city" to
+// {name: 1, profile.city: 1}, "-password" to {password: 0}, nil is returned if Select is empty.
+// The fields are converted by the field name converter and checked by the whitelist of the options in the same
+// way as the columns, id is converted to _id, which is always allowed. The inclusion and the exclusion cannot be
+// mixed except _id, and the field cannot be the parent of the other field, e.g. profile and profile.city, they
+// are rejected with ErrInvalidProjection.
+func (p *Params) ConvertToProjection(opts ...RulerOption) (bson.M, error) {
+	o := defaultRulerOptions()
+	o.apply(opts...)
+	return p.convertToProjection(o)
+}
+
+func (p *Params) convertToProjection(o *rulerOptions) (bson.M, error) {
+	if strings.TrimSpace(p.Select) == "" {
+		return nil, nil
+	}
+
+	fields := strings.Split(strings.Replace(p.Select, " ", "", -1), ",")
+	if o.maxColumns > 0 && len(fields) > o.maxColumns {
+		return nil, newError(ErrTooManyColumns, "", "the number of select fields %d exceeds the limit %d", len(fields), o.maxColumns)
+	}
+
+	projection := make(bson.M, len(fields))
+	names := make([]string, 0, len(fields))
+	include, exclude := false, false
+	for _, field := range fields {
+		value := 1
+		if strings.HasPrefix(field, "-") {
+			field, value = field[1:], 0
+		}
+		if field == "" {
+			continue
+		}
+		field = o.fieldName(field)
+		if field == "id" {
+			field = oidName
+		}
+		if strings.HasPrefix(field, ".") || strings.HasSuffix(field, ".") || strings.Contains(field, "..") {
+			return nil, newError(ErrInvalidProjection, field, "select field '%s' has an empty path", field)
+		}
+		if field != oidName && ((o.whitelistNames != nil && !o.whitelistNames[field]) || isOperatorName(field)) {
+			return nil, newError(ErrNameNotAllowed, field, "select field '%s' is not allowed", field)
+		}
+
+		if v, ok := projection[field]; ok && v != value {
+			return nil, newError(ErrInvalidProjection, field, "select field '%s' is both included and excluded", field)
+		}
+		if _, ok := projection[field]; !ok {
+			names = append(names, field)
+		}
+		projection[field] = value
+		if field == oidName { // _id can be included or excluded in both ways
+			continue
+		}
+		if value == 1 {
+			include = true
+		} else {
+			exclude = true
+		}
+	}
+	if include && exclude {
+		return nil, newError(ErrInvalidProjection, "", "cannot mix the inclusion and the exclusion of select fields except _id")
+	}
+
+	// the path collision of mongodb, e.g. profile and profile.city
+	for _, field := range names {
+		for i := strings.Index(field, "."); i > 0; i = nextDot(field, i) {
+			if _, ok := projection[field[:i]]; ok {
+				return nil, newError(ErrInvalidProjection, field, "select field '%s' collides with '%s'", field, field[:i])
+			}
+		}
+	}
+
+	if len(projection) == 0 {
+		return nil, nil
+	}
+	return projection, nil
+}
+
+// the index of the next dot after i, -1 if there is none
+func nextDot(s string, i int) int {
+	j := strings.Index(s[i+1:], ".")
+	if j < 0 {
+		return -1
+	}
+	return i + 1 + j
+}
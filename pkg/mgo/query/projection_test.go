@@ -0,0 +1,71 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestParams_ConvertToProjection(t *testing.T) {
+	whitelist := map[string]bool{"name": true, "age": true, "password": true, "profile": true, "profile.city": true}
+
+	tests := []struct {
+		selectFields string
+		want         bson.M
+		wantErr      error
+	}{
+		{"", nil, nil},
+		{" , ", nil, nil},
+		{"name, age", bson.M{"name": 1, "age": 1}, nil},
+		{"name,-_id", bson.M{"name": 1, "_id": 0}, nil},
+		{"name,-id", bson.M{"name": 1, "_id": 0}, nil},
+		{"-password", bson.M{"password": 0}, nil},
+		{"_id,-password", bson.M{"_id": 1, "password": 0}, nil},
+		{"profile.city,name,name", bson.M{"profile.city": 1, "name": 1}, nil},
+
+		{"name,-password", nil, ErrInvalidProjection},
+		{"name,-name", nil, ErrInvalidProjection},
+		{"profile,profile.city", nil, ErrInvalidProjection},
+		{"profile.", nil, ErrInvalidProjection},
+		{"email", nil, ErrNameNotAllowed},
+		{"-profile.$where", nil, ErrNameNotAllowed},
+	}
+	for _, tt := range tests {
+		p := &Params{Select: tt.selectFields}
+		got, err := p.ConvertToProjection(WithWhitelistNames(whitelist))
+		if tt.wantErr != nil {
+			assert.ErrorIs(t, err, tt.wantErr, tt.selectFields)
+			assert.True(t, IsInvalid(err), tt.selectFields)
+			continue
+		}
+		require.NoError(t, err, tt.selectFields)
+		assert.Equal(t, tt.want, got, tt.selectFields)
+	}
+
+	// the fields are converted by name, the operator names are rejected without the whitelist
+	p := &Params{Select: "firstName,profile.zipCode"}
+	got, err := p.ConvertToProjection(WithFieldNameConverter(CamelToSnake))
+	require.NoError(t, err)
+	assert.Equal(t, bson.M{"first_name": 1, "profile.zip_code": 1}, got)
+	_, err = (&Params{Select: "$where"}).ConvertToProjection()
+	assert.ErrorIs(t, err, ErrNameNotAllowed)
+	_, err = (&Params{Select: "a,b,c"}).ConvertToProjection(WithMaxColumns(2))
+	assert.ErrorIs(t, err, ErrTooManyColumns)
+}
+
+func TestParams_ConvertToQuery_Projection(t *testing.T) {
+	p := &Params{Limit: 10, Select: "name,-_id"}
+	_, findOpts, err := p.ConvertToQuery()
+	require.NoError(t, err)
+	assert.Equal(t, bson.M{"name": 1, "_id": 0}, findOpts.Projection)
+
+	_, findOpts, err = (&Params{Limit: 10}).ConvertToQuery()
+	require.NoError(t, err)
+	assert.Nil(t, findOpts.Projection)
+
+	_, findOpts, err = (&Params{Limit: 10, Select: "password"}).ConvertToQuery(WithWhitelistNames(map[string]bool{"name": true}))
+	assert.ErrorIs(t, err, ErrNameNotAllowed)
+	assert.Nil(t, findOpts)
+}
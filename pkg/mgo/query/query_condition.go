@@ -2,12 +2,20 @@
 package query
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 const (
@@ -31,10 +39,39 @@ const (
 	lteSymbol = "<="
 	// Like fuzzy lookup
 	Like = "like"
-	// In include
+	// NotLike the opposite of Like, the value is not contained, e.g. "does not contain" of the search UI
+	NotLike = "nlike"
+	// Contains the same as Like, the value is contained
+	Contains = "contains"
+	// StartsWith the value is the prefix, the filter of the left-anchored regex can use the index if it is case-sensitive
+	StartsWith = "startswith"
+	// EndsWith the value is the suffix
+	EndsWith = "endswith"
+	// IsNull the field is null or missing, the value is ignored, use it with the exists exp to match the null only
+	IsNull = "isnull"
+	// NotNull the field is neither null nor missing, the value is ignored
+	NotNull = "notnull"
+	// IEq case-insensitive equal, the filter is selected by WithIEqStrategy
+	IEq = "ieq"
+	// INeq case-insensitive not equal, the opposite of IEq
+	INeq = "ineq"
+	// In include, the value is a comma-separated string or a slice
 	In = "in"
-	// NotIn exclude
+	// NotIn exclude, the value is the same as In
 	NotIn = "nin"
+	// Exists the field exists or not, the value is bool, "true"/"false" or 1/0
+	Exists = "exists"
+	// NotExists the field does not exist or exists, the opposite of Exists
+	NotExists = "notexists"
+	// Between the range including both ends, the value is a two-element slice or a comma-separated string
+	Between = "between"
+	// Regex the raw regular expression, case-insensitive, unlike Like the value is not escaped
+	Regex = "regex"
+	// All the array field contains all the values, the value is the same as In
+	All = "all"
+	// ElemMatch an element of the array field matches all the sub-columns, the value is the list of the sub-columns,
+	// e.g. [{"name":"sku","value":"X"},{"name":"qty","exp":"gt","value":2}], or the json string of it
+	ElemMatch = "elemmatch"
 
 	// AND logic and
 	AND        string = "and" //nolint
@@ -49,6 +86,36 @@ const (
 	allLogicOr  = 2
 )
 
+// the type hints of the column value, the value is cast to the type before building the filter,
+// e.g. the values bound from the form or query are strings, {name:"age", exp:"gt", value:"30", type:"int"}
+const (
+	// TypeInt int64, the integral float64 decoded from json is accepted
+	TypeInt = "int"
+	// TypeFloat float64
+	TypeFloat = "float"
+	// TypeBool bool, true/false or 1/0
+	TypeBool = "bool"
+	// TypeDatetime time.Time, RFC3339 or "2006-01-02 15:04:05" in UTC
+	TypeDatetime = "datetime"
+	// TypeOID ObjectID, the same as the suffix :oid of the column name
+	TypeOID = "oid"
+	// TypeString string, the value is not converted to ObjectID even if it is a 24-char hex
+	TypeString = "string"
+)
+
+// the filters of the case-insensitive equality of ieq and ineq, see WithIEqStrategy
+const (
+	// IEqRegex the anchored case-insensitive regex, e.g. {name: {$regex: "^foo$", $options: "i"}}, the index of
+	// the field can be scanned, but all the keys of the index are examined
+	IEqRegex = "regex"
+	// IEqExpr the equality of the lower case, e.g. {$expr: {$eq: [{$toLower: "$name"}, {$literal: "foo"}]}}, the
+	// index cannot be used, all the documents matching the other conditions are examined, but the same expression
+	// can be reused in the aggregation stages
+	IEqExpr = "expr"
+)
+
+const datetimeLayout = "2006-01-02 15:04:05"
+
 var expMap = map[string]string{
 	Eq:        eqSymbol,
 	eqSymbol:  eqSymbol,
@@ -67,6 +134,32 @@ var expMap = map[string]string{
 	NotIn:     NotIn,
 	"notin":   NotIn,
 	"not in":  NotIn,
+	Exists:    Exists,
+	NotExists: NotExists,
+	Between:   Between,
+	Regex:     Regex,
+
+	NotLike:    NotLike,
+	"notlike":  NotLike,
+	"not like": NotLike,
+
+	Contains:      Like,
+	StartsWith:    StartsWith,
+	"starts with": StartsWith,
+	EndsWith:      EndsWith,
+	"ends with":   EndsWith,
+	IEq:           IEq,
+	INeq:          INeq,
+
+	IsNull:        IsNull,
+	"is null":     IsNull,
+	NotNull:       NotNull,
+	"isnotnull":   NotNull,
+	"is not null": NotNull,
+
+	All:          All,
+	ElemMatch:    ElemMatch,
+	"elem match": ElemMatch,
 }
 
 var logicMap = map[string]string{
@@ -78,11 +171,47 @@ var logicMap = map[string]string{
 	orSymbol2:  orSymbol1,
 }
 
+// the normalized exp of the case-insensitive exp or its alias, e.g. "GTE" and ">=" are normalized to ">="
+func lookupExp(exp string) (string, bool) {
+	v, ok := expMap[strings.ToLower(exp)]
+	return v, ok
+}
+
+// the normalized logic of the case-insensitive logic or its alias, e.g. "AND" and "&&" are normalized to "&"
+func lookupLogic(logic string) (string, bool) {
+	v, ok := logicMap[strings.ToLower(logic)]
+	return v, ok
+}
+
 // ---------------------------------------------------------------------------
 
 type rulerOptions struct {
 	whitelistNames map[string]bool
 	validateFn     func(columns []Column) error
+	schema         Schema
+	allowRegexExp  bool
+	maxRegexLength int
+	allowRawValues bool
+	maxColumns     int
+	maxInValues    int
+	maxLimit       int
+	convertName    func(string) string
+	canonicalize   bool
+	totalCount     bool
+
+	disableAutoObjectID bool
+	caseSensitiveLike   bool
+	ieqStrategy         string
+}
+
+func defaultRulerOptions() *rulerOptions {
+	return &rulerOptions{
+		allowRegexExp:  true,
+		maxRegexLength: 256,
+		maxColumns:     20,
+		maxInValues:    1000,
+		ieqStrategy:    IEqRegex,
+	}
 }
 
 // RulerOption set the parameters of ruler options
@@ -108,6 +237,120 @@ func WithValidateFn(fn func(columns []Column) error) RulerOption {
 	}
 }
 
+// WithSchema validate and convert the values of the columns by the types and the rules of the fields, e.g. the
+// string "18" is converted to int for the int field and "abc" is rejected with *ColumnError, instead of matching
+// nothing. The type of the schema takes precedence over the type hint of the column, and the columns not in the
+// schema are converted in the same way as without the schema. The schema is generated by SchemaFromStruct, e.g.
+//
+//	query.WithSchema(query.SchemaFromStruct(&model.User{}))
+func WithSchema(schema Schema) RulerOption {
+	return func(o *rulerOptions) {
+		o.schema = schema
+	}
+}
+
+// WithAllowRegexExp set whether the regex exp is allowed, default true, disable it if the server only
+// exposes the whitelisted search, the columns with the regex exp are rejected
+func WithAllowRegexExp(allow bool) RulerOption {
+	return func(o *rulerOptions) {
+		o.allowRegexExp = allow
+	}
+}
+
+// WithMaxRegexLength set the max length of the pattern of the regex exp, default 256
+func WithMaxRegexLength(n int) RulerOption {
+	return func(o *rulerOptions) {
+		if n > 0 {
+			o.maxRegexLength = n
+		}
+	}
+}
+
+// WithMaxColumns set the max number of the columns, the sub-columns of elemmatch are limited separately,
+// default 20, 0 means unlimited, the columns exceeding the limit are rejected before the conversion
+func WithMaxColumns(n int) RulerOption {
+	return func(o *rulerOptions) {
+		if n >= 0 {
+			o.maxColumns = n
+		}
+	}
+}
+
+// WithMaxInValues set the max number of the values of in, nin and all of a column, default 1000, 0 means
+// unlimited, the values exceeding the limit are rejected before the conversion
+func WithMaxInValues(n int) RulerOption {
+	return func(o *rulerOptions) {
+		if n >= 0 {
+			o.maxInValues = n
+		}
+	}
+}
+
+// WithMaxLimit set the max number of the documents per page, the limit of the params exceeding it is clamped,
+// 0 means the max size of SetMaxSize, the default
+func WithMaxLimit(n int) RulerOption {
+	return func(o *rulerOptions) {
+		if n >= 0 {
+			o.maxLimit = n
+		}
+	}
+}
+
+// WithFieldNameConverter convert the names of the columns and the sort fields before the whitelist check, the
+// dot-path names are converted by segment, e.g. the camelCase names of json to the snake_case names of bson by
+// CamelToSnake, profile.firstName becomes profile.first_name, so the whitelist is written in the storage names.
+func WithFieldNameConverter(fn func(string) string) RulerOption {
+	return func(o *rulerOptions) {
+		o.convertName = fn
+	}
+}
+
+// WithCanonicalize canonicalize the columns by Canonicalize before the conversion, so the logically identical
+// conditions are converted to the same filter, note that the index of *Error is the index of the canonical columns
+func WithCanonicalize() RulerOption {
+	return func(o *rulerOptions) {
+		o.canonicalize = true
+	}
+}
+
+// WithAllowRawValues allow the documents as the column values, e.g. map, bson.M and bson.D, they are passed to
+// the filter as they are, default the documents are rejected, otherwise the client can inject the operators by
+// the value, e.g. {"name":"role", "value":{"$ne":"user"}} becomes {"role":{"$ne":"user"}}.
+// Only set it if the columns are built by the server instead of the client.
+func WithAllowRawValues() RulerOption {
+	return func(o *rulerOptions) {
+		o.allowRawValues = true
+	}
+}
+
+// WithCaseSensitiveLike match the values of like, nlike, startswith and endswith case-sensitively, default they
+// are matched case-insensitively, which cannot use the index even if the regex is left-anchored, e.g. startswith.
+func WithCaseSensitiveLike() RulerOption {
+	return func(o *rulerOptions) {
+		o.caseSensitiveLike = true
+	}
+}
+
+// WithIEqStrategy set the filter of ieq and ineq, IEqRegex or IEqExpr, default IEqRegex. Neither of them uses the
+// index efficiently, create the index with the collation of strength 2 and query with the same collation if the
+// case-insensitive equality must use the index.
+func WithIEqStrategy(strategy string) RulerOption {
+	return func(o *rulerOptions) {
+		if strategy == IEqRegex || strategy == IEqExpr {
+			o.ieqStrategy = strategy
+		}
+	}
+}
+
+// WithDisableAutoObjectID disable converting the 24-char hex strings to ObjectID for all the columns, the values
+// are converted only if the column name is id or _id, or has the suffix :oid, e.g. the external trace ids of
+// 24-char hex stored as the strings, default the 24-char hex strings of all the columns are converted.
+func WithDisableAutoObjectID() RulerOption {
+	return func(o *rulerOptions) {
+		o.disableAutoObjectID = true
+	}
+}
+
 // -----------------------------------------------------------------------------
 
 // Params query parameters
@@ -115,6 +358,12 @@ type Params struct {
 	Page  int    `json:"page" form:"page" binding:"gte=0"`
 	Limit int    `json:"limit" form:"limit" binding:"gte=1"`
 	Sort  string `json:"sort,omitempty" form:"sort" binding:""`
+	// the fields of the projection separated by comma, a '-' sign in front of the field indicates exclusion,
+	// e.g. "name,age" or "-password", empty means all the fields, see ConvertToProjection
+	Select string `json:"select,omitempty" form:"select"`
+	// the structured sort fields in order, they take precedence over Sort, e.g. [{"name":"age","desc":true}],
+	// Sort must be empty or the same order if both are set, see ConvertToFindOptions
+	Sorts []SortField `json:"sorts,omitempty" form:"sorts"`
 
 	Columns []Column `json:"columns,omitempty" form:"columns"` // not required
 
@@ -122,49 +371,132 @@ type Params struct {
 	Size int `json:"size" form:"size"`
 }
 
+// SortField the structured sort field of Sorts, the field is in ascending order unless Desc is true
+type SortField struct {
+	Name string `json:"name" form:"name"`
+	Desc bool   `json:"desc" form:"desc"`
+}
+
 // Column query info
 type Column struct {
-	Name  string      `json:"name" form:"name"`   // column name
-	Exp   string      `json:"exp" form:"exp"`     // expressions, default value is "=", support =, !=, >, >=, <, <=, like, in
-	Value interface{} `json:"value" form:"value"` // column value
-	Logic string      `json:"logic" form:"logic"` // logical type, defaults to and when the value is null, with &(and), ||(or)
+	Name  string      `json:"name" form:"name"`           // column name
+	Exp   string      `json:"exp" form:"exp"`             // expressions, default value is "=", support =, !=, >, >=, <, <=, ieq, ineq, isnull, notnull, like, nlike, contains, startswith, endswith, in, nin, all, elemmatch, exists, notexists, between, regex
+	Value interface{} `json:"value" form:"value"`         // column value
+	Logic string      `json:"logic" form:"logic"`         // logical type, defaults to and when the value is null, with &(and), ||(or)
+	Type  string      `json:"type,omitempty" form:"type"` // type hint of the value, support int, float, bool, datetime, oid, string, the value is converted automatically if it is empty
+	// the id of the parenthesized group, int or string, the columns of the same group are combined by their logic
+	// in the parentheses, and the groups are combined by the logic of the last column of each group
+	Group interface{} `json:"group,omitempty" form:"group"`
 }
 
-func (c *Column) checkName(whitelists map[string]bool) error {
-	if c.Name == "" || (whitelists != nil && !whitelists[c.Name]) {
-		return fmt.Errorf("field name '%s' is not allowed", c.Name)
+// the name is converted by the field name converter, then it is checked even without the whitelist, the name with
+// a segment starting with $ is rejected, e.g. $where or profile.$gt, otherwise the client can inject the operators
+// by the name
+func (c *Column) checkName(o *rulerOptions) error {
+	if c.Name == "" {
+		return newError(ErrEmptyName, c.Name, "field 'name' cannot be empty")
+	}
+	c.Name = o.fieldName(c.Name)
+	if (o.whitelistNames != nil && !o.whitelistNames[c.Name]) || isOperatorName(c.Name) {
+		return newError(ErrNameNotAllowed, c.Name, "field name '%s' is not allowed", c.Name)
 	}
 	return nil
 }
 
+func isOperatorName(name string) bool {
+	for {
+		segment, rest, found := strings.Cut(name, ".")
+		if strings.HasPrefix(strings.TrimSpace(segment), "$") {
+			return true
+		}
+		if !found {
+			return false
+		}
+		name = rest
+	}
+}
+
 func (c *Column) checkValid() error {
 	if c.Name == "" {
-		return fmt.Errorf("field 'name' cannot be empty")
+		return newError(ErrEmptyName, c.Name, "field 'name' cannot be empty")
 	}
-	if c.Value == nil {
-		return fmt.Errorf("field 'value' cannot be nil")
+	if c.Value == nil && !isNullExp(c.Exp) && !isEqualExp(c.Exp) {
+		return newError(ErrNilValue, c.Name, "field 'value' cannot be nil")
 	}
 	return nil
 }
 
+// the value of isnull and notnull is ignored, it can be nil
+func isNullExp(exp string) bool {
+	v, _ := lookupExp(exp)
+	return v == IsNull || v == NotNull
+}
+
+// the nil value of eq and neq is the explicit null equality, e.g. {field: null} and {field: {$ne: null}}
+func isEqualExp(exp string) bool {
+	if exp == "" {
+		return true
+	}
+	v, _ := lookupExp(exp)
+	return v == eqSymbol || v == neqSymbol
+}
+
 func (c *Column) convertLogic() error {
 	if c.Logic == "" {
 		c.Logic = AND
 	}
-	if v, ok := logicMap[strings.ToLower(c.Logic)]; ok { //nolint
+	if v, ok := lookupLogic(c.Logic); ok {
 		c.Logic = v
 		return nil
 	}
-	return fmt.Errorf("unknown logic type '%s'", c.Logic)
+	return newError(ErrUnknownLogic, c.Name, "unknown logic type '%s'", c.Logic)
 }
 
 // converting ExpType to sql expressions and LogicType to sql using characters
-func (c *Column) convert() error {
+func (c *Column) convert(o *rulerOptions) error {
 	if err := c.checkValid(); err != nil {
 		return err
 	}
+	if expMap[strings.ToLower(c.Exp)] == ElemMatch {
+		// the documents of the sub-columns are not the raw values, the values of the sub-columns are checked
+		if err := c.elemMatchValue(o); err != nil {
+			return err
+		}
+		return c.convertLogic()
+	}
+	if !o.allowRawValues && hasDocumentValue(c.Value) {
+		return newError(ErrInvalidValue, c.Name, "column '%s': the document value '%v' is not allowed", c.Name, c.Value)
+	}
 
-	if oid, ok := isObjectID(c.Value); ok {
+	if c.Value == nil && isEqualExp(c.Exp) {
+		// the value is not cast, {field: null} matches the null and the missing field as isnull does
+		if exp, _ := lookupExp(c.Exp); exp == neqSymbol {
+			c.Exp = neqSymbol
+			c.Value = bson.M{"$ne": nil}
+		} else {
+			c.Exp = eqSymbol
+		}
+		return c.convertLogic()
+	}
+	if isNullExp(c.Exp) {
+		c.Exp, _ = lookupExp(c.Exp)
+		if c.Exp == IsNull {
+			// the same as {field: null}, the null and the missing field are matched
+			c.Value = bson.M{"$in": []interface{}{nil}}
+		} else {
+			c.Value = bson.M{"$ne": nil}
+		}
+		return c.convertLogic()
+	}
+
+	if field := o.schemaField(c.Name); field != nil && field.Type != "" {
+		c.Type = field.Type
+	}
+	if c.Type != "" {
+		if err := c.checkType(); err != nil {
+			return err
+		}
+	} else if oid, ok := isObjectID(c.Value); ok && c.autoObjectID(o) {
 		c.Value = oid
 
 		if c.Name == "id" {
@@ -177,45 +509,540 @@ func (c *Column) convert() error {
 	if c.Exp == "" {
 		c.Exp = Eq
 	}
-	if v, ok := expMap[strings.ToLower(c.Exp)]; ok { //nolint
+	if v, ok := lookupExp(c.Exp); ok {
 		c.Exp = v
 		switch c.Exp {
-		//case eqSymbol:
-		case neqSymbol:
-			c.Value = bson.M{"$ne": c.Value}
-		case gtSymbol:
-			c.Value = bson.M{"$gt": c.Value}
-		case gteSymbol:
-			c.Value = bson.M{"$gte": c.Value}
-		case ltSymbol:
-			c.Value = bson.M{"$lt": c.Value}
-		case lteSymbol:
-			c.Value = bson.M{"$lte": c.Value}
+		case eqSymbol, neqSymbol, gtSymbol, gteSymbol, ltSymbol, lteSymbol:
+			value, err := c.castValue(c.Value, o)
+			if err != nil {
+				return err
+			}
+			c.Value = comparisonValue(c.Exp, value)
 		case Like:
-			escapedValue := regexp.QuoteMeta(fmt.Sprintf("%v", c.Value))
-			c.Value = bson.M{"$regex": escapedValue, "$options": "i"}
-		case In, NotIn:
-			val, ok2 := c.Value.(string)
-			if !ok2 {
-				return fmt.Errorf("invalid value type '%s'", c.Value)
+			c.Value = o.likeValue(regexp.QuoteMeta(likeString(c.Value)))
+		case StartsWith:
+			c.Value = o.likeValue("^" + regexp.QuoteMeta(likeString(c.Value)))
+		case EndsWith:
+			c.Value = o.likeValue(regexp.QuoteMeta(likeString(c.Value)) + "$")
+		case NotLike:
+			regex := primitive.Regex{Pattern: regexp.QuoteMeta(likeString(c.Value)), Options: "i"}
+			if o.caseSensitiveLike {
+				regex.Options = ""
 			}
-			values := []interface{}{}
-			ss := strings.Split(val, ",")
-			for _, s := range ss {
-				values = append(values, s)
+			c.Value = bson.M{"$not": regex}
+		case IEq, INeq:
+			c.ieqValue(o)
+		case In, NotIn, All:
+			values, err := c.inValues(o)
+			if err != nil {
+				return err
 			}
 			c.Value = bson.M{"$" + c.Exp: values}
+		case Exists, NotExists:
+			exists, err := parseExistsValue(c.Value)
+			if err != nil {
+				return c.invalidValue(err)
+			}
+			if c.Exp == NotExists {
+				exists = !exists
+			}
+			c.Value = bson.M{"$exists": exists}
+		case Between:
+			lo, hi, err := c.betweenValues(o)
+			if err != nil {
+				return err
+			}
+			c.Value = bson.M{"$gte": lo, "$lte": hi}
+		case Regex:
+			pattern, err := checkRegex(c.Value, o)
+			if err != nil {
+				if !o.allowRegexExp {
+					return newError(ErrUnsupportedExp, c.Name, "column '%s': %v", c.Name, err)
+				}
+				return c.invalidValue(err)
+			}
+			c.Value = bson.M{"$regex": pattern, "$options": "i"}
 		}
 	} else {
-		return fmt.Errorf("unsported exp type '%s'", c.Exp)
+		return newError(ErrUnsupportedExp, c.Name, "unsupported exp type '%s'", c.Exp)
 	}
 
 	return c.convertLogic()
 }
 
-// ConvertToPage converted to page
-func (p *Params) ConvertToPage() (sort bson.D, limit int, skip int) { //nolint
-	page := NewPage(p.Page, p.Limit, p.Sort)
+// the filter of elemmatch, the sub-columns are converted in the same way as the top-level columns, and the names
+// are checked by the whitelist with the prefix of the array field, e.g. items.sku, the sub-columns are combined
+// with AND only, and the nested elemmatch is not supported.
+func (c *Column) elemMatchValue(o *rulerOptions) error {
+	columns, err := elemMatchColumns(c.Value)
+	if err != nil {
+		return c.invalidValue(err)
+	}
+	if len(columns) == 0 {
+		return newError(ErrInvalidValue, c.Name, "column '%s': the sub-columns of %s cannot be empty", c.Name, ElemMatch)
+	}
+
+	if err = o.checkLimits(columns); err != nil {
+		return c.subError(err)
+	}
+
+	subOpts := *o
+	subOpts.ieqStrategy = IEqRegex // $expr is not allowed in $elemMatch
+	subOpts.whitelistNames = nil   // the sub-columns are checked by the dot-path names
+	filter := make(bson.M, len(columns))
+	conditions := make([]bson.M, 0, len(columns))
+	for i, sub := range columns {
+		if err = sub.checkName(&subOpts); err != nil {
+			return c.subError(err)
+		}
+		if o.whitelistNames != nil && !o.whitelistNames[c.Name+"."+sub.Name] {
+			return newError(ErrNameNotAllowed, c.Name+"."+sub.Name, "field name '%s.%s' is not allowed", c.Name, sub.Name)
+		}
+		if expMap[strings.ToLower(sub.Exp)] == ElemMatch {
+			return newError(ErrUnsupportedExp, c.Name, "column '%s': the nested %s is not supported", c.Name, ElemMatch)
+		}
+		if err = sub.convert(&subOpts); err != nil {
+			return c.subError(err)
+		}
+		if sub.Logic == orSymbol1 && i < len(columns)-1 { // ignore the logical type of the last column
+			return newError(ErrInvalidValue, c.Name, "column '%s': the logic of the sub-columns of %s must be and", c.Name, ElemMatch)
+		}
+		conditions = append(conditions, bson.M{sub.Name: sub.Value})
+		filter[sub.Name] = sub.Value
+	}
+
+	if len(filter) < len(conditions) { // the same field is matched more than once, e.g. qty > 2 and qty < 10
+		c.Value = bson.M{"$elemMatch": bson.M{"$and": conditions}}
+		return nil
+	}
+	c.Value = bson.M{"$elemMatch": filter}
+	return nil
+}
+
+// the name of the sub-column of the error is prefixed by the name of the column
+func (c *Column) subError(err error) error {
+	var e *Error
+	if errors.As(err, &e) {
+		e.Name = c.Name + "." + e.Name
+	}
+	return err
+}
+
+// the error of the invalid value of the column, the error of the value is the cause
+func (c *Column) invalidValue(err error) error {
+	e := newError(ErrInvalidValue, c.Name, "column '%s': %v", c.Name, err)
+	e.Cause = err
+	return e
+}
+
+// the sub-columns of elemmatch, the value is []Column, the documents decoded from json, or the json string
+func elemMatchColumns(v interface{}) ([]Column, error) {
+	var values []interface{}
+	switch val := v.(type) {
+	case []Column:
+		return val, nil
+	case string:
+		var columns []Column
+		if err := json.Unmarshal([]byte(val), &columns); err != nil {
+			return nil, fmt.Errorf("invalid %s value '%s', it should be a json array of the columns", ElemMatch, val)
+		}
+		return columns, nil
+	case []interface{}:
+		values = val
+	case primitive.A:
+		values = val
+	case []map[string]interface{}:
+		for _, m := range val {
+			values = append(values, m)
+		}
+	case []bson.M:
+		for _, m := range val {
+			values = append(values, m)
+		}
+	default:
+		return nil, fmt.Errorf("invalid %s value type '%T', it should be a list of the columns", ElemMatch, v)
+	}
+
+	columns := make([]Column, 0, len(values))
+	for _, value := range values {
+		column, err := toColumn(value)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// the column of the document, e.g. {"name":"qty", "exp":"gt", "value":2}
+func toColumn(v interface{}) (Column, error) {
+	if column, ok := v.(Column); ok {
+		return column, nil
+	}
+	m, ok := toBsonM(v)
+	if !ok {
+		return Column{}, fmt.Errorf("invalid sub-column '%v', it should be a document", v)
+	}
+	column := Column{}
+	for key, value := range m {
+		var field *string
+		switch key {
+		case "name":
+			field = &column.Name
+		case "exp":
+			field = &column.Exp
+		case "logic":
+			field = &column.Logic
+		case "type":
+			field = &column.Type
+		case "value":
+			column.Value = value
+			continue
+		default:
+			return Column{}, fmt.Errorf("unknown field '%s' of the sub-column", key)
+		}
+		if *field, ok = value.(string); !ok && value != nil {
+			return Column{}, fmt.Errorf("field '%s' of the sub-column should be a string", key)
+		}
+	}
+	return column, nil
+}
+
+func likeString(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case primitive.ObjectID: // the 24-char hex string is converted already
+		return val.Hex()
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// the filter of ieq and ineq, the column of IEqExpr is named $expr, the value is wrapped by $literal, otherwise
+// the value starting with $ is the field path, e.g. "$password"
+func (c *Column) ieqValue(o *rulerOptions) {
+	str := likeString(c.Value)
+	if o.ieqStrategy == IEqExpr {
+		op := "$eq"
+		if c.Exp == INeq {
+			op = "$ne"
+		}
+		field := bson.M{"$toLower": "$" + c.Name}
+		c.Name = "$expr"
+		c.Value = bson.M{op: []interface{}{field, bson.M{"$literal": strings.ToLower(str)}}}
+		return
+	}
+
+	pattern := "^" + regexp.QuoteMeta(str) + "$"
+	if c.Exp == INeq {
+		c.Value = bson.M{"$not": primitive.Regex{Pattern: pattern, Options: "i"}}
+		return
+	}
+	c.Value = bson.M{"$regex": pattern, "$options": "i"}
+}
+
+// the filter of the escaped pattern of like, startswith and endswith
+func (o *rulerOptions) likeValue(pattern string) bson.M {
+	if o.caseSensitiveLike {
+		return bson.M{"$regex": pattern}
+	}
+	return bson.M{"$regex": pattern, "$options": "i"}
+}
+
+var (
+	typeRaw = reflect.TypeOf(bson.Raw{})
+	typeE   = reflect.TypeOf(primitive.E{})
+)
+
+// the value is a document or contains documents, e.g. map, bson.M, bson.D, or the slice of them in the in-list,
+// which are converted to the operators of mongodb instead of the values to compare
+func hasDocumentValue(v interface{}) bool {
+	return isDocument(reflect.ValueOf(v))
+}
+
+func isDocument(rv reflect.Value) bool {
+	for rv.Kind() == reflect.Interface || rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return false
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		return true
+	case reflect.Struct:
+		return rv.Type() == typeE
+	case reflect.Slice, reflect.Array:
+		if rv.Type() == typeRaw {
+			return true
+		}
+		switch rv.Type().Elem().Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Struct, reflect.Slice, reflect.Array:
+			for i := 0; i < rv.Len(); i++ {
+				if isDocument(rv.Index(i)) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// the 24-char hex strings of the column are converted to ObjectID without the type hint
+func (c *Column) autoObjectID(o *rulerOptions) bool {
+	return !o.disableAutoObjectID || c.Name == "id" || c.Name == "_id" || strings.HasSuffix(c.Name, ":oid")
+}
+
+func comparisonValue(exp string, value interface{}) interface{} {
+	switch exp {
+	case neqSymbol:
+		return bson.M{"$ne": value}
+	case gtSymbol:
+		return bson.M{"$gt": value}
+	case gteSymbol:
+		return bson.M{"$gte": value}
+	case ltSymbol:
+		return bson.M{"$lt": value}
+	case lteSymbol:
+		return bson.M{"$lte": value}
+	}
+	return value
+}
+
+// check the type hint, the column of the oid type is renamed in the same way as the ObjectID value
+func (c *Column) checkType() error {
+	c.Type = strings.ToLower(c.Type)
+	if _, ok := typeCasters[c.Type]; !ok {
+		return newError(ErrUnknownType, c.Name, "column '%s': unknown type '%s'", c.Name, c.Type)
+	}
+	if c.Type == TypeOID {
+		if strings.HasSuffix(c.Name, ":oid") {
+			c.Name = strings.TrimSuffix(c.Name, ":oid")
+		} else if c.Name == "id" {
+			c.Name = "_id" // force to "_id"
+		}
+	}
+	return nil
+}
+
+// the elements of in and nin are cast to the type hint, or converted automatically without the type hint
+func (c *Column) inValues(o *rulerOptions) ([]interface{}, error) {
+	if c.Type == "" {
+		values, err := parseInValues(c.Value, c.autoObjectID(o))
+		if err == nil {
+			err = c.convertInName(values)
+		}
+		if err != nil {
+			return nil, c.invalidValue(err)
+		}
+		return values, nil
+	}
+
+	values, err := listValues(c.Value)
+	if err != nil {
+		return nil, c.invalidValue(err)
+	}
+	for i, v := range values {
+		if values[i], err = c.castValue(v, o); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// both ends of between are cast to the type hint, or converted automatically without the type hint
+func (c *Column) betweenValues(o *rulerOptions) (lo interface{}, hi interface{}, err error) {
+	if c.Type == "" {
+		var isOID bool
+		lo, hi, isOID, err = parseBetweenValue(c.Value, c.autoObjectID(o))
+		if err != nil {
+			return nil, nil, c.invalidValue(err)
+		}
+		if isOID {
+			if c.Name == "id" {
+				c.Name = "_id" // force to "_id"
+			} else if strings.HasSuffix(c.Name, ":oid") {
+				c.Name = strings.TrimSuffix(c.Name, ":oid")
+			}
+		}
+		return lo, hi, nil
+	}
+
+	parts, err := betweenParts(c.Value)
+	if err != nil {
+		return nil, nil, c.invalidValue(err)
+	}
+	if lo, err = c.castValue(parts[0], o); err != nil {
+		return nil, nil, err
+	}
+	if hi, err = c.castValue(parts[1], o); err != nil {
+		return nil, nil, err
+	}
+	return lo, hi, nil
+}
+
+// ColumnError the value of the column is not the expected type, or does not satisfy the rules of the schema
+type ColumnError struct {
+	Name     string      // column name
+	Value    interface{} // the value of the request
+	Expected string      // the expected type, e.g. int
+	Rules    string      // the rules of the schema which are not satisfied, empty if the type is not expected
+	Err      error
+}
+
+// Error returns the error message
+func (e *ColumnError) Error() string {
+	if e.Rules != "" {
+		return fmt.Sprintf("column '%s': value '%v' does not satisfy the rules '%s'", e.Name, e.Value, e.Rules)
+	}
+	return fmt.Sprintf("column '%s': value '%v' is not a valid %s", e.Name, e.Value, e.Expected)
+}
+
+// Unwrap returns the error of the conversion or the validation
+func (e *ColumnError) Unwrap() error {
+	return e.Err
+}
+
+// cast the value to the type hint, the value is returned as it is without the type hint, the exact values
+// are validated by the rules of the schema, e.g. the values of eq and in
+func (c *Column) castValue(v interface{}, o *rulerOptions) (interface{}, error) {
+	if c.Type == "" {
+		return v, nil
+	}
+	value, err := typeCasters[c.Type](v)
+	if err != nil {
+		columnErr := &ColumnError{Name: c.Name, Value: v, Expected: c.Type, Err: err}
+		return nil, &Error{Index: -1, Name: c.Name, Err: ErrInvalidValue, Cause: columnErr, msg: columnErr.Error()}
+	}
+	if field := o.schemaField(c.Name); field != nil && field.Rules != "" {
+		switch c.Exp {
+		case eqSymbol, neqSymbol, In, NotIn, All:
+			if err = schemaValidate.Var(value, field.Rules); err != nil {
+				columnErr := &ColumnError{Name: c.Name, Value: v, Expected: c.Type, Rules: field.Rules, Err: err}
+				return nil, &Error{Index: -1, Name: c.Name, Err: ErrInvalidValue, Cause: columnErr, msg: columnErr.Error()}
+			}
+		}
+	}
+	return value, nil
+}
+
+var typeCasters = map[string]func(v interface{}) (interface{}, error){
+	TypeInt:      castInt,
+	TypeFloat:    castFloat,
+	TypeBool:     castBool,
+	TypeDatetime: castDatetime,
+	TypeOID:      castOID,
+	TypeString:   castString,
+}
+
+var errCast = errors.New("cast error")
+
+func castInt(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case string:
+		return strconv.ParseInt(strings.TrimSpace(val), 10, 64)
+	case int:
+		return int64(val), nil
+	case int8:
+		return int64(val), nil
+	case int16:
+		return int64(val), nil
+	case int32:
+		return int64(val), nil
+	case int64:
+		return val, nil
+	case uint:
+		return int64(val), nil
+	case uint8:
+		return int64(val), nil
+	case uint16:
+		return int64(val), nil
+	case uint32:
+		return int64(val), nil
+	case uint64:
+		if val <= math.MaxInt64 {
+			return int64(val), nil
+		}
+	case float32:
+		return castInt(float64(val))
+	case float64: // the numbers decoded from json
+		if val == math.Trunc(val) && math.Abs(val) < 1<<63 {
+			return int64(val), nil
+		}
+	}
+	return nil, errCast
+}
+
+func castFloat(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(val), 64)
+	case float64:
+		return val, nil
+	case float32:
+		return float64(val), nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return strconv.ParseFloat(fmt.Sprintf("%v", val), 64)
+	}
+	return nil, errCast
+}
+
+func castBool(v interface{}) (interface{}, error) {
+	if str, ok := v.(string); ok {
+		return strconv.ParseBool(strings.TrimSpace(str)) // true/false, 1/0 and the cases of t/f
+	}
+	return parseExistsValue(v)
+}
+
+func castDatetime(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case time.Time:
+		return val, nil
+	case string:
+		str := strings.TrimSpace(val)
+		if t, err := time.Parse(time.RFC3339, str); err == nil {
+			return t, nil
+		}
+		return time.Parse(datetimeLayout, str)
+	}
+	return nil, errCast
+}
+
+func castOID(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case primitive.ObjectID:
+		return val, nil
+	case string:
+		return primitive.ObjectIDFromHex(strings.TrimSpace(val))
+	}
+	return nil, errCast
+}
+
+func castString(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case string:
+		return val, nil
+	case primitive.ObjectID:
+		return val.Hex(), nil
+	}
+	return fmt.Sprintf("%v", v), nil
+}
+
+// ConvertToPage converted to page, the sort is of Sorts if it is set, otherwise Sort, the sort fields are converted
+// by the field name converter of the options, and the limit is clamped by WithMaxLimit. The sort fields are not
+// checked, use ConvertToFindOptions to reject the fields not in the whitelist and the conflicting Sort and Sorts.
+func (p *Params) ConvertToPage(opts ...RulerOption) (sort bson.D, limit int, skip int) { //nolint
+	var page *Page
+	if len(opts) > 0 {
+		o := defaultRulerOptions()
+		o.apply(opts...)
+		page = o.newPage(p)
+	} else {
+		page = NewPage(p.Page, p.Limit, p.Sort)
+		if len(p.Sorts) > 0 {
+			page.sort = defaultRulerOptions().sortFields(p.Sorts)
+		}
+	}
 	sort = page.sort
 	limit = page.limit
 	skip = page.page * page.limit
@@ -223,137 +1050,657 @@ func (p *Params) ConvertToPage() (sort bson.D, limit int, skip int) { //nolint
 }
 
 // ConvertToMongoFilter conversion to mongo-compliant parameters based on the Columns parameter
-// ignore the logical type of the last column, whether it is a one-column or multi-column query
+// ignore the logical type of the last column, whether it is a one-column or multi-column query.
+// The columns are converted on the copies, p is not modified, so it can be converted more than once,
+// e.g. for counting and paging, or retrying.
 func (p *Params) ConvertToMongoFilter(opts ...RulerOption) (bson.M, error) {
-	o := rulerOptions{}
+	o := defaultRulerOptions()
+	o.apply(opts...)
+	return p.convertToMongoFilter(o)
+}
+
+// ConvertToQuery converted to the filter and the find options with the sort, skip and limit of the page and the
+// projection of Select, the columns, the sort fields and the projection fields are validated together by the same
+// options, the sort fields not in the whitelist are rejected with ErrNameNotAllowed, except _id, e.g.
+//
+//	filter, findOpts, err := params.ConvertToQuery(query.WithWhitelistNames(names), query.WithMaxLimit(100))
+//	cursor, err := collection.Find(ctx, filter, findOpts)
+func (p *Params) ConvertToQuery(opts ...RulerOption) (filter bson.M, findOpts *options.FindOptions, err error) {
+	o := defaultRulerOptions()
+	o.apply(opts...)
+
+	filter, err = p.convertToMongoFilter(o)
+	if err != nil {
+		return nil, nil, err
+	}
+	findOpts, err = p.convertToFindOptions(o)
+	if err != nil {
+		return nil, nil, err
+	}
+	return filter, findOpts, nil
+}
+
+// ConvertToFindOptions converted to the find options with the sort, skip and limit of the page and the projection
+// of Select, the sort is of Sorts if it is set, otherwise Sort. The sort fields not in the whitelist are rejected
+// with ErrNameNotAllowed, except _id, and ErrSortConflict is returned if Sort and Sorts are both set in the
+// different orders, e.g.
+//
+//	// {"page":0,"limit":10,"sorts":[{"name":"status"},{"name":"createdAt","desc":true}]}
+//	findOpts, err := params.ConvertToFindOptions(query.WithWhitelistNames(names))
+func (p *Params) ConvertToFindOptions(opts ...RulerOption) (*options.FindOptions, error) {
+	o := defaultRulerOptions()
 	o.apply(opts...)
+	return p.convertToFindOptions(o)
+}
+
+func (p *Params) convertToFindOptions(o *rulerOptions) (*options.FindOptions, error) {
+	page, err := o.checkedPage(p)
+	if err != nil {
+		return nil, err
+	}
+	projection, err := p.convertToProjection(o)
+	if err != nil {
+		return nil, err
+	}
+
+	findOpts := options.Find().
+		SetSort(page.sort).
+		SetSkip(int64(page.Skip())).
+		SetLimit(int64(page.limit))
+	if projection != nil {
+		findOpts.SetProjection(projection)
+	}
+	return findOpts, nil
+}
+
+func (p *Params) convertToMongoFilter(o *rulerOptions) (bson.M, error) {
+	if err := o.checkLimits(p.Columns); err != nil {
+		return nil, err
+	}
+	columns := p.Columns
+	if o.canonicalize {
+		columns = Canonicalize(columns)
+	}
 	if o.validateFn != nil {
-		err := o.validateFn(p.Columns)
+		err := o.validateFn(columns)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	filter := bson.M{}
-	l := len(p.Columns)
+	node, err := buildFilterNode(columns, o)
+	if err != nil {
+		return nil, err
+	}
+	return node.toMongo(), nil
+}
+
+// the page of the params, the sort fields are converted by name and the limit is clamped by the max limit
+func (o *rulerOptions) newPage(p *Params) *Page {
+	page := NewPage(p.Page, p.Limit, o.sortNames(p.Sort))
+	if len(p.Sorts) > 0 {
+		page.sort = o.sortFields(p.Sorts)
+	}
+	if o.maxLimit > 0 && page.limit > o.maxLimit {
+		page.limit = o.maxLimit
+	}
+	return page
+}
+
+// the page of the params, Sorts and Sort are checked, and the sort fields are checked by checkSortNames
+func (o *rulerOptions) checkedPage(p *Params) (*Page, error) {
+	if err := o.checkSorts(p); err != nil {
+		return nil, err
+	}
+	page := o.newPage(p)
+	if err := o.checkSortNames(page.sort); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// the names of Sorts cannot be empty, and Sort must be empty or the same order as Sorts
+func (o *rulerOptions) checkSorts(p *Params) error {
+	if len(p.Sorts) == 0 {
+		return nil
+	}
+	for i, field := range p.Sorts {
+		if strings.TrimSpace(field.Name) == "" {
+			return newError(ErrEmptyName, "", "field 'sorts[%d].name' cannot be empty", i)
+		}
+	}
+	if strings.Trim(p.Sort, " ,") != "" && !reflect.DeepEqual(getSort(o.sortNames(p.Sort)), o.sortFields(p.Sorts)) {
+		return newError(ErrSortConflict, "", "sort '%s' conflicts with sorts, set one of them", p.Sort)
+	}
+	return nil
+}
+
+// the sort of Sorts in order, the names are converted by name, id is converted to _id
+func (o *rulerOptions) sortFields(sorts []SortField) bson.D {
+	d := make(bson.D, 0, len(sorts))
+	for _, field := range sorts {
+		name := o.fieldName(strings.TrimSpace(field.Name))
+		if name == "id" {
+			name = oidName
+		}
+		direction := 1
+		if field.Desc {
+			direction = -1
+		}
+		d = append(d, bson.E{Key: name, Value: direction})
+	}
+	return d
+}
+
+// the sort fields are checked in the same way as the names of the columns, _id of the default sort is allowed
+func (o *rulerOptions) checkSortNames(sort bson.D) error {
+	for _, e := range sort {
+		if e.Key == oidName {
+			continue
+		}
+		if (o.whitelistNames != nil && !o.whitelistNames[e.Key]) || isOperatorName(e.Key) {
+			return newError(ErrNameNotAllowed, e.Key, "sort field '%s' is not allowed", e.Key)
+		}
+	}
+	return nil
+}
+
+// buildFilterNode convert the columns to the filter node, the columns are not modified
+func buildFilterNode(columns []Column, o *rulerOptions) (*filterNode, error) {
+	for i := range columns {
+		if columns[i].Group != nil {
+			return buildGroupedFilterNode(columns, o)
+		}
+	}
+	return buildColumnsNode(columns, o)
+}
+
+// the group of the column, empty means the column is not in any group
+func (c *Column) groupKey() (string, error) {
+	switch v := c.Group.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprintf("%v", v), nil
+	}
+	return "", newError(ErrInvalidGroup, c.Name, "column '%s': invalid group '%v', it should be an int or a string", c.Name, c.Group)
+}
+
+// buildGroupedFilterNode the columns of the same group are converted to one node, the groups are ordered by their
+// first columns, each column without group is a group of itself, then the groups are combined in the same way as
+// the columns, i.e. and takes precedence over or, e.g. the groups [A or B], [C] are combined to (A or B) and C if
+// the logic of B is and.
+func buildGroupedFilterNode(columns []Column, o *rulerOptions) (*filterNode, error) {
+	for i := range columns {
+		if _, err := columns[i].groupKey(); err != nil {
+			return nil, withIndex(err, i)
+		}
+	}
+	groupIndexes := splitGroupIndexes(columns)
+	groups := indexGroups(columns, groupIndexes)
+
+	nodes := make([]*filterNode, 0, len(groups))
+	logics := make([]string, 0, len(groups))
+	for i, group := range groups {
+		node, err := buildColumnsNode(group, o)
+		if err != nil {
+			// the index of the column in the group is converted to the index in the columns
+			var e *Error
+			if errors.As(err, &e) && e.Index >= 0 && e.Index < len(groupIndexes[i]) {
+				e.Index = groupIndexes[i][e.Index]
+			}
+			return nil, err
+		}
+		nodes = append(nodes, node)
+		if i == len(groups)-1 { // ignore the logical type of the last group
+			break
+		}
+		last := group[len(group)-1]
+		if err = last.convertLogic(); err != nil {
+			return nil, withIndex(err, groupIndexes[i][len(group)-1])
+		}
+		logics = append(logics, last.Logic)
+	}
+	return combineNodes(nodes, logics), nil
+}
+
+// split the columns to the groups ordered by their first columns, each column without group or with the invalid
+// group is a group of itself, the columns are copied
+func splitGroups(columns []Column) [][]Column {
+	return indexGroups(columns, splitGroupIndexes(columns))
+}
+
+// the indexes of the columns of the groups, see splitGroups
+func splitGroupIndexes(columns []Column) [][]int {
+	groups := make([][]int, 0, len(columns))
+	indexes := make(map[string]int)
+	for i, column := range columns {
+		key, err := column.groupKey()
+		if err != nil || key == "" {
+			groups = append(groups, []int{i})
+			continue
+		}
+		if g, ok := indexes[key]; ok {
+			groups[g] = append(groups[g], i)
+			continue
+		}
+		indexes[key] = len(groups)
+		groups = append(groups, []int{i})
+	}
+	return groups
+}
+
+// the copies of the columns of the groups of the indexes
+func indexGroups(columns []Column, groupIndexes [][]int) [][]Column {
+	groups := make([][]Column, 0, len(groupIndexes))
+	for _, indexes := range groupIndexes {
+		group := make([]Column, 0, len(indexes))
+		for _, i := range indexes {
+			group = append(group, columns[i])
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// combine the nodes by the logics, the logic of the last node is ignored, and takes precedence over or
+func combineNodes(nodes []*filterNode, logics []string) *filterNode {
+	if len(nodes) == 1 {
+		return nodes[0]
+	}
+
+	var orNodes, andNodes []*filterNode
+	for i, node := range nodes {
+		andNodes = append(andNodes, node)
+		if i < len(nodes)-1 && logics[i] != orSymbol1 {
+			continue
+		}
+		if len(andNodes) == 1 {
+			orNodes = append(orNodes, andNodes[0])
+		} else {
+			orNodes = append(orNodes, &filterNode{logic: "$and", children: andNodes})
+		}
+		andNodes = nil
+	}
+	if len(orNodes) == 1 {
+		return orNodes[0]
+	}
+	return &filterNode{logic: "$or", children: orNodes}
+}
+
+// buildColumnsNode convert the columns without groups to the filter node, the index of the error is the index
+// of the column in the columns
+func buildColumnsNode(columns []Column, o *rulerOptions) (*filterNode, error) {
+	l := len(columns)
 	switch l {
 	case 0:
-		return bson.M{}, nil
+		return nil, nil
 
 	case 1: // l == 1
-		err := p.Columns[0].checkName(o.whitelistNames)
+		column := columns[0]
+		err := column.checkName(o)
 		if err != nil {
-			return nil, err
+			return nil, withIndex(err, 0)
 		}
-		err = p.Columns[0].convert()
+		err = column.convert(o)
 		if err != nil {
-			return nil, err
+			return nil, withIndex(err, 0)
 		}
-		filter[p.Columns[0].Name] = p.Columns[0].Value
-		return filter, nil
+		return column.node(), nil
 
 	case 2: // l == 2
-		err := p.Columns[0].checkName(o.whitelistNames)
+		column0, column1 := columns[0], columns[1]
+		err := column0.checkName(o)
 		if err != nil {
-			return nil, err
+			return nil, withIndex(err, 0)
 		}
-		err = p.Columns[1].checkName(o.whitelistNames)
+		err = column1.checkName(o)
 		if err != nil {
-			return nil, err
+			return nil, withIndex(err, 1)
 		}
-		err = p.Columns[0].convert()
+		err = column0.convert(o)
 		if err != nil {
-			return nil, err
+			return nil, withIndex(err, 0)
 		}
-		err = p.Columns[1].convert()
+		err = column1.convert(o)
 		if err != nil {
-			return nil, err
+			return nil, withIndex(err, 1)
 		}
-		if p.Columns[0].Logic == andSymbol1 {
-			filter = bson.M{"$and": []bson.M{
-				{p.Columns[0].Name: p.Columns[0].Value},
-				{p.Columns[1].Name: p.Columns[1].Value}}}
-		} else {
-			filter = bson.M{"$or": []bson.M{
-				{p.Columns[0].Name: p.Columns[0].Value},
-				{p.Columns[1].Name: p.Columns[1].Value}}}
+		logic := "$or"
+		if column0.Logic == andSymbol1 {
+			logic = "$and"
 		}
-		return filter, nil
+		return &filterNode{logic: logic, children: []*filterNode{column0.node(), column1.node()}}, nil
 
 	default: // l >=3
-		return p.convertMultiColumns(o.whitelistNames)
+		return convertMultiColumns(columns, o)
 	}
 }
 
-func (p *Params) convertMultiColumns(whitelistNames map[string]bool) (bson.M, error) {
-	filter := bson.M{}
-	logicType, groupIndexes, err := checkSameLogic(p.Columns)
+func convertMultiColumns(columns []Column, o *rulerOptions) (*filterNode, error) {
+	logicType, groupIndexes, err := checkSameLogic(columns)
 	if err != nil {
 		return nil, err
 	}
-	if logicType == allLogicAnd {
-		for _, column := range p.Columns {
-			err = column.checkName(whitelistNames)
-			if err != nil {
-				return nil, err
-			}
 
-			err = column.convert()
+	// the nodes of the columns are allocated at once
+	columnNodes := make([]filterNode, len(columns))
+	convertGroup := func(indexes []int) ([]*filterNode, error) {
+		nodes := make([]*filterNode, 0, len(indexes))
+		for _, index := range indexes {
+			column := columns[index] // the copy is converted
+			err := column.checkName(o)
 			if err != nil {
-				return nil, err
-			}
-			if v, ok := filter["$and"]; !ok {
-				filter["$and"] = []bson.M{{column.Name: column.Value}}
-			} else {
-				if cols, ok1 := v.([]bson.M); ok1 {
-					cols = append(cols, bson.M{column.Name: column.Value})
-					filter["$and"] = cols
-				}
+				return nil, withIndex(err, index)
 			}
-		}
-		return filter, nil
-	} else if logicType == allLogicOr {
-		for _, column := range p.Columns {
-			err = column.convert()
+			err = column.convert(o)
 			if err != nil {
-				return nil, err
-			}
-			if v, ok := filter["$or"]; !ok {
-				filter["$or"] = []bson.M{{column.Name: column.Value}}
-			} else {
-				if cols, ok1 := v.([]bson.M); ok1 {
-					cols = append(cols, bson.M{column.Name: column.Value})
-					filter["$or"] = cols
-				}
+				return nil, withIndex(err, index)
 			}
+			columnNodes[index] = filterNode{name: column.Name, value: column.Value}
+			nodes = append(nodes, &columnNodes[index])
 		}
-		return filter, nil
+		return nodes, nil
 	}
-	orConditions := []bson.M{}
+
+	if logicType == allLogicAnd || logicType == allLogicOr {
+		indexes := make([]int, len(columns))
+		for i := range indexes {
+			indexes[i] = i
+		}
+		nodes, err := convertGroup(indexes)
+		if err != nil {
+			return nil, err
+		}
+		logic := "$and"
+		if logicType == allLogicOr {
+			logic = "$or"
+		}
+		return &filterNode{logic: logic, children: nodes}, nil
+	}
+
+	orNodes := make([]*filterNode, 0, len(groupIndexes))
 	for _, indexes := range groupIndexes {
-		if len(indexes) == 1 {
-			column := p.Columns[indexes[0]]
-			err := column.convert()
-			if err != nil {
-				return nil, err
+		nodes, err := convertGroup(indexes)
+		if err != nil {
+			return nil, err
+		}
+		if len(nodes) == 1 {
+			orNodes = append(orNodes, nodes[0])
+		} else {
+			orNodes = append(orNodes, &filterNode{logic: "$and", children: nodes})
+		}
+	}
+	if len(orNodes) == 1 {
+		return orNodes[0], nil
+	}
+	return &filterNode{logic: "$or", children: orNodes}, nil
+}
+
+// the pattern of the regex exp is checked by regexp.Compile instead of being pushed to mongodb, note that
+// the syntax of go (RE2) is a subset of the syntax of mongodb (PCRE), e.g. the lookaround is rejected
+func checkRegex(v interface{}, o *rulerOptions) (string, error) {
+	if !o.allowRegexExp {
+		return "", fmt.Errorf("exp type '%s' is not allowed", Regex)
+	}
+	pattern, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("invalid regex value type '%T'", v)
+	}
+	if len(pattern) > o.maxRegexLength {
+		return "", fmt.Errorf("regex length %d exceeds the limit %d", len(pattern), o.maxRegexLength)
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		return "", fmt.Errorf("invalid regex: %v", err)
+	}
+	return pattern, nil
+}
+
+// the value of in and nin is a comma-separated string or a slice, e.g. ["a","b"] decoded from json, the string
+// in the slice is not split by comma, the elements of 24-char hex are converted to ObjectID if autoOID is true
+func parseInValues(v interface{}, autoOID bool) ([]interface{}, error) {
+	values, err := listValues(v)
+	if err != nil {
+		return nil, err
+	}
+	if autoOID {
+		for i, val := range values {
+			values[i] = toInValue(val)
+		}
+	}
+	return values, nil
+}
+
+// the number of the columns and the number of the values of in, nin and all are checked before the conversion,
+// the values are counted without parsing, e.g. the commas of the string
+func (o *rulerOptions) checkLimits(columns []Column) error {
+	if o.maxColumns > 0 && len(columns) > o.maxColumns {
+		return newError(ErrTooManyColumns, "", "the number of columns %d exceeds the limit %d", len(columns), o.maxColumns)
+	}
+	if o.maxInValues <= 0 {
+		return nil
+	}
+	for i, c := range columns {
+		switch v, _ := lookupExp(c.Exp); v {
+		case In, NotIn, All:
+			if n := countValues(c.Value); n > o.maxInValues {
+				return withIndex(newError(ErrTooManyValues, c.Name, "column '%s': the number of %s values %d exceeds the limit %d",
+					c.Name, v, n, o.maxInValues), i)
 			}
-			orConditions = append(orConditions, bson.M{column.Name: column.Value})
+		}
+	}
+	return nil
+}
+
+func countValues(v interface{}) int {
+	if s, ok := v.(string); ok {
+		return strings.Count(s, ",") + 1
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Slice || (rv.Kind() == reflect.Array && rv.Type() != oidType) {
+		return rv.Len()
+	}
+	return 1
+}
+
+// the name converted by segment, the name is returned as it is without the converter
+func (o *rulerOptions) fieldName(name string) string {
+	if o.convertName == nil {
+		return name
+	}
+	segments := strings.Split(name, ".")
+	for i, segment := range segments {
+		segments[i] = o.convertName(segment)
+	}
+	return strings.Join(segments, ".")
+}
+
+// the sort fields converted by name, the - sign of the descending order is kept
+func (o *rulerOptions) sortNames(columnNames string) string {
+	if o.convertName == nil || columnNames == "" {
+		return columnNames
+	}
+	names := strings.Split(strings.Replace(columnNames, " ", "", -1), ",")
+	for i, name := range names {
+		if strings.HasPrefix(name, "-") {
+			names[i] = "-" + o.fieldName(name[1:])
 		} else {
-			andConditions := []bson.M{}
-			for _, index := range indexes {
-				column := p.Columns[index]
-				err := column.convert()
-				if err != nil {
-					return nil, err
+			names[i] = o.fieldName(name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// CamelToSnake convert the camelCase name to the snake_case name, the acronyms are kept as one word, e.g. userID
+// becomes user_id, HTTPServer becomes http_server, the snake_case name is returned unchanged
+func CamelToSnake(name string) string {
+	runes := []rune(name)
+	b := strings.Builder{}
+	b.Grow(len(name) + 4)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && runes[i-1] != '_' {
+				prev := runes[i-1]
+				nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+				if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+					b.WriteByte('_')
 				}
-				andConditions = append(andConditions, bson.M{column.Name: column.Value})
 			}
-			orConditions = append(orConditions, bson.M{"$and": andConditions})
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// the elements of the value of in and nin without conversion
+func listValues(v interface{}) ([]interface{}, error) {
+	var values []interface{}
+	switch vals := v.(type) {
+	case string:
+		ss := strings.Split(vals, ",")
+		values = make([]interface{}, len(ss))
+		for i, s := range ss {
+			values[i] = s
+		}
+	case primitive.ObjectID: // a single 24-char hex string is converted already
+		values = []interface{}{vals}
+	case []interface{}:
+		values = make([]interface{}, len(vals))
+		copy(values, vals)
+	case []string:
+		values = make([]interface{}, len(vals))
+		for i, val := range vals {
+			values[i] = val
+		}
+	case []int:
+		values = make([]interface{}, len(vals))
+		for i, val := range vals {
+			values[i] = val
+		}
+	case []float64:
+		values = make([]interface{}, len(vals))
+		for i, val := range vals {
+			values[i] = val
 		}
+	default:
+		return nil, fmt.Errorf("invalid value type '%T', it should be a comma-separated string or a slice", v)
 	}
-	filter["$or"] = orConditions
+	return values, nil
+}
 
-	return filter, nil
+// the name of the in and nin column is converted in the same way as the scalar value, id is renamed to _id if
+// there is an ObjectID in the values, and the suffix :oid is trimmed, then all the values of _id and the :oid
+// column must be ObjectIDs, the other columns keep the values which are not ObjectIDs
+func (c *Column) convertInName(values []interface{}) error {
+	hasOID, allOID := false, true
+	for _, v := range values {
+		if _, ok := v.(primitive.ObjectID); ok {
+			hasOID = true
+		} else {
+			allOID = false
+		}
+	}
+
+	isOIDColumn := strings.HasSuffix(c.Name, ":oid")
+	if isOIDColumn {
+		c.Name = strings.TrimSuffix(c.Name, ":oid")
+	} else if c.Name == "id" && hasOID {
+		c.Name = "_id" // force to "_id"
+	}
+	if (isOIDColumn || c.Name == "_id") && !allOID {
+		for _, v := range values {
+			if _, ok := v.(primitive.ObjectID); !ok {
+				return fmt.Errorf("'%v' is not a valid ObjectID", v)
+			}
+		}
+	}
+	return nil
+}
+
+func toInValue(v interface{}) interface{} {
+	if oid, ok := isObjectID(v); ok {
+		return oid
+	}
+	return v
+}
+
+// the value of exists is bool, the strings "true"/"false" or the numbers 1/0
+func parseExistsValue(v interface{}) (bool, error) {
+	switch val := v.(type) {
+	case bool:
+		return val, nil
+	case string:
+		switch strings.ToLower(val) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		switch fmt.Sprintf("%v", val) {
+		case "1":
+			return true, nil
+		case "0":
+			return false, nil
+		}
+	}
+	return false, fmt.Errorf("invalid exists value '%v', it should be true, false, 1 or 0", v)
+}
+
+// the value of between is a two-element slice or a comma-separated string, e.g. "2024-01-01T00:00:00Z,2024-02-01T00:00:00Z",
+// the strings are converted to ObjectID (if autoOID is true), time (RFC3339) or number if possible,
+// isOID reports both are ObjectID.
+func parseBetweenValue(v interface{}, autoOID bool) (lo interface{}, hi interface{}, isOID bool, err error) {
+	parts, err := betweenParts(v)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	lo, hi = parseRangeValue(parts[0], autoOID), parseRangeValue(parts[1], autoOID)
+	_, loIsOID := lo.(primitive.ObjectID)
+	_, hiIsOID := hi.(primitive.ObjectID)
+	return lo, hi, loIsOID && hiIsOID, nil
+}
+
+// the two parts of the value of between without conversion
+func betweenParts(v interface{}) ([]interface{}, error) {
+	var parts []interface{}
+	if str, ok := v.(string); ok {
+		for _, s := range strings.Split(str, ",") {
+			parts = append(parts, strings.TrimSpace(s))
+		}
+	} else if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		for i := 0; i < rv.Len(); i++ {
+			parts = append(parts, rv.Index(i).Interface())
+		}
+	}
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("between value '%v' should contain exactly two parts", v)
+	}
+	return parts, nil
+}
+
+func parseRangeValue(v interface{}, autoOID bool) interface{} {
+	str, ok := v.(string)
+	if !ok {
+		return v
+	}
+	if oid, ok := isObjectID(str); ok && autoOID {
+		return oid
+	}
+	if t, err := time.Parse(time.RFC3339, str); err == nil {
+		return t
+	}
+	if n, err := strconv.ParseInt(str, 10, 64); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(str, 64); err == nil {
+		return f
+	}
+	return str
 }
 
 func isObjectID(v interface{}) (primitive.ObjectID, bool) {
@@ -367,15 +1714,15 @@ func isObjectID(v interface{}) (primitive.ObjectID, bool) {
 }
 
 func checkSameLogic(columns []Column) (int, [][]int, error) {
-	orIndexes := []int{}
 	l := len(columns)
+	orIndexes := make([]int, 0, l)
 	for i, column := range columns {
 		if i == l-1 { // ignore the logical type of the last column
 			break
 		}
 		err := column.convertLogic()
 		if err != nil {
-			return 0, nil, err
+			return 0, nil, withIndex(err, i)
 		}
 		if column.Logic == orSymbol1 {
 			orIndexes = append(orIndexes, i)
@@ -393,26 +1740,27 @@ func checkSameLogic(columns []Column) (int, [][]int, error) {
 	return 0, groupIndexes, nil
 }
 
+// split the indexes of the columns to the groups joined by or, the or logic of a column ends its group, e.g. the
+// logics [or, or, and, and] are grouped to [0] [1] [2, 3]. The or indexes not increasing or out of the range of the
+// columns except the last one are ignored, so there is no empty group.
 func groupingIndex(l int, orIndexes []int) [][]int {
-	groupIndexes := [][]int{}
-	lastIndex := 0
+	// all the groups share one backing array of the indexes
+	indexes := make([]int, l)
+	for i := range indexes {
+		indexes[i] = i
+	}
+	groupIndexes := make([][]int, 0, len(orIndexes)+1)
+	start := 0
 	for _, index := range orIndexes {
-		group := []int{}
-		for i := lastIndex; i <= index; i++ {
-			group = append(group, i)
-		}
-		groupIndexes = append(groupIndexes, group)
-		if lastIndex == index {
-			lastIndex++
-		} else {
-			lastIndex = index
+		if index < start || index >= l-1 {
+			continue
 		}
+		groupIndexes = append(groupIndexes, indexes[start:index+1:index+1])
+		start = index + 1
 	}
-	group := []int{}
-	for i := lastIndex + 1; i < l; i++ {
-		group = append(group, i)
+	if start < l {
+		groupIndexes = append(groupIndexes, indexes[start:])
 	}
-	groupIndexes = append(groupIndexes, group)
 	return groupIndexes
 }
 
@@ -424,22 +1772,22 @@ type Conditions struct {
 // CheckValid check valid
 func (c *Conditions) CheckValid() error {
 	if len(c.Columns) == 0 {
-		return fmt.Errorf("field 'columns' cannot be empty")
+		return newError(ErrEmptyColumns, "", "field 'columns' cannot be empty")
 	}
 
-	for _, column := range c.Columns {
+	for i, column := range c.Columns {
 		err := column.checkValid()
 		if err != nil {
-			return err
+			return withIndex(err, i)
 		}
 		if column.Exp != "" {
-			if _, ok := expMap[column.Exp]; !ok {
-				return fmt.Errorf("unknown exp type '%s'", column.Exp)
+			if _, ok := lookupExp(column.Exp); !ok {
+				return withIndex(newError(ErrUnsupportedExp, column.Name, "unknown exp type '%s'", column.Exp), i)
 			}
 		}
 		if column.Logic != "" {
-			if _, ok := logicMap[column.Logic]; !ok {
-				return fmt.Errorf("unknown logic type '%s'", column.Logic)
+			if _, ok := lookupLogic(column.Logic); !ok {
+				return withIndex(newError(ErrUnknownLogic, column.Name, "unknown logic type '%s'", column.Logic), i)
 			}
 		}
 	}
@@ -1,11 +1,17 @@
 package query
 
 import (
+	"encoding/json"
 	"errors"
 	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -31,6 +37,58 @@ func TestParams_ConvertToPage(t *testing.T) {
 	t.Logf("order=%v, limit=%d, skip=%d", order, limit, offset)
 }
 
+func TestParams_ConvertToQuery(t *testing.T) {
+	defer SetMaxSize(defaultMaxSize)
+	SetMaxSize(1000)
+
+	p := &Params{
+		Page:    2,
+		Limit:   50,
+		Sort:    "-age,name",
+		Columns: []Column{{Name: "name", Value: "ZhangSan"}},
+	}
+	whitelist := map[string]bool{"name": true, "age": true}
+
+	filter, findOpts, err := p.ConvertToQuery(WithWhitelistNames(whitelist), WithMaxLimit(20))
+	require.NoError(t, err)
+	assert.Equal(t, bson.M{"name": "ZhangSan"}, filter)
+	assert.Equal(t, bson.D{{Key: "age", Value: -1}, {Key: "name", Value: 1}}, findOpts.Sort)
+	assert.Equal(t, int64(20), *findOpts.Limit)
+	assert.Equal(t, int64(40), *findOpts.Skip)
+
+	// the limit is not clamped without the max limit, the default sort is allowed by the whitelist
+	p2 := &Params{Page: 1, Limit: 50}
+	filter, findOpts, err = p2.ConvertToQuery(WithWhitelistNames(whitelist))
+	require.NoError(t, err)
+	assert.Empty(t, filter)
+	assert.Equal(t, bson.D{{Key: "_id", Value: -1}}, findOpts.Sort)
+	assert.Equal(t, int64(50), *findOpts.Limit)
+	assert.Equal(t, int64(50), *findOpts.Skip)
+
+	// the sort fields are converted by name
+	p3 := &Params{Limit: 10, Sort: "-createdAt"}
+	_, findOpts, err = p3.ConvertToQuery(WithFieldNameConverter(CamelToSnake))
+	require.NoError(t, err)
+	assert.Equal(t, bson.D{{Key: "created_at", Value: -1}}, findOpts.Sort)
+
+	// the sort field and the column not in the whitelist are rejected
+	for _, p := range []*Params{
+		{Limit: 10, Sort: "-password"},
+		{Limit: 10, Sort: "$natural"},
+		{Limit: 10, Columns: []Column{{Name: "password", Value: "123456"}}},
+	} {
+		filter, findOpts, err = p.ConvertToQuery(WithWhitelistNames(whitelist))
+		assert.ErrorIs(t, err, ErrNameNotAllowed)
+		assert.Nil(t, filter)
+		assert.Nil(t, findOpts)
+	}
+
+	// the limit of ConvertToPage is clamped in the same way
+	_, limit, skip := p.ConvertToPage(WithMaxLimit(20))
+	assert.Equal(t, 20, limit)
+	assert.Equal(t, 40, skip)
+}
+
 func TestParams_ConvertToMongoFilter(t *testing.T) {
 	type args struct {
 		columns []Column
@@ -537,12 +595,187 @@ func TestParams_ConvertToMongoFilter(t *testing.T) {
 			want:    nil,
 			wantErr: true,
 		},
+		{
+			name: "1 column exists",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "deleted_at",
+						Exp:   "exists",
+						Value: false,
+					},
+				},
+			},
+			want:    bson.M{"deleted_at": bson.M{"$exists": false}},
+			wantErr: false,
+		},
+		{
+			name: "1 column notexists string value",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "deleted_at",
+						Exp:   "notexists",
+						Value: "true",
+					},
+				},
+			},
+			want:    bson.M{"deleted_at": bson.M{"$exists": false}},
+			wantErr: false,
+		},
+		{
+			name: "1 column exists numeric value",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "avatar",
+						Exp:   "EXISTS",
+						Value: float64(1),
+					},
+				},
+			},
+			want:    bson.M{"avatar": bson.M{"$exists": true}},
+			wantErr: false,
+		},
+		{
+			name: "1 column exists invalid value",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "deleted_at",
+						Exp:   "exists",
+						Value: 2,
+					},
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "3 columns exists mix and or",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "age",
+						Exp:   ">=",
+						Value: 18,
+					},
+					{
+						Name:  "deleted_at",
+						Exp:   "exists",
+						Value: "false",
+						Logic: "||",
+					},
+					{
+						Name:  "vip",
+						Exp:   "notexists",
+						Value: 0,
+					},
+				},
+			},
+			want: bson.M{"$or": []bson.M{
+				{"$and": []bson.M{{"age": bson.M{"$gte": 18}}, {"deleted_at": bson.M{"$exists": false}}}},
+				{"vip": bson.M{"$exists": true}},
+			}},
+			wantErr: false,
+		},
+		{
+			name: "1 column between numbers",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "age",
+						Exp:   "between",
+						Value: "18, 30",
+					},
+				},
+			},
+			want:    bson.M{"age": bson.M{"$gte": int64(18), "$lte": int64(30)}},
+			wantErr: false,
+		},
+		{
+			name: "1 column between slice",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "score",
+						Exp:   "between",
+						Value: []interface{}{float64(1.5), float64(9.5)},
+					},
+				},
+			},
+			want:    bson.M{"score": bson.M{"$gte": float64(1.5), "$lte": float64(9.5)}},
+			wantErr: false,
+		},
+		{
+			name: "1 column between timestamps",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "created_at",
+						Exp:   "between",
+						Value: []string{"2024-01-01T00:00:00Z", "2024-02-01T00:00:00+08:00"},
+					},
+				},
+			},
+			want: bson.M{"created_at": bson.M{
+				"$gte": time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				"$lte": time.Date(2024, 2, 1, 0, 0, 0, 0, time.FixedZone("", 8*3600)),
+			}},
+			wantErr: false,
+		},
+		{
+			name: "1 column between object ids",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "id",
+						Exp:   "between",
+						Value: "65ce48483f11aff697e30d6d,65ce48483f11aff697e30d7f",
+					},
+				},
+			},
+			want: bson.M{"_id": bson.M{
+				"$gte": oidFromHex("65ce48483f11aff697e30d6d"),
+				"$lte": oidFromHex("65ce48483f11aff697e30d7f"),
+			}},
+			wantErr: false,
+		},
+		{
+			name: "1 column between three parts",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "age",
+						Exp:   "between",
+						Value: "1,2,3",
+					},
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "1 column between one element",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "age",
+						Exp:   "between",
+						Value: []int{1},
+					},
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
 		{
 			name: "value empty",
 			args: args{
 				columns: []Column{
 					{
 						Name:  "name",
+						Exp:   Gt,
 						Value: nil,
 					},
 				},
@@ -576,6 +809,680 @@ func TestParams_ConvertToMongoFilter(t *testing.T) {
 	}
 }
 
+func oidFromHex(s string) primitive.ObjectID {
+	oid, _ := primitive.ObjectIDFromHex(s)
+	return oid
+}
+
+func TestParams_ConvertToMongoFilter_Between(t *testing.T) {
+	p := &Params{Columns: []Column{{Name: "created_at", Exp: "between", Value: 10}}}
+	_, err := p.ConvertToMongoFilter()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "column 'created_at'")
+
+	columns := []Column{{Name: "age", Exp: "between", Value: "18,30", Logic: "||"}, {Name: "vip", Value: true}}
+	assert.Equal(t, "age BETWEEN 18 AND 30 OR vip = true", DescribeFilter(columns))
+}
+
+func TestParams_ConvertToMongoFilter_InSlice(t *testing.T) {
+	oid := "65ce48483f11aff697e30d6d"
+	tests := []struct {
+		name  string
+		value interface{}
+		want  []interface{}
+	}{
+		{"string", "a,b", []interface{}{"a", "b"}},
+		{"string with oid", "a," + oid, []interface{}{"a", oidFromHex(oid)}},
+		{"[]interface{}", []interface{}{"a,b", float64(1), oid}, []interface{}{"a,b", float64(1), oidFromHex(oid)}},
+		{"[]string", []string{"a,b", "c"}, []interface{}{"a,b", "c"}},
+		{"[]int", []int{1, 2}, []interface{}{1, 2}},
+		{"[]float64", []float64{1.5, 2}, []interface{}{1.5, float64(2)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, exp := range []string{In, NotIn} {
+				p := &Params{Columns: []Column{{Name: "name", Exp: exp, Value: tt.value}}}
+				got, err := p.ConvertToMongoFilter()
+				assert.NoError(t, err)
+				assert.Equal(t, bson.M{"name": bson.M{"$" + exp: tt.want}}, got)
+			}
+		})
+	}
+
+	// the slice decoded from json
+	var columns []Column
+	err := json.Unmarshal([]byte(`[{"name":"name","exp":"in","value":["a","b,c"]}]`), &columns)
+	assert.NoError(t, err)
+	got, err := (&Params{Columns: columns}).ConvertToMongoFilter()
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{"name": bson.M{"$in": []interface{}{"a", "b,c"}}}, got)
+
+	p := &Params{Columns: []Column{{Name: "name", Exp: In, Value: []bool{true}}}}
+	_, err = p.ConvertToMongoFilter()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "column 'name'")
+	}
+}
+
+func TestParams_ConvertToMongoFilter_InObjectID(t *testing.T) {
+	oid1, oid2 := "65ce48483f11aff697e30d6d", "65ce48483f11aff697e30d6e"
+	tests := []struct {
+		name    string
+		column  Column
+		want    bson.M
+		wantErr bool
+	}{
+		{
+			name:   "id",
+			column: Column{Name: "id", Exp: In, Value: oid1 + "," + oid2},
+			want:   bson.M{"_id": bson.M{"$in": []interface{}{oidFromHex(oid1), oidFromHex(oid2)}}},
+		},
+		{
+			name:   "single id",
+			column: Column{Name: "id", Exp: NotIn, Value: oid1},
+			want:   bson.M{"_id": bson.M{"$nin": []interface{}{oidFromHex(oid1)}}},
+		},
+		{
+			name:   "_id slice",
+			column: Column{Name: "_id", Exp: In, Value: []string{oid1, oid2}},
+			want:   bson.M{"_id": bson.M{"$in": []interface{}{oidFromHex(oid1), oidFromHex(oid2)}}},
+		},
+		{
+			name:   "oid suffix",
+			column: Column{Name: "user_id:oid", Exp: In, Value: []interface{}{oid1, oid2}},
+			want:   bson.M{"user_id": bson.M{"$in": []interface{}{oidFromHex(oid1), oidFromHex(oid2)}}},
+		},
+		{
+			name:   "mixed",
+			column: Column{Name: "trace", Exp: In, Value: oid1 + ",abc"},
+			want:   bson.M{"trace": bson.M{"$in": []interface{}{oidFromHex(oid1), "abc"}}},
+		},
+		{
+			name:   "id without ObjectID",
+			column: Column{Name: "id", Exp: In, Value: []int{1, 2}},
+			want:   bson.M{"id": bson.M{"$in": []interface{}{1, 2}}},
+		},
+		{
+			name:    "mixed id",
+			column:  Column{Name: "id", Exp: In, Value: oid1 + ",abc"},
+			wantErr: true,
+		},
+		{
+			name:    "mixed _id",
+			column:  Column{Name: "_id", Exp: NotIn, Value: []interface{}{oid1, float64(1)}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid oid suffix",
+			column:  Column{Name: "user_id:oid", Exp: In, Value: "abc"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Params{Columns: []Column{tt.column}}
+			got, err := p.ConvertToMongoFilter()
+			if tt.wantErr {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), "is not a valid ObjectID")
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParams_ConvertToMongoFilter_Type(t *testing.T) {
+	oid1, oid2 := "65ce48483f11aff697e30d6d", "65ce48483f11aff697e30d6e"
+	t1 := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	t2 := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		column Column
+		want   bson.M
+	}{
+		{
+			name:   "int",
+			column: Column{Name: "age", Exp: Gt, Value: "30", Type: TypeInt},
+			want:   bson.M{"age": bson.M{"$gt": int64(30)}},
+		},
+		{
+			name:   "int from json number",
+			column: Column{Name: "age", Value: float64(30), Type: TypeInt},
+			want:   bson.M{"age": int64(30)},
+		},
+		{
+			name:   "float",
+			column: Column{Name: "score", Exp: Lte, Value: "9.5", Type: "FLOAT"},
+			want:   bson.M{"score": bson.M{"$lte": 9.5}},
+		},
+		{
+			name:   "bool",
+			column: Column{Name: "vip", Exp: Neq, Value: "1", Type: TypeBool},
+			want:   bson.M{"vip": bson.M{"$ne": true}},
+		},
+		{
+			name:   "datetime RFC3339",
+			column: Column{Name: "created_at", Exp: Gte, Value: "2024-01-02T03:04:05Z", Type: TypeDatetime},
+			want:   bson.M{"created_at": bson.M{"$gte": t1}},
+		},
+		{
+			name:   "datetime",
+			column: Column{Name: "created_at", Exp: Lt, Value: "2024-01-02 03:04:05", Type: TypeDatetime},
+			want:   bson.M{"created_at": bson.M{"$lt": t1}},
+		},
+		{
+			name:   "oid",
+			column: Column{Name: "id", Value: oid1, Type: TypeOID},
+			want:   bson.M{"_id": oidFromHex(oid1)},
+		},
+		{
+			name:   "string is not converted to ObjectID",
+			column: Column{Name: "id", Value: oid1, Type: TypeString},
+			want:   bson.M{"id": oid1},
+		},
+		{
+			name:   "string from number",
+			column: Column{Name: "code", Value: float64(1001), Type: TypeString},
+			want:   bson.M{"code": "1001"},
+		},
+		{
+			name:   "in",
+			column: Column{Name: "age", Exp: In, Value: "18, 20,30", Type: TypeInt},
+			want:   bson.M{"age": bson.M{"$in": []interface{}{int64(18), int64(20), int64(30)}}},
+		},
+		{
+			name:   "nin oid",
+			column: Column{Name: "user_id:oid", Exp: NotIn, Value: []interface{}{oid1, oid2}, Type: TypeOID},
+			want:   bson.M{"user_id": bson.M{"$nin": []interface{}{oidFromHex(oid1), oidFromHex(oid2)}}},
+		},
+		{
+			name:   "between",
+			column: Column{Name: "created_at", Exp: Between, Value: "2024-01-02 03:04:05,2024-02-01T00:00:00Z", Type: TypeDatetime},
+			want:   bson.M{"created_at": bson.M{"$gte": t1, "$lte": t2}},
+		},
+		{
+			name:   "between float",
+			column: Column{Name: "score", Exp: Between, Value: []interface{}{"1", float64(2)}, Type: TypeFloat},
+			want:   bson.M{"score": bson.M{"$gte": float64(1), "$lte": float64(2)}},
+		},
+		{
+			name:   "like ignores the type",
+			column: Column{Name: "name", Exp: Like, Value: "foo", Type: TypeString},
+			want:   bson.M{"name": bson.M{"$regex": "foo", "$options": "i"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Params{Columns: []Column{tt.column}}
+			got, err := p.ConvertToMongoFilter()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	errCases := []struct {
+		column Column
+		errMsg string
+	}{
+		{Column{Name: "age", Exp: Gt, Value: "abc", Type: TypeInt}, "column 'age': value 'abc' is not a valid int"},
+		{Column{Name: "age", Value: 1.5, Type: TypeInt}, "column 'age': value '1.5' is not a valid int"},
+		{Column{Name: "vip", Value: "yes", Type: TypeBool}, "column 'vip': value 'yes' is not a valid bool"},
+		{Column{Name: "created_at", Value: "2024/01/02", Type: TypeDatetime}, "column 'created_at': value '2024/01/02' is not a valid datetime"},
+		{Column{Name: "_id", Value: "abc", Type: TypeOID}, "column '_id': value 'abc' is not a valid oid"},
+		{Column{Name: "age", Exp: In, Value: "1,x", Type: TypeInt}, "column 'age': value 'x' is not a valid int"},
+		{Column{Name: "score", Exp: Between, Value: "1,x", Type: TypeFloat}, "column 'score': value 'x' is not a valid float"},
+		{Column{Name: "age", Value: "1", Type: "uint"}, "column 'age': unknown type 'uint'"},
+	}
+	for _, c := range errCases {
+		p := &Params{Columns: []Column{c.column}}
+		_, err := p.ConvertToMongoFilter()
+		if assert.Error(t, err) {
+			assert.Equal(t, c.errMsg, err.Error())
+		}
+	}
+}
+
+// the columns are not modified by the conversion, the same params can be converted again, e.g. for counting and
+// paging, for retrying, or cached by the middleware
+func TestParams_ConvertToMongoFilter_Idempotent(t *testing.T) {
+	oid1, oid2 := "65ce48483f11aff697e30d6d", "65ce48483f11aff697e30d6e"
+	newColumns := func() []Column {
+		return []Column{
+			{Name: "id", Value: oid1, Logic: "||"},
+			{Name: "user_id:oid", Exp: "in", Value: []interface{}{oid1, oid2}},
+			{Name: "age", Exp: "gt", Value: "18", Type: "INT", Logic: "or"},
+			{Name: "name", Exp: "like", Value: "foo"},
+			{Name: "created_at", Exp: "between", Value: []interface{}{"2024-01-01T00:00:00Z", "2024-02-01T00:00:00Z"}, Logic: "&"},
+			{Name: "status", Exp: "not in", Value: []string{"a", "b"}},
+			{Name: "deleted_at", Exp: "notexists", Value: "true"},
+			{Name: "email", Exp: "regex", Value: "^foo"},
+		}
+	}
+
+	for _, l := range []int{1, 2, len(newColumns())} {
+		p := &Params{Columns: newColumns()[:l]}
+		first, err := p.ConvertToMongoFilter()
+		assert.NoError(t, err)
+		assert.Equal(t, newColumns()[:l], p.Columns)
+
+		second, err := p.ConvertToMongoFilter()
+		assert.NoError(t, err)
+		assert.Equal(t, first, second)
+		assert.Equal(t, newColumns()[:l], p.Columns)
+
+		c := &Conditions{Columns: p.Columns}
+		third, err := c.ConvertToMongo()
+		assert.NoError(t, err)
+		assert.Equal(t, first, third)
+		assert.Equal(t, newColumns()[:l], c.Columns)
+	}
+}
+
+func TestParams_ConvertToMongoFilter_Regex(t *testing.T) {
+	p := &Params{Columns: []Column{{Name: "name", Exp: "regex", Value: "^foo.*(bar|baz)$"}}}
+	got, err := p.ConvertToMongoFilter()
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{"name": bson.M{"$regex": "^foo.*(bar|baz)$", "$options": "i"}}, got)
+
+	// like escapes the value, regex does not
+	p = &Params{Columns: []Column{{Name: "name", Exp: "like", Value: "foo.*"}}}
+	got, err = p.ConvertToMongoFilter()
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{"name": bson.M{"$regex": `foo\.\*`, "$options": "i"}}, got)
+
+	errCases := []struct {
+		columns []Column
+		opts    []RulerOption
+		errMsg  string
+	}{
+		{[]Column{{Name: "name", Exp: "regex", Value: "foo(bar"}}, nil, "invalid regex"},
+		{[]Column{{Name: "name", Exp: "regex", Value: 10}}, nil, "invalid regex value type"},
+		{[]Column{{Name: "name", Exp: "regex", Value: "^foo$"}}, []RulerOption{WithAllowRegexExp(false)}, "not allowed"},
+		{[]Column{{Name: "name", Exp: "regex", Value: strings.Repeat("a", 257)}}, nil, "exceeds the limit 256"},
+		{[]Column{{Name: "name", Exp: "regex", Value: "^foobar$"}}, []RulerOption{WithMaxRegexLength(6)}, "exceeds the limit 6"},
+		{[]Column{{Name: "age", Value: 10}, {Name: "name", Exp: "regex", Value: "a++"}}, nil, "column 'name'"},
+	}
+	for _, c := range errCases {
+		p = &Params{Columns: c.columns}
+		_, err = p.ConvertToMongoFilter(c.opts...)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), c.errMsg)
+		}
+	}
+
+	assert.Equal(t, "name LIKE ^foo", DescribeFilter([]Column{{Name: "name", Exp: "regex", Value: "^foo"}}))
+}
+
+func TestParams_ConvertToMongoFilter_NotLike(t *testing.T) {
+	p := &Params{Columns: []Column{{Name: "name", Exp: "nlike", Value: "a.b*"}}}
+	got, err := p.ConvertToMongoFilter()
+	assert.NoError(t, err)
+	regex := primitive.Regex{Pattern: `a\.b\*`, Options: "i"}
+	assert.Equal(t, bson.M{"name": bson.M{"$not": regex}}, got)
+
+	// the metacharacters are matched literally
+	re := regexp.MustCompile("(?" + regex.Options + ")" + regex.Pattern)
+	assert.True(t, re.MatchString("xA.B*y"))
+	assert.False(t, re.MatchString("axbb"))
+
+	// the aliases, and the and/or grouping
+	p = &Params{Columns: []Column{
+		{Name: "age", Exp: "gt", Value: 18},
+		{Name: "name", Exp: "not like", Value: "foo", Logic: "or"},
+		{Name: "email", Exp: "NotLike", Value: 1},
+	}}
+	got, err = p.ConvertToMongoFilter()
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{"$or": []bson.M{
+		{"$and": []bson.M{{"age": bson.M{"$gt": 18}}, {"name": bson.M{"$not": primitive.Regex{Pattern: "foo", Options: "i"}}}}},
+		{"email": bson.M{"$not": primitive.Regex{Pattern: "1", Options: "i"}}},
+	}}, got)
+	assert.Equal(t, "(age > 18 AND name NOT LIKE foo) OR email NOT LIKE 1", DescribeFilter(p.Columns))
+
+	c := &Conditions{Columns: []Column{{Name: "name", Exp: "nlike", Value: "a"}, {Name: "email", Exp: "notlike", Value: "b"}, {Name: "sex", Exp: "not like", Value: "c"}}}
+	assert.NoError(t, c.CheckValid())
+	_, err = c.ConvertToMongo()
+	assert.NoError(t, err)
+}
+
+func TestParams_ConvertToMongoFilter_LikeVariants(t *testing.T) {
+	special := `a.b*c+d?e(f)g[h]i{j}k|l^m$n\o`
+	escaped := regexp.QuoteMeta(special)
+	cases := []struct {
+		exp     string
+		pattern string
+		match   []string
+		noMatch []string
+	}{
+		{Like, escaped, []string{"x" + special + "y", strings.ToUpper(special)}, []string{"axbbc"}},
+		{Contains, escaped, []string{"x" + special + "y"}, []string{"axbbc"}},
+		{StartsWith, "^" + escaped, []string{special + "y", strings.ToUpper(special)}, []string{"x" + special}},
+		{EndsWith, escaped + "$", []string{"x" + special}, []string{special + "y"}},
+	}
+	for _, c := range cases {
+		p := &Params{Columns: []Column{{Name: "name", Exp: c.exp, Value: special}}}
+		got, err := p.ConvertToMongoFilter()
+		assert.NoError(t, err, c.exp)
+		assert.Equal(t, bson.M{"name": bson.M{"$regex": c.pattern, "$options": "i"}}, got, c.exp)
+
+		// the special characters are matched literally
+		re := regexp.MustCompile("(?i)" + c.pattern)
+		for _, s := range c.match {
+			assert.True(t, re.MatchString(s), "%s %s", c.exp, s)
+		}
+		for _, s := range c.noMatch {
+			assert.False(t, re.MatchString(s), "%s %s", c.exp, s)
+		}
+
+		// case-sensitive
+		got, err = p.ConvertToMongoFilter(WithCaseSensitiveLike())
+		assert.NoError(t, err, c.exp)
+		assert.Equal(t, bson.M{"name": bson.M{"$regex": c.pattern}}, got, c.exp)
+	}
+
+	p := &Params{Columns: []Column{{Name: "name", Exp: "nlike", Value: "a.b"}}}
+	got, err := p.ConvertToMongoFilter(WithCaseSensitiveLike())
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{"name": bson.M{"$not": primitive.Regex{Pattern: `a\.b`}}}, got)
+
+	// the aliases, and the and/or grouping
+	p = &Params{Columns: []Column{
+		{Name: "name", Exp: "starts with", Value: "foo", Logic: "or"},
+		{Name: "email", Exp: "Ends With", Value: ".com"},
+		{Name: "phone", Exp: "STARTSWITH", Value: 138},
+	}}
+	got, err = p.ConvertToMongoFilter()
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{"$or": []bson.M{
+		{"name": bson.M{"$regex": "^foo", "$options": "i"}},
+		{"$and": []bson.M{
+			{"email": bson.M{"$regex": `\.com$`, "$options": "i"}},
+			{"phone": bson.M{"$regex": "^138", "$options": "i"}},
+		}},
+	}}, got)
+	assert.Equal(t, `name LIKE ^foo OR (email LIKE \.com$ AND phone LIKE ^138)`, DescribeFilter(p.Columns))
+}
+
+func TestParams_ConvertToMongoFilter_IEq(t *testing.T) {
+	columns := []Column{
+		{Name: "name", Exp: "ieq", Value: "Foo.Bar"},
+		{Name: "email", Exp: "ineq", Value: "a@b.com"},
+	}
+
+	// regex, the default strategy
+	for _, opts := range [][]RulerOption{nil, {WithIEqStrategy(IEqRegex)}, {WithIEqStrategy("unknown")}} {
+		p := &Params{Columns: columns}
+		got, err := p.ConvertToMongoFilter(opts...)
+		assert.NoError(t, err)
+		assert.Equal(t, bson.M{"$and": []bson.M{
+			{"name": bson.M{"$regex": `^Foo\.Bar$`, "$options": "i"}},
+			{"email": bson.M{"$not": primitive.Regex{Pattern: `^a@b\.com$`, Options: "i"}}},
+		}}, got)
+	}
+	re := regexp.MustCompile(`(?i)^Foo\.Bar$`)
+	assert.True(t, re.MatchString("foo.bar"))
+	assert.False(t, re.MatchString("fooxbar"))
+	assert.False(t, re.MatchString("foo.bar2"))
+
+	// expr, the multiple $expr are merged under $and
+	p := &Params{Columns: columns}
+	got, err := p.ConvertToMongoFilter(WithIEqStrategy(IEqExpr))
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{"$and": []bson.M{
+		{"$expr": bson.M{"$eq": []interface{}{bson.M{"$toLower": "$name"}, bson.M{"$literal": "foo.bar"}}}},
+		{"$expr": bson.M{"$ne": []interface{}{bson.M{"$toLower": "$email"}, bson.M{"$literal": "a@b.com"}}}},
+	}}, got)
+	assert.Equal(t, "lower(name) = foo.bar AND lower(email) != a@b.com", DescribeMongoFilter(got))
+	assert.Equal(t, "name LIKE ^Foo\\.Bar$ AND email NOT LIKE ^a@b\\.com$", DescribeFilter(columns))
+
+	// the value is the literal instead of the field path, and the groups of and/or
+	p = &Params{Columns: []Column{
+		{Name: "name", Exp: "ieq", Value: "$Password", Logic: "or"},
+		{Name: "age", Exp: "gt", Value: 18},
+		{Name: "code", Exp: "ieq", Value: "65ce48483f11aff697e30d6d"},
+	}}
+	got, err = p.ConvertToMongoFilter(WithIEqStrategy(IEqExpr))
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{"$or": []bson.M{
+		{"$expr": bson.M{"$eq": []interface{}{bson.M{"$toLower": "$name"}, bson.M{"$literal": "$password"}}}},
+		{"$and": []bson.M{
+			{"age": bson.M{"$gt": 18}},
+			{"$expr": bson.M{"$eq": []interface{}{bson.M{"$toLower": "$code"}, bson.M{"$literal": "65ce48483f11aff697e30d6d"}}}},
+		}},
+	}}, got)
+
+	// the name is checked before
+	p = &Params{Columns: []Column{{Name: "$where", Exp: "ieq", Value: "foo"}}}
+	_, err = p.ConvertToMongoFilter(WithIEqStrategy(IEqExpr))
+	assert.Error(t, err)
+}
+
+func TestParams_ConvertToMongoFilter_Null(t *testing.T) {
+	p := &Params{Columns: []Column{
+		{Name: "phone", Exp: "isnull"},
+		{Name: "email", Exp: "notnull", Value: "ignored", Logic: "or"},
+		{Name: "avatar", Exp: "is null", Value: nil},
+		{Name: "avatar", Exp: "exists", Value: true},
+	}}
+	got, err := p.ConvertToMongoFilter()
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{"$or": []bson.M{
+		{"$and": []bson.M{{"phone": bson.M{"$in": []interface{}{nil}}}, {"email": bson.M{"$ne": nil}}}},
+		{"$and": []bson.M{{"avatar": bson.M{"$in": []interface{}{nil}}}, {"avatar": bson.M{"$exists": true}}}},
+	}}, got)
+	assert.Equal(t, "(phone IS NULL AND email IS NOT NULL) OR (avatar IS NULL AND avatar EXISTS true)", DescribeFilter(p.Columns))
+	assert.Equal(t, "phone IS NULL", DescribeMongoFilter(bson.M{"phone": bson.M{"$in": bson.A{nil}}}))
+
+	// the aliases
+	for _, exp := range []string{"isnotnull", "is not null", "NotNull"} {
+		p = &Params{Columns: []Column{{Name: "phone", Exp: exp}}}
+		got, err = p.ConvertToMongoFilter()
+		assert.NoError(t, err, exp)
+		assert.Equal(t, bson.M{"phone": bson.M{"$ne": nil}}, got, exp)
+	}
+
+	c := &Conditions{Columns: []Column{{Name: "phone", Exp: "isnull"}, {Name: "email", Exp: "notnull"}}}
+	assert.NoError(t, c.CheckValid())
+	_, err = c.ConvertToMongo()
+	assert.NoError(t, err)
+
+	// the nil value of eq and neq is the explicit null equality, it is not cast by the type
+	for _, tt := range []struct {
+		column Column
+		want   bson.M
+	}{
+		{Column{Name: "phone"}, bson.M{"phone": nil}},
+		{Column{Name: "phone", Exp: "eq", Type: "int"}, bson.M{"phone": nil}},
+		{Column{Name: "phone", Exp: "="}, bson.M{"phone": nil}},
+		{Column{Name: "phone", Exp: "neq"}, bson.M{"phone": bson.M{"$ne": nil}}},
+		{Column{Name: "phone", Exp: "!=", Type: "string"}, bson.M{"phone": bson.M{"$ne": nil}}},
+	} {
+		c = &Conditions{Columns: []Column{tt.column}}
+		assert.NoError(t, c.CheckValid(), tt.column.Exp)
+		got, err = c.ConvertToMongo()
+		assert.NoError(t, err, tt.column.Exp)
+		assert.Equal(t, tt.want, got, tt.column.Exp)
+		p = &Params{Columns: []Column{tt.column, {Name: "age", Value: 1}}}
+		got, err = p.ConvertToMongoFilter()
+		assert.NoError(t, err, tt.column.Exp)
+		assert.Equal(t, bson.M{"$and": []bson.M{tt.want, {"age": 1}}}, got, tt.column.Exp)
+	}
+
+	// the nil value of the other exps is still rejected
+	for _, column := range []Column{{Name: "phone", Exp: "gt"}, {Name: "phone", Exp: "like"}, {Name: "phone", Exp: "in"}} {
+		c = &Conditions{Columns: []Column{column}}
+		assert.Error(t, c.CheckValid())
+		p = &Params{Columns: []Column{column}}
+		_, err = p.ConvertToMongoFilter()
+		assert.Error(t, err)
+	}
+}
+
+func TestParams_ConvertToMongoFilter_Array(t *testing.T) {
+	p := &Params{Columns: []Column{
+		{Name: "tags", Exp: "all", Value: "go,mongo"},
+		{Name: "items", Exp: "elemmatch", Value: []interface{}{
+			map[string]interface{}{"name": "sku", "value": "X"},
+			map[string]interface{}{"name": "qty", "exp": "gt", "value": float64(2)},
+		}},
+	}}
+	got, err := p.ConvertToMongoFilter()
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{"$and": []bson.M{
+		{"tags": bson.M{"$all": []interface{}{"go", "mongo"}}},
+		{"items": bson.M{"$elemMatch": bson.M{"sku": "X", "qty": bson.M{"$gt": float64(2)}}}},
+	}}, got)
+	assert.Equal(t, "tags ALL [go, mongo] AND items ELEM MATCH (qty > 2 AND sku = X)", DescribeFilter(p.Columns))
+
+	// the sub-columns of []Column and the json string, the same field is matched more than once
+	for _, value := range []interface{}{
+		[]Column{{Name: "qty", Exp: "gte", Value: 2}, {Name: "qty", Exp: "lt", Value: 10}, {Name: "sku", Exp: "ieq", Value: "x"}},
+		`[{"name":"qty","exp":"gte","value":2},{"name":"qty","exp":"lt","value":10},{"name":"sku","exp":"ieq","value":"x"}]`,
+	} {
+		p = &Params{Columns: []Column{{Name: "items", Exp: "elem match", Value: value}}}
+		got, err = p.ConvertToMongoFilter(WithIEqStrategy(IEqExpr)) // $expr is not allowed in $elemMatch
+		assert.NoError(t, err)
+		conditions := got["items"].(bson.M)["$elemMatch"].(bson.M)["$and"].([]bson.M)
+		assert.Len(t, conditions, 3)
+		assert.Equal(t, bson.M{"sku": bson.M{"$regex": "^x$", "$options": "i"}}, conditions[2])
+	}
+
+	// the whitelist of the sub-columns is prefixed by the array field
+	whitelist := WithWhitelistNames(map[string]bool{"tags": true, "items": true, "items.sku": true})
+	p = &Params{Columns: []Column{{Name: "items", Exp: "elemmatch", Value: []Column{{Name: "sku", Value: "X"}}}}}
+	_, err = p.ConvertToMongoFilter(whitelist)
+	assert.NoError(t, err)
+
+	for _, value := range []interface{}{
+		[]Column{{Name: "qty", Value: 1}},      // not in the whitelist
+		[]Column{{Name: "$where", Value: "1"}}, // operator name
+		[]Column{{Name: "sku", Exp: "unknown", Value: "X"}},
+		[]Column{{Name: "sku", Value: map[string]interface{}{"$ne": "X"}}}, // document value
+		[]Column{{Name: "sku", Value: "X", Logic: "or"}, {Name: "sku", Value: "Y"}},
+		[]Column{{Name: "sku", Exp: "elemmatch", Value: []Column{{Name: "a", Value: 1}}}},
+		[]Column{},
+		[]interface{}{map[string]interface{}{"name": "sku", "value": "X", "unknown": 1}},
+		[]interface{}{"sku"},
+		"[{",
+		1,
+	} {
+		p = &Params{Columns: []Column{{Name: "items", Exp: "elemmatch", Value: value}}}
+		_, err = p.ConvertToMongoFilter(whitelist)
+		assert.Error(t, err, value)
+	}
+
+	// all with the type hint and the ObjectIDs
+	p = &Params{Columns: []Column{
+		{Name: "scores", Exp: "all", Value: []string{"1", "2"}, Type: "int"},
+		{Name: "refs", Exp: "all", Value: "65ce48483f11aff697e30d6d"},
+	}}
+	got, err = p.ConvertToMongoFilter()
+	assert.NoError(t, err)
+	oid, _ := primitive.ObjectIDFromHex("65ce48483f11aff697e30d6d")
+	assert.Equal(t, bson.M{"$and": []bson.M{
+		{"scores": bson.M{"$all": []interface{}{int64(1), int64(2)}}},
+		{"refs": bson.M{"$all": []interface{}{oid}}},
+	}}, got)
+}
+
+func TestParams_ConvertToMongoFilter_Group(t *testing.T) {
+	a, b, c, d := bson.M{"a": 1}, bson.M{"b": 2}, bson.M{"c": 3}, bson.M{"d": 4}
+	tests := []struct {
+		name    string
+		columns []Column
+		want    bson.M
+		desc    string
+	}{
+		{
+			name: "(A or B) and C",
+			columns: []Column{
+				{Name: "a", Value: 1, Logic: "or", Group: 1},
+				{Name: "b", Value: 2, Group: 1},
+				{Name: "c", Value: 3},
+			},
+			want: bson.M{"$and": []bson.M{{"$or": []bson.M{a, b}}, c}},
+			desc: "(a = 1 OR b = 2) AND c = 3",
+		},
+		{
+			name: "A and (B or C)",
+			columns: []Column{
+				{Name: "a", Value: 1},
+				{Name: "b", Value: 2, Logic: "||", Group: "g"},
+				{Name: "c", Value: 3, Group: "g"},
+			},
+			want: bson.M{"$and": []bson.M{a, {"$or": []bson.M{b, c}}}},
+			desc: "a = 1 AND (b = 2 OR c = 3)",
+		},
+		{
+			name: "(A or B) or (C and D) and E, and takes precedence over or",
+			columns: []Column{
+				{Name: "a", Value: 1, Logic: "or", Group: 1},
+				{Name: "b", Value: 2, Logic: "or", Group: 1},
+				{Name: "c", Value: 3, Group: 2},
+				{Name: "d", Value: 4, Group: 2},
+				{Name: "e", Value: 5, Group: 3},
+			},
+			want: bson.M{"$or": []bson.M{
+				{"$or": []bson.M{a, b}},
+				{"$and": []bson.M{{"$and": []bson.M{c, d}}, {"e": 5}}},
+			}},
+			desc: "(a = 1 OR b = 2) OR ((c = 3 AND d = 4) AND e = 5)",
+		},
+		{
+			name: "the columns of the same group are gathered, json numbers",
+			columns: []Column{
+				{Name: "a", Value: 1, Logic: "or", Group: float64(1)},
+				{Name: "c", Value: 3, Group: 2, Logic: "or"},
+				{Name: "b", Value: 2, Group: 1},
+				{Name: "d", Value: 4, Group: 2, Logic: "and"},
+			},
+			want: bson.M{"$and": []bson.M{{"$or": []bson.M{a, b}}, {"$or": []bson.M{c, d}}}},
+			desc: "(a = 1 OR b = 2) AND (c = 3 OR d = 4)", // the logic of b, the last column of the group 1
+		},
+		{
+			name: "single group",
+			columns: []Column{
+				{Name: "a", Value: 1, Logic: "or", Group: 1},
+				{Name: "b", Value: 2, Group: 1},
+			},
+			want: bson.M{"$or": []bson.M{a, b}},
+			desc: "a = 1 OR b = 2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Params{Columns: tt.columns}
+			got, err := p.ConvertToMongoFilter()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.desc, DescribeFilter(tt.columns))
+		})
+	}
+
+	// decoded from json
+	p := &Params{}
+	err := json.Unmarshal([]byte(`{"columns":[{"name":"a","value":1},{"name":"b","value":2,"logic":"or","group":1},{"name":"c","value":3,"group":1}]}`), p)
+	assert.NoError(t, err)
+	got, err := p.ConvertToMongoFilter()
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{"$and": []bson.M{{"a": float64(1)}, {"$or": []bson.M{{"b": float64(2)}, {"c": float64(3)}}}}}, got)
+
+	// the columns in the groups are checked in the same way
+	for _, columns := range [][]Column{
+		{{Name: "a", Value: 1, Group: []int{1}}},
+		{{Name: "a", Value: 1, Group: 1, Logic: "xor"}, {Name: "b", Value: 2}},
+		{{Name: "a", Value: 1, Group: 1}, {Name: "$where", Value: 2, Group: 1}},
+		{{Name: "a", Value: 1, Group: 1}, {Name: "email", Value: 2}},
+	} {
+		p = &Params{Columns: columns}
+		_, err = p.ConvertToMongoFilter(WithWhitelistNames(map[string]bool{"a": true, "b": true, "$where": true}))
+		assert.Error(t, err)
+	}
+}
+
 func TestParams_ConvertToMongoFilter_Error(t *testing.T) {
 	p := &Params{
 		Limit: 10,
@@ -606,6 +1513,210 @@ func TestParams_ConvertToMongoFilter_Error(t *testing.T) {
 	_, err = p.ConvertToMongoFilter(WithValidateFn(fn))
 	t.Log(err)
 	assert.Error(t, err)
+
+	// the operators as the names are rejected without the whitelist
+	for _, name := range []string{"$where", " $function", "profile.$gt", "a.b.$ne", "$expr:oid"} {
+		p = &Params{Columns: []Column{{Name: name, Value: "1"}}}
+		_, err = p.ConvertToMongoFilter()
+		assert.Error(t, err, name)
+	}
+	p = &Params{Columns: []Column{{Name: "price$", Value: "1"}, {Name: "a.b$", Value: "1"}}}
+	_, err = p.ConvertToMongoFilter()
+	assert.NoError(t, err)
+}
+
+func TestParams_ConvertToMongoFilter_DisableAutoObjectID(t *testing.T) {
+	hex1, hex2 := "65ce48483f11aff697e30d6d", "65ce48483f11aff697e30d6e"
+	oid1, _ := primitive.ObjectIDFromHex(hex1)
+	oid2, _ := primitive.ObjectIDFromHex(hex2)
+	opt := WithDisableAutoObjectID()
+
+	// the strings of the other columns are kept
+	columns := []Column{
+		{Name: "trace_id", Value: hex1},
+		{Name: "parent_id", Exp: "in", Value: []string{hex1, hex2}},
+		{Name: "span_id", Exp: "between", Value: hex1 + "," + hex2},
+	}
+	p := &Params{Columns: columns}
+	got, err := p.ConvertToMongoFilter(opt)
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{"$and": []bson.M{
+		{"trace_id": hex1},
+		{"parent_id": bson.M{"$in": []interface{}{hex1, hex2}}},
+		{"span_id": bson.M{"$gte": hex1, "$lte": hex2}},
+	}}, got)
+	got, err = (&Conditions{Columns: columns[:1]}).ConvertToMongo(opt)
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{"trace_id": hex1}, got)
+
+	// id, _id and the suffix :oid are still converted
+	p = &Params{Columns: []Column{
+		{Name: "id", Value: hex1, Logic: "or"},
+		{Name: "_id", Exp: "nin", Value: hex1 + "," + hex2, Logic: "or"},
+		{Name: "user_id:oid", Exp: "between", Value: []string{hex1, hex2}},
+	}}
+	got, err = p.ConvertToMongoFilter(opt)
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{"$or": []bson.M{
+		{"_id": oid1},
+		{"_id": bson.M{"$nin": []interface{}{oid1, oid2}}},
+		{"user_id": bson.M{"$gte": oid1, "$lte": oid2}},
+	}}, got)
+
+	// converted by default
+	p = &Params{Columns: columns[:2]}
+	got, err = p.ConvertToMongoFilter()
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{"$and": []bson.M{
+		{"trace_id": oid1},
+		{"parent_id": bson.M{"$in": []interface{}{oid1, oid2}}},
+	}}, got)
+}
+
+// the documents as the values are rejected, otherwise the client can inject the operators by the value
+func TestParams_ConvertToMongoFilter_DocumentValue(t *testing.T) {
+	var p Params
+	err := json.Unmarshal([]byte(`{"columns":[{"name":"role","exp":"eq","value":{"$ne":"user"}}]}`), &p)
+	assert.NoError(t, err)
+	_, err = p.ConvertToMongoFilter()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "column 'role'")
+	}
+	err = json.Unmarshal([]byte(`{"columns":[{"name":"role","exp":"in","value":["admin",{"$gt":""}]}]}`), &p)
+	assert.NoError(t, err)
+	_, err = p.ConvertToMongoFilter()
+	assert.Error(t, err)
+
+	values := []interface{}{
+		bson.M{"$ne": "user"},
+		map[string]interface{}{"$gt": ""},
+		map[string]string{"$regex": ".*"},
+		bson.D{{Key: "$ne", Value: "user"}},
+		primitive.E{Key: "$ne", Value: "user"},
+		bson.Raw{5, 0, 0, 0, 0}, // empty document
+		&map[string]interface{}{"$ne": "user"},
+		[]interface{}{"admin", []interface{}{bson.M{"$ne": "user"}}}, // nested
+		[]bson.M{{"$ne": "user"}},
+		[2]interface{}{"a", bson.M{"$gt": ""}},
+	}
+	for _, exp := range []string{Eq, Neq, Gt, In, NotIn, Between} {
+		for _, value := range values {
+			p := &Params{Columns: []Column{{Name: "age", Value: 1}, {Name: "role", Exp: exp, Value: value}}}
+			_, err = p.ConvertToMongoFilter()
+			assert.Error(t, err, "%s %v", exp, value)
+			p.Columns[1].Type = TypeString
+			_, err = p.ConvertToMongoFilter()
+			assert.Error(t, err, "%s %v", exp, value)
+		}
+	}
+
+	// the scalars and the slices of the scalars are not documents
+	for _, value := range []interface{}{"user", 1, 1.5, true, time.Now(), primitive.NewObjectID(),
+		[]string{"a", "b"}, []int{1, 2}, []interface{}{"a", 1, nil}, []interface{}{primitive.NewObjectID()}} {
+		assert.False(t, hasDocumentValue(value), "%v", value)
+	}
+
+	// the raw values are allowed by the option
+	p = Params{Columns: []Column{{Name: "role", Value: bson.M{"$ne": "user"}}}}
+	got, err := p.ConvertToMongoFilter(WithAllowRawValues())
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{"role": bson.M{"$ne": "user"}}, got)
+}
+
+// the whitelist and the validate function are applied to all the columns whatever the number of the columns
+// and the logic, e.g. the or-joined columns can't bypass the whitelist
+func TestParams_ConvertToMongoFilter_Whitelist(t *testing.T) {
+	whitelists := map[string]bool{"name": true, "age": true}
+	forbidden := Column{Name: "password_hash", Value: "x"}
+	tests := []struct {
+		name    string
+		columns []Column
+	}{
+		{"one", []Column{forbidden}},
+		{"two and", []Column{{Name: "name", Value: "foo"}, forbidden}},
+		{"two or", []Column{{Name: "name", Value: "foo", Logic: "||"}, forbidden}},
+		{"three and", []Column{{Name: "name", Value: "foo"}, {Name: "age", Value: 1}, forbidden}},
+		{"three or", []Column{{Name: "name", Value: "foo", Logic: "||"}, {Name: "age", Value: 1, Logic: "or"}, forbidden}},
+		{"three or first", []Column{{Name: "password_hash", Value: "x", Logic: "||"}, {Name: "age", Value: 1, Logic: "||"}, {Name: "name", Value: "foo"}}},
+		{"mixed", []Column{{Name: "name", Value: "foo", Logic: "||"}, {Name: "age", Value: 1}, forbidden}},
+		{"mixed in or group", []Column{{Name: "name", Value: "foo"}, {Name: "age", Value: 1, Logic: "||"}, forbidden, {Name: "age", Value: 2}}},
+	}
+	validateFn := func(columns []Column) error {
+		for _, column := range columns {
+			if column.Name == "password_hash" {
+				return errors.New("password_hash is not allowed")
+			}
+		}
+		return nil
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Params{Columns: tt.columns}
+			_, err := p.ConvertToMongoFilter(WithWhitelistNames(whitelists))
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), "'password_hash' is not allowed")
+			}
+			_, err = p.ConvertToMongoFilter(WithValidateFn(validateFn))
+			assert.Error(t, err)
+
+			c := &Conditions{Columns: tt.columns}
+			_, err = c.ConvertToMongo(WithWhitelistNames(whitelists))
+			assert.Error(t, err)
+
+			// allowed if it is in the whitelist
+			_, err = p.ConvertToMongoFilter(WithWhitelistNames(map[string]bool{"name": true, "age": true, "password_hash": true}))
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestCamelToSnake(t *testing.T) {
+	tests := map[string]string{
+		"name":         "name",
+		"createdAt":    "created_at",
+		"userId":       "user_id",
+		"userID":       "user_id",
+		"userIDList":   "user_id_list",
+		"HTTPServer":   "http_server",
+		"address2Line": "address2_line",
+		"created_at":   "created_at",
+		"user_id":      "user_id",
+		"_id":          "_id",
+		"":             "",
+	}
+	for name, want := range tests {
+		assert.Equal(t, want, CamelToSnake(name), name)
+	}
+}
+
+func TestParams_ConvertToMongoFilter_FieldNameConverter(t *testing.T) {
+	whitelists := map[string]bool{"created_at": true, "user_id": true, "profile.first_name": true, "items": true, "items.unit_price": true}
+	p := &Params{Columns: []Column{
+		{Name: "createdAt", Exp: Gt, Value: 1},
+		{Name: "userID", Value: "u1"},
+		{Name: "profile.firstName", Value: "foo"},
+		{Name: "items", Exp: ElemMatch, Value: []Column{{Name: "unitPrice", Exp: Lt, Value: 10}}},
+	}}
+	filter, err := p.ConvertToMongoFilter(WithFieldNameConverter(CamelToSnake), WithWhitelistNames(whitelists))
+	require.NoError(t, err)
+	assert.Equal(t, bson.M{"$and": []bson.M{
+		{"created_at": bson.M{"$gt": 1}},
+		{"user_id": "u1"},
+		{"profile.first_name": "foo"},
+		{"items": bson.M{"$elemMatch": bson.M{"unit_price": bson.M{"$lt": 10}}}},
+	}}, filter)
+	assert.Equal(t, "createdAt", p.Columns[0].Name) // p is not modified
+
+	// the snake_case names pass through unchanged, the whitelist is checked by the converted names
+	_, err = (&Params{Columns: []Column{{Name: "user_id", Value: "u1"}}}).ConvertToMongoFilter(
+		WithFieldNameConverter(CamelToSnake), WithWhitelistNames(whitelists))
+	assert.NoError(t, err)
+	_, err = (&Params{Columns: []Column{{Name: "userID", Value: "u1"}}}).ConvertToMongoFilter(WithWhitelistNames(whitelists))
+	assert.ErrorIs(t, err, ErrNameNotAllowed)
+
+	sort, _, _ := (&Params{Sort: "-createdAt, profile.firstName,id"}).ConvertToPage(WithFieldNameConverter(CamelToSnake))
+	assert.Equal(t, bson.D{{Key: "created_at", Value: -1}, {Key: "profile.first_name", Value: 1}, {Key: "_id", Value: 1}}, sort)
 }
 
 func TestConditions_ConvertToMongo(t *testing.T) {
@@ -641,6 +1752,7 @@ func TestConditions_checkValid(t *testing.T) {
 		Columns: []Column{
 			{
 				Name:  "foo",
+				Exp:   Gt,
 				Value: nil,
 			},
 		},
@@ -677,6 +1789,16 @@ func TestConditions_checkValid(t *testing.T) {
 	// success
 	c = Conditions{
 		Columns: []Column{
+			{
+				Name:  "deleted_at",
+				Exp:   "exists",
+				Value: false,
+			},
+			{
+				Name:  "avatar",
+				Exp:   "notexists",
+				Value: true,
+			},
 			{
 				Name:  "name",
 				Value: "ZhangSan",
@@ -690,6 +1812,49 @@ func TestConditions_checkValid(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestConditions_CheckValid_CaseInsensitive(t *testing.T) {
+	mixedCase := func(s string) string {
+		b := []byte(strings.ToLower(s))
+		for i := 0; i < len(b); i += 2 {
+			b[i] = strings.ToUpper(string(b[i]))[0]
+		}
+		return string(b)
+	}
+	spellings := func(s string) []string {
+		return []string{strings.ToLower(s), strings.ToUpper(s), mixedCase(s)}
+	}
+
+	// the same spellings are accepted by CheckValid and the conversion
+	for exp := range expMap {
+		for _, s := range spellings(exp) {
+			c := &Conditions{Columns: []Column{{Name: "age", Exp: s, Value: 1}}}
+			assert.NoError(t, c.CheckValid(), s)
+			_, err := c.ConvertToMongo()
+			assert.NotErrorIs(t, err, ErrUnsupportedExp, s)
+		}
+	}
+	for logic := range logicMap {
+		for _, s := range spellings(logic) {
+			c := &Conditions{Columns: []Column{{Name: "age", Value: 1, Logic: s}, {Name: "name", Value: "foo"}}}
+			assert.NoError(t, c.CheckValid(), s)
+			_, err := c.ConvertToMongo()
+			assert.NoError(t, err, s)
+		}
+	}
+
+	c := &Conditions{Columns: []Column{{Name: "age", Exp: "GTE", Value: 1, Logic: "AND"}, {Name: "name", Exp: "Like", Value: "foo"}}}
+	assert.NoError(t, c.CheckValid())
+	filter, err := c.ConvertToMongo()
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{"$and": []bson.M{{"age": bson.M{"$gte": 1}}, {"name": bson.M{"$regex": "foo", "$options": "i"}}}}, filter)
+
+	// the unknown spellings are still rejected
+	for _, column := range []Column{{Name: "age", Exp: "GTEE", Value: 1}, {Name: "age", Value: 1, Logic: "ANDD"}, {Name: "age", Value: 1, Logic: "&&&"}} {
+		c = &Conditions{Columns: []Column{column}}
+		assert.Error(t, c.CheckValid(), column)
+	}
+}
+
 func Test_groupingIndex(t *testing.T) {
 	type args struct {
 		l         int
@@ -716,6 +1881,38 @@ func Test_groupingIndex(t *testing.T) {
 			},
 			want: [][]int{{0, 1}, {2, 3}},
 		},
+		{
+			name: "3 index 0",
+			args: args{
+				l:         3,
+				orIndexes: []int{0},
+			},
+			want: [][]int{{0}, {1, 2}},
+		},
+		{
+			name: "5 index 1 3",
+			args: args{
+				l:         5,
+				orIndexes: []int{1, 3},
+			},
+			want: [][]int{{0, 1}, {2, 3}, {4}},
+		},
+		{
+			name: "4 index 0 1",
+			args: args{
+				l:         4,
+				orIndexes: []int{0, 1},
+			},
+			want: [][]int{{0}, {1}, {2, 3}},
+		},
+		{
+			name: "4 invalid index",
+			args: args{
+				l:         4,
+				orIndexes: []int{1, 1, 0, 3, 5},
+			},
+			want: [][]int{{0, 1}, {2, 3}},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -728,6 +1925,93 @@ func Test_groupingIndex(t *testing.T) {
 	}
 }
 
+// every pattern of the logics of 3, 4 and 5 columns, the key is the logics of the columns except the last one,
+// a is and, o is or, the value is the expected filter, e.g. "0&1|2" is {$or: [{$and: [c0, c1]}, c2]}
+func TestParams_ConvertToMongoFilter_LogicPatterns(t *testing.T) {
+	patterns := map[string]string{
+		"aa": "0&1&2",
+		"ao": "0&1|2",
+		"oa": "0|1&2",
+		"oo": "0|1|2",
+
+		"aaa": "0&1&2&3",
+		"aao": "0&1&2|3",
+		"aoa": "0&1|2&3",
+		"aoo": "0&1|2|3",
+		"oaa": "0|1&2&3",
+		"oao": "0|1&2|3",
+		"ooa": "0|1|2&3",
+		"ooo": "0|1|2|3",
+
+		"aaaa": "0&1&2&3&4",
+		"aaao": "0&1&2&3|4",
+		"aaoa": "0&1&2|3&4",
+		"aaoo": "0&1&2|3|4",
+		"aoaa": "0&1|2&3&4",
+		"aoao": "0&1|2&3|4",
+		"aooa": "0&1|2|3&4",
+		"aooo": "0&1|2|3|4",
+		"oaaa": "0|1&2&3&4",
+		"oaao": "0|1&2&3|4",
+		"oaoa": "0|1&2|3&4",
+		"oaoo": "0|1&2|3|4",
+		"ooaa": "0|1|2&3&4",
+		"ooao": "0|1|2&3|4",
+		"oooa": "0|1|2|3&4",
+		"oooo": "0|1|2|3|4",
+	}
+	column := func(i string) bson.M {
+		return bson.M{"c" + i: "v" + i}
+	}
+	// the expected filter of the pattern, the group of one column is not wrapped by $and
+	expected := func(pattern string) bson.M {
+		var groups []bson.M
+		for _, group := range strings.Split(pattern, "|") {
+			indexes := strings.Split(group, "&")
+			if len(indexes) == 1 {
+				groups = append(groups, column(indexes[0]))
+				continue
+			}
+			and := make([]bson.M, 0, len(indexes))
+			for _, i := range indexes {
+				and = append(and, column(i))
+			}
+			groups = append(groups, bson.M{"$and": and})
+		}
+		if len(groups) == 1 {
+			return groups[0]
+		}
+		return bson.M{"$or": groups}
+	}
+
+	for n := 3; n <= 5; n++ {
+		for mask := 0; mask < 1<<(n-1); mask++ {
+			logics := ""
+			columns := make([]Column, n)
+			for i := range columns {
+				columns[i] = Column{Name: "c" + strconv.Itoa(i), Value: "v" + strconv.Itoa(i), Logic: "||"} // the logic of the last column is ignored
+				if i < n-1 {
+					if mask&(1<<i) == 0 {
+						logics += "a"
+						columns[i].Logic = "&"
+					} else {
+						logics += "o"
+					}
+				}
+			}
+			pattern, ok := patterns[logics]
+			if !assert.True(t, ok, logics) {
+				continue
+			}
+			p := &Params{Columns: columns}
+			got, err := p.ConvertToMongoFilter()
+			if assert.NoError(t, err, logics) {
+				assert.Equal(t, expected(pattern), got, logics)
+			}
+		}
+	}
+}
+
 func Test_getSort(t *testing.T) {
 	names := []string{
 		"", "id", "-id", "gender", "gender,id", "-gender,-id",
@@ -738,6 +2022,36 @@ func Test_getSort(t *testing.T) {
 	}
 }
 
+func Test_getSort_EmptyNames(t *testing.T) {
+	tests := []struct {
+		columnNames string
+		want        bson.D
+	}{
+		{"a,,", bson.D{{Key: "a", Value: 1}}},
+		{",-a,,b", bson.D{{Key: "a", Value: -1}, {Key: "b", Value: 1}}},
+		{",", bson.D{{Key: oidName, Value: -1}}},
+		{" , ,", bson.D{{Key: oidName, Value: -1}}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, getSort(tt.columnNames), tt.columnNames)
+	}
+
+	// the sort of the user input does not panic in the conversions
+	whitelist := WithWhitelistNames(map[string]bool{"a": true, "b": true})
+	p := &Params{Limit: 10, Sort: "a,,"}
+	_, findOpts, err := p.ConvertToQuery(whitelist)
+	require.NoError(t, err)
+	assert.Equal(t, bson.D{{Key: "a", Value: 1}}, findOpts.Sort)
+	findOpts, err = (&Params{Sort: ",,-b", Sorts: []SortField{{Name: "b", Desc: true}}}).ConvertToFindOptions(whitelist)
+	require.NoError(t, err)
+	assert.Equal(t, bson.D{{Key: "b", Value: -1}}, findOpts.Sort)
+	_, err = (&Params{Sort: "a,,", Sorts: []SortField{{Name: "b"}}}).ConvertToFindOptions(whitelist)
+	assert.ErrorIs(t, err, ErrSortConflict)
+	pipeline, err := (&Params{Sort: ",a,"}).ConvertToAggregatePipeline(whitelist)
+	require.NoError(t, err)
+	assert.Equal(t, bson.D{{Key: "$sort", Value: bson.D{{Key: "a", Value: 1}}}}, pipeline[1])
+}
+
 func TestConditions_ConvertToMongo_Error(t *testing.T) {
 	c := Conditions{Columns: []Column{
 		{
@@ -767,3 +2081,65 @@ func TestConditions_ConvertToMongo_Error(t *testing.T) {
 	t.Log(err)
 	assert.Error(t, err)
 }
+
+func TestParams_Sorts(t *testing.T) {
+	whitelist := WithWhitelistNames(map[string]bool{"status": true, "created_at": true, "age": true})
+
+	// Sorts takes precedence over the empty Sort, the order of the fields is kept
+	p := &Params{Limit: 10, Sorts: []SortField{{Name: "status"}, {Name: "created_at", Desc: true}, {Name: "id"}}}
+	findOpts, err := p.ConvertToFindOptions(whitelist)
+	require.NoError(t, err)
+	want := bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: 1}}
+	assert.Equal(t, want, findOpts.Sort)
+	sort, limit, _ := p.ConvertToPage()
+	assert.Equal(t, want, sort)
+	assert.Equal(t, 10, limit)
+
+	// the same order of Sort and Sorts is not the conflict
+	p = &Params{Limit: 10, Sort: "status, -age", Sorts: []SortField{{Name: "status"}, {Name: "age", Desc: true}}}
+	findOpts, err = p.ConvertToFindOptions(whitelist)
+	require.NoError(t, err)
+	assert.Equal(t, bson.D{{Key: "status", Value: 1}, {Key: "age", Value: -1}}, findOpts.Sort)
+
+	// the errors
+	tests := []struct {
+		name    string
+		params  *Params
+		wantErr error
+	}{
+		{"conflict direction", &Params{Sort: "-age", Sorts: []SortField{{Name: "age"}}}, ErrSortConflict},
+		{"conflict order", &Params{Sort: "age,status", Sorts: []SortField{{Name: "status"}, {Name: "age"}}}, ErrSortConflict},
+		{"not allowed entry", &Params{Sorts: []SortField{{Name: "status"}, {Name: "password", Desc: true}}}, ErrNameNotAllowed},
+		{"operator entry", &Params{Sorts: []SortField{{Name: "$natural"}}}, ErrNameNotAllowed},
+		{"not allowed sort", &Params{Sort: "-password"}, ErrNameNotAllowed},
+		{"empty name", &Params{Sorts: []SortField{{Name: "age"}, {Name: " "}}}, ErrEmptyName},
+	}
+	for _, tt := range tests {
+		_, err = tt.params.ConvertToFindOptions(whitelist)
+		assert.ErrorIs(t, err, tt.wantErr, tt.name)
+		assert.True(t, IsInvalid(err), tt.name)
+	}
+	_, err = (&Params{Sort: "-age", Sorts: []SortField{{Name: "age"}}}).ConvertToFindOptions()
+	assert.EqualError(t, err, "sort '-age' conflicts with sorts, set one of them")
+
+	// the names of Sorts are converted by the field name converter
+	p = &Params{Limit: 10, Sorts: []SortField{{Name: "createdAt", Desc: true}}}
+	findOpts, err = p.ConvertToFindOptions(whitelist, WithFieldNameConverter(CamelToSnake))
+	require.NoError(t, err)
+	assert.Equal(t, bson.D{{Key: "created_at", Value: -1}}, findOpts.Sort)
+
+	// Sorts is honored and checked by ConvertToQuery and ConvertToAggregatePipeline too
+	p = &Params{Limit: 10, Sorts: []SortField{{Name: "age", Desc: true}}}
+	_, findOpts, err = p.ConvertToQuery(whitelist)
+	require.NoError(t, err)
+	assert.Equal(t, bson.D{{Key: "age", Value: -1}}, findOpts.Sort)
+	pipeline, err := p.ConvertToAggregatePipeline(whitelist)
+	require.NoError(t, err)
+	assert.Equal(t, bson.D{{Key: "$sort", Value: bson.D{{Key: "age", Value: -1}}}}, pipeline[1])
+	for _, tt := range tests {
+		_, _, err = tt.params.ConvertToQuery(whitelist)
+		assert.ErrorIs(t, err, tt.wantErr, tt.name)
+		_, err = tt.params.ConvertToAggregatePipeline(whitelist)
+		assert.ErrorIs(t, err, tt.wantErr, tt.name)
+	}
+}
@@ -0,0 +1,135 @@
+package query
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// the operators which can be the keys of the filter, the other keys are the column names
+var filterOperators = map[string]bool{
+	"$and": true, "$or": true, "$ne": true, "$gt": true, "$gte": true, "$lt": true, "$lte": true,
+	"$in": true, "$nin": true, "$exists": true, "$regex": true, "$options": true, "$not": true,
+	"$expr": true, "$eq": true, "$toLower": true, "$literal": true, "$all": true, "$elemMatch": true,
+}
+
+// check the filter converted from the input of the client, the keys starting with $ must be the operators
+// built by the converter, and the size of the filter is bounded by the size of the input
+func checkFuzzFilter(t *testing.T, input string, filter bson.M) {
+	size := 0
+	var walk func(v interface{}, isOperatorValue bool)
+	walk = func(v interface{}, isOperatorValue bool) {
+		size++
+		switch val := v.(type) {
+		case bson.M:
+			for k, child := range val {
+				if strings.HasPrefix(k, "$") {
+					if !filterOperators[k] {
+						t.Fatalf("operator '%s' is injected by %q: %v", k, input, filter)
+					}
+				} else if isOperatorName(k) || (isOperatorValue && k != "") {
+					t.Fatalf("key '%s' is injected by %q: %v", k, input, filter)
+				}
+				walk(child, strings.HasPrefix(k, "$") && k != "$and" && k != "$or" && k != "$elemMatch")
+			}
+		case []bson.M:
+			for _, child := range val {
+				walk(child, false)
+			}
+		case []interface{}:
+			for _, child := range val {
+				walk(child, isOperatorValue)
+			}
+		}
+	}
+	walk(filter, false)
+
+	if size > 8*len(input)+64 {
+		t.Fatalf("the filter of %d nodes is too large for %q", size, input)
+	}
+}
+
+func FuzzConvertToMongoFilter(f *testing.F) {
+	// the seeds are the columns of the tests
+	seeds := [][]Column{
+		{{Name: "name", Value: "ZhangSan"}},
+		{{Name: "id", Value: "65ce48483f11aff697e30d6d"}},
+		{{Name: "age", Exp: "gt", Value: 20}, {Name: "name", Exp: "like", Value: "foo.*", Logic: "||"}},
+		{{Name: "status", Exp: "in", Value: "active,trial"}, {Name: "vip", Exp: "exists", Value: "true"}},
+		{{Name: "id:oid", Exp: "nin", Value: []string{"65ce48483f11aff697e30d6d"}}},
+		{{Name: "created_at", Exp: "between", Value: "2024-01-01 00:00:00,2024-02-01 00:00:00", Type: "datetime"}},
+		{{Name: "name", Exp: "regex", Value: "^foo.*(bar|baz)$"}},
+		{{Name: "name", Exp: "startswith", Value: "a.b"}, {Name: "email", Exp: "endswith", Value: ".com$"}},
+		{{Name: "name", Exp: "ieq", Value: "$password"}, {Name: "email", Exp: "ineq", Value: "A.b"}},
+		{{Name: "age", Value: "30", Type: "int"}, {Name: "email", Value: "foo@bar.com", Logic: "or"}, {Name: "sex", Value: "male"}},
+		{{Name: "a", Value: 1}, {Name: "b", Value: 2, Logic: "|"}, {Name: "c", Value: 3, Logic: "&"}, {Name: "d", Value: 4}},
+		{{Name: "tags", Exp: "all", Value: "a,b"}, {Name: "items", Exp: "elemmatch", Value: []Column{{Name: "qty", Exp: "gt", Value: 2}}}},
+		{{Name: "items", Exp: "elemmatch", Value: []Column{{Name: "$where", Value: "1"}, {Name: "sku", Value: map[string]interface{}{"$ne": "X"}}}}},
+		{{Name: "a", Value: 1}, {Name: "b", Value: 2, Logic: "or", Group: 1}, {Name: "c", Value: 3, Group: 1}},
+		{{Name: "role", Value: map[string]interface{}{"$ne": "user"}}},
+		{{Name: "$where", Value: "sleep(1000)"}},
+		{{Name: "profile.$gt", Value: ""}},
+	}
+	for _, seed := range seeds {
+		data, err := json.Marshal(seed)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(string(data))
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		var columns []Column
+		if err := json.Unmarshal([]byte(input), &columns); err != nil || len(columns) > defaultMaxColumns {
+			return
+		}
+		p := &Params{Columns: columns}
+		filter, err := p.ConvertToMongoFilter()
+		if err != nil {
+			return
+		}
+		checkFuzzFilter(t, input, filter)
+		_ = DescribeFilter(columns)
+
+		filter, err = p.ConvertToMongoFilter(WithIEqStrategy(IEqExpr))
+		if err != nil {
+			t.Fatalf("the strategy of ieq fails %q: %v", input, err)
+		}
+		checkFuzzFilter(t, input, filter)
+		_ = DescribeMongoFilter(filter)
+	})
+}
+
+func FuzzParseFilter(f *testing.F) {
+	// the seeds are the filters of the tests
+	for _, seed := range []string{
+		`age:gt:18;status:in:active,trial|name:like:a\;b`,
+		"deleted_at:isnull|name::foo",
+		"age",
+		"age:eq:1;",
+		"id::65ce48483f11aff697e30d6d;age:between:18,30",
+		"name:regex:^foo$|vip:exists:1",
+		"$where::sleep(1000)",
+		"profile.$ne::x",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		columns, err := ParseFilter(input)
+		if err != nil {
+			return
+		}
+		if len(columns) > defaultMaxColumns {
+			t.Fatalf("%d columns are parsed from %q", len(columns), input)
+		}
+		p := &Params{Columns: columns}
+		filter, err := p.ConvertToMongoFilter()
+		if err != nil {
+			return
+		}
+		checkFuzzFilter(t, input, filter)
+	})
+}
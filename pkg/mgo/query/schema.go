@@ -0,0 +1,121 @@
+package query
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	oidType      = reflect.TypeOf(primitive.ObjectID{})
+	dateTimeType = reflect.TypeOf(primitive.DateTime(0))
+
+	schemaValidate = validator.New()
+)
+
+// Schema the types and the validation rules of the values of the fields, the key is the name of the column,
+// e.g. "profile.city", it is generated by SchemaFromStruct and used by WithSchema
+type Schema map[string]SchemaField
+
+// SchemaField the type and the validation rules of the values of a field
+type SchemaField struct {
+	// the type of the values, TypeInt, TypeFloat, TypeBool, TypeDatetime, TypeOID or TypeString, the type of
+	// the elements for the slice, empty means the values are converted in the same way as without the schema
+	Type string
+	// the rules of the validator for the values of eq, ne, in, nin and all, e.g. "oneof=1 2 3", the range
+	// expressions and the pattern expressions are not validated, e.g. gt and like
+	Rules string
+}
+
+// SchemaFromStruct generate the schema of the fields from the model struct, the names are the same as
+// WhitelistFromStruct, the type is got from the kind of the field, and the rules are got from the tag validate,
+// or the tag binding if there is no validate tag, the rules required and omitempty are ignored, and the rules
+// of the slice fields are ignored. It panics if the rules are invalid. e.g.
+//
+//	var userSchema = query.SchemaFromStruct(&model.User{}, query.WithExcludes("password"))
+func SchemaFromStruct(model interface{}, opts ...StructOption) Schema {
+	o := defaultStructOptions()
+	o.apply(opts...)
+
+	typ := indirectType(reflect.TypeOf(model))
+	if typ == nil || typ.Kind() != reflect.Struct {
+		panic("query: model must be a struct or pointer to struct, got " + reflect.TypeOf(model).String())
+	}
+
+	schema := Schema{}
+	visitStruct(typ, o, func(path string, field reflect.StructField) {
+		fieldType, isSlice := schemaElemType(field.Type)
+		sf := SchemaField{Type: schemaType(fieldType)}
+		if !isSlice && sf.Type != "" {
+			sf.Rules = schemaRules(field)
+		}
+		if sf.Rules != "" {
+			// the invalid rules panic here instead of the requests
+			_ = schemaValidate.Var(reflect.Zero(fieldType).Interface(), sf.Rules)
+		}
+		schema[path] = sf
+	})
+	return schema
+}
+
+// the type of the field, or the type of the elements if the field is a slice or an array
+func schemaElemType(typ reflect.Type) (reflect.Type, bool) {
+	typ = indirectType(typ)
+	if typ == oidType {
+		return typ, false
+	}
+	if (typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array) && typ.Elem().Kind() != reflect.Uint8 {
+		return indirectType(typ.Elem()), true
+	}
+	return typ, false
+}
+
+func schemaType(typ reflect.Type) string {
+	switch typ {
+	case oidType:
+		return TypeOID
+	case timeType, dateTimeType:
+		return TypeDatetime
+	}
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return TypeInt
+	case reflect.Float32, reflect.Float64:
+		return TypeFloat
+	case reflect.Bool:
+		return TypeBool
+	case reflect.String:
+		return TypeString
+	}
+	return ""
+}
+
+func schemaRules(field reflect.StructField) string {
+	tag, ok := field.Tag.Lookup("validate")
+	if !ok {
+		tag = field.Tag.Get("binding")
+	}
+	var rules []string
+	for _, rule := range strings.Split(tag, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" || rule == "-" || rule == "required" || rule == "omitempty" {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return strings.Join(rules, ",")
+}
+
+// the field of the column in the schema, nil if there is no schema or the column is not in the schema
+func (o *rulerOptions) schemaField(name string) *SchemaField {
+	if o.schema == nil {
+		return nil
+	}
+	if f, ok := o.schema[name]; ok {
+		return &f
+	}
+	return nil
+}
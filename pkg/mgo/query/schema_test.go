@@ -0,0 +1,124 @@
+package query
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type schemaUser struct {
+	ID       primitive.ObjectID `bson:"_id"`
+	Name     string             `bson:"name" binding:"required,min=2"`
+	Age      int                `bson:"age" validate:"gte=0,lte=150"`
+	Score    float64            `bson:"score"`
+	Status   int                `bson:"status" validate:"omitempty,oneof=1 2 3"`
+	Verified bool               `bson:"verified"`
+	LoginAt  time.Time          `bson:"login_at"`
+	Tags     []string           `bson:"tags" validate:"max=3"`
+	Address  struct {
+		City string `bson:"city" validate:"oneof=canton beijing"`
+	} `bson:"address"`
+	Extra map[string]interface{} `bson:"extra"`
+}
+
+func TestSchemaFromStruct(t *testing.T) {
+	schema := SchemaFromStruct(&schemaUser{}, WithExcludes("extra"))
+	assert.Equal(t, Schema{
+		"_id":          {Type: TypeOID},
+		"name":         {Type: TypeString, Rules: "min=2"},
+		"age":          {Type: TypeInt, Rules: "gte=0,lte=150"},
+		"score":        {Type: TypeFloat},
+		"status":       {Type: TypeInt, Rules: "oneof=1 2 3"},
+		"verified":     {Type: TypeBool},
+		"login_at":     {Type: TypeDatetime},
+		"tags":         {Type: TypeString}, // the rules of the slice are ignored
+		"address":      {},
+		"address.city": {Type: TypeString, Rules: "oneof=canton beijing"},
+	}, schema)
+
+	// the invalid rules panic on generating instead of the requests
+	assert.Panics(t, func() {
+		SchemaFromStruct(&struct {
+			Age int `bson:"age" validate:"unknownRule"`
+		}{})
+	})
+}
+
+func TestParams_ConvertToMongoFilter_Schema(t *testing.T) {
+	schema := SchemaFromStruct(&schemaUser{})
+	oid := primitive.NewObjectID()
+	loginAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		column Column
+		want   bson.M
+	}{
+		{"int from string", Column{Name: "age", Value: "18"}, bson.M{"age": int64(18)}},
+		{"int from json number", Column{Name: "age", Exp: ">", Value: float64(18)}, bson.M{"age": bson.M{"$gt": int64(18)}}},
+		{"float", Column{Name: "score", Exp: "<", Value: "9.5"}, bson.M{"score": bson.M{"$lt": 9.5}}},
+		{"bool", Column{Name: "verified", Value: "true"}, bson.M{"verified": true}},
+		{"datetime", Column{Name: "login_at", Exp: ">=", Value: "2024-01-02T03:04:05Z"}, bson.M{"login_at": bson.M{"$gte": loginAt}}},
+		{"oid", Column{Name: "_id", Value: oid.Hex()}, bson.M{"_id": oid}},
+		{"in", Column{Name: "status", Exp: "in", Value: "1,2"}, bson.M{"status": bson.M{"$in": []interface{}{int64(1), int64(2)}}}},
+		{"between", Column{Name: "age", Exp: "between", Value: "10,20"}, bson.M{"age": bson.M{"$gte": int64(10), "$lte": int64(20)}}},
+		{"element of slice", Column{Name: "tags", Value: 123}, bson.M{"tags": "123"}},
+		{"schema type takes precedence", Column{Name: "name", Value: "12", Type: TypeInt}, bson.M{"name": "12"}},
+		{"range is not validated by rules", Column{Name: "status", Exp: ">", Value: "5"}, bson.M{"status": bson.M{"$gt": int64(5)}}},
+		{"nested field", Column{Name: "address.city", Value: "canton"}, bson.M{"address.city": "canton"}},
+		{"not in schema", Column{Name: "nickname", Value: "18"}, bson.M{"nickname": "18"}},
+		{"not in schema auto oid", Column{Name: "id", Value: oid.Hex()}, bson.M{"_id": oid}},
+		{"like is not validated by rules", Column{Name: "name", Exp: "like", Value: "a"}, bson.M{"name": bson.M{"$regex": "a", "$options": "i"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Params{Columns: []Column{tt.column}}
+			got, err := p.ConvertToMongoFilter(WithSchema(schema))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	errTests := []struct {
+		name     string
+		column   Column
+		expected string
+		rules    string
+	}{
+		{"not int", Column{Name: "age", Value: "abc"}, TypeInt, ""},
+		{"not integral", Column{Name: "age", Exp: "<", Value: 1.5}, TypeInt, ""},
+		{"not bool", Column{Name: "verified", Value: "yes"}, TypeBool, ""},
+		{"not oid", Column{Name: "_id", Value: "123"}, TypeOID, ""},
+		{"not int in", Column{Name: "status", Exp: "in", Value: "1,x"}, TypeInt, ""},
+		{"out of range", Column{Name: "age", Value: "200"}, TypeInt, "gte=0,lte=150"},
+		{"out of enum", Column{Name: "status", Exp: "!=", Value: 4}, TypeInt, "oneof=1 2 3"},
+		{"out of enum in", Column{Name: "status", Exp: "in", Value: []interface{}{1, 4}}, TypeInt, "oneof=1 2 3"},
+		{"too short", Column{Name: "name", Value: "a"}, TypeString, "min=2"},
+		{"nested enum", Column{Name: "address.city", Value: "paris"}, TypeString, "oneof=canton beijing"},
+	}
+	for _, tt := range errTests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Params{Columns: []Column{{Name: "name", Value: "foo"}, tt.column}}
+			_, err := p.ConvertToMongoFilter(WithSchema(schema))
+			var columnErr *ColumnError
+			require.True(t, errors.As(err, &columnErr), err)
+			assert.Equal(t, tt.column.Name, columnErr.Name)
+			assert.Equal(t, tt.expected, columnErr.Expected)
+			assert.Equal(t, tt.rules, columnErr.Rules)
+			assert.Error(t, errors.Unwrap(columnErr))
+			t.Log(err)
+		})
+	}
+
+	// the type hint without the schema is reported by the same error
+	p := &Params{Columns: []Column{{Name: "age", Value: "abc", Type: TypeInt}}}
+	_, err := p.ConvertToMongoFilter()
+	var columnErr *ColumnError
+	require.True(t, errors.As(err, &columnErr))
+	assert.Equal(t, "column 'age': value 'abc' is not a valid int", err.Error())
+}
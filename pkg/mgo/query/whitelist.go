@@ -0,0 +1,221 @@
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const sortableTag = "sortable"
+
+var timeType = reflect.TypeOf(time.Time{})
+
+type structOptions struct {
+	maxDepth int
+	excludes map[string]bool
+	strict   bool
+}
+
+// StructOption set the options of generating the names from struct
+type StructOption func(*structOptions)
+
+func defaultStructOptions() *structOptions {
+	return &structOptions{
+		maxDepth: 3,
+		excludes: map[string]bool{},
+	}
+}
+
+func (o *structOptions) apply(opts ...StructOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithMaxDepth set the max depth of the nested structs, 1 means only the top level fields, default 3
+func WithMaxDepth(depth int) StructOption {
+	return func(o *structOptions) {
+		if depth > 0 {
+			o.maxDepth = depth
+		}
+	}
+}
+
+// WithExcludes exclude the names and their nested names, e.g. "password", "-password", "profile.phone",
+// the leading '-' is optional
+func WithExcludes(names ...string) StructOption {
+	return func(o *structOptions) {
+		for _, name := range names {
+			o.excludes[strings.TrimPrefix(name, "-")] = true
+		}
+	}
+}
+
+// WithStrict panic if an excluded name is not a field of the struct, so that the exclusions don't drift
+// from the model when the field is renamed or removed
+func WithStrict() StructOption {
+	return func(o *structOptions) {
+		o.strict = true
+	}
+}
+
+// WhitelistFromStruct generate the whitelist names of columns from the bson tags of the model struct, the json
+// tag is used if there is no bson tag, and the lowercase field name is used if there is no tag. The fields of
+// the nested structs are named with dot paths, e.g. "profile.city", and the embedded structs without a name
+// (or with the inline option) are flattened, _id is always included unless it is excluded. The names are cached
+// per type and options, so it is cheap to call it per request. It is used by WithWhitelistNames, e.g.
+//
+//	var userColumnNames = query.WhitelistFromStruct(&model.User{}, query.WithExcludes("-password"))
+func WhitelistFromStruct(model interface{}, opts ...StructOption) map[string]bool {
+	return namesFromStruct(model, false, opts...)
+}
+
+// SortableFromStruct the same as WhitelistFromStruct, but only the fields with the tag `sortable:"true"` are included
+func SortableFromStruct(model interface{}, opts ...StructOption) map[string]bool {
+	return namesFromStruct(model, true, opts...)
+}
+
+// the names generated from struct, key is namesCacheKey --> map[string]bool
+var namesCache sync.Map
+
+type namesCacheKey struct {
+	typ      reflect.Type
+	sortable bool
+	options  string
+}
+
+// the options of the key of the cache, the excludes are sorted
+func (o *structOptions) cacheKey() string {
+	excludes := make([]string, 0, len(o.excludes))
+	for name := range o.excludes {
+		excludes = append(excludes, name)
+	}
+	sort.Strings(excludes)
+	return strconv.Itoa(o.maxDepth) + "," + strconv.FormatBool(o.strict) + "," + strings.Join(excludes, ",")
+}
+
+// the copy of the cached names is returned, the caller may modify it
+func namesFromStruct(model interface{}, sortable bool, opts ...StructOption) map[string]bool {
+	o := defaultStructOptions()
+	o.apply(opts...)
+
+	typ := indirectType(reflect.TypeOf(model))
+	if typ == nil || typ.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("query: model must be a struct or pointer to struct, got %T", model))
+	}
+
+	key := namesCacheKey{typ: typ, sortable: sortable, options: o.cacheKey()}
+	cached, ok := namesCache.Load(key)
+	if !ok {
+		names := map[string]bool{}
+		visitStruct(typ, o, func(path string, field reflect.StructField) {
+			if !sortable || field.Tag.Get(sortableTag) == "true" {
+				names[path] = true
+			}
+		})
+		if !sortable && !o.excludes[oidName] {
+			names[oidName] = true
+		}
+		cached, _ = namesCache.LoadOrStore(key, names)
+	}
+
+	names := make(map[string]bool, len(cached.(map[string]bool)))
+	for name := range cached.(map[string]bool) {
+		names[name] = true
+	}
+	return names
+}
+
+// visit the fields of the struct which are not excluded, the names are checked if the option strict is set
+func visitStruct(typ reflect.Type, o *structOptions, visit func(path string, field reflect.StructField)) {
+	seen := map[string]bool{oidName: true} // all the names including the excluded ones, _id is always a field
+	walkStruct(typ, "", 1, o, visit, seen, map[reflect.Type]bool{})
+
+	if o.strict {
+		for name := range o.excludes {
+			if !seen[name] {
+				panic(fmt.Sprintf("query: excluded name '%s' is not a field of %s", name, typ.String()))
+			}
+		}
+	}
+}
+
+func walkStruct(typ reflect.Type, prefix string, depth int, o *structOptions,
+	visit func(path string, field reflect.StructField), seen map[string]bool, visiting map[reflect.Type]bool) {
+	if visiting[typ] { // recursive type
+		return
+	}
+	visiting[typ] = true
+	defer delete(visiting, typ)
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name, inline, skip := fieldName(field)
+		if skip {
+			continue
+		}
+		fieldType := indirectType(field.Type)
+
+		if inline {
+			if fieldType.Kind() == reflect.Struct {
+				walkStruct(fieldType, prefix, depth, o, visit, seen, visiting)
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		path := prefix + name
+		seen[path] = true
+		if o.excludes[path] {
+			continue
+		}
+		visit(path, field)
+
+		// the fields of nested struct, or the elements of slice
+		if fieldType.Kind() == reflect.Slice || fieldType.Kind() == reflect.Array {
+			fieldType = indirectType(fieldType.Elem())
+		}
+		if fieldType.Kind() == reflect.Struct && fieldType != timeType && depth < o.maxDepth {
+			walkStruct(fieldType, path+".", depth+1, o, visit, seen, visiting)
+		}
+	}
+}
+
+// fieldName returns the name of the field, bson tag takes precedence over json tag
+func fieldName(field reflect.StructField) (name string, inline bool, skip bool) {
+	tag, ok := field.Tag.Lookup("bson")
+	if !ok {
+		tag = field.Tag.Get("json")
+	}
+	if tag == "-" {
+		return "", false, true
+	}
+
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	for _, opt := range parts[1:] {
+		if opt == "inline" {
+			return "", true, false
+		}
+	}
+	if name == "" {
+		if field.Anonymous {
+			return "", true, false
+		}
+		name = strings.ToLower(field.Name)
+	}
+	return name, false, false
+}
+
+func indirectType(typ reflect.Type) reflect.Type {
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ
+}
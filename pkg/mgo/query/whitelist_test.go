@@ -0,0 +1,120 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type baseModel struct {
+	ID        primitive.ObjectID `bson:"_id" json:"id" sortable:"true"`
+	CreatedAt time.Time          `bson:"created_at" json:"createdAt" sortable:"true"`
+}
+
+type Audit struct {
+	Creator string `json:"creator"`
+}
+
+type address struct {
+	City   string `bson:"city" sortable:"true"`
+	Street string `bson:"street"`
+	Geo    struct {
+		Lat float64 `bson:"lat"`
+		Lng float64 `bson:"lng"`
+	} `bson:"geo"`
+}
+
+type testUser struct {
+	baseModel `bson:",inline"`
+	Audit     // embedded without tag
+
+	Name      string    `bson:"name" json:"userName" sortable:"true"`
+	Email     string    `json:"email"`
+	Password  string    `bson:"password" json:"-"`
+	Age       int       `sortable:"true"`
+	Ignored   string    `bson:"-"`
+	Address   *address  `bson:"address"`
+	Tags      []address `bson:"tags"`
+	LoginAt   time.Time `bson:"login_at"`
+	Friends   []*testUser
+	unexposed string
+}
+
+func TestWhitelistFromStruct(t *testing.T) {
+	names := WhitelistFromStruct(&testUser{})
+	for _, name := range []string{
+		"_id", "created_at", "creator", // embedded
+		"name", "email", "password", "age", // bson over json, json, lowercase field name
+		"address", "address.city", "address.street", "address.geo", "address.geo.lat", "address.geo.lng", // nested
+		"tags", "tags.city", "login_at", "friends",
+	} {
+		assert.True(t, names[name], name)
+	}
+	for _, name := range []string{"userName", "ignored", "Ignored", "unexposed", "baseModel", "audit", "login_at.wall", "friends.name"} { // the recursive type is not expanded
+		assert.False(t, names[name], name)
+	}
+
+	// depth
+	names = WhitelistFromStruct(testUser{}, WithMaxDepth(2))
+	assert.True(t, names["address.geo"])
+	assert.False(t, names["address.geo.lat"])
+	names = WhitelistFromStruct(testUser{}, WithMaxDepth(1))
+	assert.True(t, names["address"])
+	assert.False(t, names["address.city"])
+
+	// exclusions
+	names = WhitelistFromStruct(&testUser{}, WithExcludes("-password", "address.geo", "tags"), WithStrict())
+	assert.False(t, names["password"])
+	assert.True(t, names["address.city"])
+	assert.False(t, names["address.geo"])
+	assert.False(t, names["address.geo.lat"])
+	assert.False(t, names["tags.city"])
+
+	assert.Panics(t, func() { WhitelistFromStruct(&testUser{}, WithExcludes("passwd"), WithStrict()) })
+	assert.NotPanics(t, func() { WhitelistFromStruct(&testUser{}, WithExcludes("passwd")) })
+	assert.Panics(t, func() { WhitelistFromStruct("user") })
+
+	// used as the whitelist of the query
+	p := &Params{Columns: []Column{{Name: "address.city", Value: "foo"}}}
+	_, err := p.ConvertToMongoFilter(WithWhitelistNames(names))
+	assert.NoError(t, err)
+	p = &Params{Columns: []Column{{Name: "password", Value: "foo"}}}
+	_, err = p.ConvertToMongoFilter(WithWhitelistNames(names))
+	assert.Error(t, err)
+}
+
+func TestWhitelistFromStruct_Cache(t *testing.T) {
+	type order struct {
+		UserID string `bson:"user_id"`
+		Items  []struct {
+			SKU string `bson:"sku"`
+		} `bson:"items"`
+	}
+
+	// _id is included even if it is not a field
+	names := WhitelistFromStruct(&order{})
+	assert.Equal(t, map[string]bool{"_id": true, "user_id": true, "items": true, "items.sku": true}, names)
+	assert.False(t, WhitelistFromStruct(&order{}, WithExcludes("_id"), WithStrict())["_id"])
+
+	// the copy of the cached names is returned
+	names["password"] = true
+	assert.False(t, WhitelistFromStruct(order{})["password"])
+	assert.Equal(t, map[string]bool{"_id": true, "user_id": true, "items": true}, WhitelistFromStruct(&order{}, WithMaxDepth(1)))
+	assert.Equal(t, map[string]bool{"_id": true, "user_id": true}, WhitelistFromStruct(&order{}, WithExcludes("items")))
+
+	allocs := testing.AllocsPerRun(100, func() { WhitelistFromStruct(&order{}) })
+	assert.True(t, allocs < 20, allocs)
+}
+
+func TestSortableFromStruct(t *testing.T) {
+	names := SortableFromStruct(&testUser{}, WithExcludes("age"))
+	assert.Equal(t, map[string]bool{
+		"_id":          true,
+		"created_at":   true,
+		"name":         true,
+		"address.city": true,
+		"tags.city":    true,
+	}, names)
+}
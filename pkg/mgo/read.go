@@ -0,0 +1,109 @@
+package mgo
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+
+	"github.com/go-dev-frame/sponge/pkg/mgo/query"
+	"github.com/go-dev-frame/sponge/pkg/utils"
+)
+
+type readConcernKey struct{}
+
+// WithMajorityRead set the read concern "majority" to the context, the documents read by FindPage and Iterate
+// are acknowledged by the majority of the replica set, e.g. the paths reading after writing.
+func WithMajorityRead(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readConcernKey{}, readconcern.Majority())
+}
+
+// WithLocalRead set the read concern "local" to the context, the documents read by FindPage and Iterate are the
+// most recent data of the instance, they may be rolled back, e.g. the cheap reads of the analytics.
+func WithLocalRead(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readConcernKey{}, readconcern.Local())
+}
+
+// the options of the collection used by FindPage and Iterate, nil if the read concern is not set in the context.
+// The read concern of the context takes precedence over the ones of the collection, the database and the client,
+// it is ignored in the transaction, which uses the read concern of the transaction.
+func readCollectionOptions(ctx context.Context) *options.CollectionOptions {
+	rc, ok := ctx.Value(readConcernKey{}).(*readconcern.ReadConcern)
+	if !ok || rc == nil {
+		return nil
+	}
+	return options.Collection().SetReadConcern(rc)
+}
+
+func readCollection(ctx context.Context, coll *mongo.Collection) (*mongo.Collection, error) {
+	opts := readCollectionOptions(ctx)
+	if opts == nil {
+		return coll, nil
+	}
+	return coll.Clone(opts)
+}
+
+// FindPage count the documents matching the filter and find the documents of the page, results must be a pointer
+// to a slice, the read concern set by WithMajorityRead or WithLocalRead in ctx is used. If ctx has the deadline,
+// e.g. the deadline budget of the request, the timeout of the count and the find is the fraction of the remaining
+// budget by utils.CallContext, utils.ErrBudgetExhausted is returned without querying if the budget is exhausted, e.g.
+//
+//	records := []*model.UserExample{}
+//	total, err := mgo.FindPage(mgo.WithMajorityRead(ctx), collection, mgo.ExcludeDeleted(filter), query.NewPage(0, 20, "-_id"), &records)
+func FindPage(ctx context.Context, coll *mongo.Collection, filter interface{}, page *query.Page, results interface{}) (int64, error) {
+	coll, err := readCollection(ctx, coll)
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel, err := utils.CallContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer cancel()
+
+	total, err := coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	if total == 0 {
+		return 0, nil
+	}
+
+	findOpts := options.Find().
+		SetSort(page.Sort()).
+		SetLimit(int64(page.Limit())).
+		SetSkip(int64(page.Skip()))
+	cursor, err := coll.Find(ctx, filter, findOpts)
+	if err != nil {
+		return 0, err
+	}
+	if err = cursor.All(ctx, results); err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+// Iterate find the documents matching the filter and call fn for each document, the document is decoded by
+// cursor.Decode in fn, the iteration stops when fn returns an error, which is returned by Iterate.
+// The read concern set by WithMajorityRead or WithLocalRead in ctx is used.
+func Iterate(ctx context.Context, coll *mongo.Collection, filter interface{}, fn func(cursor *mongo.Cursor) error, opts ...*options.FindOptions) error {
+	coll, err := readCollection(ctx, coll)
+	if err != nil {
+		return err
+	}
+
+	cursor, err := coll.Find(ctx, filter, opts...)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx) //nolint
+
+	for cursor.Next(ctx) {
+		if err = fn(cursor); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
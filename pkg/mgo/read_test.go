@@ -0,0 +1,102 @@
+package mgo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+
+	"github.com/go-dev-frame/sponge/pkg/mgo/query"
+	"github.com/go-dev-frame/sponge/pkg/utils"
+)
+
+func TestNewClientOptions(t *testing.T) {
+	o := newClientOptions("mongodb://127.0.0.1:27017")
+	assert.True(t, *o.RetryWrites)
+	assert.True(t, *o.RetryReads)
+
+	// the uri takes precedence over the defaults
+	o = newClientOptions("mongodb://127.0.0.1:27017/?retryWrites=false&retryReads=false")
+	assert.False(t, *o.RetryWrites)
+	assert.False(t, *o.RetryReads)
+
+	// the options passed to Init2 take precedence over the uri, they are merged by mongo.Connect in the same way
+	o = options.MergeClientOptions(newClientOptions("mongodb://127.0.0.1:27017"), WithOption().SetRetryWrites(false)) //nolint
+	assert.False(t, *o.RetryWrites)
+	assert.True(t, *o.RetryReads)
+}
+
+func TestReadCollectionOptions(t *testing.T) {
+	ctx := context.Background()
+	assert.Nil(t, readCollectionOptions(ctx))
+
+	o := readCollectionOptions(WithMajorityRead(ctx))
+	assert.Equal(t, readconcern.Majority(), o.ReadConcern)
+
+	o = readCollectionOptions(WithLocalRead(ctx))
+	assert.Equal(t, readconcern.Local(), o.ReadConcern)
+
+	// the latest one of the context is used
+	o = readCollectionOptions(WithLocalRead(WithMajorityRead(ctx)))
+	assert.Equal(t, readconcern.Local(), o.ReadConcern)
+
+	// the read concern of the context takes precedence over the client, the options of the collection are merged
+	// by Clone in the same way
+	clientOpts := WithOption().SetReadConcern(readconcern.Local())
+	collOpts := options.MergeCollectionOptions(options.Collection().SetReadConcern(clientOpts.ReadConcern), //nolint
+		readCollectionOptions(WithMajorityRead(ctx)))
+	assert.Equal(t, readconcern.Majority(), collOpts.ReadConcern)
+}
+
+func TestFindPageAndIterate(t *testing.T) {
+	client, err := mongo.Connect(context.Background(), options.Client().
+		ApplyURI("mongodb://127.0.0.1:1/?serverSelectionTimeoutMS=100&connectTimeoutMS=100"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer client.Disconnect(context.Background()) //nolint
+	coll := client.Database("account").Collection("user")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	// the server is unavailable
+	var records []bson.M
+	_, err = FindPage(WithMajorityRead(ctx), coll, bson.M{}, query.NewPage(0, 10, "-_id"), &records)
+	assert.Error(t, err)
+
+	err = Iterate(WithLocalRead(ctx), coll, bson.M{}, func(cursor *mongo.Cursor) error {
+		return nil
+	})
+	assert.Error(t, err)
+}
+
+func TestFindPage_Budget(t *testing.T) {
+	client, err := mongo.Connect(context.Background(), options.Client().
+		ApplyURI("mongodb://127.0.0.1:1/?serverSelectionTimeoutMS=5000&connectTimeoutMS=100"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer client.Disconnect(context.Background()) //nolint
+	coll := client.Database("account").Collection("user")
+	var records []bson.M
+
+	// the budget is exhausted, the query is not issued
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = FindPage(ctx, coll, bson.M{}, query.NewPage(0, 10, "-_id"), &records)
+	assert.ErrorIs(t, err, utils.ErrBudgetExhausted)
+
+	// the query gives up before the server selection timeout, with the fraction of the remaining budget
+	ctx, cancel = context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = FindPage(ctx, coll, bson.M{}, query.NewPage(0, 10, "-_id"), &records)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.True(t, time.Since(start) < 450*time.Millisecond, time.Since(start))
+}
@@ -0,0 +1,221 @@
+package mgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+)
+
+// IndexSpec the index required by the collection, it is matched by Name if Name is set, otherwise by Keys,
+// e.g. IndexSpec{Keys: bson.D{{Key: "name", Value: 1}, {Key: "age", Value: -1}}}
+type IndexSpec struct {
+	Name string
+	Keys bson.D
+}
+
+func (s IndexSpec) String() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	keys := make([]string, 0, len(s.Keys))
+	for _, e := range s.Keys {
+		keys = append(keys, fmt.Sprintf("%s:%v", e.Key, e.Value))
+	}
+	return "{" + strings.Join(keys, ",") + "}"
+}
+
+// CollectionSpec the collection verified and warmed by VerifyAndWarm, Warm is the optional cheap query issued to
+// populate the connection pool and the caches, e.g. FindOne of the latest document, mongo.ErrNoDocuments of the
+// query is not the error.
+type CollectionSpec struct {
+	Name    string
+	Indexes []IndexSpec
+	Warm    func(ctx context.Context, coll *mongo.Collection) error
+}
+
+// the index of the collection listed by listIndexes
+type indexInfo struct {
+	Name string `bson:"name"`
+	Key  bson.D `bson:"key"`
+}
+
+// listIndexes list the indexes of the collection, it is replaced in the tests
+var listIndexes = func(ctx context.Context, coll *mongo.Collection) ([]indexInfo, error) {
+	cursor, err := coll.Indexes().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var indexes []indexInfo
+	err = cursor.All(ctx, &indexes)
+	return indexes, err
+}
+
+// VerifyOption set the options of VerifyAndWarm.
+type VerifyOption func(*verifyOptions)
+
+type verifyOptions struct {
+	missingIndexAsWarning bool
+	log                   *zap.Logger
+	openReadiness         func(err error)
+}
+
+func defaultVerifyOptions() *verifyOptions {
+	return &verifyOptions{
+		log: zap.NewNop(),
+	}
+}
+
+func (o *verifyOptions) apply(opts ...VerifyOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithMissingIndexAsWarning the missing indexes are logged as the warnings instead of the errors, e.g. the
+// environments whose indexes are created after the deployment.
+func WithMissingIndexAsWarning() VerifyOption {
+	return func(o *verifyOptions) {
+		o.missingIndexAsWarning = true
+	}
+}
+
+// WithVerifyLogger set the logger of the warnings and the results of VerifyAndWarm.
+func WithVerifyLogger(l *zap.Logger) VerifyOption {
+	return func(o *verifyOptions) {
+		if l != nil {
+			o.log = l
+		}
+	}
+}
+
+// WithReadinessGate the outcome of VerifyAndWarm is reported to the readiness gate, open is called with the error
+// of VerifyAndWarm, nil if the verification succeeds, e.g.
+//
+//	mgo.WithReadinessGate(handlerfunc.AddReadinessGate("mongodb"))
+func WithReadinessGate(open func(err error)) VerifyOption {
+	return func(o *verifyOptions) {
+		if open != nil {
+			o.openReadiness = open
+		}
+	}
+}
+
+// VerifyAndWarm verify the required indexes of the collections and issue the warm queries at startup, all the
+// missing indexes and the failures of the collections are returned in one error, the missing indexes are the
+// warnings if WithMissingIndexAsWarning is set. The outcome is reported to the readiness gate set by
+// WithReadinessGate, so the traffic only arrives after the verification, e.g.
+//
+//	err := mgo.VerifyAndWarm(ctx, db, []mgo.CollectionSpec{{
+//		Name:    "user_example",
+//		Indexes: []mgo.IndexSpec{{Name: "name_1"}, {Keys: bson.D{{Key: "created_at", Value: -1}}}},
+//		Warm: func(ctx context.Context, coll *mongo.Collection) error {
+//			return coll.FindOne(ctx, bson.M{}).Err()
+//		},
+//	}}, mgo.WithReadinessGate(handlerfunc.AddReadinessGate("mongodb")))
+func VerifyAndWarm(ctx context.Context, db *mongo.Database, specs []CollectionSpec, opts ...VerifyOption) error {
+	o := defaultVerifyOptions()
+	o.apply(opts...)
+
+	var errs []error
+	for _, spec := range specs {
+		coll := db.Collection(spec.Name)
+		errs = append(errs, o.verifyIndexes(ctx, coll, spec.Indexes)...)
+		if spec.Warm != nil {
+			if err := spec.Warm(ctx, coll); err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+				errs = append(errs, fmt.Errorf("warm collection '%s' error: %w", spec.Name, err))
+			}
+		}
+	}
+
+	err := errors.Join(errs...)
+	if err != nil {
+		o.log.Error("mongodb verification failed", zap.Error(err))
+	} else {
+		o.log.Info("mongodb verification succeeded", zap.Int("collections", len(specs)))
+	}
+	if o.openReadiness != nil {
+		o.openReadiness(err)
+	}
+	return err
+}
+
+func (o *verifyOptions) verifyIndexes(ctx context.Context, coll *mongo.Collection, required []IndexSpec) []error {
+	if len(required) == 0 {
+		return nil
+	}
+	indexes, err := listIndexes(ctx, coll)
+	if err != nil {
+		return []error{fmt.Errorf("list indexes of collection '%s' error: %w", coll.Name(), err)}
+	}
+
+	var errs []error
+	for _, spec := range required {
+		if hasIndex(indexes, spec) {
+			continue
+		}
+		if o.missingIndexAsWarning {
+			o.log.Warn("missing index", zap.String("collection", coll.Name()), zap.String("index", spec.String()))
+			continue
+		}
+		errs = append(errs, fmt.Errorf("collection '%s' is missing index %s", coll.Name(), spec))
+	}
+	return errs
+}
+
+func hasIndex(indexes []indexInfo, spec IndexSpec) bool {
+	for _, index := range indexes {
+		if spec.Name != "" {
+			if index.Name == spec.Name {
+				return true
+			}
+			continue
+		}
+		if isSameKeys(index.Key, spec.Keys) {
+			return true
+		}
+	}
+	return false
+}
+
+// the keys are the same in order, the numeric directions of the different types are the same, e.g. 1 and int32(1)
+func isSameKeys(a bson.D, b bson.D) bool {
+	if len(a) != len(b) || len(a) == 0 {
+		return false
+	}
+	for i := range a {
+		if a[i].Key != b[i].Key {
+			return false
+		}
+		x, ok1 := toFloat(a[i].Value)
+		y, ok2 := toFloat(b[i].Value)
+		if ok1 && ok2 {
+			if x != y {
+				return false
+			}
+			continue
+		}
+		if ok1 || ok2 || fmt.Sprint(a[i].Value) != fmt.Sprint(b[i].Value) {
+			return false
+		}
+	}
+	return true
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
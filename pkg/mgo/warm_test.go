@@ -0,0 +1,125 @@
+package mgo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/go-dev-frame/sponge/pkg/gin/handlerfunc"
+)
+
+// the database is not connected, the indexes are listed by the stub
+func newWarmDB(t *testing.T, indexes map[string][]indexInfo) *mongo.Database {
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI("mongodb://127.0.0.1:1/?serverSelectionTimeoutMS=100"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	old := listIndexes
+	listIndexes = func(ctx context.Context, coll *mongo.Collection) ([]indexInfo, error) {
+		list, ok := indexes[coll.Name()]
+		if !ok {
+			return nil, errors.New("collection not found")
+		}
+		return list, nil
+	}
+	t.Cleanup(func() { listIndexes = old })
+	return client.Database("account")
+}
+
+func TestVerifyAndWarm(t *testing.T) {
+	db := newWarmDB(t, map[string][]indexInfo{
+		"user": {
+			{Name: "_id_", Key: bson.D{{Key: "_id", Value: int32(1)}}},
+			{Name: "name_1_age_-1", Key: bson.D{{Key: "name", Value: int32(1)}, {Key: "age", Value: int32(-1)}}},
+			{Name: "bio_text", Key: bson.D{{Key: "_fts", Value: "text"}, {Key: "_ftsx", Value: int32(1)}}},
+		},
+		"order": {{Name: "_id_", Key: bson.D{{Key: "_id", Value: int32(1)}}}},
+	})
+
+	warmed := map[string]bool{}
+	warm := func(ctx context.Context, coll *mongo.Collection) error {
+		warmed[coll.Name()] = true
+		return mongo.ErrNoDocuments // the empty collection is warmed
+	}
+	specs := []CollectionSpec{
+		{
+			Name: "user",
+			Indexes: []IndexSpec{
+				{Name: "bio_text"},
+				{Keys: bson.D{{Key: "name", Value: 1}, {Key: "age", Value: -1}}},
+			},
+			Warm: warm,
+		},
+		{Name: "order", Warm: warm},
+	}
+	assert.NoError(t, VerifyAndWarm(context.Background(), db, specs))
+	assert.Equal(t, map[string]bool{"user": true, "order": true}, warmed)
+
+	// the missing indexes are the errors
+	specs = []CollectionSpec{
+		{
+			Name: "user",
+			Indexes: []IndexSpec{
+				{Name: "email_1"},
+				{Keys: bson.D{{Key: "age", Value: -1}, {Key: "name", Value: 1}}}, // the order of the keys is different
+				{Keys: bson.D{{Key: "name", Value: "hashed"}}},
+			},
+		},
+		{Name: "order", Indexes: []IndexSpec{{Name: "_id_"}}, Warm: func(context.Context, *mongo.Collection) error {
+			return errors.New("timeout")
+		}},
+		{Name: "product", Indexes: []IndexSpec{{Name: "_id_"}}},
+	}
+	err := VerifyAndWarm(context.Background(), db, specs)
+	require.Error(t, err)
+	assert.Equal(t, "collection 'user' is missing index email_1\n"+
+		"collection 'user' is missing index {age:-1,name:1}\n"+
+		"collection 'user' is missing index {name:hashed}\n"+
+		"warm collection 'order' error: timeout\n"+
+		"list indexes of collection 'product' error: collection not found", err.Error())
+
+	// the missing indexes are the warnings
+	err = VerifyAndWarm(context.Background(), db, specs[:1], WithMissingIndexAsWarning(), WithVerifyLogger(nil))
+	assert.NoError(t, err)
+	err = VerifyAndWarm(context.Background(), db, specs, WithMissingIndexAsWarning())
+	assert.EqualError(t, err, "warm collection 'order' error: timeout\n"+
+		"list indexes of collection 'product' error: collection not found")
+}
+
+func TestVerifyAndWarm_Readiness(t *testing.T) {
+	db := newWarmDB(t, map[string][]indexInfo{"user": {{Name: "_id_", Key: bson.D{{Key: "_id", Value: int32(1)}}}}})
+
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.GET("/ready", handlerfunc.CheckReady)
+	ready := func() int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ready", nil))
+		return w.Code
+	}
+
+	// not ready until the verification is done
+	open := handlerfunc.AddReadinessGate("mongodb")
+	defer open(nil)
+	assert.Equal(t, http.StatusServiceUnavailable, ready())
+
+	specs := []CollectionSpec{{Name: "user", Indexes: []IndexSpec{{Name: "name_1"}}}}
+	err := VerifyAndWarm(context.Background(), db, specs, WithReadinessGate(open))
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, ready())
+
+	specs[0].Indexes = []IndexSpec{{Keys: bson.D{{Key: "_id", Value: 1}}}}
+	err = VerifyAndWarm(context.Background(), db, specs, WithReadinessGate(open))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, ready())
+}
@@ -0,0 +1,289 @@
+// Package mq is an at-least-once consumer helper for the message queue clients, e.g. rabbitmq and kafka,
+// it supports the bounded concurrency, retry with the max attempts, dead-lettering, panic recovery,
+// graceful shutdown and metrics.
+package mq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/go-dev-frame/sponge/pkg/logger"
+)
+
+// the headers set by the consumer
+const (
+	// HeaderAttempt the number of the attempts that have failed, it is increased when the message is requeued
+	HeaderAttempt = "x-attempt"
+	// HeaderDeadLetterReason the error of the last attempt, it is set when the message is dead-lettered
+	HeaderDeadLetterReason = "x-dead-letter-reason"
+)
+
+// Message is the message received from the broker
+type Message struct {
+	ID        string
+	Topic     string // topic of kafka, or queue name of rabbitmq
+	Key       string
+	Body      []byte
+	Headers   map[string]string
+	Timestamp time.Time
+}
+
+// Attempt returns the number of the attempts that have failed, it is parsed from the header x-attempt
+func (m *Message) Attempt() int {
+	n, _ := strconv.Atoi(m.Headers[HeaderAttempt])
+	return n
+}
+
+// clone the message with the copied headers
+func (m *Message) clone() *Message {
+	cp := *m
+	cp.Headers = make(map[string]string, len(m.Headers)+2)
+	for k, v := range m.Headers {
+		cp.Headers[k] = v
+	}
+	return &cp
+}
+
+// Delivery is a message to be acknowledged
+type Delivery interface {
+	Message() *Message
+	// Ack the message is finished, it is not delivered again
+	Ack() error
+	// Nack the message is not finished, if requeue is true, it is delivered again
+	Nack(requeue bool) error
+}
+
+// LagDelivery is the delivery that knows the lag of the consumer, e.g. the number of the messages behind
+// the latest offset of the kafka partition
+type LagDelivery interface {
+	Delivery
+	Lag() int64
+}
+
+// Broker is the adapter of the message queue client
+type Broker interface {
+	// Receive start receiving, the channel is closed when the connection is lost or ctx is done,
+	// and Receive is called again to reconnect.
+	Receive(ctx context.Context) (<-chan Delivery, error)
+	// Requeue publish the message to the same queue or topic again for retrying.
+	Requeue(ctx context.Context, msg *Message) error
+	// DeadLetter publish the message to the dead letter queue or topic.
+	DeadLetter(ctx context.Context, msg *Message) error
+}
+
+// Handler handle the message, the message is retried if it returns a retryable error
+type Handler func(ctx context.Context, msg *Message) error
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent mark the error as not retryable, the message is dead-lettered directly, e.g. the message is malformed.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether the error is permanent
+func IsPermanent(err error) bool {
+	var pe *permanentError
+	return errors.As(err, &pe)
+}
+
+// ------------------------------------------------------------------------------------------
+
+// Consumer consume the messages of the broker at least once, the message is acknowledged after the handler
+// succeeds, if the handler fails or panics, the message is requeued with the increased attempt header, and it
+// is dead-lettered after the attempts are exhausted.
+//
+// Consumer implements app.Worker, it can be started from the app runner by app.WithWorkers(consumer).
+type Consumer struct {
+	name    string
+	broker  Broker
+	handler Handler
+	opts    *options
+	metrics *consumerMetrics
+}
+
+// NewConsumer create a consumer, the name is used in the logs and the label of the metrics.
+func NewConsumer(name string, broker Broker, handler Handler, opts ...Option) (*Consumer, error) {
+	if broker == nil || handler == nil {
+		return nil, errors.New("broker and handler must not be nil")
+	}
+	o := defaultOptions()
+	o.apply(opts...)
+
+	m, err := getConsumerMetrics(o.registerer)
+	if err != nil {
+		return nil, err
+	}
+	return &Consumer{
+		name:    name,
+		broker:  broker,
+		handler: handler,
+		opts:    o,
+		metrics: m,
+	}, nil
+}
+
+// String returns the name of the consumer
+func (c *Consumer) String() string {
+	return c.name
+}
+
+// Run consume the messages until ctx is canceled, the in-flight messages are finished before returning,
+// the messages received but not started are not acknowledged, and they are delivered again by the broker.
+func (c *Consumer) Run(ctx context.Context) error {
+	sem := make(chan struct{}, c.opts.concurrency)
+	wg := &sync.WaitGroup{}
+	defer wg.Wait() // drain the in-flight messages
+
+	for {
+		deliveries, err := c.broker.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			logger.Warn("receive messages error, reconnecting", logger.String("consumer", c.name), logger.Err(err))
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(c.opts.reconnectInterval):
+			}
+			continue
+		}
+
+		if err = c.consume(ctx, deliveries, sem, wg); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Consumer) consume(ctx context.Context, deliveries <-chan Delivery, sem chan struct{}, wg *sync.WaitGroup) error {
+	for {
+		// acquire the slot before receiving, the messages are not taken if the handlers are busy
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case sem <- struct{}{}:
+		}
+
+		select {
+		case <-ctx.Done():
+			<-sem
+			return ctx.Err()
+		case d, ok := <-deliveries:
+			if !ok {
+				<-sem
+				return nil // reconnect
+			}
+			wg.Add(1)
+			go func() {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				// the in-flight message is finished even if ctx is canceled
+				c.process(context.WithoutCancel(ctx), d)
+			}()
+		}
+	}
+}
+
+func (c *Consumer) process(ctx context.Context, d Delivery) {
+	msg := d.Message()
+	if ld, ok := d.(LagDelivery); ok {
+		c.metrics.lag.WithLabelValues(c.name).Set(float64(ld.Lag()))
+	}
+
+	start := time.Now()
+	err := c.handle(ctx, msg)
+	c.metrics.duration.WithLabelValues(c.name).Observe(time.Since(start).Seconds())
+	if err == nil {
+		c.metrics.messages.WithLabelValues(c.name, resultSuccess).Inc()
+		c.ack(d)
+		return
+	}
+
+	c.metrics.failures.WithLabelValues(c.name).Inc()
+	next := msg.clone()
+	attempt := msg.Attempt() + 1
+	next.Headers[HeaderAttempt] = strconv.Itoa(attempt)
+
+	if attempt < c.opts.maxAttempts && !IsPermanent(err) {
+		logger.Warn("handle message error, requeue", logger.String("consumer", c.name), logger.String("id", msg.ID),
+			logger.Int("attempt", attempt), logger.Err(err))
+		if e := c.broker.Requeue(ctx, next); e != nil {
+			c.nack(d, e)
+			return
+		}
+		c.metrics.messages.WithLabelValues(c.name, resultRetry).Inc()
+		c.ack(d)
+		return
+	}
+
+	logger.Error("handle message error, dead-letter", logger.String("consumer", c.name), logger.String("id", msg.ID),
+		logger.Int("attempt", attempt), logger.Err(err))
+	next.Headers[HeaderDeadLetterReason] = err.Error()
+	if e := c.broker.DeadLetter(ctx, next); e != nil {
+		c.nack(d, e)
+		return
+	}
+	c.metrics.messages.WithLabelValues(c.name, resultDeadLetter).Inc()
+	c.ack(d)
+}
+
+// handle the message with the trace context extracted from the headers, the panic is a failure
+func (c *Consumer) handle(ctx context.Context, msg *Message) (err error) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(msg.Headers))
+	ctx, span := otel.Tracer("mq").Start(ctx, "consume "+c.name, trace.WithSpanKind(trace.SpanKindConsumer))
+	defer span.End()
+
+	if c.opts.handleTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opts.handleTimeout)
+		defer cancel()
+	}
+
+	defer func() {
+		if e := recover(); e != nil {
+			logger.Error("handle message panic", logger.String("consumer", c.name), logger.String("id", msg.ID),
+				logger.Any("panic", e), logger.String("stack", string(debug.Stack())))
+			err = fmt.Errorf("panic: %v", e)
+		}
+		if err != nil {
+			span.RecordError(err)
+		}
+	}()
+
+	return c.handler(ctx, msg)
+}
+
+func (c *Consumer) ack(d Delivery) {
+	if err := d.Ack(); err != nil {
+		logger.Warn("ack message error", logger.String("consumer", c.name), logger.String("id", d.Message().ID), logger.Err(err))
+	}
+}
+
+// nack with requeue if the message can not be requeued or dead-lettered, it is delivered again with the same attempt
+func (c *Consumer) nack(d Delivery, err error) {
+	logger.Error("publish message error, nack", logger.String("consumer", c.name), logger.String("id", d.Message().ID), logger.Err(err))
+	c.metrics.messages.WithLabelValues(c.name, resultNack).Inc()
+	if err = d.Nack(true); err != nil {
+		logger.Warn("nack message error", logger.String("consumer", c.name), logger.String("id", d.Message().ID), logger.Err(err))
+	}
+}
@@ -0,0 +1,284 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// fakeBroker redelivers the requeued messages like rabbitmq
+type fakeBroker struct {
+	ch chan Delivery
+
+	mu       sync.Mutex
+	acked    []string
+	nacked   []string
+	dead     []*Message
+	requeued int
+	failPub  bool
+	receives int
+}
+
+func newFakeBroker() *fakeBroker {
+	return &fakeBroker{ch: make(chan Delivery, 100)}
+}
+
+func (b *fakeBroker) Receive(_ context.Context) (<-chan Delivery, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.receives++
+	return b.ch, nil
+}
+
+func (b *fakeBroker) Requeue(_ context.Context, msg *Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failPub {
+		return errors.New("connection closed")
+	}
+	b.requeued++
+	b.ch <- &fakeDelivery{b: b, msg: msg}
+	return nil
+}
+
+func (b *fakeBroker) DeadLetter(_ context.Context, msg *Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failPub {
+		return errors.New("connection closed")
+	}
+	b.dead = append(b.dead, msg)
+	return nil
+}
+
+func (b *fakeBroker) publish(id string, headers map[string]string) {
+	b.ch <- &fakeDelivery{b: b, msg: &Message{ID: id, Body: []byte(id), Headers: headers}}
+}
+
+func (b *fakeBroker) getAcked() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string{}, b.acked...)
+}
+
+func (b *fakeBroker) getDead() []*Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]*Message{}, b.dead...)
+}
+
+type fakeDelivery struct {
+	b   *fakeBroker
+	msg *Message
+}
+
+func (d *fakeDelivery) Message() *Message { return d.msg }
+func (d *fakeDelivery) Lag() int64        { return 7 }
+
+func (d *fakeDelivery) Ack() error {
+	d.b.mu.Lock()
+	defer d.b.mu.Unlock()
+	d.b.acked = append(d.b.acked, d.msg.ID+"#"+d.msg.Headers[HeaderAttempt])
+	return nil
+}
+
+func (d *fakeDelivery) Nack(requeue bool) error {
+	d.b.mu.Lock()
+	defer d.b.mu.Unlock()
+	d.b.nacked = append(d.b.nacked, d.msg.ID+" requeue="+strconv.FormatBool(requeue))
+	return nil
+}
+
+func runConsumer(t *testing.T, c *Consumer) context.CancelFunc {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.ErrorIs(t, c.Run(ctx), context.Canceled)
+	}()
+	stop := func() {
+		cancel()
+		<-done
+	}
+	t.Cleanup(stop)
+	return stop
+}
+
+func TestConsumer_Retry(t *testing.T) {
+	b := newFakeBroker()
+	var calls sync.Map
+	c, err := NewConsumer("orders", b, func(ctx context.Context, msg *Message) error {
+		n, _ := calls.LoadOrStore(msg.ID, new(int32))
+		if atomic.AddInt32(n.(*int32), 1) < 3 {
+			return errors.New("db timeout")
+		}
+		return nil
+	}, WithRegisterer(prometheus.NewRegistry()), WithMaxAttempts(3))
+	require.NoError(t, err)
+	runConsumer(t, c)
+
+	b.publish("1", nil)
+	require.Eventually(t, func() bool { return len(b.getAcked()) == 3 }, time.Second, 5*time.Millisecond)
+	// the original and the requeued messages are acknowledged, the attempt header is increased
+	assert.Equal(t, []string{"1#", "1#1", "1#2"}, b.getAcked())
+	assert.Empty(t, b.getDead())
+	assert.Equal(t, float64(1), testutil.ToFloat64(c.metrics.messages.WithLabelValues("orders", resultSuccess)))
+	assert.Equal(t, float64(2), testutil.ToFloat64(c.metrics.messages.WithLabelValues("orders", resultRetry)))
+	assert.Equal(t, float64(2), testutil.ToFloat64(c.metrics.failures.WithLabelValues("orders")))
+	assert.Equal(t, float64(7), testutil.ToFloat64(c.metrics.lag.WithLabelValues("orders")))
+	assert.Equal(t, "orders", c.String())
+}
+
+func TestConsumer_DeadLetter(t *testing.T) {
+	b := newFakeBroker()
+	c, err := NewConsumer("orders", b, func(ctx context.Context, msg *Message) error {
+		switch msg.ID {
+		case "panic":
+			panic("nil map")
+		case "malformed":
+			return Permanent(errors.New("invalid json"))
+		}
+		return errors.New("db timeout")
+	}, WithRegisterer(prometheus.NewRegistry()), WithMaxAttempts(2), WithConcurrency(4))
+	require.NoError(t, err)
+	runConsumer(t, c)
+
+	b.publish("timeout", nil)
+	b.publish("panic", nil)
+	b.publish("malformed", nil)
+	b.publish("exhausted", map[string]string{HeaderAttempt: "5"}) // requeued by the other consumer
+
+	require.Eventually(t, func() bool { return len(b.getDead()) == 4 }, time.Second, 5*time.Millisecond)
+	dead := map[string]*Message{}
+	for _, m := range b.getDead() {
+		dead[m.ID] = m
+	}
+	assert.Equal(t, "2", dead["timeout"].Headers[HeaderAttempt])
+	assert.Equal(t, "db timeout", dead["timeout"].Headers[HeaderDeadLetterReason])
+	assert.Equal(t, "2", dead["panic"].Headers[HeaderAttempt]) // the panic is a failure
+	assert.Equal(t, "panic: nil map", dead["panic"].Headers[HeaderDeadLetterReason])
+	assert.Equal(t, "1", dead["malformed"].Headers[HeaderAttempt]) // not retried
+	assert.Equal(t, "6", dead["exhausted"].Headers[HeaderAttempt])
+	assert.Equal(t, float64(4), testutil.ToFloat64(c.metrics.messages.WithLabelValues("orders", resultDeadLetter)))
+	assert.Equal(t, float64(6), testutil.ToFloat64(c.metrics.failures.WithLabelValues("orders")))
+}
+
+func TestConsumer_PublishError(t *testing.T) {
+	b := newFakeBroker()
+	b.failPub = true
+	c, err := NewConsumer("orders", b, func(ctx context.Context, msg *Message) error {
+		return errors.New("db timeout")
+	}, WithRegisterer(nil))
+	require.NoError(t, err)
+	stop := runConsumer(t, c)
+
+	b.publish("1", nil)
+	require.Eventually(t, func() bool {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		return len(b.nacked) > 0
+	}, time.Second, 5*time.Millisecond)
+	stop()
+	// the message is delivered again with the same attempt, not acknowledged
+	assert.Equal(t, "1 requeue=true", b.nacked[0])
+	assert.Empty(t, b.getAcked())
+}
+
+func TestConsumer_Drain(t *testing.T) {
+	b := newFakeBroker()
+	started := make(chan struct{}, 10)
+	var finished int32
+	c, err := NewConsumer("orders", b, func(ctx context.Context, msg *Message) error {
+		started <- struct{}{}
+		time.Sleep(100 * time.Millisecond)
+		assert.NoError(t, ctx.Err()) // not canceled when shutting down
+		atomic.AddInt32(&finished, 1)
+		return nil
+	}, WithRegisterer(nil), WithConcurrency(2))
+	require.NoError(t, err)
+	stop := runConsumer(t, c)
+
+	for i := 0; i < 5; i++ {
+		b.publish(strconv.Itoa(i), nil)
+	}
+	<-started
+	<-started
+	stop() // returns after the in-flight messages are finished
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&finished))
+	assert.Len(t, b.getAcked(), 2)
+	assert.Len(t, b.ch, 3) // the others are not taken, they are delivered again
+}
+
+func TestConsumer_Reconnect(t *testing.T) {
+	b := &reconnectBroker{fakeBroker: newFakeBroker()}
+	c, err := NewConsumer("orders", b, func(ctx context.Context, msg *Message) error { return nil },
+		WithRegisterer(nil), WithReconnectInterval(10*time.Millisecond), WithHandleTimeout(time.Second))
+	require.NoError(t, err)
+	runConsumer(t, c)
+
+	require.Eventually(t, func() bool {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		return b.receives >= 3
+	}, time.Second, 5*time.Millisecond)
+	b.publish("1", nil)
+	require.Eventually(t, func() bool { return len(b.getAcked()) == 1 }, time.Second, 5*time.Millisecond)
+
+	_, err = NewConsumer("orders", nil, nil)
+	assert.Error(t, err)
+}
+
+// reconnectBroker fails at the first receive, and the channel of the second receive is closed
+type reconnectBroker struct {
+	*fakeBroker
+}
+
+func (b *reconnectBroker) Receive(ctx context.Context) (<-chan Delivery, error) {
+	ch, _ := b.fakeBroker.Receive(ctx)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.receives {
+	case 1:
+		return nil, errors.New("connection refused")
+	case 2:
+		closed := make(chan Delivery)
+		close(closed)
+		return closed, nil
+	}
+	return ch, nil
+}
+
+func TestConsumer_Trace(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+
+	b := newFakeBroker()
+	traceIDs := make(chan string, 1)
+	c, err := NewConsumer("orders", b, func(ctx context.Context, msg *Message) error {
+		traceIDs <- trace.SpanContextFromContext(ctx).TraceID().String()
+		return nil
+	}, WithRegisterer(nil))
+	require.NoError(t, err)
+	runConsumer(t, c)
+
+	b.publish("1", map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"})
+	select {
+	case id := <-traceIDs:
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", id)
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+}
@@ -0,0 +1,76 @@
+package mq
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// the results of the messages
+const (
+	resultSuccess    = "success"
+	resultRetry      = "retry"
+	resultDeadLetter = "dead_letter"
+	resultNack       = "nack"
+)
+
+type consumerMetrics struct {
+	messages *prometheus.CounterVec   // consumer, result
+	failures *prometheus.CounterVec   // consumer
+	duration *prometheus.HistogramVec // consumer
+	lag      *prometheus.GaugeVec     // consumer
+}
+
+func getConsumerMetrics(reg prometheus.Registerer) (*consumerMetrics, error) {
+	m := &consumerMetrics{
+		messages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mq_consumer_messages_total",
+			Help: "Total number of the consumed messages by result.",
+		}, []string{"consumer", "result"}),
+		failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mq_consumer_failures_total",
+			Help: "Total number of the handler failures, including panics.",
+		}, []string{"consumer"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "mq_consumer_handle_duration_seconds",
+			Help:    "Duration of handling a message.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"consumer"}),
+		lag: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "mq_consumer_lag",
+			Help: "Number of the messages behind the latest, only available for the brokers reporting the lag.",
+		}, []string{"consumer"}),
+	}
+	if reg == nil {
+		return m, nil
+	}
+
+	// the metrics are shared by the consumers registered to the same registerer
+	var err error
+	if m.messages, err = register(reg, m.messages); err != nil {
+		return nil, err
+	}
+	if m.failures, err = register(reg, m.failures); err != nil {
+		return nil, err
+	}
+	if m.duration, err = register(reg, m.duration); err != nil {
+		return nil, err
+	}
+	if m.lag, err = register(reg, m.lag); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func register[T prometheus.Collector](reg prometheus.Registerer, c T) (T, error) {
+	if err := reg.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(T); ok {
+				return existing, nil
+			}
+		}
+		return c, err
+	}
+	return c, nil
+}
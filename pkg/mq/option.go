@@ -0,0 +1,75 @@
+package mq
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Option set the consumer options.
+type Option func(*options)
+
+type options struct {
+	concurrency       int
+	maxAttempts       int
+	handleTimeout     time.Duration
+	reconnectInterval time.Duration
+	registerer        prometheus.Registerer
+}
+
+func defaultOptions() *options {
+	return &options{
+		concurrency:       1,
+		maxAttempts:       3,
+		reconnectInterval: 2 * time.Second,
+		registerer:        prometheus.DefaultRegisterer,
+	}
+}
+
+func (o *options) apply(opts ...Option) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithConcurrency set the max number of the messages handled concurrently, default 1
+func WithConcurrency(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.concurrency = n
+		}
+	}
+}
+
+// WithMaxAttempts set the max attempts of handling a message including the first one, the message is
+// dead-lettered after the attempts are exhausted, default 3
+func WithMaxAttempts(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.maxAttempts = n
+		}
+	}
+}
+
+// WithHandleTimeout set the timeout of handling a message, default no timeout
+func WithHandleTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.handleTimeout = timeout
+	}
+}
+
+// WithReconnectInterval set the interval of receiving again after the broker fails, default 2s
+func WithReconnectInterval(interval time.Duration) Option {
+	return func(o *options) {
+		if interval > 0 {
+			o.reconnectInterval = interval
+		}
+	}
+}
+
+// WithRegisterer set the registerer of the metrics, default prometheus.DefaultRegisterer, nil means not registered
+func WithRegisterer(reg prometheus.Registerer) Option {
+	return func(o *options) {
+		o.registerer = reg
+	}
+}
@@ -0,0 +1,249 @@
+package nacoscli
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/clients/config_client"
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// DriftGauge is 1 if the configuration running in the process is not the one in nacos, otherwise 0, by
+	// namespace, group and data id, register it to expose the metrics, e.g.
+	// prometheus.MustRegister(nacoscli.DriftGauge, nacoscli.DriftAgeGauge)
+	DriftGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "config_drift",
+			Help: "Whether the configuration running in the process is not the one in nacos, 1 is drifted, 0 is not.",
+		},
+		[]string{"namespace", "group", "data_id"},
+	)
+
+	// DriftAgeGauge the ages in seconds of the versions of the configuration, the version is loaded or remote,
+	// the age of the loaded version is the time since it is loaded at startup or the last reload, the age of
+	// the remote version is the time since it is first seen in nacos by the drift checker
+	DriftAgeGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "config_version_age_seconds",
+			Help: "Age in seconds of the loaded and the remote versions of the configuration.",
+		},
+		[]string{"namespace", "group", "data_id", "version"},
+	)
+)
+
+// DriftStatus the result of comparing the configuration running in the process to the one in nacos
+type DriftStatus struct {
+	NamespaceID string
+	Group       string
+	DataID      string
+
+	LoadedMD5 string        // md5 of the configuration loaded at startup or the last reload
+	RemoteMD5 string        // md5 of the configuration in nacos, empty if it is never got
+	LoadedAge time.Duration // time since the loaded version is loaded
+	RemoteAge time.Duration // time since the remote version is first seen
+
+	Drifted   bool  // the loaded md5 is not the remote md5
+	Reloaded  bool  // the remote configuration is reloaded by WithDriftReload in this check, the drift is cleared
+	ReloadErr error // error of validating or reloading the remote configuration, nil if it is not reloaded
+	Err       error // error of getting the remote configuration, the previous remote md5 is kept
+}
+
+// DriftChecker periodically compare the md5 of the configuration running in the process to the md5 of the one
+// in nacos, e.g. the watch of the configuration is broken, or the configuration is rolled back in nacos.
+type DriftChecker struct {
+	params *Params
+	o      *options
+	client config_client.IConfigClient
+
+	namespaceID string
+
+	mu           sync.Mutex
+	loadedMD5    string
+	loadedAt     time.Time
+	remoteMD5    string
+	remoteSeenAt time.Time
+	notifiedMD5  string // the remote md5 of the last drift passed to the callback
+	status       DriftStatus
+
+	startOnce sync.Once
+	stopOnce  sync.Once
+	started   bool
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// NewDriftChecker create a drift checker of the configuration, loadedMD5 is the md5 of the configuration loaded
+// by the process at startup, e.g. Report.ContentMD5 of WithSourceReport, call SetLoaded after reloading it.
+// Call Start to check periodically, or Check to check once.
+func NewDriftChecker(params *Params, loadedMD5 string, opts ...Option) (*DriftChecker, error) {
+	err := params.valid()
+	if err != nil {
+		return nil, err
+	}
+	if loadedMD5 == "" {
+		return nil, errors.New("loadedMD5 cannot be empty")
+	}
+
+	o := setParams(params, opts...)
+	clientConfig := *params.clientConfig
+	clientConfig.DisableUseSnapShot = true // the snapshot hides the drift
+	client, err := newConfigClient(
+		vo.NacosClientParam{
+			ClientConfig:  &clientConfig,
+			ServerConfigs: params.serverConfigs,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DriftChecker{
+		params:      params,
+		o:           o,
+		client:      client,
+		namespaceID: params.clientConfig.NamespaceId,
+		loadedMD5:   loadedMD5,
+		loadedAt:    time.Now(),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}, nil
+}
+
+// SetLoaded set the md5 of the configuration reloaded by the process, e.g. in the callback of ListenConfig
+func (d *DriftChecker) SetLoaded(md5Hex string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.loadedMD5 = md5Hex
+	d.loadedAt = time.Now()
+}
+
+// Status returns the result of the last check
+func (d *DriftChecker) Status() DriftStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.status
+}
+
+// Check get the configuration from nacos and compare its md5 to the loaded one, the gauges are updated. If the
+// configuration is drifted, it is reloaded by WithDriftReload if it is valid, and the callback of WithDriftCallback
+// is called once per remote version.
+func (d *DriftChecker) Check() DriftStatus {
+	content, err := d.client.GetConfig(vo.ConfigParam{
+		DataId: d.params.DataID,
+		Group:  d.params.Group,
+	})
+	if err == nil && content == "" {
+		err = ErrNotFound
+	}
+
+	d.mu.Lock()
+	if err == nil {
+		if remoteMD5 := contentMD5([]byte(content)); remoteMD5 != d.remoteMD5 {
+			d.remoteMD5 = remoteMD5
+			d.remoteSeenAt = time.Now()
+		}
+	}
+	remoteMD5, drifted := d.remoteMD5, d.remoteMD5 != "" && d.remoteMD5 != d.loadedMD5
+	d.mu.Unlock()
+
+	// the lock is not held by the reload, SetLoaded may be called in it
+	var reloadErr error
+	reloaded := false
+	if drifted && err == nil && d.o.driftReload != nil {
+		reloadErr = d.reload(content)
+		reloaded = reloadErr == nil
+	}
+
+	d.mu.Lock()
+	now := time.Now()
+	if reloaded {
+		d.loadedMD5, d.loadedAt = remoteMD5, now
+	}
+	status := DriftStatus{
+		NamespaceID: d.namespaceID,
+		Group:       d.params.Group,
+		DataID:      d.params.DataID,
+		LoadedMD5:   d.loadedMD5,
+		RemoteMD5:   d.remoteMD5,
+		LoadedAge:   now.Sub(d.loadedAt),
+		Drifted:     d.remoteMD5 != "" && d.remoteMD5 != d.loadedMD5,
+		Reloaded:    reloaded,
+		ReloadErr:   reloadErr,
+		Err:         err,
+	}
+	if d.remoteMD5 != "" {
+		status.RemoteAge = now.Sub(d.remoteSeenAt)
+	}
+	notify := status.Drifted && d.notifiedMD5 != d.remoteMD5
+	if notify {
+		d.notifiedMD5 = d.remoteMD5
+	}
+	d.status = status
+	d.mu.Unlock()
+
+	d.setGauges(status)
+	if notify && d.o.driftCallback != nil {
+		d.o.driftCallback(status)
+	}
+	return status
+}
+
+// the remote configuration is validated by the schema of WithDriftReload before reloading it
+func (d *DriftChecker) reload(content string) error {
+	data := []byte(content)
+	if err := ValidateContent(d.params.Format, data, d.o.driftSchema); err != nil {
+		return err
+	}
+	return d.o.driftReload(d.params.Format, data)
+}
+
+func (d *DriftChecker) setGauges(status DriftStatus) {
+	drift := 0.0
+	if status.Drifted {
+		drift = 1
+	}
+	DriftGauge.WithLabelValues(d.namespaceID, d.params.Group, d.params.DataID).Set(drift)
+	DriftAgeGauge.WithLabelValues(d.namespaceID, d.params.Group, d.params.DataID, "loaded").Set(status.LoadedAge.Seconds())
+	DriftAgeGauge.WithLabelValues(d.namespaceID, d.params.Group, d.params.DataID, "remote").Set(status.RemoteAge.Seconds())
+}
+
+// Start check the drift periodically in the background by the interval of WithDriftInterval, the first check is
+// done immediately
+func (d *DriftChecker) Start() {
+	d.startOnce.Do(d.run)
+}
+
+func (d *DriftChecker) run() {
+	d.mu.Lock()
+	d.started = true
+	d.mu.Unlock()
+	go func() {
+		defer close(d.done)
+		ticker := time.NewTicker(d.o.driftInterval)
+		defer ticker.Stop()
+		for {
+			d.Check()
+			select {
+			case <-d.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stop checking and wait for the running check to finish
+func (d *DriftChecker) Stop() {
+	d.stopOnce.Do(func() {
+		close(d.stop)
+		d.mu.Lock()
+		started := d.started
+		d.mu.Unlock()
+		if started {
+			<-d.done
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package nacoscli
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type driftConfig struct {
+	App struct {
+		Name    string `yaml:"name" validate:"required"`
+		Version int    `yaml:"version"`
+	} `yaml:"app"`
+}
+
+func driftGaugeValue(dataID string) float64 {
+	return testutil.ToFloat64(DriftGauge.WithLabelValues(namespaceID, "dev", dataID))
+}
+
+func TestDriftChecker(t *testing.T) {
+	client := &stubConfigClient{content: oldContent}
+	setStubConfigClient(t, client)
+
+	var statuses []DriftStatus
+	d, err := NewDriftChecker(newVersionParams("drift.yml"), contentMD5([]byte(oldContent)),
+		append(versionOpts, WithDriftCallback(func(s DriftStatus) { statuses = append(statuses, s) }))...)
+	require.NoError(t, err)
+
+	status := d.Check()
+	assert.False(t, status.Drifted)
+	assert.Equal(t, status.LoadedMD5, status.RemoteMD5)
+	assert.Equal(t, float64(0), driftGaugeValue("drift.yml"))
+
+	// the configuration is changed in nacos, the callback is called once per remote version
+	client.content = newContent
+	for i := 0; i < 2; i++ {
+		status = d.Check()
+		assert.True(t, status.Drifted)
+		assert.Equal(t, contentMD5([]byte(newContent)), status.RemoteMD5)
+		assert.Equal(t, contentMD5([]byte(oldContent)), status.LoadedMD5)
+	}
+	assert.Equal(t, float64(1), driftGaugeValue("drift.yml"))
+	require.Len(t, statuses, 1)
+	assert.Equal(t, status.RemoteMD5, statuses[0].RemoteMD5)
+	assert.Equal(t, status, d.Status())
+
+	// the remote md5 is kept if nacos is unavailable
+	client.err = errors.New("connection refused")
+	status = d.Check()
+	assert.ErrorIs(t, status.Err, client.err)
+	assert.True(t, status.Drifted)
+	client.err = nil
+
+	// the process reloads the configuration
+	d.SetLoaded(contentMD5([]byte(newContent)))
+	assert.False(t, d.Check().Drifted)
+	assert.Equal(t, float64(0), driftGaugeValue("drift.yml"))
+
+	// rolled back in nacos
+	client.content = oldContent
+	assert.True(t, d.Check().Drifted)
+	assert.Len(t, statuses, 2)
+
+	_, err = NewDriftChecker(newVersionParams("drift.yml"), "", versionOpts...)
+	assert.Error(t, err)
+	_, err = NewDriftChecker(&Params{}, contentMD5([]byte(oldContent)), versionOpts...)
+	assert.Error(t, err)
+}
+
+func TestDriftChecker_Reload(t *testing.T) {
+	client := &stubConfigClient{content: oldContent}
+	setStubConfigClient(t, client)
+
+	var reloaded []string
+	reload := func(format string, data []byte) error {
+		assert.Equal(t, "yaml", format)
+		reloaded = append(reloaded, string(data))
+		return nil
+	}
+	d, err := NewDriftChecker(newVersionParams("reload.yml"), contentMD5([]byte(oldContent)),
+		append(versionOpts, WithDriftReload(reload, &driftConfig{}))...)
+	require.NoError(t, err)
+
+	// the invalid configuration is not reloaded, the drift is kept
+	client.content = "app:\n  name: \"\"\n"
+	status := d.Check()
+	assert.True(t, status.Drifted)
+	assert.False(t, status.Reloaded)
+	var multiErr *MultiError
+	assert.True(t, errors.As(status.ReloadErr, &multiErr))
+	assert.Empty(t, reloaded)
+
+	// the valid configuration is reloaded, the drift is cleared
+	client.content = newContent
+	status = d.Check()
+	assert.False(t, status.Drifted)
+	assert.True(t, status.Reloaded)
+	assert.Equal(t, contentMD5([]byte(newContent)), status.LoadedMD5)
+	assert.Equal(t, []string{newContent}, reloaded)
+	assert.Equal(t, float64(0), driftGaugeValue("reload.yml"))
+	assert.False(t, d.Check().Reloaded)
+
+	// the error of reloading keeps the drift
+	reloadErr := errors.New("apply failed")
+	d.o.driftReload = func(string, []byte) error { return reloadErr }
+	client.content = oldContent
+	status = d.Check()
+	assert.True(t, status.Drifted)
+	assert.ErrorIs(t, status.ReloadErr, reloadErr)
+}
+
+func TestDriftChecker_Start(t *testing.T) {
+	client := &stubConfigClient{content: newContent}
+	setStubConfigClient(t, client)
+
+	drifted := make(chan DriftStatus, 1)
+	d, err := NewDriftChecker(newVersionParams("start.yml"), contentMD5([]byte(oldContent)),
+		append(versionOpts, WithDriftInterval(10*time.Millisecond), WithDriftCallback(func(s DriftStatus) { drifted <- s }))...)
+	require.NoError(t, err)
+	d.Start()
+	defer d.Stop()
+
+	select {
+	case s := <-drifted:
+		assert.True(t, s.Drifted)
+	case <-time.After(time.Second):
+		t.Fatal("drift is not detected")
+	}
+	time.Sleep(30 * time.Millisecond)
+	client.mu.Lock()
+	assert.True(t, client.polls > 1)
+	client.mu.Unlock()
+
+	// stop without start
+	d, err = NewDriftChecker(newVersionParams("start.yml"), contentMD5([]byte(oldContent)), versionOpts...)
+	require.NoError(t, err)
+	d.Stop()
+}
@@ -6,13 +6,29 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/nacos-group/nacos-sdk-go/v2/clients"
+	"github.com/nacos-group/nacos-sdk-go/v2/clients/cache"
 	"github.com/nacos-group/nacos-sdk-go/v2/clients/naming_client"
 	"github.com/nacos-group/nacos-sdk-go/v2/common/constant"
+	"github.com/nacos-group/nacos-sdk-go/v2/util"
 	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+
+	"github.com/go-dev-frame/sponge/pkg/conf"
+	"github.com/go-dev-frame/sponge/pkg/conf/configsource"
+)
+
+var (
+	// ErrNotFound the configuration does not exist in nacos, it is the same as configsource.ErrNotFound
+	ErrNotFound = configsource.ErrNotFound
+	// ErrUnavailable nacos is unavailable and there is no local cache, it is the same as configsource.ErrUnavailable
+	ErrUnavailable = configsource.ErrUnavailable
 )
 
+// MultiError is all the problems of the configuration content returned by ValidateContent
+type MultiError = conf.MultiError
+
 // Params nacos parameters
 type Params struct {
 	IPAddr      string // server address
@@ -54,7 +70,7 @@ func (p *Params) valid() error {
 	return nil
 }
 
-func setParams(params *Params, opts ...Option) {
+func setParams(params *Params, opts ...Option) *options {
 	o := defaultOptions()
 	o.apply(opts...)
 	params.clientConfig = o.clientConfig
@@ -84,38 +100,107 @@ func setParams(params *Params, opts ...Option) {
 			},
 		}
 	}
+
+	return o
 }
 
-// GetConfig get configuration from nacos
+// the prefix of the dataID of the encrypted configuration
+const cipherPrefix = "cipher-"
+
+// the constructor of the nacos config client, it is replaced in tests
+var newConfigClient = clients.NewConfigClient
+
+// GetConfig get configuration from nacos, the error can be checked by errors.Is with ErrNotFound and ErrUnavailable.
+// Use WithRetry to retry when nacos is unavailable, and WithSourceReport to report where the configuration is got from.
 func GetConfig(params *Params, opts ...Option) (string, []byte, error) {
 	err := params.valid()
 	if err != nil {
 		return "", nil, err
 	}
 
-	setParams(params, opts...)
+	o := setParams(params, opts...)
+
+	report := newReport(params)
+	start := time.Now()
+	data, err := getConfig(params, o, report)
+	report.Duration = time.Since(start)
+	report.Err = err
+	if err == nil {
+		report.setContent(data)
+	}
+	if o.sourceReport != nil {
+		o.sourceReport(*report)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	return params.Format, data, nil
+}
+
+func getConfig(params *Params, o *options, report *Report) ([]byte, error) {
+	// the snapshot of the local cache is read here instead of by the nacos client, so that the fallback is
+	// known to the report, the encrypted configuration is still left to the nacos client to decrypt it.
+	clientConfig := *params.clientConfig
+	readSnapshot := !clientConfig.DisableUseSnapShot && !strings.HasPrefix(params.DataID, cipherPrefix)
+	if readSnapshot {
+		clientConfig.DisableUseSnapShot = true
+	}
 
 	// create a dynamic configuration client
-	configClient, err := clients.NewConfigClient(
+	configClient, err := newConfigClient(
 		vo.NacosClientParam{
-			ClientConfig:  params.clientConfig,
+			ClientConfig:  &clientConfig,
 			ServerConfigs: params.serverConfigs,
 		},
 	)
 	if err != nil {
-		return "", nil, err
+		return nil, err
+	}
+
+	cacheKey := util.GetConfigCacheKey(params.DataID, params.Group, params.clientConfig.NamespaceId)
+	cacheDir := params.clientConfig.CacheDir + string(os.PathSeparator) + "config"
+	if _, statErr := os.Stat(cache.GetConfigFailOverContentFileName(cacheKey, cacheDir)); statErr == nil {
+		// the nacos client returns the content of the failover file without contacting the servers
+		report.FromLocalCache = true
+	} else {
+		report.Endpoints = getEndpoints(params.serverConfigs)
 	}
 
 	// read config content
-	data, err := configClient.GetConfig(vo.ConfigParam{
-		DataId: params.DataID,
-		Group:  params.Group,
-	})
+	var data string
+	for i := 0; i <= o.retries; i++ {
+		if i > 0 {
+			time.Sleep(o.retryInterval)
+			report.Retries++
+		}
+		data, err = configClient.GetConfig(vo.ConfigParam{
+			DataId: params.DataID,
+			Group:  params.Group,
+		})
+		if err == nil {
+			break
+		}
+		if strings.Contains(err.Error(), "not exist") {
+			return nil, fmt.Errorf("%w: nacos dataId %s, group %s", ErrNotFound, params.DataID, params.Group)
+		}
+	}
 	if err != nil {
-		return "", nil, err
+		if !readSnapshot {
+			return nil, configsource.Unavailable(err)
+		}
+		cached, cacheErr := cache.ReadConfigFromFile(cacheKey, cacheDir)
+		if cacheErr != nil {
+			return nil, configsource.Unavailable(err)
+		}
+		report.FromLocalCache = true
+		data = cached
+	}
+	if data == "" {
+		return nil, fmt.Errorf("%w: nacos dataId %s, group %s", ErrNotFound, params.DataID, params.Group)
 	}
 
-	return params.Format, []byte(data), err
+	return []byte(data), nil
 }
 
 // Init get configuration from nacos and parse to struct, use for configuration center
@@ -134,6 +219,12 @@ func NewNamingClient(nacosIPAddr string, nacosPort int, nacosNamespaceID string,
 		Port:        uint64(nacosPort),
 		NamespaceID: nacosNamespaceID,
 	}
+	return NewNamingClientWithParams(params, opts...)
+}
+
+// NewNamingClientWithParams create a service registration and discovery of nacos client from params,
+// the fields Group, DataID and Format are not used.
+func NewNamingClientWithParams(params *Params, opts ...Option) (naming_client.INamingClient, error) {
 	setParams(params, opts...)
 
 	return clients.NewNamingClient(
@@ -143,3 +234,103 @@ func NewNamingClient(nacosIPAddr string, nacosPort int, nacosNamespaceID string,
 		},
 	)
 }
+
+// Fragment returns the function getting the configuration from nacos, use for the fragment of the layered
+// configuration, e.g. conf.NewLoader(file, conf.WithFragment("nacos:dev/user.yml", nacoscli.Fragment(params))).
+func Fragment(params *Params, opts ...Option) func() (string, []byte, error) {
+	return func() (string, []byte, error) {
+		return GetConfig(params, opts...)
+	}
+}
+
+// ListenConfig listen for the configuration changes of nacos, onChange is called with the new content when
+// the configuration is changed, e.g. apply the log level live. Call the returned function to cancel listening.
+func ListenConfig(params *Params, onChange func(format string, data []byte), opts ...Option) (func() error, error) {
+	err := params.valid()
+	if err != nil {
+		return nil, err
+	}
+	if onChange == nil {
+		return nil, errors.New("onChange cannot be nil")
+	}
+
+	setParams(params, opts...)
+
+	configClient, err := clients.NewConfigClient(
+		vo.NacosClientParam{
+			ClientConfig:  params.clientConfig,
+			ServerConfigs: params.serverConfigs,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	format := params.Format
+	configParam := vo.ConfigParam{
+		DataId: params.DataID,
+		Group:  params.Group,
+		OnChange: func(_, _, _, data string) {
+			onChange(format, []byte(data))
+		},
+	}
+	err = configClient.ListenConfig(configParam)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		return configClient.CancelListenConfig(configParam)
+	}, nil
+}
+
+// ValidateContent dry-run validate the configuration content before publishing it, the content is parsed per
+// format, and if the schema is not nil, it is decoded to the schema strictly and the `validate` struct tags are
+// checked, all the problems are returned at once by *MultiError.
+func ValidateContent(format string, content []byte, schema interface{}) error {
+	return conf.ValidateContent(format, content, schema)
+}
+
+// PublishConfig publish the configuration content to nacos, use WithValidateBeforePublish to validate the
+// content before publishing it. It returns the md5 of the content, pass it to GetConfigAtLeast to read the
+// published version, e.g. the pods restarted after publishing.
+func PublishConfig(params *Params, content []byte, opts ...Option) (string, error) {
+	err := params.valid()
+	if err != nil {
+		return "", err
+	}
+
+	o := setParams(params, opts...)
+	if o.validateBeforePublish {
+		if err = ValidateContent(params.Format, content, o.schema); err != nil {
+			return "", err
+		}
+	}
+
+	configClient, err := newConfigClient(
+		vo.NacosClientParam{
+			ClientConfig:  params.clientConfig,
+			ServerConfigs: params.serverConfigs,
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+
+	ok, err := configClient.PublishConfig(vo.ConfigParam{
+		DataId:  params.DataID,
+		Group:   params.Group,
+		Content: string(content),
+		Type:    params.Format,
+	})
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("publish config failed, nacos dataId %s, group %s", params.DataID, params.Group)
+	}
+
+	md5Hex := contentMD5(content)
+	addPublishedMD5(params, md5Hex)
+	return md5Hex, nil
+}
@@ -2,6 +2,7 @@ package nacoscli
 
 import (
 	"context"
+	"errors"
 	"os"
 	"testing"
 	"time"
@@ -68,6 +69,12 @@ func TestNewNamingClient(t *testing.T) {
 		namingClient, err := NewNamingClient(ipAddr, port, namespaceID)
 		t.Log(err, namingClient)
 	})
+
+	utils.SafeRunWithTimeout(time.Second*2, func(cancel context.CancelFunc) {
+		namingClient, err := NewNamingClientWithParams(&Params{IPAddr: ipAddr, Port: uint64(port), NamespaceID: namespaceID, Scheme: "http"},
+			WithAuth("foo", "bar"))
+		t.Log(err, namingClient)
+	})
 }
 
 func TestError(t *testing.T) {
@@ -102,3 +109,80 @@ func TestError(t *testing.T) {
 	_, _, err = GetConfig(&Params{})
 	assert.Error(t, err)
 }
+
+func TestFragment(t *testing.T) {
+	_, _, err := Fragment(&Params{})()
+	assert.Error(t, err)
+}
+
+func TestListenConfig(t *testing.T) {
+	_, err := ListenConfig(&Params{}, func(format string, data []byte) {})
+	assert.Error(t, err)
+
+	params := &Params{Group: "dev", DataID: "serverNameExample.yml", Format: "yaml"}
+	_, err = ListenConfig(params, nil)
+	assert.Error(t, err)
+
+	params = &Params{
+		IPAddr:      ipAddr,
+		Port:        uint64(port),
+		NamespaceID: namespaceID,
+		Group:       "dev",
+		DataID:      "serverNameExample.yml",
+		Format:      "yaml",
+	}
+	utils.SafeRunWithTimeout(time.Second*2, func(cancel context.CancelFunc) {
+		cancelListen, err := ListenConfig(params, func(format string, data []byte) {
+			t.Log(format, string(data))
+		})
+		if err != nil {
+			t.Log(err)
+			return
+		}
+		_ = cancelListen()
+	})
+}
+
+type publishConfig struct {
+	App struct {
+		Name string `yaml:"name" validate:"required"`
+	} `yaml:"app"`
+}
+
+func TestValidateContent(t *testing.T) {
+	err := ValidateContent("yaml", []byte("app:\n  name: user\n"), &publishConfig{})
+	assert.NoError(t, err)
+
+	// malformed yaml
+	err = ValidateContent("yaml", []byte("app:\n  name: user\n port: 8080\n"), nil)
+	var multiErr *MultiError
+	assert.True(t, errors.As(err, &multiErr))
+
+	// unknown keys and tag violations
+	err = ValidateContent("yaml", []byte("app:\n  name: \"\"\n  port: 8080\n"), &publishConfig{})
+	assert.True(t, errors.As(err, &multiErr))
+	assert.Len(t, multiErr.Errors, 2)
+}
+
+func TestPublishConfig(t *testing.T) {
+	_, err := PublishConfig(&Params{}, []byte("app:\n  name: user\n"))
+	assert.Error(t, err)
+
+	// not published if the content is invalid
+	params := &Params{
+		IPAddr:      ipAddr,
+		Port:        uint64(port),
+		NamespaceID: namespaceID,
+		Group:       "dev",
+		DataID:      "serverNameExample.yml",
+		Format:      "yaml",
+	}
+	_, err = PublishConfig(params, []byte("app:\n  name: \"\"\n"), WithValidateBeforePublish(&publishConfig{}))
+	var multiErr *MultiError
+	assert.True(t, errors.As(err, &multiErr))
+
+	utils.SafeRunWithTimeout(time.Second*2, func(cancel context.CancelFunc) {
+		_, err := PublishConfig(params, []byte("app:\n  name: user\n"), WithValidateBeforePublish(&publishConfig{}))
+		t.Log(err)
+	})
+}
@@ -1,6 +1,8 @@
 package nacoscli
 
 import (
+	"time"
+
 	"github.com/nacos-group/nacos-sdk-go/v2/common/constant"
 )
 
@@ -11,12 +13,26 @@ type options struct {
 	// if set the clientConfig, the above fields(username, password) are invalid
 	clientConfig  *constant.ClientConfig
 	serverConfigs []constant.ServerConfig
+
+	validateBeforePublish bool
+	schema                interface{}
+
+	retries       int
+	retryInterval time.Duration
+	sourceReport  func(Report)
+
+	driftInterval time.Duration
+	driftCallback func(DriftStatus)
+	driftReload   func(format string, data []byte) error
+	driftSchema   interface{}
 }
 
 func defaultOptions() *options {
 	return &options{
 		clientConfig:  nil,
 		serverConfigs: nil,
+		retryInterval: time.Second,
+		driftInterval: time.Minute,
 	}
 }
 
@@ -50,3 +66,62 @@ func WithServerConfigs(serverConfigs []constant.ServerConfig) Option {
 		o.serverConfigs = serverConfigs
 	}
 }
+
+// WithValidateBeforePublish validate the content by ValidateContent with the schema before publishing it,
+// the content is not published if it is invalid, the schema can be nil to check the syntax only
+func WithValidateBeforePublish(schema interface{}) Option {
+	return func(o *options) {
+		o.validateBeforePublish = true
+		o.schema = schema
+	}
+}
+
+// WithRetry set the retry times and interval of getting the configuration when nacos is unavailable,
+// default no retry, 1s
+func WithRetry(retries int, interval time.Duration) Option {
+	return func(o *options) {
+		if retries >= 0 {
+			o.retries = retries
+		}
+		if interval > 0 {
+			o.retryInterval = interval
+		}
+	}
+}
+
+// WithSourceReport set the function called with the report of the resolved configuration source after
+// getting the configuration, whether it succeeds or not, e.g. log it once at startup. The report never
+// includes the content of the configuration.
+func WithSourceReport(fn func(Report)) Option {
+	return func(o *options) {
+		o.sourceReport = fn
+	}
+}
+
+// WithDriftInterval set the interval of checking the drift of the configuration by DriftChecker.Start, default 1m
+func WithDriftInterval(interval time.Duration) Option {
+	return func(o *options) {
+		if interval > 0 {
+			o.driftInterval = interval
+		}
+	}
+}
+
+// WithDriftCallback set the function called when the drift of the configuration is detected by DriftChecker,
+// it is called once per remote version, e.g. alert that the process is running the stale configuration.
+func WithDriftCallback(fn func(DriftStatus)) Option {
+	return func(o *options) {
+		o.driftCallback = fn
+	}
+}
+
+// WithDriftReload reload the remote configuration when the drift is detected by DriftChecker, the content is
+// validated by ValidateContent with the schema first, and it is not reloaded if it is invalid, the schema can be
+// nil to check the syntax only. The drift is cleared if reload returns nil, e.g. pass the function applying the
+// configuration which is also passed to ListenConfig.
+func WithDriftReload(reload func(format string, data []byte) error, schema interface{}) Option {
+	return func(o *options) {
+		o.driftReload = reload
+		o.driftSchema = schema
+	}
+}
@@ -0,0 +1,54 @@
+package nacoscli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/common/constant"
+)
+
+// Report the resolved source of the configuration got from nacos, set by WithSourceReport.
+// It never includes the content of the configuration, which may contain secrets.
+type Report struct {
+	// the nacos servers contacted, empty if the content is read from the failover file of the local cache
+	Endpoints   []string
+	NamespaceID string
+	Group       string
+	DataID      string
+	Format      string
+
+	ContentLength int    // length of the content in bytes
+	ContentMD5    string // md5 of the content in hex, it is the same as the md5 shown by the nacos console
+
+	// the content is read from the local cache (the failover file or the snapshot) instead of the servers
+	FromLocalCache bool
+	Duration       time.Duration // time taken to get the configuration, including the retries
+	Retries        int           // times of retrying when nacos is unavailable
+	Err            error         // error of getting the configuration, nil if succeeded
+}
+
+func newReport(params *Params) *Report {
+	return &Report{
+		NamespaceID: params.clientConfig.NamespaceId,
+		Group:       params.Group,
+		DataID:      params.DataID,
+		Format:      params.Format,
+	}
+}
+
+func (r *Report) setContent(data []byte) {
+	r.ContentLength = len(data)
+	r.ContentMD5 = contentMD5(data)
+}
+
+func getEndpoints(serverConfigs []constant.ServerConfig) []string {
+	endpoints := make([]string, 0, len(serverConfigs))
+	for _, sc := range serverConfigs {
+		scheme := sc.Scheme
+		if scheme == "" {
+			scheme = "http"
+		}
+		endpoints = append(endpoints, fmt.Sprintf("%s://%s:%d%s", scheme, sc.IpAddr, sc.Port, sc.ContextPath))
+	}
+	return endpoints
+}
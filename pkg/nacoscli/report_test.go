@@ -0,0 +1,156 @@
+package nacoscli
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/clients/cache"
+	"github.com/nacos-group/nacos-sdk-go/v2/clients/config_client"
+	"github.com/nacos-group/nacos-sdk-go/v2/common/constant"
+	"github.com/nacos-group/nacos-sdk-go/v2/util"
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+	"github.com/stretchr/testify/assert"
+)
+
+const reportContent = "app:\n  name: user\n  password: secret\n"
+
+type fakeConfigClient struct {
+	config_client.IConfigClient
+	calls     int
+	getConfig func(calls int) (string, error)
+}
+
+func (c *fakeConfigClient) GetConfig(_ vo.ConfigParam) (string, error) {
+	c.calls++
+	return c.getConfig(c.calls)
+}
+
+func setFakeConfigClient(t *testing.T, client *fakeConfigClient) {
+	old := newConfigClient
+	newConfigClient = func(param vo.NacosClientParam) (config_client.IConfigClient, error) {
+		// the snapshot is read by GetConfig itself
+		assert.True(t, param.ClientConfig.DisableUseSnapShot)
+		return client, nil
+	}
+	t.Cleanup(func() { newConfigClient = old })
+}
+
+func getReportConfig(t *testing.T, cacheDir string, opts ...Option) (Report, []byte, error) {
+	params := &Params{Group: "dev", DataID: "user.yml", Format: "yml"}
+	var report Report
+	opts = append([]Option{
+		WithClientConfig(&constant.ClientConfig{NamespaceId: namespaceID, CacheDir: cacheDir}),
+		WithServerConfigs([]constant.ServerConfig{{IpAddr: "127.0.0.1", Port: 8848}, {IpAddr: "127.0.0.2", Port: 8848, Scheme: "https"}}),
+		WithSourceReport(func(r Report) { report = r }),
+	}, opts...)
+	_, data, err := GetConfig(params, opts...)
+	assert.False(t, report.Duration < 0)
+	assert.NotContains(t, fmt.Sprintf("%+v", report), "secret")
+	return report, data, err
+}
+
+func writeSnapshot(t *testing.T, cacheDir string, failover bool) {
+	key := util.GetConfigCacheKey("user.yml", "dev", namespaceID)
+	dir := cacheDir + string(os.PathSeparator) + "config"
+	if failover {
+		assert.NoError(t, os.MkdirAll(dir, 0o700))
+		assert.NoError(t, os.WriteFile(cache.GetConfigFailOverContentFileName(key, dir), []byte(reportContent), 0o600))
+		return
+	}
+	assert.NoError(t, cache.WriteConfigToFile(key, dir, reportContent))
+}
+
+func TestSourceReport_Success(t *testing.T) {
+	setFakeConfigClient(t, &fakeConfigClient{getConfig: func(int) (string, error) {
+		return reportContent, nil
+	}})
+
+	report, data, err := getReportConfig(t, t.TempDir())
+	assert.NoError(t, err)
+	assert.Equal(t, reportContent, string(data))
+
+	sum := md5.Sum([]byte(reportContent))
+	assert.Equal(t, []string{"http://127.0.0.1:8848", "https://127.0.0.2:8848"}, report.Endpoints)
+	assert.Equal(t, namespaceID, report.NamespaceID)
+	assert.Equal(t, "dev", report.Group)
+	assert.Equal(t, "user.yml", report.DataID)
+	assert.Equal(t, "yaml", report.Format)
+	assert.Equal(t, len(reportContent), report.ContentLength)
+	assert.Equal(t, hex.EncodeToString(sum[:]), report.ContentMD5)
+	assert.False(t, report.FromLocalCache)
+	assert.Equal(t, 0, report.Retries)
+	assert.NoError(t, report.Err)
+}
+
+func TestSourceReport_Retry(t *testing.T) {
+	client := &fakeConfigClient{getConfig: func(calls int) (string, error) {
+		if calls < 3 {
+			return "", errors.New("connection refused")
+		}
+		return reportContent, nil
+	}}
+	setFakeConfigClient(t, client)
+
+	report, data, err := getReportConfig(t, t.TempDir(), WithRetry(3, time.Millisecond))
+	assert.NoError(t, err)
+	assert.Equal(t, reportContent, string(data))
+	assert.Equal(t, 3, client.calls)
+	assert.Equal(t, 2, report.Retries)
+	assert.False(t, report.FromLocalCache)
+	assert.Equal(t, len(reportContent), report.ContentLength)
+
+	// not retried if the configuration does not exist
+	client = &fakeConfigClient{getConfig: func(int) (string, error) {
+		return "", errors.New("config data not exist")
+	}}
+	setFakeConfigClient(t, client)
+	report, _, err = getReportConfig(t, t.TempDir(), WithRetry(3, time.Millisecond))
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, report.Err, ErrNotFound)
+	assert.Equal(t, 1, client.calls)
+	assert.Equal(t, 0, report.Retries)
+	assert.Empty(t, report.ContentMD5)
+}
+
+func TestSourceReport_CacheFallback(t *testing.T) {
+	client := &fakeConfigClient{getConfig: func(int) (string, error) {
+		return "", errors.New("connection refused")
+	}}
+	setFakeConfigClient(t, client)
+
+	// no local cache
+	report, _, err := getReportConfig(t, t.TempDir(), WithRetry(1, time.Millisecond))
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.ErrorIs(t, report.Err, ErrUnavailable)
+	assert.False(t, report.FromLocalCache)
+	assert.Equal(t, 1, report.Retries)
+
+	// the snapshot is used after retrying
+	cacheDir := t.TempDir()
+	writeSnapshot(t, cacheDir, false)
+	client.calls = 0
+	report, data, err := getReportConfig(t, cacheDir, WithRetry(1, time.Millisecond))
+	assert.NoError(t, err)
+	assert.Equal(t, reportContent, string(data))
+	assert.Equal(t, 2, client.calls)
+	assert.True(t, report.FromLocalCache)
+	assert.Equal(t, 1, report.Retries)
+	assert.Len(t, report.Endpoints, 2)
+	assert.Equal(t, len(reportContent), report.ContentLength)
+
+	// the servers are not contacted if the failover file exists
+	cacheDir = t.TempDir()
+	writeSnapshot(t, cacheDir, true)
+	setFakeConfigClient(t, &fakeConfigClient{getConfig: func(int) (string, error) {
+		return reportContent, nil
+	}})
+	report, _, err = getReportConfig(t, cacheDir)
+	assert.NoError(t, err)
+	assert.True(t, report.FromLocalCache)
+	assert.Empty(t, report.Endpoints)
+}
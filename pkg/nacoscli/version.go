@@ -0,0 +1,169 @@
+package nacoscli
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+
+	"github.com/go-dev-frame/sponge/pkg/utils"
+)
+
+// the interval of polling the configuration by GetConfigAtLeast, it is doubled after each poll
+var (
+	pollInterval    = 200 * time.Millisecond
+	maxPollInterval = 2 * time.Second
+)
+
+// the max number of the md5 of the published contents kept per configuration
+const maxPublishedMD5s = 16
+
+// VersionTimeoutError the configuration of the expected version is not got in time by GetConfigAtLeast,
+// e.g. the published configuration is not propagated to all the nacos servers yet.
+type VersionTimeoutError struct {
+	DataID      string
+	Group       string
+	ExpectedMD5 string        // md5 of the expected content, returned by PublishConfig
+	ActualMD5   string        // md5 of the last content got, empty if no content is got
+	Wait        time.Duration // the time waited
+	Err         error         // the last error of getting the configuration, nil if the content is got
+}
+
+func (e *VersionTimeoutError) Error() string {
+	msg := fmt.Sprintf("nacos dataId %s, group %s: waiting for md5 %s timed out after %s, the actual md5 is '%s'",
+		e.DataID, e.Group, e.ExpectedMD5, e.Wait, e.ActualMD5)
+	if e.Err != nil {
+		msg += ", last error: " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the last error of getting the configuration
+func (e *VersionTimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// the md5 of the contents published by PublishConfig in this process, in the order of publishing,
+// so that the newer contents than the expected one are accepted by GetConfigAtLeast
+var publishedMD5s = struct {
+	mu   sync.Mutex
+	md5s map[string][]string // namespace id + group + data id --> md5s
+}{md5s: map[string][]string{}}
+
+func publishedKey(params *Params) string {
+	return params.clientConfig.NamespaceId + "+" + params.Group + "+" + params.DataID
+}
+
+func addPublishedMD5(params *Params, md5Hex string) {
+	key := publishedKey(params)
+	publishedMD5s.mu.Lock()
+	defer publishedMD5s.mu.Unlock()
+	md5s := append(publishedMD5s.md5s[key], md5Hex)
+	if len(md5s) > maxPublishedMD5s {
+		md5s = md5s[len(md5s)-maxPublishedMD5s:]
+	}
+	publishedMD5s.md5s[key] = md5s
+}
+
+// the content of md5Hex is the expected one, or it is published after the expected one in this process
+func isAtLeast(params *Params, minMD5 string, md5Hex string) bool {
+	if md5Hex == minMD5 {
+		return true
+	}
+	publishedMD5s.mu.Lock()
+	defer publishedMD5s.mu.Unlock()
+	md5s := publishedMD5s.md5s[publishedKey(params)]
+	for i, v := range md5s {
+		if v == minMD5 {
+			for _, newer := range md5s[i+1:] {
+				if newer == md5Hex {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return false
+}
+
+func contentMD5(data []byte) string {
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// GetConfigAtLeast get the configuration from nacos until its content is the expected version, minMD5 is the md5
+// returned by PublishConfig, e.g. the deploy pipeline publishes the configuration and passes the md5 to the
+// restarted pods, so that the pods never read the previous version because of the propagation lag.
+// The configuration is polled with the exponential backoff until wait expires, the content published after the
+// expected one is accepted too if it is published in the same process, otherwise only the same md5 is accepted.
+// The local cache is never used, *VersionTimeoutError with both md5 is returned if the version is not got in time.
+func GetConfigAtLeast(params *Params, minMD5 string, wait time.Duration, opts ...Option) ([]byte, error) {
+	err := params.valid()
+	if err != nil {
+		return nil, err
+	}
+	if minMD5 == "" {
+		return nil, errors.New("minMD5 cannot be empty")
+	}
+
+	setParams(params, opts...)
+	clientConfig := *params.clientConfig
+	clientConfig.DisableUseSnapShot = true // the snapshot may be the previous version
+	configClient, err := newConfigClient(
+		vo.NacosClientParam{
+			ClientConfig:  &clientConfig,
+			ServerConfigs: params.serverConfigs,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		data      []byte
+		actualMD5 string
+		lastErr   error
+		errStale  = errors.New("stale configuration")
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), wait)
+	defer cancel()
+	start := time.Now()
+	err = utils.Retry(ctx, func(context.Context) error {
+		content, err := configClient.GetConfig(vo.ConfigParam{
+			DataId: params.DataID,
+			Group:  params.Group,
+		})
+		if err != nil {
+			lastErr = err // the configuration may not exist on the server yet, keep polling
+			return err
+		}
+		lastErr = nil
+		if content == "" {
+			return errStale
+		}
+		actualMD5 = contentMD5([]byte(content))
+		if !isAtLeast(params, minMD5, actualMD5) {
+			return errStale
+		}
+		data = []byte(content)
+		return nil
+	}, utils.WithRetryAttempts(math.MaxInt), utils.WithRetryInterval(pollInterval, maxPollInterval))
+	if err != nil {
+		return nil, &VersionTimeoutError{
+			DataID:      params.DataID,
+			Group:       params.Group,
+			ExpectedMD5: minMD5,
+			ActualMD5:   actualMD5,
+			Wait:        time.Since(start),
+			Err:         lastErr,
+		}
+	}
+
+	return data, nil
+}
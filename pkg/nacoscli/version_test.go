@@ -0,0 +1,126 @@
+package nacoscli
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/clients/config_client"
+	"github.com/nacos-group/nacos-sdk-go/v2/common/constant"
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	oldContent = "app:\n  name: user\n  version: 1\n"
+	newContent = "app:\n  name: user\n  version: 2\n"
+)
+
+// stubConfigClient serves the published content after the first lagPolls polls, like the nacos server that the
+// published content is not propagated to yet
+type stubConfigClient struct {
+	config_client.IConfigClient
+	mu        sync.Mutex
+	content   string
+	published string
+	lagPolls  int
+	polls     int
+	err       error
+}
+
+func (c *stubConfigClient) GetConfig(_ vo.ConfigParam) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.polls++
+	if c.err != nil {
+		return "", c.err
+	}
+	if c.published != "" && c.polls > c.lagPolls {
+		return c.published, nil
+	}
+	return c.content, nil
+}
+
+func (c *stubConfigClient) PublishConfig(param vo.ConfigParam) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.published = param.Content
+	c.polls = 0
+	return true, nil
+}
+
+func setStubConfigClient(t *testing.T, client *stubConfigClient) {
+	old, oldInterval, oldMaxInterval := newConfigClient, pollInterval, maxPollInterval
+	newConfigClient = func(param vo.NacosClientParam) (config_client.IConfigClient, error) {
+		return client, nil
+	}
+	pollInterval, maxPollInterval = 10*time.Millisecond, 20*time.Millisecond
+	t.Cleanup(func() { newConfigClient, pollInterval, maxPollInterval = old, oldInterval, oldMaxInterval })
+}
+
+func newVersionParams(dataID string) *Params {
+	return &Params{Group: "dev", DataID: dataID, Format: "yaml"}
+}
+
+var versionOpts = []Option{
+	WithClientConfig(&constant.ClientConfig{NamespaceId: namespaceID}),
+	WithServerConfigs([]constant.ServerConfig{{IpAddr: "127.0.0.1", Port: 8848}}),
+}
+
+func TestGetConfigAtLeast(t *testing.T) {
+	client := &stubConfigClient{content: oldContent, lagPolls: 3}
+	setStubConfigClient(t, client)
+
+	md5Hex, err := PublishConfig(newVersionParams("user.yml"), []byte(newContent), versionOpts...)
+	assert.NoError(t, err)
+	assert.Equal(t, contentMD5([]byte(newContent)), md5Hex)
+
+	// the old content is served for the first 3 polls
+	data, err := GetConfigAtLeast(newVersionParams("user.yml"), md5Hex, 5*time.Second, versionOpts...)
+	assert.NoError(t, err)
+	assert.Equal(t, newContent, string(data))
+	assert.Equal(t, 4, client.polls)
+
+	// the content published later supersedes the expected one
+	newerContent := newContent + "  debug: true\n"
+	_, err = PublishConfig(newVersionParams("user.yml"), []byte(newerContent), versionOpts...)
+	assert.NoError(t, err)
+	data, err = GetConfigAtLeast(newVersionParams("user.yml"), md5Hex, 5*time.Second, versionOpts...)
+	assert.NoError(t, err)
+	assert.Equal(t, newerContent, string(data))
+
+	// the earlier content is not accepted
+	assert.False(t, isAtLeast(newVersionParams("user.yml"), contentMD5([]byte(newerContent)), md5Hex))
+	assert.False(t, isAtLeast(newVersionParams("order.yml"), md5Hex, contentMD5([]byte(newerContent))))
+}
+
+func TestGetConfigAtLeast_Timeout(t *testing.T) {
+	client := &stubConfigClient{content: oldContent}
+	setStubConfigClient(t, client)
+	expectedMD5 := contentMD5([]byte(newContent))
+
+	_, err := GetConfigAtLeast(newVersionParams("user.yml"), expectedMD5, 100*time.Millisecond, versionOpts...)
+	var timeoutErr *VersionTimeoutError
+	if assert.True(t, errors.As(err, &timeoutErr)) {
+		assert.Equal(t, expectedMD5, timeoutErr.ExpectedMD5)
+		assert.Equal(t, contentMD5([]byte(oldContent)), timeoutErr.ActualMD5)
+		assert.NoError(t, timeoutErr.Err)
+		assert.Contains(t, err.Error(), expectedMD5)
+		assert.Contains(t, err.Error(), timeoutErr.ActualMD5)
+	}
+	assert.True(t, client.polls > 1)
+
+	// the error of getting the configuration
+	client.err = errors.New("config data not exist")
+	_, err = GetConfigAtLeast(newVersionParams("user.yml"), expectedMD5, 50*time.Millisecond, versionOpts...)
+	assert.True(t, errors.As(err, &timeoutErr))
+	assert.ErrorIs(t, err, client.err)
+	assert.Empty(t, timeoutErr.ActualMD5)
+
+	// invalid params
+	_, err = GetConfigAtLeast(newVersionParams("user.yml"), "", time.Second, versionOpts...)
+	assert.Error(t, err)
+	_, err = GetConfigAtLeast(&Params{}, expectedMD5, time.Second, versionOpts...)
+	assert.Error(t, err)
+}
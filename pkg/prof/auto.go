@@ -0,0 +1,372 @@
+package prof
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrCapturing the profiles are being captured
+var ErrCapturing = errors.New("profiles are being captured")
+
+const captureTimeFormat = "20060102T150405.000"
+
+// AutoOption set the auto profiler options.
+type AutoOption func(*autoOptions)
+
+type autoOptions struct {
+	dir                string
+	cpuThreshold       float64
+	goroutineThreshold int
+	cooldown           time.Duration
+	cpuDuration        time.Duration
+	maxFiles           int
+	maxTotalSize       int64
+}
+
+func defaultAutoOptions() *autoOptions {
+	return &autoOptions{
+		dir:                joinPath(os.TempDir(), serverName+"_profile", "auto"),
+		cpuThreshold:       80,
+		goroutineThreshold: 10000,
+		cooldown:           10 * time.Minute,
+		cpuDuration:        10 * time.Second,
+		maxFiles:           30,
+		maxTotalSize:       100 << 20,
+	}
+}
+
+func (o *autoOptions) apply(opts ...AutoOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithProfileDir set the directory of the captured profiles, default is <tmp>/<serverName>_profile/auto
+func WithProfileDir(dir string) AutoOption {
+	return func(o *autoOptions) {
+		if dir != "" {
+			o.dir = dir
+		}
+	}
+}
+
+// WithCPUThreshold set the threshold of the process cpu usage to trigger capturing, unit(%), default 80, <= 0 means disabled
+func WithCPUThreshold(percent float64) AutoOption {
+	return func(o *autoOptions) {
+		o.cpuThreshold = percent
+	}
+}
+
+// WithGoroutineThreshold set the threshold of the number of goroutines to trigger capturing, default 10000,
+// <= 0 means disabled
+func WithGoroutineThreshold(n int) AutoOption {
+	return func(o *autoOptions) {
+		o.goroutineThreshold = n
+	}
+}
+
+// WithCooldown set the minimum interval between the automatic captures, default 10 minutes
+func WithCooldown(d time.Duration) AutoOption {
+	return func(o *autoOptions) {
+		if d > 0 {
+			o.cooldown = d
+		}
+	}
+}
+
+// WithCPUProfileDuration set the duration of sampling cpu profile, default 10 seconds
+func WithCPUProfileDuration(d time.Duration) AutoOption {
+	return func(o *autoOptions) {
+		if d > 0 {
+			o.cpuDuration = d
+		}
+	}
+}
+
+// WithRotation set the max number of files and the max total size of the profile directory,
+// the oldest files are deleted when exceeded, default 30 files and 100MB.
+func WithRotation(maxFiles int, maxTotalSize int64) AutoOption {
+	return func(o *autoOptions) {
+		if maxFiles > 0 {
+			o.maxFiles = maxFiles
+		}
+		if maxTotalSize > 0 {
+			o.maxTotalSize = maxTotalSize
+		}
+	}
+}
+
+// ProfileFile the captured profile file
+type ProfileFile struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// AutoProfiler capture cpu profile, goroutine dump and heap profile to the directory when the load is high.
+type AutoProfiler struct {
+	opts *autoOptions
+	now  func() time.Time
+
+	mu          sync.Mutex
+	lastCapture time.Time
+	capturing   atomic.Bool
+	wg          sync.WaitGroup
+}
+
+// NewAutoProfiler create an auto profiler, the load is reported by Observe.
+func NewAutoProfiler(opts ...AutoOption) (*AutoProfiler, error) {
+	o := defaultAutoOptions()
+	o.apply(opts...)
+	if err := os.MkdirAll(o.dir, 0766); err != nil {
+		return nil, err
+	}
+	return &AutoProfiler{opts: o, now: time.Now}, nil
+}
+
+// Observe check the load, the profiles are captured in the background if the cpu usage or the number of goroutines
+// exceeds the threshold, and it is not triggered again in the cooldown window, returns true if triggered.
+func (a *AutoProfiler) Observe(cpuUsage float64, goroutines int) bool {
+	var reason string
+	switch {
+	case a.opts.cpuThreshold > 0 && cpuUsage >= a.opts.cpuThreshold:
+		reason = fmt.Sprintf("cpu usage %.1f%% >= %.1f%%", cpuUsage, a.opts.cpuThreshold)
+	case a.opts.goroutineThreshold > 0 && goroutines >= a.opts.goroutineThreshold:
+		reason = fmt.Sprintf("goroutines %d >= %d", goroutines, a.opts.goroutineThreshold)
+	default:
+		return false
+	}
+
+	if a.capturing.Load() {
+		return false
+	}
+	a.mu.Lock()
+	now := a.now()
+	if !a.lastCapture.IsZero() && now.Sub(a.lastCapture) < a.opts.cooldown {
+		a.mu.Unlock()
+		return false
+	}
+	a.lastCapture = now
+	a.mu.Unlock()
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		files, err := a.Capture()
+		fmt.Printf("[profile] auto capture profiles, reason: %s, files: %d, err: %v\n", reason, len(files), err)
+	}()
+	return true
+}
+
+// Wait for the background captures to finish
+func (a *AutoProfiler) Wait() {
+	a.wg.Wait()
+}
+
+// Capture the goroutine dump, heap profile and cpu profile immediately, it is not limited by the cooldown,
+// returns ErrCapturing if the profiles are being captured.
+func (a *AutoProfiler) Capture() ([]ProfileFile, error) {
+	if !a.capturing.CompareAndSwap(false, true) {
+		return nil, ErrCapturing
+	}
+	defer a.capturing.Store(false)
+
+	prefix := a.now().Format(captureTimeFormat)
+	var files []ProfileFile
+	var errs []error
+	write := func(name string, fn func(w io.Writer) error) {
+		file, err := a.writeFile(prefix+"_"+name, fn)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			return
+		}
+		files = append(files, *file)
+	}
+
+	// snapshot the goroutines and heap first, then sample cpu for a while
+	write("goroutine.txt", func(w io.Writer) error {
+		return pprof.Lookup("goroutine").WriteTo(w, 2)
+	})
+	write("heap.pprof", func(w io.Writer) error {
+		return pprof.Lookup("heap").WriteTo(w, 0)
+	})
+	write("cpu.pprof", func(w io.Writer) error {
+		if err := pprof.StartCPUProfile(w); err != nil {
+			return err
+		}
+		time.Sleep(a.opts.cpuDuration)
+		pprof.StopCPUProfile()
+		return nil
+	})
+
+	if err := a.rotate(); err != nil {
+		errs = append(errs, err)
+	}
+	return files, errors.Join(errs...)
+}
+
+func (a *AutoProfiler) writeFile(name string, fn func(w io.Writer) error) (*ProfileFile, error) {
+	file := filepath.Join(a.opts.dir, name)
+	f, err := os.Create(file)
+	if err != nil {
+		return nil, err
+	}
+	err = fn(f)
+	_ = f.Close()
+	if err != nil {
+		_ = os.Remove(file)
+		return nil, err
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+	return &ProfileFile{Name: name, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+// List the captured profiles from new to old
+func (a *AutoProfiler) List() ([]ProfileFile, error) {
+	files, err := a.files()
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(files)-1; i < j; i, j = i+1, j-1 {
+		files[i], files[j] = files[j], files[i]
+	}
+	return files, nil
+}
+
+// files sorted from old to new, the file names are prefixed with the capture time
+func (a *AutoProfiler) files() ([]ProfileFile, error) {
+	entries, err := os.ReadDir(a.opts.dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []ProfileFile
+	for _, entry := range entries {
+		if entry.IsDir() || !isProfileFile(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, ProfileFile{Name: entry.Name(), Size: info.Size(), ModTime: info.ModTime()})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files, nil
+}
+
+// delete the oldest files if the number of files or the total size exceeds the limit
+func (a *AutoProfiler) rotate() error {
+	files, err := a.files()
+	if err != nil {
+		return err
+	}
+	var total int64
+	for _, f := range files {
+		total += f.Size
+	}
+	for len(files) > 0 && (len(files) > a.opts.maxFiles || total > a.opts.maxTotalSize) {
+		if err = os.Remove(filepath.Join(a.opts.dir, files[0].Name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		total -= files[0].Size
+		files = files[1:]
+	}
+	return nil
+}
+
+func isProfileFile(name string) bool {
+	return strings.HasSuffix(name, ".pprof") || strings.HasSuffix(name, ".txt")
+}
+
+// ServeHTTP list, download and capture the profiles,
+// GET /debug/profiles list the profiles, GET /debug/profiles?name=xxx download the profile,
+// POST /debug/profiles capture the profiles immediately.
+func (a *AutoProfiler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		name := r.URL.Query().Get("name")
+		if name == "" {
+			files, err := a.List()
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]interface{}{"profiles": files})
+			return
+		}
+		a.download(w, r, name)
+
+	case http.MethodPost:
+		files, err := a.Capture()
+		if err != nil {
+			code := http.StatusInternalServerError
+			if errors.Is(err, ErrCapturing) {
+				code = http.StatusConflict
+			}
+			writeJSON(w, code, map[string]interface{}{"error": err.Error(), "profiles": files})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"profiles": files})
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+func (a *AutoProfiler) download(w http.ResponseWriter, r *http.Request, name string) {
+	if filepath.Base(name) != name || !isProfileFile(name) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid profile name"})
+		return
+	}
+	file := filepath.Join(a.opts.dir, name)
+	if _, err := os.Stat(file); err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "profile not found"})
+		return
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	http.ServeFile(w, r, file)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+var defaultAutoProfiler atomic.Pointer[AutoProfiler]
+
+// InitAutoProfiler create the default auto profiler, the profiles are served by ProfilesHandler.
+func InitAutoProfiler(opts ...AutoOption) (*AutoProfiler, error) {
+	a, err := NewAutoProfiler(opts...)
+	if err != nil {
+		return nil, err
+	}
+	defaultAutoProfiler.Store(a)
+	return a, nil
+}
+
+// ProfilesHandler list, download and capture the profiles of the default auto profiler, see AutoProfiler.ServeHTTP.
+func ProfilesHandler(w http.ResponseWriter, r *http.Request) {
+	a := defaultAutoProfiler.Load()
+	if a == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "the auto profiler is not initialized"})
+		return
+	}
+	a.ServeHTTP(w, r)
+}
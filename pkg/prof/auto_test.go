@@ -0,0 +1,171 @@
+package prof
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAutoProfiler(t *testing.T, opts ...AutoOption) (*AutoProfiler, *time.Time) {
+	opts = append([]AutoOption{
+		WithProfileDir(t.TempDir()),
+		WithCPUProfileDuration(50 * time.Millisecond),
+	}, opts...)
+	a, err := NewAutoProfiler(opts...)
+	require.NoError(t, err)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	a.now = func() time.Time { return now }
+	return a, &now
+}
+
+func TestAutoProfiler_Observe(t *testing.T) {
+	a, now := newTestAutoProfiler(t,
+		WithCPUThreshold(80),
+		WithGoroutineThreshold(1000),
+		WithCooldown(time.Minute),
+	)
+
+	// fake samples of cpu usage and goroutines
+	assert.False(t, a.Observe(50, 100))
+	assert.False(t, a.Observe(79.9, 999))
+	assert.True(t, a.Observe(85, 100))
+	a.Wait()
+
+	files, err := a.List()
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+	for _, f := range files {
+		assert.True(t, strings.HasPrefix(f.Name, "20240101T000000.000_"), f.Name)
+		assert.Greater(t, f.Size, int64(0))
+	}
+
+	// in the cooldown window
+	*now = now.Add(30 * time.Second)
+	assert.False(t, a.Observe(95, 100))
+	assert.False(t, a.Observe(10, 5000))
+
+	// after the cooldown window, triggered by goroutines
+	*now = now.Add(31 * time.Second)
+	assert.True(t, a.Observe(10, 5000))
+	a.Wait()
+	files, err = a.List()
+	require.NoError(t, err)
+	assert.Len(t, files, 6)
+	assert.True(t, strings.HasPrefix(files[0].Name, "20240101T000101.000_"), files[0].Name) // new to old
+
+	// disabled thresholds
+	b, _ := newTestAutoProfiler(t, WithCPUThreshold(0), WithGoroutineThreshold(0))
+	assert.False(t, b.Observe(100, 100000))
+}
+
+func TestAutoProfiler_Capturing(t *testing.T) {
+	a, _ := newTestAutoProfiler(t, WithCPUProfileDuration(300*time.Millisecond))
+
+	assert.True(t, a.Observe(100, 0))
+	time.Sleep(100 * time.Millisecond)
+	_, err := a.Capture()
+	assert.ErrorIs(t, err, ErrCapturing)
+	a.Wait()
+
+	// manual capture is not limited by the cooldown
+	files, err := a.Capture()
+	assert.NoError(t, err)
+	assert.Len(t, files, 3)
+}
+
+func TestAutoProfiler_Rotate(t *testing.T) {
+	a, now := newTestAutoProfiler(t, WithRotation(4, 1<<30))
+
+	// the files of the other types are ignored
+	require.NoError(t, os.WriteFile(filepath.Join(a.opts.dir, "readme.md"), []byte("x"), 0666))
+
+	for i := 0; i < 3; i++ {
+		_, err := a.Capture()
+		require.NoError(t, err)
+		*now = now.Add(time.Second)
+	}
+	files, err := a.List()
+	require.NoError(t, err)
+	require.Len(t, files, 4)
+	assert.Equal(t, "20240101T000001.000_heap.pprof", files[3].Name) // the oldest kept
+	_, err = os.Stat(filepath.Join(a.opts.dir, "readme.md"))
+	assert.NoError(t, err)
+
+	// limited by the total size
+	a.opts.maxFiles = 100
+	var total int64
+	for _, f := range files[:2] {
+		total += f.Size
+	}
+	a.opts.maxTotalSize = total
+	require.NoError(t, a.rotate())
+	files, err = a.List()
+	require.NoError(t, err)
+	assert.Len(t, files, 2)
+}
+
+func TestAutoProfiler_ServeHTTP(t *testing.T) {
+	a, _ := newTestAutoProfiler(t)
+
+	// manual trigger
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/debug/profiles", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// list
+	w = httptest.NewRecorder()
+	a.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/profiles", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Profiles []ProfileFile `json:"profiles"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Profiles, 3)
+
+	// download
+	var name string
+	for _, f := range resp.Profiles {
+		if strings.HasSuffix(f.Name, "goroutine.txt") {
+			name = f.Name
+		}
+	}
+	w = httptest.NewRecorder()
+	a.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/profiles?name="+name, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), name)
+	assert.Contains(t, w.Body.String(), "goroutine")
+
+	w = httptest.NewRecorder()
+	a.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/profiles?name=../auto.go", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = httptest.NewRecorder()
+	a.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/profiles?name=notfound.pprof", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = httptest.NewRecorder()
+	a.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/debug/profiles", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestProfilesHandler(t *testing.T) {
+	defer defaultAutoProfiler.Store(nil)
+
+	w := httptest.NewRecorder()
+	ProfilesHandler(w, httptest.NewRequest(http.MethodGet, "/debug/profiles", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	_, err := InitAutoProfiler(WithProfileDir(t.TempDir()))
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	ProfilesHandler(w, httptest.NewRequest(http.MethodGet, "/debug/profiles", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"profiles":null}`, w.Body.String())
+}
@@ -7,7 +7,10 @@ import (
 	"github.com/felixge/fgprof"
 )
 
-var defaultPrefix = "/debug/pprof"
+var (
+	defaultPrefix   = "/debug/pprof"
+	profilesPattern = "/debug/profiles"
+)
 
 // Option set defaultPrefix func
 type Option func(o *options)
@@ -15,6 +18,7 @@ type Option func(o *options)
 type options struct {
 	prefix           string
 	enableIOWaitTime bool
+	enableProfiles   bool
 }
 
 func (o *options) apply(opts ...Option) {
@@ -40,6 +44,14 @@ func WithIOWaitTime() Option {
 	}
 }
 
+// WithProfiles enable the route /debug/profiles to list, download and capture the profiles of the auto profiler,
+// see InitAutoProfiler.
+func WithProfiles() Option {
+	return func(o *options) {
+		o.enableProfiles = true
+	}
+}
+
 // Register pprof server mux
 func Register(mux *http.ServeMux, opts ...Option) {
 	o := &options{prefix: defaultPrefix}
@@ -60,4 +72,8 @@ func Register(mux *http.ServeMux, opts ...Option) {
 		// Similar to /profile, add IO wait time,  https://github.com/felixge/fgprof
 		mux.Handle(o.prefix+"/profile-io", fgprof.Handler())
 	}
+
+	if o.enableProfiles {
+		mux.HandleFunc(profilesPattern, ProfilesHandler)
+	}
 }
@@ -12,7 +12,7 @@ import (
 
 func TestRegister(t *testing.T) {
 	mux := http.NewServeMux()
-	Register(mux, WithPrefix(""), WithPrefix("/myServer"), WithIOWaitTime())
+	Register(mux, WithPrefix(""), WithPrefix("/myServer"), WithIOWaitTime(), WithProfiles())
 
 	serverAddr, requestAddr := utils.GetLocalHTTPAddrPairs()
 	httpServer := &http.Server{
@@ -32,4 +32,10 @@ func TestRegister(t *testing.T) {
 		t.Fatal(err)
 	}
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Get(requestAddr + "/debug/profiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode) // the auto profiler is not initialized
 }
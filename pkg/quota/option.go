@@ -0,0 +1,54 @@
+package quota
+
+import (
+	"time"
+)
+
+// Option set the quota options.
+type Option func(*options)
+
+type options struct {
+	keyPrefix        string
+	ttl              time.Duration
+	reconcileTimeout time.Duration
+}
+
+func defaultOptions() *options {
+	return &options{
+		keyPrefix:        "quota:",
+		ttl:              24 * time.Hour,
+		reconcileTimeout: time.Minute,
+	}
+}
+
+func (o *options) apply(opts ...Option) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithKeyPrefix set the prefix of the counter keys, the key is "<prefix><resource>:<tenant>", default "quota:"
+func WithKeyPrefix(prefix string) Option {
+	return func(o *options) {
+		o.keyPrefix = prefix
+	}
+}
+
+// WithTTL set the expiration of the counter, it is refreshed when a record is created, the expired counter is
+// loaded from the database again, default 24h
+func WithTTL(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.ttl = d
+		}
+	}
+}
+
+// WithReconcileTimeout set the timeout of each run of the reconcile task, default 1m
+func WithReconcileTimeout(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.reconcileTimeout = d
+		}
+	}
+}
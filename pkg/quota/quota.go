@@ -0,0 +1,272 @@
+// Package quota provides the soft quota of the records per tenant, the usage is cached by an atomic counter
+// in redis, and it is reconciled with the database periodically to heal the drift.
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/go-dev-frame/sponge/pkg/gocron"
+	"github.com/go-dev-frame/sponge/pkg/logger"
+)
+
+// ExceededError the quota of the tenant is exceeded
+type ExceededError struct {
+	Resource string
+	Tenant   string
+	Current  int64 // the current usage
+	Limit    int64
+}
+
+// Error the error message
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("quota exceeded, resource=%s, tenant=%s, current=%d, limit=%d", e.Resource, e.Tenant, e.Current, e.Limit)
+}
+
+// Provider returns the limit and the current usage of a tenant
+type Provider interface {
+	Limit(ctx context.Context, tenant string) (int64, error)
+	Usage(ctx context.Context, tenant string) (int64, error)
+}
+
+// LimitFunc returns the limit of the tenant, e.g. by the plan of the tenant, a negative value means unlimited
+type LimitFunc func(ctx context.Context, tenant string) (int64, error)
+
+// CountFunc returns the number of the records of the tenant in the database, it is the source of truth of the usage
+type CountFunc func(ctx context.Context, tenant string) (int64, error)
+
+// check the limit and increase the counter atomically, returns {1, usage} if admitted, {0, usage} if
+// exceeded, {-1, 0} if the counter is not cached.
+var reserveScript = redis.NewScript(`
+local cur = redis.call('GET', KEYS[1])
+if not cur then
+	return {-1, 0}
+end
+cur = tonumber(cur)
+local n = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+if limit >= 0 and cur + n > limit then
+	return {0, cur}
+end
+cur = redis.call('INCRBY', KEYS[1], n)
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+return {1, cur}
+`)
+
+// decrease the counter if it is cached, not less than 0
+var releaseScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return -1
+end
+local cur = redis.call('DECRBY', KEYS[1], ARGV[1])
+if cur < 0 then
+	cur = 0
+	redis.call('SET', KEYS[1], 0, 'PX', ARGV[2])
+end
+return cur
+`)
+
+var _ Provider = (*Quota)(nil)
+
+// Quota the soft quota of a resource per tenant. The creates are admitted by checking and increasing the
+// counter atomically, so the concurrent creates at the boundary don't exceed the limit. It is soft because:
+//
+//   - if redis is unavailable, the creates are admitted and a warning is logged.
+//   - the records created bypassing Reserve (e.g. imported) are not counted until the next reconciliation.
+//   - a reconciliation running concurrently with creates may miss the creates that are reserved but not yet
+//     written to the database, the limit can be exceeded by at most the number of these in-flight creates.
+type Quota struct {
+	client   redis.UniversalClient
+	resource string
+	limit    LimitFunc
+	count    CountFunc
+	o        *options
+}
+
+// New create a quota of the resource, e.g. "userExample", limit returns the limit of the tenant, count returns
+// the number of the records of the tenant in the database.
+func New(client redis.UniversalClient, resource string, limit LimitFunc, count CountFunc, opts ...Option) (*Quota, error) {
+	if client == nil {
+		return nil, errors.New("redis client cannot be nil")
+	}
+	if resource == "" {
+		return nil, errors.New("resource cannot be empty")
+	}
+	if limit == nil || count == nil {
+		return nil, errors.New("limit and count functions cannot be nil")
+	}
+	o := defaultOptions()
+	o.apply(opts...)
+
+	return &Quota{
+		client:   client,
+		resource: resource,
+		limit:    limit,
+		count:    count,
+		o:        o,
+	}, nil
+}
+
+func (q *Quota) key(tenant string) string {
+	return q.o.keyPrefix + q.resource + ":" + tenant
+}
+
+// Limit returns the limit of the tenant, a negative value means unlimited
+func (q *Quota) Limit(ctx context.Context, tenant string) (int64, error) {
+	return q.limit(ctx, tenant)
+}
+
+// Usage returns the current usage of the tenant, it is loaded from the database if it is not cached
+func (q *Quota) Usage(ctx context.Context, tenant string) (int64, error) {
+	usage, err := q.client.Get(ctx, q.key(tenant)).Int64()
+	if err == nil {
+		return usage, nil
+	}
+	if !errors.Is(err, redis.Nil) {
+		return 0, err
+	}
+	return q.load(ctx, tenant)
+}
+
+// Reserve reserve n records for the tenant before creating them, it returns *ExceededError if the usage
+// would exceed the limit. Call Release if the records are not created successfully.
+func (q *Quota) Reserve(ctx context.Context, tenant string, n int64) error {
+	if n <= 0 {
+		return nil
+	}
+	limit, err := q.limit(ctx, tenant)
+	if err != nil {
+		return fmt.Errorf("get the quota limit error: %w", err)
+	}
+
+	key := q.key(tenant)
+	for i := 0; i < 2; i++ {
+		res, err := reserveScript.Run(ctx, q.client, []string{key}, n, limit, q.o.ttl.Milliseconds()).Int64Slice()
+		if err != nil {
+			q.warn("reserve", tenant, err)
+			return nil
+		}
+		switch res[0] {
+		case 1:
+			return nil
+		case 0:
+			return &ExceededError{Resource: q.resource, Tenant: tenant, Current: res[1], Limit: limit}
+		}
+
+		// the counter is not cached, load it from the database and try again
+		usage, err := q.count(ctx, tenant)
+		if err != nil {
+			return fmt.Errorf("count the quota usage error: %w", err)
+		}
+		if err = q.client.SetNX(ctx, key, usage, q.o.ttl).Err(); err != nil {
+			q.warn("load", tenant, err)
+			return nil
+		}
+	}
+	return nil
+}
+
+// Release release n records of the tenant, call it after the records are deleted or purged, or failed to create
+func (q *Quota) Release(ctx context.Context, tenant string, n int64) error {
+	if n <= 0 {
+		return nil
+	}
+	err := releaseScript.Run(ctx, q.client, []string{q.key(tenant)}, n, q.o.ttl.Milliseconds()).Err()
+	if err != nil {
+		q.warn("release", tenant, err)
+	}
+	return err
+}
+
+// Reconcile recount the usage of the tenant from the database and reset the counter
+func (q *Quota) Reconcile(ctx context.Context, tenant string) error {
+	usage, err := q.count(ctx, tenant)
+	if err != nil {
+		return err
+	}
+	return q.client.Set(ctx, q.key(tenant), usage, q.o.ttl).Err()
+}
+
+// ReconcileAll reconcile all the tenants whose counters are cached, returns the number of the reconciled tenants,
+// the tenants not cached are loaded from the database when they are used.
+func (q *Quota) ReconcileAll(ctx context.Context) (int, error) {
+	prefix := q.key("")
+	var keys []string
+	scan := func(ctx context.Context, client redis.UniversalClient) error {
+		iter := client.Scan(ctx, 0, prefix+"*", 100).Iterator()
+		for iter.Next(ctx) {
+			keys = append(keys, iter.Val())
+		}
+		return iter.Err()
+	}
+
+	var err error
+	if cc, ok := q.client.(*redis.ClusterClient); ok {
+		mu := sync.Mutex{}
+		err = cc.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+			mu.Lock() // the masters are scanned concurrently
+			defer mu.Unlock()
+			return scan(ctx, client)
+		})
+	} else {
+		err = scan(ctx, q.client)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	var errs []error
+	for _, key := range keys {
+		if err = q.Reconcile(ctx, strings.TrimPrefix(key, prefix)); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		count++
+	}
+	return count, errors.Join(errs...)
+}
+
+// ReconcileTask returns the periodic task of reconciling all the cached tenants, e.g.
+// gocron.Run(q.ReconcileTask(gocron.EveryMinute(10)))
+func (q *Quota) ReconcileTask(timeSpec string) *gocron.Task {
+	return &gocron.Task{
+		Name:     "quota-reconcile-" + q.resource,
+		TimeSpec: timeSpec,
+		Fn: func() {
+			ctx, cancel := context.WithTimeout(context.Background(), q.o.reconcileTimeout)
+			defer cancel()
+			start := time.Now()
+			n, err := q.ReconcileAll(ctx)
+			if err != nil {
+				logger.Warn("quota reconcile error", logger.String("resource", q.resource), logger.Int("tenants", n), logger.Err(err))
+				return
+			}
+			logger.Info("quota reconciled", logger.String("resource", q.resource), logger.Int("tenants", n),
+				logger.String("elapsed", time.Since(start).String()))
+		},
+	}
+}
+
+// load the usage from the database, the counter is set only if it is not cached
+func (q *Quota) load(ctx context.Context, tenant string) (int64, error) {
+	usage, err := q.count(ctx, tenant)
+	if err != nil {
+		return 0, err
+	}
+	if err = q.client.SetNX(ctx, q.key(tenant), usage, q.o.ttl).Err(); err != nil {
+		return 0, err
+	}
+	return usage, nil
+}
+
+func (q *Quota) warn(action string, tenant string, err error) {
+	logger.Warn("quota counter is unavailable, the request is allowed", logger.String("action", action),
+		logger.String("resource", q.resource), logger.String("tenant", tenant), logger.Err(err))
+}
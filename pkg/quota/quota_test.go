@@ -0,0 +1,232 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-dev-frame/sponge/pkg/gocron"
+)
+
+// fakeDB the number of the records per tenant
+type fakeDB struct {
+	mu     sync.Mutex
+	counts map[string]int64
+	err    error
+}
+
+func (db *fakeDB) count(_ context.Context, tenant string) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.counts[tenant], db.err
+}
+
+func (db *fakeDB) add(tenant string, n int64) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.counts[tenant] += n
+}
+
+func fixedLimit(limit int64) LimitFunc {
+	return func(context.Context, string) (int64, error) { return limit, nil }
+}
+
+func newTestQuota(t *testing.T, limit LimitFunc, opts ...Option) (*Quota, *fakeDB, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	db := &fakeDB{counts: map[string]int64{}}
+	q, err := New(client, "userExample", limit, db.count, opts...)
+	require.NoError(t, err)
+	return q, db, mr
+}
+
+func TestNew(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:6379"})
+	defer client.Close()
+	count := func(context.Context, string) (int64, error) { return 0, nil }
+
+	_, err := New(nil, "userExample", fixedLimit(1), count)
+	assert.Error(t, err)
+	_, err = New(client, "", fixedLimit(1), count)
+	assert.Error(t, err)
+	_, err = New(client, "userExample", nil, count)
+	assert.Error(t, err)
+	_, err = New(client, "userExample", fixedLimit(1), nil)
+	assert.Error(t, err)
+}
+
+func TestQuota_Reserve(t *testing.T) {
+	q, db, mr := newTestQuota(t, fixedLimit(3), WithKeyPrefix("test:"), WithTTL(time.Hour))
+	ctx := context.Background()
+	db.add("t1", 1)
+
+	// the counter is loaded from the database
+	usage, err := q.Usage(ctx, "t1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), usage)
+	assert.True(t, mr.Exists("test:userExample:t1"))
+	assert.Equal(t, time.Hour, mr.TTL("test:userExample:t1"))
+
+	assert.NoError(t, q.Reserve(ctx, "t1", 2))
+	assert.NoError(t, q.Reserve(ctx, "t1", 0))
+	err = q.Reserve(ctx, "t1", 1)
+	var exceeded *ExceededError
+	require.True(t, errors.As(err, &exceeded))
+	assert.Equal(t, &ExceededError{Resource: "userExample", Tenant: "t1", Current: 3, Limit: 3}, exceeded)
+	assert.Contains(t, err.Error(), "current=3, limit=3")
+
+	// released after deleted
+	assert.NoError(t, q.Release(ctx, "t1", 1))
+	assert.NoError(t, q.Reserve(ctx, "t1", 1))
+	// not less than 0
+	assert.NoError(t, q.Release(ctx, "t1", 10))
+	usage, _ = q.Usage(ctx, "t1")
+	assert.Equal(t, int64(0), usage)
+	// the counter is not cached
+	assert.NoError(t, q.Release(ctx, "t2", 1))
+	assert.False(t, mr.Exists("test:userExample:t2"))
+
+	// the tenants are isolated, the counter of the new tenant is loaded when reserving
+	db.add("t2", 3)
+	err = q.Reserve(ctx, "t2", 1)
+	assert.True(t, errors.As(err, &exceeded))
+	assert.Equal(t, int64(3), exceeded.Current)
+
+	// batch create
+	err = q.Reserve(ctx, "t3", 4)
+	assert.True(t, errors.As(err, &exceeded))
+	assert.NoError(t, q.Reserve(ctx, "t3", 3))
+}
+
+func TestQuota_Limit(t *testing.T) {
+	plans := map[string]int64{"free": 1, "pro": -1}
+	q, db, _ := newTestQuota(t, func(_ context.Context, tenant string) (int64, error) {
+		limit, ok := plans[tenant]
+		if !ok {
+			return 0, errors.New("unknown tenant")
+		}
+		return limit, nil
+	})
+	ctx := context.Background()
+
+	limit, err := q.Limit(ctx, "free")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), limit)
+
+	// unlimited, the usage is still counted
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, q.Reserve(ctx, "pro", 1))
+	}
+	usage, _ := q.Usage(ctx, "pro")
+	assert.Equal(t, int64(5), usage)
+
+	assert.Error(t, q.Reserve(ctx, "unknown", 1))
+
+	db.err = errors.New("db error")
+	assert.ErrorContains(t, q.Reserve(ctx, "free", 1), "db error")
+	_, err = q.Usage(ctx, "free")
+	assert.Error(t, err)
+}
+
+func TestQuota_Soft(t *testing.T) {
+	q, _, mr := newTestQuota(t, fixedLimit(1))
+	ctx := context.Background()
+	assert.NoError(t, q.Reserve(ctx, "t1", 1))
+	assert.Error(t, q.Reserve(ctx, "t1", 1))
+
+	// allowed if redis is unavailable
+	mr.SetError("ERR unavailable")
+	assert.NoError(t, q.Reserve(ctx, "t1", 1))
+	assert.Error(t, q.Release(ctx, "t1", 1))
+	_, err := q.Usage(ctx, "t1")
+	assert.Error(t, err)
+	mr.SetError("")
+}
+
+func TestQuota_Reconcile(t *testing.T) {
+	q, db, mr := newTestQuota(t, fixedLimit(5))
+	ctx := context.Background()
+
+	for _, tenant := range []string{"t1", "t2"} {
+		assert.NoError(t, q.Reserve(ctx, tenant, 1))
+		db.add(tenant, 1)
+	}
+	// drift: the reserved create failed without release, and a record is imported bypassing the quota
+	assert.NoError(t, q.Reserve(ctx, "t1", 1))
+	db.add("t2", 2)
+	db.add("t3", 4) // not cached
+
+	n, err := q.ReconcileAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	usage, _ := q.Usage(ctx, "t1")
+	assert.Equal(t, int64(1), usage)
+	usage, _ = q.Usage(ctx, "t2")
+	assert.Equal(t, int64(3), usage)
+	usage, _ = q.Usage(ctx, "t3")
+	assert.Equal(t, int64(4), usage)
+
+	// the periodic task
+	assert.NoError(t, q.Reserve(ctx, "t1", 1))
+	task := q.ReconcileTask(gocron.EveryMinute(10))
+	assert.Equal(t, "quota-reconcile-userExample", task.Name)
+	assert.Equal(t, "@every 10m", task.TimeSpec)
+	task.Fn()
+	usage, _ = q.Usage(ctx, "t1")
+	assert.Equal(t, int64(1), usage)
+
+	db.err = errors.New("db error")
+	_, err = q.ReconcileAll(ctx)
+	assert.Error(t, err)
+	task.Fn()
+
+	mr.SetError("ERR unavailable")
+	_, err = q.ReconcileAll(ctx)
+	assert.Error(t, err)
+	mr.SetError("")
+}
+
+// the concurrent creates at the boundary never exceed the limit, the tolerance is 0 without drift
+func TestQuota_ConcurrentReserve(t *testing.T) {
+	const limit = 10
+	q, db, _ := newTestQuota(t, fixedLimit(limit))
+	ctx := context.Background()
+	db.add("t1", 5)
+
+	var admitted, exceeded int64
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := q.Reserve(ctx, "t1", 1)
+			if err != nil {
+				var e *ExceededError
+				if assert.True(t, errors.As(err, &e)) {
+					assert.LessOrEqual(t, e.Current, int64(limit))
+					atomic.AddInt64(&exceeded, 1)
+				}
+				return
+			}
+			atomic.AddInt64(&admitted, 1)
+			db.add("t1", 1) // create the record
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(limit-5), admitted)
+	assert.Equal(t, int64(50-limit+5), exceeded)
+	count, _ := db.count(ctx, "t1")
+	assert.Equal(t, int64(limit), count)
+	usage, _ := q.Usage(ctx, "t1")
+	assert.Equal(t, int64(limit), usage)
+}
@@ -0,0 +1,130 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+
+	"github.com/go-dev-frame/sponge/pkg/mq"
+)
+
+// Broker adapt the consumer to mq.Broker, the messages are consumed at least once by mq.Consumer with the
+// bounded concurrency, retry and dead-lettering. The consumer is set to manual ack, the requeued messages
+// are published to the queue directly by the default exchange, and the dead letters are published to the
+// dead letter queue. The prefetch count should be set not less than the concurrency by WithConsumerQosOptions.
+type Broker struct {
+	c               *Consumer
+	deadLetterQueue string
+}
+
+// NewBroker create a broker of the consumer, the default dead letter queue is <queue name>.dlq
+func NewBroker(c *Consumer, deadLetterQueue string) *Broker {
+	c.isAutoAck = false
+	if deadLetterQueue == "" {
+		deadLetterQueue = c.QueueName + ".dlq"
+	}
+	return &Broker{c: c, deadLetterQueue: deadLetterQueue}
+}
+
+// Receive declare the exchange and queues and start consuming, the channel is closed when the rabbitmq
+// channel is closed or ctx is done.
+func (b *Broker) Receive(ctx context.Context) (<-chan mq.Delivery, error) {
+	if !b.c.connection.CheckConnected() {
+		return nil, errors.New("rabbitmq is not connected")
+	}
+	b.c.Close() // close the channel of the last session
+	if err := b.c.initialize(); err != nil {
+		return nil, err
+	}
+	_, err := b.c.ch.QueueDeclare(b.deadLetterQueue, b.c.isPersistent, false, false, false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("declare dead letter queue %s error: %w", b.deadLetterQueue, err)
+	}
+
+	deliveries, err := b.c.consumeWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b.c.zapLog.Info("[rabbitmq broker] queue is ready and waiting for messages", zap.String("queue", b.c.QueueName),
+		zap.String("deadLetterQueue", b.deadLetterQueue))
+
+	out := make(chan mq.Delivery)
+	go func() {
+		defer close(out)
+		for d := range deliveries {
+			select {
+			case out <- newDelivery(d, b.c.QueueName):
+			case <-ctx.Done():
+				return // the delivery not taken is delivered again after the channel is closed
+			}
+		}
+	}()
+	return out, nil
+}
+
+// Requeue publish the message to the queue again
+func (b *Broker) Requeue(ctx context.Context, msg *mq.Message) error {
+	return b.publish(ctx, b.c.QueueName, msg)
+}
+
+// DeadLetter publish the message to the dead letter queue
+func (b *Broker) DeadLetter(ctx context.Context, msg *mq.Message) error {
+	return b.publish(ctx, b.deadLetterQueue, msg)
+}
+
+func (b *Broker) publish(ctx context.Context, queue string, msg *mq.Message) error {
+	headers := make(amqp.Table, len(msg.Headers))
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+	deliveryMode := amqp.Transient
+	if b.c.isPersistent {
+		deliveryMode = amqp.Persistent
+	}
+	return b.c.ch.PublishWithContext(ctx, "", queue, false, false, amqp.Publishing{
+		Headers:      headers,
+		DeliveryMode: deliveryMode,
+		MessageId:    msg.ID,
+		Timestamp:    msg.Timestamp,
+		ContentType:  "text/plain",
+		Body:         msg.Body,
+	})
+}
+
+type delivery struct {
+	d   amqp.Delivery
+	msg *mq.Message
+}
+
+func newDelivery(d amqp.Delivery, queue string) *delivery {
+	headers := make(map[string]string, len(d.Headers))
+	for k, v := range d.Headers {
+		headers[k] = fmt.Sprint(v)
+	}
+	return &delivery{
+		d: d,
+		msg: &mq.Message{
+			ID:        d.MessageId,
+			Topic:     queue,
+			Key:       d.RoutingKey,
+			Body:      d.Body,
+			Headers:   headers,
+			Timestamp: d.Timestamp,
+		},
+	}
+}
+
+func (d *delivery) Message() *mq.Message {
+	return d.msg
+}
+
+func (d *delivery) Ack() error {
+	return d.d.Ack(false)
+}
+
+func (d *delivery) Nack(requeue bool) error {
+	return d.d.Nack(false, requeue)
+}
@@ -0,0 +1,35 @@
+package rabbitmq
+
+import (
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-dev-frame/sponge/pkg/mq"
+)
+
+var _ mq.Broker = (*Broker)(nil)
+
+func TestNewBroker(t *testing.T) {
+	c := &Consumer{QueueName: "orders", isAutoAck: true}
+	b := NewBroker(c, "")
+	assert.False(t, c.isAutoAck) // manual ack
+	assert.Equal(t, "orders.dlq", b.deadLetterQueue)
+
+	now := time.Now()
+	d := newDelivery(amqp.Delivery{
+		MessageId:  "1",
+		RoutingKey: "key",
+		Body:       []byte("body"),
+		Headers:    amqp.Table{mq.HeaderAttempt: "2", "count": int32(3)},
+		Timestamp:  now,
+	}, "orders")
+	msg := d.Message()
+	assert.Equal(t, "1", msg.ID)
+	assert.Equal(t, "orders", msg.Topic)
+	assert.Equal(t, 2, msg.Attempt())
+	assert.Equal(t, "3", msg.Headers["count"])
+	assert.Equal(t, now, msg.Timestamp)
+}
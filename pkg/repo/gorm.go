@@ -0,0 +1,148 @@
+package repo
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	"github.com/go-dev-frame/sponge/pkg/sgorm/query"
+)
+
+var _ Repo[struct{}, uint64] = (*GormRepo[struct{}, uint64])(nil)
+
+type gormTxKey struct {
+	db *gorm.DB
+}
+
+// GormRepo the repository over gorm, the query parameters are converted by ConvertToGormConditions, the records
+// are deleted softly if T has the field of gorm.DeletedAt.
+type GormRepo[T any, ID any] struct {
+	db *gorm.DB
+	o  *options
+}
+
+// NewGormRepo create a repository over gorm
+func NewGormRepo[T any, ID any](db *gorm.DB, opts ...Option) *GormRepo[T, ID] {
+	o := defaultOptions("id")
+	o.apply(opts...)
+	return &GormRepo[T, ID]{db: db, o: o}
+}
+
+// the transaction of the context started by WithTx, or the db
+func (r *GormRepo[T, ID]) conn(ctx context.Context) *gorm.DB {
+	if tx, ok := ctx.Value(gormTxKey{db: r.db}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return r.db.WithContext(ctx)
+}
+
+// WithTx run fn in a transaction, the methods called with the ctx passed to fn are executed in the transaction,
+// including the methods of the other repositories over the same db. The transaction is committed if fn returns
+// nil, otherwise it is rolled back, fn joins the transaction of ctx if there is one.
+func (r *GormRepo[T, ID]) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(gormTxKey{db: r.db}).(*gorm.DB); ok {
+		return fn(ctx)
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, gormTxKey{db: r.db}, tx))
+	})
+}
+
+// Create a record
+func (r *GormRepo[T, ID]) Create(ctx context.Context, record *T) error {
+	return r.conn(ctx).Create(record).Error
+}
+
+// GetByID get a record by id
+func (r *GormRepo[T, ID]) GetByID(ctx context.Context, id ID) (*T, error) {
+	record := new(T)
+	err := r.conn(ctx).Where(r.o.idName+" = ?", id).First(record).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, notFound(err)
+		}
+		return nil, err
+	}
+	return record, nil
+}
+
+// UpdateByID update the columns of a record by id, the updated time is set by gorm even if update is empty
+func (r *GormRepo[T, ID]) UpdateByID(ctx context.Context, id ID, update map[string]interface{}) error {
+	return r.conn(ctx).Model(new(T)).Where(r.o.idName+" = ?", id).Updates(update).Error
+}
+
+// DeleteByID delete a record by id
+func (r *GormRepo[T, ID]) DeleteByID(ctx context.Context, id ID) error {
+	return r.conn(ctx).Where(r.o.idName+" = ?", id).Delete(new(T)).Error
+}
+
+// List get the records of the page, the total is not counted if params.Sort is "ignore count"
+func (r *GormRepo[T, ID]) List(ctx context.Context, params *query.Params) ([]*T, int64, error) {
+	queryStr, args, err := params.ConvertToGormConditions(query.WithWhitelistNames(r.o.whitelistNames))
+	if err != nil {
+		return nil, 0, errors.New("query params error: " + err.Error())
+	}
+
+	var total int64
+	if params.Sort != "ignore count" { // determine if count is required
+		err = r.conn(ctx).Model(new(T)).Where(queryStr, args...).Count(&total).Error
+		if err != nil {
+			return nil, 0, err
+		}
+		if total == 0 {
+			return nil, total, nil
+		}
+	}
+
+	records := []*T{}
+	order, limit, offset := params.ConvertToPage()
+	err = r.conn(ctx).Order(order).Limit(limit).Offset(offset).Where(queryStr, args...).Find(&records).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return records, total, nil
+}
+
+// Count the records matching the conditions
+func (r *GormRepo[T, ID]) Count(ctx context.Context, conditions *query.Conditions) (int64, error) {
+	db, err := r.where(ctx, conditions, false)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	err = db.Count(&total).Error
+	return total, err
+}
+
+// GetByCondition get the first record matching the conditions
+func (r *GormRepo[T, ID]) GetByCondition(ctx context.Context, conditions *query.Conditions) (*T, error) {
+	db, err := r.where(ctx, conditions, true)
+	if err != nil {
+		return nil, err
+	}
+	record := new(T)
+	err = db.First(record).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, notFound(err)
+		}
+		return nil, err
+	}
+	return record, nil
+}
+
+func (r *GormRepo[T, ID]) where(ctx context.Context, conditions *query.Conditions, required bool) (*gorm.DB, error) {
+	if err := checkConditions(conditions, required); err != nil {
+		return nil, err
+	}
+	db := r.conn(ctx).Model(new(T))
+	if conditions == nil || len(conditions.Columns) == 0 {
+		return db, nil
+	}
+	queryStr, args, err := conditions.ConvertToGorm(query.WithWhitelistNames(r.o.whitelistNames))
+	if err != nil {
+		return nil, errors.New("query params error: " + err.Error())
+	}
+	return db.Where(queryStr, args...), nil
+}
@@ -0,0 +1,40 @@
+package repo
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-dev-frame/sponge/pkg/sgorm"
+	"github.com/go-dev-frame/sponge/pkg/sgorm/query"
+	"github.com/go-dev-frame/sponge/pkg/sgorm/sqlite"
+)
+
+type gormUser struct {
+	sgorm.Model `gorm:"embedded"`
+
+	Name string `gorm:"column:name;type:varchar(50)"`
+	Age  int    `gorm:"column:age"`
+}
+
+func TestGormRepo(t *testing.T) {
+	db, err := sqlite.Init(filepath.Join(t.TempDir(), "repo.db"))
+	require.NoError(t, err)
+	defer sqlite.Close(db)
+	require.NoError(t, db.AutoMigrate(&gormUser{}))
+
+	s := &conformance[gormUser, uint64]{
+		repo: NewGormRepo[gormUser, uint64](db, WithWhitelistNames(map[string]bool{"name": true, "age": true})),
+		newRecord: func(name string, age int) *gormUser {
+			return &gormUser{Name: name, Age: age}
+		},
+		getID:   func(record *gormUser) uint64 { return record.ID },
+		getName: func(record *gormUser) string { return record.Name },
+	}
+	s.run(t)
+
+	_, _, err = s.repo.List(context.Background(), &query.Params{Limit: 10, Columns: []query.Column{{Name: "deleted_at", Value: "1"}}})
+	require.Error(t, err)
+}
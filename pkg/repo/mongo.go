@@ -0,0 +1,217 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongooptions "go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/go-dev-frame/sponge/pkg/mgo"
+	mgoquery "github.com/go-dev-frame/sponge/pkg/mgo/query"
+	"github.com/go-dev-frame/sponge/pkg/sgorm/query"
+)
+
+var _ Repo[struct{}, string] = (*MongoRepo[struct{}, string])(nil)
+
+// MongoRepo the repository over mongodb, the query parameters are converted to the columns of the mgo query
+// package, the records are deleted softly by the deleted_at field, T embeds mgo.Model usually.
+type MongoRepo[T any, ID any] struct {
+	collection *mongo.Collection
+	o          *options
+}
+
+// NewMongoRepo create a repository over the mongodb collection
+func NewMongoRepo[T any, ID any](collection *mongo.Collection, opts ...Option) *MongoRepo[T, ID] {
+	o := defaultOptions("_id")
+	o.apply(opts...)
+	return &MongoRepo[T, ID]{collection: collection, o: o}
+}
+
+// WithTx run fn in a transaction of a session, the methods called with the ctx passed to fn are executed in the
+// transaction. The transaction is committed if fn returns nil, otherwise it is aborted, fn joins the transaction
+// of ctx if there is one. Note that the transactions require the replica set or the sharded cluster.
+func (r *MongoRepo[T, ID]) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+
+	session, err := r.collection.Database().Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+	return err
+}
+
+// Create a record, the id and the created time are set if T has the method SetModelValue, e.g. mgo.Model
+func (r *MongoRepo[T, ID]) Create(ctx context.Context, record *T) error {
+	if m, ok := interface{}(record).(interface{ SetModelValue() }); ok {
+		m.SetModelValue()
+	}
+	_, err := r.collection.InsertOne(ctx, record)
+	return err
+}
+
+// GetByID get a record by id
+func (r *MongoRepo[T, ID]) GetByID(ctx context.Context, id ID) (*T, error) {
+	return r.findOne(ctx, bson.M{r.o.idName: id})
+}
+
+// UpdateByID update the fields of a record by id, updated_at is set even if update is empty
+func (r *MongoRepo[T, ID]) UpdateByID(ctx context.Context, id ID, update map[string]interface{}) error {
+	updateM := bson.M{}
+	for k, v := range update {
+		updateM[k] = v
+	}
+	filter := mgo.ExcludeDeleted(bson.M{r.o.idName: id})
+	_, err := r.collection.UpdateOne(ctx, filter, mgo.EmbedUpdatedAt(updateM))
+	return err
+}
+
+// DeleteByID delete a record by id softly
+func (r *MongoRepo[T, ID]) DeleteByID(ctx context.Context, id ID) error {
+	filter := mgo.ExcludeDeleted(bson.M{r.o.idName: id})
+	_, err := r.collection.UpdateOne(ctx, filter, mgo.EmbedDeletedAt(bson.M{}))
+	return err
+}
+
+// List get the records of the page, the total is not counted if params.Sort is "ignore count"
+func (r *MongoRepo[T, ID]) List(ctx context.Context, params *query.Params) ([]*T, int64, error) {
+	filter, err := r.filter(params.Columns)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	limit := params.Limit
+	if limit == 0 {
+		limit = params.Size
+	}
+	records := []*T{}
+	if params.Sort == "ignore count" {
+		page := mgoquery.NewPage(params.Page, limit, "")
+		findOpts := mongooptions.Find().SetSort(page.Sort()).SetLimit(int64(page.Limit())).SetSkip(int64(page.Skip()))
+		cursor, err := r.collection.Find(ctx, filter, findOpts)
+		if err != nil {
+			return nil, 0, err
+		}
+		if err = cursor.All(ctx, &records); err != nil {
+			return nil, 0, err
+		}
+		return records, 0, nil
+	}
+
+	total, err := mgo.FindPage(ctx, r.collection, filter, mgoquery.NewPage(params.Page, limit, params.Sort), &records)
+	if err != nil {
+		return nil, 0, err
+	}
+	if total == 0 {
+		return nil, 0, nil
+	}
+	return records, total, nil
+}
+
+// Count the records matching the conditions
+func (r *MongoRepo[T, ID]) Count(ctx context.Context, conditions *query.Conditions) (int64, error) {
+	if err := checkConditions(conditions, false); err != nil {
+		return 0, err
+	}
+	var columns []query.Column
+	if conditions != nil {
+		columns = conditions.Columns
+	}
+	filter, err := r.filter(columns)
+	if err != nil {
+		return 0, err
+	}
+	return r.collection.CountDocuments(ctx, filter)
+}
+
+// GetByCondition get the first record matching the conditions
+func (r *MongoRepo[T, ID]) GetByCondition(ctx context.Context, conditions *query.Conditions) (*T, error) {
+	if err := checkConditions(conditions, true); err != nil {
+		return nil, err
+	}
+	filter, err := r.filter(conditions.Columns)
+	if err != nil {
+		return nil, err
+	}
+	return r.findOne(ctx, filter)
+}
+
+func (r *MongoRepo[T, ID]) findOne(ctx context.Context, filter bson.M) (*T, error) {
+	record := new(T)
+	err := r.collection.FindOne(ctx, mgo.ExcludeDeleted(filter)).Decode(record)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, notFound(err)
+		}
+		return nil, err
+	}
+	return record, nil
+}
+
+// convert the columns to the filter of mongodb excluding the deleted records
+func (r *MongoRepo[T, ID]) filter(columns []query.Column) (bson.M, error) {
+	if len(columns) == 0 {
+		return mgo.ExcludeDeleted(bson.M{}), nil
+	}
+	mgoColumns, err := convertToMongoColumns(columns)
+	if err != nil {
+		return nil, errors.New("query params error: " + err.Error())
+	}
+	p := &mgoquery.Params{Columns: mgoColumns}
+	filter, err := p.ConvertToMongoFilter(mgoquery.WithWhitelistNames(r.o.whitelistNames))
+	if err != nil {
+		return nil, fmt.Errorf("query params error: %w", err) // e.g. errors.Is(err, mgoquery.ErrNameNotAllowed)
+	}
+	return mgo.ExcludeDeleted(filter), nil
+}
+
+// convert the columns of the sql query to the columns of the mgo query, the like value with the sql wildcard on
+// one side is converted to startswith or endswith, the grouping logic is not supported.
+func convertToMongoColumns(columns []query.Column) ([]mgoquery.Column, error) {
+	mgoColumns := make([]mgoquery.Column, 0, len(columns))
+	for _, c := range columns {
+		column := mgoquery.Column{Name: c.Name, Exp: c.Exp, Value: c.Value}
+
+		switch strings.ToLower(c.Logic) {
+		case "", query.AND, "&", "&&":
+			column.Logic = mgoquery.AND
+		case query.OR, "|", "||":
+			column.Logic = mgoquery.OR
+		default:
+			return nil, fmt.Errorf("column '%s': the logic '%s' is not supported by mongodb", c.Name, c.Logic)
+		}
+
+		switch strings.ToLower(c.Exp) {
+		case query.Like:
+			val, ok := c.Value.(string)
+			if !ok {
+				return nil, fmt.Errorf("column '%s': invalid value type '%v'", c.Name, c.Value)
+			}
+			prefix := strings.HasPrefix(val, "%")
+			val = strings.TrimPrefix(val, "%")
+			suffix := strings.HasSuffix(val, "%")
+			val = strings.TrimSuffix(val, "%")
+			switch {
+			case prefix == suffix || val == "":
+				column.Value = val
+			case prefix:
+				column.Exp, column.Value = mgoquery.EndsWith, val
+			default:
+				column.Exp, column.Value = mgoquery.StartsWith, val
+			}
+		}
+
+		mgoColumns = append(mgoColumns, column)
+	}
+	return mgoColumns, nil
+}
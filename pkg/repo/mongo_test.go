@@ -0,0 +1,65 @@
+package repo
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongooptions "go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/go-dev-frame/sponge/pkg/mgo"
+)
+
+type mongoUser struct {
+	mgo.Model `bson:",inline"`
+
+	Name string `bson:"name"`
+	Age  int    `bson:"age"`
+}
+
+// the mongodb server is set by the environment variable REPO_TEST_MONGO_URI, the test is skipped if it is unreachable
+func TestMongoRepo(t *testing.T) {
+	uri := os.Getenv("REPO_TEST_MONGO_URI")
+	if uri == "" {
+		uri = "mongodb://127.0.0.1:27017"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, mongooptions.Client().ApplyURI(uri).SetServerSelectionTimeout(time.Second))
+	if err == nil {
+		err = client.Ping(ctx, nil)
+	}
+	if err != nil {
+		t.Skipf("mongodb is unreachable: %v", err)
+	}
+	defer client.Disconnect(context.Background()) //nolint
+
+	// the transactions require the replica set
+	hello := bson.M{}
+	err = client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	require.NoError(t, err)
+	_, isReplicaSet := hello["setName"]
+
+	collection := client.Database("repo_test").Collection("user_" + primitive.NewObjectID().Hex())
+	defer collection.Drop(context.Background()) //nolint
+	if isReplicaSet {
+		// the collection cannot be created implicitly in the transaction before mongodb 4.4
+		require.NoError(t, collection.Database().CreateCollection(ctx, collection.Name()))
+	}
+
+	s := &conformance[mongoUser, primitive.ObjectID]{
+		repo: NewMongoRepo[mongoUser, primitive.ObjectID](collection, WithWhitelistNames(map[string]bool{"name": true, "age": true})),
+		newRecord: func(name string, age int) *mongoUser {
+			return &mongoUser{Name: name, Age: age}
+		},
+		getID:   func(record *mongoUser) primitive.ObjectID { return record.ID },
+		getName: func(record *mongoUser) string { return record.Name },
+		skipTx:  !isReplicaSet,
+	}
+	s.run(t)
+}
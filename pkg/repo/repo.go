@@ -0,0 +1,86 @@
+// Package repo is the generic repository of the records, the handlers depend on the Repo interface only, and the
+// backend, e.g. mysql by gorm or mongodb, is chosen when wiring, the same query parameters are used by all the backends.
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-dev-frame/sponge/pkg/sgorm/query"
+)
+
+// ErrNotFound the record is not found, the error of the backend is wrapped too, e.g. gorm.ErrRecordNotFound
+var ErrNotFound = errors.New("record not found")
+
+func notFound(err error) error {
+	return fmt.Errorf("%w: %w", ErrNotFound, err)
+}
+
+// Repo the repository of the records of type T with the id of type ID
+type Repo[T any, ID any] interface {
+	// Create a record, the id is written back to the record
+	Create(ctx context.Context, record *T) error
+	// GetByID get a record by id, ErrNotFound is returned if it does not exist
+	GetByID(ctx context.Context, id ID) (*T, error)
+	// UpdateByID update the columns of a record by id, the missing record is not an error
+	UpdateByID(ctx context.Context, id ID, update map[string]interface{}) error
+	// DeleteByID delete a record by id, the missing record is not an error
+	DeleteByID(ctx context.Context, id ID) error
+	// List get the records of the page and the total number of the records matching the columns of params
+	List(ctx context.Context, params *query.Params) ([]*T, int64, error)
+	// Count the records matching the conditions, all the records are counted if conditions is nil
+	Count(ctx context.Context, conditions *query.Conditions) (int64, error)
+	// GetByCondition get the first record matching the conditions, ErrNotFound is returned if it does not exist
+	GetByCondition(ctx context.Context, conditions *query.Conditions) (*T, error)
+}
+
+// Option set the repository options.
+type Option func(*options)
+
+type options struct {
+	idName         string
+	whitelistNames map[string]bool
+}
+
+func defaultOptions(idName string) *options {
+	return &options{
+		idName: idName,
+	}
+}
+
+func (o *options) apply(opts ...Option) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithIDName set the column name of the id, default "id" of gorm and "_id" of mongodb
+func WithIDName(name string) Option {
+	return func(o *options) {
+		if name != "" {
+			o.idName = name
+		}
+	}
+}
+
+// WithWhitelistNames set the column names allowed in the query parameters and the conditions, e.g.
+// model.UserExampleColumnNames, default all the column names are allowed
+func WithWhitelistNames(names map[string]bool) Option {
+	return func(o *options) {
+		o.whitelistNames = names
+	}
+}
+
+func checkConditions(conditions *query.Conditions, required bool) error {
+	if conditions == nil || len(conditions.Columns) == 0 {
+		if required {
+			return errors.New("query params error: conditions cannot be empty")
+		}
+		return nil
+	}
+	if err := conditions.CheckValid(); err != nil {
+		return errors.New("query params error: " + err.Error())
+	}
+	return nil
+}
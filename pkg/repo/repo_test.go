@@ -0,0 +1,192 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-dev-frame/sponge/pkg/sgorm/query"
+)
+
+// the conformance tests which all the backends must pass, the records are created by newRecord
+type conformance[T any, ID any] struct {
+	repo interface {
+		Repo[T, ID]
+		WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+	}
+	newRecord func(name string, age int) *T
+	getID     func(record *T) ID
+	getName   func(record *T) string
+	skipTx    bool
+}
+
+func (s *conformance[T, ID]) run(t *testing.T) {
+	ctx := context.Background()
+
+	names := []string{"foo1", "foo2", "bar1", "bar2", "baz"}
+	ids := make([]ID, 0, len(names))
+	for i, name := range names {
+		record := s.newRecord(name, 20+i)
+		require.NoError(t, s.repo.Create(ctx, record))
+		ids = append(ids, s.getID(record))
+	}
+
+	t.Run("GetByID", func(t *testing.T) {
+		record, err := s.repo.GetByID(ctx, ids[0])
+		require.NoError(t, err)
+		assert.Equal(t, "foo1", s.getName(record))
+	})
+
+	t.Run("UpdateByID", func(t *testing.T) {
+		require.NoError(t, s.repo.UpdateByID(ctx, ids[4], map[string]interface{}{"name": "qux"}))
+		require.NoError(t, s.repo.UpdateByID(ctx, ids[4], nil))
+		record, err := s.repo.GetByID(ctx, ids[4])
+		require.NoError(t, err)
+		assert.Equal(t, "qux", s.getName(record))
+	})
+
+	t.Run("List", func(t *testing.T) {
+		records, total, err := s.repo.List(ctx, &query.Params{
+			Page:  0,
+			Limit: 2,
+			Sort:  "-age",
+			Columns: []query.Column{
+				{Name: "name", Exp: query.Like, Value: "foo", Logic: "||"},
+				{Name: "age", Exp: query.Gte, Value: 23},
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), total)
+		require.Len(t, records, 2)
+		assert.Equal(t, "qux", s.getName(records[0]))
+		assert.Equal(t, "bar2", s.getName(records[1]))
+
+		records, _, err = s.repo.List(ctx, &query.Params{
+			Limit:   10,
+			Sort:    "name",
+			Columns: []query.Column{{Name: "name", Exp: query.Like, Value: "ba%"}},
+		})
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+		assert.Equal(t, "bar1", s.getName(records[0]))
+
+		records, total, err = s.repo.List(ctx, &query.Params{Limit: 10, Columns: []query.Column{{Name: "name", Value: "none"}}})
+		assert.NoError(t, err)
+		assert.Zero(t, total)
+		assert.Empty(t, records)
+
+		_, _, err = s.repo.List(ctx, &query.Params{Limit: 10, Columns: []query.Column{{Name: "name", Exp: "unknown", Value: "foo"}}})
+		assert.Error(t, err)
+	})
+
+	t.Run("Count", func(t *testing.T) {
+		total, err := s.repo.Count(ctx, nil)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(names)), total)
+
+		total, err = s.repo.Count(ctx, &query.Conditions{Columns: []query.Column{
+			{Name: "age", Exp: query.Gte, Value: 20},
+			{Name: "age", Exp: query.Lte, Value: 22},
+			{Name: "name", Exp: query.Neq, Value: "foo2"},
+		}})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), total)
+
+		total, err = s.repo.Count(ctx, &query.Conditions{Columns: []query.Column{{Name: "name", Exp: query.In, Value: "foo1,bar1,none"}}})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), total)
+	})
+
+	t.Run("GetByCondition", func(t *testing.T) {
+		record, err := s.repo.GetByCondition(ctx, &query.Conditions{Columns: []query.Column{{Name: "name", Value: "bar1"}}})
+		require.NoError(t, err)
+		assert.Equal(t, "bar1", s.getName(record))
+
+		_, err = s.repo.GetByCondition(ctx, &query.Conditions{Columns: []query.Column{{Name: "name", Value: "none"}}})
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		_, err = s.repo.GetByCondition(ctx, nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("DeleteByID", func(t *testing.T) {
+		require.NoError(t, s.repo.DeleteByID(ctx, ids[3]))
+		_, err := s.repo.GetByID(ctx, ids[3])
+		assert.ErrorIs(t, err, ErrNotFound)
+		total, err := s.repo.Count(ctx, nil)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(names)-1), total)
+	})
+
+	t.Run("WithTx", func(t *testing.T) {
+		if s.skipTx {
+			t.Skip("the transactions are not supported")
+		}
+
+		err := s.repo.WithTx(ctx, func(ctx context.Context) error {
+			if err := s.repo.UpdateByID(ctx, ids[0], map[string]interface{}{"name": "committed"}); err != nil {
+				return err
+			}
+			// nested transaction joins the outer one
+			return s.repo.WithTx(ctx, func(ctx context.Context) error {
+				return s.repo.Create(ctx, s.newRecord("committed", 30))
+			})
+		})
+		require.NoError(t, err)
+		total, err := s.repo.Count(ctx, &query.Conditions{Columns: []query.Column{{Name: "name", Value: "committed"}}})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), total)
+
+		rollbackErr := errors.New("rollback")
+		err = s.repo.WithTx(ctx, func(ctx context.Context) error {
+			if err := s.repo.UpdateByID(ctx, ids[1], map[string]interface{}{"name": "rolled back"}); err != nil {
+				return err
+			}
+			if err := s.repo.Create(ctx, s.newRecord("rolled back", 30)); err != nil {
+				return err
+			}
+			return rollbackErr
+		})
+		assert.ErrorIs(t, err, rollbackErr)
+		record, err := s.repo.GetByID(ctx, ids[1])
+		require.NoError(t, err)
+		assert.Equal(t, "foo2", s.getName(record))
+		total, err = s.repo.Count(ctx, &query.Conditions{Columns: []query.Column{{Name: "name", Value: "rolled back"}}})
+		require.NoError(t, err)
+		assert.Zero(t, total)
+	})
+}
+
+func TestConvertToMongoColumns(t *testing.T) {
+	columns, err := convertToMongoColumns([]query.Column{
+		{Name: "name", Exp: "like", Value: "%foo%", Logic: "||"},
+		{Name: "name", Exp: "like", Value: "foo.%", Logic: "&"},
+		{Name: "name", Exp: "LIKE", Value: "%foo"},
+		{Name: "name", Exp: "like", Value: "%"},
+		{Name: "age", Exp: "gt", Value: 10, Logic: "or"},
+		{Name: "phone", Exp: "isnotnull"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "or", columns[0].Logic)
+	assert.Equal(t, "foo", columns[0].Value)
+	assert.Equal(t, "and", columns[1].Logic)
+	assert.Equal(t, "startswith", columns[1].Exp)
+	assert.Equal(t, "foo.", columns[1].Value)
+	assert.Equal(t, "endswith", columns[2].Exp)
+	assert.Equal(t, "foo", columns[2].Value)
+	assert.Equal(t, "", columns[3].Value)
+	assert.Equal(t, "gt", columns[4].Exp)
+	assert.Equal(t, "or", columns[4].Logic)
+	assert.Equal(t, "isnotnull", columns[5].Exp)
+
+	for _, c := range []query.Column{
+		{Name: "name", Value: "foo", Logic: "or:("},
+		{Name: "name", Exp: "like", Value: 1},
+	} {
+		_, err = convertToMongoColumns([]query.Column{c})
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,237 @@
+// Package balancer is client-side load balancing of the service instances from the registry watcher,
+// supports weighted round robin, least inflight and zone affinity strategies, and ejects the instances
+// failing repeatedly for a cooldown.
+package balancer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-dev-frame/sponge/pkg/servicerd/registry"
+)
+
+// ErrNoInstance there is no instance to pick.
+var ErrNoInstance = errors.New("balancer: no available instance")
+
+const (
+	// MetadataZone the metadata key of the zone of the instance
+	MetadataZone = "zone"
+	// MetadataWeight the metadata key of the weight of the instance
+	MetadataWeight = "weight"
+
+	defaultWeight = 100
+)
+
+// Instance is a service instance with the zone and weight parsed from the metadata.
+type Instance struct {
+	*registry.ServiceInstance
+
+	Key    string  // ID of the instance, or the first endpoint if ID is empty
+	Zone   string  // metadata zone
+	Weight float64 // metadata weight, default 100
+}
+
+// NewInstance parse the zone and weight of the service instance.
+func NewInstance(si *registry.ServiceInstance) *Instance {
+	ins := &Instance{
+		ServiceInstance: si,
+		Key:             si.ID,
+		Weight:          defaultWeight,
+	}
+	if ins.Key == "" && len(si.Endpoints) > 0 {
+		ins.Key = si.Endpoints[0]
+	}
+	if si.Metadata != nil {
+		ins.Zone = si.Metadata[MetadataZone]
+		if v, ok := si.Metadata[MetadataWeight]; ok {
+			if w, err := strconv.ParseFloat(v, 64); err == nil && w >= 0 {
+				ins.Weight = w
+			}
+		}
+	}
+	return ins
+}
+
+// Strategy picks an instance from the available instances.
+type Strategy interface {
+	// Pick returns nil if there is no instance to pick
+	Pick(ctx context.Context, instances []*Instance) *Instance
+}
+
+// Feedback is implemented by the strategies that learn from the outcomes of the picked instances.
+type Feedback interface {
+	Done(ins *Instance, err error)
+}
+
+type instanceStat struct {
+	inflight     int64
+	failures     int
+	ejectedUntil time.Time
+}
+
+// Balancer picks an instance of the service with the strategy.
+type Balancer struct {
+	strategy Strategy
+	opts     *options
+
+	mu        sync.Mutex
+	instances []*Instance
+	stats     map[string]*instanceStat
+}
+
+// New create a balancer.
+func New(strategy Strategy, opts ...Option) *Balancer {
+	o := defaultOptions()
+	o.apply(opts...)
+
+	b := &Balancer{
+		strategy: strategy,
+		opts:     o,
+		stats:    make(map[string]*instanceStat),
+	}
+	if s, ok := strategy.(counterSetter); ok {
+		s.setCounter(b.Inflight)
+	}
+	return b
+}
+
+// Update the instances of the service, the statistics of the removed instances are dropped.
+func (b *Balancer) Update(instances []*registry.ServiceInstance) {
+	list := make([]*Instance, 0, len(instances))
+	keys := make(map[string]struct{}, len(instances))
+	for _, si := range instances {
+		ins := NewInstance(si)
+		if _, ok := keys[ins.Key]; ok {
+			continue
+		}
+		keys[ins.Key] = struct{}{}
+		list = append(list, ins)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.instances = list
+	for key := range b.stats {
+		if _, ok := keys[key]; !ok {
+			delete(b.stats, key)
+		}
+	}
+}
+
+// Watch update the instances from the watcher until ctx is done, it blocks.
+func (b *Balancer) Watch(ctx context.Context, w registry.Watcher) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		instances, err := w.Next()
+		if err != nil {
+			if errors.Is(err, context.Canceled) || ctx.Err() != nil {
+				return
+			}
+			fmt.Printf("[balancer] failed to watch instances: %v\n", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		b.Update(instances)
+	}
+}
+
+// Pick an instance, done must be called with the result of the request, the instances failing
+// repeatedly are ejected for a cooldown.
+func (b *Balancer) Pick(ctx context.Context) (*Instance, func(err error), error) {
+	b.mu.Lock()
+	available := b.available()
+	b.mu.Unlock()
+	if len(available) == 0 {
+		return nil, nil, ErrNoInstance
+	}
+
+	ins := b.strategy.Pick(ctx, available)
+	if ins == nil {
+		return nil, nil, ErrNoInstance
+	}
+
+	b.mu.Lock()
+	b.stat(ins.Key).inflight++
+	b.mu.Unlock()
+
+	var once sync.Once
+	done := func(err error) {
+		once.Do(func() { b.done(ins, err) })
+	}
+	return ins, done, nil
+}
+
+// Inflight returns the number of the requests of the instance that are not done.
+func (b *Balancer) Inflight(ins *Instance) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if s, ok := b.stats[ins.Key]; ok {
+		return s.inflight
+	}
+	return 0
+}
+
+// Instances returns all the instances, including the ejected ones.
+func (b *Balancer) Instances() []*Instance {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]*Instance(nil), b.instances...)
+}
+
+func (b *Balancer) done(ins *Instance, err error) {
+	b.mu.Lock()
+	s := b.stat(ins.Key)
+	s.inflight--
+	if err == nil {
+		s.failures = 0
+	} else if b.opts.maxFailures > 0 {
+		s.failures++
+		if s.failures >= b.opts.maxFailures {
+			s.ejectedUntil = b.opts.now().Add(b.opts.cooldown)
+			s.failures = 0
+		}
+	}
+	b.mu.Unlock()
+
+	if f, ok := b.strategy.(Feedback); ok {
+		f.Done(ins, err)
+	}
+}
+
+// the instances that are not ejected, if all are ejected, all are available, the caller must hold b.mu
+func (b *Balancer) available() []*Instance {
+	now := b.opts.now()
+	list := make([]*Instance, 0, len(b.instances))
+	for _, ins := range b.instances {
+		if s, ok := b.stats[ins.Key]; ok && now.Before(s.ejectedUntil) {
+			continue
+		}
+		list = append(list, ins)
+	}
+	if len(list) == 0 {
+		return append(list, b.instances...)
+	}
+	return list
+}
+
+// the caller must hold b.mu
+func (b *Balancer) stat(key string) *instanceStat {
+	s, ok := b.stats[key]
+	if !ok {
+		s = &instanceStat{}
+		b.stats[key] = s
+	}
+	return s
+}
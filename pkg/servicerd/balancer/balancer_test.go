@@ -0,0 +1,260 @@
+package balancer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-dev-frame/sponge/pkg/servicerd/registry"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func withClock(c *fakeClock) Option {
+	return func(o *options) {
+		o.now = c.Now
+	}
+}
+
+func newInstance(id string, zone string, weight string) *registry.ServiceInstance {
+	md := map[string]string{}
+	if zone != "" {
+		md[MetadataZone] = zone
+	}
+	if weight != "" {
+		md[MetadataWeight] = weight
+	}
+	return registry.NewServiceInstance(id, "user", []string{"grpc://" + id + ":8282"}, registry.WithMetadata(md))
+}
+
+func pickN(t *testing.T, b *Balancer, n int, err error) map[string]int {
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		ins, done, e := b.Pick(context.Background())
+		assert.NoError(t, e)
+		counts[ins.Key]++
+		done(err)
+	}
+	return counts
+}
+
+func TestNewInstance(t *testing.T) {
+	ins := NewInstance(newInstance("1", "a", "20"))
+	assert.Equal(t, "1", ins.Key)
+	assert.Equal(t, "a", ins.Zone)
+	assert.Equal(t, float64(20), ins.Weight)
+
+	ins = NewInstance(&registry.ServiceInstance{Endpoints: []string{"grpc://127.0.0.1:8282"}, Metadata: map[string]string{MetadataWeight: "bad"}})
+	assert.Equal(t, "grpc://127.0.0.1:8282", ins.Key)
+	assert.Equal(t, float64(defaultWeight), ins.Weight)
+}
+
+func TestWeightedRoundRobin(t *testing.T) {
+	b := New(NewWeightedRoundRobin())
+	_, _, err := b.Pick(context.Background())
+	assert.ErrorIs(t, err, ErrNoInstance)
+
+	b.Update([]*registry.ServiceInstance{
+		newInstance("1", "", "5"),
+		newInstance("2", "", "1"),
+		newInstance("3", "", "1"),
+		newInstance("4", "", "0"),
+		newInstance("1", "", "5"), // duplicate
+	})
+	assert.Len(t, b.Instances(), 4)
+
+	// smooth weighted round robin: a a b a c a a
+	var seq []string
+	for i := 0; i < 7; i++ {
+		ins, done, err := b.Pick(context.Background())
+		assert.NoError(t, err)
+		seq = append(seq, ins.Key)
+		done(nil)
+	}
+	assert.Equal(t, []string{"1", "1", "2", "1", "3", "1", "1"}, seq)
+
+	counts := pickN(t, b, 700, nil)
+	assert.Equal(t, map[string]int{"1": 500, "2": 100, "3": 100}, counts)
+
+	// all weights are zero
+	b.Update([]*registry.ServiceInstance{newInstance("4", "", "0")})
+	_, _, err = b.Pick(context.Background())
+	assert.ErrorIs(t, err, ErrNoInstance)
+}
+
+func TestLeastInflight(t *testing.T) {
+	b := New(NewLeastInflight(nil))
+	b.Update([]*registry.ServiceInstance{newInstance("1", "", ""), newInstance("2", "", ""), newInstance("3", "", "")})
+
+	// hold the requests, each instance gets one
+	var dones []func(error)
+	counts := map[string]int{}
+	for i := 0; i < 3; i++ {
+		ins, done, err := b.Pick(context.Background())
+		assert.NoError(t, err)
+		counts[ins.Key]++
+		dones = append(dones, done)
+	}
+	assert.Equal(t, map[string]int{"1": 1, "2": 1, "3": 1}, counts)
+
+	// instance 2 is done, it has the fewest requests in flight
+	ins := b.Instances()[1]
+	dones[1](nil)
+	dones[1](nil) // done is idempotent
+	assert.Equal(t, int64(0), b.Inflight(ins))
+	picked, done, err := b.Pick(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "2", picked.Key)
+	done(nil)
+
+	// custom counter
+	load := map[string]int64{"1": 10, "2": 5, "3": 1}
+	b = New(NewLeastInflight(func(ins *Instance) int64 { return load[ins.Key] }))
+	b.Update([]*registry.ServiceInstance{newInstance("1", "", ""), newInstance("2", "", ""), newInstance("3", "", "")})
+	assert.Equal(t, map[string]int{"3": 10}, pickN(t, b, 10, nil))
+}
+
+func TestZoneAffinity(t *testing.T) {
+	b := New(NewZoneAffinity("a", NewWeightedRoundRobin))
+	b.Update([]*registry.ServiceInstance{
+		newInstance("a1", "a", "1"),
+		newInstance("a2", "a", "1"),
+		newInstance("b1", "b", "1"),
+	})
+	assert.Equal(t, map[string]int{"a1": 50, "a2": 50}, pickN(t, b, 100, nil))
+
+	// the local zone has too few instances, spill over to all zones
+	b = New(NewZoneAffinity("a", NewWeightedRoundRobin, WithMinLocal(3)))
+	b.Update([]*registry.ServiceInstance{
+		newInstance("a1", "a", "1"),
+		newInstance("a2", "a", "1"),
+		newInstance("b1", "b", "1"),
+	})
+	assert.Equal(t, map[string]int{"a1": 10, "a2": 10, "b1": 10}, pickN(t, b, 30, nil))
+
+	// no instance in the local zone
+	b = New(NewZoneAffinity("c", func() Strategy { return NewLeastInflight(nil) }))
+	b.Update([]*registry.ServiceInstance{newInstance("a1", "a", ""), newInstance("b1", "b", "")})
+	assert.Equal(t, map[string]int{"a1": 5, "b1": 5}, pickN(t, b, 10, nil))
+}
+
+func TestOutlierEjection(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	b := New(NewZoneAffinity("a", NewWeightedRoundRobin), WithOutlierDetection(3, 10*time.Second), withClock(clock))
+	b.Update([]*registry.ServiceInstance{
+		newInstance("a1", "a", "1"),
+		newInstance("a2", "a", "1"),
+		newInstance("b1", "b", "1"),
+	})
+
+	// the request to the instance of key gets err, the others succeed
+	pickKey := func(key string, err error) {
+		for {
+			ins, done, e := b.Pick(context.Background())
+			assert.NoError(t, e)
+			if ins.Key == key {
+				done(err)
+				return
+			}
+			done(nil)
+		}
+	}
+	fail := func(key string) {
+		for i := 0; i < 3; i++ {
+			pickKey(key, errors.New("unavailable"))
+		}
+	}
+
+	// a success resets the consecutive failures
+	pickKey("a1", errors.New("unavailable"))
+	pickKey("a1", errors.New("unavailable"))
+	pickKey("a1", nil)
+	pickKey("a1", errors.New("unavailable"))
+	assert.Equal(t, map[string]int{"a1": 5, "a2": 5}, pickN(t, b, 10, nil))
+
+	// a1 is ejected, the requests go to a2
+	fail("a1")
+	assert.Equal(t, map[string]int{"a2": 10}, pickN(t, b, 10, nil))
+
+	// a2 is ejected too, the requests spill over to the other zone
+	fail("a2")
+	assert.Equal(t, map[string]int{"b1": 10}, pickN(t, b, 10, nil))
+
+	// all are ejected, all instances are used
+	fail("b1")
+	assert.Len(t, pickN(t, b, 30, nil), 2)
+
+	// a1 and a2 recover after the cooldown
+	clock.Add(10 * time.Second)
+	assert.Equal(t, map[string]int{"a1": 5, "a2": 5}, pickN(t, b, 10, nil))
+
+	// the removed instances are forgotten
+	b.Update([]*registry.ServiceInstance{newInstance("a1", "a", "1")})
+	b.mu.Lock()
+	assert.Len(t, b.stats, 1)
+	b.mu.Unlock()
+
+	// never ejected
+	b = New(NewWeightedRoundRobin(), WithOutlierDetection(0, 0))
+	b.Update([]*registry.ServiceInstance{newInstance("a1", "a", "1"), newInstance("a2", "a", "1")})
+	assert.Equal(t, map[string]int{"a1": 50, "a2": 50}, pickN(t, b, 100, errors.New("unavailable")))
+}
+
+type fakeWatcher struct {
+	ch chan []*registry.ServiceInstance
+}
+
+func (w *fakeWatcher) Next() ([]*registry.ServiceInstance, error) {
+	instances, ok := <-w.ch
+	if !ok {
+		return nil, context.Canceled
+	}
+	return instances, nil
+}
+
+func (w *fakeWatcher) Stop() error {
+	close(w.ch)
+	return nil
+}
+
+func TestWatch(t *testing.T) {
+	b := New(NewWeightedRoundRobin())
+	w := &fakeWatcher{ch: make(chan []*registry.ServiceInstance)}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	finished := make(chan struct{})
+	go func() {
+		b.Watch(ctx, w)
+		close(finished)
+	}()
+
+	w.ch <- []*registry.ServiceInstance{newInstance("1", "", ""), newInstance("2", "", "")}
+	assert.Eventually(t, func() bool { return len(b.Instances()) == 2 }, time.Second, time.Millisecond)
+
+	_ = w.Stop()
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("watch is not stopped")
+	}
+}
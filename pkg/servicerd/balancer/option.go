@@ -0,0 +1,40 @@
+package balancer
+
+import "time"
+
+// Option set the balancer options.
+type Option func(*options)
+
+type options struct {
+	maxFailures int
+	cooldown    time.Duration
+
+	now func() time.Time // clock, replaced in tests
+}
+
+func (o *options) apply(opts ...Option) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+func defaultOptions() *options {
+	return &options{
+		maxFailures: 5,
+		cooldown:    30 * time.Second,
+		now:         time.Now,
+	}
+}
+
+// WithOutlierDetection set the number of consecutive failures to eject an instance and the cooldown
+// of the ejection, default 5 and 30s, maxFailures 0 means the instances are never ejected.
+func WithOutlierDetection(maxFailures int, cooldown time.Duration) Option {
+	return func(o *options) {
+		if maxFailures >= 0 {
+			o.maxFailures = maxFailures
+		}
+		if cooldown > 0 {
+			o.cooldown = cooldown
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package balancer
+
+import (
+	"context"
+	"sync"
+)
+
+// the strategies using the counter of the balancer
+type counterSetter interface {
+	setCounter(fn func(*Instance) int64)
+}
+
+// ------------------------------------------------------------------------------------------
+
+type weightedRoundRobin struct {
+	mu      sync.Mutex
+	current map[string]float64
+}
+
+// NewWeightedRoundRobin smooth weighted round robin, the instances are picked in proportion to their
+// weights and interleaved, the instances with zero weight are never picked.
+func NewWeightedRoundRobin() Strategy {
+	return &weightedRoundRobin{current: make(map[string]float64)}
+}
+
+// Pick an instance
+func (s *weightedRoundRobin) Pick(_ context.Context, instances []*Instance) *Instance {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var best *Instance
+	total := 0.0
+	keys := make(map[string]struct{}, len(instances))
+	for _, ins := range instances {
+		keys[ins.Key] = struct{}{}
+		if ins.Weight <= 0 {
+			continue
+		}
+		total += ins.Weight
+		s.current[ins.Key] += ins.Weight
+		if best == nil || s.current[ins.Key] > s.current[best.Key] {
+			best = ins
+		}
+	}
+	// drop the removed instances
+	for key := range s.current {
+		if _, ok := keys[key]; !ok {
+			delete(s.current, key)
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	s.current[best.Key] -= total
+	return best
+}
+
+// ------------------------------------------------------------------------------------------
+
+type leastInflight struct {
+	mu      sync.Mutex
+	counter func(ins *Instance) int64
+	next    int
+}
+
+// NewLeastInflight pick the instance with the fewest requests in flight, the ties are broken by round robin,
+// counter returns the number of the requests in flight of the instance, if counter is nil, the counter of the
+// balancer is used.
+func NewLeastInflight(counter func(ins *Instance) int64) Strategy {
+	return &leastInflight{counter: counter}
+}
+
+func (s *leastInflight) setCounter(fn func(*Instance) int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.counter == nil {
+		s.counter = fn
+	}
+}
+
+// Pick an instance
+func (s *leastInflight) Pick(_ context.Context, instances []*Instance) *Instance {
+	if len(instances) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	counter := s.counter
+	start := s.next % len(instances)
+	s.next++
+	s.mu.Unlock()
+	if counter == nil {
+		return instances[start]
+	}
+
+	var best *Instance
+	var least int64
+	for i := 0; i < len(instances); i++ {
+		ins := instances[(start+i)%len(instances)]
+		n := counter(ins)
+		if best == nil || n < least {
+			best, least = ins, n
+		}
+	}
+	return best
+}
+
+// ------------------------------------------------------------------------------------------
+
+// ZoneOption set the zone affinity options.
+type ZoneOption func(*zoneAffinity)
+
+// WithMinLocal set the minimum number of the available instances in the local zone, if there are fewer,
+// the requests spill over to all zones, default 1.
+func WithMinLocal(n int) ZoneOption {
+	return func(s *zoneAffinity) {
+		if n > 0 {
+			s.minLocal = n
+		}
+	}
+}
+
+type zoneAffinity struct {
+	zone     string
+	minLocal int
+	local    Strategy
+	all      Strategy
+}
+
+// NewZoneAffinity prefer the instances in the zone, the instances are picked by the strategy created by
+// newStrategy, the requests spill over to the other zones when the local zone has too few available instances.
+func NewZoneAffinity(zone string, newStrategy func() Strategy, opts ...ZoneOption) Strategy {
+	s := &zoneAffinity{
+		zone:     zone,
+		minLocal: 1,
+		local:    newStrategy(),
+		all:      newStrategy(),
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	return s
+}
+
+func (s *zoneAffinity) setCounter(fn func(*Instance) int64) {
+	for _, st := range []Strategy{s.local, s.all} {
+		if c, ok := st.(counterSetter); ok {
+			c.setCounter(fn)
+		}
+	}
+}
+
+// Pick an instance
+func (s *zoneAffinity) Pick(ctx context.Context, instances []*Instance) *Instance {
+	local := make([]*Instance, 0, len(instances))
+	for _, ins := range instances {
+		if ins.Zone == s.zone {
+			local = append(local, ins)
+		}
+	}
+	if len(local) >= s.minLocal {
+		if ins := s.local.Pick(ctx, local); ins != nil {
+			return ins
+		}
+	}
+	return s.all.Pick(ctx, instances)
+}
+
+// Done pass the outcome to the strategies
+func (s *zoneAffinity) Done(ins *Instance, err error) {
+	for _, st := range []Strategy{s.local, s.all} {
+		if f, ok := st.(Feedback); ok {
+			f.Done(ins, err)
+		}
+	}
+}
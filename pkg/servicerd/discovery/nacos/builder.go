@@ -0,0 +1,151 @@
+// Package nacos is the grpc resolver of nacos, the target is nacos:///serviceName?group=dev&clusters=a,b,
+// the addresses of healthy instances are pushed to the grpc client connection when the service changes.
+package nacos
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/common/constant"
+	"github.com/nacos-group/nacos-sdk-go/v2/model"
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/resolver"
+
+	"github.com/go-dev-frame/sponge/pkg/nacoscli"
+)
+
+// Scheme of the nacos resolver
+const Scheme = "nacos"
+
+// Subscriber is the part of the nacos naming client used by the resolver.
+type Subscriber interface {
+	Subscribe(param *vo.SubscribeParam) error
+	Unsubscribe(param *vo.SubscribeParam) error
+	SelectInstances(param vo.SelectInstancesParam) ([]model.Instance, error)
+}
+
+// Option set the builder options.
+type Option func(*builder)
+
+// WithGroup set the default group, it is overridden by the group of the target, default DEFAULT_GROUP
+func WithGroup(group string) Option {
+	return func(b *builder) {
+		b.group = group
+	}
+}
+
+// WithBackoff set the initial and maximum interval of re-subscribing after failures, default 1s and 30s
+func WithBackoff(interval time.Duration, maxInterval time.Duration) Option {
+	return func(b *builder) {
+		if interval > 0 {
+			b.interval = interval
+		}
+		if maxInterval >= b.interval {
+			b.maxInterval = maxInterval
+		}
+	}
+}
+
+// DisableDebugLog disables update instances log.
+func DisableDebugLog() Option {
+	return func(b *builder) {
+		b.debugLogDisabled = true
+	}
+}
+
+type builder struct {
+	cli              Subscriber
+	group            string
+	interval         time.Duration
+	maxInterval      time.Duration
+	debugLogDisabled bool
+}
+
+// NewBuilder creates a resolver builder of the nacos scheme, cli is usually the nacos naming client.
+func NewBuilder(cli Subscriber, opts ...Option) resolver.Builder {
+	b := &builder{
+		cli:         cli,
+		group:       constant.DEFAULT_GROUP,
+		interval:    time.Second,
+		maxInterval: 30 * time.Second,
+	}
+	for _, o := range opts {
+		o(b)
+	}
+	return b
+}
+
+// NewBuilderWithParams creates a resolver builder with the nacos naming client created from params,
+// opts can be set by nacoscli.WithXXX and nacos.WithXXX functions.
+func NewBuilderWithParams(params *nacoscli.Params, opts ...interface{}) (resolver.Builder, error) {
+	var nacosOptions []nacoscli.Option
+	var builderOptions []Option
+	for _, opt := range opts {
+		switch v := opt.(type) {
+		case nacoscli.Option:
+			nacosOptions = append(nacosOptions, v)
+		case Option:
+			builderOptions = append(builderOptions, v)
+		default:
+			return nil, fmt.Errorf("unknown option type: %T", v)
+		}
+	}
+
+	cli, err := nacoscli.NewNamingClientWithParams(params, nacosOptions...)
+	if err != nil {
+		return nil, err
+	}
+	if params.Group != "" {
+		builderOptions = append([]Option{WithGroup(params.Group)}, builderOptions...)
+	}
+	return NewBuilder(cli, builderOptions...), nil
+}
+
+// Dial creates a grpc client connection to the service of nacos, serviceName is the name registered in nacos,
+// e.g. user.grpc, the resolver is only used by this connection.
+func Dial(b resolver.Builder, serviceName string, dialOptions ...grpc.DialOption) (*grpc.ClientConn, error) {
+	dialOptions = append(dialOptions, grpc.WithResolvers(b))
+	return grpc.NewClient(Target(serviceName, ""), dialOptions...)
+}
+
+// Target returns the target of the service, e.g. nacos:///user.grpc?group=dev
+func Target(serviceName string, group string) string {
+	target := Scheme + ":///" + serviceName
+	if group != "" {
+		target += "?group=" + group
+	}
+	return target
+}
+
+// Register the builder globally, then the target nacos:///serviceName can be used by all grpc client connections.
+func Register(b resolver.Builder) {
+	resolver.Register(b)
+}
+
+func (b *builder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	serviceName := strings.TrimPrefix(target.URL.Path, "/")
+	if serviceName == "" {
+		return nil, fmt.Errorf("nacos resolver: service name is empty, target = %s", target.URL.String())
+	}
+
+	query := target.URL.Query()
+	group := query.Get("group")
+	if group == "" {
+		group = b.group
+	}
+	var clusters []string
+	if v := query.Get("clusters"); v != "" {
+		clusters = strings.Split(v, ",")
+	}
+
+	r := newResolver(b, cc, serviceName, group, clusters)
+	r.start()
+	return r, nil
+}
+
+// Scheme return scheme of nacos
+func (*builder) Scheme() string {
+	return Scheme
+}
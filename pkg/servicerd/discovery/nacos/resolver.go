@@ -0,0 +1,221 @@
+package nacos
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/model"
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+	"google.golang.org/grpc/attributes"
+	"google.golang.org/grpc/resolver"
+)
+
+type weightKey struct{}
+
+// Weight returns the nacos weight of the address resolved by the nacos resolver, it is stored in the
+// balancer attributes, so that the weighted balancers can use it, returns 0 if not set.
+func Weight(addr resolver.Address) float64 {
+	w, _ := addr.BalancerAttributes.Value(weightKey{}).(float64)
+	return w
+}
+
+type nacosResolver struct {
+	b           *builder
+	cc          resolver.ClientConn
+	serviceName string
+	group       string
+	clusters    map[string]struct{}
+	param       *vo.SubscribeParam
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu         sync.Mutex // serializes the updates of cc
+	subscribed bool
+	closed     bool
+}
+
+func newResolver(b *builder, cc resolver.ClientConn, serviceName string, group string, clusters []string) *nacosResolver {
+	r := &nacosResolver{
+		b:           b,
+		cc:          cc,
+		serviceName: serviceName,
+		group:       group,
+	}
+	if len(clusters) > 0 {
+		r.clusters = make(map[string]struct{}, len(clusters))
+		for _, c := range clusters {
+			r.clusters[c] = struct{}{}
+		}
+	}
+	// the clusters are filtered by the resolver, if the clusters are subscribed, the changes are not pushed
+	r.param = &vo.SubscribeParam{
+		ServiceName:       serviceName,
+		GroupName:         group,
+		SubscribeCallback: r.onChange,
+	}
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+	return r
+}
+
+func (r *nacosResolver) start() {
+	if err := r.subscribe(); err != nil {
+		fmt.Printf("[nacos resolver] failed to subscribe service %s: %v\n", r.serviceName, err)
+		r.cc.ReportError(err)
+		go r.resubscribe()
+	}
+}
+
+func (r *nacosResolver) subscribe() error {
+	if err := r.b.cli.Subscribe(r.param); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	if r.closed { // closed while subscribing
+		r.mu.Unlock()
+		_ = r.b.cli.Unsubscribe(r.param)
+		return nil
+	}
+	r.subscribed = true
+	r.mu.Unlock()
+
+	// the current instances, the subscription only pushes the changes
+	instances, err := r.b.cli.SelectInstances(vo.SelectInstancesParam{
+		ServiceName: r.serviceName,
+		GroupName:   r.group,
+		HealthyOnly: true,
+	})
+	if err != nil {
+		fmt.Printf("[nacos resolver] failed to select instances of service %s: %v\n", r.serviceName, err)
+		return nil
+	}
+	r.update(instances)
+	return nil
+}
+
+// resubscribe with exponential backoff until success or closed
+func (r *nacosResolver) resubscribe() {
+	interval := r.b.interval
+	for {
+		timer := time.NewTimer(interval)
+		select {
+		case <-r.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		err := r.subscribe()
+		if err == nil {
+			return
+		}
+		fmt.Printf("[nacos resolver] failed to resubscribe service %s: %v\n", r.serviceName, err)
+		r.cc.ReportError(err)
+
+		interval *= 2
+		if interval > r.b.maxInterval {
+			interval = r.b.maxInterval
+		}
+	}
+}
+
+func (r *nacosResolver) onChange(instances []model.Instance, err error) {
+	if err != nil {
+		fmt.Printf("[nacos resolver] subscribe callback error of service %s: %v\n", r.serviceName, err)
+		return
+	}
+	r.update(instances)
+}
+
+func (r *nacosResolver) update(instances []model.Instance) {
+	addrs := make([]resolver.Address, 0, len(instances))
+	endpoints := make(map[string]struct{}, len(instances))
+	for _, in := range instances {
+		if !in.Healthy || !in.Enable || in.Weight <= 0 {
+			continue
+		}
+		if r.clusters != nil {
+			if _, ok := r.clusters[in.ClusterName]; !ok {
+				continue
+			}
+		}
+		endpoint := fmt.Sprintf("%s:%d", in.Ip, in.Port)
+		// filter redundant endpoints
+		if _, ok := endpoints[endpoint]; ok {
+			continue
+		}
+		endpoints[endpoint] = struct{}{}
+		addrs = append(addrs, resolver.Address{
+			Addr:               endpoint,
+			ServerName:         r.serviceName,
+			Attributes:         parseAttributes(in.Metadata),
+			BalancerAttributes: attributes.New(weightKey{}, in.Weight),
+		})
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i].Addr < addrs[j].Addr })
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return
+	}
+
+	if len(addrs) == 0 {
+		// keep the last addresses, the balancer keeps using them
+		r.cc.ReportError(fmt.Errorf("nacos resolver: no healthy instance of service %s", r.serviceName))
+		return
+	}
+	if err := r.cc.UpdateState(resolver.State{Addresses: addrs}); err != nil {
+		fmt.Printf("[nacos resolver] failed to update state: %v\n", err)
+	}
+
+	if !r.b.debugLogDisabled {
+		fmt.Printf("[nacos resolver] update instances of service %s: %v\n", r.serviceName, endpointList(addrs))
+	}
+}
+
+// ResolveNow the changes are pushed by the subscription
+func (r *nacosResolver) ResolveNow(_ resolver.ResolveNowOptions) {}
+
+// Close unsubscribe the service, it is idempotent
+func (r *nacosResolver) Close() {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return
+	}
+	r.closed = true
+	subscribed := r.subscribed
+	r.mu.Unlock()
+
+	r.cancel()
+	if subscribed {
+		if err := r.b.cli.Unsubscribe(r.param); err != nil {
+			fmt.Printf("[nacos resolver] failed to unsubscribe service %s: %v\n", r.serviceName, err)
+		}
+	}
+}
+
+func parseAttributes(md map[string]string) *attributes.Attributes {
+	var a *attributes.Attributes
+	for k, v := range md {
+		if a == nil {
+			a = attributes.New(k, v)
+		} else {
+			a = a.WithValue(k, v)
+		}
+	}
+	return a
+}
+
+func endpointList(addrs []resolver.Address) []string {
+	list := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		list = append(list, addr.Addr)
+	}
+	return list
+}
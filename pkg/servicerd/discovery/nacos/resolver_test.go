@@ -0,0 +1,221 @@
+package nacos
+
+import (
+	"errors"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/model"
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/resolver"
+
+	"github.com/go-dev-frame/sponge/pkg/nacoscli"
+)
+
+type stubSubscriber struct {
+	mu           sync.Mutex
+	instances    []model.Instance
+	subscribeErr int // the number of failed subscriptions
+	subscribes   int
+	params       []*vo.SubscribeParam
+	unsubscribed []*vo.SubscribeParam
+}
+
+func (s *stubSubscriber) Subscribe(param *vo.SubscribeParam) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscribes++
+	if s.subscribeErr > 0 {
+		s.subscribeErr--
+		return errors.New("nacos server unavailable")
+	}
+	s.params = append(s.params, param)
+	return nil
+}
+
+func (s *stubSubscriber) Unsubscribe(param *vo.SubscribeParam) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unsubscribed = append(s.unsubscribed, param)
+	return nil
+}
+
+func (s *stubSubscriber) SelectInstances(param vo.SelectInstancesParam) ([]model.Instance, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.instances, nil
+}
+
+// push the instances to the subscribers like the naming client
+func (s *stubSubscriber) push(instances []model.Instance) {
+	s.mu.Lock()
+	params := s.params
+	s.mu.Unlock()
+	for _, p := range params {
+		p.SubscribeCallback(instances, nil)
+	}
+}
+
+func (s *stubSubscriber) getSubscribes() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.subscribes
+}
+
+type fakeClientConn struct {
+	resolver.ClientConn
+
+	mu     sync.Mutex
+	states []resolver.State
+	errs   []error
+}
+
+func (c *fakeClientConn) UpdateState(state resolver.State) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.states = append(c.states, state)
+	return nil
+}
+
+func (c *fakeClientConn) ReportError(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.errs = append(c.errs, err)
+}
+
+func (c *fakeClientConn) lastAddrs() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.states) == 0 {
+		return nil
+	}
+	return endpointList(c.states[len(c.states)-1].Addresses)
+}
+
+func instance(ip string, weight float64, healthy bool) model.Instance {
+	return model.Instance{
+		Ip:          ip,
+		Port:        8282,
+		Weight:      weight,
+		Healthy:     healthy,
+		Enable:      true,
+		ClusterName: "DEFAULT",
+		Metadata:    map[string]string{"zone": "a"},
+	}
+}
+
+func buildResolver(t *testing.T, cli Subscriber, target string, opts ...Option) (resolver.Resolver, *fakeClientConn) {
+	u, err := url.Parse(target)
+	assert.NoError(t, err)
+	cc := &fakeClientConn{}
+	b := NewBuilder(cli, append(opts, DisableDebugLog())...)
+	assert.Equal(t, Scheme, b.Scheme())
+	r, err := b.Build(resolver.Target{URL: *u}, cc, resolver.BuildOptions{})
+	assert.NoError(t, err)
+	return r, cc
+}
+
+func TestResolver(t *testing.T) {
+	cli := &stubSubscriber{instances: []model.Instance{instance("127.0.0.2", 1, true), instance("127.0.0.1", 100, true)}}
+	r, cc := buildResolver(t, cli, "nacos:///user.grpc?group=dev")
+	defer r.Close()
+
+	// the current instances
+	assert.Equal(t, []string{"127.0.0.1:8282", "127.0.0.2:8282"}, cc.lastAddrs())
+	assert.Equal(t, "user.grpc", cli.params[0].ServiceName)
+	assert.Equal(t, "dev", cli.params[0].GroupName)
+
+	addr := cc.states[0].Addresses[0]
+	assert.Equal(t, float64(100), Weight(addr))
+	assert.Equal(t, "a", addr.Attributes.Value("zone"))
+	assert.Equal(t, "user.grpc", addr.ServerName)
+
+	// address updates are propagated, unhealthy, disabled and zero weight instances are filtered
+	disabled := instance("127.0.0.5", 1, true)
+	disabled.Enable = false
+	cli.push([]model.Instance{
+		instance("127.0.0.1", 100, true),
+		instance("127.0.0.1", 100, true),
+		instance("127.0.0.3", 50, true),
+		instance("127.0.0.4", 100, false),
+		instance("127.0.0.6", 0, true),
+		disabled,
+	})
+	assert.Equal(t, []string{"127.0.0.1:8282", "127.0.0.3:8282"}, cc.lastAddrs())
+
+	// no healthy instance, the last addresses are kept
+	cli.push([]model.Instance{instance("127.0.0.1", 100, false)})
+	assert.Equal(t, []string{"127.0.0.1:8282", "127.0.0.3:8282"}, cc.lastAddrs())
+	assert.Len(t, cc.errs, 1)
+
+	r.ResolveNow(resolver.ResolveNowOptions{})
+}
+
+func TestResolverClusters(t *testing.T) {
+	other := instance("127.0.0.2", 1, true)
+	other.ClusterName = "other"
+	cli := &stubSubscriber{instances: []model.Instance{instance("127.0.0.1", 1, true), other}}
+	r, cc := buildResolver(t, cli, "nacos:///user.grpc?clusters=DEFAULT", WithGroup("prod"))
+	defer r.Close()
+
+	assert.Equal(t, []string{"127.0.0.1:8282"}, cc.lastAddrs())
+	assert.Equal(t, "prod", cli.params[0].GroupName)
+}
+
+func TestResolverResubscribe(t *testing.T) {
+	cli := &stubSubscriber{subscribeErr: 2, instances: []model.Instance{instance("127.0.0.1", 1, true)}}
+	r, cc := buildResolver(t, cli, "nacos:///user.grpc", WithBackoff(time.Millisecond, 5*time.Millisecond))
+	defer r.Close()
+
+	assert.Eventually(t, func() bool { return len(cc.lastAddrs()) == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, 3, cli.getSubscribes())
+	assert.Len(t, cc.errs, 2)
+}
+
+func TestResolverClose(t *testing.T) {
+	cli := &stubSubscriber{}
+	r, cc := buildResolver(t, cli, "nacos:///user.grpc")
+	r.Close()
+	r.Close()
+	assert.Len(t, cli.unsubscribed, 1)
+	assert.Same(t, cli.params[0], cli.unsubscribed[0])
+
+	// no updates after closing
+	cli.push([]model.Instance{instance("127.0.0.1", 1, true)})
+	assert.Empty(t, cc.states)
+
+	// closed before subscribing successfully
+	cli = &stubSubscriber{subscribeErr: 100}
+	r, _ = buildResolver(t, cli, "nacos:///user.grpc", WithBackoff(time.Millisecond, time.Millisecond))
+	time.Sleep(10 * time.Millisecond)
+	r.Close()
+	n := cli.getSubscribes()
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, n, cli.getSubscribes())
+	assert.Empty(t, cli.unsubscribed)
+}
+
+func TestBuildError(t *testing.T) {
+	u, _ := url.Parse("nacos:///")
+	_, err := NewBuilder(&stubSubscriber{}).Build(resolver.Target{URL: *u}, &fakeClientConn{}, resolver.BuildOptions{})
+	assert.Error(t, err)
+}
+
+func TestDial(t *testing.T) {
+	assert.Equal(t, "nacos:///user.grpc?group=dev", Target("user.grpc", "dev"))
+	assert.Equal(t, "nacos:///user.grpc", Target("user.grpc", ""))
+
+	b := NewBuilder(&stubSubscriber{instances: []model.Instance{instance("127.0.0.1", 1, true)}}, DisableDebugLog())
+	conn, err := Dial(b, "user.grpc", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	assert.Equal(t, "nacos:///user.grpc", conn.Target())
+	_ = conn.Close()
+
+	_, err = NewBuilderWithParams(&nacoscli.Params{IPAddr: "127.0.0.1", Port: 8848}, "unknown option")
+	assert.Error(t, err)
+}
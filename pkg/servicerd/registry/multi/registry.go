@@ -0,0 +1,279 @@
+// Package multi is a composite registry that registers the service instance to multiple registries,
+// e.g. both consul and nacos during a migration, and merges the instances watched from them.
+package multi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-dev-frame/sponge/pkg/servicerd/registry"
+)
+
+var (
+	_ registry.Registry  = (*Registry)(nil)
+	_ registry.Discovery = (*Registry)(nil)
+)
+
+// Policy is the error policy of a child registry.
+type Policy int
+
+const (
+	// BestEffort the failure of the child is aggregated into MultiError, the other children continue
+	BestEffort Policy = iota
+	// FailFast the failure of the child aborts the registration, the registered children are rolled back
+	FailFast
+)
+
+// ChildError is the error of a child registry.
+type ChildError struct {
+	Name string
+	Op   string
+	Err  error
+}
+
+// Error returns the error message
+func (e *ChildError) Error() string {
+	return fmt.Sprintf("%s %s: %v", e.Op, e.Name, e.Err)
+}
+
+// Unwrap returns the error of the child
+func (e *ChildError) Unwrap() error {
+	return e.Err
+}
+
+// MultiError is the aggregated errors of the child registries.
+type MultiError struct {
+	Errors []*ChildError
+}
+
+// Error returns the error message
+func (e *MultiError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+	return "multi registry: " + strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the errors of the children
+func (e *MultiError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// ChildHealth is the health of a child registry, it is the result of the last operation.
+type ChildHealth struct {
+	Name      string
+	Healthy   bool
+	LastOp    string // register, deregister or watch, empty if there is no operation
+	LastErr   error
+	UpdatedAt time.Time
+}
+
+// ChildOption set the child registry options.
+type ChildOption func(*child)
+
+// WithPolicy set the error policy of the child, default BestEffort.
+func WithPolicy(policy Policy) ChildOption {
+	return func(c *child) {
+		c.policy = policy
+	}
+}
+
+// WithDiscovery set the discovery of the child, default the child registry if it implements registry.Discovery.
+func WithDiscovery(discovery registry.Discovery) ChildOption {
+	return func(c *child) {
+		c.discovery = discovery
+	}
+}
+
+type child struct {
+	name      string
+	registry  registry.Registry
+	discovery registry.Discovery
+	policy    Policy
+	health    ChildHealth
+}
+
+// Option set the registry options.
+type Option func(*Registry)
+
+// WithChild add a child registry, the children are registered in the order they are added,
+// and deregistered in the reverse order.
+func WithChild(name string, r registry.Registry, opts ...ChildOption) Option {
+	return func(m *Registry) {
+		c := &child{
+			name:     name,
+			registry: r,
+			policy:   BestEffort,
+			health:   ChildHealth{Name: name, Healthy: true},
+		}
+		if d, ok := r.(registry.Discovery); ok {
+			c.discovery = d
+		}
+		for _, o := range opts {
+			o(c)
+		}
+		m.children = append(m.children, c)
+	}
+}
+
+// Registry is the composite registry.
+type Registry struct {
+	children []*child
+
+	mu sync.Mutex // protects the health of the children
+}
+
+// New create a composite registry.
+func New(opts ...Option) *Registry {
+	r := &Registry{}
+	for _, o := range opts {
+		o(r)
+	}
+	return r
+}
+
+// Register the service instance to all children in order, if a fail-fast child fails, the children
+// registered are deregistered and the error is returned, the failures of the best-effort children are
+// returned as *MultiError after all children are registered.
+func (r *Registry) Register(ctx context.Context, service *registry.ServiceInstance) error {
+	var errs []*ChildError
+	var registered []*child
+	for _, c := range r.children {
+		err := c.registry.Register(ctx, service)
+		r.report(c, "register", err)
+		if err == nil {
+			registered = append(registered, c)
+			continue
+		}
+		ce := &ChildError{Name: c.name, Op: "register", Err: err}
+		if c.policy == FailFast {
+			r.rollback(ctx, service, registered)
+			return ce
+		}
+		errs = append(errs, ce)
+	}
+	if len(errs) > 0 {
+		return &MultiError{Errors: errs}
+	}
+	return nil
+}
+
+// deregister the registered children in the reverse order
+func (r *Registry) rollback(ctx context.Context, service *registry.ServiceInstance, registered []*child) {
+	for i := len(registered) - 1; i >= 0; i-- {
+		c := registered[i]
+		err := c.registry.Deregister(ctx, service)
+		r.report(c, "deregister", err)
+	}
+}
+
+// Deregister the service instance from all children in the reverse order, whatever their policies are,
+// so that the instance is not left in any registry, the failures are returned as *MultiError.
+func (r *Registry) Deregister(ctx context.Context, service *registry.ServiceInstance) error {
+	var errs []*ChildError
+	for i := len(r.children) - 1; i >= 0; i-- {
+		c := r.children[i]
+		err := c.registry.Deregister(ctx, service)
+		r.report(c, "deregister", err)
+		if err != nil {
+			errs = append(errs, &ChildError{Name: c.name, Op: "deregister", Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return &MultiError{Errors: errs}
+	}
+	return nil
+}
+
+// GetService returns the instances of all children, de-duplicated by the address, if a fail-fast child
+// fails, the error is returned, the failures of the best-effort children are ignored unless all fail.
+func (r *Registry) GetService(ctx context.Context, serviceName string) ([]*registry.ServiceInstance, error) {
+	var lists [][]*registry.ServiceInstance
+	var errs []*ChildError
+	for _, c := range r.children {
+		if c.discovery == nil {
+			continue
+		}
+		instances, err := c.discovery.GetService(ctx, serviceName)
+		if err != nil {
+			ce := &ChildError{Name: c.name, Op: "get service", Err: err}
+			if c.policy == FailFast {
+				return nil, ce
+			}
+			errs = append(errs, ce)
+			continue
+		}
+		lists = append(lists, instances)
+	}
+	if len(lists) == 0 && len(errs) > 0 {
+		return nil, &MultiError{Errors: errs}
+	}
+	return merge(lists), nil
+}
+
+// Watch the service in all children, the instances are merged and de-duplicated by the address,
+// if a best-effort child fails to watch, it is skipped.
+func (r *Registry) Watch(ctx context.Context, serviceName string) (registry.Watcher, error) {
+	var watchers []registry.Watcher
+	var children []*child
+	var errs []*ChildError
+	for _, c := range r.children {
+		if c.discovery == nil {
+			continue
+		}
+		w, err := c.discovery.Watch(ctx, serviceName)
+		r.report(c, "watch", err)
+		if err != nil {
+			ce := &ChildError{Name: c.name, Op: "watch", Err: err}
+			if c.policy == FailFast {
+				for _, w := range watchers {
+					_ = w.Stop()
+				}
+				return nil, ce
+			}
+			errs = append(errs, ce)
+			continue
+		}
+		watchers = append(watchers, w)
+		children = append(children, c)
+	}
+	if len(watchers) == 0 {
+		if len(errs) > 0 {
+			return nil, &MultiError{Errors: errs}
+		}
+		return nil, errors.New("multi registry: no child supports discovery")
+	}
+	return newWatcher(ctx, r, children, watchers), nil
+}
+
+// Health returns the health of the children in order.
+func (r *Registry) Health() []ChildHealth {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	list := make([]ChildHealth, 0, len(r.children))
+	for _, c := range r.children {
+		list = append(list, c.health)
+	}
+	return list
+}
+
+func (r *Registry) report(c *child, op string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c.health = ChildHealth{
+		Name:      c.name,
+		Healthy:   err == nil,
+		LastOp:    op,
+		LastErr:   err,
+		UpdatedAt: time.Now(),
+	}
+}
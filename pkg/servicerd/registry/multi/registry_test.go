@@ -0,0 +1,259 @@
+package multi
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-dev-frame/sponge/pkg/servicerd/registry"
+)
+
+type fakeChild struct {
+	name string
+	ops  *[]string // the operations of all children in order
+
+	mu            sync.Mutex
+	registerErr   error
+	deregisterErr error
+	watchErr      error
+	instances     []*registry.ServiceInstance
+	watchers      []*fakeWatcher
+}
+
+func (c *fakeChild) Register(_ context.Context, _ *registry.ServiceInstance) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	*c.ops = append(*c.ops, "register "+c.name)
+	return c.registerErr
+}
+
+func (c *fakeChild) Deregister(_ context.Context, _ *registry.ServiceInstance) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	*c.ops = append(*c.ops, "deregister "+c.name)
+	return c.deregisterErr
+}
+
+func (c *fakeChild) GetService(_ context.Context, _ string) ([]*registry.ServiceInstance, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.instances, c.watchErr
+}
+
+func (c *fakeChild) Watch(_ context.Context, _ string) (registry.Watcher, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.watchErr != nil {
+		return nil, c.watchErr
+	}
+	w := &fakeWatcher{ch: make(chan []*registry.ServiceInstance, 10), stopped: make(chan struct{})}
+	c.watchers = append(c.watchers, w)
+	return w, nil
+}
+
+type fakeWatcher struct {
+	ch      chan []*registry.ServiceInstance
+	stopped chan struct{}
+	once    sync.Once
+}
+
+func (w *fakeWatcher) Next() ([]*registry.ServiceInstance, error) {
+	select {
+	case instances := <-w.ch:
+		return instances, nil
+	case <-w.stopped:
+		return nil, context.Canceled
+	}
+}
+
+func (w *fakeWatcher) Stop() error {
+	w.once.Do(func() { close(w.stopped) })
+	return nil
+}
+
+func newChildren(names ...string) ([]*fakeChild, *[]string) {
+	ops := &[]string{}
+	var children []*fakeChild
+	for _, name := range names {
+		children = append(children, &fakeChild{name: name, ops: ops})
+	}
+	return children, ops
+}
+
+func instance(id string, endpoint string) *registry.ServiceInstance {
+	return registry.NewServiceInstance(id, "user", []string{endpoint})
+}
+
+func TestRegistry_Register(t *testing.T) {
+	children, ops := newChildren("consul", "nacos")
+	r := New(WithChild("consul", children[0]), WithChild("nacos", children[1]))
+	svc := instance("1", "grpc://127.0.0.1:8282")
+
+	assert.NoError(t, r.Register(context.Background(), svc))
+	assert.NoError(t, r.Deregister(context.Background(), svc))
+	// deregistered in the reverse order
+	assert.Equal(t, []string{"register consul", "register nacos", "deregister nacos", "deregister consul"}, *ops)
+	for _, h := range r.Health() {
+		assert.True(t, h.Healthy)
+		assert.Equal(t, "deregister", h.LastOp)
+	}
+}
+
+func TestRegistry_BestEffort(t *testing.T) {
+	children, ops := newChildren("consul", "nacos", "etcd")
+	children[1].registerErr = errors.New("nacos unavailable")
+	children[1].deregisterErr = errors.New("nacos unavailable")
+	r := New(WithChild("consul", children[0]), WithChild("nacos", children[1]), WithChild("etcd", children[2]))
+	svc := instance("1", "grpc://127.0.0.1:8282")
+
+	// partial failure, the other children are registered
+	err := r.Register(context.Background(), svc)
+	var me *MultiError
+	assert.ErrorAs(t, err, &me)
+	assert.Len(t, me.Errors, 1)
+	assert.Equal(t, "nacos", me.Errors[0].Name)
+	assert.ErrorIs(t, err, children[1].registerErr)
+	assert.Equal(t, []string{"register consul", "register nacos", "register etcd"}, *ops)
+
+	health := r.Health()
+	assert.True(t, health[0].Healthy)
+	assert.False(t, health[1].Healthy)
+	assert.Equal(t, "register", health[1].LastOp)
+	assert.Equal(t, children[1].registerErr, health[1].LastErr)
+	assert.True(t, health[2].Healthy)
+
+	// the failure of deregistration doesn't stop the others
+	*ops = nil
+	err = r.Deregister(context.Background(), svc)
+	assert.ErrorAs(t, err, &me)
+	assert.Equal(t, []string{"deregister etcd", "deregister nacos", "deregister consul"}, *ops)
+	assert.Contains(t, err.Error(), "deregister nacos")
+}
+
+func TestRegistry_FailFast(t *testing.T) {
+	children, ops := newChildren("consul", "nacos", "etcd")
+	children[2].registerErr = errors.New("etcd unavailable")
+	r := New(
+		WithChild("consul", children[0]),
+		WithChild("nacos", children[1], WithPolicy(BestEffort)),
+		WithChild("etcd", children[2], WithPolicy(FailFast)),
+	)
+
+	// the registered children are rolled back in the reverse order
+	err := r.Register(context.Background(), instance("1", "grpc://127.0.0.1:8282"))
+	var ce *ChildError
+	assert.ErrorAs(t, err, &ce)
+	assert.Equal(t, "etcd", ce.Name)
+	assert.Equal(t, []string{"register consul", "register nacos", "register etcd", "deregister nacos", "deregister consul"}, *ops)
+
+	// the fail-fast child fails first, the others are not registered
+	children, ops = newChildren("nacos", "consul")
+	children[0].registerErr = errors.New("nacos unavailable")
+	r = New(WithChild("nacos", children[0], WithPolicy(FailFast)), WithChild("consul", children[1]))
+	assert.Error(t, r.Register(context.Background(), instance("1", "grpc://127.0.0.1:8282")))
+	assert.Equal(t, []string{"register nacos"}, *ops)
+}
+
+func TestRegistry_GetService(t *testing.T) {
+	children, _ := newChildren("consul", "nacos")
+	children[0].instances = []*registry.ServiceInstance{
+		instance("c1", "grpc://127.0.0.1:8282?isSecure=false"),
+		instance("c2", "grpc://127.0.0.2:8282"),
+	}
+	children[1].instances = []*registry.ServiceInstance{
+		instance("n1", "grpc://127.0.0.1:8282"),
+		instance("n3", "grpc://127.0.0.3:8282"),
+	}
+	r := New(WithChild("consul", children[0]), WithChild("nacos", children[1]))
+
+	instances, err := r.GetService(context.Background(), "user")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c1", "c2", "n3"}, ids(instances))
+
+	// the best-effort child fails
+	children[0].watchErr = errors.New("consul unavailable")
+	instances, err = r.GetService(context.Background(), "user")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"n1", "n3"}, ids(instances))
+
+	// all fail
+	children[1].watchErr = errors.New("nacos unavailable")
+	_, err = r.GetService(context.Background(), "user")
+	assert.Error(t, err)
+
+	// the fail-fast child fails
+	r = New(WithChild("consul", children[0], WithPolicy(FailFast)))
+	_, err = r.GetService(context.Background(), "user")
+	assert.Error(t, err)
+}
+
+func TestRegistry_Watch(t *testing.T) {
+	children, _ := newChildren("consul", "nacos")
+	r := New(WithChild("consul", children[0]), WithChild("nacos", children[1]))
+
+	w, err := r.Watch(context.Background(), "user")
+	assert.NoError(t, err)
+	cw, nw := children[0].watchers[0], children[1].watchers[0]
+
+	cw.ch <- []*registry.ServiceInstance{instance("c1", "grpc://127.0.0.1:8282")}
+	instances, err := w.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c1"}, ids(instances))
+
+	// merged and de-duplicated by the address
+	nw.ch <- []*registry.ServiceInstance{instance("n1", "grpc://127.0.0.1:8282"), instance("n2", "grpc://127.0.0.2:8282")}
+	assert.Eventually(t, func() bool {
+		instances, err = w.Next()
+		return err == nil && len(instances) == 2
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, []string{"c1", "n2"}, ids(instances))
+
+	// the instance is removed from consul, the one of nacos is used
+	cw.ch <- []*registry.ServiceInstance{}
+	assert.Eventually(t, func() bool {
+		instances, err = w.Next()
+		return err == nil && len(instances) == 2 && instances[0].ID == "n1"
+	}, time.Second, time.Millisecond)
+
+	assert.NoError(t, w.Stop())
+	_, err = w.Next()
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRegistry_WatchError(t *testing.T) {
+	// the best-effort child is skipped
+	children, _ := newChildren("consul", "nacos")
+	children[0].watchErr = errors.New("consul unavailable")
+	r := New(WithChild("consul", children[0]), WithChild("nacos", children[1]))
+	w, err := r.Watch(context.Background(), "user")
+	assert.NoError(t, err)
+	assert.False(t, r.Health()[0].Healthy)
+	_ = w.Stop()
+
+	// the fail-fast child fails, the started watchers are stopped
+	r = New(WithChild("nacos", children[1]), WithChild("consul", children[0], WithPolicy(FailFast)))
+	_, err = r.Watch(context.Background(), "user")
+	assert.Error(t, err)
+	select {
+	case <-children[1].watchers[1].stopped:
+	default:
+		t.Fatal("the watcher is not stopped")
+	}
+
+	// no child supports discovery
+	r = New(WithChild("consul", children[0], WithDiscovery(nil)))
+	_, err = r.Watch(context.Background(), "user")
+	assert.Error(t, err)
+}
+
+func ids(instances []*registry.ServiceInstance) []string {
+	list := make([]string, 0, len(instances))
+	for _, ins := range instances {
+		list = append(list, ins.ID)
+	}
+	return list
+}
@@ -0,0 +1,134 @@
+package multi
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/go-dev-frame/sponge/pkg/servicerd/registry"
+)
+
+var _ registry.Watcher = (*watcher)(nil)
+
+// the interval of retrying a child watcher after an error
+var retryInterval = time.Second
+
+type watcher struct {
+	r        *Registry
+	children []*child
+	watchers []registry.Watcher
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	event  chan struct{}
+
+	mu    sync.Mutex
+	lists [][]*registry.ServiceInstance // the latest instances of each child
+}
+
+func newWatcher(ctx context.Context, r *Registry, children []*child, watchers []registry.Watcher) *watcher {
+	w := &watcher{
+		r:        r,
+		children: children,
+		watchers: watchers,
+		event:    make(chan struct{}, 1),
+		lists:    make([][]*registry.ServiceInstance, len(watchers)),
+	}
+	w.ctx, w.cancel = context.WithCancel(ctx)
+
+	for i := range watchers {
+		go w.watch(i)
+	}
+	return w
+}
+
+// the goroutine exits when the child watcher returns after stopping
+func (w *watcher) watch(i int) {
+	for {
+		instances, err := w.watchers[i].Next()
+		if w.ctx.Err() != nil {
+			return
+		}
+		w.r.report(w.children[i], "watch", err)
+		if err != nil {
+			fmt.Printf("[multi registry] failed to watch %s: %v\n", w.children[i].name, err)
+			select {
+			case <-w.ctx.Done():
+				return
+			case <-time.After(retryInterval):
+			}
+			continue
+		}
+
+		w.mu.Lock()
+		w.lists[i] = instances
+		w.mu.Unlock()
+		select {
+		case w.event <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Next returns the merged instances of all children when any child changes.
+func (w *watcher) Next() ([]*registry.ServiceInstance, error) {
+	select {
+	case <-w.ctx.Done():
+		return nil, w.ctx.Err()
+	case <-w.event:
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return merge(w.lists), nil
+}
+
+// Stop all the child watchers.
+func (w *watcher) Stop() error {
+	w.cancel()
+	var errs []*ChildError
+	for i, cw := range w.watchers {
+		if err := cw.Stop(); err != nil {
+			errs = append(errs, &ChildError{Name: w.children[i].name, Op: "stop watcher", Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return &MultiError{Errors: errs}
+	}
+	return nil
+}
+
+// merge the instances in order, the instance is dropped if any of its addresses is in the instances before it.
+func merge(lists [][]*registry.ServiceInstance) []*registry.ServiceInstance {
+	var list []*registry.ServiceInstance
+	seen := make(map[string]struct{})
+	for _, instances := range lists {
+	next:
+		for _, ins := range instances {
+			addrs := make([]string, 0, len(ins.Endpoints))
+			for _, endpoint := range ins.Endpoints {
+				addr := address(endpoint)
+				if _, ok := seen[addr]; ok {
+					continue next
+				}
+				addrs = append(addrs, addr)
+			}
+			for _, addr := range addrs {
+				seen[addr] = struct{}{}
+			}
+			list = append(list, ins)
+		}
+	}
+	return list
+}
+
+// the address of the endpoint without the query, e.g. grpc://127.0.0.1:8282?isSecure=false --> grpc://127.0.0.1:8282
+func address(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return endpoint
+	}
+	return u.Scheme + "://" + u.Host
+}
@@ -7,6 +7,7 @@ import (
 	"net"
 	"net/url"
 	"strconv"
+	"sync"
 
 	"github.com/nacos-group/nacos-sdk-go/v2/clients/naming_client"
 	"github.com/nacos-group/nacos-sdk-go/v2/common/constant"
@@ -61,6 +62,10 @@ func WithDefaultKind(kind string) Option {
 type Registry struct {
 	opts options
 	cli  naming_client.INamingClient
+
+	// the registered instances, updates are serialized to avoid racing with each other and the registration
+	mu        sync.Mutex
+	instances []vo.RegisterInstanceParam
 }
 
 // NewRegistry instantiating the nacos registry
@@ -127,6 +132,8 @@ func (r *Registry) Register(_ context.Context, si *registry.ServiceInstance) err
 	if si.Name == "" {
 		return fmt.Errorf("nacos: serviceInstance.name can not be empty")
 	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	for _, endpoint := range si.Endpoints {
 		u, err := url.Parse(endpoint)
 		if err != nil {
@@ -156,7 +163,7 @@ func (r *Registry) Register(_ context.Context, si *registry.ServiceInstance) err
 			rmd["kind"] = u.Scheme
 			rmd["version"] = si.Version
 		}
-		_, e := r.cli.RegisterInstance(vo.RegisterInstanceParam{
+		param := vo.RegisterInstanceParam{
 			Ip:          host,
 			Port:        uint64(p),
 			ServiceName: si.Name + "." + u.Scheme,
@@ -167,16 +174,21 @@ func (r *Registry) Register(_ context.Context, si *registry.ServiceInstance) err
 			Metadata:    rmd,
 			ClusterName: r.opts.cluster,
 			GroupName:   r.opts.group,
-		})
+		}
+		_, e := r.cli.RegisterInstance(param)
 		if e != nil {
 			return fmt.Errorf("RegisterInstance err %v, id = %s", e, si.ID)
 		}
+		r.addInstance(param)
 	}
 	return nil
 }
 
 // Deregister the registration.
 func (r *Registry) Deregister(_ context.Context, service *registry.ServiceInstance) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	for _, endpoint := range service.Endpoints {
 		u, err := url.Parse(endpoint)
 		if err != nil {
@@ -200,6 +212,7 @@ func (r *Registry) Deregister(_ context.Context, service *registry.ServiceInstan
 		}); err != nil {
 			return err
 		}
+		r.removeInstance(host, uint64(p), service.Name+"."+u.Scheme)
 	}
 	return nil
 }
@@ -0,0 +1,141 @@
+package nacos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+)
+
+// ErrNotRegistered the instance is not registered by the registry.
+var ErrNotRegistered = errors.New("nacos: no registered instance, call Register first")
+
+// the metadata keys set by Register, they cannot be changed by updates
+var reservedMetadataKeys = map[string]struct{}{"id": {}, "kind": {}, "version": {}}
+
+type updateOptions struct {
+	weight   *float64
+	metadata map[string]string
+}
+
+// UpdateOption set the fields of the registered instance to update.
+type UpdateOption func(o *updateOptions)
+
+// WithUpdateWeight update the weight of the instance.
+func WithUpdateWeight(weight float64) UpdateOption {
+	return func(o *updateOptions) { o.weight = &weight }
+}
+
+// WithUpdateMetadata update the metadata of the instance, kv is merged into the existing metadata,
+// a key with an empty value is removed, the keys id, kind and version are ignored.
+func WithUpdateMetadata(kv map[string]string) UpdateOption {
+	return func(o *updateOptions) {
+		if o.metadata == nil {
+			o.metadata = make(map[string]string, len(kv))
+		}
+		for k, v := range kv {
+			o.metadata[k] = v
+		}
+	}
+}
+
+// UpdateInstance update the weight or metadata of the registered instances in place, the instance id
+// is unchanged, so there is no blip in discovery like re-registering. Updates are serialized, so
+// concurrent updates are applied one after another and never lose each other's changes.
+func (r *Registry) UpdateInstance(ctx context.Context, opts ...UpdateOption) error {
+	o := &updateOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.weight != nil && (*o.weight < 0 || *o.weight > 10000) {
+		return fmt.Errorf("nacos: invalid weight %v, must be in [0, 10000]", *o.weight)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.instances) == 0 {
+		return ErrNotRegistered
+	}
+
+	for i, ins := range r.instances {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		updated := ins
+		if o.weight != nil {
+			updated.Weight = *o.weight
+		}
+		updated.Metadata = mergeMetadata(ins.Metadata, o.metadata)
+
+		_, err := r.cli.UpdateInstance(vo.UpdateInstanceParam{
+			Ip:          updated.Ip,
+			Port:        updated.Port,
+			ClusterName: updated.ClusterName,
+			ServiceName: updated.ServiceName,
+			GroupName:   updated.GroupName,
+			Ephemeral:   updated.Ephemeral,
+			Weight:      updated.Weight,
+			Enable:      updated.Enable,
+			Metadata:    updated.Metadata,
+		})
+		if err != nil {
+			return fmt.Errorf("UpdateInstance err %v, service = %s", err, ins.ServiceName)
+		}
+		r.instances[i] = updated
+	}
+
+	return nil
+}
+
+// SetWeight update the weight of the registered instances, e.g. increase the traffic of a canary instance gradually.
+func (r *Registry) SetWeight(ctx context.Context, weight float64) error {
+	return r.UpdateInstance(ctx, WithUpdateWeight(weight))
+}
+
+// SetMetadata merge kv into the metadata of the registered instances, e.g. {"canary": "true"},
+// a key with an empty value is removed.
+func (r *Registry) SetMetadata(ctx context.Context, kv map[string]string) error {
+	return r.UpdateInstance(ctx, WithUpdateMetadata(kv))
+}
+
+func mergeMetadata(src map[string]string, kv map[string]string) map[string]string {
+	md := make(map[string]string, len(src)+len(kv))
+	for k, v := range src {
+		md[k] = v
+	}
+	for k, v := range kv {
+		if _, ok := reservedMetadataKeys[k]; ok {
+			continue
+		}
+		if v == "" {
+			delete(md, k)
+			continue
+		}
+		md[k] = v
+	}
+	return md
+}
+
+// the caller must hold r.mu
+func (r *Registry) addInstance(param vo.RegisterInstanceParam) {
+	for i, ins := range r.instances {
+		if ins.Ip == param.Ip && ins.Port == param.Port && ins.ServiceName == param.ServiceName {
+			r.instances[i] = param
+			return
+		}
+	}
+	r.instances = append(r.instances, param)
+}
+
+// the caller must hold r.mu
+func (r *Registry) removeInstance(ip string, port uint64, serviceName string) {
+	for i, ins := range r.instances {
+		if ins.Ip == ip && ins.Port == port && ins.ServiceName == serviceName {
+			r.instances = append(r.instances[:i], r.instances[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package nacos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/clients/naming_client"
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-dev-frame/sponge/pkg/servicerd/registry"
+)
+
+// stubNamingClient records the updates, the methods that are not overridden panic
+type stubNamingClient struct {
+	naming_client.INamingClient
+
+	mu         sync.Mutex
+	registered []vo.RegisterInstanceParam
+	updates    []vo.UpdateInstanceParam
+	updateErr  error
+
+	running    int32
+	maxRunning int32
+}
+
+func (c *stubNamingClient) RegisterInstance(param vo.RegisterInstanceParam) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.registered = append(c.registered, param)
+	return true, nil
+}
+
+func (c *stubNamingClient) DeregisterInstance(param vo.DeregisterInstanceParam) (bool, error) {
+	return true, nil
+}
+
+func (c *stubNamingClient) UpdateInstance(param vo.UpdateInstanceParam) (bool, error) {
+	n := atomic.AddInt32(&c.running, 1)
+	defer atomic.AddInt32(&c.running, -1)
+	for {
+		max := atomic.LoadInt32(&c.maxRunning)
+		if n <= max || atomic.CompareAndSwapInt32(&c.maxRunning, max, n) {
+			break
+		}
+	}
+	time.Sleep(time.Millisecond)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.updateErr != nil {
+		return false, c.updateErr
+	}
+	c.updates = append(c.updates, param)
+	return true, nil
+}
+
+func (c *stubNamingClient) lastUpdate() vo.UpdateInstanceParam {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.updates[len(c.updates)-1]
+}
+
+func newStubRegistry(t *testing.T) (*Registry, *stubNamingClient, *registry.ServiceInstance) {
+	cli := &stubNamingClient{}
+	r := New(cli, WithWeight(100), WithGroup("dev"))
+	instance := registry.NewServiceInstance("foo", "bar", []string{"grpc://127.0.0.1:8282"},
+		registry.WithMetadata(map[string]string{"zone": "a"}))
+	err := r.Register(context.Background(), instance)
+	assert.NoError(t, err)
+	return r, cli, instance
+}
+
+func TestRegistry_UpdateInstance(t *testing.T) {
+	r, cli, _ := newStubRegistry(t)
+	ctx := context.Background()
+
+	err := r.SetWeight(ctx, 10)
+	assert.NoError(t, err)
+	update := cli.lastUpdate()
+	assert.Equal(t, float64(10), update.Weight)
+	assert.Equal(t, "127.0.0.1", update.Ip)
+	assert.Equal(t, uint64(8282), update.Port)
+	assert.Equal(t, "bar.grpc", update.ServiceName)
+	assert.Equal(t, "dev", update.GroupName)
+	assert.True(t, update.Ephemeral)
+	assert.True(t, update.Enable)
+	assert.Equal(t, "a", update.Metadata["zone"])
+
+	// metadata is merged rather than replaced, the weight is kept
+	err = r.SetMetadata(ctx, map[string]string{"canary": "true"})
+	assert.NoError(t, err)
+	update = cli.lastUpdate()
+	assert.Equal(t, float64(10), update.Weight)
+	assert.Equal(t, map[string]string{"id": "foo", "kind": "grpc", "version": "", "zone": "a", "canary": "true"}, update.Metadata)
+
+	// remove a key, the reserved keys cannot be changed
+	err = r.UpdateInstance(ctx, WithUpdateMetadata(map[string]string{"canary": "", "id": "other"}), WithUpdateWeight(50))
+	assert.NoError(t, err)
+	update = cli.lastUpdate()
+	assert.Equal(t, float64(50), update.Weight)
+	assert.Equal(t, map[string]string{"id": "foo", "kind": "grpc", "version": "", "zone": "a"}, update.Metadata)
+
+	// the registered metadata is not modified by the updates
+	assert.Equal(t, map[string]string{"id": "foo", "kind": "grpc", "version": "", "zone": "a"}, cli.registered[0].Metadata)
+}
+
+func TestRegistry_UpdateInstanceSerialized(t *testing.T) {
+	r, cli, _ := newStubRegistry(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := r.SetMetadata(context.Background(), map[string]string{fmt.Sprintf("key%d", i): "v"})
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&cli.maxRunning))
+	md := cli.lastUpdate().Metadata
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, "v", md[fmt.Sprintf("key%d", i)])
+	}
+}
+
+func TestRegistry_UpdateInstanceError(t *testing.T) {
+	// not registered
+	r := New(&stubNamingClient{})
+	err := r.SetWeight(context.Background(), 1)
+	assert.ErrorIs(t, err, ErrNotRegistered)
+
+	r, cli, instance := newStubRegistry(t)
+
+	err = r.SetWeight(context.Background(), -1)
+	assert.Error(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = r.SetWeight(ctx, 1)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// a failed update is not applied
+	cli.updateErr = errors.New("server error")
+	err = r.SetMetadata(context.Background(), map[string]string{"canary": "true"})
+	assert.Error(t, err)
+	cli.updateErr = nil
+	err = r.SetWeight(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.NotContains(t, cli.lastUpdate().Metadata, "canary")
+
+	// deregistered
+	err = r.Deregister(context.Background(), instance)
+	assert.NoError(t, err)
+	err = r.SetWeight(context.Background(), 1)
+	assert.ErrorIs(t, err, ErrNotRegistered)
+}
@@ -0,0 +1,70 @@
+package registry
+
+import (
+	"context"
+	"sync"
+)
+
+// Registration is a service instance bound to a registry, it records whether the instance is registered,
+// so that Deregister is idempotent and safe when the registration never happened.
+type Registration struct {
+	iRegistry Registry
+	instance  *ServiceInstance
+
+	mu         sync.Mutex
+	registered bool
+}
+
+// NewRegistration create a registration, iRegistry can be nil, which means registration is not used.
+func NewRegistration(iRegistry Registry, instance *ServiceInstance) *Registration {
+	return &Registration{
+		iRegistry: iRegistry,
+		instance:  instance,
+	}
+}
+
+// Register the instance, do nothing if the registry is nil or the instance is already registered.
+func (r *Registration) Register(ctx context.Context) error {
+	if r == nil || r.iRegistry == nil || r.instance == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.registered {
+		return nil
+	}
+	if err := r.iRegistry.Register(ctx, r.instance); err != nil {
+		return err
+	}
+	r.registered = true
+	return nil
+}
+
+// Deregister the instance, it is idempotent, do nothing if the instance is not registered.
+func (r *Registration) Deregister(ctx context.Context) error {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.registered {
+		return nil
+	}
+	if err := r.iRegistry.Deregister(ctx, r.instance); err != nil {
+		return err
+	}
+	r.registered = false
+	return nil
+}
+
+// IsRegistered returns whether the instance is registered.
+func (r *Registration) IsRegistered() bool {
+	if r == nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.registered
+}
@@ -0,0 +1,77 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countRegistry struct {
+	registers   int
+	deregisters int
+	err         error
+}
+
+func (r *countRegistry) Register(ctx context.Context, service *ServiceInstance) error {
+	r.registers++
+	return r.err
+}
+
+func (r *countRegistry) Deregister(ctx context.Context, service *ServiceInstance) error {
+	r.deregisters++
+	return r.err
+}
+
+func TestRegistration(t *testing.T) {
+	ctx := context.Background()
+	instance := NewServiceInstance("foo", "bar", []string{"grpc://127.0.0.1:8282"})
+	cr := &countRegistry{}
+	r := NewRegistration(cr, instance)
+
+	// deregister before registering
+	assert.NoError(t, r.Deregister(ctx))
+	assert.Equal(t, 0, cr.deregisters)
+
+	assert.NoError(t, r.Register(ctx))
+	assert.NoError(t, r.Register(ctx))
+	assert.Equal(t, 1, cr.registers)
+	assert.True(t, r.IsRegistered())
+
+	// idempotent
+	assert.NoError(t, r.Deregister(ctx))
+	assert.NoError(t, r.Deregister(ctx))
+	assert.Equal(t, 1, cr.deregisters)
+	assert.False(t, r.IsRegistered())
+
+	// registration is not used
+	for _, r := range []*Registration{nil, NewRegistration(nil, instance), NewRegistration(cr, nil)} {
+		assert.NoError(t, r.Register(ctx))
+		assert.False(t, r.IsRegistered())
+		assert.NoError(t, r.Deregister(ctx))
+	}
+	assert.Equal(t, 1, cr.registers)
+}
+
+func TestRegistrationError(t *testing.T) {
+	ctx := context.Background()
+	cr := &countRegistry{err: errors.New("registry error")}
+	r := NewRegistration(cr, NewServiceInstance("foo", "bar", []string{"grpc://127.0.0.1:8282"}))
+
+	assert.Error(t, r.Register(ctx))
+	assert.False(t, r.IsRegistered())
+	assert.NoError(t, r.Deregister(ctx))
+	assert.Equal(t, 0, cr.deregisters)
+
+	cr.err = nil
+	assert.NoError(t, r.Register(ctx))
+
+	// a failed deregistration can be retried
+	cr.err = errors.New("registry error")
+	assert.Error(t, r.Deregister(ctx))
+	assert.True(t, r.IsRegistered())
+	cr.err = nil
+	assert.NoError(t, r.Deregister(ctx))
+	assert.Equal(t, 2, cr.deregisters)
+}
@@ -0,0 +1,104 @@
+package query
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/go-dev-frame/sponge/pkg/internal/bindquery"
+)
+
+var (
+	defaultLimit      = 10
+	defaultMaxColumns = 100
+
+	// the keys besides the common ones are enabled for the query of gorm
+	binder = &bindquery.Binder{
+		MaxColumns: defaultMaxColumns,
+	}
+)
+
+// SetDefaultLimit change the limit used by BindParams when the request does not specify it
+func SetDefaultLimit(limit int) {
+	if limit < 1 {
+		limit = 1
+	}
+	defaultLimit = limit
+}
+
+// FieldError a binding error of one parameter, e.g. the field is columns[0][exp], the tag is the reason of
+// the error, e.g. "number", "unknown", "syntax"
+type FieldError = bindquery.FieldError
+
+// BindError binding errors of the query parameters, it is a bad request error.
+type BindError = bindquery.BindError
+
+// BindParams bind query parameters from request, three input styles are supported:
+//
+//	json body: {"page":0,"limit":10,"sort":"-id","columns":[{"name":"age","exp":">","value":18}]}
+//	bracketed form or query: page=0&limit=10&sort=-id&columns[0][name]=age&columns[0][exp]=gt&columns[0][value]=18
+//	compact filter DSL: page=0&limit=10&sort=-id&filter=age:gt:18
+//
+// the sort can be the structured sorts in all the styles instead of the sort string, e.g. the json body
+// {"sorts":[{"name":"age","desc":true}]}, or the bracketed form sorts[0][name]=age&sorts[0][desc]=true.
+//
+// the deprecated Size is normalized into Limit, the default page is 0 and the default limit is 10,
+// a BindError is returned if the parameters cannot be parsed, a validator.ValidationErrors is
+// returned if the parameters are invalid.
+func BindParams(c *gin.Context) (*Params, error) {
+	p := &Params{}
+	t := &paramsTarget{p: p}
+	if err := binder.Bind(c, t); err != nil {
+		return nil, err
+	}
+	return p, binder.Normalize(t, defaultLimit)
+}
+
+// ParseFilter parse the compact filter DSL into columns, each condition is name:exp:value,
+// conditions are separated by ; (and) or | (or), e.g.
+//
+//	age:gt:18;status:in:active,trial|vip:eq:true
+//
+// the exp can be omitted as name::value, which means eq, value can be omitted as name:isnull,
+// a backslash escapes the separator characters in value, e.g. name:eq:a\;b.
+func ParseFilter(filter string) ([]Column, error) {
+	columns, err := binder.ParseFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	return toColumns(columns), nil
+}
+
+// the adapter of Params for the binder
+type paramsTarget struct {
+	p *Params
+}
+
+func (a *paramsTarget) Params() interface{} {
+	return a.p
+}
+
+func (a *paramsTarget) Len() (int, int) {
+	return len(a.p.Columns), len(a.p.Sorts)
+}
+
+func (a *paramsTarget) Paging() (*int, *int) {
+	return &a.p.Limit, &a.p.Size
+}
+
+func (a *paramsTarget) SetValues(v *bindquery.Values) {
+	a.p.Page, a.p.Limit, a.p.Size, a.p.Sort = v.Page, v.Limit, v.Size, v.Sort
+	a.p.Columns = toColumns(v.Columns)
+	for _, s := range v.Sorts {
+		a.p.Sorts = append(a.p.Sorts, SortField{Name: s.Name, Desc: s.Desc})
+	}
+}
+
+func toColumns(columns []bindquery.Column) []Column {
+	if columns == nil {
+		return nil
+	}
+	cols := make([]Column, 0, len(columns))
+	for _, c := range columns {
+		cols = append(cols, Column{Name: c.Name, Exp: c.Exp, Value: c.Value, Logic: c.Logic})
+	}
+	return cols
+}
@@ -0,0 +1,180 @@
+package query
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+)
+
+func newBindContext(method string, target string, contentType string, body string) *gin.Context {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(method, target, strings.NewReader(body))
+	if contentType != "" {
+		c.Request.Header.Set("Content-Type", contentType)
+	}
+	return c
+}
+
+func TestBindParams(t *testing.T) {
+	want := &Params{
+		Page:  1,
+		Limit: 20,
+		Sort:  "-id",
+		Columns: []Column{
+			{Name: "age", Exp: "gt", Value: "18"},
+			{Name: "status", Exp: "in", Value: "active,trial", Logic: "or"},
+			{Name: "name", Exp: "like", Value: "a;b"},
+		},
+	}
+
+	jsonBody := `{"page":1,"limit":20,"sort":"-id","columns":[{"name":"age","exp":"gt","value":"18"},` +
+		`{"name":"status","exp":"in","value":"active,trial","logic":"or"},{"name":"name","exp":"like","value":"a;b"}]}`
+
+	bracket := url.Values{
+		"page":                 {"1"},
+		"limit":                {"20"},
+		"sort":                 {"-id"},
+		"columns[0][name]":     {"age"},
+		"columns[0][exp]":      {"gt"},
+		"columns[0][value]":    {"18"},
+		"columns[1][name]":     {"status"},
+		"columns[1][exp]":      {"in"},
+		"columns[1][value][1]": {"trial"},
+		"columns[1][value][0]": {"active"},
+		"columns[1][logic]":    {"or"},
+		"columns[2][name]":     {"name"},
+		"columns[2][exp]":      {"like"},
+		"columns[2][value]":    {"a;b"},
+	}.Encode()
+
+	dsl := url.Values{
+		"page":   {"1"},
+		"size":   {"20"}, // deprecated size
+		"sort":   {"-id"},
+		"filter": {`age:gt:18;status:in:active,trial|name:like:a\;b`},
+	}.Encode()
+
+	testData := []struct {
+		name string
+		c    *gin.Context
+	}{
+		{"json body", newBindContext(http.MethodPost, "/list", "application/json", jsonBody)},
+		{"bracketed query", newBindContext(http.MethodGet, "/list?"+bracket, "", "")},
+		{"bracketed form", newBindContext(http.MethodPost, "/list", "application/x-www-form-urlencoded", bracket)},
+		{"filter dsl", newBindContext(http.MethodGet, "/list?"+dsl, "", "")},
+	}
+
+	for _, tt := range testData {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BindParams(tt.c)
+			assert.NoError(t, err)
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestBindParams_Sorts(t *testing.T) {
+	want := []SortField{{Name: "status"}, {Name: "age", Desc: true}, {Name: "name"}}
+
+	jsonBody := `{"sorts":[{"name":"status"},{"name":"age","desc":true},{"name":"name","desc":false}]}`
+	bracket := url.Values{
+		"sorts[2][name]": {"name"},
+		"sorts[0][name]": {"status"},
+		"sorts[1][name]": {"age"},
+		"sorts[1][desc]": {"true"},
+	}.Encode()
+
+	for _, c := range []*gin.Context{
+		newBindContext(http.MethodPost, "/list", "application/json", jsonBody),
+		newBindContext(http.MethodGet, "/list?"+bracket, "", ""),
+		newBindContext(http.MethodPost, "/list", "application/x-www-form-urlencoded", bracket),
+	} {
+		got, err := BindParams(c)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got.Sorts) // the order of the indexes
+		assert.Empty(t, got.Sort)
+	}
+
+	// both representations are bound, the conflict is checked by the conversion
+	got, err := BindParams(newBindContext(http.MethodGet, "/list?sort=-age&sorts[0][name]=age", "", ""))
+	assert.NoError(t, err)
+	assert.Equal(t, "-age", got.Sort)
+	assert.Equal(t, []SortField{{Name: "age"}}, got.Sorts)
+}
+
+func TestBindParamsDefault(t *testing.T) {
+	got, err := BindParams(newBindContext(http.MethodGet, "/list", "", ""))
+	assert.NoError(t, err)
+	assert.Equal(t, &Params{Page: 0, Limit: defaultLimit}, got)
+
+	// empty json body
+	got, err = BindParams(newBindContext(http.MethodPost, "/list?page=2", "application/json", ""))
+	assert.NoError(t, err)
+	assert.Equal(t, &Params{Page: 2, Limit: defaultLimit}, got)
+
+	got, err = BindParams(newBindContext(http.MethodPost, "/list", "application/json", `{"size":5}`))
+	assert.NoError(t, err)
+	assert.Equal(t, &Params{Limit: 5}, got)
+
+	SetDefaultLimit(0)
+	got, _ = BindParams(newBindContext(http.MethodGet, "/list", "", ""))
+	assert.Equal(t, 1, got.Limit)
+	SetDefaultLimit(10)
+
+	cols, err := ParseFilter("")
+	assert.NoError(t, err)
+	assert.Nil(t, cols)
+	cols, err = ParseFilter("deleted_at:isnull|name::foo")
+	assert.NoError(t, err)
+	assert.Equal(t, []Column{{Name: "deleted_at", Exp: "isnull", Logic: "or"}, {Name: "name", Value: "foo"}}, cols)
+}
+
+func TestBindParamsError(t *testing.T) {
+	testData := []struct {
+		name   string
+		target string
+		field  string
+		tag    string
+	}{
+		{"page not number", "/list?page=a", "page", "number"},
+		{"unknown column key", "/list?columns[0][foo]=1", "columns[0][foo]", "unknown"},
+		{"bad column key", "/list?columns[a][name]=1", "columns[a][name]", "syntax"},
+		{"too many columns", "/list?columns[100][name]=1", "columns[100][name]", "max"},
+		{"sort desc not boolean", "/list?sorts[0][name]=age&sorts[0][desc]=yes", "sorts[0][desc]", "boolean"},
+		{"unknown sort key", "/list?sorts[0][foo]=1", "sorts[0][foo]", "unknown"},
+		{"too many sorts", "/list?sorts[100][name]=age", "sorts[100][name]", "max"},
+		{"filter syntax", "/list?filter=age", "filter", "syntax"},
+		{"filter empty condition", "/list?filter=age:eq:1%3B", "filter", "syntax"},
+		{"filter with columns", "/list?filter=age:eq:1&columns[0][name]=age", "filter", "excluded_with=columns"},
+	}
+
+	for _, tt := range testData {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := BindParams(newBindContext(http.MethodGet, tt.target, "", ""))
+			var bindErr BindError
+			assert.True(t, errors.As(err, &bindErr))
+			assert.True(t, bindErr.BadRequest())
+			var fe *FieldError
+			assert.True(t, errors.As(err, &fe))
+			assert.Equal(t, tt.field, bindErr[0].Field())
+			assert.Equal(t, tt.tag, bindErr[0].Tag())
+			assert.NotEmpty(t, bindErr[0].Value())
+			assert.Contains(t, err.Error(), tt.field)
+		})
+	}
+
+	_, err := BindParams(newBindContext(http.MethodPost, "/list", "application/json", "{"))
+	assert.IsType(t, BindError{}, err)
+
+	// failed validation
+	_, err = BindParams(newBindContext(http.MethodGet, "/list?page=-1", "", ""))
+	assert.IsType(t, validator.ValidationErrors{}, err)
+}
@@ -82,6 +82,9 @@ func NewPage(page int, limit int, columnNames string) *Page {
 //	columnNames="-name" means sort by name descending,
 //	columnNames="name,age" means sort by name in ascending order, otherwise sort by age in ascending order,
 //	columnNames="-name,-age" means sort by name descending before sorting by age descending.
+//
+// the empty column names are skipped, e.g. "name,,age," is the same as "name,age", if all the column names
+// are empty, it is sorted by id backwards.
 func getSort(columnNames string) string {
 	columnNames = strings.Replace(columnNames, " ", "", -1)
 	if columnNames == "" {
@@ -91,12 +94,18 @@ func getSort(columnNames string) string {
 	names := strings.Split(columnNames, ",")
 	strs := make([]string, 0, len(names))
 	for _, name := range names {
+		if name == "" {
+			continue
+		}
 		if name[0] == '-' && len(name) > 1 {
 			strs = append(strs, name[1:]+" DESC")
 		} else {
 			strs = append(strs, name+" ASC")
 		}
 	}
+	if len(strs) == 0 {
+		return "id DESC"
+	}
 
 	return strings.Join(strs, ", ")
 }
@@ -3,6 +3,7 @@ package query
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 )
 
@@ -114,6 +115,9 @@ type Params struct {
 	Page  int    `json:"page" form:"page" binding:"gte=0"`
 	Limit int    `json:"limit" form:"limit" binding:"gte=1"`
 	Sort  string `json:"sort,omitempty" form:"sort" binding:""`
+	// the structured sort fields in order, they take precedence over Sort, e.g. [{"name":"age","desc":true}],
+	// Sort must be empty or the same order if both are set, see ConvertToOrder
+	Sorts []SortField `json:"sorts,omitempty" form:"sorts"`
 
 	Columns []Column `json:"columns,omitempty" form:"columns"` // not required
 
@@ -121,6 +125,12 @@ type Params struct {
 	Size int `json:"size" form:"size"`
 }
 
+// SortField the structured sort field of Sorts, the field is in ascending order unless Desc is true
+type SortField struct {
+	Name string `json:"name" form:"name"`
+	Desc bool   `json:"desc" form:"desc"`
+}
+
 // Column query info
 type Column struct {
 	Name  string      `json:"name" form:"name"`   // column name
@@ -192,15 +202,82 @@ func (c *Column) checkExp() (string, error) {
 	return symbol, nil
 }
 
-// ConvertToPage converted to page
+// ConvertToPage converted to page, the order is of Sorts if it is set, otherwise Sort, the sort fields are not
+// checked, use ConvertToOrder to reject the fields not in the whitelist and the conflicting Sort and Sorts.
 func (p *Params) ConvertToPage() (order string, limit int, offset int) { //nolint
 	page := NewPage(p.Page, p.Limit, p.Sort)
 	order = page.sort
+	if len(p.Sorts) > 0 {
+		order = sortFieldsOrder(p.Sorts)
+	}
 	limit = page.limit
 	offset = page.page * page.limit
 	return //nolint
 }
 
+// ConvertToOrder converted to the ORDER BY fragment, e.g. "status ASC, created_at DESC", the order is of Sorts
+// if it is set, otherwise Sort. Each sort field must be a column name in the whitelist, and an error is returned
+// if Sort and Sorts are both set in the different orders, the default "id DESC" is not checked, e.g.
+//
+//	order, err := params.ConvertToOrder(query.WithWhitelistNames(columnNames))
+//	db.Order(order)
+func (p *Params) ConvertToOrder(opts ...RulerOption) (string, error) {
+	o := rulerOptions{}
+	o.apply(opts...)
+
+	if len(p.Sorts) == 0 {
+		order := getSort(p.Sort)
+		if strings.Trim(p.Sort, " ,") == "" {
+			return order, nil // id DESC of the default sort
+		}
+		for _, field := range strings.Split(order, ", ") {
+			name := strings.TrimSuffix(strings.TrimSuffix(field, " ASC"), " DESC")
+			if err := o.checkSortName(name); err != nil {
+				return "", err
+			}
+		}
+		return order, nil
+	}
+
+	for i, field := range p.Sorts {
+		if strings.TrimSpace(field.Name) == "" {
+			return "", fmt.Errorf("field 'sorts[%d].name' cannot be empty", i)
+		}
+		if err := o.checkSortName(strings.TrimSpace(field.Name)); err != nil {
+			return "", err
+		}
+	}
+	order := sortFieldsOrder(p.Sorts)
+	if strings.Trim(p.Sort, " ,") != "" && getSort(p.Sort) != order {
+		return "", fmt.Errorf("sort '%s' conflicts with sorts, set one of them", p.Sort)
+	}
+	return order, nil
+}
+
+// the sort field is the column name in the whitelist, the name is in the ORDER BY fragment, so only the letters,
+// digits, underscores and the dot of the table name are allowed, e.g. user.created_at
+func (o *rulerOptions) checkSortName(name string) error {
+	if !sortNameRegexp.MatchString(name) || (o.whitelistNames != nil && !o.whitelistNames[name]) {
+		return fmt.Errorf("sort field '%s' is not allowed", name)
+	}
+	return nil
+}
+
+var sortNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// the ORDER BY fragment of Sorts in order, e.g. "status ASC, created_at DESC"
+func sortFieldsOrder(sorts []SortField) string {
+	strs := make([]string, 0, len(sorts))
+	for _, field := range sorts {
+		if field.Desc {
+			strs = append(strs, strings.TrimSpace(field.Name)+" DESC")
+		} else {
+			strs = append(strs, strings.TrimSpace(field.Name)+" ASC")
+		}
+	}
+	return strings.Join(strs, ", ")
+}
+
 // ConvertToGormConditions conversion to gorm-compliant parameters based on the Columns parameter
 // ignore the logical type of the last column, whether it is a one-column or multi-column query
 func (p *Params) ConvertToGormConditions(opts ...RulerOption) (string, []interface{}, error) { //nolint
@@ -699,3 +699,63 @@ func TestConditions_ConvertToGorm_Error(t *testing.T) {
 	t.Log(err)
 	assert.Error(t, err)
 }
+
+func TestParams_ConvertToOrder(t *testing.T) {
+	whitelist := WithWhitelistNames(map[string]bool{"status": true, "created_at": true, "age": true})
+
+	// Sorts takes precedence, the order of the fields is kept
+	p := &Params{Limit: 10, Sorts: []SortField{{Name: "status"}, {Name: "created_at", Desc: true}}}
+	order, err := p.ConvertToOrder(whitelist)
+	assert.NoError(t, err)
+	assert.Equal(t, "status ASC, created_at DESC", order)
+	order, _, _ = p.ConvertToPage()
+	assert.Equal(t, "status ASC, created_at DESC", order)
+
+	// Sort, the same order of Sort and Sorts is not the conflict
+	order, err = (&Params{Sort: "-age,status"}).ConvertToOrder(whitelist)
+	assert.NoError(t, err)
+	assert.Equal(t, "age DESC, status ASC", order)
+	order, err = (&Params{Sort: "-age", Sorts: []SortField{{Name: "age", Desc: true}}}).ConvertToOrder(whitelist)
+	assert.NoError(t, err)
+	assert.Equal(t, "age DESC", order)
+	order, err = (&Params{}).ConvertToOrder(whitelist)
+	assert.NoError(t, err)
+	assert.Equal(t, "id DESC", order)
+
+	tests := []struct {
+		name    string
+		params  *Params
+		wantErr string
+	}{
+		{"conflict", &Params{Sort: "age", Sorts: []SortField{{Name: "age", Desc: true}}}, "sort 'age' conflicts with sorts, set one of them"},
+		{"not allowed entry", &Params{Sorts: []SortField{{Name: "age"}, {Name: "password"}}}, "sort field 'password' is not allowed"},
+		{"not allowed sort", &Params{Sort: "age,-password"}, "sort field 'password' is not allowed"},
+		{"empty name", &Params{Sorts: []SortField{{Name: ""}}}, "field 'sorts[0].name' cannot be empty"},
+	}
+	for _, tt := range tests {
+		_, err = tt.params.ConvertToOrder(whitelist)
+		assert.EqualError(t, err, tt.wantErr, tt.name)
+	}
+
+	// the injection is rejected without the whitelist
+	_, err = (&Params{Sorts: []SortField{{Name: "age; DROP TABLE user"}}}).ConvertToOrder()
+	assert.Error(t, err)
+	_, err = (&Params{Sorts: []SortField{{Name: "(CASE WHEN 1=1 THEN age END)"}}}).ConvertToOrder()
+	assert.Error(t, err)
+	order, err = (&Params{Sorts: []SortField{{Name: "user.age"}}}).ConvertToOrder()
+	assert.NoError(t, err)
+	assert.Equal(t, "user.age ASC", order)
+
+	// the empty names of Sort are skipped
+	order, err = (&Params{Sort: "age,,"}).ConvertToOrder(whitelist)
+	assert.NoError(t, err)
+	assert.Equal(t, "age ASC", order)
+	order, err = (&Params{Sort: " , ,"}).ConvertToOrder(whitelist)
+	assert.NoError(t, err)
+	assert.Equal(t, "id DESC", order)
+	order, err = (&Params{Sort: ",-age", Sorts: []SortField{{Name: "age", Desc: true}}}).ConvertToOrder(whitelist)
+	assert.NoError(t, err)
+	assert.Equal(t, "age DESC", order)
+	order, _, _ = (&Params{Sort: ",-age,"}).ConvertToPage()
+	assert.Equal(t, "age DESC", order)
+}
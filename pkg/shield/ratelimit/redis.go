@@ -0,0 +1,325 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/time/rate"
+)
+
+var _ Limiter = &RedisLimiter{}
+
+// FallbackMode the behavior of the redis limiter when redis is unavailable
+type FallbackMode int
+
+const (
+	// FallbackLocal limit by the local token bucket of rate/replicas, default
+	FallbackLocal FallbackMode = iota
+	// FallbackOpen allow all the requests
+	FallbackOpen
+	// FallbackClosed reject all the requests
+	FallbackClosed
+)
+
+// tokenBucketScript the token bucket is refilled by the redis server time, so that the clocks of the
+// replicas do not matter, and the tokens up to the requested number are taken atomically.
+// KEYS[1] bucket key, ARGV[1] rate per second, ARGV[2] burst, ARGV[3] requested tokens.
+// return the number of the granted tokens.
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local requested = tonumber(ARGV[3])
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = burst
+	ts = now
+end
+if now > ts then
+	tokens = math.min(burst, tokens + (now - ts) * rate / 1000000)
+	ts = now
+end
+
+local granted = math.min(requested, math.floor(tokens))
+tokens = tokens - granted
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", tostring(ts))
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+return granted
+`)
+
+// RedisLimiter is a distributed token bucket limiter shared by all the replicas through redis, the limit is
+// the total of the replicas. The tokens are prefetched in batch to reduce the redis round trips, and the
+// requests are limited by the fallback mode when redis is unavailable.
+type RedisLimiter struct {
+	client  redis.UniversalClient
+	key     string
+	limit   float64
+	burst   int
+	o       *redisOptions
+	local   *rate.Limiter // the local approximation of the fallback
+	metrics *limiterMetrics
+
+	mu            sync.Mutex
+	tokens        int       // the prefetched tokens
+	tokensExpire  time.Time // the prefetched tokens are discarded after it
+	fallbackUntil time.Time // redis is not requested until it after an error
+}
+
+// NewRedisLimiter create a redis limiter of the key, limit is the number of tokens per second, burst is the
+// max number of tokens in the bucket.
+func NewRedisLimiter(client redis.UniversalClient, key string, limit float64, burst int, opts ...RedisOption) (*RedisLimiter, error) {
+	if client == nil || key == "" {
+		return nil, errors.New("redis client and key are required")
+	}
+	if limit <= 0 || burst <= 0 {
+		return nil, errors.New("limit and burst must be greater than 0")
+	}
+	o := defaultRedisOptions()
+	o.apply(opts...)
+	if o.prefetch > burst {
+		o.prefetch = burst
+	}
+
+	m, err := getLimiterMetrics(o.registerer)
+	if err != nil {
+		return nil, err
+	}
+
+	localBurst := burst / o.replicas
+	if localBurst < 1 {
+		localBurst = 1
+	}
+	return &RedisLimiter{
+		client:  client,
+		key:     key,
+		limit:   limit,
+		burst:   burst,
+		o:       o,
+		local:   rate.NewLimiter(rate.Limit(limit/float64(o.replicas)), localBurst),
+		metrics: m,
+	}, nil
+}
+
+// Allow take a token, ErrLimitExceed is returned if there is no token.
+func (l *RedisLimiter) Allow() (DoneFunc, error) {
+	ok, fallback := l.take()
+	if fallback {
+		l.metrics.fallback.WithLabelValues(l.o.name).Inc()
+	}
+	if !ok {
+		l.metrics.requests.WithLabelValues(l.o.name, resultDenied).Inc()
+		return nil, ErrLimitExceed
+	}
+	l.metrics.requests.WithLabelValues(l.o.name, resultAllowed).Inc()
+	return func(DoneInfo) {}, nil
+}
+
+// take return whether a token is taken, and whether it is decided by the fallback
+func (l *RedisLimiter) take() (bool, bool) {
+	now := time.Now()
+	l.mu.Lock()
+	if l.tokens > 0 && now.Before(l.tokensExpire) {
+		l.tokens--
+		l.mu.Unlock()
+		return true, false
+	}
+	l.tokens = 0
+	inFallback := now.Before(l.fallbackUntil)
+	l.mu.Unlock()
+
+	if inFallback {
+		return l.fallback(), true
+	}
+
+	// the redis request is not under the lock, the concurrent requests may prefetch more than one batch
+	ctx, cancel := context.WithTimeout(context.Background(), l.o.timeout)
+	granted, err := tokenBucketScript.Run(ctx, l.client, []string{l.key}, l.limit, l.burst, l.o.prefetch).Int()
+	cancel()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err != nil {
+		l.fallbackUntil = now.Add(l.o.retryInterval)
+		return l.fallback(), true
+	}
+	if granted <= 0 {
+		return false, false
+	}
+	l.tokens += granted - 1
+	l.tokensExpire = now.Add(l.o.prefetchTTL)
+	return true, false
+}
+
+func (l *RedisLimiter) fallback() bool {
+	switch l.o.fallback {
+	case FallbackOpen:
+		return true
+	case FallbackClosed:
+		return false
+	default:
+		return l.local.Allow()
+	}
+}
+
+// -------------------------------------------------------------------------------------------
+
+// RedisOption set the redis limiter options.
+type RedisOption func(*redisOptions)
+
+type redisOptions struct {
+	name          string
+	prefetch      int
+	prefetchTTL   time.Duration
+	timeout       time.Duration
+	fallback      FallbackMode
+	replicas      int
+	retryInterval time.Duration
+	registerer    prometheus.Registerer
+}
+
+func defaultRedisOptions() *redisOptions {
+	return &redisOptions{
+		name:          "default",
+		prefetch:      1,
+		prefetchTTL:   time.Second,
+		timeout:       100 * time.Millisecond,
+		fallback:      FallbackLocal,
+		replicas:      1,
+		retryInterval: time.Second,
+		registerer:    prometheus.DefaultRegisterer,
+	}
+}
+
+func (o *redisOptions) apply(opts ...RedisOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithName set the name of the limiter, used as the label of the metrics, default "default"
+func WithName(name string) RedisOption {
+	return func(o *redisOptions) {
+		if name != "" {
+			o.name = name
+		}
+	}
+}
+
+// WithPrefetch set the number of tokens taken from redis in one round trip, default 1 means no prefetch,
+// the larger the fewer round trips, but the tokens prefetched by a replica can not be used by the others.
+func WithPrefetch(n int) RedisOption {
+	return func(o *redisOptions) {
+		if n > 0 {
+			o.prefetch = n
+		}
+	}
+}
+
+// WithPrefetchTTL set the lifetime of the prefetched tokens, the unused ones are discarded, default 1s
+func WithPrefetchTTL(d time.Duration) RedisOption {
+	return func(o *redisOptions) {
+		if d > 0 {
+			o.prefetchTTL = d
+		}
+	}
+}
+
+// WithRedisTimeout set the timeout of a redis round trip, default 100ms
+func WithRedisTimeout(d time.Duration) RedisOption {
+	return func(o *redisOptions) {
+		if d > 0 {
+			o.timeout = d
+		}
+	}
+}
+
+// WithFallback set the behavior when redis is unavailable, default FallbackLocal
+func WithFallback(mode FallbackMode) RedisOption {
+	return func(o *redisOptions) {
+		o.fallback = mode
+	}
+}
+
+// WithReplicas set the number of the replicas, the local fallback limits each replica to rate/replicas, default 1
+func WithReplicas(n int) RedisOption {
+	return func(o *redisOptions) {
+		if n > 0 {
+			o.replicas = n
+		}
+	}
+}
+
+// WithRetryInterval set the interval of requesting redis again after an error, default 1s
+func WithRetryInterval(d time.Duration) RedisOption {
+	return func(o *redisOptions) {
+		if d > 0 {
+			o.retryInterval = d
+		}
+	}
+}
+
+// WithRegisterer set the registerer of the metrics, default prometheus.DefaultRegisterer, nil means not registered
+func WithRegisterer(reg prometheus.Registerer) RedisOption {
+	return func(o *redisOptions) {
+		o.registerer = reg
+	}
+}
+
+// -------------------------------------------------------------------------------------------
+
+const (
+	resultAllowed = "allowed"
+	resultDenied  = "denied"
+)
+
+type limiterMetrics struct {
+	requests *prometheus.CounterVec // limiter, result
+	fallback *prometheus.CounterVec // limiter
+}
+
+func getLimiterMetrics(reg prometheus.Registerer) (*limiterMetrics, error) {
+	m := &limiterMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ratelimit_requests_total",
+			Help: "Total number of the requests by result of the distributed rate limiter.",
+		}, []string{"limiter", "result"}),
+		fallback: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ratelimit_fallback_total",
+			Help: "Total number of the requests decided by the fallback when redis is unavailable.",
+		}, []string{"limiter"}),
+	}
+	if reg == nil {
+		return m, nil
+	}
+
+	// the metrics are shared by the limiters registered to the same registerer
+	var err error
+	if m.requests, err = register(reg, m.requests); err != nil {
+		return nil, err
+	}
+	if m.fallback, err = register(reg, m.fallback); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func register[T prometheus.Collector](reg prometheus.Registerer, c T) (T, error) {
+	if err := reg.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(T); ok {
+				return existing, nil
+			}
+		}
+		return c, err
+	}
+	return c, nil
+}
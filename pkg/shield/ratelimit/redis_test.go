@@ -0,0 +1,149 @@
+package ratelimit
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMiniredis(t *testing.T) (*miniredis.Miniredis, redis.UniversalClient) {
+	m := miniredis.RunT(t)
+	m.SetTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	client := redis.NewClient(&redis.Options{Addr: m.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return m, client
+}
+
+// roundTrips count the commands sent by the client
+type roundTrips struct {
+	n int32
+}
+
+func (h *roundTrips) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h *roundTrips) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		atomic.AddInt32(&h.n, 1)
+		return next(ctx, cmd)
+	}
+}
+
+func (h *roundTrips) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func (h *roundTrips) count() int {
+	return int(atomic.LoadInt32(&h.n))
+}
+
+func countAllowed(l Limiter, n int) int {
+	allowed := 0
+	for i := 0; i < n; i++ {
+		if done, err := l.Allow(); err == nil {
+			done(DoneInfo{})
+			allowed++
+		}
+	}
+	return allowed
+}
+
+func TestRedisLimiter_Window(t *testing.T) {
+	m, client := newMiniredis(t)
+	reg := prometheus.NewRegistry()
+	l, err := NewRedisLimiter(client, "ratelimit:api", 10, 20, WithName("api"), WithRegisterer(reg))
+	require.NoError(t, err)
+
+	// the burst is available at first
+	assert.Equal(t, 20, countAllowed(l, 30))
+	_, err = l.Allow()
+	assert.ErrorIs(t, err, ErrLimitExceed)
+
+	// refilled by the redis server time, 10 tokens per second
+	m.SetTime(time.Date(2024, 1, 1, 0, 0, 0, int(500*time.Millisecond), time.UTC))
+	assert.Equal(t, 5, countAllowed(l, 10))
+	m.SetTime(time.Date(2024, 1, 1, 0, 0, 1, int(500*time.Millisecond), time.UTC))
+	assert.Equal(t, 10, countAllowed(l, 20))
+	m.SetTime(time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC))
+	assert.Equal(t, 20, countAllowed(l, 30)) // not more than the burst
+
+	// the replicas share the bucket of the key
+	l2, err := NewRedisLimiter(client, "ratelimit:api", 10, 20, WithRegisterer(nil))
+	require.NoError(t, err)
+	assert.Equal(t, 0, countAllowed(l2, 10))
+
+	assert.Equal(t, float64(55), testutil.ToFloat64(l.metrics.requests.WithLabelValues("api", resultAllowed)))
+	assert.Equal(t, float64(36), testutil.ToFloat64(l.metrics.requests.WithLabelValues("api", resultDenied)))
+	assert.Equal(t, float64(0), testutil.ToFloat64(l.metrics.fallback.WithLabelValues("api")))
+}
+
+func TestRedisLimiter_Prefetch(t *testing.T) {
+	m, client := newMiniredis(t)
+	hook := &roundTrips{}
+	client.AddHook(hook)
+	l, err := NewRedisLimiter(client, "ratelimit:api", 1, 20, WithRegisterer(nil),
+		WithPrefetch(5), WithPrefetchTTL(50*time.Millisecond))
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, countAllowed(l, 1)) // load the script and prefetch 5 tokens
+	count := hook.count()
+	assert.Equal(t, 4, countAllowed(l, 4))
+	assert.Equal(t, count, hook.count()) // no round trip
+	assert.Equal(t, 5, countAllowed(l, 5))
+	assert.Equal(t, count+1, hook.count())
+
+	// the prefetched tokens are not available to the other replicas
+	l2, err := NewRedisLimiter(client, "ratelimit:api", 1, 20, WithRegisterer(nil), WithPrefetch(30))
+	require.NoError(t, err)
+	assert.Equal(t, 10, countAllowed(l2, 20))
+
+	// the unused prefetched tokens are discarded
+	m.SetTime(time.Date(2024, 1, 1, 0, 0, 10, 0, time.UTC)) // 10 tokens
+	assert.Equal(t, 1, countAllowed(l, 1))
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, 5, countAllowed(l, 10))
+
+	_, err = NewRedisLimiter(nil, "ratelimit:api", 1, 20)
+	assert.Error(t, err)
+	_, err = NewRedisLimiter(client, "ratelimit:api", 0, 20)
+	assert.Error(t, err)
+}
+
+func TestRedisLimiter_Fallback(t *testing.T) {
+	m, client := newMiniredis(t)
+	hook := &roundTrips{}
+	client.AddHook(hook)
+	newLimiter := func(mode FallbackMode) *RedisLimiter {
+		l, err := NewRedisLimiter(client, "ratelimit:api", 10, 10, WithRegisterer(nil),
+			WithFallback(mode), WithReplicas(2), WithRetryInterval(50*time.Millisecond))
+		require.NoError(t, err)
+		return l
+	}
+	local := newLimiter(FallbackLocal)
+	open := newLimiter(FallbackOpen)
+	closed := newLimiter(FallbackClosed)
+
+	m.SetError("ERR redis is unavailable")
+	assert.Equal(t, 5, countAllowed(local, 10)) // the burst of a replica is 10/2
+	assert.Equal(t, 10, countAllowed(open, 10))
+	assert.Equal(t, 0, countAllowed(closed, 10))
+	assert.Equal(t, float64(10), testutil.ToFloat64(local.metrics.fallback.WithLabelValues("default")))
+
+	// redis is not requested in the retry interval
+	m.SetError("")
+	count := hook.count()
+	assert.Equal(t, 0, countAllowed(closed, 1))
+	assert.Equal(t, count, hook.count())
+
+	// back to redis after the retry interval
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, 10, countAllowed(closed, 20))
+	assert.Greater(t, hook.count(), count)
+}
@@ -0,0 +1,341 @@
+package stat
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metric the name of the process metric
+type Metric string
+
+// the process metrics of the sample
+const (
+	MetricCPU        Metric = "cpu"        // process cpu usage, unit(%)
+	MetricRSS        Metric = "rss"        // use of physical memory, unit(M)
+	MetricGoroutines Metric = "goroutines" // number of goroutines
+	MetricGCPause    Metric = "gc_pause"   // the last gc pause, unit(s)
+	MetricOpenFDs    Metric = "open_fds"   // number of open file descriptors, 0 if not supported
+)
+
+// Sample the process metrics at a time
+type Sample struct {
+	Time       time.Time `json:"time"`
+	CPUUsage   float64   `json:"cpu_usage"`  // process cpu usage, unit(%)
+	RSS        uint64    `json:"rss"`        // use of physical memory, unit(M)
+	Goroutines int       `json:"goroutines"` // number of goroutines
+	GCPause    float64   `json:"gc_pause"`   // the last gc pause, unit(s)
+	OpenFDs    int       `json:"open_fds"`   // number of open file descriptors
+}
+
+// Value get the value of the metric
+func (s *Sample) Value(m Metric) float64 {
+	switch m {
+	case MetricCPU:
+		return s.CPUUsage
+	case MetricRSS:
+		return float64(s.RSS)
+	case MetricGoroutines:
+		return float64(s.Goroutines)
+	case MetricGCPause:
+		return s.GCPause
+	case MetricOpenFDs:
+		return float64(s.OpenFDs)
+	}
+	return 0
+}
+
+// CollectorOption set the collector options.
+type CollectorOption func(*collectorOptions)
+
+type collectorOptions struct {
+	sampler     func() *Sample
+	registerer  prometheus.Registerer
+	sampleSize  int
+	consecutive int
+	thresholds  []*threshold
+	alarmHook   func(m Metric, value float64)
+	sampleHooks []func(s *Sample)
+}
+
+func defaultCollectorOptions() *collectorOptions {
+	return &collectorOptions{
+		sampler:     defaultSampler,
+		registerer:  prometheus.DefaultRegisterer,
+		sampleSize:  60,
+		consecutive: 3,
+	}
+}
+
+func (o *collectorOptions) apply(opts ...CollectorOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithSampler set the sampler of the process metrics, default is sampling the current process
+func WithSampler(fn func() *Sample) CollectorOption {
+	return func(o *collectorOptions) {
+		if fn != nil {
+			o.sampler = fn
+		}
+	}
+}
+
+// WithRegisterer set the prometheus registerer of the gauges, default is prometheus.DefaultRegisterer,
+// nil means not registered.
+func WithRegisterer(reg prometheus.Registerer) CollectorOption {
+	return func(o *collectorOptions) {
+		o.registerer = reg
+	}
+}
+
+// WithSampleSize set the number of the last samples kept for querying, default 60
+func WithSampleSize(size int) CollectorOption {
+	return func(o *collectorOptions) {
+		if size > 0 {
+			o.sampleSize = size
+		}
+	}
+}
+
+// WithMetricThreshold set the alarm threshold of the metric with hysteresis, the alarm is triggered when the
+// value >= high for consecutive samples, and it is not triggered again until the value <= low.
+func WithMetricThreshold(m Metric, high float64, low float64) CollectorOption {
+	return func(o *collectorOptions) {
+		if low > high {
+			low = high
+		}
+		o.thresholds = append(o.thresholds, &threshold{metric: m, high: high, low: low})
+	}
+}
+
+// WithConsecutive set the number of the consecutive samples exceeding the threshold to trigger the alarm,
+// so a single spike doesn't trigger the alarm, default 3.
+func WithConsecutive(n int) CollectorOption {
+	return func(o *collectorOptions) {
+		if n > 0 {
+			o.consecutive = n
+		}
+	}
+}
+
+// WithAlarmHook set the hook called when the alarm of the metric threshold is triggered
+func WithAlarmHook(fn func(m Metric, value float64)) CollectorOption {
+	return func(o *collectorOptions) {
+		o.alarmHook = fn
+	}
+}
+
+// WithSampleHook add the hook called after each sample is recorded, e.g. capture profiles on high load
+func WithSampleHook(fn func(s *Sample)) CollectorOption {
+	return func(o *collectorOptions) {
+		if fn != nil {
+			o.sampleHooks = append(o.sampleHooks, fn)
+		}
+	}
+}
+
+type threshold struct {
+	metric    Metric
+	high, low float64
+
+	exceeded int  // the number of the consecutive samples exceeding the threshold
+	fired    bool // the alarm is triggered and not recovered
+}
+
+// check returns true if the alarm is triggered
+func (t *threshold) check(value float64, consecutive int) bool {
+	if t.fired {
+		if value <= t.low {
+			t.fired = false
+			t.exceeded = 0
+		}
+		return false
+	}
+
+	if value < t.high {
+		t.exceeded = 0
+		return false
+	}
+	t.exceeded++
+	if t.exceeded >= consecutive {
+		t.fired = true
+		return true
+	}
+	return false
+}
+
+var gaugeDescs = []struct {
+	metric Metric
+	desc   *prometheus.Desc
+	scale  float64
+}{
+	{MetricCPU, prometheus.NewDesc("stat_process_cpu_usage_percent", "Process cpu usage, unit(%).", nil, nil), 1},
+	{MetricRSS, prometheus.NewDesc("stat_process_rss_bytes", "Process resident memory size in bytes.", nil, nil), 1 << 20},
+	{MetricGoroutines, prometheus.NewDesc("stat_goroutines", "Number of goroutines.", nil, nil), 1},
+	{MetricGCPause, prometheus.NewDesc("stat_gc_last_pause_seconds", "The last gc pause in seconds.", nil, nil), 1},
+	{MetricOpenFDs, prometheus.NewDesc("stat_open_fds", "Number of open file descriptors.", nil, nil), 1},
+}
+
+// Collector collect the process metrics, export them to prometheus, keep the last samples
+// and trigger the alarm by the thresholds.
+type Collector struct {
+	opts *collectorOptions
+
+	mu      sync.Mutex
+	samples []Sample // ring buffer
+	next    int
+	full    bool
+}
+
+// NewCollector create a process metrics collector, the gauges are registered to the registerer.
+func NewCollector(opts ...CollectorOption) (*Collector, error) {
+	o := defaultCollectorOptions()
+	o.apply(opts...)
+
+	c := &Collector{opts: o, samples: make([]Sample, o.sampleSize)}
+	if o.registerer != nil {
+		if err := o.registerer.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Sample take a sample by the sampler and record it
+func (c *Collector) Sample() *Sample {
+	s := c.opts.sampler()
+	if s == nil {
+		return nil
+	}
+	c.Record(s)
+	return s
+}
+
+// Record the sample, and check the thresholds
+func (c *Collector) Record(s *Sample) {
+	if s.Time.IsZero() {
+		s.Time = time.Now()
+	}
+
+	c.mu.Lock()
+	c.samples[c.next] = *s
+	c.next = (c.next + 1) % len(c.samples)
+	if c.next == 0 {
+		c.full = true
+	}
+	var alarms []*threshold
+	for _, t := range c.opts.thresholds {
+		if t.check(s.Value(t.metric), c.opts.consecutive) {
+			alarms = append(alarms, t)
+		}
+	}
+	c.mu.Unlock()
+
+	if c.opts.alarmHook != nil {
+		for _, t := range alarms {
+			c.opts.alarmHook(t.metric, s.Value(t.metric))
+		}
+	}
+	for _, fn := range c.opts.sampleHooks {
+		fn(s)
+	}
+}
+
+// Samples get the last n samples from old to new, n <= 0 means all samples kept
+func (c *Collector) Samples(n int) []Sample {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var samples []Sample
+	if c.full {
+		samples = append(samples, c.samples[c.next:]...)
+	}
+	samples = append(samples, c.samples[:c.next]...)
+	if n > 0 && n < len(samples) {
+		samples = samples[len(samples)-n:]
+	}
+	return samples
+}
+
+func (c *Collector) last() (Sample, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.full && c.next == 0 {
+		return Sample{}, false
+	}
+	return c.samples[(c.next-1+len(c.samples))%len(c.samples)], true
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, g := range gaugeDescs {
+		ch <- g.desc
+	}
+}
+
+// Collect implements prometheus.Collector, the gauges are the values of the last sample
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	s, ok := c.last()
+	if !ok {
+		sp := c.Sample()
+		if sp == nil {
+			return
+		}
+		s = *sp
+	}
+	for _, g := range gaugeDescs {
+		ch <- prometheus.MustNewConstMetric(g.desc, prometheus.GaugeValue, s.Value(g.metric)*g.scale)
+	}
+}
+
+// ServeHTTP returns the last samples in json, the query parameter n limits the number of the samples,
+// e.g. /debug/stat?n=10
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n, _ := strconv.Atoi(r.URL.Query().Get("n"))
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"samples": c.Samples(n)})
+}
+
+var defaultCollector *Collector
+
+// GetCollector get the collector enabled by WithCollector, nil if it is not enabled
+func GetCollector() *Collector {
+	return defaultCollector
+}
+
+// Handler returns the last samples of the collector enabled by WithCollector in json, use for the debug endpoint.
+func Handler(w http.ResponseWriter, r *http.Request) {
+	if defaultCollector == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":"the stat collector is not enabled"}`))
+		return
+	}
+	defaultCollector.ServeHTTP(w, r)
+}
+
+func lastGCPause() float64 {
+	info := &runtime.MemStats{}
+	runtime.ReadMemStats(info)
+	if info.NumGC == 0 {
+		return 0
+	}
+	return time.Duration(info.PauseNs[(info.NumGC+255)%256]).Seconds()
+}
+
+// count the open file descriptors of the current process, 0 if not supported
+func openFDs() int {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return 0
+	}
+	return len(entries)
+}
@@ -0,0 +1,183 @@
+package stat
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSampler struct {
+	samples []*Sample
+	i       int
+}
+
+func (s *fakeSampler) sample() *Sample {
+	if s.i >= len(s.samples) {
+		return nil
+	}
+	sp := s.samples[s.i]
+	s.i++
+	return sp
+}
+
+func TestCollector_Gauges(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	fs := &fakeSampler{samples: []*Sample{
+		{CPUUsage: 12.5, RSS: 100, Goroutines: 20, GCPause: 0.002, OpenFDs: 8},
+		{CPUUsage: 30, RSS: 120, Goroutines: 25, GCPause: 0.001, OpenFDs: 9},
+	}}
+	c, err := NewCollector(WithSampler(fs.sample), WithRegisterer(reg))
+	require.NoError(t, err)
+
+	// sampling on scrape when there is no sample
+	expected := `
+# HELP stat_goroutines Number of goroutines.
+# TYPE stat_goroutines gauge
+stat_goroutines 20
+# HELP stat_process_cpu_usage_percent Process cpu usage, unit(%).
+# TYPE stat_process_cpu_usage_percent gauge
+stat_process_cpu_usage_percent 12.5
+# HELP stat_process_rss_bytes Process resident memory size in bytes.
+# TYPE stat_process_rss_bytes gauge
+stat_process_rss_bytes 1.048576e+08
+`
+	err = testutil.GatherAndCompare(reg, strings.NewReader(expected),
+		"stat_goroutines", "stat_process_cpu_usage_percent", "stat_process_rss_bytes")
+	assert.NoError(t, err)
+
+	// the gauges are the values of the last sample
+	c.Sample()
+	assert.Equal(t, 5, testutil.CollectAndCount(c))
+	expected = `
+# HELP stat_gc_last_pause_seconds The last gc pause in seconds.
+# TYPE stat_gc_last_pause_seconds gauge
+stat_gc_last_pause_seconds 0.001
+# HELP stat_open_fds Number of open file descriptors.
+# TYPE stat_open_fds gauge
+stat_open_fds 9
+`
+	err = testutil.GatherAndCompare(reg, strings.NewReader(expected), "stat_gc_last_pause_seconds", "stat_open_fds")
+	assert.NoError(t, err)
+
+	// registered repeatedly
+	_, err = NewCollector(WithSampler(fs.sample), WithRegisterer(reg))
+	assert.Error(t, err)
+}
+
+func TestCollector_Hysteresis(t *testing.T) {
+	type alarm struct {
+		m     Metric
+		value float64
+	}
+	var alarms []alarm
+	c, err := NewCollector(
+		WithRegisterer(nil),
+		WithMetricThreshold(MetricCPU, 80, 60),
+		WithMetricThreshold(MetricGoroutines, 100, 200), // low is corrected to high
+		WithConsecutive(3),
+		WithAlarmHook(func(m Metric, value float64) {
+			alarms = append(alarms, alarm{m, value})
+		}),
+	)
+	require.NoError(t, err)
+
+	cpus := []float64{
+		95, 10, // a single spike
+		85, 90, 91, // trigger
+		92, 70, 85, 88, 90, // not recovered, no repeated alarm
+		50,         // recovered
+		81, 82, 83, // trigger again
+	}
+	for _, v := range cpus {
+		c.Record(&Sample{CPUUsage: v, Goroutines: 10})
+	}
+	assert.Equal(t, []alarm{{MetricCPU, 91}, {MetricCPU, 83}}, alarms)
+
+	alarms = nil
+	for _, g := range []int{100, 150, 120, 110, 90, 100, 100, 100} {
+		c.Record(&Sample{Goroutines: g})
+	}
+	assert.Equal(t, []alarm{{MetricGoroutines, 120}, {MetricGoroutines, 100}}, alarms)
+}
+
+func TestCollector_Samples(t *testing.T) {
+	c, err := NewCollector(WithRegisterer(nil), WithSampleSize(3), WithSampler(func() *Sample { return nil }))
+	require.NoError(t, err)
+	assert.Empty(t, c.Samples(0))
+	assert.Nil(t, c.Sample())
+	assert.Equal(t, 0, testutil.CollectAndCount(c))
+
+	for i := 1; i <= 2; i++ {
+		c.Record(&Sample{Goroutines: i})
+	}
+	assert.Equal(t, []int{1, 2}, goroutines(c.Samples(0)))
+
+	for i := 3; i <= 7; i++ {
+		c.Record(&Sample{Goroutines: i})
+	}
+	assert.Equal(t, []int{5, 6, 7}, goroutines(c.Samples(0)))
+	assert.Equal(t, []int{6, 7}, goroutines(c.Samples(2)))
+	assert.Equal(t, []int{5, 6, 7}, goroutines(c.Samples(10)))
+	assert.False(t, c.Samples(1)[0].Time.IsZero())
+
+	// debug endpoint
+	w := httptest.NewRecorder()
+	c.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/stat?n=2", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Samples []Sample `json:"samples"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []int{6, 7}, goroutines(resp.Samples))
+}
+
+func TestCollector_SampleHook(t *testing.T) {
+	var got []int
+	c, err := NewCollector(
+		WithRegisterer(nil),
+		WithSampleHook(nil),
+		WithSampleHook(func(s *Sample) { got = append(got, s.Goroutines) }),
+		WithSampler(func() *Sample { return &Sample{Goroutines: 3} }),
+	)
+	require.NoError(t, err)
+	c.Record(&Sample{Goroutines: 1})
+	c.Sample()
+	assert.Equal(t, []int{1, 3}, got)
+}
+
+func TestHandler(t *testing.T) {
+	defer func() { defaultCollector = nil }()
+
+	w := httptest.NewRecorder()
+	Handler(w, httptest.NewRequest(http.MethodGet, "/debug/stat", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	c, err := NewCollector(WithRegisterer(nil))
+	require.NoError(t, err)
+	c.Record(&Sample{Goroutines: 1})
+	defaultCollector = c
+	w = httptest.NewRecorder()
+	Handler(w, httptest.NewRequest(http.MethodGet, "/debug/stat", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"goroutines":1`)
+}
+
+func Test_lastGCPauseAndOpenFDs(t *testing.T) {
+	assert.GreaterOrEqual(t, lastGCPause(), float64(0))
+	assert.GreaterOrEqual(t, openFDs(), 0)
+}
+
+func goroutines(samples []Sample) []int {
+	var gs []int
+	for _, s := range samples {
+		gs = append(gs, s.Goroutines)
+	}
+	return gs
+}
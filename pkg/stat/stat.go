@@ -23,8 +23,10 @@ var (
 type Option func(*options)
 
 type options struct {
-	enableAlarm bool
-	zapFields   []zap.Field
+	enableAlarm   bool
+	zapFields     []zap.Field
+	collectorOpts []CollectorOption
+	withCollector bool
 }
 
 func (o *options) apply(opts ...Option) {
@@ -72,11 +74,30 @@ func WithAlarm(opts ...AlarmOption) Option {
 	}
 }
 
+// WithCollector enable the process metrics collector, the gauges are exported to prometheus,
+// the last samples can be queried by Handler.
+func WithCollector(opts ...CollectorOption) Option {
+	return func(o *options) {
+		o.withCollector = true
+		o.collectorOpts = opts
+	}
+}
+
 // Init initialize statistical information
 func Init(opts ...Option) {
 	o := &options{}
 	o.apply(opts...)
 
+	if o.withCollector {
+		c, err := NewCollector(o.collectorOpts...)
+		if err != nil {
+			zapLog.Warn("stat collector is not enabled", zap.Error(err))
+		} else {
+			defaultCollector = c
+		}
+	}
+	collector := defaultCollector
+
 	//nolint
 	go func() {
 		printTick := time.NewTicker(printInfoInterval)
@@ -87,6 +108,12 @@ func Init(opts ...Option) {
 			select {
 			case <-printTick.C:
 				data := printUsageInfo(o.zapFields...)
+				if data == nil {
+					continue
+				}
+				if collector != nil {
+					collector.Record(data.sample())
+				}
 				if o.enableAlarm {
 					if sg.check(data) {
 						sendSystemSignForLinux()
@@ -168,3 +195,27 @@ type statData struct {
 	sys  system
 	proc process
 }
+
+func (d *statData) sample() *Sample {
+	return &Sample{
+		Time:       time.Now(),
+		CPUUsage:   d.proc.CPUUsage,
+		RSS:        d.proc.RSS,
+		Goroutines: d.proc.Goroutines,
+		GCPause:    lastGCPause(),
+		OpenFDs:    openFDs(),
+	}
+}
+
+// the default sampler of the collector, sampling the current process
+func defaultSampler() *Sample {
+	cProc := cpu.GetProcess()
+	return &Sample{
+		Time:       time.Now(),
+		CPUUsage:   cProc.UsagePercent,
+		RSS:        cProc.RSS,
+		Goroutines: runtime.NumGoroutine(),
+		GCPause:    lastGCPause(),
+		OpenFDs:    openFDs(),
+	}
+}
@@ -0,0 +1,85 @@
+// Package statemachine provides a guarded state machine of the lifecycle status of records, the allowed
+// transitions are configured per resource, e.g. draft -> active -> archived.
+package statemachine
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrConflict the status of the record was changed by another request, e.g. the conditional update
+// "WHERE status = expected" affected no rows
+var ErrConflict = errors.New("status conflict")
+
+// TransitionError the transition is not allowed by the state machine
+type TransitionError struct {
+	Resource string
+	From     int
+	To       int
+	Allowed  []int // the allowed next states of From
+}
+
+// Error the error message
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("illegal status transition, resource=%s, from=%d, to=%d, allowed=%v", e.Resource, e.From, e.To, e.Allowed)
+}
+
+// Machine the allowed status transitions of a resource, it is read only after created and safe for
+// concurrent use
+type Machine struct {
+	resource    string
+	transitions map[int][]int
+}
+
+// New create a state machine of the resource, transitions is the allowed next states of every state,
+// a state without an entry is terminal.
+func New(resource string, transitions map[int][]int) *Machine {
+	m := &Machine{resource: resource, transitions: make(map[int][]int, len(transitions))}
+	for from, tos := range transitions {
+		next := make([]int, 0, len(tos))
+		for _, to := range tos {
+			if to != from && !contains(next, to) {
+				next = append(next, to)
+			}
+		}
+		sort.Ints(next)
+		m.transitions[from] = next
+	}
+	return m
+}
+
+// Resource the name of the resource
+func (m *Machine) Resource() string {
+	return m.resource
+}
+
+// Next the allowed next states of the state, in ascending order
+func (m *Machine) Next(from int) []int {
+	next := m.transitions[from]
+	out := make([]int, len(next))
+	copy(out, next)
+	return out
+}
+
+// Can report whether the transition is allowed, staying in the same state is always allowed
+func (m *Machine) Can(from int, to int) bool {
+	return from == to || contains(m.transitions[from], to)
+}
+
+// Check return a *TransitionError if the transition is not allowed
+func (m *Machine) Check(from int, to int) error {
+	if m.Can(from, to) {
+		return nil
+	}
+	return &TransitionError{Resource: m.resource, From: from, To: to, Allowed: m.Next(from)}
+}
+
+func contains(values []int, v int) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package statemachine
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	draft    = 1
+	active   = 2
+	archived = 3
+)
+
+func newMachine() *Machine {
+	return New("post", map[int][]int{
+		draft:    {active, archived, active},
+		active:   {archived},
+		archived: {active},
+	})
+}
+
+func TestMachine_Can(t *testing.T) {
+	m := newMachine()
+	assert.Equal(t, "post", m.Resource())
+
+	assert.True(t, m.Can(draft, active))
+	assert.True(t, m.Can(active, archived))
+	assert.True(t, m.Can(archived, active))
+	assert.True(t, m.Can(active, active))
+
+	assert.False(t, m.Can(active, draft))
+	assert.False(t, m.Can(archived, draft))
+	assert.False(t, m.Can(4, active)) // unknown state
+}
+
+func TestMachine_Next(t *testing.T) {
+	m := newMachine()
+	assert.Equal(t, []int{active, archived}, m.Next(draft))
+	assert.Equal(t, []int{}, m.Next(4))
+
+	// the returned slice is a copy
+	next := m.Next(draft)
+	next[0] = 100
+	assert.Equal(t, []int{active, archived}, m.Next(draft))
+}
+
+func TestMachine_Check(t *testing.T) {
+	m := newMachine()
+	assert.NoError(t, m.Check(draft, active))
+
+	err := m.Check(archived, draft)
+	var e *TransitionError
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, archived, e.From)
+	assert.Equal(t, draft, e.To)
+	assert.Equal(t, []int{active}, e.Allowed)
+	assert.Contains(t, err.Error(), "illegal status transition")
+}
@@ -0,0 +1,166 @@
+package tracer
+
+import (
+	"errors"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const gormSpanKey = "tracer:span"
+
+var _ gorm.Plugin = (*GormPlugin)(nil)
+
+// GormPlugin create a span for every statement, the statement is redacted, the literal values are replaced by '?'
+type GormPlugin struct{}
+
+// NewGormPlugin create a gorm plugin, usage: db.Use(tracer.NewGormPlugin())
+func NewGormPlugin() *GormPlugin {
+	return &GormPlugin{}
+}
+
+// Name the name of the plugin
+func (p *GormPlugin) Name() string {
+	return "tracer"
+}
+
+// Initialize register the callbacks
+func (p *GormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	// the processor type of gorm is not exported, so the Register methods are used
+	hooks := []struct {
+		operation string
+		before    func(name string, fn func(*gorm.DB)) error
+		after     func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	}
+	for _, h := range hooks {
+		if err := h.before("tracer:before_"+h.operation, p.before(h.operation)); err != nil {
+			return err
+		}
+		if err := h.after("tracer:after_"+h.operation, p.after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *GormPlugin) before(operation string) func(*gorm.DB) {
+	spanName := "gorm." + operation
+	return func(db *gorm.DB) {
+		ctx, span := otel.Tracer(traceName).Start(db.Statement.Context, spanName, trace.WithSpanKind(trace.SpanKindClient))
+		db.Statement.Context = ctx
+		db.InstanceSet(gormSpanKey, span)
+	}
+}
+
+func (p *GormPlugin) after(db *gorm.DB) {
+	v, ok := db.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span, ok := v.(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	if span.IsRecording() {
+		attrs := []attribute.KeyValue{
+			attribute.String("db.system", db.Dialector.Name()),
+			attribute.String("db.statement", truncate(RedactSQL(db.Statement.SQL.String()))),
+			attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+		}
+		if db.Statement.Table != "" {
+			attrs = append(attrs, attribute.String("db.sql.table", db.Statement.Table))
+		}
+		span.SetAttributes(attrs...)
+	}
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		recordError(span, db.Error)
+	}
+}
+
+// RedactSQL replace the string and number literals in the sql with '?', and collapse the consecutive
+// placeholders, e.g. "WHERE name = 'foo' AND id IN (1, 2, 3)" --> "WHERE name = ? AND id IN (?)"
+func RedactSQL(sql string) string {
+	var b strings.Builder
+	b.Grow(len(sql))
+	last := -1 // the length of b after the last '?'
+	writePlaceholder := func() {
+		// collapse the list of placeholders, e.g. "?, ?, ?" into "?"
+		if last >= 0 {
+			if gap := strings.TrimSpace(b.String()[last:]); gap == "," || gap == "" {
+				s := b.String()[:last]
+				b.Reset()
+				b.WriteString(s)
+				return
+			}
+		}
+		b.WriteByte('?')
+		last = b.Len()
+	}
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == '\'': // string literal, '' and \' are escapes
+			j := i + 1
+			for j < len(sql) {
+				if sql[j] == '\\' {
+					j += 2
+					continue
+				}
+				if sql[j] == '\'' {
+					if j+1 < len(sql) && sql[j+1] == '\'' {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			writePlaceholder()
+			i = j + 1
+		case c == '`' || c == '"': // quoted identifier
+			end := len(sql)
+			if j := strings.IndexByte(sql[i+1:], c); j >= 0 {
+				end = i + j + 2
+			}
+			b.WriteString(sql[i:end])
+			i = end
+		case c == '?':
+			writePlaceholder()
+			i++
+		case isDigit(c) && (i == 0 || !isIdentChar(sql[i-1])):
+			j := i
+			for j < len(sql) && (isDigit(sql[j]) || sql[j] == '.' || sql[j] == 'e' || sql[j] == 'E' || sql[j] == 'x' ||
+				(sql[j] >= 'a' && sql[j] <= 'f') || (sql[j] >= 'A' && sql[j] <= 'F')) {
+				j++
+			}
+			writePlaceholder()
+			i = j
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentChar(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_' || c == '$' || c == '.' || c >= 0x80
+}
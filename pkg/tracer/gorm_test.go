@@ -0,0 +1,77 @@
+package tracer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type userExample struct {
+	ID    uint64 `gorm:"primaryKey"`
+	Name  string
+	Email string
+}
+
+func TestGormPlugin(t *testing.T) {
+	recorder := newRecorder(t)
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(NewGormPlugin()))
+	require.NoError(t, db.AutoMigrate(&userExample{}))
+
+	// HTTP --> dao --> gorm
+	ctx, endHTTP := Start(context.Background(), "HTTP POST /api/v1/userExample")
+	daoCtx, endDao := Start(ctx, "dao.userExample.Create")
+	err = db.WithContext(daoCtx).Create(&userExample{Name: "foo", Email: "foo@bar.com"}).Error
+	endDao(&err)
+	endHTTP(nil)
+	require.NoError(t, err)
+
+	httpSpan := findSpan(t, recorder, "HTTP POST /api/v1/userExample")
+	daoSpan := findSpan(t, recorder, "dao.userExample.Create")
+	gormSpan := findSpan(t, recorder, "gorm.create")
+	assert.Equal(t, httpSpan.SpanContext().SpanID(), daoSpan.Parent().SpanID())
+	assert.Equal(t, daoSpan.SpanContext().SpanID(), gormSpan.Parent().SpanID())
+	assert.Equal(t, "sqlite", spanAttr(gormSpan, "db.system").AsString())
+	assert.Equal(t, "user_examples", spanAttr(gormSpan, "db.sql.table").AsString())
+	assert.Equal(t, int64(1), spanAttr(gormSpan, "db.rows_affected").AsInt64())
+	assert.NotContains(t, spanAttr(gormSpan, "db.statement").AsString(), "foo@bar.com")
+
+	// the record not found is not an error
+	err = db.WithContext(ctx).Where("id = ?", 100).First(&userExample{}).Error
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	querySpan := findSpan(t, recorder, "gorm.query")
+	assert.Equal(t, codes.Unset, querySpan.Status().Code)
+
+	// the error status propagates to the dao span
+	daoCtx, endDao = Start(ctx, "dao.userExample.Raw")
+	err = db.WithContext(daoCtx).Exec("SELECT * FROM not_exists WHERE name = 'secret'").Error
+	endDao(&err)
+	assert.Error(t, err)
+	rawSpan := findSpan(t, recorder, "gorm.raw")
+	assert.Equal(t, codes.Error, rawSpan.Status().Code)
+	assert.Equal(t, "SELECT * FROM not_exists WHERE name = ?", spanAttr(rawSpan, "db.statement").AsString())
+	assert.Equal(t, codes.Error, findSpan(t, recorder, "dao.userExample.Raw").Status().Code)
+}
+
+func TestRedactSQL(t *testing.T) {
+	tests := map[string]string{
+		"SELECT * FROM `users` WHERE `id` = 1 LIMIT 10":                  "SELECT * FROM `users` WHERE `id` = ? LIMIT ?",
+		"SELECT * FROM users WHERE name = 'it''s' AND note = 'a\\'b'":    "SELECT * FROM users WHERE name = ? AND note = ?",
+		"SELECT * FROM users WHERE id IN (1, 2, 3) AND age > 18.5":       "SELECT * FROM users WHERE id IN (?) AND age > ?",
+		"INSERT INTO `users` (`name`,`age`) VALUES (?,?),(?,?)":          "INSERT INTO `users` (`name`,`age`) VALUES (?),(?)",
+		`SELECT "t1"."col2" FROM "t1" WHERE "t1"."v1" = $1 AND x = 0x1F`: `SELECT "t1"."col2" FROM "t1" WHERE "t1"."v1" = $1 AND x = ?`,
+		"UPDATE users SET name = ?, age = ? WHERE id = ?":                "UPDATE users SET name = ?, age = ? WHERE id = ?",
+		"SELECT * FROM t2 WHERE name = 'unterminated":                    "SELECT * FROM t2 WHERE name = ?",
+		"": "",
+	}
+	for sql, want := range tests {
+		assert.Equal(t, want, RedactSQL(sql), sql)
+	}
+}
@@ -0,0 +1,81 @@
+package tracer
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type mongoMonitor struct {
+	next  *event.CommandMonitor
+	spans sync.Map // connection id + request id --> trace.Span
+}
+
+// NewMongoMonitor create a command monitor that creates a span for every command, the documents of the
+// command are not recorded, only the command name and collection. The events are passed to next if it is
+// not nil, e.g. the monitor collecting the metrics.
+// usage: mgo.Init(dsn, mgo.WithOption().SetMonitor(tracer.NewMongoMonitor(nil)))
+func NewMongoMonitor(next *event.CommandMonitor) *event.CommandMonitor {
+	m := &mongoMonitor{next: next}
+	return &event.CommandMonitor{
+		Started:   m.started,
+		Succeeded: m.succeeded,
+		Failed:    m.failed,
+	}
+}
+
+func mongoSpanKey(connectionID string, requestID int64) string {
+	return connectionID + "/" + strconv.FormatInt(requestID, 10)
+}
+
+func (m *mongoMonitor) started(ctx context.Context, evt *event.CommandStartedEvent) {
+	_, span := otel.Tracer(traceName).Start(ctx, "mongo."+evt.CommandName, trace.WithSpanKind(trace.SpanKindClient))
+	if span.IsRecording() {
+		statement := evt.CommandName
+		attrs := []attribute.KeyValue{
+			attribute.String("db.system", "mongodb"),
+			attribute.String("db.name", evt.DatabaseName),
+			attribute.String("db.operation", evt.CommandName),
+		}
+		// the value of the command name is the collection for the crud commands, e.g. {"find": "users", ...}
+		if collection, ok := evt.Command.Lookup(evt.CommandName).StringValueOK(); ok {
+			statement += " " + collection
+			attrs = append(attrs, attribute.String("db.mongodb.collection", collection))
+		}
+		attrs = append(attrs, attribute.String("db.statement", truncate(statement)))
+		span.SetAttributes(attrs...)
+	}
+	m.spans.Store(mongoSpanKey(evt.ConnectionID, evt.RequestID), span)
+
+	if m.next != nil && m.next.Started != nil {
+		m.next.Started(ctx, evt)
+	}
+}
+
+func (m *mongoMonitor) succeeded(ctx context.Context, evt *event.CommandSucceededEvent) {
+	if v, ok := m.spans.LoadAndDelete(mongoSpanKey(evt.ConnectionID, evt.RequestID)); ok {
+		v.(trace.Span).End()
+	}
+
+	if m.next != nil && m.next.Succeeded != nil {
+		m.next.Succeeded(ctx, evt)
+	}
+}
+
+func (m *mongoMonitor) failed(ctx context.Context, evt *event.CommandFailedEvent) {
+	if v, ok := m.spans.LoadAndDelete(mongoSpanKey(evt.ConnectionID, evt.RequestID)); ok {
+		span := v.(trace.Span)
+		recordError(span, errors.New(evt.Failure))
+		span.End()
+	}
+
+	if m.next != nil && m.next.Failed != nil {
+		m.next.Failed(ctx, evt)
+	}
+}
@@ -0,0 +1,44 @@
+package tracer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel/codes"
+)
+
+func TestMongoMonitor(t *testing.T) {
+	recorder := newRecorder(t)
+	var nextCalls int
+	next := &event.CommandMonitor{
+		Started:   func(context.Context, *event.CommandStartedEvent) { nextCalls++ },
+		Succeeded: func(context.Context, *event.CommandSucceededEvent) { nextCalls++ },
+		Failed:    func(context.Context, *event.CommandFailedEvent) { nextCalls++ },
+	}
+	monitor := NewMongoMonitor(next)
+
+	ctx, end := Start(context.Background(), "dao.userExample.GetByID")
+	cmd, _ := bson.Marshal(bson.D{{Key: "find", Value: "userExample"}, {Key: "filter", Value: bson.D{{Key: "email", Value: "foo@bar.com"}}}})
+	monitor.Started(ctx, &event.CommandStartedEvent{Command: cmd, DatabaseName: "account", CommandName: "find", RequestID: 1, ConnectionID: "c1"})
+	monitor.Succeeded(ctx, &event.CommandSucceededEvent{CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "find", RequestID: 1, ConnectionID: "c1"}})
+
+	cmd, _ = bson.Marshal(bson.D{{Key: "insert", Value: "userExample"}})
+	monitor.Started(ctx, &event.CommandStartedEvent{Command: cmd, DatabaseName: "account", CommandName: "insert", RequestID: 2, ConnectionID: "c1"})
+	monitor.Failed(ctx, &event.CommandFailedEvent{CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "insert", RequestID: 2, ConnectionID: "c1"},
+		Failure: "E11000 duplicate key error"})
+	end(nil)
+
+	dao := findSpan(t, recorder, "dao.userExample.GetByID")
+	find := findSpan(t, recorder, "mongo.find")
+	assert.Equal(t, dao.SpanContext().SpanID(), find.Parent().SpanID())
+	assert.Equal(t, "find userExample", spanAttr(find, "db.statement").AsString()) // the filter is not recorded
+	assert.Equal(t, "userExample", spanAttr(find, "db.mongodb.collection").AsString())
+	assert.Equal(t, "account", spanAttr(find, "db.name").AsString())
+	insert := findSpan(t, recorder, "mongo.insert")
+	assert.Equal(t, codes.Error, insert.Status().Code)
+	assert.Equal(t, "E11000 duplicate key error", insert.Status().Description)
+	assert.Equal(t, 4, nextCalls)
+}
@@ -0,0 +1,83 @@
+package tracer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var _ redis.Hook = redisHook{}
+
+type redisHook struct{}
+
+// NewRedisHook create a redis hook that creates a span for every command and pipeline, only the command
+// name and the key are recorded, the values are not. usage: redisClient.AddHook(tracer.NewRedisHook())
+func NewRedisHook() redis.Hook {
+	return redisHook{}
+}
+
+func (redisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (redisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		ctx, span := otel.Tracer(traceName).Start(ctx, "redis."+cmd.Name(), trace.WithSpanKind(trace.SpanKindClient))
+		defer span.End()
+		if span.IsRecording() {
+			span.SetAttributes(
+				attribute.String("db.system", "redis"),
+				attribute.String("db.operation", cmd.Name()),
+				attribute.String("db.statement", truncate(redisStatement(cmd))),
+			)
+		}
+
+		err := next(ctx, cmd)
+		if err != nil && !errors.Is(err, redis.Nil) {
+			recordError(span, err)
+		}
+		return err
+	}
+}
+
+func (redisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		ctx, span := otel.Tracer(traceName).Start(ctx, "redis.pipeline", trace.WithSpanKind(trace.SpanKindClient))
+		defer span.End()
+		if span.IsRecording() {
+			statements := make([]string, 0, len(cmds))
+			for _, cmd := range cmds {
+				statements = append(statements, redisStatement(cmd))
+			}
+			span.SetAttributes(
+				attribute.String("db.system", "redis"),
+				attribute.Int("db.redis.num_cmd", len(cmds)),
+				attribute.String("db.statement", truncate(strings.Join(statements, "\n"))),
+			)
+		}
+
+		err := next(ctx, cmds)
+		if err != nil && !errors.Is(err, redis.Nil) {
+			recordError(span, err)
+		}
+		return err
+	}
+}
+
+// redisStatement the command name and the first argument, which is the key of most commands
+func redisStatement(cmd redis.Cmder) string {
+	args := cmd.Args()
+	if len(args) < 2 {
+		return cmd.Name()
+	}
+	return cmd.Name() + " " + fmt.Sprint(args[1])
+}
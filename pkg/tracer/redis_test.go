@@ -0,0 +1,43 @@
+package tracer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+)
+
+func TestRedisHook(t *testing.T) {
+	recorder := newRecorder(t)
+	m := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: m.Addr()})
+	defer client.Close() //nolint
+	client.AddHook(NewRedisHook())
+
+	ctx, end := Start(context.Background(), "cache.userExample.Set")
+	require.NoError(t, client.Set(ctx, "user:1", "secret value", 0).Err())
+	assert.ErrorIs(t, client.Get(ctx, "user:2").Err(), redis.Nil)
+	assert.Error(t, client.Incr(ctx, "user:1").Err())
+	_, err := client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		p.Get(ctx, "user:1")
+		p.Del(ctx, "user:1")
+		return nil
+	})
+	require.NoError(t, err)
+	end(nil)
+
+	parent := findSpan(t, recorder, "cache.userExample.Set")
+	set := findSpan(t, recorder, "redis.set")
+	assert.Equal(t, parent.SpanContext().SpanID(), set.Parent().SpanID())
+	assert.Equal(t, "set user:1", spanAttr(set, "db.statement").AsString()) // the value is not recorded
+	assert.Equal(t, codes.Unset, findSpan(t, recorder, "redis.get").Status().Code)
+	assert.Equal(t, codes.Error, findSpan(t, recorder, "redis.incr").Status().Code)
+
+	pipeline := findSpan(t, recorder, "redis.pipeline")
+	assert.Equal(t, int64(2), spanAttr(pipeline, "db.redis.num_cmd").AsInt64())
+	assert.Equal(t, "get user:1\ndel user:1", spanAttr(pipeline, "db.statement").AsString())
+}
@@ -0,0 +1,89 @@
+package tracer
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	maxAttributes     = 32
+	maxAttributeValue = 512
+)
+
+// EndFunc end the span, the error is recorded if errp is not nil and *errp is not nil
+type EndFunc func(errp *error)
+
+// SetAttributeLimits set the max number of attributes added by Start and the max length of a string
+// attribute value added by Start and the instrumentations, the longer value is truncated, default 32 and 512.
+func SetAttributeLimits(maxCount int, maxValueLen int) {
+	if maxCount > 0 {
+		maxAttributes = maxCount
+	}
+	if maxValueLen > 0 {
+		maxAttributeValue = maxValueLen
+	}
+}
+
+// Start create a span as a child of the span in ctx, the attributes are only added if the span is sampled.
+// example:
+//
+//	func (d *userExampleDao) GetByID(ctx context.Context, id uint64) (_ *model.UserExample, err error) {
+//		ctx, end := tracer.Start(ctx, "dao.userExample.GetByID", attribute.Int64("id", int64(id)))
+//		defer end(&err)
+//		...
+//	}
+func Start(ctx context.Context, spanName string, attrs ...attribute.KeyValue) (context.Context, EndFunc) {
+	ctx, span := otel.Tracer(traceName).Start(ctx, spanName)
+	if span.IsRecording() && len(attrs) > 0 {
+		if len(attrs) > maxAttributes {
+			attrs = attrs[:maxAttributes]
+		}
+		span.SetAttributes(limitAttributes(attrs)...)
+	}
+
+	return ctx, func(errp *error) {
+		if errp != nil {
+			recordError(span, *errp)
+		}
+		span.End()
+	}
+}
+
+// recordError record the error and set the status of the span to error
+func recordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, truncate(err.Error()))
+}
+
+// limitAttributes truncate the long string values, the attrs of the caller is not modified
+func limitAttributes(attrs []attribute.KeyValue) []attribute.KeyValue {
+	out := attrs
+	for i, attr := range attrs {
+		if attr.Value.Type() == attribute.STRING && len(attr.Value.AsString()) > maxAttributeValue {
+			if &out[0] == &attrs[0] {
+				out = append([]attribute.KeyValue{}, attrs...)
+			}
+			out[i] = attribute.String(string(attr.Key), truncate(attr.Value.AsString()))
+		}
+	}
+	return out
+}
+
+func truncate(s string) string {
+	if len(s) <= maxAttributeValue {
+		return s
+	}
+	// not cut in the middle of a utf-8 character
+	n := maxAttributeValue
+	for n > 0 && s[n]&0xC0 == 0x80 {
+		n--
+	}
+	return s[:n] + "..."
+}
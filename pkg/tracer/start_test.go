@@ -0,0 +1,110 @@
+package tracer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// newRecorder set the global tracer provider to record the ended spans in memory
+func newRecorder(t *testing.T, sampler ...sdktrace.Sampler) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	opts := []sdktrace.TracerProviderOption{sdktrace.WithSpanProcessor(recorder)}
+	if len(sampler) > 0 {
+		opts = append(opts, sdktrace.WithSampler(sampler[0]))
+	}
+	provider := sdktrace.NewTracerProvider(opts...)
+	old := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(old) })
+	return recorder
+}
+
+// findSpan get the last ended span by name
+func findSpan(t *testing.T, recorder *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	spans := recorder.Ended()
+	for i := len(spans) - 1; i >= 0; i-- {
+		if spans[i].Name() == name {
+			return spans[i]
+		}
+	}
+	require.Failf(t, "span not found", name)
+	return nil
+}
+
+func spanAttr(span sdktrace.ReadOnlySpan, key string) attribute.Value {
+	for _, attr := range span.Attributes() {
+		if string(attr.Key) == key {
+			return attr.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestStart(t *testing.T) {
+	recorder := newRecorder(t)
+
+	getByID := func(ctx context.Context, id int64) (err error) {
+		ctx, end := Start(ctx, "dao.userExample.GetByID", attribute.Int64("id", id))
+		defer end(&err)
+		_, end2 := Start(ctx, "cache.userExample.Get")
+		end2(nil)
+		if id == 0 {
+			return errors.New("id cannot be 0")
+		}
+		return nil
+	}
+	ctx, end := Start(context.Background(), "HTTP GET /api/v1/userExample/:id")
+	assert.NoError(t, getByID(ctx, 1))
+	assert.Error(t, getByID(ctx, 0))
+	end(nil)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 5)
+	root := spans[4]
+	assert.Equal(t, "HTTP GET /api/v1/userExample/:id", root.Name())
+	for _, span := range spans[:4] {
+		assert.Equal(t, root.SpanContext().TraceID(), span.SpanContext().TraceID())
+	}
+	assert.Equal(t, root.SpanContext().SpanID(), spans[1].Parent().SpanID())
+	assert.Equal(t, spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.Equal(t, int64(1), spanAttr(spans[1], "id").AsInt64())
+	assert.Equal(t, codes.Unset, spans[1].Status().Code)
+	assert.Equal(t, codes.Error, spans[3].Status().Code)
+	assert.Equal(t, "id cannot be 0", spans[3].Status().Description)
+	assert.Len(t, spans[3].Events(), 1) // the error event
+	assert.Equal(t, codes.Unset, root.Status().Code)
+}
+
+func TestStart_Limits(t *testing.T) {
+	recorder := newRecorder(t)
+	SetAttributeLimits(2, 8)
+	defer SetAttributeLimits(32, 512)
+
+	attrs := []attribute.KeyValue{
+		attribute.String("a", strings.Repeat("中", 5)),
+		attribute.String("b", "short"),
+		attribute.String("c", "dropped"),
+	}
+	_, end := Start(context.Background(), "limits", attrs...)
+	end(nil)
+	span := findSpan(t, recorder, "limits")
+	assert.Len(t, span.Attributes(), 2)
+	assert.Equal(t, "中中...", spanAttr(span, "a").AsString()) // not cut in the middle of a character
+	assert.Equal(t, strings.Repeat("中", 5), attrs[0].Value.AsString())
+
+	// the attributes are not added to the span not sampled
+	recorder = newRecorder(t, sdktrace.NeverSample())
+	_, end = Start(context.Background(), "not sampled", attrs...)
+	end(nil)
+	assert.Empty(t, recorder.Ended())
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrBudgetExhausted the remaining deadline budget of the request is less than the floor of a call, the call is
+// not issued, e.g. the client has given up waiting
+var ErrBudgetExhausted = errors.New("deadline budget exhausted")
+
+var callBudget = struct {
+	mu       sync.RWMutex
+	fraction float64
+	floor    time.Duration
+}{fraction: 0.8, floor: 50 * time.Millisecond}
+
+// SetCallBudget set the fraction of the remaining deadline budget used by a call and the floor of the timeout of
+// a call, default 0.8 and 50ms, the fraction not in (0, 1] and the negative floor are ignored
+func SetCallBudget(fraction float64, floor time.Duration) {
+	callBudget.mu.Lock()
+	defer callBudget.mu.Unlock()
+	if fraction > 0 && fraction <= 1 {
+		callBudget.fraction = fraction
+	}
+	if floor >= 0 {
+		callBudget.floor = floor
+	}
+}
+
+// CallTimeout returns the timeout of a call, e.g. a database query or an outbound request, it is the fraction of
+// the remaining deadline budget of ctx and not less than the floor, see SetCallBudget, so the rest of the budget is
+// left to write the response. 0 is returned if ctx has no deadline, ErrBudgetExhausted is returned if the remaining
+// budget is less than the floor.
+func CallTimeout(ctx context.Context) (time.Duration, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0, nil
+	}
+
+	callBudget.mu.RLock()
+	fraction, floor := callBudget.fraction, callBudget.floor
+	callBudget.mu.RUnlock()
+
+	remaining := time.Until(deadline)
+	if remaining <= 0 || remaining < floor {
+		return 0, ErrBudgetExhausted
+	}
+	timeout := time.Duration(float64(remaining) * fraction)
+	if timeout < floor {
+		timeout = floor
+	}
+	return timeout, nil
+}
+
+// CallContext returns the context of a call with the timeout of CallTimeout, ctx is returned as it is if it has
+// no deadline, call cancel after the call.
+func CallContext(ctx context.Context) (context.Context, context.CancelFunc, error) {
+	timeout, err := CallTimeout(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if timeout == 0 {
+		return ctx, func() {}, nil
+	}
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	return callCtx, cancel, nil
+}
+
+// IsBudgetExhausted report whether the error is caused by the exhausted deadline budget, it is ErrBudgetExhausted,
+// or the deadline of the call or the request is exceeded
+func IsBudgetExhausted(err error) bool {
+	return errors.Is(err, ErrBudgetExhausted) || errors.Is(err, context.DeadlineExceeded)
+}
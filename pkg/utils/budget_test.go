@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallTimeout(t *testing.T) {
+	// no deadline
+	timeout, err := CallTimeout(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), timeout)
+
+	// the fraction of the remaining budget
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	timeout, err = CallTimeout(ctx)
+	assert.NoError(t, err)
+	assert.True(t, timeout > 1500*time.Millisecond && timeout <= 1600*time.Millisecond, timeout)
+
+	// the floor
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 55*time.Millisecond)
+	defer cancel2()
+	timeout, err = CallTimeout(ctx2)
+	assert.NoError(t, err)
+	assert.Equal(t, 50*time.Millisecond, timeout)
+
+	// exhausted
+	ctx3, cancel3 := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel3()
+	_, err = CallTimeout(ctx3)
+	assert.ErrorIs(t, err, ErrBudgetExhausted)
+
+	SetCallBudget(0.5, 10*time.Millisecond)
+	defer SetCallBudget(0.8, 50*time.Millisecond)
+	timeout, err = CallTimeout(ctx3)
+	assert.NoError(t, err)
+	assert.True(t, timeout >= 10*time.Millisecond && timeout <= 20*time.Millisecond, timeout)
+	SetCallBudget(2, -1) // ignored
+	timeout, err = CallTimeout(ctx)
+	assert.NoError(t, err)
+	assert.True(t, timeout <= time.Second, timeout)
+}
+
+func TestCallContext(t *testing.T) {
+	ctx, cancel, err := CallContext(context.Background())
+	require.NoError(t, err)
+	cancel()
+	_, ok := ctx.Deadline()
+	assert.False(t, ok)
+
+	// the deadline shrinks through the layers
+	parent, parentCancel := context.WithTimeout(context.Background(), time.Second)
+	defer parentCancel()
+	parentDeadline, _ := parent.Deadline()
+	ctx, cancel, err = CallContext(parent)
+	require.NoError(t, err)
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.True(t, deadline.Before(parentDeadline))
+	inner, innerCancel, err := CallContext(ctx)
+	require.NoError(t, err)
+	defer innerCancel()
+	innerDeadline, _ := inner.Deadline()
+	assert.True(t, innerDeadline.Before(deadline))
+
+	expired, expiredCancel := context.WithTimeout(context.Background(), -time.Second)
+	defer expiredCancel()
+	_, _, err = CallContext(expired)
+	assert.ErrorIs(t, err, ErrBudgetExhausted)
+
+	assert.True(t, IsBudgetExhausted(fmt.Errorf("find: %w", ErrBudgetExhausted)))
+	assert.True(t, IsBudgetExhausted(fmt.Errorf("find: %w", context.DeadlineExceeded)))
+	assert.False(t, IsBudgetExhausted(context.Canceled))
+}
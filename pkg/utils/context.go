@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"context"
+	"sync"
+)
+
+// ContextKey is a typed key for context.WithValue, using a dedicated type avoids collisions
+// with keys defined by other packages.
+type ContextKey string
+
+// String returns the name of the key.
+func (k ContextKey) String() string {
+	return string(k)
+}
+
+var carried = &carriedKeys{}
+
+type carriedKeys struct {
+	mu   sync.RWMutex
+	keys []interface{}
+}
+
+func (c *carriedKeys) add(keys ...interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		if key == nil {
+			continue
+		}
+		exists := false
+		for _, k := range c.keys {
+			if k == key {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			c.keys = append(c.keys, key)
+		}
+	}
+}
+
+func (c *carriedKeys) list() []interface{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	keys := make([]interface{}, len(c.keys))
+	copy(keys, c.keys)
+	return keys
+}
+
+// RegisterCarriedKeys register the context keys whose values are copied by DetachContext,
+// middlewares call it for the keys they set, e.g. request id, tenant, logger.
+func RegisterCarriedKeys(keys ...interface{}) {
+	carried.add(keys...)
+}
+
+// CarriedKeys returns the registered context keys.
+func CarriedKeys() []interface{} {
+	return carried.list()
+}
+
+// DetachContext returns a context that is never canceled and has no deadline, it is used for
+// background work started by a request, e.g. webhook delivery, cache warm.
+// The values of the registered keys are copied at the time of the call, so they are still valid
+// if the parent is reused after the request ends; other values (e.g. the trace span) are looked up
+// from the parent.
+func DetachContext(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return WithValues(context.WithoutCancel(ctx), ctx, CarriedKeys()...)
+}
+
+// WithValues copy the values of the specified keys from src to dst, keys without value in src are skipped.
+func WithValues(dst context.Context, src context.Context, keys ...interface{}) context.Context {
+	if dst == nil {
+		dst = context.Background()
+	}
+	if src == nil {
+		return dst
+	}
+
+	for _, key := range keys {
+		if v := src.Value(key); v != nil {
+			dst = context.WithValue(dst, key, v) //nolint
+		}
+	}
+	return dst
+}
+
+// GoWithContext run fn in a new goroutine with the detached context, panics are recovered.
+func GoWithContext(ctx context.Context, fn func(ctx context.Context)) {
+	ctx = DetachContext(ctx)
+	go SafeRun(ctx, fn)
+}
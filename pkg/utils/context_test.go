@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetachContext(t *testing.T) {
+	tenantKey := ContextKey("tenant_id")
+	otherKey := ContextKey("other")
+	RegisterCarriedKeys(tenantKey, tenantKey, nil)
+	assert.Contains(t, CarriedKeys(), tenantKey)
+	assert.Equal(t, "tenant_id", tenantKey.String())
+
+	ctx := context.WithValue(context.Background(), tenantKey, "t1")
+	ctx = context.WithValue(ctx, otherKey, "foo")
+	ctx, cancel := context.WithTimeout(ctx, time.Millisecond*50)
+
+	dctx := DetachContext(ctx)
+	cancel()
+
+	assert.Error(t, ctx.Err())
+	assert.NoError(t, dctx.Err())
+	_, ok := dctx.Deadline()
+	assert.False(t, ok)
+	assert.Nil(t, dctx.Done())
+	assert.Equal(t, "t1", dctx.Value(tenantKey))
+	assert.Equal(t, "foo", dctx.Value(otherKey)) // looked up from the parent
+
+	assert.NotNil(t, DetachContext(nil)) //nolint
+}
+
+func TestWithValues(t *testing.T) {
+	k1, k2, k3 := ContextKey("k1"), ContextKey("k2"), ContextKey("k3")
+	src := context.WithValue(context.Background(), k1, 1)
+	src = context.WithValue(src, k2, 2)
+
+	dst := WithValues(context.Background(), src, k1, k3)
+	assert.Equal(t, 1, dst.Value(k1))
+	assert.Nil(t, dst.Value(k2))
+	assert.Nil(t, dst.Value(k3))
+
+	dst = WithValues(nil, src, k2) //nolint
+	assert.Equal(t, 2, dst.Value(k2))
+	dst = WithValues(context.Background(), nil, k2) //nolint
+	assert.Nil(t, dst.Value(k2))
+}
+
+func TestGoWithContext(t *testing.T) {
+	key := ContextKey("job")
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), key, "warm"))
+
+	done := make(chan string)
+	GoWithContext(ctx, func(ctx context.Context) {
+		time.Sleep(time.Millisecond * 50)
+		if ctx.Err() != nil {
+			done <- ""
+			return
+		}
+		done <- ctx.Value(key).(string)
+	})
+	cancel()
+
+	assert.Equal(t, "warm", <-done)
+}
@@ -0,0 +1,300 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ErrEventBusClosed the event bus is closed
+var ErrEventBusClosed = errors.New("event bus is closed")
+
+// EventBusDroppedCounter counts the events dropped by the slow subscribers by bus name and subscriber name,
+// the subscriber name is the topic pattern if not set, register it to expose the metrics,
+// e.g. prometheus.MustRegister(utils.EventBusDroppedCounter)
+var EventBusDroppedCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "event_bus_dropped_events_total",
+		Help: "Total number of the events dropped by the slow subscribers of the event bus.",
+	},
+	[]string{"bus", "subscriber"},
+)
+
+// SlowPolicy is what to do when the buffer of a subscriber is full
+type SlowPolicy int
+
+const (
+	// DropOldest drop the oldest event in the buffer to make room for the new one, the subscriber keeps receiving
+	DropOldest SlowPolicy = iota
+	// Disconnect close the channel of the subscriber, e.g. the SSE connection is closed and the client reconnects
+	Disconnect
+)
+
+// EventBridge fan out the events to the other replicas, e.g. the redis pub/sub, the events published by any
+// replica are received by all the replicas including the publisher itself, so the subscribers of all the
+// replicas receive the same events.
+type EventBridge interface {
+	// Publish send the event to all the replicas, the event is serialized by the bridge
+	Publish(topic string, event interface{}) error
+	// Start receive the events of all the replicas and pass them to deliver until Close is called
+	Start(deliver func(topic string, event interface{})) error
+	// Close stop receiving the events
+	Close() error
+}
+
+// EventBusOption set the event bus options.
+type EventBusOption func(*eventBusOptions)
+
+type eventBusOptions struct {
+	name   string
+	policy SlowPolicy
+	bridge EventBridge
+}
+
+func defaultEventBusOptions() *eventBusOptions {
+	return &eventBusOptions{
+		name:   "default",
+		policy: DropOldest,
+	}
+}
+
+func (o *eventBusOptions) apply(opts ...EventBusOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithEventBusName set the name of the event bus, it is the label of the metrics, default "default"
+func WithEventBusName(name string) EventBusOption {
+	return func(o *eventBusOptions) {
+		if name != "" {
+			o.name = name
+		}
+	}
+}
+
+// WithEventBusPolicy set the default policy of the slow subscribers, default DropOldest
+func WithEventBusPolicy(policy SlowPolicy) EventBusOption {
+	return func(o *eventBusOptions) {
+		o.policy = policy
+	}
+}
+
+// WithEventBusBridge set the bridge to fan out the events to the other replicas, default the events are
+// delivered in process only
+func WithEventBusBridge(bridge EventBridge) EventBusOption {
+	return func(o *eventBusOptions) {
+		o.bridge = bridge
+	}
+}
+
+// SubscribeOption set the subscription options.
+type SubscribeOption func(*subscriber)
+
+// WithSubscribePolicy set the policy of the subscriber when its buffer is full, default the policy of the bus
+func WithSubscribePolicy(policy SlowPolicy) SubscribeOption {
+	return func(s *subscriber) {
+		s.policy = policy
+	}
+}
+
+// WithSubscribeName set the name of the subscriber, it is the label of the metrics, default the topic pattern,
+// note that the names should be bounded, e.g. the name of the feature instead of the connection id
+func WithSubscribeName(name string) SubscribeOption {
+	return func(s *subscriber) {
+		if name != "" {
+			s.name = name
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+type subscriber struct {
+	pattern []string
+	name    string
+	policy  SlowPolicy
+	dropped prometheus.Counter
+
+	mu     sync.Mutex // guard sending to and closing ch
+	ch     chan interface{}
+	closed bool
+}
+
+// send the event without blocking, the slow subscriber drops the oldest event or is closed by its policy,
+// return false if the subscriber is closed
+func (s *subscriber) send(event interface{}) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+
+	for {
+		select {
+		case s.ch <- event:
+			return true
+		default:
+		}
+
+		if s.policy == Disconnect {
+			s.dropped.Inc()
+			s.closed = true
+			close(s.ch)
+			return false
+		}
+		select {
+		case <-s.ch:
+			s.dropped.Inc()
+		default: // the receiver took the oldest event concurrently, try again
+		}
+	}
+}
+
+func (s *subscriber) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.ch)
+	}
+}
+
+// EventBus is an in-process publish/subscribe of the events by topic, e.g. the record events used by SSE,
+// websocket and webhook. The topics are separated by dots, e.g. userExample.updated, the subscribers match the
+// topics by the pattern, * matches exactly one segment, ** as the last segment matches one or more segments,
+// e.g. userExample.* matches userExample.created and userExample.updated.
+// Each subscriber has a bounded buffer, so a slow subscriber never blocks the publishers and the others.
+type EventBus struct {
+	opts *eventBusOptions
+
+	mu          sync.RWMutex
+	subscribers map[*subscriber]struct{}
+	closed      bool
+}
+
+// NewEventBus create an event bus, the bridge is started if it is set
+func NewEventBus(opts ...EventBusOption) (*EventBus, error) {
+	o := defaultEventBusOptions()
+	o.apply(opts...)
+
+	b := &EventBus{
+		opts:        o,
+		subscribers: map[*subscriber]struct{}{},
+	}
+	if o.bridge != nil {
+		if err := o.bridge.Start(b.deliver); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// Publish the event to the subscribers whose patterns match the topic, it never blocks, the event is sent to
+// the bridge if it is set, and it is delivered when the bridge receives it back.
+func (b *EventBus) Publish(topic string, event interface{}) error {
+	b.mu.RLock()
+	closed := b.closed
+	b.mu.RUnlock()
+	if closed {
+		return ErrEventBusClosed
+	}
+
+	if b.opts.bridge != nil {
+		return b.opts.bridge.Publish(topic, event)
+	}
+	b.deliver(topic, event)
+	return nil
+}
+
+func (b *EventBus) deliver(topic string, event interface{}) {
+	segments := strings.Split(topic, ".")
+	var disconnected []*subscriber
+
+	b.mu.RLock()
+	for s := range b.subscribers {
+		if matchTopic(s.pattern, segments) && !s.send(event) {
+			disconnected = append(disconnected, s)
+		}
+	}
+	b.mu.RUnlock()
+
+	if len(disconnected) > 0 {
+		b.mu.Lock()
+		for _, s := range disconnected {
+			delete(b.subscribers, s)
+		}
+		b.mu.Unlock()
+	}
+}
+
+// Subscribe the events of the topics matching the pattern, buffer is the size of the channel, at least 1.
+// The channel is closed after cancel is called, the bus is closed, or the subscriber is disconnected as a
+// slow subscriber, so the receiver should check whether the channel is closed. Call cancel to release the
+// subscription, it can be called more than once.
+func (b *EventBus) Subscribe(pattern string, buffer int, opts ...SubscribeOption) (<-chan interface{}, func()) {
+	if buffer < 1 {
+		buffer = 1
+	}
+	s := &subscriber{
+		pattern: strings.Split(pattern, "."),
+		name:    pattern,
+		policy:  b.opts.policy,
+		ch:      make(chan interface{}, buffer),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.dropped = EventBusDroppedCounter.WithLabelValues(b.opts.name, s.name)
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		s.close()
+		return s.ch, func() {}
+	}
+	b.subscribers[s] = struct{}{}
+	b.mu.Unlock()
+
+	return s.ch, func() {
+		b.mu.Lock()
+		delete(b.subscribers, s)
+		b.mu.Unlock()
+		s.close()
+	}
+}
+
+// Close the event bus and the bridge, the channels of all the subscribers are closed
+func (b *EventBus) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	subscribers := b.subscribers
+	b.subscribers = map[*subscriber]struct{}{}
+	b.mu.Unlock()
+
+	for s := range subscribers {
+		s.close()
+	}
+	if b.opts.bridge != nil {
+		return b.opts.bridge.Close()
+	}
+	return nil
+}
+
+func matchTopic(pattern []string, segments []string) bool {
+	for i, p := range pattern {
+		if p == "**" && i == len(pattern)-1 {
+			return len(segments) > i
+		}
+		if i >= len(segments) || (p != "*" && p != segments[i]) {
+			return false
+		}
+	}
+	return len(pattern) == len(segments)
+}
@@ -0,0 +1,186 @@
+package utils
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func receiveAll(ch <-chan interface{}) []interface{} {
+	var events []interface{}
+	for {
+		select {
+		case e, ok := <-ch:
+			if !ok {
+				return events
+			}
+			events = append(events, e)
+		default:
+			return events
+		}
+	}
+}
+
+func TestEventBus_Wildcard(t *testing.T) {
+	b, err := NewEventBus()
+	assert.NoError(t, err)
+	defer b.Close() //nolint
+
+	all, cancel1 := b.Subscribe("userExample.*", 10)
+	defer cancel1()
+	updated, cancel2 := b.Subscribe("userExample.updated", 10)
+	defer cancel2()
+	deep, cancel3 := b.Subscribe("userExample.**", 10)
+	defer cancel3()
+
+	for _, topic := range []string{"userExample.created", "userExample.updated", "order.updated", "userExample", "userExample.file.created"} {
+		assert.NoError(t, b.Publish(topic, topic))
+	}
+	assert.Equal(t, []interface{}{"userExample.created", "userExample.updated"}, receiveAll(all))
+	assert.Equal(t, []interface{}{"userExample.updated"}, receiveAll(updated))
+	assert.Equal(t, []interface{}{"userExample.created", "userExample.updated", "userExample.file.created"}, receiveAll(deep))
+
+	assert.True(t, matchTopic([]string{"*", "updated"}, []string{"order", "updated"}))
+	assert.False(t, matchTopic([]string{"*"}, []string{"order", "updated"}))
+	assert.False(t, matchTopic([]string{"order", "**"}, []string{"order"}))
+}
+
+func TestEventBus_SlowSubscriber(t *testing.T) {
+	b, err := NewEventBus(WithEventBusName("test_slow"))
+	assert.NoError(t, err)
+	defer b.Close() //nolint
+	dropped := func(name string) float64 {
+		return testutil.ToFloat64(EventBusDroppedCounter.WithLabelValues("test_slow", name))
+	}
+	slowDropped, disconnectedDropped, fastDropped := dropped("slow"), dropped("disconnected"), dropped("topic")
+
+	// the oldest events are dropped, the subscriber keeps receiving
+	slow, cancel := b.Subscribe("topic", 2, WithSubscribeName("slow"))
+	defer cancel()
+	// the subscriber is closed when its buffer is full
+	disconnected, _ := b.Subscribe("topic", 2, WithSubscribePolicy(Disconnect), WithSubscribeName("disconnected"))
+	fast, cancelFast := b.Subscribe("topic", 100)
+	defer cancelFast()
+
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, b.Publish("topic", i))
+	}
+	assert.Equal(t, []interface{}{3, 4}, receiveAll(slow))
+	assert.Equal(t, 3.0, dropped("slow")-slowDropped)
+
+	assert.Equal(t, []interface{}{0, 1}, receiveAll(disconnected))
+	_, ok := <-disconnected
+	assert.False(t, ok)
+	assert.Equal(t, 1.0, dropped("disconnected")-disconnectedDropped)
+
+	assert.Len(t, receiveAll(fast), 5)
+	assert.Equal(t, 0.0, dropped("topic")-fastDropped)
+
+	b.mu.RLock()
+	assert.Len(t, b.subscribers, 2)
+	b.mu.RUnlock()
+}
+
+func TestEventBus_Concurrency(t *testing.T) {
+	b, err := NewEventBus(WithEventBusName("test_concurrency"))
+	assert.NoError(t, err)
+
+	const publishers, events = 8, 1000
+	fast, cancelFast := b.Subscribe("userExample.*", publishers*events)
+	// the slow subscribers never block the publishers and the fast subscriber
+	_, cancelSlow := b.Subscribe("userExample.*", 1)
+	defer cancelSlow()
+	_, _ = b.Subscribe("userExample.*", 1, WithSubscribePolicy(Disconnect))
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < publishers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < events; j++ {
+				_ = b.Publish("userExample.updated", j)
+			}
+		}()
+	}
+
+	// subscribe and cancel concurrently
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ch, cancel := b.Subscribe("userExample.updated", 1)
+			time.Sleep(time.Millisecond)
+			cancel()
+			cancel()
+			for range ch { //nolint
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, receiveAll(fast), publishers*events)
+	cancelFast()
+	_, ok := <-fast
+	assert.False(t, ok)
+
+	assert.NoError(t, b.Close())
+	assert.ErrorIs(t, b.Publish("userExample.updated", 1), ErrEventBusClosed)
+	ch, cancel := b.Subscribe("userExample.updated", 1)
+	_, ok = <-ch
+	assert.False(t, ok)
+	cancel()
+}
+
+// loopbackBridge receives the events it publishes, like the redis pub/sub of one replica
+type loopbackBridge struct {
+	mu        sync.Mutex
+	deliver   func(topic string, event interface{})
+	published int
+	closed    bool
+}
+
+func (l *loopbackBridge) Publish(topic string, event interface{}) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return errors.New("closed")
+	}
+	l.published++
+	l.deliver(topic, event)
+	return nil
+}
+
+func (l *loopbackBridge) Start(deliver func(topic string, event interface{})) error {
+	l.deliver = deliver
+	return nil
+}
+
+func (l *loopbackBridge) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+	return nil
+}
+
+func TestEventBus_Bridge(t *testing.T) {
+	bridge := &loopbackBridge{}
+	b, err := NewEventBus(WithEventBusBridge(bridge), WithEventBusPolicy(Disconnect))
+	assert.NoError(t, err)
+
+	ch, cancel := b.Subscribe("userExample.*", 10)
+	defer cancel()
+	assert.NoError(t, b.Publish("userExample.created", "foo"))
+	assert.Equal(t, []interface{}{"foo"}, receiveAll(ch))
+	assert.Equal(t, 1, bridge.published)
+
+	// the events of the other replicas
+	bridge.deliver("userExample.deleted", "bar")
+	assert.Equal(t, []interface{}{"bar"}, receiveAll(ch))
+
+	assert.NoError(t, b.Close())
+	assert.True(t, bridge.closed)
+}
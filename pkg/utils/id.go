@@ -0,0 +1,250 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// crockford is the Crockford base32 alphabet, it excludes I, L, O, U to avoid confusion.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+const (
+	sortableTimeLen        = 10 // 48-bit millisecond timestamp encoded as 10 base32 characters
+	defaultSortableRandLen = 16
+	maxSortableRandLen     = 64
+)
+
+var crockfordIndex = func() [256]int8 {
+	var idx [256]int8
+	for i := range idx {
+		idx[i] = -1
+	}
+	for i := 0; i < len(crockford); i++ {
+		idx[crockford[i]] = int8(i)
+		idx[strings.ToLower(crockford[i : i+1])[0]] = int8(i)
+	}
+	return idx
+}()
+
+// ErrInvalidID is returned when an ID cannot be parsed.
+var ErrInvalidID = errors.New("invalid id")
+
+// ------------------------------------------------------------------------------------------
+
+var uuidGen = &uuidV7Generator{}
+
+type uuidV7Generator struct {
+	mu     sync.Mutex
+	lastMs int64
+	seq    uint16 // 12-bit counter stored in rand_a
+}
+
+// next returns the timestamp and sequence to use, ensuring that IDs generated
+// in the same process are strictly increasing even within one millisecond.
+func (g *uuidV7Generator) next() (int64, uint16) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := time.Now().UnixMilli()
+	if ms > g.lastMs {
+		g.lastMs = ms
+		g.seq = randUint16() & 0x07ff // leave headroom for a burst within the same millisecond
+		return g.lastMs, g.seq
+	}
+
+	g.seq++
+	if g.seq > 0x0fff {
+		// counter exhausted, borrow the next millisecond
+		g.lastMs++
+		g.seq = randUint16() & 0x07ff
+	}
+	return g.lastMs, g.seq
+}
+
+// NewUUIDv7 generate a RFC 9562 UUID version 7 string, the first 48 bits are the unix
+// millisecond timestamp, so the IDs are time-sortable, monotonic within the process.
+// example: 0190b6b5-2a6d-7b3c-9f1e-5c2d4a8b7e61
+func NewUUIDv7() string {
+	ms, seq := uuidGen.next()
+
+	var b [16]byte
+	_, _ = rand.Read(b[8:])
+
+	b[0] = byte(ms >> 40)
+	b[1] = byte(ms >> 32)
+	b[2] = byte(ms >> 24)
+	b[3] = byte(ms >> 16)
+	b[4] = byte(ms >> 8)
+	b[5] = byte(ms)
+	b[6] = 0x70 | byte(seq>>8&0x0f) // version 7
+	b[7] = byte(seq)
+	b[8] = b[8]&0x3f | 0x80 // variant 10
+
+	var buf [36]byte
+	hex.Encode(buf[0:8], b[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], b[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], b[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], b[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], b[10:])
+
+	return string(buf[:])
+}
+
+// ParseUUIDv7Time extract the embedded timestamp from a UUID version 7 string.
+func ParseUUIDv7Time(id string) (time.Time, error) {
+	if len(id) != 36 || id[8] != '-' || id[13] != '-' || id[18] != '-' || id[23] != '-' {
+		return time.Time{}, ErrInvalidID
+	}
+
+	var b [8]byte
+	if _, err := hex.Decode(b[:4], []byte(id[0:8])); err != nil {
+		return time.Time{}, ErrInvalidID
+	}
+	if _, err := hex.Decode(b[4:6], []byte(id[9:13])); err != nil {
+		return time.Time{}, ErrInvalidID
+	}
+	if id[14] != '7' {
+		return time.Time{}, ErrInvalidID
+	}
+
+	var ms int64
+	for i := 0; i < 6; i++ {
+		ms = ms<<8 | int64(b[i])
+	}
+	return time.UnixMilli(ms), nil
+}
+
+// ------------------------------------------------------------------------------------------
+
+var sortableGen = &sortableIDGenerator{states: map[int]*sortableIDState{}}
+
+// the state of each size is kept separately, so that the interleaved calls with different sizes
+// don't reset the random suffix of each other
+type sortableIDGenerator struct {
+	mu     sync.Mutex
+	states map[int]*sortableIDState
+}
+
+type sortableIDState struct {
+	lastMs   int64
+	lastRand []byte // base32 digit values (0-31) of the previous random suffix
+}
+
+func (g *sortableIDGenerator) next(size int) (int64, []byte) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	s, ok := g.states[size]
+	if !ok {
+		s = &sortableIDState{}
+		g.states[size] = s
+	}
+
+	ms := time.Now().UnixMilli()
+	if ms > s.lastMs {
+		s.lastMs = ms
+		s.lastRand = randBase32Digits(size)
+		// clear the highest bit so there is room to increment during a burst
+		s.lastRand[0] &= 0x0f
+	} else if !incrementBase32(s.lastRand) {
+		// suffix overflowed, borrow the next millisecond
+		s.lastMs++
+		s.lastRand = randBase32Digits(size)
+		s.lastRand[0] &= 0x0f
+	}
+
+	digits := make([]byte, size)
+	copy(digits, s.lastRand)
+	return s.lastMs, digits
+}
+
+// NewSortableID generate a lexicographically sortable ID, millisecond timestamp + random suffix,
+// encoded with Crockford base32. The prefix is joined with an underscore if it is not empty,
+// size is the length of the random suffix, default 16, the IDs of the same size are monotonic within the process.
+// example: NewSortableID("") --> 01J2V5B8X4QZ4N7K2M9R6T3W1Y5A
+// example: NewSortableID("usr", 8) --> usr_01J2V5B8X4QZ4N7K2M
+func NewSortableID(prefix string, size ...int) string {
+	randLen := defaultSortableRandLen
+	if len(size) > 0 && size[0] > 0 {
+		randLen = size[0]
+		if randLen > maxSortableRandLen {
+			randLen = maxSortableRandLen
+		}
+	}
+
+	ms, digits := sortableGen.next(randLen)
+
+	var sb strings.Builder
+	sb.Grow(len(prefix) + 1 + sortableTimeLen + randLen)
+	if prefix != "" {
+		sb.WriteString(prefix)
+		sb.WriteByte('_')
+	}
+	for i := sortableTimeLen - 1; i >= 0; i-- {
+		sb.WriteByte(crockford[(ms>>(uint(i)*5))&0x1f])
+	}
+	for _, d := range digits {
+		sb.WriteByte(crockford[d])
+	}
+
+	return sb.String()
+}
+
+// ParseSortableIDTime extract the embedded timestamp from an ID generated by NewSortableID.
+func ParseSortableIDTime(id string) (time.Time, error) {
+	if i := strings.LastIndexByte(id, '_'); i >= 0 {
+		id = id[i+1:]
+	}
+	if len(id) <= sortableTimeLen {
+		return time.Time{}, ErrInvalidID
+	}
+
+	var ms int64
+	for i := 0; i < len(id); i++ {
+		v := crockfordIndex[id[i]]
+		if v < 0 {
+			return time.Time{}, ErrInvalidID
+		}
+		if i < sortableTimeLen {
+			ms = ms<<5 | int64(v)
+		}
+	}
+	return time.UnixMilli(ms), nil
+}
+
+// ------------------------------------------------------------------------------------------
+
+func randUint16() uint16 {
+	var b [2]byte
+	_, _ = rand.Read(b[:])
+	return uint16(b[0])<<8 | uint16(b[1])
+}
+
+func randBase32Digits(size int) []byte {
+	b := make([]byte, size)
+	_, _ = rand.Read(b)
+	for i := range b {
+		b[i] &= 0x1f
+	}
+	return b
+}
+
+// incrementBase32 add one to the base32 digits, return false if it overflows.
+func incrementBase32(digits []byte) bool {
+	for i := len(digits) - 1; i >= 0; i-- {
+		if digits[i] < 31 {
+			digits[i]++
+			return true
+		}
+		digits[i] = 0
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package utils
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewUUIDv7(t *testing.T) {
+	id := NewUUIDv7()
+	t.Log(id)
+	assert.Len(t, id, 36)
+	assert.Equal(t, byte('7'), id[14])
+	assert.Contains(t, "89ab", string(id[19]))
+
+	tm, err := ParseUUIDv7Time(id)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), tm, time.Second)
+
+	_, err = ParseUUIDv7Time("not-a-uuid")
+	assert.ErrorIs(t, err, ErrInvalidID)
+	_, err = ParseUUIDv7Time("0190b6b5-2a6d-4b3c-9f1e-5c2d4a8b7e61") // version 4
+	assert.ErrorIs(t, err, ErrInvalidID)
+	_, err = ParseUUIDv7Time("zz90b6b5-2a6d-7b3c-9f1e-5c2d4a8b7e61")
+	assert.ErrorIs(t, err, ErrInvalidID)
+}
+
+func TestNewUUIDv7_Monotonic(t *testing.T) {
+	ids := make([]string, 10000)
+	for i := range ids {
+		ids[i] = NewUUIDv7()
+	}
+	assert.True(t, sort.StringsAreSorted(ids))
+}
+
+func TestNewSortableID(t *testing.T) {
+	id := NewSortableID("")
+	t.Log(id)
+	assert.Len(t, id, sortableTimeLen+defaultSortableRandLen)
+
+	id = NewSortableID("usr", 8)
+	t.Log(id)
+	assert.True(t, strings.HasPrefix(id, "usr_"))
+	assert.Len(t, id, 4+sortableTimeLen+8)
+
+	id = NewSortableID("", 1000)
+	assert.Len(t, id, sortableTimeLen+maxSortableRandLen)
+
+	tm, err := ParseSortableIDTime("order_" + NewSortableID(""))
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), tm, time.Second)
+
+	tm2, err := ParseSortableIDTime(strings.ToLower(NewSortableID("")))
+	assert.NoError(t, err)
+	assert.WithinDuration(t, tm, tm2, time.Second)
+
+	_, err = ParseSortableIDTime("usr_01J2V")
+	assert.ErrorIs(t, err, ErrInvalidID)
+	_, err = ParseSortableIDTime("01J2V5B8X4QZ4N7K2M9R6T3W1Y5U") // U is not in the alphabet
+	assert.ErrorIs(t, err, ErrInvalidID)
+}
+
+func TestNewSortableID_Monotonic(t *testing.T) {
+	ids := make([]string, 10000)
+	for i := range ids {
+		ids[i] = NewSortableID("", 4)
+	}
+	assert.True(t, sort.StringsAreSorted(ids))
+
+	// the interleaved sizes don't reset the suffix of each other
+	ids4, ids8 := make([]string, 10000), make([]string, 10000)
+	for i := range ids4 {
+		ids4[i] = NewSortableID("", 4)
+		ids8[i] = NewSortableID("", 8)
+	}
+	assert.True(t, sort.StringsAreSorted(ids4))
+	assert.True(t, sort.StringsAreSorted(ids8))
+}
+
+func TestIncrementBase32(t *testing.T) {
+	digits := []byte{0, 31}
+	assert.True(t, incrementBase32(digits))
+	assert.Equal(t, []byte{1, 0}, digits)
+
+	digits = []byte{31, 31}
+	assert.False(t, incrementBase32(digits))
+	assert.Equal(t, []byte{0, 0}, digits)
+}
+
+func TestIDCollision(t *testing.T) {
+	total := 1000000
+	if testing.Short() {
+		total = 100000
+	}
+	workers := 8
+
+	check := func(gen func() string) {
+		results := make([][]string, workers)
+		wg := sync.WaitGroup{}
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func(n int) {
+				defer wg.Done()
+				ids := make([]string, 0, total/workers)
+				for j := 0; j < total/workers; j++ {
+					ids = append(ids, gen())
+				}
+				results[n] = ids
+			}(i)
+		}
+		wg.Wait()
+
+		seen := make(map[string]struct{}, total)
+		for _, ids := range results {
+			assert.True(t, sort.StringsAreSorted(ids))
+			for _, id := range ids {
+				_, ok := seen[id]
+				if !assert.False(t, ok, "duplicate id %s", id) {
+					return
+				}
+				seen[id] = struct{}{}
+			}
+		}
+	}
+
+	check(NewUUIDv7)
+	check(func() string { return NewSortableID("") })
+}
+
+func BenchmarkNewUUIDv7(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = NewUUIDv7()
+	}
+}
+
+func BenchmarkNewSortableID(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = NewSortableID("usr")
+	}
+}
+
+func BenchmarkNewSortableID_Parallel(b *testing.B) {
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_ = NewSortableID("")
+		}
+	})
+}
@@ -0,0 +1,261 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// the strategies of merging the lists, see WithMergeListStrategy
+const (
+	// MergeListReplace the list of src replaces the list of dst
+	MergeListReplace = "replace"
+	// MergeListAppend the elements of src are appended to the list of dst
+	MergeListAppend = "append"
+	// MergeListByKey the maps in the lists with the same value of the key are merged, the others of src are
+	// appended, e.g. the key "name" of [{name: a, port: 1}] and [{name: a, port: 2}, {name: b}]
+	MergeListByKey = "mergeByKey"
+)
+
+// ErrMergeConflict the value of src is not the same kind as the value of dst, e.g. the map is merged to the
+// string, it is returned if WithMergeConflictError is set
+var ErrMergeConflict = errors.New("merge type conflict")
+
+// ErrMergeMaxDepth the maps are nested deeper than the max depth
+var ErrMergeMaxDepth = errors.New("merge exceeds the max depth")
+
+// MergeOption set the deep merge options.
+type MergeOption func(*mergeOptions)
+
+type mergeOptions struct {
+	listStrategy  string
+	listKey       string
+	nilDeletes    bool
+	maxDepth      int
+	conflictError bool
+}
+
+func (o *mergeOptions) apply(opts ...MergeOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+func defaultMergeOptions() *mergeOptions {
+	return &mergeOptions{
+		listStrategy: MergeListReplace,
+		listKey:      "name",
+		maxDepth:     32,
+	}
+}
+
+// WithMergeListStrategy set the strategy of merging the lists, MergeListReplace, MergeListAppend or
+// MergeListByKey, default MergeListReplace
+func WithMergeListStrategy(strategy string) MergeOption {
+	return func(o *mergeOptions) {
+		switch strategy {
+		case MergeListReplace, MergeListAppend, MergeListByKey:
+			o.listStrategy = strategy
+		}
+	}
+}
+
+// WithMergeListKey set the key identifying the maps in the lists of MergeListByKey, default "name"
+func WithMergeListKey(key string) MergeOption {
+	return func(o *mergeOptions) {
+		if key != "" {
+			o.listKey = key
+		}
+	}
+}
+
+// WithMergeNilDeletes the key with the nil value of src is deleted from the result, default the nil value
+// replaces the value of dst like the other values
+func WithMergeNilDeletes() MergeOption {
+	return func(o *mergeOptions) {
+		o.nilDeletes = true
+	}
+}
+
+// WithMergeMaxDepth set the max depth of the nested maps and lists, default 32, ErrMergeMaxDepth is returned
+// if it is exceeded
+func WithMergeMaxDepth(n int) MergeOption {
+	return func(o *mergeOptions) {
+		if n > 0 {
+			o.maxDepth = n
+		}
+	}
+}
+
+// WithMergeConflictError return ErrMergeConflict if the value of src is not the same kind as the value of dst,
+// i.e. one of them is a map or a list and the other is not, default the value of src wins
+func WithMergeConflictError() MergeOption {
+	return func(o *mergeOptions) {
+		o.conflictError = true
+	}
+}
+
+// DeepMerge merge src into dst recursively and return the result, dst and src are not modified, the maps and
+// the lists of the result are copied. The maps of the same key are merged, the lists are merged by the list
+// strategy, the other values of src replace the values of dst. The merge of MergeListReplace is associative if
+// the values of the same key are the same kind, i.e. map, list or scalar, e.g. the layers of the same config.
+func DeepMerge(dst, src map[string]interface{}, opts ...MergeOption) (map[string]interface{}, error) {
+	o := defaultMergeOptions()
+	o.apply(opts...)
+
+	result, err := copyValue(dst, 0, o.maxDepth)
+	if err != nil {
+		return nil, err
+	}
+	m, _ := result.(map[string]interface{})
+	if m == nil {
+		m = map[string]interface{}{}
+	}
+	if err = o.mergeMap(m, src, "", 0); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// merge src into dst which is the copy owned by the result
+func (o *mergeOptions) mergeMap(dst, src map[string]interface{}, path string, depth int) error {
+	if depth >= o.maxDepth {
+		return fmt.Errorf("%w %d at '%s'", ErrMergeMaxDepth, o.maxDepth, path)
+	}
+
+	for k, sv := range src {
+		key := k
+		if path != "" {
+			key = path + "." + k
+		}
+		if sv == nil && o.nilDeletes {
+			delete(dst, k)
+			continue
+		}
+		value, err := o.mergeValue(dst[k], sv, key, depth+1)
+		if err != nil {
+			return err
+		}
+		dst[k] = value
+	}
+	return nil
+}
+
+func (o *mergeOptions) mergeValue(dv, sv interface{}, path string, depth int) (interface{}, error) {
+	if dv == nil || sv == nil {
+		return copyValue(sv, depth, o.maxDepth)
+	}
+
+	dstMap, dstIsMap := dv.(map[string]interface{})
+	srcMap, srcIsMap := sv.(map[string]interface{})
+	dstList, dstIsList := toList(dv)
+	srcList, srcIsList := toList(sv)
+	if o.conflictError && (dstIsMap != srcIsMap || dstIsList != srcIsList) {
+		return nil, fmt.Errorf("%w at '%s': %T and %T", ErrMergeConflict, path, dv, sv)
+	}
+
+	switch {
+	case dstIsMap && srcIsMap:
+		if err := o.mergeMap(dstMap, srcMap, path, depth); err != nil {
+			return nil, err
+		}
+		return dstMap, nil
+	case dstIsList && srcIsList && o.listStrategy != MergeListReplace:
+		return o.mergeList(dstList, srcList, path, depth)
+	}
+	return copyValue(sv, depth, o.maxDepth)
+}
+
+func (o *mergeOptions) mergeList(dst, src []interface{}, path string, depth int) (interface{}, error) {
+	if depth >= o.maxDepth {
+		return nil, fmt.Errorf("%w %d at '%s'", ErrMergeMaxDepth, o.maxDepth, path)
+	}
+
+	result := make([]interface{}, len(dst), len(dst)+len(src))
+	copy(result, dst)
+	for i, sv := range src {
+		if o.listStrategy == MergeListByKey {
+			if index := o.indexByKey(result, sv); index >= 0 {
+				value, err := o.mergeValue(result[index], sv, fmt.Sprintf("%s[%d]", path, i), depth+1)
+				if err != nil {
+					return nil, err
+				}
+				result[index] = value
+				continue
+			}
+		}
+		value, err := copyValue(sv, depth+1, o.maxDepth)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+	}
+	return result, nil
+}
+
+// the index of the map in the list with the same value of the key as v, -1 if v is not a map with the key
+func (o *mergeOptions) indexByKey(list []interface{}, v interface{}) int {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return -1
+	}
+	kv, ok := m[o.listKey]
+	if !ok || kv == nil {
+		return -1
+	}
+	for i, elem := range list {
+		if em, ok := elem.(map[string]interface{}); ok && reflect.DeepEqual(em[o.listKey], kv) {
+			return i
+		}
+	}
+	return -1
+}
+
+// the elements of the slice, e.g. []interface{} decoded from json or yaml, or []string
+func toList(v interface{}) ([]interface{}, bool) {
+	if list, ok := v.([]interface{}); ok {
+		return list, true
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice || rv.Type().Elem().Kind() == reflect.Uint8 { // []byte is a scalar
+		return nil, false
+	}
+	list := make([]interface{}, rv.Len())
+	for i := range list {
+		list[i] = rv.Index(i).Interface()
+	}
+	return list, true
+}
+
+// the deep copy of the maps and the []interface{}, the other values are returned as they are
+func copyValue(v interface{}, depth int, maxDepth int) (interface{}, error) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if depth >= maxDepth {
+			return nil, fmt.Errorf("%w %d", ErrMergeMaxDepth, maxDepth)
+		}
+		m := make(map[string]interface{}, len(val))
+		for k, sv := range val {
+			cv, err := copyValue(sv, depth+1, maxDepth)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = cv
+		}
+		return m, nil
+	case []interface{}:
+		if depth >= maxDepth {
+			return nil, fmt.Errorf("%w %d", ErrMergeMaxDepth, maxDepth)
+		}
+		list := make([]interface{}, len(val))
+		for i, sv := range val {
+			cv, err := copyValue(sv, depth+1, maxDepth)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = cv
+		}
+		return list, nil
+	}
+	return v, nil
+}
@@ -0,0 +1,177 @@
+package utils
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// the random map of the small key space, so that the same keys are merged at the different levels, the kind of
+// the value is decided by the key and the level, like the maps of the same config schema
+func randomMap(r *rand.Rand, depth int) map[string]interface{} {
+	m := make(map[string]interface{})
+	for i, n := 0, r.Intn(4); i < n; i++ {
+		key := "k" + strconv.Itoa(r.Intn(4))
+		switch key {
+		case "k0":
+			if depth < 3 {
+				m[key] = randomMap(r, depth+1)
+			} else {
+				m[key] = r.Intn(10)
+			}
+		case "k1":
+			m[key] = []interface{}{r.Intn(10), "v" + strconv.Itoa(r.Intn(10))}
+		case "k2":
+			m[key] = "v" + strconv.Itoa(r.Intn(10))
+		default:
+			if r.Intn(2) == 0 {
+				m[key] = nil
+			} else {
+				m[key] = r.Intn(10)
+			}
+		}
+	}
+	return m
+}
+
+func mustMerge(t *testing.T, dst, src map[string]interface{}, opts ...MergeOption) map[string]interface{} {
+	m, err := DeepMerge(dst, src, opts...)
+	require.NoError(t, err)
+	return m
+}
+
+func TestDeepMerge_Properties(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		a, b, c := randomMap(r, 0), randomMap(r, 0), randomMap(r, 0)
+		aCopy, bCopy := mustMerge(t, nil, a), mustMerge(t, nil, b)
+
+		// merge with empty is identity
+		assert.Equal(t, a, mustMerge(t, a, nil))
+		assert.Equal(t, a, mustMerge(t, map[string]interface{}{}, a))
+		assert.Equal(t, a, mustMerge(t, a, map[string]interface{}{}, WithMergeListStrategy(MergeListAppend)))
+
+		// associativity of the replace strategy, the value of the same key is the same kind in all the maps
+		left := mustMerge(t, mustMerge(t, a, b), c)
+		right := mustMerge(t, a, mustMerge(t, b, c))
+		assert.Equal(t, left, right, "a=%v b=%v c=%v", a, b, c)
+
+		// the inputs are not modified
+		_ = mustMerge(t, a, b, WithMergeListStrategy(MergeListAppend), WithMergeNilDeletes())
+		assert.Equal(t, aCopy, a)
+		assert.Equal(t, bCopy, b)
+	}
+}
+
+func TestDeepMerge(t *testing.T) {
+	dst := map[string]interface{}{
+		"app":   map[string]interface{}{"name": "foo", "port": 8080, "tags": []interface{}{"a"}},
+		"hosts": []string{"h1"},
+		"debug": true,
+	}
+	src := map[string]interface{}{
+		"app":   map[string]interface{}{"port": 9090, "tags": []interface{}{"b"}},
+		"hosts": []string{"h2"},
+		"debug": nil,
+	}
+
+	m := mustMerge(t, dst, src)
+	assert.Equal(t, map[string]interface{}{
+		"app":   map[string]interface{}{"name": "foo", "port": 9090, "tags": []interface{}{"b"}},
+		"hosts": []string{"h2"},
+		"debug": nil,
+	}, m)
+
+	m = mustMerge(t, dst, src, WithMergeListStrategy(MergeListAppend), WithMergeNilDeletes())
+	assert.Equal(t, map[string]interface{}{
+		"app":   map[string]interface{}{"name": "foo", "port": 9090, "tags": []interface{}{"a", "b"}},
+		"hosts": []interface{}{"h1", "h2"},
+	}, m)
+
+	// the result does not share the maps and the lists with the inputs
+	m["app"].(map[string]interface{})["name"] = "bar"
+	m["app"].(map[string]interface{})["tags"].([]interface{})[0] = "c"
+	assert.Equal(t, "foo", dst["app"].(map[string]interface{})["name"])
+	assert.Equal(t, "a", dst["app"].(map[string]interface{})["tags"].([]interface{})[0])
+
+	// the invalid strategy is ignored
+	m = mustMerge(t, dst, src, WithMergeListStrategy("unknown"))
+	assert.Equal(t, []string{"h2"}, m["hosts"])
+}
+
+func TestDeepMerge_ListByKey(t *testing.T) {
+	dst := map[string]interface{}{
+		"grpcClient": []interface{}{
+			map[string]interface{}{"name": "user", "host": "127.0.0.1", "port": 8282},
+			map[string]interface{}{"name": "order", "host": "127.0.0.1", "port": 8283},
+			"scalar",
+		},
+	}
+	src := map[string]interface{}{
+		"grpcClient": []interface{}{
+			map[string]interface{}{"name": "order", "port": 9283, "timeout": 3},
+			map[string]interface{}{"name": "pay", "port": 8284},
+			map[string]interface{}{"port": 1}, // without the key
+			"scalar",
+		},
+	}
+	m := mustMerge(t, dst, src, WithMergeListStrategy(MergeListByKey))
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"name": "user", "host": "127.0.0.1", "port": 8282},
+		map[string]interface{}{"name": "order", "host": "127.0.0.1", "port": 9283, "timeout": 3},
+		"scalar",
+		map[string]interface{}{"name": "pay", "port": 8284},
+		map[string]interface{}{"port": 1},
+		"scalar",
+	}, m["grpcClient"])
+	assert.Equal(t, 8283, dst["grpcClient"].([]interface{})[1].(map[string]interface{})["port"])
+
+	// the custom key, and the nested lists of the merged elements
+	dst = map[string]interface{}{"routes": []interface{}{
+		map[string]interface{}{"path": "/a", "methods": []interface{}{"GET"}},
+	}}
+	src = map[string]interface{}{"routes": []interface{}{
+		map[string]interface{}{"path": "/a", "methods": []interface{}{"POST"}},
+		map[string]interface{}{"path": "/b"},
+	}}
+	m = mustMerge(t, dst, src, WithMergeListStrategy(MergeListByKey), WithMergeListKey("path"))
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"path": "/a", "methods": []interface{}{"GET", "POST"}},
+		map[string]interface{}{"path": "/b"},
+	}, m["routes"])
+}
+
+func TestDeepMerge_Error(t *testing.T) {
+	dst := map[string]interface{}{"a": map[string]interface{}{"b": 1}, "c": []interface{}{1}, "d": 1}
+
+	// the value of src wins by default
+	m := mustMerge(t, dst, map[string]interface{}{"a": "x", "c": map[string]interface{}{"x": 1}, "d": "x"})
+	assert.Equal(t, map[string]interface{}{"a": "x", "c": map[string]interface{}{"x": 1}, "d": "x"}, m)
+
+	for _, src := range []map[string]interface{}{
+		{"a": "x"},
+		{"a": map[string]interface{}{"b": map[string]interface{}{}}},
+		{"c": map[string]interface{}{"x": 1}},
+		{"c": "x"},
+	} {
+		_, err := DeepMerge(dst, src, WithMergeConflictError())
+		assert.ErrorIs(t, err, ErrMergeConflict, src)
+	}
+	// the scalars of the different types and the nil are not the conflicts
+	_, err := DeepMerge(dst, map[string]interface{}{"d": "x", "a": nil}, WithMergeConflictError())
+	assert.NoError(t, err)
+
+	deep := map[string]interface{}{"v": 1}
+	for i := 0; i < 5; i++ {
+		deep = map[string]interface{}{"k": deep}
+	}
+	_, err = DeepMerge(nil, deep, WithMergeMaxDepth(5))
+	assert.ErrorIs(t, err, ErrMergeMaxDepth)
+	_, err = DeepMerge(deep, deep, WithMergeMaxDepth(5))
+	assert.ErrorIs(t, err, ErrMergeMaxDepth)
+	_, err = DeepMerge(deep, deep, WithMergeMaxDepth(6))
+	assert.NoError(t, err)
+}
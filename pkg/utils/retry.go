@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// RetryOption set the retry options.
+type RetryOption func(*retryOptions)
+
+type retryOptions struct {
+	attempts    int
+	interval    time.Duration
+	maxInterval time.Duration
+	retryIf     func(err error) bool
+}
+
+func (o *retryOptions) apply(opts ...RetryOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+func defaultRetryOptions() *retryOptions {
+	return &retryOptions{
+		attempts:    3,
+		interval:    100 * time.Millisecond,
+		maxInterval: 2 * time.Second,
+		retryIf:     func(err error) bool { return true },
+	}
+}
+
+// WithRetryAttempts set the maximum number of calls including the first one, default 3
+func WithRetryAttempts(n int) RetryOption {
+	return func(o *retryOptions) {
+		if n > 0 {
+			o.attempts = n
+		}
+	}
+}
+
+// WithRetryInterval set the initial interval and the maximum interval of the exponential backoff,
+// default 100ms and 2s, a random jitter of up to 50% is subtracted from each interval.
+func WithRetryInterval(interval time.Duration, maxInterval time.Duration) RetryOption {
+	return func(o *retryOptions) {
+		if interval > 0 {
+			o.interval = interval
+		}
+		if maxInterval >= o.interval {
+			o.maxInterval = maxInterval
+		} else {
+			o.maxInterval = o.interval
+		}
+	}
+}
+
+// WithRetryIf set the function that reports whether the error is retryable, default all errors are retried
+func WithRetryIf(fn func(err error) bool) RetryOption {
+	return func(o *retryOptions) {
+		if fn != nil {
+			o.retryIf = fn
+		}
+	}
+}
+
+// Retry call fn until it returns nil, the error is not retryable, the attempts are exhausted or ctx is done,
+// it returns the last error of fn, or the error of ctx if ctx is done while waiting.
+func Retry(ctx context.Context, fn func(ctx context.Context) error, opts ...RetryOption) error {
+	o := defaultRetryOptions()
+	o.apply(opts...)
+
+	var err error
+	interval := o.interval
+	for attempt := 1; ; attempt++ {
+		err = fn(ctx)
+		if err == nil || attempt >= o.attempts || !o.retryIf(err) {
+			return err
+		}
+
+		wait := interval - time.Duration(rand.Int63n(int64(interval)/2+1)) //nolint
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		interval *= 2
+		if interval > o.maxInterval {
+			interval = o.maxInterval
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetry(t *testing.T) {
+	errTemp := errors.New("temporary")
+	errFatal := errors.New("fatal")
+
+	count := 0
+	err := Retry(context.Background(), func(ctx context.Context) error {
+		count++
+		if count < 3 {
+			return errTemp
+		}
+		return nil
+	}, WithRetryInterval(time.Millisecond, 5*time.Millisecond))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	// attempts exhausted
+	count = 0
+	err = Retry(context.Background(), func(ctx context.Context) error {
+		count++
+		return errTemp
+	}, WithRetryAttempts(5), WithRetryInterval(time.Millisecond, 0))
+	assert.ErrorIs(t, err, errTemp)
+	assert.Equal(t, 5, count)
+
+	// not retryable
+	count = 0
+	err = Retry(context.Background(), func(ctx context.Context) error {
+		count++
+		return errFatal
+	}, WithRetryIf(func(err error) bool { return !errors.Is(err, errFatal) }))
+	assert.ErrorIs(t, err, errFatal)
+	assert.Equal(t, 1, count)
+
+	// context done while waiting
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err = Retry(ctx, func(ctx context.Context) error {
+		return errTemp
+	}, WithRetryAttempts(100), WithRetryInterval(time.Second, time.Second))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,527 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrHubClosed the hub is closed
+	ErrHubClosed = errors.New("websocket hub is closed")
+	// ErrUnauthorized the token of the connection is invalid
+	ErrUnauthorized = errors.New("unauthorized")
+)
+
+// the types of the messages between the hub and the clients
+const (
+	MsgTypeSubscribe   = "subscribe"
+	MsgTypeUnsubscribe = "unsubscribe"
+	MsgTypeSubscribed  = "subscribed"
+	MsgTypeEvent       = "event"
+	MsgTypeError       = "error"
+)
+
+// AuthenticateFn verify the token of the connection and return the user id
+type AuthenticateFn func(token string) (uid string, err error)
+
+// HubOption set the hub options.
+type HubOption func(*hubOptions)
+
+type hubOptions struct {
+	upgrader         *websocket.Upgrader
+	authenticate     AuthenticateFn
+	resources        map[string]map[string]bool // resource type --> whitelist of the condition columns
+	sendQueueSize    int
+	maxSubscriptions int
+	pingInterval     time.Duration
+	pongTimeout      time.Duration
+	writeTimeout     time.Duration
+	maxMessageSize   int64
+	zapLogger        *zap.Logger
+}
+
+func defaultHubOptions() *hubOptions {
+	return &hubOptions{
+		upgrader: &websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool { // allow all origins
+				return true
+			},
+		},
+		resources:        map[string]map[string]bool{},
+		sendQueueSize:    256,
+		maxSubscriptions: 32,
+		pingInterval:     30 * time.Second,
+		pongTimeout:      60 * time.Second,
+		writeTimeout:     10 * time.Second,
+		maxMessageSize:   4096,
+	}
+}
+
+func (o *hubOptions) apply(opts ...HubOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithHubUpgrader set the websocket upgrader of the hub
+func WithHubUpgrader(upgrader *websocket.Upgrader) HubOption {
+	return func(o *hubOptions) {
+		if upgrader != nil {
+			o.upgrader = upgrader
+		}
+	}
+}
+
+// WithHubAuthenticate set the authentication of the connections, the token is extracted by TokenFromRequest,
+// if not set, the connections are not authenticated.
+func WithHubAuthenticate(fn AuthenticateFn) HubOption {
+	return func(o *hubOptions) {
+		o.authenticate = fn
+	}
+}
+
+// WithHubResource add a resource type that can be subscribed, only the columns in the whitelist can be used
+// as the conditions of the subscription, e.g. model.UserExampleColumnNames.
+func WithHubResource(resource string, whitelistNames map[string]bool) HubOption {
+	return func(o *hubOptions) {
+		o.resources[resource] = whitelistNames
+	}
+}
+
+// WithHubSendQueueSize set the size of the send queue of each connection, the connection whose queue is full
+// is a slow consumer, and it is disconnected, default 256.
+func WithHubSendQueueSize(size int) HubOption {
+	return func(o *hubOptions) {
+		if size > 0 {
+			o.sendQueueSize = size
+		}
+	}
+}
+
+// WithHubMaxSubscriptions set the max number of subscriptions of each connection, default 32.
+func WithHubMaxSubscriptions(n int) HubOption {
+	return func(o *hubOptions) {
+		if n > 0 {
+			o.maxSubscriptions = n
+		}
+	}
+}
+
+// WithHubKeepalive set the interval of sending ping and the timeout of waiting for pong, the connection that
+// does not respond in pongTimeout is considered dead and reaped, default 30s and 60s.
+func WithHubKeepalive(pingInterval time.Duration, pongTimeout time.Duration) HubOption {
+	return func(o *hubOptions) {
+		if pingInterval > 0 {
+			o.pingInterval = pingInterval
+		}
+		if pongTimeout > 0 {
+			o.pongTimeout = pongTimeout
+		}
+	}
+}
+
+// WithHubWriteTimeout set the timeout of writing a message, default 10s.
+func WithHubWriteTimeout(timeout time.Duration) HubOption {
+	return func(o *hubOptions) {
+		if timeout > 0 {
+			o.writeTimeout = timeout
+		}
+	}
+}
+
+// WithHubLogger set the logger of the hub
+func WithHubLogger(l *zap.Logger) HubOption {
+	return func(o *hubOptions) {
+		if l != nil {
+			o.zapLogger = l
+		}
+	}
+}
+
+// --------------------------------------------------------------------------------------
+
+// Event is a change of the record, e.g. created, updated, deleted.
+type Event struct {
+	Resource string                 `json:"resource"` // resource type, e.g. userExample
+	Action   string                 `json:"action"`   // e.g. created, updated, deleted
+	Data     map[string]interface{} `json:"data"`     // column name --> value
+}
+
+// Subscription filter the events by resource type and the optional conditions, the event matches if the
+// values of all condition columns are equal.
+type Subscription struct {
+	ID         string                 `json:"id"`
+	Resource   string                 `json:"resource"`
+	Conditions map[string]interface{} `json:"conditions,omitempty"`
+}
+
+func (s *Subscription) match(e *Event) bool {
+	if s.Resource != e.Resource {
+		return false
+	}
+	for column, value := range s.Conditions {
+		v, ok := e.Data[column]
+		// compare the string form, the numbers in the json message are float64
+		if !ok || fmt.Sprint(v) != fmt.Sprint(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// Message is the message between the hub and the clients
+type Message struct {
+	Type string `json:"type"`
+	// the subscription id of the subscribe, unsubscribe, subscribed and error messages
+	ID         string                 `json:"id,omitempty"`
+	Resource   string                 `json:"resource,omitempty"`
+	Conditions map[string]interface{} `json:"conditions,omitempty"`
+	// the matched subscription ids of the event message
+	IDs   []string `json:"ids,omitempty"`
+	Event *Event   `json:"event,omitempty"`
+	Error string   `json:"error,omitempty"`
+}
+
+// --------------------------------------------------------------------------------------
+
+// Hub manage the websocket connections, the clients subscribe to the events of the resources, and the events
+// published to the hub are fanned out to the connections whose subscriptions match.
+type Hub struct {
+	opts *hubOptions
+
+	mu     sync.RWMutex
+	conns  map[*hubConn]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewHub create a websocket hub, the resources that can be subscribed are added by WithHubResource.
+func NewHub(opts ...HubOption) *Hub {
+	o := defaultHubOptions()
+	o.apply(opts...)
+	if o.zapLogger == nil {
+		o.zapLogger, _ = zap.NewProduction()
+	}
+	return &Hub{
+		opts:  o,
+		conns: map[*hubConn]struct{}{},
+	}
+}
+
+// TokenFromRequest extract the token compatible with middleware.Auth, the value of the header
+// "Authorization: Bearer <token>", browsers can not set the header of websocket, the query parameter
+// "token" is used instead.
+func TokenFromRequest(r *http.Request) string {
+	if authorization := r.Header.Get("Authorization"); len(authorization) > 7 &&
+		strings.EqualFold(authorization[:7], "Bearer ") {
+		return authorization[7:]
+	}
+	return r.URL.Query().Get("token")
+}
+
+// ServeHTTP authenticate the request, upgrade it to websocket and add the connection to the hub.
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var uid string
+	if h.opts.authenticate != nil {
+		var err error
+		uid, err = h.opts.authenticate(TokenFromRequest(r))
+		if err != nil {
+			http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+			return
+		}
+	}
+	if h.isClosed() {
+		http.Error(w, ErrHubClosed.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	conn, err := h.opts.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		h.opts.zapLogger.Warn("websocket upgrade error", zap.Error(err))
+		return // the upgrader has replied an error
+	}
+
+	c := &hubConn{
+		hub:  h,
+		conn: conn,
+		uid:  uid,
+		send: make(chan []byte, h.opts.sendQueueSize),
+		subs: map[string]*Subscription{},
+		done: make(chan struct{}),
+	}
+	if !h.add(c) {
+		_ = conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutdown"), time.Now().Add(h.opts.writeTimeout))
+		_ = conn.Close()
+		return
+	}
+	h.opts.zapLogger.Info("websocket connection added to hub", zap.String("client", conn.RemoteAddr().String()), zap.String("uid", uid))
+
+	go c.writeLoop()
+	go c.readLoop()
+}
+
+// Publish fan out the event to the connections whose subscriptions match, returns the number of the
+// connections the event is queued to, the slow consumers whose send queue is full are disconnected.
+func (h *Hub) Publish(e *Event) int {
+	if e == nil {
+		return 0
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.closed {
+		return 0
+	}
+
+	count := 0
+	for c := range h.conns {
+		ids := c.match(e)
+		if len(ids) == 0 {
+			continue
+		}
+		data, err := json.Marshal(&Message{Type: MsgTypeEvent, IDs: ids, Event: e})
+		if err != nil {
+			h.opts.zapLogger.Warn("marshal event error", zap.Error(err))
+			return count
+		}
+		if c.enqueue(data) {
+			count++
+		}
+	}
+	return count
+}
+
+// Len returns the number of the connections
+func (h *Hub) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.conns)
+}
+
+// Close the hub gracefully, no new connections are accepted, the queued messages are flushed and the
+// connections are closed with the going away code, it returns after all connections are closed or ctx is done.
+func (h *Hub) Close(ctx context.Context) error {
+	h.mu.Lock()
+	h.closed = true
+	for c := range h.conns {
+		c.close(websocket.CloseGoingAway, "server shutdown")
+	}
+	h.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		h.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (h *Hub) isClosed() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.closed
+}
+
+func (h *Hub) add(c *hubConn) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return false
+	}
+	h.conns[c] = struct{}{}
+	h.wg.Add(1)
+	return true
+}
+
+func (h *Hub) remove(c *hubConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.conns[c]; ok {
+		delete(h.conns, c)
+		h.wg.Done()
+	}
+}
+
+func (h *Hub) subscribe(c *hubConn, msg *Message) error {
+	if msg.ID == "" {
+		return errors.New("subscription id is empty")
+	}
+	whitelist, ok := h.opts.resources[msg.Resource]
+	if !ok {
+		return fmt.Errorf("unknown resource %q", msg.Resource)
+	}
+	for column := range msg.Conditions {
+		if !whitelist[column] {
+			return fmt.Errorf("column %q is not allowed", column)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok = c.subs[msg.ID]; !ok && len(c.subs) >= h.opts.maxSubscriptions {
+		return fmt.Errorf("too many subscriptions, max %d", h.opts.maxSubscriptions)
+	}
+	c.subs[msg.ID] = &Subscription{ID: msg.ID, Resource: msg.Resource, Conditions: msg.Conditions}
+	return nil
+}
+
+// --------------------------------------------------------------------------------------
+
+type hubConn struct {
+	hub  *Hub
+	conn *websocket.Conn
+	uid  string
+
+	send chan []byte
+
+	mu   sync.Mutex
+	subs map[string]*Subscription
+
+	once      sync.Once
+	done      chan struct{}
+	closeCode int
+	closeText string
+}
+
+func (c *hubConn) match(e *Event) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var ids []string
+	for id, sub := range c.subs {
+		if sub.match(e) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// enqueue the message without blocking, the slow consumer is disconnected if the queue is full
+func (c *hubConn) enqueue(data []byte) bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+
+	select {
+	case c.send <- data:
+		return true
+	default:
+		c.hub.opts.zapLogger.Warn("disconnect slow consumer", zap.String("client", c.conn.RemoteAddr().String()),
+			zap.String("uid", c.uid), zap.Int("queueSize", cap(c.send)))
+		c.close(websocket.ClosePolicyViolation, "slow consumer")
+		return false
+	}
+}
+
+func (c *hubConn) reply(msg *Message) {
+	data, _ := json.Marshal(msg) //nolint
+	c.enqueue(data)
+}
+
+func (c *hubConn) close(code int, text string) {
+	c.once.Do(func() {
+		c.closeCode, c.closeText = code, text
+		close(c.done)
+	})
+}
+
+func (c *hubConn) readLoop() {
+	opts := c.hub.opts
+	c.conn.SetReadLimit(opts.maxMessageSize)
+	_ = c.conn.SetReadDeadline(time.Now().Add(opts.pongTimeout))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(opts.pongTimeout))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			// closed by the client, or the dead connection is reaped when the pong is not received in time
+			c.close(websocket.CloseAbnormalClosure, err.Error())
+			return
+		}
+
+		msg := &Message{}
+		if err = json.Unmarshal(data, msg); err != nil {
+			c.reply(&Message{Type: MsgTypeError, Error: "invalid message"})
+			continue
+		}
+		switch msg.Type {
+		case MsgTypeSubscribe:
+			if err = c.hub.subscribe(c, msg); err != nil {
+				c.reply(&Message{Type: MsgTypeError, ID: msg.ID, Error: err.Error()})
+				continue
+			}
+			c.reply(&Message{Type: MsgTypeSubscribed, ID: msg.ID})
+		case MsgTypeUnsubscribe:
+			c.mu.Lock()
+			delete(c.subs, msg.ID)
+			c.mu.Unlock()
+		default:
+			c.reply(&Message{Type: MsgTypeError, ID: msg.ID, Error: "unknown message type " + msg.Type})
+		}
+	}
+}
+
+func (c *hubConn) writeLoop() {
+	opts := c.hub.opts
+	ticker := time.NewTicker(opts.pingInterval)
+	defer func() {
+		ticker.Stop()
+		_ = c.conn.Close()
+		c.hub.remove(c)
+		c.hub.opts.zapLogger.Info("websocket connection removed from hub", zap.String("client", c.conn.RemoteAddr().String()),
+			zap.String("uid", c.uid), zap.Int("code", c.closeCode), zap.String("reason", c.closeText))
+	}()
+
+	for {
+		select {
+		case data := <-c.send:
+			if err := c.write(websocket.TextMessage, data); err != nil {
+				c.close(websocket.CloseAbnormalClosure, err.Error())
+				return
+			}
+
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(opts.writeTimeout)); err != nil {
+				c.close(websocket.CloseAbnormalClosure, err.Error())
+				return
+			}
+
+		case <-c.done:
+			if c.closeCode == websocket.CloseGoingAway { // flush the queued messages when shutting down
+				for len(c.send) > 0 {
+					if c.write(websocket.TextMessage, <-c.send) != nil {
+						return
+					}
+				}
+			}
+			if c.closeCode != websocket.CloseAbnormalClosure {
+				_ = c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(c.closeCode, c.closeText),
+					time.Now().Add(opts.writeTimeout))
+			}
+			return
+		}
+	}
+}
+
+func (c *hubConn) write(messageType int, data []byte) error {
+	_ = c.conn.SetWriteDeadline(time.Now().Add(c.hub.opts.writeTimeout))
+	return c.conn.WriteMessage(messageType, data)
+}
@@ -0,0 +1,178 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var testWhitelist = map[string]bool{"id": true, "status": true}
+
+func newTestHub(t *testing.T, opts ...HubOption) (*Hub, string) {
+	opts = append([]HubOption{
+		WithHubResource("userExample", testWhitelist),
+		WithHubLogger(zap.NewNop()),
+		WithHubAuthenticate(func(token string) (string, error) {
+			if token != "valid-token" {
+				return "", errors.New("invalid token")
+			}
+			return "100", nil
+		}),
+	}, opts...)
+	hub := NewHub(opts...)
+	srv := httptest.NewServer(hub)
+	t.Cleanup(func() {
+		_ = hub.Close(context.Background())
+		srv.Close()
+	})
+	return hub, "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func dialHub(t *testing.T, url string) *websocket.Conn {
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?token=valid-token", nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func readMessage(t *testing.T, conn *websocket.Conn) *Message {
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	msg := &Message{}
+	require.NoError(t, conn.ReadJSON(msg))
+	return msg
+}
+
+func subscribe(t *testing.T, conn *websocket.Conn, msg *Message) *Message {
+	msg.Type = MsgTypeSubscribe
+	require.NoError(t, conn.WriteJSON(msg))
+	return readMessage(t, conn)
+}
+
+func TestHub_Authenticate(t *testing.T) {
+	_, url := newTestHub(t)
+
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	assert.ErrorIs(t, err, websocket.ErrBadHandshake)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// the token in the header is compatible with middleware.Auth
+	conn, _, err := websocket.DefaultDialer.Dial(url, http.Header{"Authorization": []string{"Bearer valid-token"}})
+	require.NoError(t, err)
+	_ = conn.Close()
+
+	r := httptest.NewRequest(http.MethodGet, "/ws?token=abc", nil)
+	assert.Equal(t, "abc", TokenFromRequest(r))
+	r.Header.Set("Authorization", "Bearer xyz")
+	assert.Equal(t, "xyz", TokenFromRequest(r))
+}
+
+func TestHub_Subscription(t *testing.T) {
+	hub, url := newTestHub(t)
+	conn := dialHub(t, url)
+
+	// invalid subscriptions
+	msg := subscribe(t, conn, &Message{ID: "1", Resource: "order"})
+	assert.Equal(t, MsgTypeError, msg.Type)
+	assert.Contains(t, msg.Error, "unknown resource")
+	msg = subscribe(t, conn, &Message{ID: "1", Resource: "userExample", Conditions: map[string]interface{}{"password": "x"}})
+	assert.Equal(t, MsgTypeError, msg.Type)
+	assert.Contains(t, msg.Error, `column "password" is not allowed`)
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("not json")))
+	assert.Equal(t, MsgTypeError, readMessage(t, conn).Type)
+
+	msg = subscribe(t, conn, &Message{ID: "active", Resource: "userExample", Conditions: map[string]interface{}{"status": 1}})
+	assert.Equal(t, MsgTypeSubscribed, msg.Type)
+	assert.Equal(t, "active", msg.ID)
+
+	assert.Equal(t, 0, hub.Publish(&Event{Resource: "userExample", Action: "updated", Data: map[string]interface{}{"id": 1, "status": 2}}))
+	assert.Equal(t, 0, hub.Publish(&Event{Resource: "order", Action: "created", Data: map[string]interface{}{"status": 1}}))
+	assert.Equal(t, 1, hub.Publish(&Event{Resource: "userExample", Action: "created", Data: map[string]interface{}{"id": 2, "status": 1}}))
+	msg = readMessage(t, conn)
+	assert.Equal(t, MsgTypeEvent, msg.Type)
+	assert.Equal(t, []string{"active"}, msg.IDs)
+	assert.Equal(t, "created", msg.Event.Action)
+	assert.EqualValues(t, 2, msg.Event.Data["id"])
+
+	// unsubscribe
+	require.NoError(t, conn.WriteJSON(&Message{Type: MsgTypeUnsubscribe, ID: "active"}))
+	assert.Eventually(t, func() bool {
+		return hub.Publish(&Event{Resource: "userExample", Data: map[string]interface{}{"status": 1}}) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestHub_SlowConsumer(t *testing.T) {
+	hub, url := newTestHub(t, WithHubSendQueueSize(2), WithHubWriteTimeout(500*time.Millisecond))
+	slow := dialHub(t, url)
+	fast := dialHub(t, url)
+	msg := subscribe(t, slow, &Message{ID: "slow", Resource: "userExample", Conditions: map[string]interface{}{"id": 1}})
+	require.Equal(t, MsgTypeSubscribed, msg.Type)
+	msg = subscribe(t, fast, &Message{ID: "fast", Resource: "userExample", Conditions: map[string]interface{}{"id": 2}})
+	require.Equal(t, MsgTypeSubscribed, msg.Type)
+
+	// the slow consumer does not read, its writer is blocked until the write timeout after the socket buffer
+	// is full, then the send queue is full, the events are only sent to the slow consumer, so the fast
+	// consumer is never disconnected by the flood
+	data := map[string]interface{}{"id": 1, "avatar": strings.Repeat("x", 1<<20)}
+	assert.Eventually(t, func() bool {
+		hub.Publish(&Event{Resource: "userExample", Action: "updated", Data: data})
+		return hub.Len() == 1
+	}, 10*time.Second, 5*time.Millisecond)
+
+	// the fast consumer is still connected
+	assert.Equal(t, 1, hub.Publish(&Event{Resource: "userExample", Action: "updated", Data: map[string]interface{}{"id": 2}}))
+	msg = readMessage(t, fast)
+	assert.Equal(t, MsgTypeEvent, msg.Type)
+	assert.Equal(t, []string{"fast"}, msg.IDs)
+}
+
+func TestHub_Keepalive(t *testing.T) {
+	hub, url := newTestHub(t, WithHubKeepalive(50*time.Millisecond, 200*time.Millisecond))
+	dialHub(t, url) // the dead connection does not read, the pong is replied only when reading
+	alive := dialHub(t, url)
+	go func() {
+		for {
+			if _, _, err := alive.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	require.Eventually(t, func() bool { return hub.Len() == 2 }, time.Second, 10*time.Millisecond)
+
+	assert.Eventually(t, func() bool { return hub.Len() == 1 }, 2*time.Second, 10*time.Millisecond)
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, 1, hub.Len())
+}
+
+func TestHub_Close(t *testing.T) {
+	hub, url := newTestHub(t)
+	conn := dialHub(t, url)
+	msg := subscribe(t, conn, &Message{ID: "all", Resource: "userExample"})
+	require.Equal(t, MsgTypeSubscribed, msg.Type)
+
+	assert.Equal(t, 1, hub.Publish(&Event{Resource: "userExample", Action: "deleted"}))
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	assert.NoError(t, hub.Close(ctx))
+	assert.Equal(t, 0, hub.Len())
+
+	// the queued event is flushed before the close frame
+	msg = readMessage(t, conn)
+	assert.Equal(t, "deleted", msg.Event.Action)
+	_, _, err := conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), err)
+
+	// no new connections and events after closed
+	assert.Equal(t, 0, hub.Publish(&Event{Resource: "userExample"}))
+	_, resp, err := websocket.DefaultDialer.Dial(url+"?token=valid-token", nil)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+}